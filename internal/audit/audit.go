@@ -0,0 +1,277 @@
+// Package audit scans Copilot assets for content that could turn an agent
+// against the project using it: prompt injections, requests to leak data,
+// risky commands and the places they point at.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Severity ranks how likely a finding is to be malicious.
+type Severity int
+
+const (
+	// Low findings are worth knowing about but common in benign assets,
+	// e.g. links to documentation.
+	Low Severity = iota + 1
+	// Medium findings are risky in an instruction to an agent and should be
+	// reviewed.
+	Medium
+	// High findings are hallmarks of an attack.
+	High
+)
+
+// String returns the name of s, as accepted by ParseSeverity.
+func (s Severity) String() string {
+	switch s {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// ParseSeverity parses "low", "medium" or "high".
+func ParseSeverity(name string) (Severity, error) {
+	for _, s := range []Severity{Low, Medium, High} {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q: must be low, medium or high", name)
+}
+
+// Finding is a risky pattern found in an asset.
+type Finding struct {
+	Rule     string // identifier of the rule that matched, e.g. "prompt-injection"
+	Severity Severity
+	Line     int    // 1-based line of the match
+	Match    string // matched text, trimmed to a readable length
+	Message  string // why the match is risky
+}
+
+// rule is a pattern Scan looks for, line by line.
+type rule struct {
+	id       string
+	severity Severity
+	pattern  *regexp.Regexp
+	message  string
+}
+
+var rules = []rule{
+	{
+		id:       "prompt-injection",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|system|original)\s+(instructions|prompts?|rules|guidelines|messages)`),
+		message:  "asks the agent to drop the instructions it was given",
+	},
+	{
+		id:       "prompt-injection",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)\b(do\s+not|don'?t|never)\s+(tell|inform|mention\s+(this\s+)?to|reveal\s+(this\s+)?to|show)\s+the\s+user\b`),
+		message:  "asks the agent to hide what it does from the user",
+	},
+	{
+		id:       "prompt-injection",
+		severity: Medium,
+		pattern:  regexp.MustCompile(`(?i)\b(reveal|print|output|repeat)\s+(your|the)\s+(system\s+prompt|hidden\s+instructions)`),
+		message:  "asks the agent to disclose its instructions",
+	},
+	{
+		id:       "exfiltration",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)\b(send|upload|post|exfiltrate|transmit|forward|leak)\b.{0,60}\b(secrets?|credentials?|tokens?|passwords?|api[\s_-]?keys?|private\s+keys?|ssh\s+keys?|\.env\b|environment\s+variables)`),
+		message:  "asks the agent to send sensitive data somewhere",
+	},
+	{
+		id:       "exfiltration",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)\b(curl|wget|Invoke-WebRequest|iwr)\b.*(\$\{?\w*(TOKEN|SECRET|PASSWORD|KEY)\w*|~/\.ssh|\.aws/credentials|\.env\b|/etc/passwd)`),
+		message:  "sends secrets or credential files over the network",
+	},
+	{
+		id:       "sensitive-file",
+		severity: Medium,
+		pattern:  regexp.MustCompile(`(~|\$HOME)/\.(ssh|aws|gnupg|kube|docker/config\.json|netrc|npmrc|git-credentials)|/etc/(passwd|shadow)`),
+		message:  "refers to credential files outside the project",
+	},
+	{
+		id:       "remote-execution",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)\b(curl|wget|iwr|Invoke-WebRequest)\b[^|\n]*\|\s*(sudo\s+)?(sh|bash|zsh|python3?|perl|ruby|node|iex|Invoke-Expression)\b`),
+		message:  "pipes a download straight into an interpreter",
+	},
+	{
+		id:       "obfuscated-execution",
+		severity: High,
+		pattern:  regexp.MustCompile(`(?i)(base64\s+(-d|--decode)|frombase64string)[^\n]*(\|\s*(sh|bash|zsh|python3?)\b|iex\b|invoke-expression)`),
+		message:  "decodes and runs hidden commands",
+	},
+	{
+		id:       "destructive-command",
+		severity: High,
+		pattern:  regexp.MustCompile(`\brm\s+-(rf|fr|r\s+-f|f\s+-r)\s+(/|~|\$HOME|\*)(\s|$)|\bmkfs(\.\w+)?\s|\bdd\s+if=.*\bof=/dev/|:\(\)\s*\{\s*:\|:&\s*\};:`),
+		message:  "runs a command that destroys data",
+	},
+	{
+		id:       "reverse-shell",
+		severity: High,
+		pattern:  regexp.MustCompile(`\b(nc|ncat|netcat)\b.*\s-(e|c)\s|/dev/tcp/|\bsocat\b.*\bexec:`),
+		message:  "opens a shell to a remote host",
+	},
+	{
+		id:       "suspicious-command",
+		severity: Medium,
+		pattern:  regexp.MustCompile(`(?i)\b(chmod\s+(\+x|[0-7]*7[0-7]{2})|sudo\s|eval\s*[\("$]|git\s+push\s+.*--force|git\s+config\s+--global|npm\s+publish|crontab\s)`),
+		message:  "runs a command with effects beyond the project",
+	},
+}
+
+var (
+	urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}` + "`" + `]+`)
+
+	// hiddenChars are invisible or reordering characters that can smuggle
+	// instructions past a reviewer.
+	hiddenChars = regexp.MustCompile(`[\x{200b}-\x{200f}\x{202a}-\x{202e}\x{2060}-\x{2064}\x{2066}-\x{2069}\x{feff}\x{e0000}-\x{e007f}]`)
+
+	// htmlComment matches an HTML comment on one line: rendered Markdown
+	// hides it, but agents read it.
+	htmlComment = regexp.MustCompile(`<!--(.*?)-->`)
+
+	// imperative words that make a hidden comment look like instructions.
+	imperative = regexp.MustCompile(`(?i)\b(you\s+must|you\s+should|always|never|ignore|instead|run|execute|send)\b`)
+
+	ipv4Pattern = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}$`)
+)
+
+// shorteners are URL shortening services, which hide where a link leads.
+var shorteners = map[string]bool{
+	"bit.ly": true, "tinyurl.com": true, "t.co": true, "goo.gl": true,
+	"is.gd": true, "ow.ly": true, "buff.ly": true, "rebrand.ly": true,
+	"cutt.ly": true, "shorturl.at": true,
+}
+
+// pasteSites host anonymous content, often used to stage payloads or
+// collect leaked data.
+var pasteSites = map[string]bool{
+	"pastebin.com": true, "paste.ee": true, "hastebin.com": true,
+	"transfer.sh": true, "webhook.site": true, "requestbin.com": true,
+	"pipedream.net": true, "ngrok.io": true, "ngrok-free.app": true,
+}
+
+// Scan returns the findings in an asset's content, leaked secrets
+// included, in line order. Binary content is not scanned.
+func Scan(content []byte) []Finding {
+	return scan(content, func(line string, n int) []Finding {
+		return append(scanLine(line, n), secretsLine(line, n)...)
+	})
+}
+
+// scan runs lineFindings over each line of content, unless it is binary.
+func scan(content []byte, lineFindings func(line string, n int) []Finding) []Finding {
+	if bytes.IndexByte(content, 0) >= 0 {
+		return nil
+	}
+	var findings []Finding
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64<<10), len(content)+1)
+	for n := 1; sc.Scan(); n++ {
+		findings = append(findings, lineFindings(sc.Text(), n)...)
+	}
+	return findings
+}
+
+// scanLine returns the findings of line n.
+func scanLine(line string, n int) []Finding {
+	var findings []Finding
+	add := func(id string, severity Severity, match, message string) {
+		findings = append(findings, Finding{Rule: id, Severity: severity, Line: n, Match: excerpt(match), Message: message})
+	}
+
+	for _, r := range rules {
+		if m := r.pattern.FindString(line); m != "" {
+			add(r.id, r.severity, m, r.message)
+		}
+	}
+	if m := hiddenChars.FindString(line); m != "" {
+		add("hidden-text", High, fmt.Sprintf("%U", []rune(m)[0]), "contains invisible characters that can hide instructions")
+	}
+	for _, m := range htmlComment.FindAllStringSubmatch(line, -1) {
+		if imperative.MatchString(m[1]) {
+			add("hidden-text", Medium, m[0], "gives instructions in a comment hidden from rendered Markdown")
+		}
+	}
+	for _, raw := range urlPattern.FindAllString(line, -1) {
+		add(classifyURL(strings.TrimRight(raw, ".,;:!?")))
+	}
+	return findings
+}
+
+// classifyURL returns the finding for a URL: where it points and how
+// suspicious that is.
+func classifyURL(raw string) (string, Severity, string, string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return "url", Low, raw, "links to an external resource"
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case isIPAddress(host):
+		return "url", Medium, raw, "links to a bare IP address rather than a domain"
+	case shorteners[host]:
+		return "url", Medium, raw, "links through a URL shortener, hiding its destination"
+	case matchesDomain(host, pasteSites):
+		return "url", Medium, raw, "links to " + host + ", which hosts anonymous content or collects requests"
+	default:
+		return "url", Low, raw, "links to " + host
+	}
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one.
+func matchesDomain(host string, domains map[string]bool) bool {
+	for {
+		if domains[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+}
+
+// isIPAddress reports whether host is an IPv4 or IPv6 literal.
+func isIPAddress(host string) bool {
+	if strings.Contains(host, ":") {
+		return true
+	}
+	return ipv4Pattern.MatchString(host)
+}
+
+// excerpt trims a match to a readable length.
+func excerpt(s string) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > 80 {
+		return string(r[:77]) + "..."
+	}
+	return s
+}
+
+// Max returns the highest severity among findings, or 0 if there are none.
+func Max(findings []Finding) Severity {
+	var highest Severity
+	for _, f := range findings {
+		highest = max(highest, f.Severity)
+	}
+	return highest
+}
@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/cbout22/copilot-sync/internal/ui"
 )
 
 // githubTokenEnvVars lists the environment variables checked for a GitHub token,
@@ -14,36 +17,77 @@ var githubTokenEnvVars = []string{
 	"GH_TOKEN",
 }
 
-// Token returns the GitHub personal access token from the environment.
-// It checks GITHUB_TOKEN first, then GH_TOKEN.
+// ErrNoToken is returned by Token when no credential source yields a token.
+var ErrNoToken = fmt.Errorf(
+	"no GitHub token found: set %s or %s in your environment, or run `cops login`",
+	githubTokenEnvVars[0], githubTokenEnvVars[1],
+)
+
+// Token returns the GitHub personal access token. It checks, in order: the
+// output of COPS_TOKEN_COMMAND, the token_file from the user configuration,
+// the system keychain (see `cops login`), GITHUB_TOKEN, GH_TOKEN, and the
+// credentials of a logged-in GitHub CLI. A command or file configured for
+// cops wins over a stored token, and one that fails is an error rather than
+// a reason to fall through.
 func Token() (string, error) {
+	for _, source := range []func() (string, error){commandToken, fileToken} {
+		token, err := source()
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+	}
+	if token := keychainToken(); token != "" {
+		return token, nil
+	}
 	for _, env := range githubTokenEnvVars {
 		if v := os.Getenv(env); v != "" {
 			return v, nil
 		}
 	}
-	return "", fmt.Errorf(
-		"no GitHub token found: set %s or %s in your environment",
-		githubTokenEnvVars[0], githubTokenEnvVars[1],
-	)
+	if token := ghCLIToken(); token != "" {
+		return token, nil
+	}
+	return "", ErrNoToken
 }
 
 // NewHTTPClient returns an *http.Client suitable for GitHub API calls.
 // If a GitHub token is available it adds Bearer auth on every request.
 // Otherwise it returns a plain client (sufficient for public repos, but
-// subject to stricter rate limits).
+// subject to stricter rate limits). A token command or file that is
+// configured but fails is returned as an error. Requests time out after
+// RequestTimeout.
 func NewHTTPClient() (*http.Client, error) {
-	return NewHTTPClientWithTimeout(0)
+	timeout, err := RequestTimeout()
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPClientWithTimeout(timeout)
 }
 
 // NewHTTPClientWithTimeout returns an *http.Client with a specific timeout.
 func NewHTTPClientWithTimeout(timeout time.Duration) (*http.Client, error) {
 	token, err := Token()
+	if err != nil && !errors.Is(err, ErrNoToken) {
+		return nil, err
+	}
+	return NewTokenClient(token, timeout)
+}
+
+// NewTokenClient returns an *http.Client for GitHub API calls authenticated
+// with token, already resolved by Token, or a plain client if it is empty.
+func NewTokenClient(token string, timeout time.Duration) (*http.Client, error) {
+	transport, err := NewTransport()
 	if err != nil {
+		return nil, err
+	}
+	if token == "" {
 		// No token — return a plain client for public repo access
-		fmt.Fprintf(os.Stderr, "⚠️  No GitHub token found — using unauthenticated requests (rate-limited).\n")
-		fmt.Fprintf(os.Stderr, "   Set GITHUB_TOKEN or GH_TOKEN for private repos and higher rate limits.\n")
-		client := &http.Client{Timeout: timeout}
+		fmt.Fprint(os.Stderr, ui.Text("⚠️  No GitHub token found — using unauthenticated requests (rate-limited).\n"))
+		fmt.Fprintf(os.Stderr, "   Set GITHUB_TOKEN or GH_TOKEN (or run `cops login`) for private repos and higher rate limits.\n")
+		client := &http.Client{Timeout: timeout, Transport: transport}
 		return client, nil
 	}
 
@@ -51,7 +95,7 @@ func NewHTTPClientWithTimeout(timeout time.Duration) (*http.Client, error) {
 		Timeout: timeout,
 		Transport: &tokenTransport{
 			token: token,
-			base:  http.DefaultTransport,
+			base:  transport,
 		},
 	}, nil
 }
@@ -65,7 +109,22 @@ type tokenTransport struct {
 func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Clone the request to avoid mutating the original
 	r := req.Clone(req.Context())
-	r.Header.Set("Authorization", "Bearer "+t.token)
-	r.Header.Set("Accept", "application/vnd.github.v3+json")
+	// Never forward the token across a redirect to another host (e.g. release
+	// asset downloads redirect to pre-signed storage URLs).
+	if !isCrossHostRedirect(req) {
+		r.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	if r.Header.Get("Accept") == "" {
+		r.Header.Set("Accept", "application/vnd.github.v3+json")
+	}
 	return t.base.RoundTrip(r)
 }
+
+// isCrossHostRedirect reports whether req was created by following a
+// redirect to a different host than the original request.
+func isCrossHostRedirect(req *http.Request) bool {
+	if req.Response == nil || req.Response.Request == nil {
+		return false
+	}
+	return req.Response.Request.URL.Host != req.URL.Host
+}
@@ -9,6 +9,7 @@ import (
 )
 
 func TestToken_GITHUB_TOKEN(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "gh-token-123")
 	t.Setenv("GH_TOKEN", "")
 
@@ -22,6 +23,7 @@ func TestToken_GITHUB_TOKEN(t *testing.T) {
 }
 
 func TestToken_GH_TOKEN_Fallback(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "")
 	t.Setenv("GH_TOKEN", "fallback-token")
 
@@ -35,6 +37,7 @@ func TestToken_GH_TOKEN_Fallback(t *testing.T) {
 }
 
 func TestToken_GITHUB_TOKEN_Priority(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "primary")
 	t.Setenv("GH_TOKEN", "secondary")
 
@@ -48,6 +51,7 @@ func TestToken_GITHUB_TOKEN_Priority(t *testing.T) {
 }
 
 func TestToken_NoToken(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "")
 	t.Setenv("GH_TOKEN", "")
 
@@ -58,6 +62,7 @@ func TestToken_NoToken(t *testing.T) {
 }
 
 func TestNewHTTPClient_WithToken(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "test-token")
 
 	client, err := NewHTTPClient()
@@ -90,6 +95,7 @@ func TestNewHTTPClient_WithToken(t *testing.T) {
 }
 
 func TestNewHTTPClient_NoToken(t *testing.T) {
+	isolateCredentials(t)
 	t.Setenv("GITHUB_TOKEN", "")
 	t.Setenv("GH_TOKEN", "")
 
@@ -118,3 +124,65 @@ func TestNewHTTPClientWithTimeout(t *testing.T) {
 		t.Errorf("Timeout: got %v, want %v", client.Timeout, 5*time.Second)
 	}
 }
+
+func TestNewTokenClient(t *testing.T) {
+	isolateCredentials(t)
+	// The given token is used as is, not resolved again.
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer given-token" {
+			t.Errorf("Authorization header: got %q, want %q", auth, "Bearer given-token")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewTokenClient("given-token", 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewTokenClient(): unexpected error: %v", err)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Timeout: got %v, want %v", client.Timeout, 5*time.Second)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("client.Get(): %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNewHTTPClient_TokenNotForwardedAcrossHosts(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("redirect target received Authorization %q", auth)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storage.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("origin did not receive the token")
+		}
+		if got := r.Header.Get("Accept"); got != "application/octet-stream" {
+			t.Errorf("Accept header overridden: got %q", got)
+		}
+		http.Redirect(w, r, storage.URL+"/asset", http.StatusFound)
+	}))
+	defer api.Close()
+
+	client, err := NewHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, api.URL, nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do(): %v", err)
+	}
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OAuthClientIDEnvVar names the environment variable overriding the OAuth
+// app used by the device flow.
+const OAuthClientIDEnvVar = "COPS_OAUTH_CLIENT_ID"
+
+// oauthClientID is the OAuth app client ID, set at build time via
+// -ldflags "-X github.com/cbout22/copilot-sync/internal/auth.oauthClientID=<id>"
+// (see .goreleaser.yaml). Builds without it need OAuthClientIDEnvVar.
+var oauthClientID = ""
+
+// refreshMargin renews device-flow tokens slightly before they expire.
+const refreshMargin = time.Minute
+
+// OAuthClientID returns the OAuth app client ID used by the device flow.
+func OAuthClientID() string {
+	if id := os.Getenv(OAuthClientIDEnvVar); id != "" {
+		return id
+	}
+	return oauthClientID
+}
+
+// Credential is a token obtained through the device flow. Tokens issued by
+// GitHub Apps with expiring user tokens carry a refresh token and expiry.
+type Credential struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expires      time.Time `json:"expires,omitzero"`
+}
+
+// expired reports whether the access token is past (or about to pass) its expiry.
+func (c Credential) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && now.Add(refreshMargin).After(c.Expires)
+}
+
+// Encode serialises the credential for storage in the keychain.
+func (c Credential) Encode() string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// decodeCredential parses a keychain value. Plain tokens stored by
+// `cops login` are returned as credentials without expiry.
+func decodeCredential(stored string) Credential {
+	var c Credential
+	if strings.HasPrefix(stored, "{") && json.Unmarshal([]byte(stored), &c) == nil {
+		return c
+	}
+	return Credential{AccessToken: stored}
+}
+
+// DeviceCode is the code the user enters at VerificationURI to approve a
+// device-flow login.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceFlow runs GitHub's OAuth device authorization flow.
+type DeviceFlow struct {
+	ClientID string
+	Scopes   []string
+	BaseURL  string // "https://github.com"
+	Client   *http.Client
+
+	// Sleep and Now are replaceable for tests.
+	Sleep func(time.Duration)
+	Now   func() time.Time
+}
+
+// NewDeviceFlow creates a DeviceFlow against github.com for clientID.
+func NewDeviceFlow(clientID string, scopes ...string) *DeviceFlow {
+	client := &http.Client{Timeout: 30 * time.Second}
+	// A broken proxy or CA setting is reported by the commands that build
+	// their client with NewHTTPClient.
+	if transport, err := NewTransport(); err == nil {
+		client.Transport = transport
+	}
+	return &DeviceFlow{
+		ClientID: clientID,
+		Scopes:   scopes,
+		BaseURL:  "https://github.com",
+		Client:   client,
+		Sleep:    time.Sleep,
+		Now:      time.Now,
+	}
+}
+
+// tokenResponse is the access token endpoint's reply, success or error.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// Start requests a device and user code.
+func (f *DeviceFlow) Start() (*DeviceCode, error) {
+	if f.ClientID == "" {
+		return nil, fmt.Errorf("no OAuth client ID configured: set %s", OAuthClientIDEnvVar)
+	}
+	var code DeviceCode
+	form := url.Values{"client_id": {f.ClientID}, "scope": {strings.Join(f.Scopes, " ")}}
+	if err := f.post("/login/device/code", form, &code); err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+	if code.DeviceCode == "" {
+		return nil, fmt.Errorf("requesting device code: empty response")
+	}
+	return &code, nil
+}
+
+// Poll waits for the user to approve code and returns the issued credential.
+func (f *DeviceFlow) Poll(code *DeviceCode) (Credential, error) {
+	interval := time.Duration(max(code.Interval, 1)) * time.Second
+	deadline := f.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	form := url.Values{
+		"client_id":   {f.ClientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	for {
+		if code.ExpiresIn > 0 && f.Now().After(deadline) {
+			return Credential{}, fmt.Errorf("device code expired before it was approved")
+		}
+		f.Sleep(interval)
+
+		var resp tokenResponse
+		if err := f.post("/login/oauth/access_token", form, &resp); err != nil {
+			return Credential{}, fmt.Errorf("polling for access token: %w", err)
+		}
+		switch resp.Error {
+		case "":
+			return f.credential(resp), nil
+		case "authorization_pending":
+		case "slow_down":
+			interval = time.Duration(max(resp.Interval, int(interval/time.Second)+5)) * time.Second
+		default:
+			return Credential{}, fmt.Errorf("device authorization failed: %s — %s", resp.Error, resp.ErrorDescription)
+		}
+	}
+}
+
+// Refresh exchanges cred's refresh token for a new credential.
+func (f *DeviceFlow) Refresh(cred Credential) (Credential, error) {
+	if cred.RefreshToken == "" {
+		return Credential{}, fmt.Errorf("token expired and cannot be refreshed: run `cops login --device`")
+	}
+	form := url.Values{
+		"client_id":     {f.ClientID},
+		"refresh_token": {cred.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+	var resp tokenResponse
+	if err := f.post("/login/oauth/access_token", form, &resp); err != nil {
+		return Credential{}, fmt.Errorf("refreshing access token: %w", err)
+	}
+	if resp.Error != "" {
+		return Credential{}, fmt.Errorf("refreshing access token: %s — %s", resp.Error, resp.ErrorDescription)
+	}
+	return f.credential(resp), nil
+}
+
+func (f *DeviceFlow) credential(resp tokenResponse) Credential {
+	c := Credential{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken}
+	if resp.ExpiresIn > 0 {
+		c.Expires = f.Now().Add(time.Duration(resp.ExpiresIn) * time.Second).UTC().Truncate(time.Second)
+	}
+	return c
+}
+
+// post sends form to path and decodes the JSON reply into v.
+func (f *DeviceFlow) post(path string, form url.Values, v any) error {
+	req, err := http.NewRequest(http.MethodPost, f.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d — %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newDeviceTestServer serves the device flow endpoints. Access token polls
+// replay replies in order; refresh requests get refreshReply.
+func newDeviceTestServer(t *testing.T, replies []map[string]any, refreshReply map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_id") != "cid" {
+			t.Errorf("client_id = %q", r.PostForm.Get("client_id"))
+		}
+		switch {
+		case r.URL.Path == "/login/device/code":
+			if r.PostForm.Get("scope") != "repo read:org" {
+				t.Errorf("scope = %q", r.PostForm.Get("scope"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"device_code": "dev123", "user_code": "ABCD-1234",
+				"verification_uri": "https://github.com/login/device", "expires_in": 900, "interval": 5,
+			})
+		case r.URL.Path == "/login/oauth/access_token" && r.PostForm.Get("grant_type") == "refresh_token":
+			_ = json.NewEncoder(w).Encode(refreshReply)
+		case r.URL.Path == "/login/oauth/access_token":
+			if r.PostForm.Get("device_code") != "dev123" {
+				t.Errorf("device_code = %q", r.PostForm.Get("device_code"))
+			}
+			reply := replies[0]
+			replies = replies[1:]
+			_ = json.NewEncoder(w).Encode(reply)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// testFlow returns a flow against ts with a fake clock advanced by Sleep.
+func testFlow(ts *httptest.Server, slept *[]time.Duration) *DeviceFlow {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewDeviceFlow("cid", "repo", "read:org")
+	f.BaseURL = ts.URL
+	f.Client = ts.Client()
+	f.Now = func() time.Time { return now }
+	f.Sleep = func(d time.Duration) {
+		*slept = append(*slept, d)
+		now = now.Add(d)
+	}
+	return f
+}
+
+func TestDeviceFlow_Poll(t *testing.T) {
+	t.Parallel()
+	ts := newDeviceTestServer(t, []map[string]any{
+		{"error": "authorization_pending"},
+		{"error": "slow_down", "interval": 10},
+		{"access_token": "ghu_new", "refresh_token": "ghr_new", "expires_in": 28800},
+	}, nil)
+	defer ts.Close()
+
+	var slept []time.Duration
+	flow := testFlow(ts, &slept)
+	code, err := flow.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.UserCode != "ABCD-1234" {
+		t.Errorf("UserCode = %q", code.UserCode)
+	}
+
+	cred, err := flow.Poll(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credential{AccessToken: "ghu_new", RefreshToken: "ghr_new", Expires: time.Date(2025, 1, 1, 8, 0, 20, 0, time.UTC)}
+	if cred != want {
+		t.Errorf("Poll() = %+v, want %+v", cred, want)
+	}
+	if !slices.Equal(slept, []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second}) {
+		t.Errorf("poll intervals = %v, want slow_down honoured", slept)
+	}
+}
+
+func TestDeviceFlow_Denied(t *testing.T) {
+	t.Parallel()
+	ts := newDeviceTestServer(t, []map[string]any{{"error": "access_denied", "error_description": "denied"}}, nil)
+	defer ts.Close()
+
+	var slept []time.Duration
+	flow := testFlow(ts, &slept)
+	if _, err := flow.Poll(&DeviceCode{DeviceCode: "dev123", ExpiresIn: 900, Interval: 5}); err == nil {
+		t.Fatal("Poll(): expected error when the user denies access")
+	}
+}
+
+func TestDeviceFlow_StartWithoutClientID(t *testing.T) {
+	t.Parallel()
+	if _, err := NewDeviceFlow("").Start(); err == nil {
+		t.Fatal("Start(): expected error without a client ID")
+	}
+}
+
+func TestDecodeCredential(t *testing.T) {
+	t.Parallel()
+	if got := decodeCredential("ghp_plain"); got != (Credential{AccessToken: "ghp_plain"}) {
+		t.Errorf("plain token decoded as %+v", got)
+	}
+	c := Credential{AccessToken: "a", RefreshToken: "r", Expires: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if got := decodeCredential(c.Encode()); got != c {
+		t.Errorf("round trip = %+v, want %+v", got, c)
+	}
+}
+
+func TestToken_RefreshesExpiredKeychainCredential(t *testing.T) {
+	ts := newDeviceTestServer(t, nil, map[string]any{"access_token": "ghu_fresh", "refresh_token": "ghr_fresh", "expires_in": 3600})
+	defer ts.Close()
+
+	var slept []time.Duration
+	flow := testFlow(ts, &slept)
+	prev := refreshFlow
+	refreshFlow = func() *DeviceFlow { return flow }
+	t.Cleanup(func() { refreshFlow = prev })
+
+	expired := Credential{AccessToken: "ghu_old", RefreshToken: "ghr_old", Expires: flow.Now().Add(-time.Hour)}
+	useKeychain(t, expired.Encode())
+
+	tok, err := Token()
+	if err != nil || tok != "ghu_fresh" {
+		t.Fatalf("Token() = %q, %v; want refreshed token", tok, err)
+	}
+	stored, _ := keychain.Get()
+	if got := decodeCredential(stored); got.RefreshToken != "ghr_fresh" {
+		t.Errorf("keychain holds %+v, want the refreshed credential", got)
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// githubHost is the host whose GitHub CLI credentials cops reuses.
+const githubHost = "github.com"
+
+// ghCommandTimeout bounds `gh auth token`, which may prompt a keyring
+// unlock on some systems.
+const ghCommandTimeout = 5 * time.Second
+
+// ghCLIToken returns the token the GitHub CLI is logged in with, asking
+// `gh auth token` first (which also covers tokens kept in the system
+// keyring) and falling back to gh's hosts.yml.
+func ghCLIToken() string {
+	if token := ghAuthTokenCommand(); token != "" {
+		return token
+	}
+	return ghHostsFileToken()
+}
+
+// ghAuthTokenCommand runs `gh auth token`. It returns "" if gh is not
+// installed or not logged in.
+func ghAuthTokenCommand() string {
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ghCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "auth", "token", "--hostname", githubHost).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ghConfigDir returns the GitHub CLI's configuration directory.
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh")
+}
+
+// ghHostsFileToken reads the github.com oauth_token from gh's hosts.yml.
+func ghHostsFileToken() string {
+	dir := ghConfigDir()
+	if dir == "" {
+		return ""
+	}
+	f, err := os.Open(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	return parseGHHosts(bufio.NewScanner(f), githubHost)
+}
+
+// parseGHHosts extracts the oauth_token from host's block in a hosts.yml:
+//
+//	github.com:
+//	    user: octocat
+//	    oauth_token: gho_xxx
+//
+// Only the subset of YAML gh writes is understood.
+func parseGHHosts(scanner *bufio.Scanner, host string) string {
+	inHost := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			inHost = strings.TrimSuffix(trimmed, ":") == host
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		if key, value, ok := strings.Cut(trimmed, ":"); ok && key == "oauth_token" {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
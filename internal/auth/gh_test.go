@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// isolateCredentials hides the user's GitHub CLI, cops configuration and
+// token command from the test.
+func isolateCredentials(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv(TokenCommandEnvVar, "")
+	t.Setenv(CACertsEnvVar, "")
+}
+
+func TestParseGHHosts(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name, data, want string
+	}{
+		{"single host", "github.com:\n    user: octocat\n    oauth_token: gho_abc\n    git_protocol: https\n", "gho_abc"},
+		{"other host first", "ghe.corp:\n    oauth_token: ghe_tok\ngithub.com:\n    oauth_token: \"gho_q\"\n", "gho_q"},
+		{"keyring only", "github.com:\n    user: octocat\n    git_protocol: https\n", ""},
+		{"host missing", "ghe.corp:\n    oauth_token: ghe_tok\n", ""},
+	}
+	for _, tc := range cases {
+		got := parseGHHosts(bufio.NewScanner(strings.NewReader(tc.data)), "github.com")
+		if got != tc.want {
+			t.Errorf("%s: parseGHHosts() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestToken_GHHostsFileFallback(t *testing.T) {
+	isolateCredentials(t)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	hosts := "github.com:\n    oauth_token: gho_from_file\n"
+	if err := os.WriteFile(filepath.Join(os.Getenv("GH_CONFIG_DIR"), "hosts.yml"), []byte(hosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := Token()
+	if err != nil {
+		t.Fatalf("Token(): unexpected error: %v", err)
+	}
+	if tok != "gho_from_file" {
+		t.Errorf("Token(): got %q, want hosts.yml token", tok)
+	}
+}
+
+func TestToken_GHCommandFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake gh")
+	}
+	isolateCredentials(t)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	script := "#!/bin/sh\n[ \"$1 $2\" = \"auth token\" ] && echo gho_from_cli\n"
+	if err := os.WriteFile(filepath.Join(os.Getenv("PATH"), "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := Token()
+	if err != nil {
+		t.Fatalf("Token(): unexpected error: %v", err)
+	}
+	if tok != "gho_from_cli" {
+		t.Errorf("Token(): got %q, want gh CLI token", tok)
+	}
+}
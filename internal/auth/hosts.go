@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// NewHostTransport returns base sending the token of the user
+// configuration's [hosts] table to each host listed there, as a bearer
+// token. Requests that already carry credentials, such as mirrors with a
+// netrc entry, are left alone. A token_file that cannot be read is an
+// error; an unset token_env sends nothing.
+func NewHostTransport(base http.RoundTripper) (http.RoundTripper, error) {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]string)
+	for host, h := range cfg.Hosts {
+		var token string
+		switch {
+		case h.TokenFile != "":
+			path, err := ExpandHome(h.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("hosts.%q: token_file: %w", host, err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("hosts.%q: token_file: %w", host, err)
+			}
+			token = strings.TrimSpace(string(data))
+		case h.TokenEnv != "":
+			token = os.Getenv(h.TokenEnv)
+		default:
+			return nil, fmt.Errorf("hosts.%q: set token_file or token_env", host)
+		}
+		if token != "" {
+			tokens[strings.ToLower(host)] = token
+		}
+	}
+	if len(tokens) == 0 {
+		return base, nil
+	}
+	return &hostTokenTransport{tokens: tokens, base: base}, nil
+}
+
+// hostTokenTransport adds the token of the request's host, if it has one.
+type hostTokenTransport struct {
+	tokens map[string]string // lower-case host → token
+	base   http.RoundTripper
+}
+
+func (t *hostTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, ok := t.tokens[strings.ToLower(req.URL.Host)]
+	if !ok {
+		token, ok = t.tokens[strings.ToLower(req.URL.Hostname())]
+	}
+	if !ok || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHostTransport(t *testing.T) {
+	isolateCredentials(t)
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+	host := mustParse(t, server.URL).Host
+
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "artifacts-token"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeUserConfig(t, `[hosts."`+host+`"]
+token_file = "~/artifacts-token"
+
+[hosts."other.example.invalid"]
+token_env = "COPS_TEST_UNSET_TOKEN"
+`)
+	transport, err := NewHostTransport(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+	if resp, err := client.Get(server.URL + "/go.md"); err == nil {
+		resp.Body.Close()
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/go.md", nil)
+	req.SetBasicAuth("user", "netrc")
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	if len(got) != 2 || got[0] != "Bearer from-file" || got[1] == "Bearer from-file" {
+		t.Errorf("Authorization headers = %q; want the token, then the request's own credentials", got)
+	}
+
+	writeUserConfig(t, `[hosts."`+host+`"]
+token_file = "/nonexistent/token"
+`)
+	if _, err := NewHostTransport(http.DefaultTransport); err == nil {
+		t.Error("NewHostTransport(): expected error for a missing token_file")
+	}
+	writeUserConfig(t, `[hosts."`+host+`"]`)
+	if _, err := NewHostTransport(http.DefaultTransport); err == nil {
+		t.Error("NewHostTransport(): expected error for a host without a token source")
+	}
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
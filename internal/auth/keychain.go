@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/ui"
+)
+
+// ErrNoStoredToken is returned when the system keychain holds no cops token.
+var ErrNoStoredToken = errors.New("no GitHub token stored in the system keychain")
+
+// keychainService and keychainAccount identify the cops entry in the
+// system keychain.
+const (
+	keychainService = "cops"
+	keychainAccount = githubHost
+)
+
+// keychainCommandTimeout bounds keychain helper commands, which may wait
+// for the user to unlock the keychain.
+const keychainCommandTimeout = 30 * time.Second
+
+// Keychain stores the GitHub token set with `cops login`.
+type Keychain interface {
+	// Get returns the stored token, or ErrNoStoredToken.
+	Get() (string, error)
+	// Set stores token, replacing any previous one.
+	Set(token string) error
+	// Delete removes the stored token, or returns ErrNoStoredToken.
+	Delete() error
+}
+
+// SystemKeychain returns the current platform's credential store: the macOS
+// Keychain, the Windows Credential Manager, or the Secret Service (through
+// secret-tool) elsewhere.
+func SystemKeychain() Keychain {
+	return systemKeychain{}
+}
+
+// keychain is the store Token consults; tests replace it.
+var keychain = SystemKeychain()
+
+// refreshFlow is the device flow used to renew expired keychain tokens;
+// tests replace it.
+var refreshFlow = func() *DeviceFlow { return NewDeviceFlow(OAuthClientID()) }
+
+// keychainToken returns the token stored by `cops login`, or "" if there is
+// none or the keychain is unavailable. An expired device-flow token is
+// refreshed and the new credential stored in its place.
+func keychainToken() string {
+	stored, err := keychain.Get()
+	if err != nil {
+		return ""
+	}
+	cred := decodeCredential(stored)
+	flow := refreshFlow()
+	if !cred.expired(flow.Now()) {
+		return cred.AccessToken
+	}
+	cred, err = flow.Refresh(cred)
+	if err != nil {
+		fmt.Fprint(os.Stderr, ui.Text(fmt.Sprintf("⚠️  Stored GitHub token expired: %v\n", err)))
+		return ""
+	}
+	if err := keychain.Set(cred.Encode()); err != nil {
+		fmt.Fprint(os.Stderr, ui.Text(fmt.Sprintf("⚠️  Could not store refreshed GitHub token: %v\n", err)))
+	}
+	return cred.AccessToken
+}
+
+// runKeychainCommand runs a keychain helper, feeding it stdin, and returns
+// its trimmed standard output along with the exit code (-1 if it did not
+// run to completion).
+func runKeychainCommand(stdin string, name string, args ...string) (string, int, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", -1, fmt.Errorf("system keychain unavailable: %s not found in PATH", name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keychainCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", exitErr.ExitCode(), fmt.Errorf("%s: %w — %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return "", -1, fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimSpace(string(out)), 0, nil
+}
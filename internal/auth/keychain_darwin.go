@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// securityItemNotFound is the exit code of `security` when no matching
+// keychain item exists.
+const securityItemNotFound = 44
+
+// systemKeychain stores the token in the macOS login keychain through the
+// security command.
+type systemKeychain struct{}
+
+func (systemKeychain) Get() (string, error) {
+	out, code, err := runKeychainCommand("", "security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	if code == securityItemNotFound {
+		return "", ErrNoStoredToken
+	}
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// Set passes the token through security's interactive mode, hex-encoded,
+// so that it never appears in a process listing.
+func (systemKeychain) Set(token string) error {
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", keychainService, keychainAccount, hex.EncodeToString([]byte(token)))
+	_, _, err := runKeychainCommand(cmd, "security", "-i")
+	return err
+}
+
+func (systemKeychain) Delete() error {
+	_, code, err := runKeychainCommand("", "security", "delete-generic-password", "-s", keychainService, "-a", keychainAccount)
+	if code == securityItemNotFound {
+		return ErrNoStoredToken
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// memKeychain is an in-memory Keychain.
+type memKeychain struct{ token string }
+
+func (k *memKeychain) Get() (string, error) {
+	if k.token == "" {
+		return "", ErrNoStoredToken
+	}
+	return k.token, nil
+}
+
+func (k *memKeychain) Set(token string) error {
+	k.token = token
+	return nil
+}
+
+func (k *memKeychain) Delete() error {
+	if k.token == "" {
+		return ErrNoStoredToken
+	}
+	k.token = ""
+	return nil
+}
+
+// TestMain keeps the developer's real keychain out of every test.
+func TestMain(m *testing.M) {
+	keychain = &memKeychain{}
+	os.Exit(m.Run())
+}
+
+// useKeychain installs a keychain holding token for the duration of t.
+func useKeychain(t *testing.T, token string) {
+	t.Helper()
+	prev := keychain
+	keychain = &memKeychain{token: token}
+	t.Cleanup(func() { keychain = prev })
+}
+
+func TestToken_KeychainBeforeEnv(t *testing.T) {
+	useKeychain(t, "stored-token")
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	tok, err := Token()
+	if err != nil {
+		t.Fatalf("Token(): unexpected error: %v", err)
+	}
+	if tok != "stored-token" {
+		t.Errorf("Token(): got %q, want keychain token", tok)
+	}
+}
+
+func TestToken_EmptyKeychainFallsBackToEnv(t *testing.T) {
+	useKeychain(t, "")
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	tok, err := Token()
+	if err != nil || tok != "env-token" {
+		t.Errorf("Token() = %q, %v; want env token", tok, err)
+	}
+}
+
+func TestRunKeychainCommand_Missing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, code, err := runKeychainCommand("", "secret-tool", "lookup")
+	if err == nil || code != -1 {
+		t.Errorf("runKeychainCommand() = %d, %v; want not-found error", code, err)
+	}
+	if errors.Is(err, ErrNoStoredToken) {
+		t.Error("a missing helper must not look like an empty keychain")
+	}
+}
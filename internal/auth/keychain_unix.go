@@ -0,0 +1,35 @@
+//go:build !darwin && !windows
+
+package auth
+
+// systemKeychain stores the token with the freedesktop Secret Service
+// (GNOME Keyring, KWallet) through libsecret's secret-tool.
+type systemKeychain struct{}
+
+func (systemKeychain) Get() (string, error) {
+	out, code, err := runKeychainCommand("", "secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	// secret-tool exits 1 without output when no item matches.
+	if code == 1 || (err == nil && out == "") {
+		return "", ErrNoStoredToken
+	}
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// Set passes the token on standard input so that it never appears in a
+// process listing.
+func (systemKeychain) Set(token string) error {
+	_, _, err := runKeychainCommand(token, "secret-tool", "store", "--label=cops GitHub token", "service", keychainService, "account", keychainAccount)
+	return err
+}
+
+func (k systemKeychain) Delete() error {
+	// secret-tool clear succeeds whether or not an item matched.
+	if _, err := k.Get(); err != nil {
+		return err
+	}
+	_, _, err := runKeychainCommand("", "secret-tool", "clear", "service", keychainService, "account", keychainAccount)
+	return err
+}
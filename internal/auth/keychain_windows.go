@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential mirrors the Win32 CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// systemKeychain stores the token as a generic credential in the Windows
+// Credential Manager.
+type systemKeychain struct{}
+
+// credentialTarget is the Credential Manager entry name, e.g. "cops:github.com".
+func credentialTarget() (*uint16, error) {
+	return syscall.UTF16PtrFromString(keychainService + ":" + keychainAccount)
+}
+
+func (systemKeychain) Get() (string, error) {
+	target, err := credentialTarget()
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	r, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(callErr, errorNotFound) {
+			return "", ErrNoStoredToken
+		}
+		return "", fmt.Errorf("reading Windows credential: %w", callErr)
+	}
+	defer func() { _, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred))) }()
+	if cred.CredentialBlobSize == 0 {
+		return "", ErrNoStoredToken
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (systemKeychain) Set(token string) error {
+	target, err := credentialTarget()
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(keychainAccount)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("refusing to store an empty token")
+	}
+	blob := []byte(token)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	r, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return fmt.Errorf("writing Windows credential: %w", callErr)
+	}
+	return nil
+}
+
+func (systemKeychain) Delete() error {
+	target, err := credentialTarget()
+	if err != nil {
+		return err
+	}
+	r, _, callErr := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 {
+		if errors.Is(callErr, errorNotFound) {
+			return ErrNoStoredToken
+		}
+		return fmt.Errorf("deleting Windows credential: %w", callErr)
+	}
+	return nil
+}
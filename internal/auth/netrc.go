@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NetrcCredentials returns the login and password for host from the user's
+// netrc file ($NETRC, or ~/.netrc — ~/_netrc on Windows), the same file curl
+// and git read. A "default" entry matches any host without its own entry.
+func NetrcCredentials(host string) (login, password string, ok bool) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		name := ".netrc"
+		if runtime.GOOS == "windows" {
+			name = "_netrc"
+		}
+		path = filepath.Join(home, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	return parseNetrc(string(data), host)
+}
+
+// parseNetrc looks up host in netrc-formatted data.
+func parseNetrc(data, host string) (login, password string, ok bool) {
+	type entry struct{ login, password string }
+	var found, fallback, current *entry
+
+	fields := strings.Fields(data)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 >= len(fields) {
+				break
+			}
+			i++
+			current = &entry{}
+			if fields[i] == host && found == nil {
+				found = current
+			}
+		case "default":
+			current = &entry{}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login", "password", "account":
+			if i+1 >= len(fields) || current == nil {
+				break
+			}
+			i++
+			if fields[i-1] == "login" {
+				current.login = fields[i]
+			} else if fields[i-1] == "password" {
+				current.password = fields[i]
+			}
+		case "macdef":
+			// Macro bodies are free text; stop rather than misread them.
+			// Credentials come before macros in practice.
+			i = len(fields)
+		}
+	}
+
+	if found == nil {
+		found = fallback
+	}
+	if found == nil {
+		return "", "", false
+	}
+	return found.login, found.password, true
+}
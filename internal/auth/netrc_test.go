@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	t.Parallel()
+	data := `machine artifacts.corp.example
+  login ci-bot
+  password s3cret
+
+machine other.example login a password b
+default login anon password guest
+`
+	cases := []struct {
+		host            string
+		login, password string
+		ok              bool
+	}{
+		{"artifacts.corp.example", "ci-bot", "s3cret", true},
+		{"other.example", "a", "b", true},
+		{"unknown.example", "anon", "guest", true},
+	}
+	for _, tc := range cases {
+		login, password, ok := parseNetrc(data, tc.host)
+		if login != tc.login || password != tc.password || ok != tc.ok {
+			t.Errorf("parseNetrc(%q) = %q, %q, %v; want %q, %q, %v", tc.host, login, password, ok, tc.login, tc.password, tc.ok)
+		}
+	}
+
+	if _, _, ok := parseNetrc("machine a login x password y", "b"); ok {
+		t.Error("parseNetrc: expected no match without a default entry")
+	}
+}
+
+func TestNetrcCredentials_EnvPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(path, []byte("machine mirror.example login u password p\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", path)
+
+	login, password, ok := NetrcCredentials("mirror.example")
+	if !ok || login != "u" || password != "p" {
+		t.Errorf("NetrcCredentials() = %q, %q, %v", login, password, ok)
+	}
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TokenCommandEnvVar names the environment variable holding a command whose
+// standard output is the GitHub token, e.g. "vault kv get -field=token secret/gh".
+const TokenCommandEnvVar = "COPS_TOKEN_COMMAND"
+
+// tokenCommandTimeout bounds COPS_TOKEN_COMMAND, which may prompt for a
+// password or hardware key touch.
+const tokenCommandTimeout = time.Minute
+
+// userConfig is the subset of the user configuration file read by auth.
+type userConfig struct {
+	// TokenFile is a file holding the GitHub token. A leading "~/" is
+	// expanded to the home directory.
+	TokenFile string `toml:"token_file"`
+
+	// Proxy is the URL of the proxy every request goes through, instead
+	// of the one the environment sets.
+	Proxy string `toml:"proxy"`
+
+	// CACerts lists PEM bundles of CA certificates trusted on top of the
+	// system roots. A leading "~/" is expanded to the home directory.
+	CACerts []string `toml:"ca_certs"`
+
+	// RequestTimeout bounds every HTTP request, body and retries
+	// included, as a Go duration such as "90s" or "5m".
+	RequestTimeout string `toml:"request_timeout"`
+
+	// MaxConnections caps the connections open to a single host.
+	MaxConnections int `toml:"max_connections"`
+
+	// Hosts holds the credentials sent to hosts other than GitHub, such
+	// as an internal artifact server, keyed by host name.
+	Hosts map[string]hostConfig `toml:"hosts"`
+}
+
+// hostConfig is a [hosts."<host>"] table of the user configuration. The
+// token comes from TokenFile, if set, or from the TokenEnv variable.
+type hostConfig struct {
+	TokenFile string `toml:"token_file"` // a leading "~/" is expanded
+	TokenEnv  string `toml:"token_env"`
+}
+
+// UserConfigPath returns the path of the user configuration file,
+// e.g. ~/.config/cops/config.toml on Linux.
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cops", "config.toml"), nil
+}
+
+// loadUserConfig reads the user configuration file. A missing file yields
+// an empty configuration.
+func loadUserConfig() (userConfig, error) {
+	var cfg userConfig
+	path, err := UserConfigPath()
+	if err != nil {
+		return cfg, nil
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// commandToken runs the COPS_TOKEN_COMMAND helper through the shell and
+// returns its trimmed output. It returns "" with no error when the
+// variable is unset.
+func commandToken() (string, error) {
+	command := os.Getenv(TokenCommandEnvVar)
+	if command == "" {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Let the helper prompt on the terminal if it needs to. Any other
+	// stdin, such as the JSON-RPC stream of `cops mcp-serve`, is not the
+	// helper's to read.
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		cmd.Stdin = os.Stdin
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w — %s", TokenCommandEnvVar, err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("%s printed no token", TokenCommandEnvVar)
+	}
+	return token, nil
+}
+
+// fileToken reads the token from the token_file set in the user
+// configuration. It returns "" with no error when none is configured.
+func fileToken() (string, error) {
+	cfg, err := loadUserConfig()
+	if err != nil || cfg.TokenFile == "" {
+		return "", err
+	}
+	path, err := ExpandHome(cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("token_file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("token_file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token_file %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestToken_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	isolateCredentials(t)
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+"/bin:/usr/bin")
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	t.Setenv(TokenCommandEnvVar, "echo '  from-command  '")
+
+	tok, err := Token()
+	if err != nil || tok != "from-command" {
+		t.Errorf("Token() = %q, %v; want command output", tok, err)
+	}
+}
+
+func TestToken_CommandLeavesStdinAlone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	isolateCredentials(t)
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+"/bin:/usr/bin")
+	t.Setenv(TokenCommandEnvVar, "cat; echo from-command")
+
+	// Stdin is a pipe, as under `cops mcp-serve`.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+	if _, err := w.WriteString("{\"jsonrpc\":\"2.0\"}\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	prev := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = prev })
+
+	tok, err := Token()
+	if err != nil || tok != "from-command" {
+		t.Errorf("Token() = %q, %v; want only the command output", tok, err)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "{\"jsonrpc\":\"2.0\"}\n" {
+		t.Errorf("stdin left = %q, want it unread", rest)
+	}
+}
+
+func TestToken_CommandAndFileBeforeKeychain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	isolateCredentials(t)
+	useKeychain(t, "stored-token")
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+"/bin:/usr/bin")
+
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "gh-token"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeUserConfig(t, `token_file = "~/gh-token"`)
+	if tok, err := Token(); err != nil || tok != "from-file" {
+		t.Errorf("Token() = %q, %v; want file content", tok, err)
+	}
+
+	t.Setenv(TokenCommandEnvVar, "echo from-command")
+	if tok, err := Token(); err != nil || tok != "from-command" {
+		t.Errorf("Token() = %q, %v; want command output", tok, err)
+	}
+}
+
+func TestToken_CommandFailureIsAnError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	isolateCredentials(t)
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+"/bin:/usr/bin")
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	for _, command := range []string{"exit 3", "true"} {
+		t.Setenv(TokenCommandEnvVar, command)
+		if _, err := Token(); err == nil || errors.Is(err, ErrNoToken) {
+			t.Errorf("%q: Token() err = %v, want a command error", command, err)
+		}
+	}
+	if _, err := NewHTTPClient(); err == nil {
+		t.Error("NewHTTPClient(): expected the command error, got a plain client")
+	}
+}
+
+func TestToken_File(t *testing.T) {
+	isolateCredentials(t)
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "gh-token"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeUserConfig(t, `token_file = "~/gh-token"`)
+
+	tok, err := Token()
+	if err != nil || tok != "from-file" {
+		t.Errorf("Token() = %q, %v; want file content", tok, err)
+	}
+}
+
+func TestToken_MissingFileIsAnError(t *testing.T) {
+	isolateCredentials(t)
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	writeUserConfig(t, `token_file = "/nonexistent/token"`)
+
+	if _, err := Token(); err == nil {
+		t.Error("Token(): expected error for a missing token_file")
+	}
+}
+
+func writeUserConfig(t *testing.T, content string) {
+	t.Helper()
+	path, err := UserConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Network defaults, used when the user configuration does not set them.
+const (
+	DefaultRequestTimeout = 2 * time.Minute
+	DefaultMaxConnections = 8
+)
+
+// CACertsEnvVar names the environment variable listing extra CA bundles to
+// trust, separated like PATH entries.
+const CACertsEnvVar = "COPS_CA_CERTS"
+
+// NewTransport returns the transport every client of cops is built on:
+// http.DefaultTransport with the proxy, CA bundles and connection limit
+// per host of the user configuration. Without a proxy setting,
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY (or their lowercase forms) apply.
+// The CA bundles of ca_certs and $COPS_CA_CERTS are trusted on top of the
+// system roots, for networks behind a TLS-intercepting proxy.
+func NewTransport() (*http.Transport, error) {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch {
+	case cfg.MaxConnections < 0:
+		return nil, fmt.Errorf("max_connections: must be positive, got %d", cfg.MaxConnections)
+	case cfg.MaxConnections > 0:
+		transport.MaxConnsPerHost = cfg.MaxConnections
+	default:
+		transport.MaxConnsPerHost = DefaultMaxConnections
+	}
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("proxy: invalid URL %q", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	bundles := cfg.CACerts
+	if env := os.Getenv(CACertsEnvVar); env != "" {
+		bundles = append(bundles, filepath.SplitList(env)...)
+	}
+	if len(bundles) > 0 {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		for _, bundle := range bundles {
+			path, err := ExpandHome(bundle)
+			if err != nil {
+				return nil, fmt.Errorf("ca_certs: %w", err)
+			}
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("ca_certs: %w", err)
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca_certs: no PEM certificate found in %s", path)
+			}
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+	return transport, nil
+}
+
+// RequestTimeout returns how long a single HTTP request may take: the
+// request_timeout of the user configuration, or DefaultRequestTimeout.
+func RequestTimeout() (time.Duration, error) {
+	cfg, err := loadUserConfig()
+	if err != nil || cfg.RequestTimeout == "" {
+		return DefaultRequestTimeout, err
+	}
+	timeout, err := time.ParseDuration(cfg.RequestTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("request_timeout: invalid duration %q (e.g. \"90s\" or \"5m\")", cfg.RequestTimeout)
+	}
+	return timeout, nil
+}
+
+// ExpandHome expands a leading "~/" in path to the home directory.
+func ExpandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
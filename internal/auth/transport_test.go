@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewTransport_CACerts(t *testing.T) {
+	isolateCredentials(t)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	get := func() error {
+		t.Helper()
+		transport, err := NewTransport()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(ts.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(); err == nil {
+		t.Fatal("request to an untrusted server succeeded")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "corp-ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(bundle, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeUserConfig(t, `ca_certs = ["`+filepath.ToSlash(bundle)+`"]`)
+	if err := get(); err != nil {
+		t.Errorf("request with ca_certs: %v", err)
+	}
+
+	writeUserConfig(t, "")
+	t.Setenv(CACertsEnvVar, bundle)
+	if err := get(); err != nil {
+		t.Errorf("request with %s: %v", CACertsEnvVar, err)
+	}
+
+	t.Setenv(CACertsEnvVar, filepath.Join(t.TempDir(), "missing.pem"))
+	if _, err := NewTransport(); err == nil {
+		t.Error("NewTransport(): expected error for a missing bundle")
+	}
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	isolateCredentials(t)
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	writeUserConfig(t, `proxy = "`+proxy.URL+`"`)
+	transport, err := NewTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://assets.example.invalid/go.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://assets.example.invalid/go.md" {
+		t.Errorf("proxy saw %q, want the absolute URL", proxied)
+	}
+
+	writeUserConfig(t, `proxy = "not a url"`)
+	if _, err := NewTransport(); err == nil {
+		t.Error("NewTransport(): expected error for an invalid proxy")
+	}
+}
+
+func TestNetworkSettings(t *testing.T) {
+	isolateCredentials(t)
+	transport, err := NewTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, err := RequestTimeout()
+	if err != nil || timeout != DefaultRequestTimeout || transport.MaxConnsPerHost != DefaultMaxConnections {
+		t.Errorf("defaults = %v, %d, %v", timeout, transport.MaxConnsPerHost, err)
+	}
+
+	writeUserConfig(t, "request_timeout = \"90s\"\nmax_connections = 2")
+	if transport, err = NewTransport(); err != nil {
+		t.Fatal(err)
+	}
+	timeout, err = RequestTimeout()
+	if err != nil || timeout != 90*time.Second || transport.MaxConnsPerHost != 2 {
+		t.Errorf("configured = %v, %d, %v; want 1m30s, 2", timeout, transport.MaxConnsPerHost, err)
+	}
+
+	writeUserConfig(t, "request_timeout = \"soon\"\nmax_connections = -1")
+	if _, err := RequestTimeout(); err == nil {
+		t.Error("RequestTimeout(): expected error for an invalid duration")
+	}
+	if _, err := NewTransport(); err == nil {
+		t.Error("NewTransport(): expected error for negative max_connections")
+	}
+}
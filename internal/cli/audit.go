@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/audit"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// auditOptions holds the flags accepted by the audit command.
+type auditOptions struct {
+	Strict bool           // exit with an error on findings at FailOn or above
+	FailOn audit.Severity // lowest severity failing a strict audit
+	Min    audit.Severity // lowest severity reported
+}
+
+// newAuditCmd creates the `audit` command.
+// Usage: cops audit [--strict] [--fail-on <severity>] [--min-severity <severity>]
+func newAuditCmd() *cobra.Command {
+	var opts auditOptions
+	var failOn, minSeverity string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Scan the managed assets for risky content",
+		Long: `Scans every asset recorded in .cops.lock for content that could turn an
+agent against the project: prompt-injection phrases, invisible text,
+requests to send secrets away, risky shell commands, leaked credentials,
+and the URLs and domains assets point at.
+
+Each finding has a severity: high for hallmarks of an attack, medium for
+content to review, low for links to know about. With --strict, the command
+exits with a non-zero code if any finding is at or above --fail-on.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if opts.FailOn, err = audit.ParseSeverity(failOn); err != nil {
+				return err
+			}
+			if opts.Min, err = audit.ParseSeverity(minSeverity); err != nil {
+				return err
+			}
+			return runAuditWith(opts, lockFile(), ".")
+		},
+	}
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Exit with error code if findings reach the --fail-on severity")
+	cmd.Flags().StringVar(&failOn, "fail-on", "medium", "Lowest severity failing a strict audit: low, medium or high")
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "low", "Lowest severity reported: low, medium or high")
+	return cmd
+}
+
+// runAuditWith is the testable core of the audit command.
+func runAuditWith(opts auditOptions, lockPath, rootDir string) error {
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	if len(lock.Entries) == 0 {
+		printf("📋 No entries in %s — nothing to audit.\n", lockPath)
+		return nil
+	}
+
+	printf("🔍 Auditing %d asset(s) from %s...\n\n", len(lock.Entries), lockPath)
+
+	counts := make(map[audit.Severity]int)
+	unreadable := 0
+	for _, key := range manifest.SortedKeys(lock.Entries) {
+		e := lock.Entries[key]
+		files, err := auditAsset(filepath.Join(rootDir, e.TargetPath))
+		if err != nil {
+			printf("  ❌ %s — %v\n", key, err)
+			unreadable++
+			continue
+		}
+		reported := 0
+		for _, path := range manifest.SortedKeys(files) {
+			for _, f := range files[path] {
+				if f.Severity < opts.Min {
+					continue
+				}
+				if reported == 0 {
+					printf("  ⚠️  %s\n", key)
+				}
+				rel, _ := filepath.Rel(rootDir, path)
+				printf("     %-6s %s:%d  %s — %q %s\n", f.Severity, filepath.ToSlash(rel), f.Line, f.Rule, f.Match, f.Message)
+				counts[f.Severity]++
+				reported++
+			}
+		}
+		if reported == 0 {
+			printf("  ✅ %s — no findings\n", key)
+		}
+	}
+
+	printf("\n📊 %d high, %d medium, %d low\n", counts[audit.High], counts[audit.Medium], counts[audit.Low])
+	if unreadable > 0 {
+		return fmt.Errorf("%d asset(s) could not be audited. Run 'cops sync' to restore them", unreadable)
+	}
+	if opts.Strict {
+		failing := 0
+		for severity, n := range counts {
+			if severity >= opts.FailOn {
+				failing += n
+			}
+		}
+		if failing > 0 {
+			return fmt.Errorf("%d finding(s) at or above %s severity", failing, opts.FailOn)
+		}
+	}
+	return nil
+}
+
+// auditAsset scans the file or directory at target, returning the
+// findings of each file that has some.
+func auditAsset(target string) (map[string][]audit.Finding, error) {
+	files := make(map[string][]audit.Finding)
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if findings := audit.Scan(data); len(findings) > 0 {
+			files[path] = findings
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read asset: %w", err)
+	}
+	return files, nil
+}
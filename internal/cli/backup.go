@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Backup modes accepted by sync --backup and COPS_BACKUP.
+const (
+	backupDir  = "dir"  // copy to .cops-backup/<timestamp>/<path>
+	backupOrig = "orig" // copy to <path>.orig
+)
+
+// backupEnvVar sets the default backup mode of sync.
+const backupEnvVar = "COPS_BACKUP"
+
+// backupFolder is the folder, relative to the project root, that the dir
+// mode copies files into.
+const backupFolder = ".cops-backup"
+
+// backupMode returns the backup mode to use: the --backup flag value if
+// given, COPS_BACKUP otherwise.
+func backupMode(flag string) (string, error) {
+	mode := flag
+	if mode == "" {
+		mode = os.Getenv(backupEnvVar)
+	}
+	switch mode {
+	case "", backupDir, backupOrig:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid backup mode %q: use %q or %q", mode, backupDir, backupOrig)
+}
+
+// backup copies files edited by hand before sync overwrites or deletes
+// them. The zero value copies nothing.
+type backup struct {
+	mode    string // "", backupDir or backupOrig
+	rootDir string // project root
+	stamp   string // timestamp of the sync, naming its backupDir folder
+}
+
+// newBackup returns the backup of one sync run under rootDir.
+func newBackup(mode, rootDir string) backup {
+	return backup{mode: mode, rootDir: rootDir, stamp: time.Now().UTC().Format("20060102T150405Z")}
+}
+
+// save copies each file of paths, relative to the project root, and
+// returns where the copies were written, relative to the project root.
+func (b backup) save(paths []string) ([]string, error) {
+	if b.mode == "" {
+		return nil, nil
+	}
+	var saved []string
+	for _, rel := range paths {
+		dest := rel + ".orig"
+		if b.mode == backupDir {
+			dest = filepath.Join(backupFolder, b.stamp, rel)
+		}
+		if err := copyFile(filepath.Join(b.rootDir, rel), filepath.Join(b.rootDir, dest)); err != nil {
+			return saved, fmt.Errorf("backing up %s: %w", rel, err)
+		}
+		saved = append(saved, dest)
+	}
+	return saved, nil
+}
+
+// copyFile copies src to dst with the same permission bits, creating the
+// parent directories of dst.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
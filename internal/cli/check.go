@@ -1,160 +1,556 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
 	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/ui"
 )
 
+// checkOptions holds the flags accepted by the check command.
+type checkOptions struct {
+	Strict        bool     // exit with an error when issues are found
+	RequirePinned bool     // report entries tracking a branch without an exception
+	Groups        []string // only check entries tagged with one of these groups
+	Entries       []string // only check these "<type>/<name>" entries, if set
+	Env           string   // manifest overlay to apply (copilot.<env>.toml)
+	Workspace     bool     // check every member listed in cops-workspace.toml
+	Report        string   // file to write the results to as JSON
+
+	// Frozen checks manifest, lock and disk against each other without any
+	// network access, and implies Strict.
+	Frozen bool
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// Fetch downloads the template named by an extends directive.
+	Fetch manifest.Fetcher
+
+	// Updates, when set, is used to prefetch the latest SHAs of floating refs
+	// in the background and print "update available" hints. Nil disables it.
+	Updates resolver.ResolverAPI
+}
+
 // newCheckCmd creates the `check` command.
-// Usage: cops check [--strict]
+// Usage: cops check [<type>/<name>]... [--strict] [--frozen] [--require-pinned] [--group <name>]... [--env <env>] [--workspace] [--no-global] [--report <file>]
 func newCheckCmd() *cobra.Command {
-	var strict bool
+	var opts checkOptions
+	var updates, noGlobal bool
 
 	cmd := &cobra.Command{
-		Use:   "check",
+		Use:   "check [<type>/<name>]...",
 		Short: "Check if local assets are in sync with copilot.toml",
 		Long: `Validates that all entries in copilot.toml have corresponding local files
 and that they match the lock file checksums. Useful in CI/CD pipelines.
+With arguments, only the given entries are checked, e.g.
+'cops check prompts/review'.
 
 With --strict, the command exits with a non-zero code if any asset is
-missing or stale.`,
-		Args: cobra.NoArgs,
+missing or stale.
+
+With --frozen, nothing is downloaded or resolved: manifests extending a
+remote template are rejected and --updates is not allowed. Entries of the
+lock file missing from copilot.toml, and entries locked to an unverified
+commit by 'cops lock rebuild', are reported too. --frozen implies --strict,
+for pre-commit hooks and air-gapped CI.
+
+With --require-pinned, entries tracking a branch (or @latest) instead of a
+tag or commit SHA are reported, unless they carry an allow_branch_until
+exception. Expired exceptions are always reported; exceptions expiring
+soon produce a warning.
+
+With --updates, the latest commits of floating refs are looked up in the
+background while local checks run, and entries with newer commits are
+annotated with an "update available" hint. Lookups that have not finished
+shortly after the local checks are dropped.
+
+With --group, only entries tagged with one of the given groups are checked.
+With --env (or COPS_ENV), the copilot.<env>.toml overlay is applied first.
+
+With --workspace, every subproject listed in cops-workspace.toml is checked
+and failures are summarised at the end.
+
+Entries of the user-level manifest are checked too, unless --no-global is
+given.
+
+With --report, the result of every entry is also written to a file as
+JSON: its status (ok, never_synced, missing, unlocked, ref_changed,
+modified, targets_changed, unverified or orphaned), refs, path and sync
+time, for dashboards and later comparison. The report is written even
+when --strict fails.`,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return resolveEntryIDs(args, toComplete)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCheck(strict)
+			if len(args) > 0 && opts.Workspace {
+				return fmt.Errorf("entries cannot be given with --workspace")
+			}
+			if len(args) > 0 {
+				opts.Entries = args
+			}
+			opts.GlobalManifest = globalManifest(noGlobal)
+			opts.Fetch = lazyFetchTemplate(cmd.Context())
+			opts.Report = userPath(opts.Report)
+			if opts.Report != "" && opts.Workspace {
+				return fmt.Errorf("--report cannot be used with --workspace")
+			}
+			if updates && opts.Frozen {
+				return fmt.Errorf("--updates needs network access and cannot be used with --frozen")
+			}
+			if updates {
+				res, err := newResolver(cmd.Context())
+				if err != nil {
+					return err
+				}
+				opts.Updates = res
+			}
+			return runCheck(opts)
 		},
 	}
 
-	cmd.Flags().BoolVar(&strict, "strict", false, "Exit with error code if assets are stale or missing")
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Exit with error code if assets are stale or missing")
+	cmd.Flags().BoolVar(&opts.Frozen, "frozen", false, "Check manifest, lock and disk consistency without network access (implies --strict)")
+	cmd.Flags().BoolVar(&opts.RequirePinned, "require-pinned", false, "Report entries that track a branch without an allow_branch_until exception")
+	cmd.Flags().StringSliceVar(&opts.Groups, "group", nil, "Only check entries in this group (repeatable)")
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&opts.Workspace, "workspace", false, "Check every member of cops-workspace.toml")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+	cmd.Flags().BoolVar(&updates, "updates", false, "Look up newer commits for floating refs in the background and show hints")
+	cmd.Flags().StringVar(&opts.Report, "report", "", "Write the result of every entry to this file as JSON")
 
 	return cmd
 }
 
-func runCheck(strict bool) error {
-	return runCheckWith(strict, manifest.DefaultManifestFile, manifest.DefaultLockFile, ".")
+func runCheck(opts checkOptions) error {
+	opts.Env = manifestEnv(opts.Env)
+	if opts.Workspace {
+		return runWorkspaceWith(manifest.FindWorkspaceRoot("."), func(dir string) error {
+			manifestPath, lockPath := memberPaths(dir)
+			return runCheckWith(opts, manifestPath, lockPath, dir)
+		})
+	}
+	return runCheckWith(opts, manifestFile(), lockFile(), ".")
 }
 
 // runCheckWith is the testable core of the check command.
-func runCheckWith(strict bool, manifestPath, lockPath, rootDir string) error {
-	m, err := manifest.Load(manifestPath)
+func runCheckWith(opts checkOptions, manifestPath, lockPath, rootDir string) error {
+	if opts.Frozen {
+		opts.Fetch, opts.Updates = frozenFetch, nil
+		opts.Strict = true
+	}
+
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      opts.Fetch,
+	})
 	if err != nil {
 		return fmt.Errorf("loading manifest: %w", err)
 	}
 
-	entries := m.AllEntries()
-	if len(entries) == 0 {
-		fmt.Println("📋 No entries in copilot.toml — nothing to check.")
-		return nil
+	entries, err := m.EntriesInGroups(opts.Groups)
+	if err != nil {
+		return err
 	}
-
 	lock, err := manifest.LoadLock(lockPath)
 	if err != nil {
 		return fmt.Errorf("loading lock file: %w", err)
 	}
+	// Collections are checked against the members they were last synced
+	// with; those not synced yet are reported on their own.
+	var unsynced []string
+	ids := opts.Entries
+	if len(m.Collections) > 0 && len(opts.Groups) == 0 {
+		members := m.LockedMembers(lock)
+		entries, _ = manifest.MergeMembers(entries, members)
+		for _, name := range manifest.SortedKeys(m.Collections) {
+			id := manifest.CollectionsSection + "/" + name
+			if slices.ContainsFunc(members, func(e manifest.Entry) bool { return e.Collection == name }) {
+				continue
+			}
+			if ids == nil || slices.Contains(ids, id) {
+				unsynced = append(unsynced, name)
+			}
+			if ids != nil {
+				ids = slices.DeleteFunc(slices.Clone(ids), func(s string) bool { return s == id })
+			}
+		}
+	}
+	if entries, err = selectEntries(entries, ids); err != nil {
+		return err
+	}
+	now := time.Now()
+	report := checkReport{Manifest: manifestPath}
+	if len(entries) == 0 && len(unsynced) == 0 {
+		printf("📋 No entries in copilot.toml — nothing to check.\n")
+		if opts.Report != "" {
+			return writeCheckReport(opts.Report, report, now)
+		}
+		return nil
+	}
 
-	fmt.Printf("🔍 Checking %d asset(s)...\n\n", len(entries))
+	var prefetch *updatePrefetcher
+	if opts.Updates != nil {
+		prefetch = startUpdatePrefetch(opts.Updates, entries, lock)
+	}
+
+	printf("🔍 Checking %d asset(s)...\n\n", len(entries)+len(unsynced))
 
 	var issues int
+	for _, name := range unsynced {
+		printf("  ❌ %s/%s — missing (never synced)\n", manifest.CollectionsSection, name)
+		issues++
+		report.Assets = append(report.Assets, checkResult{
+			Type:   manifest.CollectionsSection,
+			Name:   name,
+			Status: checkNeverSynced,
+			Detail: "missing (never synced)",
+			Ref:    m.Collections[name],
+		})
+	}
 
 	for _, entry := range entries {
-		assetType := config.AssetType(entry.Type)
-		targetPath := filepath.Join(rootDir, assetType.TargetPath(entry.Name))
-
-		_, statErr := os.Stat(targetPath)
-		fileExists := statErr == nil
-
-		lockEntry, locked := lock.Get(entry.Type, entry.Name)
-
-		switch {
-		case !fileExists && !locked:
-			fmt.Printf("  ❌ %s/%s — missing (never synced)\n", entry.Type, entry.Name)
-			issues++
-		case !fileExists && locked:
-			fmt.Printf("  ❌ %s/%s — missing (was synced at %s)\n", entry.Type, entry.Name, lockEntry.SyncedAt)
+		result := checkEntry(m, entry, lock, rootDir, opts.Frozen)
+		printCheckResult(result)
+		if result.Status != checkOK {
 			issues++
-		case fileExists && !locked:
-			fmt.Printf("  ⚠️  %s/%s — file exists but not in lock file (run 'cops sync')\n", entry.Type, entry.Name)
-			issues++
-		case fileExists && locked && lockEntry.Ref != entry.Ref:
-			fmt.Printf("  ⚠️  %s/%s — ref changed: lock=%s manifest=%s\n", entry.Type, entry.Name, lockEntry.Ref, entry.Ref)
+		}
+
+		var violates bool
+		if result.Policy, violates = checkPinPolicy(entry, opts.RequirePinned, now); violates {
 			issues++
-		default:
-			// fileExists && locked && refs match — verify content integrity
-			cs, err := localChecksum(targetPath, assetType.IsDirectory())
-			if err != nil {
-				fmt.Printf("  ❌ %s/%s — error reading local file: %v\n", entry.Type, entry.Name, err)
-				issues++
-			} else if cs != lockEntry.Checksum {
-				fmt.Printf("  ❌ %s/%s — content modified (checksum mismatch)\n", entry.Type, entry.Name)
+		}
+		report.Assets = append(report.Assets, result)
+	}
+
+	// With every entry in scope, the lock must not know of others.
+	if opts.Frozen && len(opts.Groups) == 0 && opts.Entries == nil {
+		inManifest := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			inManifest[entry.Type+"/"+entry.Name] = true
+		}
+		for _, key := range manifest.SortedKeys(lock.Entries) {
+			if !inManifest[key] {
+				printf("  ⚠️  %s — in the lock file but not in copilot.toml (run 'cops sync' to prune it)\n", key)
 				issues++
-			} else {
-				fmt.Printf("  ✅ %s/%s — ok\n", entry.Type, entry.Name)
+				locked := lock.Entries[key]
+				report.Assets = append(report.Assets, checkResult{
+					Type:        locked.Type,
+					Name:        locked.Name,
+					Status:      checkOrphaned,
+					Detail:      "in the lock file but not in copilot.toml",
+					LockedRef:   locked.Ref,
+					ResolvedSHA: locked.ResolvedSHA,
+					Path:        locked.TargetPath,
+					SyncedAt:    locked.SyncedAt,
+				})
+			}
+		}
+	}
+
+	if prefetch != nil {
+		for _, h := range prefetch.collect(updateHintTimeout) {
+			printf("  ⬆️  %s/%s — update available (%s → %s)\n", h.Type, h.Name, shortSHA(h.LockedSHA), shortSHA(h.LatestSHA))
+			for i := range report.Assets {
+				if r := &report.Assets[i]; r.Type == h.Type && r.Name == h.Name {
+					r.LatestSHA = h.LatestSHA
+				}
 			}
 		}
 	}
 
 	fmt.Println()
 
+	if opts.Report != "" {
+		report.Issues = issues
+		if err := writeCheckReport(opts.Report, report, now); err != nil {
+			return err
+		}
+	}
+
 	if issues > 0 {
 		msg := fmt.Sprintf("Found %d issue(s). Run 'cops sync' to fix.", issues)
-		if strict {
+		if opts.Strict {
 			return fmt.Errorf("%s", msg)
 		}
-		fmt.Printf("⚠️  %s\n", msg)
+		printf("⚠️  %s\n", msg)
 	} else {
-		fmt.Println("✅ All assets are in sync.")
+		printf("✅ All assets are in sync.\n")
 	}
 
 	return nil
 }
 
+// checkEntry compares entry with its lock entry and its files under
+// rootDir. With frozen, entries locked to an unverified commit are
+// reported.
+func checkEntry(m *manifest.Manifest, entry manifest.Entry, lock *manifest.LockFile, rootDir string, frozen bool) checkResult {
+	assetType := config.AssetType(entry.Type)
+	targetPath := filepath.Join(rootDir, entry.TargetPath())
+	_, statErr := os.Stat(targetPath)
+	fileExists := statErr == nil
+	locked, isLocked := lock.Get(entry.Type, entry.Name)
+
+	result := newCheckResult(entry, locked)
+	switch {
+	case !fileExists && !isLocked:
+		result.Status, result.Detail = checkNeverSynced, "missing (never synced)"
+	case !fileExists && isLocked:
+		result.Status, result.Detail = checkMissing, fmt.Sprintf("missing (was synced at %s)", locked.SyncedAt)
+	case fileExists && !isLocked:
+		result.Status, result.Detail = checkUnlocked, "file exists but not in lock file"
+	case locked.Ref != entry.Ref:
+		result.Status, result.Detail = checkRefChanged, fmt.Sprintf("ref changed: lock=%s manifest=%s", locked.Ref, entry.Ref)
+	default:
+		// fileExists && locked && refs match — verify content integrity
+		copies, sections := m.Outputs(entry.Type, entry.Name)
+		if problem := verifyContent(locked, targetPath, assetType.IsDirectory()); problem != "" {
+			result.Status, result.Detail = checkModified, problem
+		} else if !slices.Equal(copies, locked.Copies) || !slices.Equal(sections, locked.Sections) {
+			result.Status, result.Detail = checkTargetsChanged, "targets changed"
+		} else if problem := verifyOutputs(locked, rootDir, assetType.IsDirectory()); problem != "" {
+			result.Status, result.Detail = checkModified, problem
+		} else if frozen && locked.ResolvedSHA == injector.UnknownSHA {
+			result.Status, result.Detail = checkUnverified, "locked to an unverified commit"
+		} else {
+			result.Status = checkOK
+		}
+	}
+	return result
+}
+
+// printCheckResult prints the line of check output of result.
+func printCheckResult(r checkResult) {
+	switch r.Status {
+	case checkOK:
+		printf("  ✅ %s/%s — ok\n", r.Type, r.Name)
+	case checkNeverSynced, checkMissing, checkModified:
+		printf("  ❌ %s/%s — %s\n", r.Type, r.Name, ui.Text(r.Detail))
+	case checkRefChanged:
+		printf("  ⚠️  %s/%s — %s\n", r.Type, r.Name, r.Detail)
+	default:
+		printf("  ⚠️  %s/%s — %s (run 'cops sync')\n", r.Type, r.Name, r.Detail)
+	}
+}
+
+// checkPinPolicy reports on the entry's branch exception and, when
+// requirePinned is set, on floating refs. A pinned entry needs no
+// exception, so one left on it is only reported as stale. It returns the
+// rule that applies to the entry, if any, and true if the entry violates it.
+func checkPinPolicy(entry manifest.Entry, requirePinned bool, now time.Time) (checkPolicy, bool) {
+	ref, err := config.ParseRef(entry.Ref)
+	if err == nil && ref.IsPinned() {
+		if entry.Options.AllowBranchUntil == "" {
+			return "", false
+		}
+		printf("  ⚠️  %s/%s — pinned to %s: allow_branch_until %s is no longer needed and can be removed\n",
+			entry.Type, entry.Name, ref.Ref, entry.Options.AllowBranchUntil)
+		return policyExceptionStale, false
+	}
+
+	status, expiry := entry.Options.BranchException(now)
+	switch status {
+	case manifest.ExceptionExpired:
+		printf("  ❌ %s/%s — allow_branch_until %s has expired: pin to a tag or commit\n",
+			entry.Type, entry.Name, entry.Options.AllowBranchUntil)
+		return policyExceptionExpired, true
+	case manifest.ExceptionExpiring:
+		days := int(expiry.Sub(now).Hours()/24) + 1
+		printf("  ⚠️  %s/%s — allow_branch_until %s expires in %d day(s)\n",
+			entry.Type, entry.Name, entry.Options.AllowBranchUntil, days)
+		return policyExceptionExpiring, false
+	case manifest.ExceptionActive:
+		return "", false
+	}
+
+	if !requirePinned || err != nil {
+		return "", false
+	}
+	printf("  ❌ %s/%s — tracks a floating ref (%s): pin it or add allow_branch_until\n",
+		entry.Type, entry.Name, entry.Ref)
+	return policyFloating, true
+}
+
+// frozenFetch refuses to download the template of an extends directive
+// under --frozen.
+func frozenFetch(ref string) ([]byte, error) {
+	return nil, fmt.Errorf("--frozen allows no network access")
+}
+
+// verifyContent compares the asset at path with its lock entry and
+// describes the first difference found, or returns "" if they match.
+func verifyContent(locked manifest.LockEntry, path string, isDir bool) string {
+	if isDir && len(locked.Files) > 0 {
+		// Per-file digests (lock v2) name the files that drifted.
+		files, modes, err := localFiles(path)
+		if err != nil {
+			return fmt.Sprintf("error reading local files: %v", err)
+		}
+		if changes := locked.DiffFiles(files, comparableModes(modes)); len(changes) > 0 {
+			return "files changed: " + formatFileChanges(changes)
+		}
+		return ""
+	}
+
+	cs, err := localChecksum(path, isDir)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("error reading local file: %v", err)
+	case cs != locked.Checksum:
+		return fmt.Sprintf("content modified (%s)", sizeChange(path, locked))
+	}
+	if mode := localMode(path); locked.Mode != "" && mode != "" && mode != locked.Mode {
+		return fmt.Sprintf("mode changed: %s → %s", locked.Mode, mode)
+	}
+	return ""
+}
+
+// verifyOutputs checks the copies and sections recorded by locked, whose
+// target matches it, and describes the first problem found, or returns ""
+// if they match.
+func verifyOutputs(locked manifest.LockEntry, rootDir string, isDir bool) string {
+	for _, path := range locked.Copies {
+		abs := filepath.Join(rootDir, path)
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Sprintf("copy %s missing", path)
+		}
+		if problem := verifyContent(locked, abs, isDir); problem != "" {
+			return fmt.Sprintf("copy %s: %s", path, problem)
+		}
+	}
+	if len(locked.Sections) == 0 {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(rootDir, locked.TargetPath))
+	if err != nil {
+		return fmt.Sprintf("error reading local file: %v", err)
+	}
+	want := injector.SectionBody(content)
+	for _, path := range locked.Sections {
+		doc, err := os.ReadFile(filepath.Join(rootDir, path))
+		body, ok := injector.FindSection(doc, locked.Type+"/"+locked.Name)
+		switch {
+		case err != nil || !ok:
+			return fmt.Sprintf("section in %s missing", path)
+		case !bytes.Equal(body, want):
+			return fmt.Sprintf("section in %s modified", path)
+		}
+	}
+	return ""
+}
+
 // localChecksum computes the SHA-256 checksum of a local file or directory,
 // using the same algorithm as the injector for comparison against lock file entries.
 func localChecksum(path string, isDir bool) (string, error) {
-	if !isDir {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return "", err
-		}
-		return manifest.Checksum(data), nil
+	data, err := localContent(path, isDir)
+	if err != nil {
+		return "", err
 	}
+	return manifest.Checksum(data), nil
+}
 
-	// For directories: collect files in sorted order and concatenate content,
-	// matching the deterministic algorithm used by the injector.
-	type filePair struct {
-		rel  string
-		data []byte
+// localContent returns the bytes a lock file checksum is computed from: the
+// file itself, or for directories the concatenation of all files in sorted
+// path order, matching the deterministic algorithm used by the injector.
+func localContent(path string, isDir bool) ([]byte, error) {
+	if !isDir {
+		return os.ReadFile(path)
 	}
-	var pairs []filePair
-	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+	files, _, err := localFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.DirectoryContent(files), nil
+}
+
+// localFiles reads every file below dir, keyed by its slash-separated path
+// relative to dir like the injector keys remote files, along with their
+// modes.
+func localFiles(dir string) (map[string][]byte, map[string]fs.FileMode, error) {
+	files := make(map[string][]byte)
+	modes := make(map[string]fs.FileMode)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
 			return nil
 		}
-		rel, _ := filepath.Rel(path, p)
+		// Walk does not follow links; a file linked into the content store
+		// counts with the mode of its target.
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if info, err = os.Stat(p); err != nil {
+				return err
+			}
+		}
+		rel, _ := filepath.Rel(dir, p)
 		data, err := os.ReadFile(p)
 		if err != nil {
 			return err
 		}
-		pairs = append(pairs, filePair{rel, data})
+		files[filepath.ToSlash(rel)] = data
+		modes[filepath.ToSlash(rel)] = info.Mode()
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return nil, nil, err
+	}
+	return files, modes, nil
+}
+
+// comparableModes returns modes, or nil where permission bits do not
+// round-trip through the file system (Windows) and cannot be checked.
+func comparableModes(modes map[string]fs.FileMode) map[string]fs.FileMode {
+	if runtime.GOOS == "windows" {
+		return nil
 	}
-	sort.Slice(pairs, func(i, j int) bool { return pairs[i].rel < pairs[j].rel })
+	return modes
+}
+
+// localMode returns the recorded form of the mode of the file at path, or
+// "" if it cannot be compared.
+func localMode(path string) string {
+	info, err := os.Stat(path)
+	if err != nil || runtime.GOOS == "windows" {
+		return ""
+	}
+	return manifest.FormatMode(info.Mode())
+}
+
+// sizeChange explains a checksum mismatch, pointing out truncation when
+// the lock entry records the size that was written.
+func sizeChange(path string, e manifest.LockEntry) string {
+	info, err := os.Stat(path)
+	switch {
+	case err != nil || e.Size == 0 || info.Size() == e.Size:
+		return "checksum mismatch"
+	case info.Size() < e.Size:
+		return fmt.Sprintf("truncated: %d of %d bytes", info.Size(), e.Size)
+	default:
+		return fmt.Sprintf("size %d, locked %d bytes", info.Size(), e.Size)
+	}
+}
 
-	var combined []byte
-	for _, p := range pairs {
-		combined = append(combined, p.data...)
+// formatFileChanges lists changed files as "a.md (modified), b.md (added)".
+func formatFileChanges(changes []manifest.FileChange) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = fmt.Sprintf("%s (%s)", c.Path, c.Change)
 	}
-	return manifest.Checksum(combined), nil
+	return strings.Join(parts, ", ")
 }
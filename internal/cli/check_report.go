@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// checkStatus is the outcome of checking one entry, as recorded in a
+// check report.
+type checkStatus string
+
+const (
+	checkOK             checkStatus = "ok"
+	checkNeverSynced    checkStatus = "never_synced"    // no file, no lock entry
+	checkMissing        checkStatus = "missing"         // locked, but the file is gone
+	checkUnlocked       checkStatus = "unlocked"        // the file exists without a lock entry
+	checkRefChanged     checkStatus = "ref_changed"     // copilot.toml and the lock disagree on the ref
+	checkModified       checkStatus = "modified"        // content or mode differs from the lock
+	checkTargetsChanged checkStatus = "targets_changed" // copies or sections differ from the lock
+	checkUnverified     checkStatus = "unverified"      // locked to a commit 'lock rebuild' could not verify
+	checkOrphaned       checkStatus = "orphaned"        // in the lock file but not in copilot.toml
+)
+
+// checkPolicy is the state of an entry with regard to pinning, as recorded
+// in a check report; it is empty when no pinning rule applies.
+type checkPolicy string
+
+const (
+	policyExceptionExpired  checkPolicy = "exception_expired"
+	policyExceptionExpiring checkPolicy = "exception_expiring"
+	policyFloating          checkPolicy = "floating"
+	policyExceptionStale    checkPolicy = "exception_stale" // allow_branch_until on a pinned ref
+)
+
+// checkReport is the machine-readable result of a check, written by
+// `cops check --report`.
+type checkReport struct {
+	CheckedAt string        `json:"checked_at"` // RFC 3339
+	Manifest  string        `json:"manifest"`
+	Issues    int           `json:"issues"`
+	Assets    []checkResult `json:"assets"`
+}
+
+// checkResult is the result of checking one entry.
+type checkResult struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Status      checkStatus `json:"status"`
+	Detail      string      `json:"detail,omitempty"` // what differs, for statuses other than ok
+	Ref         string      `json:"ref,omitempty"`    // from copilot.toml
+	LockedRef   string      `json:"locked_ref,omitempty"`
+	ResolvedSHA string      `json:"resolved_sha,omitempty"`
+	Path        string      `json:"path"`
+	SyncedAt    string      `json:"synced_at,omitempty"`
+	Policy      checkPolicy `json:"policy,omitempty"`
+	LatestSHA   string      `json:"latest_sha,omitempty"` // newer commit of a floating ref, with --updates
+}
+
+// newCheckResult returns the result of entry, before its status is known.
+func newCheckResult(entry manifest.Entry, locked manifest.LockEntry) checkResult {
+	return checkResult{
+		Type:        entry.Type,
+		Name:        entry.Name,
+		Ref:         entry.Ref,
+		LockedRef:   locked.Ref,
+		ResolvedSHA: locked.ResolvedSHA,
+		Path:        entry.TargetPath(),
+		SyncedAt:    locked.SyncedAt,
+	}
+}
+
+// writeCheckReport writes report to path as indented JSON.
+func writeCheckReport(path string, report checkReport, now time.Time) error {
+	report.CheckedAt = now.UTC().Format(time.RFC3339)
+	if report.Assets == nil {
+		report.Assets = []checkResult{}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	printf("📄 Wrote the results of %d asset(s) to %s\n", len(report.Assets), path)
+	return nil
+}
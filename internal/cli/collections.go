@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// collectionMembers returns the entries the collections of m expand to, in
+// the order of the collections. Each collection manifest is downloaded
+// through res, unless policies refuse its source; with frozen set, the
+// members are those the lock file records instead, and a collection
+// without any fails.
+func collectionMembers(m *manifest.Manifest, lock *manifest.LockFile, res resolver.ResolverAPI, frozen bool, policies manifest.Policies) ([]manifest.Entry, error) {
+	if frozen {
+		members := m.LockedMembers(lock)
+		for _, name := range manifest.SortedKeys(m.Collections) {
+			if !slices.ContainsFunc(members, func(e manifest.Entry) bool { return e.Collection == name }) {
+				return nil, fmt.Errorf("%s/%s is not in the lock file; run 'cops sync' without --frozen-lockfile to update the lock file", manifest.CollectionsSection, name)
+			}
+		}
+		return members, nil
+	}
+	var members []manifest.Entry
+	for _, name := range manifest.SortedKeys(m.Collections) {
+		entries, err := expandCollection(m, name, res, policies)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", manifest.CollectionsSection, name, err)
+		}
+		members = append(members, entries...)
+	}
+	return members, nil
+}
+
+// expandCollection downloads the collection name of m and returns its
+// entries, which share the collection's ref. A source policies refuse is
+// not downloaded from.
+func expandCollection(m *manifest.Manifest, name string, res resolver.ResolverAPI, policies manifest.Policies) ([]manifest.Entry, error) {
+	raw, err := m.ExpandRef(m.Collections[name])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSource(policies, res, raw); err != nil {
+		return nil, err
+	}
+	ref, err := config.ParseRef(raw)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := res.ResolveRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := res.DownloadFile(resolved)
+	if err != nil {
+		return nil, err
+	}
+	c, err := manifest.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref.Raw(), err)
+	}
+	return c.Entries(name, ref, m)
+}
+
+// inCollection reports whether id, "collections/<name>", names the
+// collection entry was expanded from.
+func inCollection(entry manifest.Entry, id string) bool {
+	return entry.Collection != "" && id == manifest.CollectionsSection+"/"+entry.Collection
+}
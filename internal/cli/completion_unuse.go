@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/cbout22/copilot-sync/internal/manifest"
@@ -9,7 +10,7 @@ import (
 
 func resolveManifestName(assetType string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	// Load the manifest
-	m, err := manifest.Load(manifest.DefaultManifestFile)
+	m, err := manifest.Load(manifestFile())
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -22,11 +23,22 @@ func resolveManifestName(assetType string, toComplete string) ([]string, cobra.S
 
 	// Filter based on toComplete prefix
 	var completions []string
-	for name, ref := range names {
+	for _, name := range manifest.SortedKeys(names) {
 		if strings.HasPrefix(name, toComplete) {
-			completions = append(completions, formatCompletionLine(name, ref))
+			completions = append(completions, formatCompletionLine(name, names[name]))
 		}
 	}
 
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
+
+// resolveEntryIDs completes the "<type>/<name>" arguments of commands
+// taking any number of entries, leaving out those already given.
+func resolveEntryIDs(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions, directive := resolveEntryID(toComplete)
+	completions = slices.DeleteFunc(completions, func(line string) bool {
+		id, _, _ := strings.Cut(line, "\t")
+		return slices.Contains(args, id)
+	})
+	return completions, directive
+}
@@ -5,23 +5,34 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cbout22/copilot-sync/internal/auth"
+	"github.com/cbout22/copilot-sync/internal/resolver"
 )
 
 const githubAPIBase = "https://api.github.com"
 
+// completionCacheMaxAge is how long completion reuses a repository's
+// search results, tree and refs without asking GitHub again, so typing a
+// ref costs one request rather than one per keystroke.
+const completionCacheMaxAge = 5 * time.Minute
+
 // resolveGitHubCompletions provides dynamic shell completion for GitHub assets.
 func resolveGitHubCompletions(toComplete string) ([]string, cobra.ShellCompDirective) {
+	if noNetwork() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
 	// Use a short timeout to prevent blocking the shell
 	client, err := auth.NewHTTPClientWithTimeout(time.Second)
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
+	client = resolver.WithHTTPCacheMaxAge(client, httpCacheDir(), completionCacheMaxAge)
 
 	// 1. Version state: Typing `@`
 	if idx := strings.Index(toComplete, "@"); idx != -1 {
@@ -162,6 +173,7 @@ func completePaths(client *http.Client, org, repo, pathPrefix, fullToComplete st
 		}
 	}
 
+	slices.Sort(completions)
 	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
 }
 
@@ -196,6 +208,7 @@ func completeRefs(client *http.Client, repoPart, refPart string) ([]string, cobr
 		return completions, cobra.ShellCompDirectiveNoFileComp
 	}
 
+	var refs []string
 	for _, item := range result {
 		// Only suggest branches and tags
 		if !strings.HasPrefix(item.Ref, "refs/heads/") && !strings.HasPrefix(item.Ref, "refs/tags/") {
@@ -212,9 +225,13 @@ func completeRefs(client *http.Client, repoPart, refPart string) ([]string, cobr
 			if strings.HasPrefix(item.Ref, "refs/tags/") {
 				desc = "Tag"
 			}
-			completions = append(completions, formatCompletionLine(fmt.Sprintf("%s@%s", repoPart, shortRef), desc))
+			refs = append(refs, formatCompletionLine(fmt.Sprintf("%s@%s", repoPart, shortRef), desc))
 		}
 	}
 
+	// "latest" stays first; concrete refs follow in byte-wise order.
+	slices.Sort(refs)
+	completions = append(completions, refs...)
+
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each change.
+	diffContext = 3
+	// maxDiffCells bounds the line comparisons of a file diff (lines of
+	// one side times lines of the other), to keep memory in check.
+	maxDiffCells = 1 << 22
+)
+
+// newDiffCmd creates the `diff` command.
+// Usage: cops diff [<type>/<name>]... [--remote]
+func newDiffCmd() *cobra.Command {
+	var remote bool
+
+	cmd := &cobra.Command{
+		Use:   "diff [<type>/<name>]...",
+		Short: "Show local changes to the managed assets",
+		Long: `Compares the managed assets on disk with .cops.lock and shows what was
+changed locally since the last sync, as a unified diff per file. With no
+argument every locked asset is compared; otherwise only the given ones,
+e.g. 'cops diff prompts/review'.
+
+The locked content is read from the download cache (see 'cops sync'), so
+nothing is downloaded. Files whose locked content is not cached, such as
+those rewritten by template variables, are only reported as modified.
+
+With --remote, the assets on disk are compared with the current tip of
+the ref each one tracks instead, as a sync would write it, to preview
+what 'cops sync' or 'cops update' would bring in. Skills are compared file
+by file. Local edits show up as changes too.`,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return resolveEntryIDs(args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remote {
+				res, err := newResolver(cmd.Context())
+				if err != nil {
+					return err
+				}
+				return runRemoteDiffWith(args, manifestFile(), lockFile(), res, ".")
+			}
+			var cache *store.Store
+			if st, err := contentStore(); err == nil && !store.CacheDisabled() {
+				cache = &st
+			}
+			return runDiffWith(args, lockFile(), ".", cache)
+		},
+	}
+
+	cmd.Flags().BoolVar(&remote, "remote", false, "Compare with the current tip of each asset's ref instead of the lock file")
+
+	return cmd
+}
+
+// runDiffWith is the testable core of the diff command. cache holds the
+// locked content; nil only reports which files changed.
+func runDiffWith(assets []string, lockPath, rootDir string, cache *store.Store) error {
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	keys, err := diffKeys(assets, lock, lockPath)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	changed := 0
+	for _, key := range keys {
+		e := lock.Entries[key]
+		target := filepath.Join(rootDir, e.TargetPath)
+		if _, err := os.Stat(target); err != nil {
+			printf("❌ %s — missing (run 'cops sync' to restore it)\n\n", key)
+			changed++
+			continue
+		}
+		var diff string
+		if config.AssetType(e.Type).IsDirectory() {
+			diff, err = diffDirectory(e, target, cache)
+		} else {
+			diff, err = diffFile(e, target, cache)
+		}
+		if err != nil {
+			printf("❌ %s — %v\n\n", key, err)
+			changed++
+			continue
+		}
+		if diff != "" {
+			printf("✏️  %s\n%s\n", key, diff)
+			changed++
+		}
+	}
+
+	if changed == 0 {
+		printf("✅ No local changes.\n")
+	} else {
+		printf("📋 %d asset(s) differ from %s. Run 'cops sync --force' to discard the changes.\n", changed, lockPath)
+	}
+	return nil
+}
+
+// diffKeys returns the keys of the lock entries of assets, or of every
+// lock entry if none is given.
+func diffKeys(assets []string, lock *manifest.LockFile, lockPath string) ([]string, error) {
+	keys := assets
+	if len(keys) == 0 {
+		keys = manifest.SortedKeys(lock.Entries)
+	}
+	for _, key := range keys {
+		if _, ok := lock.Entries[key]; !ok {
+			return nil, fmt.Errorf("%s is not in %s: expected <type>/<name> of a synced asset", key, lockPath)
+		}
+	}
+	if len(keys) == 0 {
+		printf("📋 No entries in %s — nothing to compare.\n", lockPath)
+	}
+	return keys, nil
+}
+
+// runRemoteDiffWith is the testable core of diff --remote.
+func runRemoteDiffWith(assets []string, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	keys, err := diffKeys(assets, lock, lockPath)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	inj := injector.New(res, lock, rootDir)
+
+	changed, failed := 0, 0
+	for _, key := range keys {
+		e := lock.Entries[key]
+		diff, sha, err := remoteDiff(m, inj, res, e, rootDir)
+		switch {
+		case err != nil:
+			printf("❌ %s — %v\n\n", key, err)
+			failed++
+		case diff == "":
+			printf("✅ %s — same as %s (%s)\n", key, e.Ref, shortSHA(sha))
+		default:
+			printf("⬆️  %s — %s (%s)\n%s\n", key, e.Ref, shortSHA(sha), diff)
+			changed++
+		}
+	}
+
+	if changed == 0 && failed == 0 {
+		printf("✅ Every asset matches the tip of its ref.\n")
+	} else if changed > 0 {
+		printf("📋 %d asset(s) differ from the tip of their ref. Run 'cops sync' to bring the changes in, or 'cops update' for newer tags.\n", changed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d asset(s) could not be compared", failed)
+	}
+	return nil
+}
+
+// remoteDiff returns the unified diff from the asset of e on disk to the
+// current tip of its ref, as a sync would write it, and the commit SHA of
+// that tip.
+func remoteDiff(m *manifest.Manifest, inj *injector.Injector, res resolver.ResolverAPI, e manifest.LockEntry, rootDir string) (string, string, error) {
+	ref, err := config.ParseRef(e.Ref)
+	if err != nil {
+		return "", "", err
+	}
+	if ref, err = res.ResolveRef(ref); err != nil {
+		return "", "", err
+	}
+	entry := m.Entry(e.Type, e.Name)
+	entry.Ref = ref.Raw()
+	opts := injectOptions(m, entry)
+	target := filepath.Join(rootDir, e.TargetPath)
+	name := filepath.ToSlash(e.TargetPath)
+
+	if !config.AssetType(e.Type).IsDirectory() {
+		remote, sha, err := inj.Fetch(config.AssetType(e.Type), entry.Ref, opts)
+		if err != nil {
+			return "", "", err
+		}
+		local, err := os.ReadFile(target)
+		from := "a/" + name
+		if errors.Is(err, fs.ErrNotExist) {
+			from = "/dev/null"
+		} else if err != nil {
+			return "", "", err
+		}
+		return unifiedDiff(from, "b/"+name, local, remote), sha, nil
+	}
+
+	remote, sha, err := inj.FetchDirectory(entry.Ref, opts)
+	if err != nil {
+		return "", "", err
+	}
+	local, _, err := localFiles(target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+	paths := manifest.SortedKeys(remote)
+	for p := range local {
+		if _, ok := remote[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		from, to := "a/"+path.Join(name, p), "b/"+path.Join(name, p)
+		if _, ok := local[p]; !ok {
+			from = "/dev/null"
+		}
+		if _, ok := remote[p]; !ok {
+			to = "/dev/null"
+		}
+		b.WriteString(unifiedDiff(from, to, local[p], remote[p]))
+	}
+	return b.String(), sha, nil
+}
+
+// diffFile returns the unified diff from the locked content of a single
+// file asset to the file at target, or "" if it is unchanged.
+func diffFile(e manifest.LockEntry, target string, cache *store.Store) (string, error) {
+	local, err := os.ReadFile(target)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Checksum(local) == e.Checksum {
+		return "", nil
+	}
+	return lockedDiff(e.TargetPath, e.Checksum, true, local, true, cache), nil
+}
+
+// diffDirectory returns the unified diffs of the files of a directory
+// asset that changed since they were locked, or "" if none did.
+func diffDirectory(e manifest.LockEntry, target string, cache *store.Store) (string, error) {
+	files, _, err := localFiles(target)
+	if err != nil {
+		return "", err
+	}
+	if len(e.Files) == 0 {
+		// Version 1 entries record no per-file digests.
+		if manifest.Checksum(manifest.DirectoryContent(files)) == e.Checksum {
+			return "", nil
+		}
+		return "content modified (the lock file records no per-file digests)\n", nil
+	}
+	var b strings.Builder
+	for _, c := range e.DiffFiles(files, nil) {
+		name := path.Join(filepath.ToSlash(e.TargetPath), c.Path)
+		b.WriteString(lockedDiff(name, e.Files[c.Path].SHA256, c.Change != "added", files[c.Path], c.Change != "removed", cache))
+	}
+	return b.String(), nil
+}
+
+// lockedDiff returns the unified diff of the file name from its locked
+// content, looked up by digest in cache, to local. A side that does not
+// exist is diffed as empty; a locked content that is not cached is only
+// reported.
+func lockedDiff(name, digest string, wasLocked bool, local []byte, exists bool, cache *store.Store) string {
+	var locked []byte
+	if wasLocked {
+		content, ok := []byte(nil), false
+		if cache != nil {
+			content, ok = cache.Get(digest)
+		}
+		if !ok {
+			return fmt.Sprintf("--- a/%s\n+++ b/%s\n(locked content not in the download cache: modified)\n", name, name)
+		}
+		locked = content
+	}
+	from, to := "a/"+name, "b/"+name
+	if !wasLocked {
+		from = "/dev/null"
+	}
+	if !exists {
+		to = "/dev/null"
+	}
+	return unifiedDiff(from, to, locked, local)
+}
+
+// diffOp is one line of an edit script: kept (' '), removed ('-') or
+// added ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the differences from a to b in unified format, with
+// diffContext lines of context, or "" if they are equal.
+func unifiedDiff(fromName, toName string, a, b []byte) string {
+	from, to := splitLines(a), splitLines(b)
+	header := fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName)
+	if (len(from)+1)*(len(to)+1) > maxDiffCells {
+		return header + fmt.Sprintf("(too large to diff: %d → %d lines)\n", len(from), len(to))
+	}
+	ops := diffLines(from, to)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change, then extend the hunk while the changes
+		// that follow are close enough to share context.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(ops) && i <= last+2*diffContext; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		lo, hi := max(first-diffContext, 0), min(last+diffContext+1, len(ops))
+
+		fromLine, toLine := 1, 1
+		for _, op := range ops[:lo] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, op := range ops[lo:hi] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		// An empty range starts at the line before it.
+		if fromCount == 0 {
+			fromLine--
+		}
+		if toCount == 0 {
+			toLine--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, op := range ops[lo:hi] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hi
+	}
+	if out.Len() == 0 {
+		return ""
+	}
+	return header + out.String()
+}
+
+// splitLines splits data after each newline, keeping them.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns an edit script turning a into b, keeping a longest
+// common subsequence of their lines.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	n, m := len(a), len(b)
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, max(n, m))
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
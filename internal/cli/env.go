@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/cbout22/copilot-sync/internal/auth"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// Environment variables configuring cops without flags, for containers and
+// CI. Paths are relative to the directory cops is started in.
+const (
+	manifestEnvVar  = "COPS_MANIFEST"   // the manifest, instead of looking for copilot.toml
+	lockEnvVar      = "COPS_LOCK"       // the lock file, instead of .cops.lock
+	parallelEnvVar  = "COPS_PARALLEL"   // overrides [settings] parallelism
+	noNetworkEnvVar = "COPS_NO_NETWORK" // refuses every network request
+	cacheDirEnvVar  = "COPS_CACHE_DIR"  // overrides cache_dir of the user configuration
+)
+
+// errNoNetwork is returned for every request made with COPS_NO_NETWORK set.
+var errNoNetwork = errors.New("network access is disabled (" + noNetworkEnvVar + " is set)")
+
+// manifestFile returns the manifest of the project: $COPS_MANIFEST if
+// set, the manifest file found in the project root otherwise.
+func manifestFile() string {
+	if path := os.Getenv(manifestEnvVar); path != "" {
+		return userPath(path)
+	}
+	return manifest.Find(".")
+}
+
+// lockFile returns the lock file of the project: $COPS_LOCK if set,
+// .cops.lock in the project root otherwise.
+func lockFile() string {
+	if path := os.Getenv(lockEnvVar); path != "" {
+		return userPath(path)
+	}
+	return manifest.DefaultLockFile
+}
+
+// parallelism returns how many files of a skill are downloaded at once:
+// $COPS_PARALLEL if set, the [settings] parallelism of m otherwise.
+func parallelism(m *manifest.Manifest) (int, error) {
+	env := os.Getenv(parallelEnvVar)
+	if env == "" {
+		return m.Settings.Parallelism, nil
+	}
+	n, err := strconv.Atoi(env)
+	if err != nil || n < 1 || n > manifest.MaxParallelism {
+		return 0, fmt.Errorf("%s: invalid value %q: must be between 1 and %d", parallelEnvVar, env, manifest.MaxParallelism)
+	}
+	return n, nil
+}
+
+// envEnabled reports whether the variable name is set to anything but
+// "", "0" or "false", the way CI services set CI.
+func envEnabled(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0" && v != "false"
+}
+
+// noNetwork reports whether COPS_NO_NETWORK forbids network requests, so
+// that only local sources and the download cache are used.
+func noNetwork() bool {
+	return envEnabled(noNetworkEnvVar)
+}
+
+// httpClient returns the client for GitHub API calls (see
+// auth.NewHTTPClient), or errNoNetwork if network access is disabled.
+func httpClient() (*http.Client, error) {
+	if noNetwork() {
+		return nil, errNoNetwork
+	}
+	return auth.NewHTTPClient()
+}
+
+// offlineTransport fails every request with errNoNetwork.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%s: %w", req.URL.Host, errNoNetwork)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("locked")
+
+// projectLockSuffix names the advisory lock of a project after its lock
+// file: .cops.lock.lck next to .cops.lock.
+const projectLockSuffix = ".lck"
+
+// projectLock is the advisory lock a cops process holds while it changes a
+// project, so that two runs, such as watch and a manual sync, do not
+// interleave their writes to the lock file and the assets. The OS releases
+// it when the process exits, however it exits.
+type projectLock struct {
+	f    *os.File
+	path string
+}
+
+// acquireProjectLock takes the lock of the project whose lock file is
+// lockPath, recording the process ID in it. It fails at once, naming the
+// holder, if another process has it.
+func acquireProjectLock(lockPath string) (*projectLock, error) {
+	path := lockPath + projectLockSuffix
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("locking the project: %w", err)
+		}
+		if err := tryLock(f); err != nil {
+			holder, _ := os.ReadFile(path)
+			_ = f.Close()
+			if errors.Is(err, errLocked) {
+				return nil, busyError(strings.TrimSpace(string(holder)))
+			}
+			return nil, fmt.Errorf("locking the project: %w", err)
+		}
+		// The holder removes the file when it is done: if it did between
+		// the open and the lock, the lock is on a file nobody else sees.
+		held, err := f.Stat()
+		if current, statErr := os.Stat(path); err == nil && statErr == nil && os.SameFile(held, current) {
+			if err := f.Truncate(0); err == nil {
+				_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+			}
+			return &projectLock{f: f, path: path}, nil
+		}
+		_ = f.Close()
+	}
+}
+
+// busyError is the error of a project locked by the process pid, if known.
+func busyError(pid string) error {
+	if pid == "" {
+		return fmt.Errorf("another cops process is running in this project — wait for it to finish and try again")
+	}
+	return fmt.Errorf("another cops process is running in this project (pid %s) — wait for it to finish and try again", pid)
+}
+
+// release removes the lock file and releases the lock.
+func (l *projectLock) release() {
+	removed := os.Remove(l.path) == nil
+	_ = l.f.Close()
+	if !removed {
+		// Windows does not remove open files.
+		_ = os.Remove(l.path)
+	}
+}
+
+// withProjectLock runs fn holding the lock of the project whose lock file
+// is lockPath.
+func withProjectLock(lockPath string, fn func() error) error {
+	lock, err := acquireProjectLock(lockPath)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	return fn()
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = kernel32.NewProc("LockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// tryLock locks f exclusively with LockFileEx without waiting, or returns
+// errLocked. The locked byte lies far past the process ID the file holds,
+// which other processes must still be able to read. Closing f releases it.
+func tryLock(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: 0x7fffffff}
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return errLocked
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// gitHookMarker identifies the git hooks cops wrote, so they can be
+// updated and removed without touching anyone else's.
+const gitHookMarker = "# cops: managed hook"
+
+// gitHookNames are the git hooks cops can install: checks that stop drift
+// from being committed or pushed, and syncs that install the assets a
+// pull or checkout changed.
+var gitHookNames = []string{"pre-commit", "pre-push", "post-merge", "post-checkout"}
+
+// autoSyncHooks are the hooks installed by --auto-sync.
+var autoSyncHooks = []string{"post-merge", "post-checkout"}
+
+// hooksInstallOptions holds the flags of the hooks install command.
+type hooksInstallOptions struct {
+	Hooks    []string // git hooks to install, from gitHookNames
+	AutoSync bool     // also install autoSyncHooks
+	Force    bool     // overwrite hooks cops did not write
+}
+
+// newHooksCmd creates the `hooks` command group.
+func newHooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Install git hooks that keep assets in sync",
+	}
+
+	cmd.AddCommand(newHooksInstallCmd())
+	cmd.AddCommand(newHooksUninstallCmd())
+
+	return cmd
+}
+
+// newHooksInstallCmd creates the `hooks install` subcommand.
+// Usage: cops hooks install [--hook <name>]... [--auto-sync] [--force]
+func newHooksInstallCmd() *cobra.Command {
+	var opts hooksInstallOptions
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install git hooks that check, or sync, assets on commit and pull",
+		Long: `Installs a git hook that runs 'cops check --frozen --strict', so a commit
+or push fails while the managed assets drift from copilot.toml and
+.cops.lock. The check needs no network access.
+
+With --auto-sync, post-merge and post-checkout hooks are installed too:
+after a pull or a branch switch, they run 'cops sync --frozen-lockfile
+--changed', so asset updates that landed in copilot.toml and .cops.lock
+reach the working tree without a manual sync. Only the entries that
+changed are downloaded; a failed sync is reported but does not fail the
+git command.
+
+Hooks are written to the repository's hooks directory (honouring
+core.hooksPath). An existing hook that cops did not write is kept unless
+--force is given. If cops is not installed, the hooks do nothing but
+warn.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHooksInstallWith(opts, ".")
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.Hooks, "hook", []string{"pre-commit"}, "Git hook to install: pre-commit, pre-push, post-merge or post-checkout (repeatable)")
+	cmd.Flags().BoolVar(&opts.AutoSync, "auto-sync", false, "Also install post-merge and post-checkout hooks syncing changed assets")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite hooks that cops did not write")
+
+	return cmd
+}
+
+// newHooksUninstallCmd creates the `hooks uninstall` subcommand.
+// Usage: cops hooks uninstall
+func newHooksUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the git hooks installed by 'cops hooks install'",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHooksUninstallWith(".")
+		},
+	}
+}
+
+// runHooksInstallWith is the testable core of the hooks install command.
+func runHooksInstallWith(opts hooksInstallOptions, rootDir string) error {
+	hooks := slices.Clone(opts.Hooks)
+	if opts.AutoSync {
+		for _, name := range autoSyncHooks {
+			if !slices.Contains(hooks, name) {
+				hooks = append(hooks, name)
+			}
+		}
+	}
+	for _, name := range hooks {
+		if !slices.Contains(gitHookNames, name) {
+			return fmt.Errorf("invalid hook %q: must be one of %s", name, strings.Join(gitHookNames, ", "))
+		}
+	}
+	hooksDir, project, err := gitHooksDir(rootDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return fmt.Errorf("creating hooks directory: %w", err)
+	}
+
+	for _, name := range hooks {
+		path := filepath.Join(hooksDir, name)
+		if existing, err := os.ReadFile(path); err == nil && !bytes.Contains(existing, []byte(gitHookMarker)) && !opts.Force {
+			return fmt.Errorf("%s already exists and was not written by cops: add '%s' to it, or use --force to replace it", path, gitHookCommand(name))
+		}
+		if err := os.WriteFile(path, []byte(gitHookScript(name, project)), 0o755); err != nil {
+			return fmt.Errorf("writing %s hook: %w", name, err)
+		}
+		printf("✅ Installed %s hook at %s\n", name, path)
+	}
+	return nil
+}
+
+// runHooksUninstallWith is the testable core of the hooks uninstall
+// command.
+func runHooksUninstallWith(rootDir string) error {
+	hooksDir, _, err := gitHooksDir(rootDir)
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for _, name := range gitHookNames {
+		path := filepath.Join(hooksDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil || !bytes.Contains(data, []byte(gitHookMarker)) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s hook: %w", name, err)
+		}
+		printf("🗑️  Removed %s hook\n", name)
+		removed++
+	}
+	if removed == 0 {
+		printf("📋 No hooks installed by cops.\n")
+	}
+	return nil
+}
+
+// gitHooksDir returns the hooks directory of the git repository holding
+// rootDir, and the slash-separated path of rootDir from the repository's
+// top level, which is where hooks run from.
+func gitHooksDir(rootDir string) (hooksDir, project string, err error) {
+	cmd := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-path", "hooks", "--show-prefix")
+	cmd.Dir = rootDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("%s is not in a git repository: %w", rootDir, err)
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) != 2 {
+		return "", "", fmt.Errorf("unexpected output of git rev-parse: %q", out)
+	}
+	return lines[0], strings.TrimSuffix(lines[1], "/"), nil
+}
+
+// isAutoSyncHook reports whether the git hook name syncs assets rather
+// than checking them.
+func isAutoSyncHook(name string) bool {
+	return slices.Contains(autoSyncHooks, name)
+}
+
+// gitHookCommand returns the cops command the git hook name runs.
+func gitHookCommand(name string) string {
+	if isAutoSyncHook(name) {
+		return "cops sync --frozen-lockfile --changed"
+	}
+	return "cops check --frozen --strict"
+}
+
+// gitHookScript returns the script of the git hook name, for the project
+// at the slash-separated path project of the repository.
+func gitHookScript(name, project string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(gitHookMarker + ", installed by 'cops hooks install'.\n")
+	if isAutoSyncHook(name) {
+		b.WriteString("# Installs the Copilot assets that changed in copilot.toml and .cops.lock.\n")
+	} else {
+		fmt.Fprintf(&b, "# Fails the %s while Copilot assets drift from copilot.toml and .cops.lock.\n", name)
+	}
+	if name == "post-checkout" {
+		// Only branch checkouts that moved HEAD, not file checkouts.
+		b.WriteString("[ \"$3\" = 1 ] && [ \"$1\" != \"$2\" ] || exit 0\n")
+	}
+	b.WriteString("if ! command -v cops >/dev/null 2>&1; then\n")
+	b.WriteString("\techo \"cops: not installed, skipping the Copilot assets\" >&2\n")
+	b.WriteString("\texit 0\n")
+	b.WriteString("fi\n")
+	if project != "" {
+		fmt.Fprintf(&b, "cd '%s' || exit 1\n", strings.ReplaceAll(project, "'", `'\''`))
+	}
+	if isAutoSyncHook(name) {
+		// The git command already succeeded: a failed sync is reported
+		// without failing it.
+		fmt.Fprintf(&b, "%s || echo \"cops: sync failed; run 'cops sync' to retry\" >&2\n", gitHookCommand(name))
+		b.WriteString("exit 0\n")
+	} else {
+		fmt.Fprintf(&b, "exec %s\n", gitHookCommand(name))
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// hookTimeout bounds each run of a hook command.
+const hookTimeout = 10 * time.Minute
+
+// runHooks runs commands in order through the shell from rootDir,
+// printing the output of each. It stops at the first command that fails,
+// or once ctx is done.
+func runHooks(ctx context.Context, name string, commands []string, rootDir string) error {
+	for _, command := range commands {
+		printf("🔧 Running %s hook: %s\n", name, command)
+		out, err := runHook(ctx, command, rootDir)
+		if out = strings.TrimRight(out, "\n"); out != "" {
+			fmt.Println("   " + strings.ReplaceAll(out, "\n", "\n   "))
+		}
+		if err != nil {
+			printf("  ❌ %s\n", err)
+			return fmt.Errorf("%s hook %q failed: %w", name, command, err)
+		}
+	}
+	return nil
+}
+
+// runHook runs command through the shell from dir and returns its
+// combined output. The command is killed when ctx is done.
+func runHook(ctx context.Context, command, dir string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return string(out), fmt.Errorf("timed out after %s", hookTimeout)
+	}
+	return string(out), err
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// infoOptions holds the flags accepted by the info command.
+type infoOptions struct {
+	Env string // manifest overlay to apply (copilot.<env>.toml)
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// Fetch downloads the template named by an extends directive.
+	Fetch manifest.Fetcher
+}
+
+// newInfoCmd creates the `info` command.
+// Usage: cops info <type>/<name> [--env <env>] [--no-global]
+func newInfoCmd() *cobra.Command {
+	var opts infoOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "info <type>/<name>",
+		Short: "Show the details of an entry of copilot.toml",
+		Long: `Shows everything known about a single entry: its ref, description, owner,
+tags, groups, target path and, if it has been synced, the commit it is
+locked to.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return resolveEntryID(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Env = manifestEnv(opts.Env)
+			opts.GlobalManifest = globalManifest(noGlobal)
+			opts.Fetch = lazyFetchTemplate(cmd.Context())
+			return runInfoWith(opts, args[0], manifestFile(), lockFile())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+
+	return cmd
+}
+
+// runInfoWith is the testable core of the info command.
+func runInfoWith(opts infoOptions, id, manifestPath, lockPath string) error {
+	typeName, name, ok := strings.Cut(id, "/")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid entry %q: use <type>/<name>, e.g. agents/planner", id)
+	}
+	if !config.AssetType(typeName).IsValid() {
+		return fmt.Errorf("invalid asset type: %s", typeName)
+	}
+
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      opts.Fetch,
+	})
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	var entry manifest.Entry
+	found := false
+	for _, e := range m.AllEntries() {
+		if e.Type == typeName && e.Name == name {
+			entry, found = e, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s/%s not found in %s", typeName, name, manifestPath)
+	}
+
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	opt := entry.Options
+	printf("📦 %s/%s\n", entry.Type, entry.Name)
+	printf("  Ref:          %s\n", entry.Ref)
+	printf("  Description:  %s\n", orDash(opt.Description))
+	printf("  Owner:        %s\n", orDash(opt.Owner))
+	printf("  Tags:         %s\n", orDash(strings.Join(opt.Tags, ", ")))
+	printf("  Groups:       %s\n", orDash(strings.Join(opt.Groups, ", ")))
+	printf("  Target:       %s\n", entry.TargetPath())
+	if opt.AllowBranchUntil != "" {
+		printf("  Branch until: %s\n", opt.AllowBranchUntil)
+	}
+	switch locked, ok := lock.Get(entry.Type, entry.Name); {
+	case !ok:
+		printf("  Locked:       not synced yet\n")
+	case locked.Ref != entry.Ref:
+		printf("  Locked:       %s at %s (ref changed; run 'cops sync')\n", locked.Ref, locked.ResolvedSHA)
+	default:
+		printf("  Locked:       %s (synced %s)\n", locked.ResolvedSHA, locked.SyncedAt)
+		if locked.Host != "" {
+			printf("  Source:       %s (%s)\n", locked.Source, locked.Host)
+		} else if locked.Source != "" {
+			printf("  Source:       %s\n", locked.Source)
+		}
+		if locked.License != "" {
+			printf("  License:      %s\n", locked.License)
+		}
+	}
+	return nil
+}
+
+// resolveEntryID completes "<type>/<name>" from the manifest, with each
+// entry's description as help text.
+func resolveEntryID(toComplete string) ([]string, cobra.ShellCompDirective) {
+	m, err := manifest.Load(manifestFile())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	for _, e := range m.AllEntries() {
+		id := e.Type + "/" + e.Name
+		if strings.HasPrefix(id, toComplete) {
+			completions = append(completions, formatCompletionLine(id, cmp.Or(e.Options.Description, e.Ref)))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+// linkEnvVar sets the default link mode of sync.
+const linkEnvVar = "COPS_LINK"
+
+// linkMode returns the link mode to use: the --link flag value if given,
+// COPS_LINK otherwise. Empty means files are written as copies.
+func linkMode(flag string) (string, error) {
+	mode := flag
+	if mode == "" {
+		mode = os.Getenv(linkEnvVar)
+	}
+	if mode == "" {
+		return "", nil
+	}
+	return mode, store.CheckMode(mode)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// listOptions holds the flags accepted by the list command.
+type listOptions struct {
+	Tags  []string // only list entries with one of these tags
+	Owner string   // only list entries with this owner
+	Env   string   // manifest overlay to apply (copilot.<env>.toml)
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// Fetch downloads the template named by an extends directive.
+	Fetch manifest.Fetcher
+}
+
+// newListCmd creates the `list` command.
+// Usage: cops list [--tag <tag>]... [--owner <owner>] [--env <env>] [--no-global]
+func newListCmd() *cobra.Command {
+	var opts listOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the entries of copilot.toml with their descriptions",
+		Long: `Lists every entry of copilot.toml, including included, template and
+user-level entries, with its ref, owner, tags, description and the license
+of the repository it was synced from.
+
+With --tag, only entries carrying one of the given tags are listed. With
+--owner, only entries owned by the given owner are listed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Env = manifestEnv(opts.Env)
+			opts.GlobalManifest = globalManifest(noGlobal)
+			opts.Fetch = lazyFetchTemplate(cmd.Context())
+			return runListWith(opts, manifestFile(), lockFile())
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.Tags, "tag", nil, "Only list entries with this tag (repeatable)")
+	cmd.Flags().StringVar(&opts.Owner, "owner", "", "Only list entries with this owner")
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+
+	return cmd
+}
+
+// runListWith is the testable core of the list command.
+func runListWith(opts listOptions, manifestPath, lockPath string) error {
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      opts.Fetch,
+	})
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	var entries []manifest.Entry
+	for _, e := range m.AllEntries() {
+		if len(opts.Tags) > 0 && !slices.ContainsFunc(opts.Tags, func(tag string) bool { return slices.Contains(e.Options.Tags, tag) }) {
+			continue
+		}
+		if opts.Owner != "" && e.Options.Owner != opts.Owner {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		printf("📋 No matching entries in copilot.toml.\n")
+		return nil
+	}
+
+	printf("📋 %d asset(s):\n\n", len(entries))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ENTRY\tREF\tOWNER\tTAGS\tLICENSE\tDESCRIPTION")
+	for _, e := range entries {
+		var license string
+		if locked, ok := lock.Get(e.Type, e.Name); ok && locked.Ref == e.Ref {
+			license = locked.License
+		}
+		fmt.Fprintf(w, "  %s/%s\t%s\t%s\t%s\t%s\t%s\n", e.Type, e.Name, e.Ref,
+			orDash(e.Options.Owner), orDash(strings.Join(e.Options.Tags, ",")), orDash(license), e.Options.Description)
+	}
+	return w.Flush()
+}
+
+// orDash returns s, or "-" if it is empty, so table columns stay aligned.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
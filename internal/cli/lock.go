@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// newLockCmd creates the `lock` command group.
+func newLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Inspect and repair the .cops.lock file",
+	}
+
+	cmd.AddCommand(newLockRebuildCmd())
+	cmd.AddCommand(newLockMergeCmd())
+
+	return cmd
+}
+
+// lockRebuildOptions holds the flags of the lock rebuild command.
+type lockRebuildOptions struct {
+	Env string // manifest overlay to apply (copilot.<env>.toml)
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+}
+
+// newLockRebuildCmd creates the `lock rebuild` subcommand.
+// Usage: cops lock rebuild [--env <env>] [--no-global]
+func newLockRebuildCmd() *cobra.Command {
+	var opts lockRebuildOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Reconstruct .cops.lock from copilot.toml and the files on disk",
+		Long: `Rebuilds a corrupted or deleted .cops.lock without re-downloading assets.
+
+For every entry in copilot.toml, the local file or directory is hashed and
+the ref is resolved to its current commit SHA. The remote content is fetched
+(but not written) to confirm it matches what is on disk. Entries that cannot
+be confirmed are recorded with an "unknown" SHA and flagged; run 'cops sync'
+to replace them with a verified state.
+
+With --env (or COPS_ENV), the copilot.<env>.toml overlay is applied first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Env = manifestEnv(opts.Env)
+			opts.GlobalManifest = globalManifest(noGlobal)
+			return runLockRebuild(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+
+	return cmd
+}
+
+func runLockRebuild(ctx context.Context, opts lockRebuildOptions) error {
+	res, err := newResolver(ctx)
+	if err != nil {
+		return err
+	}
+	return withProjectLock(lockFile(), func() error {
+		return runLockRebuildWith(opts, manifestFile(), lockFile(), res, ".")
+	})
+}
+
+// runLockRebuildWith is the testable core of the lock rebuild command.
+func runLockRebuildWith(opts lockRebuildOptions, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      fetchTemplate(res),
+	})
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+
+	// Start from scratch: the existing lock may be unreadable.
+	lock := manifest.NewLockFile()
+	entries := m.AllEntries()
+	if len(m.Collections) > 0 {
+		members, err := collectionMembers(m, lock, res, false, nil)
+		if err != nil {
+			return err
+		}
+		entries, _ = manifest.MergeMembers(entries, members)
+	}
+	if len(entries) == 0 {
+		printf("📋 No entries in copilot.toml — nothing to rebuild.\n")
+		return nil
+	}
+
+	inj := injector.New(res, lock, rootDir)
+	n, err := parallelism(m)
+	if err != nil {
+		return err
+	}
+	inj.SetParallelism(n)
+
+	printf("🔧 Rebuilding lock file from %d asset(s)...\n\n", len(entries))
+
+	var missing, uncertain int
+	for _, entry := range entries {
+		assetType := config.AssetType(entry.Type)
+		targetPath := entry.TargetPath()
+		absTarget := filepath.Join(rootDir, targetPath)
+
+		if _, err := os.Stat(absTarget); err != nil {
+			printf("  ❌ %s/%s — missing on disk, skipped (run 'cops sync')\n", entry.Type, entry.Name)
+			missing++
+			continue
+		}
+
+		var local []byte
+		var files map[string][]byte // per-file content of directory assets
+		if assetType.IsDirectory() {
+			files, _, err = localFiles(absTarget)
+			local = manifest.DirectoryContent(files)
+		} else {
+			local, err = os.ReadFile(absTarget)
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", targetPath, err)
+		}
+
+		remote, sha, err := inj.Fetch(assetType, entry.Ref, injectOptions(m, entry))
+		switch {
+		case err != nil:
+			printf("  ⚠️  %s/%s — could not verify against remote: %v\n", entry.Type, entry.Name, err)
+			sha = injector.UnknownSHA
+			uncertain++
+		case !bytes.Equal(local, remote):
+			printf("  ⚠️  %s/%s — local content differs from %s, recorded as-is\n", entry.Type, entry.Name, entry.Ref)
+			sha = injector.UnknownSHA
+			uncertain++
+		default:
+			printf("  ✅ %s/%s — verified at %s\n", entry.Type, entry.Name, sha)
+		}
+
+		if files != nil {
+			lock.SetDirectory(entry.Type, entry.Name, entry.Ref, sha, targetPath, files)
+		} else {
+			lock.Set(entry.Type, entry.Name, entry.Ref, sha, targetPath, local)
+		}
+		if err := lock.RecordModes(entry.Type, entry.Name, absTarget); err != nil {
+			return fmt.Errorf("reading %s: %w", targetPath, err)
+		}
+		copies, sections := m.Outputs(entry.Type, entry.Name)
+		lock.RecordOutputs(entry.Type, entry.Name, copies, sections)
+		lock.RecordCollection(entry.Type, entry.Name, entry.Collection)
+	}
+
+	if err := lock.Save(lockPath); err != nil {
+		return fmt.Errorf("saving lock file: %w", err)
+	}
+
+	fmt.Println()
+	if missing > 0 || uncertain > 0 {
+		printf("⚠️  Lock file rebuilt with %d missing and %d unverified asset(s). Run 'cops sync' to restore a verified state.\n", missing, uncertain)
+		return nil
+	}
+
+	printf("✅ Lock file rebuilt and verified.\n")
+	return nil
+}
+
+// newLockMergeCmd creates the `lock merge` subcommand.
+// Usage: cops lock merge <base> <ours> <theirs> [-o <output>]
+func newLockMergeCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "merge <base> <ours> <theirs>",
+		Short: "Merge two diverged .cops.lock files",
+		Long: `Merges the ours and theirs versions of .cops.lock, which diverged from
+base, and writes the result to ours (or to --output).
+
+Each entry is taken from the side that changed it, and entries added on
+either side are kept. When both sides changed the same entry, the one synced
+last wins, so the result is the same whichever side merges. Such entries are
+listed; run 'cops check' after the merge to confirm they match copilot.toml.
+
+To let git resolve lock conflicts with it, register it as a merge driver:
+
+  git config merge.cops-lock.name "cops lock merge"
+  git config merge.cops-lock.driver "cops lock merge %O %A %B"
+  echo ".cops.lock merge=cops-lock" >> .gitattributes`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLockMerge(userPath(args[0]), userPath(args[1]), userPath(args[2]), userPath(cmp.Or(output, args[1])))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the merged lock file here instead of over <ours>")
+
+	return cmd
+}
+
+// runLockMerge is the testable core of the lock merge command.
+func runLockMerge(basePath, oursPath, theirsPath, outputPath string) error {
+	var locks [3]*manifest.LockFile
+	for i, path := range []string{basePath, oursPath, theirsPath} {
+		lock, err := loadMergeInput(path)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", path, err)
+		}
+		locks[i] = lock
+	}
+
+	merged, notes := manifest.MergeLocks(locks[0], locks[1], locks[2])
+	if err := merged.Save(outputPath); err != nil {
+		return fmt.Errorf("saving lock file: %w", err)
+	}
+
+	for _, note := range notes {
+		printf("  ⚠️  %s\n", note)
+	}
+	if len(notes) > 0 {
+		printf("🔧 Merged %d asset(s); %d changed on both sides. Run 'cops check' to confirm them.\n", len(merged.Entries), len(notes))
+		return nil
+	}
+	printf("✅ Merged %d asset(s).\n", len(merged.Entries))
+	return nil
+}
+
+// loadMergeInput loads one side of a lock merge. git passes an empty file
+// as the base when both sides added the lock file independently.
+func loadMergeInput(path string) (*manifest.LockFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return manifest.NewLockFile(), nil
+	}
+	return manifest.LoadLock(path)
+}
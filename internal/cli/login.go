@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/auth"
+)
+
+// newLoginCmd creates the `login` command.
+// Usage: cops login [--device [--scopes repo]] < token.txt
+func newLoginCmd() *cobra.Command {
+	var device bool
+	var scopes []string
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Store a GitHub token in the system keychain",
+		Long: `Reads a GitHub token from standard input and stores it in the system
+keychain (macOS Keychain, Windows Credential Manager, or the Secret Service
+on Linux). Stored tokens take precedence over GITHUB_TOKEN and GH_TOKEN,
+but not over COPS_TOKEN_COMMAND or a token_file.
+
+With --device, cops runs GitHub's device authorization flow instead: it
+prints a code to enter at github.com/login/device and stores the token
+GitHub issues once you approve. Expiring tokens are refreshed automatically.
+
+  cops login                  # paste the token when prompted
+  gh auth token | cops login  # reuse the GitHub CLI's token
+  cops login --device         # authorize in the browser`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if device {
+				return runDeviceLoginWith(auth.NewDeviceFlow(auth.OAuthClientID(), scopes...), auth.SystemKeychain())
+			}
+			if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				printf("🔑 Paste a GitHub token: ")
+			}
+			return runLoginWith(os.Stdin, auth.SystemKeychain())
+		},
+	}
+
+	cmd.Flags().BoolVar(&device, "device", false, "Authorize in the browser with the OAuth device flow")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", []string{"repo"}, "OAuth scopes to request with --device")
+
+	return cmd
+}
+
+// runLoginWith is the testable core of the login command.
+func runLoginWith(in io.Reader, kc auth.Keychain) error {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading token: %w", err)
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return fmt.Errorf("no token provided on standard input")
+	}
+
+	if err := kc.Set(token); err != nil {
+		return fmt.Errorf("storing token: %w", err)
+	}
+	printf("✅ Token stored in the system keychain.\n")
+	return nil
+}
+
+// runDeviceLoginWith is the testable core of `login --device`.
+func runDeviceLoginWith(flow *auth.DeviceFlow, kc auth.Keychain) error {
+	code, err := flow.Start()
+	if err != nil {
+		return err
+	}
+	printf("🔑 Open %s and enter the code: %s\n", code.VerificationURI, code.UserCode)
+	printf("   Waiting for authorization...\n")
+
+	cred, err := flow.Poll(code)
+	if err != nil {
+		return err
+	}
+	if err := kc.Set(cred.Encode()); err != nil {
+		return fmt.Errorf("storing token: %w", err)
+	}
+	printf("✅ Authorized — token stored in the system keychain.\n")
+	return nil
+}
+
+// newLogoutCmd creates the `logout` command.
+// Usage: cops logout
+func newLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the GitHub token from the system keychain",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLogoutWith(auth.SystemKeychain())
+		},
+	}
+}
+
+// runLogoutWith is the testable core of the logout command.
+func runLogoutWith(kc auth.Keychain) error {
+	err := kc.Delete()
+	if errors.Is(err, auth.ErrNoStoredToken) {
+		printf("📋 No token stored — already logged out.\n")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("removing token: %w", err)
+	}
+	printf("✅ Token removed from the system keychain.\n")
+	return nil
+}
@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// mcpProtocolVersions are the Model Context Protocol revisions the server
+// speaks, latest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// mcpArg is an argument of an MCP tool, passed to the command it runs as
+// the flag of the same name, with dashes for underscores.
+type mcpArg struct {
+	Name        string
+	Type        string // JSON schema type: "boolean", "string" or "array" (of strings)
+	Description string
+}
+
+// mcpTool is a cops command exposed as an MCP tool.
+type mcpTool struct {
+	Name        string
+	Description string
+	Command     string
+	Args        []mcpArg
+
+	// Positional, if set, is an array argument passed as the command's
+	// positional arguments.
+	Positional *mcpArg
+}
+
+// mcpTools are the tools `cops mcp-serve` exposes.
+var mcpTools = []mcpTool{
+	{
+		Name:        "list",
+		Description: "List the Copilot assets declared in copilot.toml, with their type, ref, owner, tags and description.",
+		Command:     "list",
+		Args: []mcpArg{
+			{"tag", "array", "Only list entries with one of these tags"},
+			{"owner", "string", "Only list entries with this owner"},
+		},
+	},
+	{
+		Name:        "check",
+		Description: "Check that the Copilot assets on disk match copilot.toml and .cops.lock, reporting missing, modified and outdated assets.",
+		Command:     "check",
+		Args: []mcpArg{
+			{"frozen", "boolean", "Check without any network access"},
+			{"require_pinned", "boolean", "Also report entries tracking a branch instead of a tag or commit"},
+			{"group", "array", "Only check entries in these groups"},
+		},
+	},
+	{
+		Name:        "diff",
+		Description: "Show, as unified diffs, the local changes made to the Copilot assets since they were last synced.",
+		Command:     "diff",
+		Positional:  &mcpArg{"assets", "array", "Assets to compare, as <type>/<name> (default: all)"},
+	},
+	{
+		Name:        "sync",
+		Description: "Download the Copilot assets of copilot.toml and update .cops.lock, repairing missing or outdated assets.",
+		Command:     "sync",
+		Args: []mcpArg{
+			{"frozen_lockfile", "boolean", "Install exactly the versions locked in .cops.lock"},
+			{"changed", "boolean", "Only sync entries that differ from .cops.lock"},
+			{"force", "boolean", "Overwrite files edited since the last sync, discarding the local changes"},
+			{"group", "array", "Only sync entries in these groups"},
+		},
+	},
+}
+
+// rpcMessage is a JSON-RPC 2.0 request or notification.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a failed JSON-RPC request.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newMCPServeCmd creates the `mcp-serve` command.
+// Usage: cops mcp-serve [--dir <project>]
+func newMCPServeCmd() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "mcp-serve",
+		Short: "Serve list, check, diff and sync as MCP tools over stdio",
+		Long: `Runs a Model Context Protocol server on standard input and output, so
+AI agents can inspect and repair asset drift from chat. It exposes four
+tools running the matching cops commands in the project directory (the
+current one, or --dir): list, check, diff and sync.
+
+The server is meant to be started by an MCP client, such as an editor,
+not run by hand.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir != "" {
+				if err := os.Chdir(userPath(dir)); err != nil {
+					return fmt.Errorf("changing to the project directory: %w", err)
+				}
+			}
+			// Tools print to os.Stdout, which runCommand swaps while they
+			// run: responses go to the original, bound here.
+			return runMCPServeWith(cmd.Context(), os.Stdin, os.Stdout, runCommand)
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Project directory the tools run in (default: the current directory)")
+
+	return cmd
+}
+
+// runMCPServeWith is the testable core of the mcp-serve command. It
+// answers the newline-delimited JSON-RPC messages of in on out until in is
+// closed, running tools with run.
+func runMCPServeWith(ctx context.Context, in io.Reader, out io.Writer, run func(ctx context.Context, args []string) (string, error)) error {
+	r := bufio.NewReader(in)
+	enc := json.NewEncoder(out)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := handleMCPMessage(ctx, line, run); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return fmt.Errorf("writing response: %w", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading request: %w", err)
+		}
+	}
+}
+
+// handleMCPMessage answers one JSON-RPC message, or returns nil for
+// notifications.
+func handleMCPMessage(ctx context.Context, data []byte, run func(ctx context.Context, args []string) (string, error)) *rpcResponse {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "invalid JSON: " + err.Error()}}
+	}
+	if len(msg.ID) == 0 {
+		// Notifications (initialized, cancelled, ...) need no answer.
+		return nil
+	}
+
+	resp := &rpcResponse{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		protocol := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			protocol = params.ProtocolVersion
+		}
+		resp.Result = map[string]any{
+			"protocolVersion": protocol,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "cops", "version": version},
+			"instructions":    "Tools managing the GitHub Copilot assets (instructions, agents, prompts, chat modes, skills) declared in copilot.toml. Use check or diff to find drift, and sync to repair it.",
+		}
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		tools := make([]map[string]any, 0, len(mcpTools))
+		for _, t := range mcpTools {
+			tools = append(tools, map[string]any{
+				"name":        t.Name,
+				"description": t.Description,
+				"inputSchema": t.inputSchema(),
+			})
+		}
+		resp.Result = map[string]any{"tools": tools}
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			resp.Error = &rpcError{rpcInvalidParams, err.Error()}
+			break
+		}
+		i := slices.IndexFunc(mcpTools, func(t mcpTool) bool { return t.Name == params.Name })
+		if i < 0 {
+			resp.Error = &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", params.Name)}
+			break
+		}
+		args, err := mcpTools[i].commandArgs(params.Arguments)
+		if err != nil {
+			resp.Error = &rpcError{rpcInvalidParams, err.Error()}
+			break
+		}
+		output, err := run(ctx, args)
+		if err != nil {
+			output += "Error: " + err.Error() + "\n"
+		}
+		resp.Result = map[string]any{
+			"content": []map[string]any{{"type": "text", "text": output}},
+			"isError": err != nil,
+		}
+	default:
+		resp.Error = &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", msg.Method)}
+	}
+	return resp
+}
+
+// inputSchema returns the JSON schema of the tool's arguments.
+func (t mcpTool) inputSchema() map[string]any {
+	properties := map[string]any{}
+	args := t.Args
+	if t.Positional != nil {
+		args = append(slices.Clone(args), *t.Positional)
+	}
+	for _, a := range args {
+		p := map[string]any{"type": a.Type, "description": a.Description}
+		if a.Type == "array" {
+			p["items"] = map[string]any{"type": "string"}
+		}
+		properties[a.Name] = p
+	}
+	return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+// commandArgs returns the command line running the tool with arguments.
+func (t mcpTool) commandArgs(arguments map[string]any) ([]string, error) {
+	args := []string{t.Command}
+	var positional []string
+	for _, name := range manifest.SortedKeys(arguments) {
+		i := slices.IndexFunc(t.Args, func(a mcpArg) bool { return a.Name == name })
+		var spec mcpArg
+		switch {
+		case i >= 0:
+			spec = t.Args[i]
+		case t.Positional != nil && t.Positional.Name == name:
+			spec = *t.Positional
+		default:
+			return nil, fmt.Errorf("unknown argument %q for tool %s", name, t.Name)
+		}
+
+		flag := "--" + strings.ReplaceAll(name, "_", "-")
+		switch value := arguments[name].(type) {
+		case bool:
+			if spec.Type != "boolean" {
+				return nil, fmt.Errorf("argument %q must be a %s", name, spec.Type)
+			}
+			args = append(args, fmt.Sprintf("%s=%t", flag, value))
+		case string:
+			if spec.Type != "string" {
+				return nil, fmt.Errorf("argument %q must be a %s", name, spec.Type)
+			}
+			args = append(args, flag+"="+value)
+		case []any:
+			if spec.Type != "array" {
+				return nil, fmt.Errorf("argument %q must be a %s", name, spec.Type)
+			}
+			for _, item := range value {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("argument %q must be an array of strings", name)
+				}
+				if t.Positional != nil && t.Positional.Name == name {
+					positional = append(positional, s)
+				} else {
+					args = append(args, flag+"="+s)
+				}
+			}
+		case nil:
+		default:
+			return nil, fmt.Errorf("argument %q must be a %s", name, spec.Type)
+		}
+	}
+	if len(positional) > 0 {
+		// "--" keeps positional values from being read as flags.
+		args = append(append(args, "--"), positional...)
+	}
+	return args, nil
+}
+
+// runCommand runs the cops command line args in-process and returns what
+// it printed on standard output and error.
+func runCommand(ctx context.Context, args []string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	var output bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&output, r)
+		close(copied)
+	}()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	root := NewRootCmd()
+	root.SetArgs(args)
+	runErr := root.ExecuteContext(ctx)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	_ = w.Close()
+	<-copied
+	_ = r.Close()
+	return output.String(), runErr
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// newOptions holds the flags accepted by the new command.
+type newOptions struct {
+	Description string   // frontmatter description; default: a TODO placeholder
+	ApplyTo     string   // glob of the files instructions apply to
+	Tools       []string // tools prompts, agents and chat modes may use
+	Register    bool     // add the asset to copilot.toml as a local entry
+	Force       bool     // overwrite an existing file
+}
+
+// newNewCmd creates the `new` command.
+// Usage: cops new <type> <name> [--description <text>] [--apply-to <glob>] [--tools <tool>]... [--register] [--force]
+func newNewCmd() *cobra.Command {
+	var opts newOptions
+
+	cmd := &cobra.Command{
+		Use:   "new <type> <name>",
+		Short: "Scaffold a new asset to author in the project",
+		Long: `Creates a new asset, correctly named and with valid frontmatter, where
+Copilot looks for it: .github/instructions/<name>.instructions.md,
+.github/agents/<name>.agent.md, .github/prompts/<name>.prompt.md,
+.github/chatmodes/<name>.chatmode.md or .github/skills/<name>/SKILL.md
+(under output_root, if copilot.toml sets it).
+
+The frontmatter holds a description and, depending on the type, the files
+instructions apply to (--apply-to, "**" by default) or the tools a prompt,
+agent or chat mode may use (--tools). Fill in the body, and the description
+if none was given.
+
+With --register, the asset is also added to copilot.toml as a local entry,
+"local:<path>", so it is listed, checked and locked like the assets
+synced from elsewhere, and can later be proposed upstream with
+'cops publish --to'.
+
+Example:
+  cops new instructions go-style --apply-to '**/*.go' --register
+  cops new prompts review --description "Review the staged changes" --tools codebase`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var types []string
+			for _, t := range config.ValidAssetTypes() {
+				if !t.IsCustom() && !t.IsSingleton() {
+					types = append(types, string(t))
+				}
+			}
+			return types, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withProjectLock(lockFile(), func() error {
+				return runNewWith(opts, args[0], args[1], manifestFile(), lockFile(), ".")
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Description, "description", "", "Description of the asset, in its frontmatter")
+	cmd.Flags().StringVar(&opts.ApplyTo, "apply-to", "**", "Files the instructions apply to, as a glob (instructions only)")
+	cmd.Flags().StringSliceVar(&opts.Tools, "tools", nil, "Tools the prompt, agent or chat mode may use (repeatable)")
+	cmd.Flags().BoolVar(&opts.Register, "register", false, "Add the asset to copilot.toml as a local entry")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite an existing asset")
+
+	return cmd
+}
+
+// runNewWith is the testable core of the new command.
+func runNewWith(opts newOptions, typeName, name, manifestPath, lockPath, rootDir string) error {
+	assetType := config.AssetType(typeName)
+	if !assetType.IsValid() {
+		return fmt.Errorf("invalid asset type: %s", typeName)
+	}
+	if assetType.IsCustom() {
+		return fmt.Errorf("no template for %s: custom types are not scaffolded", typeName)
+	}
+	if assetType.IsSingleton() {
+		return fmt.Errorf("no template for %s: write %s and add it with 'cops %s use'", typeName, assetType.SingletonFile(), typeName)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || !filepath.IsLocal(name) {
+		return fmt.Errorf("invalid name %q: must be a plain file name, e.g. review", name)
+	}
+	if len(opts.Tools) > 0 && assetType != config.Prompts && assetType != config.Agents && assetType != config.Chatmodes {
+		return fmt.Errorf("--tools only applies to prompts, agents and chatmodes")
+	}
+
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	if opts.Register {
+		if section, err := m.Section(typeName); err == nil {
+			if ref, ok := section[name]; ok {
+				return fmt.Errorf("%s/%s is already in copilot.toml (%s)", typeName, name, ref)
+			}
+		}
+	}
+
+	target := m.TargetPath(typeName, name)
+	file := target
+	if assetType.IsDirectory() {
+		file = filepath.Join(target, "SKILL.md")
+	}
+	absFile := filepath.Join(rootDir, file)
+	if _, err := os.Stat(absFile); err == nil && !opts.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite it)", file)
+	}
+
+	content, err := scaffold(assetType, name, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(absFile), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(absFile, content, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	printf("✨ Created %s\n", file)
+
+	if !opts.Register {
+		return nil
+	}
+	// The asset is its own source: adding it locks its current content.
+	return runUseWith(typeName, name, "local:"+filepath.ToSlash(target), manifestPath, lockPath, resolver.NewLocalSource(rootDir), rootDir)
+}
+
+// scaffold returns the content of a new asset of assetType named name: a
+// frontmatter following the conventions of the type and a body to fill in.
+func scaffold(assetType config.AssetType, name string, opts newOptions) ([]byte, error) {
+	description := opts.Description
+	if description == "" {
+		description = "TODO: describe what this " + strings.TrimSuffix(string(assetType), "s") + " is for"
+	}
+
+	var fields [][2]any
+	var body string
+	switch assetType {
+	case config.Instructions:
+		fields = [][2]any{{"description", description}, {"applyTo", opts.ApplyTo}}
+		body = "Write the conventions Copilot should follow in the matching files.\n"
+	case config.Prompts:
+		fields = [][2]any{{"description", description}, {"mode", "agent"}}
+		body = "Write the task Copilot should carry out when this prompt is run.\n"
+	case config.Agents:
+		fields = [][2]any{{"description", description}}
+		body = "Describe the role of this agent and how it should work.\n"
+	case config.Chatmodes:
+		fields = [][2]any{{"description", description}}
+		body = "Describe how Copilot should behave and respond in this chat mode.\n"
+	case config.Skills:
+		fields = [][2]any{{"name", name}, {"description", description}}
+		body = "Explain when to use this skill and the steps it follows.\n"
+	}
+	if len(opts.Tools) > 0 {
+		fields = append(fields, [2]any{"tools", opts.Tools})
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, f := range fields {
+		// Values are written in their JSON form, which YAML reads as-is.
+		value, err := json.Marshal(f[1])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s: %s\n", f[0], value)
+	}
+	fmt.Fprintf(&b, "---\n\n# %s\n\n%s", name, body)
+	return []byte(b.String()), nil
+}
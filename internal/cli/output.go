@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/cbout22/copilot-sync/internal/ui"
+)
+
+// printf prints a message on standard output, like fmt.Printf. In plain
+// mode, the symbols of format and of error arguments are spelled in ASCII;
+// other args, such as names and file contents, are printed as they are.
+func printf(format string, args ...any) {
+	fprintf(os.Stdout, format, args...)
+}
+
+// fprintf is printf printing to w.
+func fprintf(w io.Writer, format string, args ...any) {
+	if ui.Plain() {
+		args = slices.Clone(args)
+		for i, arg := range args {
+			if err, ok := arg.(error); ok {
+				args[i] = ui.Text(err.Error())
+			}
+		}
+	}
+	fmt.Fprintf(w, ui.Text(format), args...)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// promoteOptions holds the flags accepted by the promote command.
+type promoteOptions struct {
+	Branch string // branch the asset is committed to; default: cops/promote/<type>/<name>
+	Title  string // pull request title
+	Body   string // pull request description
+}
+
+// newPromoteCmd creates the `promote` command.
+// Usage: cops promote <type>/<name> <org/repo[/path][@ref]> [--branch <name>] [--title <title>] [--body <text>]
+func newPromoteCmd() *cobra.Command {
+	var opts promoteOptions
+
+	cmd := &cobra.Command{
+		Use:   "promote <type>/<name> <org/repo[/path][@ref]>",
+		Short: "Move a local asset to a shared repository and manage it from there",
+		Long: `Turns an asset authored in the project (a "local:" entry, see 'cops new')
+into one managed from a shared repository, in two steps:
+
+1. The asset is committed to a branch of the repository, at the given path
+   or at <type>/<file name> by default, and a pull request is opened.
+2. Once the pull request is merged, running the same command again finds
+   the asset there and rewrites the copilot.toml entry to the remote ref,
+   org/repo/path@ref, then syncs it.
+
+The ref defaults to "latest", the repository's default branch, which the
+pull request targets unless another branch is given.
+
+Example:
+  cops promote instructions/go-style myorg/copilot-assets@main
+  cops promote skills/k8s myorg/copilot-assets/skills/kubernetes`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return resolveEntryID(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := httpClient()
+			if err != nil {
+				return err
+			}
+			pub := resolver.NewPublisher(resolver.WithContext(client, cmd.Context()))
+			res, err := newResolver(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return withProjectLock(lockFile(), func() error {
+				return runPromoteWith(opts, args[0], args[1], manifestFile(), lockFile(), pub, res, ".")
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Branch, "branch", "", "Branch to commit to (default: cops/promote/<type>/<name>)")
+	cmd.Flags().StringVar(&opts.Title, "title", "", "Pull request title")
+	cmd.Flags().StringVar(&opts.Body, "body", "", "Pull request description")
+
+	return cmd
+}
+
+// runPromoteWith is the testable core of the promote command.
+func runPromoteWith(opts promoteOptions, id, dest, manifestPath, lockPath string, pub publisher, res resolver.ResolverAPI, rootDir string) error {
+	typeName, name, ok := strings.Cut(id, "/")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid entry %q: use <type>/<name>, e.g. instructions/go-style", id)
+	}
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	section, err := m.Section(typeName)
+	if err != nil {
+		return err
+	}
+	raw, ok := section[name]
+	if !ok {
+		return fmt.Errorf("%s is not in copilot.toml", id)
+	}
+	if ref, err := config.ParseRef(raw); err != nil || !ref.IsLocal() {
+		return fmt.Errorf("%s is not a local entry (%s): use 'cops publish' to propose changes to it", id, raw)
+	}
+
+	remote, err := promotedRef(dest, typeName, m.TargetPath(typeName, name))
+	if err != nil {
+		return err
+	}
+
+	if opts.Branch == "" {
+		opts.Branch = "cops/promote/" + typeName + "/" + name
+	}
+	if opts.Title == "" {
+		opts.Title = fmt.Sprintf("Add %s/%s", typeName, name)
+	}
+	if opts.Body == "" {
+		opts.Body = fmt.Sprintf("Adds `%s`, authored in a project, to share it as `%s`.", id, remote)
+	}
+	url, err := publishAsset(publishOptions{To: remote, Branch: opts.Branch, Title: opts.Title, Body: opts.Body}, id, manifestPath, lockPath, pub, rootDir)
+	if err != nil {
+		return err
+	}
+	if url != "" {
+		printf("⏳ Once it is merged, run 'cops promote %s %s' again to switch the entry to %s.\n", id, dest, remote)
+		return nil
+	}
+
+	// The repository has the asset: the entry is managed from there on.
+	printf("🔁 Switching %s from %s to %s...\n", id, raw, remote)
+	return runUseWith(typeName, name, remote, manifestPath, lockPath, res, rootDir)
+}
+
+// promotedRef returns the GitHub ref an asset written at target is
+// promoted to, from dest, "org/repo[/path][@ref]". The path defaults to
+// <type>/<file or directory name of target> and the ref to "latest".
+func promotedRef(dest, typeName, target string) (string, error) {
+	repo, ref, _ := strings.Cut(dest, "@")
+	if ref == "" {
+		ref = "latest"
+	}
+	if strings.Count(repo, "/") == 1 {
+		repo += "/" + path.Join(typeName, filepath.Base(target))
+	}
+	remote := repo + "@" + ref
+	if parsed, err := config.ParseRef(remote); err != nil || !parsed.IsGitHub() {
+		return "", fmt.Errorf("invalid destination %q: must be org/repo[/path][@ref]", dest)
+	}
+	return remote, nil
+}
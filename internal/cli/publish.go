@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// publishOptions holds the flags accepted by the publish command.
+type publishOptions struct {
+	To     string // "org/repo/path[@branch]" to publish to; default: the entry's ref
+	Branch string // branch the change is committed to; default: cops/publish/<type>/<name>
+	Base   string // branch the pull request targets; default: the ref's branch, or the default branch
+	Title  string // pull request title
+	Body   string // pull request description
+}
+
+// publisher commits assets to GitHub repositories and opens pull requests
+// for them; *resolver.Publisher implements it.
+type publisher interface {
+	DefaultBranch(repo string) (string, error)
+	Publish(pub resolver.Publication) (string, error)
+	OpenPullRequest(repo string, pr resolver.PullRequest) (string, error)
+}
+
+// newPublishCmd creates the `publish` command.
+// Usage: cops publish <type>/<name> [--to <org/repo/path[@branch]>] [--branch <name>] [--base <branch>] [--title <title>] [--body <text>]
+func newPublishCmd() *cobra.Command {
+	var opts publishOptions
+
+	cmd := &cobra.Command{
+		Use:   "publish <type>/<name>",
+		Short: "Propose a local asset to its source repository in a pull request",
+		Long: `Sends the local copy of an asset upstream: its files are committed to a
+branch of the GitHub repository it comes from, in a single commit, and a
+pull request is opened, so improvements made in a project flow back to the
+team's shared assets.
+
+The asset is published where its ref points (repository and path), unless
+--to names another location: this also publishes assets not in
+copilot.toml yet, e.g. 'cops publish prompts/review --to
+myorg/assets/prompts/review.md'. The pull request targets the ref's branch,
+or the repository's default branch for tags and commits (--base overrides
+it). Files of a skill that were synced but deleted locally are deleted
+upstream too.
+
+The branch, cops/publish/<type>/<name> by default, is reset on every
+publication, and a pull request already open for it is updated. Files are
+published as they are on disk: changes made by frontmatter, template
+variables or transform options are published too.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return resolveEntryID(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := httpClient()
+			if err != nil {
+				return err
+			}
+			pub := resolver.NewPublisher(resolver.WithContext(client, cmd.Context()))
+			return runPublishWith(opts, args[0], manifestFile(), lockFile(), pub, ".")
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.To, "to", "", "Where to publish, as org/repo/path[@branch] (default: the entry's ref)")
+	cmd.Flags().StringVar(&opts.Branch, "branch", "", "Branch to commit to (default: cops/publish/<type>/<name>)")
+	cmd.Flags().StringVar(&opts.Base, "base", "", "Branch the pull request targets (default: the ref's branch or the default branch)")
+	cmd.Flags().StringVar(&opts.Title, "title", "", "Pull request title")
+	cmd.Flags().StringVar(&opts.Body, "body", "", "Pull request description")
+
+	return cmd
+}
+
+// runPublishWith is the testable core of the publish command.
+func runPublishWith(opts publishOptions, id, manifestPath, lockPath string, pub publisher, rootDir string) error {
+	_, err := publishAsset(opts, id, manifestPath, lockPath, pub, rootDir)
+	return err
+}
+
+// publishAsset publishes the local copy of the asset id as the publish
+// command does. It returns the URL of the pull request, or "" if the
+// repository already has the content.
+func publishAsset(opts publishOptions, id, manifestPath, lockPath string, pub publisher, rootDir string) (string, error) {
+	typeName, name, ok := strings.Cut(id, "/")
+	if !ok || name == "" {
+		return "", fmt.Errorf("invalid entry %q: use <type>/<name>, e.g. prompts/review", id)
+	}
+	assetType := config.AssetType(typeName)
+	if !assetType.IsValid() {
+		return "", fmt.Errorf("invalid asset type: %s", typeName)
+	}
+
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return "", fmt.Errorf("loading manifest: %w", err)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return "", fmt.Errorf("loading lock file: %w", err)
+	}
+	var entry *manifest.Entry
+	for _, e := range m.AllEntries() {
+		if e.Type == typeName && e.Name == name {
+			entry = &e
+			break
+		}
+	}
+
+	to := opts.To
+	if to == "" {
+		if entry == nil {
+			return "", fmt.Errorf("%s is not in copilot.toml: pass --to <org/repo/path> to publish it", id)
+		}
+		to = entry.Ref
+	} else if !strings.Contains(to, "@") {
+		to += "@latest"
+	}
+	ref, err := config.ParseRef(to)
+	if err != nil {
+		return "", err
+	}
+	if !ref.IsGitHub() {
+		return "", fmt.Errorf("can only publish to GitHub repositories, not %s", to)
+	}
+	repo := ref.RepoFullName()
+
+	// The local copy is where it was last synced, or where it would be.
+	locked, isLocked := lock.Get(typeName, name)
+	target := m.TargetPath(typeName, name)
+	if isLocked {
+		target = locked.TargetPath
+	}
+	if entry != nil && (len(entry.Options.Frontmatter) > 0 || entry.Options.Transform != "" || len(m.Vars) > 0) {
+		printf("⚠️  %s is rewritten on sync (frontmatter, template variables or transform): those changes are published too\n", id)
+	}
+
+	publication := resolver.Publication{
+		Repo:    repo,
+		Base:    opts.Base,
+		Branch:  opts.Branch,
+		Message: opts.Title,
+		Files:   make(map[string]resolver.PublishedFile),
+	}
+	if assetType.IsDirectory() {
+		files, modes, err := localFiles(filepath.Join(rootDir, target))
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", target, err)
+		}
+		for rel, content := range files {
+			publication.Files[path.Join(ref.Path, rel)] = resolver.PublishedFile{Content: content, Executable: modes[rel]&0o111 != 0}
+		}
+		// Only files cops synced are deleted: the rest of the upstream
+		// directory may be filtered out locally.
+		for _, rel := range manifest.SortedKeys(locked.Files) {
+			if _, ok := files[rel]; !ok {
+				publication.Delete = append(publication.Delete, path.Join(ref.Path, rel))
+			}
+		}
+	} else {
+		content, err := os.ReadFile(filepath.Join(rootDir, target))
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", target, err)
+		}
+		publication.Files[ref.Path] = resolver.PublishedFile{Content: content, Executable: localMode(filepath.Join(rootDir, target)) == "0755"}
+	}
+
+	if publication.Base == "" {
+		if !ref.IsPinned() && ref.Ref != "latest" {
+			publication.Base = ref.Ref
+		} else if publication.Base, err = pub.DefaultBranch(repo); err != nil {
+			return "", err
+		}
+	}
+	if publication.Branch == "" {
+		publication.Branch = "cops/publish/" + typeName + "/" + name
+	}
+	if publication.Message == "" {
+		publication.Message = fmt.Sprintf("Update %s/%s", typeName, name)
+	}
+
+	printf("📤 Publishing %s (%d file(s)) to %s/%s...\n", target, len(publication.Files), repo, ref.Path)
+	sha, err := pub.Publish(publication)
+	if err != nil {
+		return "", err
+	}
+	if sha == "" {
+		printf("✅ %s already has this content on %s — nothing to publish.\n", repo, publication.Base)
+		return "", nil
+	}
+
+	body := opts.Body
+	if body == "" {
+		body = fmt.Sprintf("Updates `%s` with the copy of `%s/%s` from a project using it.", ref.Path, typeName, name)
+	}
+	var changed strings.Builder
+	for _, p := range manifest.SortedKeys(publication.Files) {
+		fmt.Fprintf(&changed, "- `%s`\n", p)
+	}
+	for _, p := range publication.Delete {
+		fmt.Fprintf(&changed, "- `%s` (deleted)\n", p)
+	}
+	body += "\n\n" + changed.String() + "\n---\nOpened by `cops publish`.\n"
+
+	url, err := pub.OpenPullRequest(repo, resolver.PullRequest{
+		Title: publication.Message,
+		Body:  body,
+		Head:  publication.Branch,
+		Base:  publication.Base,
+	})
+	if err != nil {
+		return "", err
+	}
+	printf("🔀 Pull request: %s\n", url)
+	return url, nil
+}
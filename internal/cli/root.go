@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/auth"
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/ui"
 )
 
 // version is set at build time via -ldflags.
@@ -15,32 +27,256 @@ func NewRootCmd() *cobra.Command {
 	root := &cobra.Command{
 		Use:   "cops",
 		Short: "Copilot Sync — the deterministic package manager for your copilot AI agent files",
-		Long: `cops manages your GitHub Copilot assets (instructions, agents, prompts, skills)
-through a copilot.toml manifest. Pin your team's practices to a specific release
-tag, branch, or commit hash and sync them across projects.`,
+		Long: `cops manages your GitHub Copilot assets (instructions, agents, prompts,
+chat modes, skills) through a copilot.toml manifest. Pin your team's practices
+to a specific release tag, branch, or commit hash and sync them across projects.`,
 		Version:       version,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
 
-	// Register type subcommands (instructions, agents, prompts, skills)
+	// Messages are spelled in plain ASCII, instead of emoji and symbols,
+	// when printed to a file or pipe (as in CI logs) or with NO_COLOR set,
+	// unless --no-emoji says otherwise.
+	var noEmoji bool
+	root.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Print plain ASCII instead of emoji (default when output is not a terminal or NO_COLOR is set)")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyUserConfig(cmd); err != nil {
+			return err
+		}
+		plain := ui.PlainByDefault(os.Stdout)
+		if cmd.Flags().Changed("no-emoji") {
+			plain = noEmoji
+		}
+		ui.SetPlain(plain)
+		return enterProjectRoot()
+	}
+
+	// Register type subcommands (instructions, agents, prompts, chatmodes, skills, root-instructions, agents-md, setup-steps)
 	root.AddCommand(newTypeCmd("instructions", "Manage instruction files"))
 	root.AddCommand(newTypeCmd("agents", "Manage agent files"))
 	root.AddCommand(newTypeCmd("prompts", "Manage prompt files"))
+	root.AddCommand(newTypeCmd("chatmodes", "Manage chat mode files"))
 	root.AddCommand(newTypeCmd("skills", "Manage skill directories"))
+	root.AddCommand(newTypeCmd("root-instructions", "Manage the repository-wide copilot-instructions.md"))
+	root.AddCommand(newTypeCmd("agents-md", "Manage the AGENTS.md at the root of the project"))
+	root.AddCommand(newTypeCmd("setup-steps", "Manage the setup steps workflow of the Copilot coding agent"))
 
 	// Register top-level commands
 	root.AddCommand(newSyncCmd())
+	root.AddCommand(newUpdateCmd())
+	root.AddCommand(newWatchCmd())
+	root.AddCommand(newPublishCmd())
+	root.AddCommand(newNewCmd())
+	root.AddCommand(newPromoteCmd())
 	root.AddCommand(newCheckCmd())
+	root.AddCommand(newStatusCmd())
+	root.AddCommand(newDiffCmd())
+	root.AddCommand(newValidateCmd())
+	root.AddCommand(newVerifyCmd())
+	root.AddCommand(newAuditCmd())
+	root.AddCommand(newSbomCmd())
+	root.AddCommand(newListCmd())
+	root.AddCommand(newInfoCmd())
+	root.AddCommand(newLockCmd())
+	root.AddCommand(newSnapshotCmd())
+	root.AddCommand(newHooksCmd())
+	root.AddCommand(newMCPServeCmd())
+	root.AddCommand(newLoginCmd())
+	root.AddCommand(newLogoutCmd())
+
+	// Register the types the project declares (see manifest.Manifest.Types)
+	addCustomTypeCmds(root)
 
 	return root
 }
 
 // Execute runs the root command.
 func Execute() {
+	// Ctrl-C cancels the context commands run with, so downloads in
+	// flight stop and no asset is left half-written. A second Ctrl-C
+	// kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
 	root := NewRootCmd()
-	if err := root.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	if err := root.ExecuteContext(ctx); err != nil {
+		fprintf(os.Stderr, "Error: %s\n", err)
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
+
+// workDir is the directory cops was started in, relative to the project
+// root it runs from (see enterProjectRoot).
+var workDir = "."
+
+// enterProjectRoot changes to the root of the project the working
+// directory is in (see manifest.FindRoot), so that commands run from a
+// subdirectory act on the whole project, as git does. With COPS_MANIFEST
+// set, the working directory is the project root.
+func enterProjectRoot() error {
+	if os.Getenv(manifestEnvVar) != "" {
+		workDir = "."
+		return nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root := manifest.FindRoot(wd)
+	if root == wd {
+		workDir = "."
+		return nil
+	}
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("changing to the project root: %w", err)
+	}
+	if workDir, err = filepath.Rel(root, wd); err != nil {
+		workDir = wd
+	}
+	return nil
+}
+
+// userPath returns where path, given on the command line relative to the
+// directory cops was started in, is from the project root.
+func userPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workDir, path)
+}
+
+// manifestEnv returns the manifest overlay to apply: the --env flag value
+// if given, COPS_ENV otherwise.
+func manifestEnv(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(manifest.EnvVar)
+}
+
+// globalManifest returns the user-level manifest to merge beneath the
+// project's, or "" when disabled with --no-global.
+func globalManifest(noGlobal bool) string {
+	if noGlobal {
+		return ""
+	}
+	return manifest.GlobalManifestPath()
+}
+
+// fetchTemplate returns a manifest.Fetcher downloading extends templates
+// through res.
+func fetchTemplate(res resolver.ResolverAPI) manifest.Fetcher {
+	return func(ref string) ([]byte, error) {
+		parsed, err := config.ParseRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		return res.DownloadFile(parsed)
+	}
+}
+
+// lazyFetchTemplate is fetchTemplate for commands that otherwise work
+// offline: the resolver is only built if the manifest extends a template.
+func lazyFetchTemplate(ctx context.Context) manifest.Fetcher {
+	return func(ref string) ([]byte, error) {
+		res, err := newResolver(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return fetchTemplate(res)(ref)
+	}
+}
+
+// newResolver builds the resolver used by commands that download assets.
+// URL, OCI, bucket, registry-index and mirror requests get a plain client so
+// GitHub credentials never leak to third-party hosts. Registry packages resolve
+// through the other sources. Every request is cancelled when ctx is done.
+// With COPS_NO_NETWORK set, every request fails with errNoNetwork. Source
+// plugins found in the plugin directory serve their "plugin:" refs.
+func newResolver(ctx context.Context) (resolver.ResolverAPI, error) {
+	plugins, err := resolver.DiscoverPlugins(ctx, resolver.DefaultPluginDir())
+	if err != nil {
+		return nil, err
+	}
+	if noNetwork() {
+		offline := &http.Client{Transport: offlineTransport{}}
+		return newRouter(offline, offline, "", nil, plugins...), nil
+	}
+	// The token is resolved once, for GitHub and OCI pulls alike: a token
+	// command runs a single time. A missing token is fine: requests are
+	// anonymous.
+	token, err := auth.Token()
+	if err != nil && !errors.Is(err, auth.ErrNoToken) {
+		return nil, err
+	}
+	timeout, err := auth.RequestTimeout()
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.NewTokenClient(token, timeout)
+	if err != nil {
+		return nil, err
+	}
+	// Unchanged GitHub responses are revalidated instead of downloaded.
+	client = resolver.WithHTTPCache(client, httpCacheDir())
+	// Transient failures are retried, so one flaky request does not fail
+	// a whole sync.
+	retries, err := resolver.RetriesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	client = resolver.WithRetries(client, retries)
+	// An exhausted GitHub rate limit fails with its reset time, or is
+	// waited out.
+	rateLimit, err := resolver.RateLimitModeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	client = resolver.WithContext(resolver.WithRateLimit(client, rateLimit), ctx)
+	// Requests to other hosts share the proxy and CA settings, not the
+	// token.
+	transport, err := auth.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	// Hosts listed in the user configuration get their own token.
+	hostTransport, err := auth.NewHostTransport(transport)
+	if err != nil {
+		return nil, err
+	}
+	plain := resolver.WithContext(resolver.WithRetries(&http.Client{Transport: hostTransport, Timeout: timeout}, retries), ctx)
+	mirrors, err := resolver.ParseMirrors(os.Getenv(resolver.MirrorsEnvVar))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", resolver.MirrorsEnvVar, err)
+	}
+	for i, m := range mirrors {
+		if m.Username == "" && m.Password == "" {
+			mirrors[i].Username, mirrors[i].Password, _ = auth.NetrcCredentials(m.Host())
+		}
+	}
+	return newRouter(client, plain, token, mirrors, plugins...), nil
+}
+
+// newRouter returns the sources of newResolver: client serves GitHub, plain
+// every other host, token authenticates OCI pulls and plugins serve their
+// own refs.
+func newRouter(client, plain *http.Client, token string, mirrors []resolver.Mirror, plugins ...*resolver.PluginSource) resolver.ResolverAPI {
+	sources := []resolver.SourceRepository{
+		resolver.NewLocalSource("."),
+		resolver.NewURLSource(plain),
+		resolver.NewOCISource(plain, token),
+		resolver.NewBucketSource(plain, resolver.BucketOptionsFromEnv()),
+		resolver.NewReleaseSource(client),
+		resolver.New(client).WithMirrors(plain, mirrors...),
+	}
+	for _, p := range plugins {
+		sources = append(sources, p)
+	}
+	registry := resolver.NewRegistrySource(plain, os.Getenv(resolver.RegistryIndexEnvVar), resolver.NewRouter(sources...))
+	return resolver.NewRouter(append([]resolver.SourceRepository{registry}, sources...)...)
+}
@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// SBOM formats accepted by `cops sbom --format`.
+const (
+	sbomCycloneDX = "cyclonedx"
+	sbomSPDX      = "spdx"
+)
+
+// sbomOptions holds the flags accepted by the sbom command.
+type sbomOptions struct {
+	Format string // sbomCycloneDX or sbomSPDX
+	Output string // file to write; empty writes to stdout
+
+	// Now is the creation time recorded in the document.
+	Now time.Time
+}
+
+// newSbomCmd creates the `sbom` command.
+// Usage: cops sbom [--format cyclonedx|spdx] [--output <file>]
+func newSbomCmd() *cobra.Command {
+	var opts sbomOptions
+
+	cmd := &cobra.Command{
+		Use:   "sbom",
+		Short: "Export a software bill of materials of the managed assets",
+		Long: `Writes a CycloneDX 1.5 or SPDX 2.3 JSON document listing every asset
+recorded in .cops.lock, with its source, ref, resolved commit or digest and
+SHA-256 checksum, so the provenance of AI assets can flow into existing
+supply-chain tooling. Like verify, it reads the lock file only.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Now = time.Now()
+			opts.Output = userPath(opts.Output)
+			return runSbomWith(opts, lockFile(), ".")
+		},
+	}
+	cmd.Flags().StringVar(&opts.Format, "format", sbomCycloneDX, "Document format: cyclonedx or spdx")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write the document to this file instead of stdout")
+	return cmd
+}
+
+// runSbomWith is the testable core of the sbom command.
+func runSbomWith(opts sbomOptions, lockPath, rootDir string) error {
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	project := "project"
+	if abs, err := filepath.Abs(rootDir); err == nil {
+		project = filepath.Base(abs)
+	}
+
+	var doc any
+	switch opts.Format {
+	case sbomCycloneDX, "":
+		doc = cycloneDXDocument(lock, project, opts.Now)
+	case sbomSPDX:
+		doc = spdxDocument(lock, project, opts.Now)
+	default:
+		return fmt.Errorf("invalid format %q: must be %s or %s", opts.Format, sbomCycloneDX, sbomSPDX)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if opts.Output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(opts.Output, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", opts.Output, err)
+	}
+	fprintf(os.Stderr, "📄 Wrote %d asset(s) to %s\n", len(lock.Entries), opts.Output)
+	return nil
+}
+
+// sbomAsset is what both formats record of a lock entry.
+type sbomAsset struct {
+	ID       string // "<type>/<name>"
+	Entry    manifest.LockEntry
+	Version  string // tag, branch, digest or object version the ref names
+	Source   string // where the asset comes from: a repository, registry or URL
+	PURL     string // package URL, empty for sources without a purl type
+	Checksum string // SHA-256 of the content, hex-encoded
+}
+
+// sbomAssets returns the entries of lock in byte-wise order.
+func sbomAssets(lock *manifest.LockFile) []sbomAsset {
+	var assets []sbomAsset
+	for _, key := range manifest.SortedKeys(lock.Entries) {
+		e := lock.Entries[key]
+		a := sbomAsset{ID: key, Entry: e, Checksum: e.Checksum, Source: e.Ref}
+		if ref, err := config.ParseRef(e.Ref); err == nil {
+			a.Version, a.Source, a.PURL = ref.Ref, sbomSource(ref), packageURL(ref, e)
+		}
+		assets = append(assets, a)
+	}
+	return assets
+}
+
+// sbomSource returns where ref is downloaded from.
+func sbomSource(ref config.AssetRef) string {
+	switch {
+	case ref.IsURL():
+		return ref.URL
+	case ref.IsOCI():
+		return ref.Registry + "/" + ref.Repo
+	case ref.IsBucket():
+		return ref.Store + "://" + ref.Bucket + "/" + ref.Path
+	case ref.IsRegistry():
+		return ref.RepoFullName()
+	case ref.IsPlugin():
+		return ref.RepoFullName() + "/" + ref.Path
+	default:
+		return "https://github.com/" + ref.RepoFullName()
+	}
+}
+
+// packageURL returns the package URL (https://github.com/package-url/purl-spec)
+// of the asset ref names, at the commit or digest locked, or "" for
+// sources no purl type describes.
+func packageURL(ref config.AssetRef, e manifest.LockEntry) string {
+	version := ref.Ref
+	if e.ResolvedSHA != "" && e.ResolvedSHA != injector.UnknownSHA {
+		version = e.ResolvedSHA
+	}
+	switch {
+	case ref.IsGitHub():
+		purl := fmt.Sprintf("pkg:github/%s/%s@%s", strings.ToLower(ref.Org), strings.ToLower(ref.Repo), purlEscape(version))
+		if ref.Path != "" {
+			purl += "#" + ref.Path
+		}
+		return purl
+	case ref.IsRelease():
+		return fmt.Sprintf("pkg:github/%s/%s@%s#%s", strings.ToLower(ref.Org), strings.ToLower(ref.Repo), purlEscape(ref.Ref), ref.ReleaseAsset)
+	case ref.IsOCI():
+		q := url.Values{"repository_url": {ref.Registry + "/" + ref.Repo}}
+		return fmt.Sprintf("pkg:oci/%s@%s?%s", path.Base(ref.Repo), purlEscape(version), q.Encode())
+	case ref.IsURL():
+		q := url.Values{"download_url": {ref.URL}, "checksum": {"sha256:" + e.Checksum}}
+		return fmt.Sprintf("pkg:generic/%s?%s", purlEscape(path.Base(ref.URL)), q.Encode())
+	default:
+		return ""
+	}
+}
+
+// purlEscape percent-encodes a package URL name or version, colons
+// included.
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+}
+
+// CycloneDX 1.5 JSON document, limited to the fields cops fills in.
+type (
+	cdxDocument struct {
+		BOMFormat   string         `json:"bomFormat"`
+		SpecVersion string         `json:"specVersion"`
+		Version     int            `json:"version"`
+		Metadata    cdxMetadata    `json:"metadata"`
+		Components  []cdxComponent `json:"components"`
+	}
+	cdxMetadata struct {
+		Timestamp string       `json:"timestamp"`
+		Tools     cdxTools     `json:"tools"`
+		Component cdxComponent `json:"component"`
+	}
+	cdxTools struct {
+		Components []cdxComponent `json:"components"`
+	}
+	cdxComponent struct {
+		Type               string        `json:"type"`
+		BOMRef             string        `json:"bom-ref,omitempty"`
+		Group              string        `json:"group,omitempty"`
+		Name               string        `json:"name"`
+		Version            string        `json:"version,omitempty"`
+		Hashes             []cdxHash     `json:"hashes,omitempty"`
+		Licenses           []cdxLicense  `json:"licenses,omitempty"`
+		PURL               string        `json:"purl,omitempty"`
+		ExternalReferences []cdxExtRef   `json:"externalReferences,omitempty"`
+		Properties         []cdxProperty `json:"properties,omitempty"`
+	}
+	cdxHash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+	cdxLicense struct {
+		License struct {
+			ID string `json:"id"`
+		} `json:"license"`
+	}
+	cdxExtRef struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}
+	cdxProperty struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+)
+
+// cycloneDXDocument describes the assets of lock as file components of
+// project.
+func cycloneDXDocument(lock *manifest.LockFile, project string, now time.Time) cdxDocument {
+	doc := cdxDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "cops", Version: version}}},
+			Component: cdxComponent{Type: "application", Name: project},
+		},
+		Components: []cdxComponent{},
+	}
+	for _, a := range sbomAssets(lock) {
+		c := cdxComponent{
+			Type:    "file",
+			BOMRef:  a.ID,
+			Group:   a.Entry.Type,
+			Name:    a.Entry.Name,
+			Version: a.Version,
+			PURL:    a.PURL,
+			Properties: []cdxProperty{
+				{Name: "cops:ref", Value: a.Entry.Ref},
+				{Name: "cops:resolved_sha", Value: a.Entry.ResolvedSHA},
+				{Name: "cops:target_path", Value: a.Entry.TargetPath},
+			},
+		}
+		if a.Checksum != "" {
+			c.Hashes = []cdxHash{{Alg: "SHA-256", Content: a.Checksum}}
+		}
+		if a.Entry.License != "" && a.Entry.License != resolver.NoLicense && a.Entry.License != resolver.UnknownLicense {
+			var l cdxLicense
+			l.License.ID = a.Entry.License
+			c.Licenses = []cdxLicense{l}
+		}
+		if strings.Contains(a.Source, "://") {
+			refType := "distribution"
+			if strings.HasPrefix(a.Source, "https://github.com/") {
+				refType = "vcs"
+			}
+			c.ExternalReferences = []cdxExtRef{{Type: refType, URL: a.Source}}
+		}
+		if a.Entry.Signature != "" {
+			c.Properties = append(c.Properties, cdxProperty{Name: "cops:signature", Value: a.Entry.Signature})
+		}
+		doc.Components = append(doc.Components, c)
+	}
+	return doc
+}
+
+// SPDX 2.3 JSON document, limited to the fields cops fills in.
+type (
+	spdxDoc struct {
+		SPDXVersion       string             `json:"spdxVersion"`
+		DataLicense       string             `json:"dataLicense"`
+		SPDXID            string             `json:"SPDXID"`
+		Name              string             `json:"name"`
+		DocumentNamespace string             `json:"documentNamespace"`
+		CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+		Packages          []spdxPackage      `json:"packages"`
+		Relationships     []spdxRelationship `json:"relationships"`
+	}
+	spdxCreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	}
+	spdxPackage struct {
+		SPDXID           string         `json:"SPDXID"`
+		Name             string         `json:"name"`
+		VersionInfo      string         `json:"versionInfo,omitempty"`
+		DownloadLocation string         `json:"downloadLocation"`
+		FilesAnalyzed    bool           `json:"filesAnalyzed"`
+		LicenseDeclared  string         `json:"licenseDeclared,omitempty"`
+		SourceInfo       string         `json:"sourceInfo,omitempty"`
+		Checksums        []spdxChecksum `json:"checksums,omitempty"`
+		ExternalRefs     []spdxExtRef   `json:"externalRefs,omitempty"`
+	}
+	spdxChecksum struct {
+		Algorithm     string `json:"algorithm"`
+		ChecksumValue string `json:"checksumValue"`
+	}
+	spdxExtRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+	spdxRelationship struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelationshipType   string `json:"relationshipType"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+	}
+)
+
+// spdxDocument describes the assets of lock as packages of project. The
+// document namespace is derived from the assets, so the same lock file
+// always yields the same namespace.
+func spdxDocument(lock *manifest.LockFile, project string, now time.Time) spdxDoc {
+	assets := sbomAssets(lock)
+	h := sha256.New()
+	for _, a := range assets {
+		fmt.Fprintf(h, "%s %s %s\n", a.ID, a.Entry.Ref, a.Checksum)
+	}
+	doc := spdxDoc{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              project + " Copilot assets",
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/cops-%s-%x", url.PathEscape(project), h.Sum(nil)[:8]),
+		CreationInfo: spdxCreationInfo{
+			Created:  now.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: cops-" + version},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	for _, a := range assets {
+		p := spdxPackage{
+			SPDXID:           "SPDXRef-" + spdxIDPart(a.ID),
+			Name:             a.ID,
+			VersionInfo:      a.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseDeclared:  a.Entry.License,
+			SourceInfo:       fmt.Sprintf("%s, resolved to %s, installed at %s", a.Entry.Ref, a.Entry.ResolvedSHA, a.Entry.TargetPath),
+		}
+		if strings.Contains(a.Source, "://") {
+			p.DownloadLocation = a.Source
+		}
+		if a.Checksum != "" {
+			p.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: a.Checksum}}
+		}
+		if a.PURL != "" {
+			p.ExternalRefs = []spdxExtRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: a.PURL}}
+		}
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: p.SPDXID})
+	}
+	return doc
+}
+
+// spdxIDPart replaces the characters SPDX identifiers may not contain.
+func spdxIDPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, s)
+}
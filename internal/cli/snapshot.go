@@ -0,0 +1,450 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// snapshotFolder is the folder, relative to the project root, holding one
+// folder per snapshot.
+const snapshotFolder = ".cops-snapshots"
+
+// snapshotInfoFile describes a snapshot, inside its folder.
+const snapshotInfoFile = "snapshot.json"
+
+// snapshotNamePattern matches snapshot names, which name their folder.
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// snapshotInfo is the content of snapshot.json. The folder also holds a
+// copy of the manifest, under its own file name, and of the lock file.
+type snapshotInfo struct {
+	Name      string `json:"name"`
+	Message   string `json:"message,omitempty"`
+	CreatedAt string `json:"created_at"` // RFC 3339
+	Manifest  string `json:"manifest"`   // file name of the manifest copy
+
+	// Digests holds the checksum of each asset on disk when the snapshot
+	// was taken, keyed by "<type>/<name>", with --content.
+	Digests map[string]string `json:"digests,omitempty"`
+}
+
+// newSnapshotCmd creates the `snapshot` command group.
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save, compare and restore named states of copilot.toml and .cops.lock",
+		Long: `Snapshots freeze the manifest and the lock file under a name, such as
+q3-audit, in .cops-snapshots/<name>/. Commit the folder to share the
+snapshot with the team, then compare the project with it or go back to it.`,
+	}
+
+	cmd.AddCommand(newSnapshotCreateCmd())
+	cmd.AddCommand(newSnapshotListCmd())
+	cmd.AddCommand(newSnapshotDiffCmd())
+	cmd.AddCommand(newSnapshotRestoreCmd())
+
+	return cmd
+}
+
+// snapshotCreateOptions holds the flags of the snapshot create command.
+type snapshotCreateOptions struct {
+	Message string // what the snapshot is, e.g. "Q3 audit baseline"
+	Content bool   // record the checksum of each asset on disk
+	Force   bool   // replace a snapshot of the same name
+}
+
+// newSnapshotCreateCmd creates the `snapshot create` subcommand.
+// Usage: cops snapshot create <name> [-m <message>] [--content] [--force]
+func newSnapshotCreateCmd() *cobra.Command {
+	var opts snapshotCreateOptions
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Save copilot.toml and .cops.lock as a named snapshot",
+		Long: `Copies copilot.toml and .cops.lock to .cops-snapshots/<name>/.
+
+With --content, the checksum of every asset as it is on disk is recorded
+too, so 'cops snapshot diff' also reports files edited since, even when
+they were edited before the snapshot was taken.
+
+Example:
+  cops snapshot create q3-audit -m "Q3 audit baseline" --content`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotCreateWith(opts, args[0], manifestFile(), lockFile(), ".", time.Now())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Message, "message", "m", "", "Describe the snapshot, e.g. \"Q3 audit baseline\"")
+	cmd.Flags().BoolVar(&opts.Content, "content", false, "Record the checksum of every asset on disk")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace the snapshot if it exists")
+
+	return cmd
+}
+
+// runSnapshotCreateWith is the testable core of the snapshot create
+// command.
+func runSnapshotCreateWith(opts snapshotCreateOptions, name, manifestPath, lockPath, rootDir string, now time.Time) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-', e.g. q3-audit", name)
+	}
+	dir := filepath.Join(rootDir, snapshotFolder, name)
+	if _, err := os.Stat(dir); err == nil && !opts.Force {
+		return fmt.Errorf("snapshot %s already exists (use --force to replace it)", name)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		return fmt.Errorf("nothing to snapshot: %s not found; run 'cops sync' first", lockPath)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	info := snapshotInfo{
+		Name:      name,
+		Message:   opts.Message,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		Manifest:  filepath.Base(manifestPath),
+	}
+	if opts.Content {
+		info.Digests = make(map[string]string, len(lock.Entries))
+		for key, e := range lock.Entries {
+			// Missing assets have no digest, and show as such in a diff.
+			if cs, err := localChecksum(filepath.Join(rootDir, e.TargetPath), config.AssetType(e.Type).IsDirectory()); err == nil {
+				info.Digests[key] = cs
+			}
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("replacing snapshot %s: %w", name, err)
+	}
+	if err := copyFile(manifestPath, filepath.Join(dir, info.Manifest)); err != nil {
+		return fmt.Errorf("saving %s: %w", manifestPath, err)
+	}
+	if err := copyFile(lockPath, filepath.Join(dir, manifest.DefaultLockFile)); err != nil {
+		return fmt.Errorf("saving %s: %w", lockPath, err)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotInfoFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("saving snapshot %s: %w", name, err)
+	}
+
+	printf("📸 Saved snapshot %s: %d asset(s) in %s\n", name, len(lock.Entries), filepath.Join(snapshotFolder, name))
+	return nil
+}
+
+// newSnapshotListCmd creates the `snapshot list` subcommand.
+// Usage: cops snapshot list
+func newSnapshotListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots of the project",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotListWith(".")
+		},
+	}
+}
+
+// runSnapshotListWith is the testable core of the snapshot list command.
+func runSnapshotListWith(rootDir string) error {
+	names, err := snapshotNames(rootDir)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		printf("📋 No snapshots. Run 'cops snapshot create <name>' to take one.\n")
+		return nil
+	}
+	for _, name := range names {
+		info, lock, err := loadSnapshot(rootDir, name)
+		if err != nil {
+			printf("❌ %s — %v\n", name, err)
+			continue
+		}
+		line := fmt.Sprintf("📸 %s — %s, %d asset(s)", name, info.CreatedAt, len(lock.Entries))
+		if info.Message != "" {
+			line += ": " + info.Message
+		}
+		printf("%s\n", line)
+	}
+	return nil
+}
+
+// newSnapshotDiffCmd creates the `snapshot diff` subcommand.
+// Usage: cops snapshot diff <name>
+func newSnapshotDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <name>",
+		Short: "Show what changed since a snapshot",
+		Long: `Compares the project with a snapshot: the manifest, as a unified diff,
+then each asset added, removed or synced to other content since, from
+.cops.lock. If the snapshot recorded content checksums, assets whose files
+changed on disk are reported too.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeSnapshot(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotDiffWith(args[0], manifestFile(), lockFile(), ".")
+		},
+	}
+}
+
+// runSnapshotDiffWith is the testable core of the snapshot diff command.
+func runSnapshotDiffWith(name, manifestPath, lockPath, rootDir string) error {
+	info, snapLock, err := loadSnapshot(rootDir, name)
+	if err != nil {
+		return err
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	changes := 0
+	snapManifest, err := os.ReadFile(filepath.Join(rootDir, snapshotFolder, name, info.Manifest))
+	if err != nil {
+		return fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	current, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+	if diff := unifiedDiff(name+"/"+info.Manifest, filepath.Base(manifestPath), snapManifest, current); diff != "" {
+		printf("📝 %s\n%s\n", filepath.Base(manifestPath), diff)
+		changes++
+	}
+
+	keys := manifest.SortedKeys(snapLock.Entries)
+	for key := range lock.Entries {
+		if _, ok := snapLock.Entries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if change := lockChange(snapLock.Entries[key], lock.Entries[key]); change != "" {
+			printf("%s\n", change)
+			changes++
+			continue
+		}
+		// Synced the same, the asset may still have been edited by hand.
+		digest, ok := info.Digests[key]
+		if !ok {
+			continue
+		}
+		e := lock.Entries[key]
+		cs, err := localChecksum(filepath.Join(rootDir, e.TargetPath), config.AssetType(e.Type).IsDirectory())
+		if err != nil {
+			printf("❌ %s — missing from %s\n", key, e.TargetPath)
+			changes++
+		} else if cs != digest {
+			printf("✏️  %s — %s changed on disk since the snapshot\n", key, e.TargetPath)
+			changes++
+		}
+	}
+
+	if changes == 0 {
+		printf("✅ No changes since snapshot %s (%s).\n", name, info.CreatedAt)
+	} else {
+		printf("📋 %d change(s) since snapshot %s (%s). Run 'cops snapshot restore %s' to go back to it.\n", changes, name, info.CreatedAt, name)
+	}
+	return nil
+}
+
+// lockChange describes how the lock entry of an asset changed from was to
+// is, or returns "" if it did not. A zero entry is an asset not locked.
+func lockChange(was, is manifest.LockEntry) string {
+	switch {
+	case was.Type == "":
+		return fmt.Sprintf("✨ %s/%s — added (%s)", is.Type, is.Name, is.Ref)
+	case is.Type == "":
+		return fmt.Sprintf("🗑️  %s/%s — removed (was %s)", was.Type, was.Name, was.Ref)
+	case was.Ref != is.Ref:
+		return fmt.Sprintf("⬆️  %s/%s — %s → %s", is.Type, is.Name, was.Ref, is.Ref)
+	case was.ResolvedSHA != is.ResolvedSHA:
+		return fmt.Sprintf("⬆️  %s/%s — %s → %s", is.Type, is.Name, shortSHA(was.ResolvedSHA), shortSHA(is.ResolvedSHA))
+	case was.Checksum != is.Checksum:
+		return fmt.Sprintf("📝 %s/%s — synced with other content", is.Type, is.Name)
+	case was.TargetPath != is.TargetPath:
+		return fmt.Sprintf("📂 %s/%s — moved from %s to %s", is.Type, is.Name, was.TargetPath, is.TargetPath)
+	}
+	return ""
+}
+
+// snapshotRestoreOptions holds the flags of the snapshot restore command.
+type snapshotRestoreOptions struct {
+	Force bool // overwrite or delete assets edited since the last sync
+}
+
+// newSnapshotRestoreCmd creates the `snapshot restore` subcommand.
+// Usage: cops snapshot restore <name> [--force]
+func newSnapshotRestoreCmd() *cobra.Command {
+	var opts snapshotRestoreOptions
+
+	cmd := &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Go back to the manifest, lock file and assets of a snapshot",
+		Long: `Writes back the copilot.toml and .cops.lock of a snapshot, then installs
+every asset exactly as that lock file records it, like 'cops sync --frozen'.
+Assets added since the snapshot are deleted.
+
+Assets edited by hand since the last sync are only overwritten or deleted
+with --force.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeSnapshot(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newResolver(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return withProjectLock(lockFile(), func() error {
+				return runSnapshotRestoreWith(opts, args[0], manifestFile(), lockFile(), res, ".")
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite or delete assets edited since the last sync")
+
+	return cmd
+}
+
+// runSnapshotRestoreWith is the testable core of the snapshot restore
+// command.
+func runSnapshotRestoreWith(opts snapshotRestoreOptions, name, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
+	info, snapLock, err := loadSnapshot(rootDir, name)
+	if err != nil {
+		return err
+	}
+	if info.Manifest != filepath.Base(manifestPath) {
+		return fmt.Errorf("snapshot %s holds %s, but the project's manifest is %s", name, info.Manifest, filepath.Base(manifestPath))
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	// Nothing is written unless every edit the restore discards is allowed.
+	var stale, edited []string
+	for _, key := range manifest.SortedKeys(lock.Entries) {
+		e := lock.Entries[key]
+		if snap, ok := snapLock.Entries[key]; ok && snap.Checksum == e.Checksum && snap.TargetPath == e.TargetPath {
+			continue
+		}
+		stale = append(stale, key)
+		if !authoredInPlace(e.Ref, e.TargetPath) {
+			edited = append(edited, localEdits(manifest.Entry{Type: e.Type, Name: e.Name}, lock, rootDir)...)
+		}
+	}
+	if len(edited) > 0 && !opts.Force {
+		return fmt.Errorf("%d file(s) edited since the last sync would be lost: %v (use --force to restore anyway)", len(edited), edited)
+	}
+
+	// Sync rewrites the assets the snapshot holds; what it no longer
+	// knows about is removed here, from the current lock.
+	for _, key := range stale {
+		e := lock.Entries[key]
+		if err := injector.RemoveOutputs(rootDir, e); err != nil {
+			return err
+		}
+		snap, ok := snapLock.Entries[key]
+		if (ok && snap.TargetPath == e.TargetPath) || authoredInPlace(e.Ref, e.TargetPath) || !filepath.IsLocal(e.TargetPath) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(rootDir, e.TargetPath)); err != nil {
+			return fmt.Errorf("deleting %s: %w", e.TargetPath, err)
+		}
+		printf("  🗑️  %s — not in snapshot %s, deleted %s\n", key, name, e.TargetPath)
+	}
+
+	dir := filepath.Join(rootDir, snapshotFolder, name)
+	if err := copyFile(filepath.Join(dir, info.Manifest), manifestPath); err != nil {
+		return fmt.Errorf("restoring %s: %w", manifestPath, err)
+	}
+	if err := copyFile(filepath.Join(dir, manifest.DefaultLockFile), lockPath); err != nil {
+		return fmt.Errorf("restoring %s: %w", lockPath, err)
+	}
+	printf("🔁 Restored %s and %s from snapshot %s (%s)\n", filepath.Base(manifestPath), filepath.Base(lockPath), name, info.CreatedAt)
+
+	if len(snapLock.Entries) == 0 {
+		return nil
+	}
+	// Edits were checked above, against the lock they were synced with.
+	return runSyncWith(syncOptions{FrozenLockfile: true, Force: true}, manifestPath, lockPath, res, rootDir)
+}
+
+// loadSnapshot reads the description and the lock file of the snapshot
+// name.
+func loadSnapshot(rootDir, name string) (snapshotInfo, *manifest.LockFile, error) {
+	var info snapshotInfo
+	if !snapshotNamePattern.MatchString(name) {
+		return info, nil, fmt.Errorf("invalid snapshot name %q", name)
+	}
+	dir := filepath.Join(rootDir, snapshotFolder, name)
+	data, err := os.ReadFile(filepath.Join(dir, snapshotInfoFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return info, nil, fmt.Errorf("no snapshot named %s (see 'cops snapshot list')", name)
+	}
+	if err != nil {
+		return info, nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	if info.Manifest == "" || info.Manifest != filepath.Base(info.Manifest) {
+		return info, nil, fmt.Errorf("reading snapshot %s: invalid manifest file name %q", name, info.Manifest)
+	}
+	lock, err := manifest.LoadLock(filepath.Join(dir, manifest.DefaultLockFile))
+	if err != nil {
+		return info, nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	return info, lock, nil
+}
+
+// snapshotNames returns the names of the snapshots under rootDir, in
+// byte-wise order.
+func snapshotNames(rootDir string) ([]string, error) {
+	dirs, err := os.ReadDir(filepath.Join(rootDir, snapshotFolder))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	var names []string
+	for _, d := range dirs {
+		if d.IsDir() && snapshotNamePattern.MatchString(d.Name()) {
+			names = append(names, d.Name())
+		}
+	}
+	return names, nil
+}
+
+// completeSnapshot completes the name of a snapshot of the project.
+func completeSnapshot(args []string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, _ := snapshotNames(".")
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
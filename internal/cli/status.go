@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/auth"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+// statusListed is how many assets a status line names before it only
+// counts the others.
+const statusListed = 3
+
+// statusOptions holds the flags and the sources of the status command.
+type statusOptions struct {
+	Env string // manifest overlay to apply (copilot.<env>.toml)
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// Fetch downloads the template named by an extends directive.
+	Fetch manifest.Fetcher
+
+	// Updates looks up the latest commits of floating refs; nil skips the
+	// lookups, as --offline does.
+	Updates resolver.ResolverAPI
+
+	// RateLimit returns the GitHub API rate limit; nil skips it.
+	RateLimit func() (resolver.RateLimitStatus, error)
+
+	// Authenticated says a GitHub token was found.
+	Authenticated bool
+
+	// Cache is the download cache; nil when it is disabled.
+	Cache *store.Store
+}
+
+// newStatusCmd creates the `status` command.
+// Usage: cops status [--offline] [--env <env>] [--no-global]
+func newStatusCmd() *cobra.Command {
+	var opts statusOptions
+	var offline, noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show a health overview of the project's assets",
+		Long: `Summarises the state of the project in a few lines: how many assets
+copilot.toml lists, how many are in sync, modified, missing or drifted
+from the lock file (see 'cops check'), which floating refs have newer
+commits, when the assets were last synced, how much the download cache
+holds, and whether a GitHub token is set and how much of its rate limit
+is left.
+
+With --offline (or COPS_NO_NETWORK), newer commits and the rate limit are
+not looked up.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Env = manifestEnv(opts.Env)
+			opts.GlobalManifest = globalManifest(noGlobal)
+			opts.Fetch = lazyFetchTemplate(cmd.Context())
+			_, err := auth.Token()
+			opts.Authenticated = err == nil
+			if st, err := contentStore(); err == nil && !store.CacheDisabled() {
+				opts.Cache = &st
+			}
+			if !offline && !noNetwork() {
+				res, err := newResolver(cmd.Context())
+				if err != nil {
+					return err
+				}
+				opts.Updates = res
+				client, err := rateLimitClient(opts.Authenticated)
+				if err != nil {
+					return err
+				}
+				client = resolver.WithContext(client, cmd.Context())
+				opts.RateLimit = func() (resolver.RateLimitStatus, error) {
+					return resolver.CurrentRateLimit(client)
+				}
+			}
+			return runStatusWith(opts, manifestFile(), lockFile(), ".", time.Now())
+		},
+	}
+
+	cmd.Flags().BoolVar(&offline, "offline", false, "Do not look up newer commits or the rate limit")
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+
+	return cmd
+}
+
+// rateLimitClient returns the client the rate limit is looked up with:
+// the GitHub client when a token is set, a plain one otherwise, which
+// newResolver already warned about.
+func rateLimitClient(authenticated bool) (*http.Client, error) {
+	if authenticated {
+		return httpClient()
+	}
+	transport, err := auth.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := auth.RequestTimeout()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// runStatusWith is the testable core of the status command.
+func runStatusWith(opts statusOptions, manifestPath, lockPath, rootDir string, now time.Time) error {
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      opts.Fetch,
+	})
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	entries, _ := manifest.MergeMembers(m.AllEntries(), m.LockedMembers(lock))
+
+	var prefetch *updatePrefetcher
+	if opts.Updates != nil {
+		prefetch = startUpdatePrefetch(opts.Updates, entries, lock)
+	}
+
+	var inSync int
+	var modified, missing, drifted, outdated []string
+	for _, entry := range entries {
+		r := checkEntry(m, entry, lock, rootDir, false)
+		id := r.Type + "/" + r.Name
+		switch r.Status {
+		case checkOK:
+			inSync++
+		case checkModified:
+			modified = append(modified, id)
+		case checkNeverSynced, checkMissing:
+			missing = append(missing, id)
+		default:
+			drifted = append(drifted, fmt.Sprintf("%s (%s)", id, r.Status))
+		}
+	}
+	orphaned := orphanedEntries(entries, lock)
+	if prefetch != nil {
+		for _, h := range prefetch.collect(updateHintTimeout) {
+			outdated = append(outdated, h.Type+"/"+h.Name)
+		}
+	}
+
+	printf("📊 %s — %d asset(s), %s\n\n", manifestPath, len(entries), lastSynced(lock, now))
+	printf("  ✅ In sync     %d\n", inSync)
+	printf("  ✏️  Modified    %s\n", countAndNames(modified))
+	printf("  ❌ Missing     %s\n", countAndNames(missing))
+	printf("  ⚠️  Drifted     %s\n", countAndNames(drifted))
+	printf("  🗑️  Orphaned    %s\n", countAndNames(orphaned))
+	if opts.Updates != nil {
+		printf("  ⬆️  Outdated    %s\n", countAndNames(outdated))
+	} else {
+		printf("  ⬆️  Outdated    not looked up (offline)\n")
+	}
+	fmt.Println()
+
+	if opts.Cache == nil {
+		printf("  💾 Cache       disabled\n")
+	} else if files, size, err := opts.Cache.Usage(); err != nil {
+		printf("  💾 Cache       %v\n", err)
+	} else {
+		printf("  💾 Cache       %d file(s), %s in %s\n", files, manifest.FormatSize(size), opts.Cache.Dir)
+	}
+	token := "none (unauthenticated requests are limited to 60 per hour)"
+	if opts.Authenticated {
+		token = "found"
+	}
+	if opts.RateLimit != nil {
+		if limit, err := opts.RateLimit(); err != nil {
+			token += fmt.Sprintf("; rate limit unknown: %v", err)
+		} else {
+			token += fmt.Sprintf("; %d of %d requests left, resets at %s", limit.Remaining, limit.Limit, limit.Reset.Local().Format("15:04"))
+		}
+	}
+	printf("  🔑 Token       %s\n", token)
+
+	fmt.Println()
+	if issues := len(modified) + len(missing) + len(drifted) + len(orphaned); issues > 0 {
+		printf("⚠️  %d asset(s) need attention. Run 'cops check' for details, or 'cops sync' to fix them.\n", issues)
+	} else if len(outdated) > 0 {
+		printf("✅ All assets are in sync. Run 'cops sync' to get the newer commits.\n")
+	} else {
+		printf("✅ All assets are in sync.\n")
+	}
+	return nil
+}
+
+// countAndNames returns the number of ids, followed by the first of them.
+func countAndNames(ids []string) string {
+	if len(ids) == 0 {
+		return "0"
+	}
+	shown := ids[:min(len(ids), statusListed)]
+	s := fmt.Sprintf("%d  %s", len(ids), strings.Join(shown, ", "))
+	if more := len(ids) - len(shown); more > 0 {
+		s += fmt.Sprintf(" and %d more", more)
+	}
+	return s
+}
+
+// lastSynced describes when an asset of lock was last synced.
+func lastSynced(lock *manifest.LockFile, now time.Time) string {
+	var last time.Time
+	for _, e := range lock.Entries {
+		if t, err := time.Parse(time.RFC3339, e.SyncedAt); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	if last.IsZero() {
+		return "never synced"
+	}
+	return fmt.Sprintf("last synced %s (%s)", last.UTC().Format(time.RFC3339), ago(now.Sub(last)))
+}
+
+// ago describes a past duration d in the largest whole unit that fits.
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d h ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%d days ago", int(d/(24*time.Hour)))
+	}
+}
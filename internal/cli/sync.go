@@ -1,87 +1,883 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/cbout22/copilot-sync/internal/auth"
 	"github.com/cbout22/copilot-sync/internal/config"
 	"github.com/cbout22/copilot-sync/internal/injector"
 	"github.com/cbout22/copilot-sync/internal/manifest"
 	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
+	"github.com/cbout22/copilot-sync/internal/ui"
 )
 
+// syncOptions holds the flags accepted by the sync command.
+type syncOptions struct {
+	Groups    []string // only sync entries tagged with one of these groups
+	Entries   []string // only sync these "<type>/<name>" entries, if set
+	Env       string   // manifest overlay to apply (copilot.<env>.toml)
+	Workspace bool     // sync every member listed in cops-workspace.toml
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// Force overwrites files edited since they were last synced.
+	Force bool
+
+	// FrozenLockfile installs every entry exactly as the lock file records
+	// it instead of re-resolving refs, and fails if the manifest and the
+	// lock file disagree. The lock file is not written.
+	FrozenLockfile bool
+
+	// Changed only syncs the entries that are not on disk as the lock
+	// file records them, e.g. after a pull changed copilot.toml or
+	// .cops.lock. The others are left as they are, without a network call.
+	Changed bool
+
+	// KeepOrphans leaves lock entries no longer in the manifest, and their
+	// files, in place instead of pruning them.
+	KeepOrphans bool
+
+	// Backup copies edited files before they are overwritten or pruned:
+	// "dir" into .cops-backup/<timestamp>/, "orig" to <path>.orig. Empty
+	// disables backups.
+	Backup string
+
+	// Link writes files as links into Store instead of copies:
+	// store.Symlink or store.Hardlink. Empty writes copies.
+	Link  string
+	Store store.Store
+
+	// Cache looks downloads up in Store before any network call, and
+	// keeps them there for later syncs.
+	Cache bool
+
+	// NoHooks skips the post_sync hooks of the manifest.
+	NoHooks bool
+
+	// FixRefs rewrites the references of copilot.toml to repositories
+	// GitHub reports as renamed or transferred, instead of only warning.
+	FixRefs bool
+
+	// Timeout stops the sync, as an interruption would, once it has run
+	// that long. Zero never stops it.
+	Timeout time.Duration
+
+	// Confirm asks whether to overwrite, or delete when pruning, the
+	// listed edited files of an entry. Nil means no one can be asked:
+	// edited entries are skipped unless Force is set.
+	Confirm func(id string, edited []string) bool
+
+	// Context stops the sync when done: downloads in flight are
+	// cancelled and no further asset is written. Nil never stops it.
+	Context context.Context
+
+	// Live shows a progress bar while the files of a directory asset are
+	// downloaded, as on a terminal.
+	Live bool
+}
+
+// context returns o.Context, or a context that is never done.
+func (o syncOptions) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
 // newSyncCmd creates the `sync` command.
-// Usage: cops sync
+// Usage: cops sync [<type>/<name>]... [--group <name>]... [--env <env>] [--workspace] [--no-global] [--force] [--frozen-lockfile] [--changed] [--keep-orphans] [--backup[=dir|orig]] [--link[=symlink|hardlink]] [--no-hooks] [--fix-refs] [--timeout <duration>]
 func newSyncCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "sync",
+	var opts syncOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "sync [<type>/<name>]...",
 		Short: "Sync all assets defined in copilot.toml",
 		Long: `Downloads or updates all assets declared in copilot.toml.
 Each entry is fetched from GitHub and written to its corresponding
-.github/<type>/ directory.`,
-		Args: cobra.NoArgs,
+.github/<type>/ directory.
+
+Every entry reports how much it downloaded and how long it took, and
+skills show a progress bar on a terminal while their files download. The
+sync ends with a summary: entries updated, unchanged and failed, and the
+total downloaded.
+
+With arguments, only the given entries are synced, e.g.
+'cops sync prompts/review'; with --group, only entries tagged with one of
+the given groups. Other entries and their lock records are left
+untouched.
+
+With --env (or COPS_ENV), entries from copilot.<env>.toml are added to or
+override those of copilot.toml.
+
+With --workspace, every subproject listed in cops-workspace.toml is synced
+against its own copilot.toml and .cops.lock.
+
+Entries of the user-level manifest (~/.config/cops/copilot.toml) are synced
+too, unless the project defines the same entry or --no-global is given.
+
+Files edited by hand since the last sync are never overwritten silently:
+cops asks first when run in a terminal, and skips them otherwise. Pass
+--force to overwrite them anyway.
+
+With --frozen-lockfile, refs are not re-resolved: each entry is downloaded
+at the commit or digest recorded in .cops.lock and must match its locked
+checksum, so every machine gets byte-identical files. The sync fails before
+downloading anything if an entry is missing from the lock file or its ref
+changed, and the lock file is left as-is.
+
+With --changed, only entries that are not on disk as .cops.lock records
+them are synced: new entries, entries whose ref or target changed, and
+entries whose files are missing or differ. The others are skipped without
+a network call, so 'cops sync --frozen-lockfile --changed' is cheap enough
+to run after every pull.
+
+Entries of the lock file that are no longer in copilot.toml are pruned:
+their files are deleted (after asking, if they were edited by hand) and
+the entries dropped, so removals reach everyone who syncs. Pass
+--keep-orphans to leave them in place.
+
+With --backup (or COPS_BACKUP=dir), edited files are copied into
+.cops-backup/<timestamp>/ before being overwritten or pruned; with
+--backup=orig (or COPS_BACKUP=orig), to <path>.orig next to them.
+
+With --link (or COPS_LINK=symlink), each file is stored once in a
+user-level content store (~/.cache/cops, or COPS_STORE) and the
+project gets a symbolic link to it; --link=hardlink makes hard links
+instead. Repositories sharing assets then share one read-only copy.
+
+Downloads are kept in the same store whether or not --link is given.
+Assets the lock file pins by checksum — with --frozen-lockfile, or when
+the ref is the full commit SHA they were locked at — are then copied
+from it without any network call. COPS_CACHE=off disables this.
+
+After a sync without errors, the post_sync commands of [hooks] run in
+order from the project root, e.g. to format the written files. A failing
+hook fails the sync. --no-hooks skips them.
+
+When GitHub redirects a download because its repository was renamed or
+transferred, the sync still succeeds but warns about the new name; with
+--fix-refs, the references of copilot.toml are rewritten to it.
+
+--timeout bounds the whole sync: once it runs out, downloads in flight
+are cancelled and, as with Ctrl-C, no asset is left half-written. Single
+requests time out after the request_timeout of the user configuration
+(2m by default).`,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return resolveEntryIDs(args, toComplete)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSync()
+			if len(args) > 0 && opts.Workspace {
+				return fmt.Errorf("entries cannot be given with --workspace")
+			}
+			if len(args) > 0 {
+				opts.Entries = args
+			}
+			opts.GlobalManifest = globalManifest(noGlobal)
+			mode, err := backupMode(opts.Backup)
+			if err != nil {
+				return err
+			}
+			opts.Backup = mode
+			if opts.Link, err = linkMode(opts.Link); err != nil {
+				return err
+			}
+			st, storeErr := contentStore()
+			if opts.Link != "" && storeErr != nil {
+				return storeErr
+			}
+			opts.Store, opts.Cache = st, storeErr == nil && !store.CacheDisabled()
+			if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				opts.Confirm = confirmOverwrite(os.Stdin)
+			}
+			if stat, err := os.Stdout.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				opts.Live = true
+			}
+			opts.Context = cmd.Context()
+			if opts.Timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+			if opts.Timeout > 0 {
+				var cancel context.CancelFunc
+				opts.Context, cancel = context.WithTimeout(opts.Context, opts.Timeout)
+				defer cancel()
+			}
+			return runSync(opts)
 		},
 	}
+
+	cmd.Flags().StringSliceVar(&opts.Groups, "group", nil, "Only sync entries in this group (repeatable)")
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&opts.Workspace, "workspace", false, "Sync every member of cops-workspace.toml")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite files edited since the last sync without asking")
+	cmd.Flags().StringVar(&opts.Backup, "backup", "", "Copy edited files before overwriting them: \"dir\" (.cops-backup/) or \"orig\" (<path>.orig) (default $COPS_BACKUP)")
+	cmd.Flags().Lookup("backup").NoOptDefVal = backupDir
+	cmd.Flags().StringVar(&opts.Link, "link", "", "Link files to a shared content store: \"symlink\" or \"hardlink\" (default $COPS_LINK)")
+	cmd.Flags().Lookup("link").NoOptDefVal = store.Symlink
+	cmd.Flags().BoolVar(&opts.NoHooks, "no-hooks", false, "Do not run the post_sync hooks of copilot.toml")
+	cmd.Flags().BoolVar(&opts.FixRefs, "fix-refs", false, "Rewrite references to renamed repositories in copilot.toml")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 0, "Stop the sync after this long, e.g. 10m (default no limit)")
+	cmd.Flags().BoolVar(&opts.KeepOrphans, "keep-orphans", false, "Keep the files of lock entries removed from copilot.toml")
+	cmd.Flags().BoolVar(&opts.FrozenLockfile, "frozen-lockfile", false, "Install exactly the locked versions; fail if copilot.toml and .cops.lock disagree")
+	cmd.Flags().BoolVar(&opts.Changed, "changed", false, "Only sync entries that are not on disk as .cops.lock records them")
+
+	return cmd
 }
 
-func runSync() error {
-	client, err := auth.NewHTTPClient()
+func runSync(opts syncOptions) error {
+	res, err := newResolver(opts.context())
 	if err != nil {
 		return err
 	}
-	res := resolver.New(client)
-	return runSyncWith(manifest.DefaultManifestFile, manifest.DefaultLockFile, res, ".")
+	opts.Env = manifestEnv(opts.Env)
+	if opts.Workspace {
+		return runWorkspaceWith(manifest.FindWorkspaceRoot("."), func(dir string) error {
+			manifestPath, lockPath := memberPaths(dir)
+			return withProjectLock(lockPath, func() error {
+				return runSyncWith(opts, manifestPath, lockPath, res, dir)
+			})
+		})
+	}
+	return withProjectLock(lockFile(), func() error {
+		return runSyncWith(opts, manifestFile(), lockFile(), res, ".")
+	})
+}
+
+// selectEntries keeps the entries named by ids, "<type>/<name>" or
+// "collections/<name>" for the members of a collection, or all of them if
+// ids is nil. It fails if an id names none of them.
+func selectEntries(entries []manifest.Entry, ids []string) ([]manifest.Entry, error) {
+	if ids == nil {
+		return entries, nil
+	}
+	selected := func(entry manifest.Entry, id string) bool {
+		return entry.Type+"/"+entry.Name == id || inCollection(entry, id)
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(entries, func(entry manifest.Entry) bool { return selected(entry, id) }) {
+			return nil, fmt.Errorf("%s matches no entry: expected <type>/<name> of an entry of copilot.toml", id)
+		}
+	}
+	return slices.DeleteFunc(entries, func(entry manifest.Entry) bool {
+		return !slices.ContainsFunc(ids, func(id string) bool { return selected(entry, id) })
+	}), nil
 }
 
 // runSyncWith is the testable core of the sync command.
-func runSyncWith(manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
-	m, err := manifest.Load(manifestPath)
+func runSyncWith(opts syncOptions, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
+	policies, err := manifest.LoadPolicies(rootDir, manifest.OrgPolicyPath())
+	if err != nil {
+		return err
+	}
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      policies.Guard(fetchTemplate(res)),
+	})
 	if err != nil {
 		return fmt.Errorf("loading manifest: %w", err)
 	}
 
-	entries := m.AllEntries()
-	if len(entries) == 0 {
-		fmt.Println("📋 No entries in copilot.toml — nothing to sync.")
-		return nil
+	entries, err := m.EntriesInGroups(opts.Groups)
+	if err != nil {
+		return err
 	}
-
 	lock, err := manifest.LoadLock(lockPath)
 	if err != nil {
 		return fmt.Errorf("loading lock file: %w", err)
 	}
 
+	// Collections belong to no group: they are expanded when the whole
+	// manifest is synced, and their last members are kept otherwise.
+	members := m.LockedMembers(lock)
+	if len(m.Collections) > 0 && len(opts.Groups) == 0 {
+		if members, err = collectionMembers(m, lock, res, opts.FrozenLockfile, policies); err != nil {
+			return err
+		}
+		var skipped []string
+		entries, skipped = manifest.MergeMembers(entries, members)
+		for _, s := range skipped {
+			printf("⚠️  %s\n", s)
+		}
+	}
+	if entries, err = selectEntries(entries, opts.Entries); err != nil {
+		return err
+	}
+
+	// The frozen lock file is never written, so it keeps its orphans.
+	var orphans []string
+	if !opts.KeepOrphans && !opts.FrozenLockfile {
+		orphans = orphanedEntries(append(m.AllEntries(), members...), lock)
+	}
+	if len(entries) == 0 && len(orphans) == 0 {
+		printf("📋 No entries in copilot.toml — nothing to sync.\n")
+		return nil
+	}
+
+	if err := checkPolicies(policies, entries, res); err != nil {
+		return err
+	}
+	if opts.FrozenLockfile {
+		if err := checkLockedEntries(entries, lock); err != nil {
+			return err
+		}
+	}
+	if opts.Changed {
+		entries = slices.DeleteFunc(entries, func(entry manifest.Entry) bool {
+			return upToDate(m, entry, lock, rootDir)
+		})
+		if len(entries) == 0 && len(orphans) == 0 {
+			printf("✅ All assets are up to date — nothing to sync.\n")
+			return nil
+		}
+	}
+
 	inj := injector.New(res, lock, rootDir)
+	if opts.Link != "" {
+		inj.UseStore(opts.Store, opts.Link)
+	}
+	if opts.Cache {
+		inj.UseCache(opts.Store)
+	}
+	inj.SetReadOnly(m.ReadOnly)
+	n, err := parallelism(m)
+	if err != nil {
+		return err
+	}
+	inj.SetParallelism(n)
+	ctx := opts.context()
+	inj.SetContext(ctx)
+	bak := newBackup(opts.Backup, rootDir)
+	status := &statusLine{live: opts.Live}
+	var stats syncStats
+	started := time.Now()
 
-	fmt.Printf("🔄 Syncing %d asset(s)...\n\n", len(entries))
+	// Commit SHAs are resolved in one batch where the source allows it,
+	// rather than one request per entry. Refs the batch misses are
+	// resolved one by one as before.
+	if p, ok := res.(resolver.SHAPrefetcher); ok && !opts.FrozenLockfile {
+		_ = p.PrefetchSHAs(entryRefs(entries))
+	}
+
+	printf("🔄 Syncing %d asset(s)...\n\n", len(entries))
 
 	var errors []error
+	synced := 0
 	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
 		assetType := config.AssetType(entry.Type)
-		fmt.Printf("  📦 %s/%s ← %s\n", entry.Type, entry.Name, entry.Ref)
+		printf("  📦 %s/%s ← %s\n", entry.Type, entry.Name, entry.Ref)
+
+		id := entry.Type + "/" + entry.Name
+		var edited []string
+		if !authoredInPlace(entry.Ref, entry.TargetPath()) {
+			edited = localEdits(entry, lock, rootDir)
+		}
+		if len(edited) > 0 && !opts.Force && (opts.Confirm == nil || !opts.Confirm(id, edited)) {
+			printf("  ⚠️  %s — skipped: %s edited since the last sync (use --force to overwrite)\n", id, strings.Join(edited, ", "))
+			errors = append(errors, fmt.Errorf("%s: local changes kept", id))
+			stats.failed++
+			continue
+		}
+		if existing := unmanagedFile(entry, lock, rootDir); existing != "" {
+			if !opts.Force && (opts.Confirm == nil || !opts.Confirm(id, []string{existing})) {
+				printf("  ⚠️  %s — skipped: %s was not written by cops, and syncing replaces the entire file (use --force to overwrite)\n", id, existing)
+				errors = append(errors, fmt.Errorf("%s: existing %s kept", id, existing))
+				stats.failed++
+				continue
+			}
+			edited = append(edited, existing)
+		}
+		if err := saveBackup(bak, id, edited); err != nil {
+			printf("  ❌ %s: %s\n", id, err)
+			errors = append(errors, fmt.Errorf("%s: %w", id, err))
+			stats.failed++
+			continue
+		}
 
-		result := inj.Inject(assetType, entry.Name, entry.Ref)
+		prev, _ := lock.Get(entry.Type, entry.Name)
+		entryStarted := time.Now()
+		inj.SetProgress(func(p injector.Progress) {
+			status.show(progressBar(id, p, time.Since(entryStarted)))
+		})
+		var result injector.InjectResult
+		if opts.FrozenLockfile {
+			locked, _ := lock.Get(entry.Type, entry.Name)
+			result = inj.InjectLocked(assetType, entry.Name, entry.Ref, entry.TargetPath(), locked, injectOptions(m, entry))
+		} else {
+			result = inj.InjectTo(assetType, entry.Name, entry.Ref, entry.TargetPath(), guardLicense(injectOptions(m, entry), policies))
+		}
+		status.clear()
+		elapsed := time.Since(entryStarted)
+		if result.Err != nil && ctx.Err() != nil {
+			printf("  ⏹️  %s/%s — interrupted, left as it was\n", entry.Type, entry.Name)
+			break
+		}
+		synced++
+		stats.bytes += result.Bytes
 		if result.Err != nil {
-			fmt.Printf("  ❌ %s/%s: %s\n", entry.Type, entry.Name, result.Err)
+			printf("  ❌ %s/%s: %s\n", entry.Type, entry.Name, result.Err)
 			errors = append(errors, fmt.Errorf("%s/%s: %w", entry.Type, entry.Name, result.Err))
+			stats.failed++
 		} else {
-			fmt.Printf("  ✅ %s/%s → %s\n", entry.Type, entry.Name, result.TargetPath)
+			printf("  ✅ %s/%s → %s (%s)\n", entry.Type, entry.Name, result.TargetPath, transferred(result.Bytes, elapsed))
+			printWarnings(id, result.Warnings)
+			if !opts.FrozenLockfile {
+				lock.RecordCollection(entry.Type, entry.Name, entry.Collection)
+			}
+			if locked, _ := lock.Get(entry.Type, entry.Name); locked.Checksum == prev.Checksum && locked.TargetPath == prev.TargetPath {
+				stats.unchanged++
+			} else {
+				stats.updated++
+			}
+		}
+		if result.Err == nil && !opts.FrozenLockfile && prev.TargetPath != "" && prev.TargetPath != result.TargetPath {
+			if deleted, err := removeMoved(rootDir, prev.TargetPath, result.TargetPath); err != nil {
+				printf("  ❌ %s: %s\n", id, err)
+				errors = append(errors, fmt.Errorf("%s: %w", id, err))
+			} else if deleted {
+				printf("  🗑️  %s — moved, deleted %s\n", id, prev.TargetPath)
+			}
+		}
+	}
+
+	// An interrupted sync keeps the orphans for the next one to prune.
+	if ctx.Err() != nil {
+		orphans = nil
+	}
+	for _, key := range orphans {
+		if err := pruneOrphan(opts, bak, key, lock, rootDir); err != nil {
+			printf("  ❌ %s: %s\n", key, err)
+			errors = append(errors, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	if rr, ok := res.(resolver.RenameReporter); ok {
+		if err := reportRenames(rr.Renames(), entries, opts.FixRefs, manifestPath); err != nil {
+			printf("  ❌ %s\n", err)
+			errors = append(errors, err)
 		}
 	}
 
-	if err := lock.Save(lockPath); err != nil {
-		return fmt.Errorf("saving lock file: %w", err)
+	if !opts.FrozenLockfile {
+		if err := lock.Save(lockPath); err != nil {
+			return fmt.Errorf("saving lock file: %w", err)
+		}
 	}
 
 	fmt.Println()
+	stats.elapsed = time.Since(started)
+	printf("📊 %s\n\n", ui.Text(stats.String()))
+	switch ctx.Err() {
+	case nil:
+	case context.DeadlineExceeded:
+		return fmt.Errorf("sync timed out after %d of %d asset(s); run 'cops sync' again to finish", synced, len(entries))
+	default:
+		return fmt.Errorf("sync interrupted after %d of %d asset(s); run 'cops sync' again to finish", synced, len(entries))
+	}
 	if len(errors) > 0 {
 		return fmt.Errorf("sync completed with %d error(s)", len(errors))
 	}
 
-	fmt.Println("✅ All assets synced successfully.")
+	printf("✅ All assets synced successfully.\n")
+	if opts.NoHooks || len(m.Hooks.PostSync) == 0 {
+		return nil
+	}
+	fmt.Println()
+	return runHooks(ctx, "post_sync", m.Hooks.PostSync, rootDir)
+}
+
+// syncStats sums up what a sync did.
+type syncStats struct {
+	updated, unchanged, failed int
+	bytes                      int64
+	elapsed                    time.Duration
+}
+
+// String returns the summary printed at the end of a sync.
+func (s syncStats) String() string {
+	return fmt.Sprintf("%d updated, %d unchanged, %d failed — %s", s.updated, s.unchanged, s.failed, transferred(s.bytes, s.elapsed))
+}
+
+// transferred describes a download of n bytes that took d, such as
+// "12.5 KB in 340ms", or only its duration when nothing was downloaded.
+func transferred(n int64, d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if n == 0 {
+		return "nothing downloaded in " + d.String()
+	}
+	return manifest.FormatSize(n) + " in " + d.String()
+}
+
+// progressBarWidth is the number of cells of a progress bar.
+const progressBarWidth = 20
+
+// progressBar renders the progress of the download of entry id.
+func progressBar(id string, p injector.Progress, elapsed time.Duration) string {
+	filled := 0
+	if p.Total > 0 {
+		filled = p.Done * progressBarWidth / p.Total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+	return fmt.Sprintf("  ⏬ %s [%s] %d/%d files, %s", id, bar, p.Done, p.Total, transferred(p.Bytes, elapsed))
+}
+
+// checkPolicies reports the entries whose source the policies refuse,
+// before anything is downloaded.
+func checkPolicies(policies manifest.Policies, entries []manifest.Entry, res resolver.ResolverAPI) error {
+	refused := 0
+	for _, entry := range entries {
+		if err := checkSource(policies, res, entry.Ref); err != nil {
+			printf("  ❌ %s/%s: %s\n", entry.Type, entry.Name, err)
+			refused++
+		}
+	}
+	if refused > 0 {
+		return fmt.Errorf("%d entr(ies) refused by the source policy; nothing was synced", refused)
+	}
+	return nil
+}
+
+// checkSource reports an error if policies refuse the reference raw or,
+// when res serves it from another source, as it does registry packages,
+// the reference it is fetched from.
+func checkSource(policies manifest.Policies, res resolver.ResolverAPI, raw string) error {
+	if len(policies) == 0 {
+		return nil
+	}
+	if err := policies.Check(raw); err != nil {
+		return err
+	}
+	r, ok := res.(resolver.Redirector)
+	if !ok {
+		return nil
+	}
+	ref, err := config.ParseRef(raw)
+	if err != nil {
+		return err
+	}
+	target, err := r.Target(ref)
+	if err != nil {
+		return fmt.Errorf("finding the source of %s: %w", raw, err)
+	}
+	if target.Raw() == ref.Raw() {
+		return nil
+	}
+	if err := policies.Check(target.Raw()); err != nil {
+		return fmt.Errorf("%s is fetched from %s: %w", raw, target.Raw(), err)
+	}
+	return nil
+}
+
+// entryRefs returns the parsed refs of entries, skipping invalid ones.
+func entryRefs(entries []manifest.Entry) []config.AssetRef {
+	var refs []config.AssetRef
+	for _, entry := range entries {
+		if ref, err := config.ParseRef(entry.Ref); err == nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// reportRenames warns about the repositories of entries that GitHub
+// redirected to a new name and, with fix, rewrites the references of the
+// manifest at manifestPath to it. Entries coming from an included or
+// global manifest must be updated there.
+func reportRenames(renames map[string]string, entries []manifest.Entry, fix bool, manifestPath string) error {
+	used := make(map[string]bool)
+	for _, ref := range entryRefs(entries) {
+		if ref.IsGitHub() || ref.IsRelease() {
+			used[strings.ToLower(ref.RepoFullName())] = true
+		}
+	}
+	var moved []string
+	for from := range renames {
+		if used[strings.ToLower(from)] {
+			moved = append(moved, from)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	sort.Strings(moved)
+	if !fix {
+		for _, from := range moved {
+			printf("  ⚠️  %s was renamed to %s — run 'cops sync --fix-refs' to update copilot.toml\n", from, renames[from])
+		}
+		return nil
+	}
+
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	for _, from := range moved {
+		if n := m.RenameRepo(from, renames[from]); n > 0 {
+			printf("  ✏️  %s → %s: %d reference(s) updated in copilot.toml\n", from, renames[from], n)
+		} else {
+			printf("  ⚠️  %s was renamed to %s — update the manifest that references it\n", from, renames[from])
+		}
+	}
+	if err := m.Save(manifestPath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+	return nil
+}
+
+// injectOptions returns how entry of m is written: its options, the
+// manifest's template variables and its extra outputs.
+func injectOptions(m *manifest.Manifest, entry manifest.Entry) injector.Options {
+	o := injector.Options{Frontmatter: entry.Options.Frontmatter, Vars: m.TemplateVars(), Transform: entry.Options.Transform, Integrity: entry.Options.Integrity, Cosign: entry.Options.Cosign, Secrets: entry.Options.Secrets}
+	if entry.Options.Filtered() {
+		o.Files = entry.Options.SelectsFile
+	}
+	o.Copies, o.Sections = m.Outputs(entry.Type, entry.Name)
+	o.MaxFileSize, o.MaxDirSize = m.SizeLimits()
+	o.Binaries = m.Limits.Binaries
+	o.EOL = m.Settings.EOL
+	if m.Settings.Banner {
+		o.Banner = fmt.Sprintf("Managed by cops from %s: local edits are overwritten by 'cops sync'.", entry.Ref)
+	}
+	return o
+}
+
+// guardLicense returns o refusing the licenses policies refuse.
+func guardLicense(o injector.Options, policies manifest.Policies) injector.Options {
+	if policies.RestrictsLicenses() {
+		o.License = policies.CheckLicense
+	}
+	return o
+}
+
+// printWarnings reports the problems that did not stop entry id from
+// being written.
+func printWarnings(id string, warnings []string) {
+	for _, w := range warnings {
+		printf("  ⚠️  %s — %s\n", id, w)
+	}
+}
+
+// saveBackup backs up the edited files of entry id before they are
+// overwritten or deleted, reporting where the copies went.
+func saveBackup(bak backup, id string, edited []string) error {
+	saved, err := bak.save(edited)
+	if err != nil {
+		return err
+	}
+	if len(saved) > 0 {
+		printf("  💾 %s — previous version saved to %s\n", id, strings.Join(saved, ", "))
+	}
+	return nil
+}
+
+// orphanedEntries returns, in byte-wise order, the keys of the lock entries
+// that match none of the manifest's entries.
+func orphanedEntries(entries []manifest.Entry, lock *manifest.LockFile) []string {
+	inManifest := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		inManifest[entry.Type+"/"+entry.Name] = true
+	}
+	var orphans []string
+	for _, key := range manifest.SortedKeys(lock.Entries) {
+		if !inManifest[key] {
+			orphans = append(orphans, key)
+		}
+	}
+	return orphans
+}
+
+// pruneOrphan deletes the file or directory of the orphaned lock entry key
+// and drops the entry. A file edited by hand since it was synced is only
+// deleted with Force or after confirmation; otherwise it is kept, no longer
+// managed by cops.
+func pruneOrphan(opts syncOptions, bak backup, key string, lock *manifest.LockFile, rootDir string) error {
+	locked := lock.Entries[key]
+	if locked.TargetPath == "" || !filepath.IsLocal(locked.TargetPath) {
+		return fmt.Errorf("not pruned: target %q is outside the project", locked.TargetPath)
+	}
+
+	// Copies and sections are derived from the target, never edited.
+	if err := injector.RemoveOutputs(rootDir, locked); err != nil {
+		return err
+	}
+	if authoredInPlace(locked.Ref, locked.TargetPath) {
+		lock.Remove(locked.Type, locked.Name)
+		printf("  ⚠️  %s — removed from copilot.toml; kept %s, authored in the project\n", key, locked.TargetPath)
+		return nil
+	}
+
+	entry := manifest.Entry{Type: locked.Type, Name: locked.Name, Ref: locked.Ref}
+	edited := localEdits(entry, lock, rootDir)
+	if len(edited) > 0 && !opts.Force && (opts.Confirm == nil || !opts.Confirm(key, edited)) {
+		lock.Remove(locked.Type, locked.Name)
+		printf("  ⚠️  %s — removed from copilot.toml; kept %s, edited since the last sync\n", key, locked.TargetPath)
+		return nil
+	}
+	if err := saveBackup(bak, key, edited); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(rootDir, locked.TargetPath)); err != nil {
+		return fmt.Errorf("deleting %s: %w", locked.TargetPath, err)
+	}
+	lock.Remove(locked.Type, locked.Name)
+	printf("  🗑️  %s — removed from copilot.toml, deleted %s\n", key, locked.TargetPath)
+	return nil
+}
+
+// authoredInPlace reports whether rawRef is a local reference to target
+// itself: the asset is edited where it is written, so its edits are not
+// changes a sync would overwrite, and it is never deleted.
+func authoredInPlace(rawRef, target string) bool {
+	ref, err := config.ParseRef(rawRef)
+	return err == nil && ref.IsLocal() && filepath.FromSlash(ref.Path) == filepath.Clean(target)
+}
+
+// removeMoved deletes what an entry left at its previous target once it was
+// written to a new one, e.g. after output_root or its target option
+// changed. Edits to the previous copy were handled before the entry was
+// synced, like edits to any file sync overwrites. It reports whether
+// anything was deleted.
+func removeMoved(rootDir, prevTarget, newTarget string) (bool, error) {
+	if !filepath.IsLocal(prevTarget) || strings.HasPrefix(newTarget, prevTarget+string(filepath.Separator)) {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, prevTarget)); err != nil {
+		return false, nil
+	}
+	if err := os.RemoveAll(filepath.Join(rootDir, prevTarget)); err != nil {
+		return false, fmt.Errorf("deleting %s: %w", prevTarget, err)
+	}
+	return true, nil
+}
+
+// checkLockedEntries reports the entries --frozen-lockfile cannot install
+// because the lock file does not record them at their current ref.
+func checkLockedEntries(entries []manifest.Entry, lock *manifest.LockFile) error {
+	var mismatched int
+	for _, entry := range entries {
+		locked, ok := lock.Get(entry.Type, entry.Name)
+		switch {
+		case !ok:
+			printf("  ❌ %s/%s — not in the lock file\n", entry.Type, entry.Name)
+			mismatched++
+		case locked.Ref != entry.Ref:
+			printf("  ❌ %s/%s — ref changed: lock=%s manifest=%s\n", entry.Type, entry.Name, locked.Ref, entry.Ref)
+			mismatched++
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("copilot.toml and the lock file disagree on %d asset(s); run 'cops sync' without --frozen-lockfile to update the lock file", mismatched)
+	}
+	return nil
+}
+
+// localEdits returns the files of entry that were edited by hand since it
+// was last synced and that syncing it would overwrite, relative to rootDir.
+// Files added to a skill directory are kept by a sync and not reported.
+func localEdits(entry manifest.Entry, lock *manifest.LockFile, rootDir string) []string {
+	locked, ok := lock.Get(entry.Type, entry.Name)
+	if !ok || locked.Checksum == "" {
+		return nil
+	}
+	// The lock records where the synced copy was written.
+	target := locked.TargetPath
+	if target == "" {
+		target = entry.TargetPath()
+	}
+	absTarget := filepath.Join(rootDir, target)
+	if _, err := os.Stat(absTarget); err != nil {
+		return nil
+	}
+
+	isDir := config.AssetType(entry.Type).IsDirectory()
+	if isDir && len(locked.Files) > 0 {
+		files, _, err := localFiles(absTarget)
+		if err != nil {
+			return nil
+		}
+		var edited []string
+		for _, c := range locked.DiffFiles(files, nil) {
+			if c.Change == "modified" {
+				edited = append(edited, filepath.Join(target, filepath.FromSlash(c.Path)))
+			}
+		}
+		return edited
+	}
+	if cs, err := localChecksum(absTarget, isDir); err == nil && cs != locked.Checksum {
+		return []string{target}
+	}
 	return nil
 }
+
+// unmanagedFile returns the target of entry if it is a singleton, such as
+// root-instructions, and a file no lock entry wrote is there: syncing would
+// replace that file entirely. It returns "" otherwise.
+func unmanagedFile(entry manifest.Entry, lock *manifest.LockFile, rootDir string) string {
+	if !config.AssetType(entry.Type).IsSingleton() {
+		return ""
+	}
+	target := entry.TargetPath()
+	if _, err := os.Stat(filepath.Join(rootDir, target)); err != nil {
+		return ""
+	}
+	for _, locked := range lock.Entries {
+		if filepath.Clean(filepath.FromSlash(locked.TargetPath)) == target {
+			return ""
+		}
+	}
+	return target
+}
+
+// upToDate reports whether entry of m is on disk, outputs included, as
+// the lock file records it at its current ref and target.
+func upToDate(m *manifest.Manifest, entry manifest.Entry, lock *manifest.LockFile, rootDir string) bool {
+	locked, ok := lock.Get(entry.Type, entry.Name)
+	if !ok || locked.Ref != entry.Ref || locked.TargetPath != entry.TargetPath() {
+		return false
+	}
+	copies, sections := m.Outputs(entry.Type, entry.Name)
+	if !slices.Equal(copies, locked.Copies) || !slices.Equal(sections, locked.Sections) {
+		return false
+	}
+	isDir := config.AssetType(entry.Type).IsDirectory()
+	target := filepath.Join(rootDir, entry.TargetPath())
+	if _, err := os.Stat(target); err != nil {
+		return false
+	}
+	return verifyContent(locked, target, isDir) == "" && verifyOutputs(locked, rootDir, isDir) == ""
+}
+
+// confirmOverwrite returns a syncOptions.Confirm that asks on standard
+// output and reads a yes or no answer from in. Anything but "y" or "yes"
+// keeps the local changes.
+func confirmOverwrite(in io.Reader) func(id string, edited []string) bool {
+	r := bufio.NewReader(in)
+	return func(id string, edited []string) bool {
+		printf("  ❓ %s: discard local changes to %s? [y/N] ", id, strings.Join(edited, ", "))
+		answer, _ := r.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
 )
 
-// newTypeCmd creates a subcommand for a given asset type (instructions, agents, prompts, skills).
+// newTypeCmd creates a subcommand for a given asset type (instructions, agents, prompts, chatmodes, skills, root-instructions, agents-md, setup-steps).
 // Each type command has `use` and `unuse` sub-subcommands.
 func newTypeCmd(typeName, description string) *cobra.Command {
 	cmd := &cobra.Command{
@@ -18,3 +23,26 @@ func newTypeCmd(typeName, description string) *cobra.Command {
 
 	return cmd
 }
+
+// addCustomTypeCmds adds a type subcommand to root for each custom asset
+// type the project's manifest declares under [types], unless a command
+// already has its name. The manifest is only read for its types: a
+// manifest that does not load is reported by the command that runs.
+func addCustomTypeCmds(root *cobra.Command) {
+	path := os.Getenv(manifestEnvVar)
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return
+		}
+		path = manifest.Find(manifest.FindRoot(wd))
+	}
+	// Loading registers the types, even if the manifest is otherwise invalid.
+	_, _ = manifest.Load(path)
+	for _, t := range config.CustomTypes() {
+		if cmd, _, err := root.Find([]string{string(t)}); err == nil && cmd != root {
+			continue
+		}
+		root.AddCommand(newTypeCmd(string(t), "Manage "+string(t)+" assets"))
+	}
+}
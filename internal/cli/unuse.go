@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
 	"github.com/cbout22/copilot-sync/internal/manifest"
 )
 
@@ -17,8 +18,8 @@ func newUnuseCmd(typeName string) *cobra.Command {
 	return &cobra.Command{
 		Use:   "unuse <name>",
 		Short: fmt.Sprintf("Remove a %s entry and delete its local file", typeName),
-		Long: fmt.Sprintf(`Removes a %s entry from copilot.toml and deletes the
-corresponding local file or directory from disk.
+		Long: fmt.Sprintf(`Removes a %s entry from the manifest (copilot.toml, or its YAML or JSON
+form) and deletes the corresponding local file or directory from disk.
 
 Example:
   cops %s unuse my-asset`, typeName, typeName),
@@ -34,7 +35,9 @@ Example:
 }
 
 func runUnuse(typeName, name string) error {
-	return runUnuseWith(typeName, name, manifest.DefaultManifestFile, manifest.DefaultLockFile, ".")
+	return withProjectLock(lockFile(), func() error {
+		return runUnuseWith(typeName, name, manifestFile(), lockFile(), ".")
+	})
 }
 
 // runUnuseWith is the testable core of the unuse command.
@@ -56,22 +59,30 @@ func runUnuseWith(typeName, name, manifestPath, lockPath, rootDir string) error
 		return fmt.Errorf("loading lock file: %w", err)
 	}
 
-	// Remove the entry
+	// Remove the entry, remembering where it was written
+	relTarget := m.TargetPath(typeName, name)
 	removed, err := m.Remove(typeName, name)
 	if err != nil {
 		return err
 	}
 
 	if !removed {
-		return fmt.Errorf("%s/%s not found in copilot.toml", typeName, name)
+		return fmt.Errorf("%s/%s not found in %s", typeName, name, filepath.Base(manifestPath))
 	}
 
 	// Delete the local file or directory from disk
-	targetPath := filepath.Join(rootDir, assetType.TargetPath(name))
+	targetPath := filepath.Join(rootDir, relTarget)
 	if err := os.RemoveAll(targetPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("deleting %s: %w", targetPath, err)
 	}
 
+	// Delete its copies and sections written for other tools
+	if locked, ok := lock.Get(typeName, name); ok {
+		if err := injector.RemoveOutputs(rootDir, locked); err != nil {
+			return err
+		}
+	}
+
 	// Remove from lock file
 	lock.Remove(typeName, name)
 
@@ -85,7 +96,7 @@ func runUnuseWith(typeName, name, manifestPath, lockPath, rootDir string) error
 		return fmt.Errorf("saving lock file: %w", err)
 	}
 
-	fmt.Printf("🗑️  Removed %s/%s from copilot.toml\n", typeName, name)
-	fmt.Printf("🧹 Deleted %s\n", assetType.TargetPath(name))
+	printf("🗑️  Removed %s/%s from %s\n", typeName, name, filepath.Base(manifestPath))
+	printf("🧹 Deleted %s\n", relTarget)
 	return nil
 }
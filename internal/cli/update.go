@@ -0,0 +1,468 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// defaultUpdateBranch is the branch `cops update --pr` commits to. Reusing
+// it keeps a single pull request open, refreshed by every run.
+const defaultUpdateBranch = "cops/update-assets"
+
+// changelogLimit caps the commits listed per asset, in the output and in a
+// pull request.
+const changelogLimit = 10
+
+// updateOptions holds the flags accepted by the update command.
+type updateOptions struct {
+	DryRun bool   // only list the available updates
+	Env    string // manifest overlay to apply (copilot.<env>.toml)
+
+	// GlobalManifest is the user-level manifest merged beneath the
+	// project's; empty disables it.
+	GlobalManifest string
+
+	// PR commits the updates to Branch, pushes it to the origin remote
+	// and opens a pull request against Base (the current branch if
+	// empty) on Repo (taken from the origin remote if empty).
+	PR     bool
+	Branch string
+	Base   string
+	Repo   string
+
+	// OpenPR opens a pull request on the GitHub repository repo and
+	// returns its URL.
+	OpenPR func(repo string, pr resolver.PullRequest) (string, error)
+}
+
+// assetUpdate is a newer version of a manifest entry.
+type assetUpdate struct {
+	Entry    manifest.Entry
+	From, To string // versions, e.g. "v1.0.0" → "v1.1.0" or "main@1a2b3c" → "main@4d5e6f"
+	NewRef   string // manifest ref to write, or "" if the ref stays (a branch moved)
+
+	Repo       string            // "org/repo" the asset comes from
+	Path       string            // path of the asset in Repo
+	Base, Head string            // revisions compared for the changelog
+	Commits    []resolver.Commit // changing Path; nil if they could not be listed
+}
+
+// newUpdateCmd creates the `update` command.
+// Usage: cops update [--dry-run] [--env <env>] [--no-global] [--pr [--branch <name>] [--base <branch>] [--repo <org/repo>]]
+func newUpdateCmd() *cobra.Command {
+	var opts updateOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Bump entries to their latest versions, optionally in a pull request",
+		Long: `Looks for newer versions of the GitHub entries of copilot.toml: a higher
+version tag for entries pinned to one (pre-releases aside), and a newer
+commit for entries tracking a branch. Version tags are bumped in
+copilot.toml, then every asset is synced and .cops.lock updated.
+
+Each update is listed with the upstream commits that changed the asset
+between the locked version and the new one (the 10 most recent), so
+they can be reviewed first: with --dry-run, the updates are listed and
+nothing is changed.
+
+With --pr, meant for CI or cron jobs, the updates are committed to the
+cops/update-assets branch (--branch), force-pushed to origin, and a pull
+request is opened against the current branch (--base) with the upstream
+commits of each asset. A pull request already open for the branch is
+updated instead. The working tree is left on the base branch.
+
+The manifest is read as 'cops sync' reads it: with --env (or COPS_ENV),
+the copilot.<env>.toml overlay is applied, and the user-level manifest is
+merged in unless --no-global is given. Entries from included, template or user-level manifests, and entries whose
+ref comes from [sources] or default_ref, are reported but not bumped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newResolver(cmd.Context())
+			if err != nil {
+				return err
+			}
+			opts.OpenPR = openPullRequest(cmd.Context())
+			opts.Env = manifestEnv(opts.Env)
+			opts.GlobalManifest = globalManifest(noGlobal)
+			return withProjectLock(lockFile(), func() error {
+				return runUpdateWith(opts, manifestFile(), lockFile(), res, ".")
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "List the available updates without changing anything")
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+	cmd.Flags().BoolVar(&opts.PR, "pr", false, "Commit the updates to a branch and open a pull request")
+	cmd.Flags().StringVar(&opts.Branch, "branch", defaultUpdateBranch, "Branch the updates are committed to with --pr")
+	cmd.Flags().StringVar(&opts.Base, "base", "", "Branch the pull request targets (default: the current branch)")
+	cmd.Flags().StringVar(&opts.Repo, "repo", "", "GitHub repository to open the pull request on (default: from the origin remote)")
+
+	return cmd
+}
+
+// openPullRequest returns an updateOptions.OpenPR using the GitHub
+// credentials cops resolves.
+func openPullRequest(ctx context.Context) func(repo string, pr resolver.PullRequest) (string, error) {
+	return func(repo string, pr resolver.PullRequest) (string, error) {
+		client, err := httpClient()
+		if err != nil {
+			return "", err
+		}
+		return resolver.OpenPullRequest(resolver.WithContext(client, ctx), repo, pr)
+	}
+}
+
+// runUpdateWith is the testable core of the update command. Updates are
+// looked up for the manifest as sync reads it, and bumped in the project
+// manifest alone.
+func runUpdateWith(opts updateOptions, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) (err error) {
+	finder, ok := res.(resolver.UpdateFinder)
+	if !ok {
+		return fmt.Errorf("updates cannot be looked up with this resolver")
+	}
+	policies, err := manifest.LoadPolicies(rootDir, manifest.OrgPolicyPath())
+	if err != nil {
+		return err
+	}
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Env,
+		GlobalPath: opts.GlobalManifest,
+		Fetch:      policies.Guard(fetchTemplate(res)),
+	})
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	project, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+
+	printf("🔍 Looking for updates...\n")
+	fmt.Println()
+	updates := findUpdates(m, project, lock, res, finder)
+	if len(updates) == 0 {
+		printf("\n✅ All assets are up to date.\n")
+		return nil
+	}
+	printf("\n⬆️  %d update(s) available.\n", len(updates))
+	if opts.DryRun {
+		return nil
+	}
+
+	var g *updateBranch
+	if opts.PR {
+		if g, err = startUpdateBranch(opts, rootDir, manifestPath, lockPath); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				g.abort()
+			}
+		}()
+	}
+
+	for _, u := range updates {
+		if u.NewRef != "" {
+			if err := project.Set(u.Entry.Type, u.Entry.Name, u.NewRef); err != nil {
+				return err
+			}
+		}
+	}
+	if err := project.Save(manifestPath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+	fmt.Println()
+	if err := runSyncWith(syncOptions{Env: opts.Env, GlobalManifest: opts.GlobalManifest}, manifestPath, lockPath, res, rootDir); err != nil {
+		return err
+	}
+	if g == nil {
+		return nil
+	}
+
+	lock, err = manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	var paths []string
+	for _, u := range updates {
+		if e, ok := lock.Get(u.Entry.Type, u.Entry.Name); ok {
+			paths = append(paths, e.TargetPath)
+			paths = append(paths, e.Copies...)
+			paths = append(paths, e.Sections...)
+		}
+	}
+	title := fmt.Sprintf("Update %d Copilot asset(s)", len(updates))
+	if len(updates) == 1 {
+		u := updates[0]
+		title = fmt.Sprintf("Update %s/%s to %s", u.Entry.Type, u.Entry.Name, u.To)
+	}
+	url, err := g.finish(paths, title, pullRequestBody(updates), opts.OpenPR)
+	if err != nil {
+		return err
+	}
+	printf("\n🔀 Pull request: %s\n", url)
+	return nil
+}
+
+// findUpdates returns the newer versions of the GitHub entries of m,
+// reporting each one, and the entries that cannot be bumped in project, the
+// project manifest alone, as it goes.
+func findUpdates(m, project *manifest.Manifest, lock *manifest.LockFile, res resolver.ResolverAPI, finder resolver.UpdateFinder) []assetUpdate {
+	var updates []assetUpdate
+	for _, entry := range m.AllEntries() {
+		id := entry.Type + "/" + entry.Name
+		ref, err := config.ParseRef(entry.Ref)
+		if err != nil || !ref.IsGitHub() {
+			continue
+		}
+		section, _ := project.Section(entry.Type)
+		raw, local := section[entry.Name]
+		u := assetUpdate{Entry: entry, Repo: ref.RepoFullName(), Path: ref.Path}
+
+		switch {
+		case ref.IsVersionTag():
+			newer, err := finder.NewerTag(ref)
+			if err != nil {
+				printf("  ❌ %s — %v\n", id, err)
+				continue
+			}
+			if newer == "" {
+				continue
+			}
+			if !local || !strings.HasSuffix(raw, "@"+ref.Ref) {
+				printf("  ⚠️  %s — %s available; update it where its ref is set\n", id, newer)
+				continue
+			}
+			u.From, u.To = ref.Ref, newer
+			u.NewRef = strings.TrimSuffix(raw, ref.Ref) + newer
+			u.Base, u.Head = ref.Ref, newer
+
+		case !ref.IsPinned():
+			locked, ok := lock.Get(entry.Type, entry.Name)
+			if !ok || locked.Ref != entry.Ref {
+				continue
+			}
+			latest, err := res.ResolveSHA(ref)
+			if err != nil {
+				printf("  ❌ %s — %v\n", id, err)
+				continue
+			}
+			if latest == "" || latest == locked.ResolvedSHA {
+				continue
+			}
+			u.From = ref.Ref + "@" + shortSHA(locked.ResolvedSHA)
+			u.To = ref.Ref + "@" + shortSHA(latest)
+			u.Base, u.Head = locked.ResolvedSHA, latest
+
+		default:
+			continue
+		}
+
+		// The changelog is a convenience: an update is not dropped for it.
+		commits, err := finder.Compare(ref, u.Base, u.Head)
+		if err != nil {
+			printf("  ⬆️  %s — %s → %s (changelog unavailable: %v)\n", id, u.From, u.To, err)
+			updates = append(updates, u)
+			continue
+		}
+		u.Commits = commits
+		printf("  ⬆️  %s — %s → %s (%d commit(s) changing it)\n", id, u.From, u.To, len(u.Commits))
+		latest, more := changelog(u.Commits)
+		for _, c := range latest {
+			printf("      %s %s\n", shortSHA(c.SHA), c.Message)
+		}
+		if more > 0 {
+			printf("      …and %d more\n", more)
+		}
+		updates = append(updates, u)
+	}
+	return updates
+}
+
+// pullRequestBody describes updates in Markdown, with the upstream commits
+// of each.
+func pullRequestBody(updates []assetUpdate) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Updates %d Copilot asset(s) declared in copilot.toml.\n\n", len(updates))
+	b.WriteString("| Asset | Source | Update |\n|---|---|---|\n")
+	for _, u := range updates {
+		fmt.Fprintf(&b, "| `%s/%s` | %s | `%s` → `%s` |\n", u.Entry.Type, u.Entry.Name, u.Repo, u.From, u.To)
+	}
+	for _, u := range updates {
+		fmt.Fprintf(&b, "\n### %s/%s\n\n", u.Entry.Type, u.Entry.Name)
+		fmt.Fprintf(&b, "[%s compare](https://github.com/%s/compare/%s...%s)\n\n", u.Repo, u.Repo, u.Base, u.Head)
+		if u.Commits == nil {
+			b.WriteString("No commit list available.\n")
+			continue
+		}
+		if len(u.Commits) == 0 {
+			fmt.Fprintf(&b, "No commit changed `%s`.\n", u.Path)
+			continue
+		}
+		latest, more := changelog(u.Commits)
+		for _, c := range latest {
+			fmt.Fprintf(&b, "- [`%s`](%s) %s\n", shortSHA(c.SHA), c.URL, c.Message)
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, "- …and %d more\n", more)
+		}
+	}
+	b.WriteString("\n---\nOpened by `cops update --pr`.\n")
+	return b.String()
+}
+
+// changelog returns the changelogLimit latest of commits, which are listed
+// oldest first, newest first as a changelog reads, and how many others
+// there are.
+func changelog(commits []resolver.Commit) ([]resolver.Commit, int) {
+	latest := slices.Clone(commits[max(len(commits)-changelogLimit, 0):])
+	slices.Reverse(latest)
+	return latest, len(commits) - len(latest)
+}
+
+// updateBranch is the git branch `cops update --pr` commits to.
+type updateBranch struct {
+	dir          string // project root, where git runs
+	branch, base string
+	repo         string // "org/repo" the pull request is opened on
+	files        []string
+}
+
+// startUpdateBranch checks the repository at rootDir is ready for an
+// update pull request and switches to a fresh update branch from the base
+// branch.
+func startUpdateBranch(opts updateOptions, rootDir, manifestPath, lockPath string) (*updateBranch, error) {
+	g := &updateBranch{dir: rootDir, branch: opts.Branch, base: opts.Base, repo: opts.Repo}
+	if g.branch == "" {
+		g.branch = defaultUpdateBranch
+	}
+	current, err := git(rootDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if g.base == "" {
+		if current == "HEAD" {
+			return nil, fmt.Errorf("HEAD is detached: pass --base with the branch the pull request targets")
+		}
+		g.base = current
+	}
+	if g.repo == "" {
+		url, err := git(rootDir, "remote", "get-url", "origin")
+		if err != nil {
+			return nil, err
+		}
+		if g.repo, err = parseGitHubRemote(url); err != nil {
+			return nil, fmt.Errorf("%w: pass --repo", err)
+		}
+	}
+
+	for _, path := range []string{manifestPath, lockPath} {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		g.files = append(g.files, abs)
+	}
+	status, err := git(rootDir, append([]string{"status", "--porcelain", "--"}, g.files...)...)
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		return nil, fmt.Errorf("copilot.toml or .cops.lock has uncommitted changes: commit or stash them first")
+	}
+	if current != g.base {
+		if _, err := git(rootDir, "checkout", g.base); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := git(rootDir, "checkout", "-B", g.branch); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// finish commits the manifest, the lock file and the asset paths (relative
+// to the project root) to the update branch, pushes it, opens the pull
+// request and switches back to the base branch.
+func (g *updateBranch) finish(paths []string, title, body string, openPR func(string, resolver.PullRequest) (string, error)) (string, error) {
+	files := g.files
+	for _, path := range paths {
+		abs, err := filepath.Abs(filepath.Join(g.dir, path))
+		if err != nil {
+			return "", err
+		}
+		// Assets kept out of version control stay out of the commit.
+		if _, err := git(g.dir, "check-ignore", "-q", abs); err != nil {
+			files = append(files, abs)
+		}
+	}
+	if _, err := git(g.dir, append([]string{"add", "-A", "--"}, files...)...); err != nil {
+		return "", err
+	}
+	// Only the update is committed, not what the user had staged.
+	if _, err := git(g.dir, append([]string{"commit", "-m", title, "-m", "Updated by cops update --pr.", "--"}, files...)...); err != nil {
+		return "", err
+	}
+	if _, err := git(g.dir, "push", "--force", "origin", g.branch); err != nil {
+		return "", err
+	}
+	if _, err := git(g.dir, "checkout", g.base); err != nil {
+		return "", err
+	}
+	return openPR(g.repo, resolver.PullRequest{Title: title, Body: body, Head: g.branch, Base: g.base})
+}
+
+// abort switches back to the base branch after a failed update, restoring
+// the manifest and the lock file if they were not committed yet.
+func (g *updateBranch) abort() {
+	for _, file := range g.files {
+		_, _ = git(g.dir, "checkout", "HEAD", "--", file)
+	}
+	if _, err := git(g.dir, "checkout", g.base); err == nil {
+		printf("🔁 Switched back to %s after the failure.\n", g.base)
+	}
+}
+
+// git runs git with args from dir and returns its trimmed standard output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// parseGitHubRemote returns the "org/repo" of a github.com remote URL, in
+// its HTTPS or SSH form.
+func parseGitHubRemote(url string) (string, error) {
+	_, rest, ok := strings.Cut(url, "github.com")
+	if !ok || rest == "" || (rest[0] != '/' && rest[0] != ':') {
+		return "", fmt.Errorf("remote %s is not on github.com", url)
+	}
+	repo := strings.TrimSuffix(strings.Trim(rest[1:], "/"), ".git")
+	if org, name, ok := strings.Cut(repo, "/"); !ok || org == "" || name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("remote %s does not name a GitHub repository", url)
+	}
+	return repo, nil
+}
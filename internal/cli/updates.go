@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+const (
+	// updatePrefetchConcurrency bounds simultaneous update lookups.
+	updatePrefetchConcurrency = 4
+	// updatePrefetchLimit caps lookups per run to stay well within API rate limits.
+	updatePrefetchLimit = 30
+	// updateHintTimeout is how long a command waits for pending lookups once
+	// its own work is done; lookups still in flight are dropped.
+	updateHintTimeout = 2 * time.Second
+)
+
+// updateHint reports that a floating ref now resolves to a newer commit than
+// the one recorded in the lock file.
+type updateHint struct {
+	Type      string
+	Name      string
+	LockedSHA string
+	LatestSHA string
+}
+
+// updatePrefetcher resolves the latest SHAs of floating refs in the
+// background so commands can annotate their output with "update available"
+// hints without waiting on the network.
+type updatePrefetcher struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	hints []updateHint
+}
+
+// startUpdatePrefetch launches background lookups for every locked entry
+// whose ref is floating (a branch or @latest). Pinned refs never change and
+// are skipped.
+func startUpdatePrefetch(res resolver.ResolverAPI, entries []manifest.Entry, lock *manifest.LockFile) *updatePrefetcher {
+	p := &updatePrefetcher{}
+	sem := make(chan struct{}, updatePrefetchConcurrency)
+
+	started := 0
+	for _, entry := range entries {
+		if started == updatePrefetchLimit {
+			break
+		}
+		ref, err := config.ParseRef(entry.Ref)
+		if err != nil || ref.IsPinned() {
+			continue
+		}
+		locked, ok := lock.Get(entry.Type, entry.Name)
+		if !ok || locked.Ref != entry.Ref {
+			continue
+		}
+
+		started++
+		p.wg.Add(1)
+		go func(entry manifest.Entry, ref config.AssetRef, lockedSHA string) {
+			defer p.wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			latest, err := res.ResolveSHA(ref)
+			if err != nil || latest == "" || latest == lockedSHA {
+				return
+			}
+			p.mu.Lock()
+			p.hints = append(p.hints, updateHint{
+				Type:      entry.Type,
+				Name:      entry.Name,
+				LockedSHA: lockedSHA,
+				LatestSHA: latest,
+			})
+			p.mu.Unlock()
+		}(entry, ref, locked.ResolvedSHA)
+	}
+
+	return p
+}
+
+// collect waits up to timeout for pending lookups and returns the hints
+// gathered so far, in byte-wise type/name order.
+func (p *updatePrefetcher) collect(timeout time.Duration) []updateHint {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hints := make([]updateHint, len(p.hints))
+	copy(hints, p.hints)
+	slices.SortFunc(hints, func(a, b updateHint) int {
+		return strings.Compare(a.Type+"/"+a.Name, b.Type+"/"+b.Name)
+	})
+	return hints
+}
+
+// shortSHA abbreviates a commit SHA for display.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
@@ -1,11 +1,11 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
-	"github.com/cbout22/copilot-sync/internal/auth"
 	"github.com/cbout22/copilot-sync/internal/config"
 	"github.com/cbout22/copilot-sync/internal/injector"
 	"github.com/cbout22/copilot-sync/internal/manifest"
@@ -14,15 +14,32 @@ import (
 
 // newUseCmd creates the `use` subcommand for a given asset type.
 // Usage: cops <type> use <name> <org/repo/path@ref>
+//
+//	cops <type> use --glob <org/repo/dir/pattern@ref>
 func newUseCmd(typeName string) *cobra.Command {
-	return &cobra.Command{
+	var glob bool
+
+	cmd := &cobra.Command{
 		Use:   "use <name> <org/repo/path@ref>",
 		Short: fmt.Sprintf("Add a %s entry and download it", typeName),
-		Long: fmt.Sprintf(`Adds a %s entry to copilot.toml and downloads the file from GitHub.
+		Long: fmt.Sprintf(`Adds a %s entry to the manifest (copilot.toml, or its YAML or JSON form)
+and downloads the file from GitHub. Comments and the order of entries of a
+YAML manifest are kept.
+
+With --glob, the single argument is a pattern whose last path segments may
+contain *, ? and [...] wildcards (as in path.Match: * does not cross '/').
+The remote directory is listed and one entry is added per match, named after
+the matched file without its extension.
 
 Example:
-  cops %s use my-asset my-org/repo/path/to/file@v1.0`, typeName, typeName),
-		Args: cobra.ExactArgs(2),
+  cops %s use my-asset my-org/repo/path/to/file@v1.0
+  cops %s use --glob 'my-org/repo/path/to/*.md@v1.0'`, typeName, typeName, typeName),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if glob {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 1 {
 				return resolveGitHubCompletions(toComplete)
@@ -30,21 +47,30 @@ Example:
 			return nil, cobra.ShellCompDirectiveDefault
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if glob {
+				return runUseGlob(cmd.Context(), typeName, args[0])
+			}
+
 			name := args[0]
 			rawRef := args[1]
 
-			return runUse(typeName, name, rawRef)
+			return runUse(cmd.Context(), typeName, name, rawRef)
 		},
 	}
+
+	cmd.Flags().BoolVar(&glob, "glob", false, "Add one entry per remote file matching the pattern")
+
+	return cmd
 }
 
-func runUse(typeName, name, rawRef string) error {
-	client, err := auth.NewHTTPClient()
+func runUse(ctx context.Context, typeName, name, rawRef string) error {
+	res, err := newResolver(ctx)
 	if err != nil {
 		return err
 	}
-	res := resolver.New(client)
-	return runUseWith(typeName, name, rawRef, manifest.DefaultManifestFile, manifest.DefaultLockFile, res, ".")
+	return withProjectLock(lockFile(), func() error {
+		return runUseWith(typeName, name, rawRef, manifestFile(), lockFile(), res, ".")
+	})
 }
 
 // runUseWith is the testable core of the use command.
@@ -54,33 +80,73 @@ func runUseWith(typeName, name, rawRef, manifestPath, lockPath string, res resol
 		return fmt.Errorf("invalid asset type: %s", typeName)
 	}
 
-	// Validate the ref format early
-	if _, err := config.ParseRef(rawRef); err != nil {
-		return err
-	}
-
 	// Load or create the manifest
 	m, err := manifest.Load(manifestPath)
 	if err != nil {
 		return fmt.Errorf("loading manifest: %w", err)
 	}
 
+	// Validate the ref format early. The manifest keeps a [sources] alias
+	// as written; the lock records the expanded ref.
+	expandedRef, err := m.ExpandRef(rawRef)
+	if err != nil {
+		return err
+	}
+	ref, err := config.ParseRef(expandedRef)
+	if err != nil {
+		return err
+	}
+	policies, err := manifest.LoadPolicies(rootDir, manifest.OrgPolicyPath())
+	if err != nil {
+		return err
+	}
+	if err := checkSource(policies, res, expandedRef); err != nil {
+		return err
+	}
+	if ref.IsURL() && assetType.IsDirectory() {
+		return fmt.Errorf("%s cannot be sourced from a URL: only single-file assets are supported", typeName)
+	}
+
 	// Load the lock file
 	lock, err := manifest.LoadLock(lockPath)
 	if err != nil {
 		return fmt.Errorf("loading lock file: %w", err)
 	}
 
+	entry := m.Entry(typeName, name)
+	if assetType.IsSingleton() {
+		section, _ := m.Section(typeName)
+		for other := range section {
+			if other != name {
+				return fmt.Errorf("%s already holds %s: run 'cops %s unuse %s' first", typeName, other, typeName, other)
+			}
+		}
+		if existing := unmanagedFile(entry, lock, rootDir); existing != "" {
+			return fmt.Errorf("%s was not written by cops, and %s/%s would replace the entire file: move what it holds into the asset, or delete it first", existing, typeName, name)
+		}
+		printf("⚠️  %s/%s replaces the entire %s\n", typeName, name, entry.TargetPath())
+	}
+	if assetType == config.SetupSteps && !ref.IsPinned() {
+		printf("⚠️  %s/%s follows %s: pin it to a tag or commit, so the coding agent's environment only changes when you update it\n", typeName, name, rawRef)
+	}
+
 	// Create injector
 	inj := injector.New(res, lock, rootDir)
+	inj.SetReadOnly(m.ReadOnly)
+	n, err := parallelism(m)
+	if err != nil {
+		return err
+	}
+	inj.SetParallelism(n)
 
-	fmt.Printf("📦 Adding %s/%s from %s...\n", typeName, name, rawRef)
+	printf("📦 Adding %s/%s from %s...\n", typeName, name, rawRef)
 
 	// Download and inject the asset
-	result := inj.Inject(assetType, name, rawRef)
+	result := inj.InjectTo(assetType, name, expandedRef, entry.TargetPath(), guardLicense(injectOptions(m, entry), policies))
 	if result.Err != nil {
 		return fmt.Errorf("failed to download: %w", result.Err)
 	}
+	printWarnings(typeName+"/"+name, result.Warnings)
 
 	// Update the manifest
 	if err := m.Set(typeName, name, rawRef); err != nil {
@@ -97,6 +163,6 @@ func runUseWith(typeName, name, rawRef, manifestPath, lockPath string, res resol
 		return fmt.Errorf("saving lock file: %w", err)
 	}
 
-	fmt.Printf("✅ %s/%s synced to %s\n", typeName, name, result.TargetPath)
+	printf("✅ %s/%s synced to %s\n", typeName, name, result.TargetPath)
 	return nil
 }
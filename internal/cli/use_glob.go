@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/injector"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// globMeta are the characters that make a path segment a pattern.
+const globMeta = "*?["
+
+// globMatch is an entry derived from a file (or skill directory) matching a
+// glob pattern.
+type globMatch struct {
+	name   string
+	rawRef string // the pattern with its wildcard segments replaced
+}
+
+func runUseGlob(ctx context.Context, typeName, pattern string) error {
+	res, err := newResolver(ctx)
+	if err != nil {
+		return err
+	}
+	return withProjectLock(lockFile(), func() error {
+		return runUseGlobWith(typeName, pattern, manifestFile(), lockFile(), res, ".")
+	})
+}
+
+// runUseGlobWith is the testable core of `use --glob`.
+func runUseGlobWith(typeName, pattern, manifestPath, lockPath string, res resolver.ResolverAPI, rootDir string) error {
+	assetType := config.AssetType(typeName)
+	if !assetType.IsValid() {
+		return fmt.Errorf("invalid asset type: %s", typeName)
+	}
+	if assetType.IsSingleton() {
+		return fmt.Errorf("%s holds a single entry: add it without --glob", typeName)
+	}
+
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("loading manifest: %w", err)
+	}
+	expanded, err := m.ExpandRef(pattern)
+	if err != nil {
+		return err
+	}
+	policies, err := manifest.LoadPolicies(rootDir, manifest.OrgPolicyPath())
+	if err != nil {
+		return err
+	}
+	if err := checkSource(policies, res, expanded); err != nil {
+		return err
+	}
+	matches, err := expandGlob(assetType, pattern, expanded, res)
+	if err != nil {
+		return err
+	}
+
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	inj := injector.New(res, lock, rootDir)
+	inj.SetReadOnly(m.ReadOnly)
+	n, err := parallelism(m)
+	if err != nil {
+		return err
+	}
+	inj.SetParallelism(n)
+
+	printf("📦 Adding %d %s matching %s...\n\n", len(matches), typeName, pattern)
+
+	var failed int
+	for _, match := range matches {
+		ref, err := m.ExpandRef(match.rawRef)
+		var warnings []string
+		if err == nil {
+			result := inj.InjectTo(assetType, match.name, ref, m.TargetPath(typeName, match.name), guardLicense(injectOptions(m, m.Entry(typeName, match.name)), policies))
+			err, warnings = result.Err, result.Warnings
+		}
+		if err != nil {
+			printf("  ❌ %s/%s — %v\n", typeName, match.name, err)
+			failed++
+			continue
+		}
+		if err := m.Set(typeName, match.name, match.rawRef); err != nil {
+			return err
+		}
+		printf("  ✅ %s/%s ← %s\n", typeName, match.name, match.rawRef)
+		printWarnings(typeName+"/"+match.name, warnings)
+	}
+
+	// Keep what was downloaded even if some matches failed.
+	if err := m.Save(manifestPath); err != nil {
+		return fmt.Errorf("saving manifest: %w", err)
+	}
+	if err := lock.Save(lockPath); err != nil {
+		return fmt.Errorf("saving lock file: %w", err)
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d match(es) failed to download", failed, len(matches))
+	}
+	printf("✅ Added %d %s.\n", len(matches), typeName)
+	return nil
+}
+
+// expandGlob lists the remote directory above the first wildcard segment of
+// the expanded pattern and returns one entry per matching file, or per
+// matching directory for skills, in byte-wise path order. Entry refs keep
+// the pattern's form (aliases included) with the wildcard segments
+// replaced by the matched ones.
+func expandGlob(assetType config.AssetType, rawPattern, expanded string, res resolver.ResolverAPI) ([]globMatch, error) {
+	ref, err := config.ParseRef(expanded)
+	if err != nil {
+		return nil, err
+	}
+	if !ref.IsGitHub() {
+		return nil, fmt.Errorf("--glob only supports GitHub references (org/repo/path@ref)")
+	}
+	if _, err := path.Match(ref.Path, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", rawPattern, err)
+	}
+
+	segments := strings.Split(ref.Path, "/")
+	first := slices.IndexFunc(segments, func(s string) bool { return strings.ContainsAny(s, globMeta) })
+	switch first {
+	case -1:
+		return nil, fmt.Errorf("pattern %q has no wildcard; use 'use <name> <ref>' for a single entry", rawPattern)
+	case 0:
+		return nil, fmt.Errorf("pattern %q must start with a directory (e.g. org/repo/dir/*.md@ref)", rawPattern)
+	}
+	wildcards := strings.Join(segments[first:], "/")
+	if !strings.Contains(rawPattern, wildcards) {
+		// Cannot happen for aliases, which only prefix the path.
+		return nil, fmt.Errorf("pattern %q: wildcards must be in the last path segments", rawPattern)
+	}
+
+	dir := ref
+	dir.Path = strings.Join(segments[:first], "/")
+	files, err := res.ListDirectory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir.Path, err)
+	}
+
+	candidates := make(map[string]bool)
+	for _, f := range files {
+		if f.Type != "" && f.Type != "blob" {
+			continue
+		}
+		if !assetType.IsDirectory() {
+			candidates[f.Path] = true
+			continue
+		}
+		// Skills are directories: every directory below dir is a candidate.
+		for p := path.Dir(f.Path); strings.HasPrefix(p, dir.Path+"/"); p = path.Dir(p) {
+			candidates[p] = true
+		}
+	}
+
+	var matches []globMatch
+	names := make(map[string]string)
+	for _, p := range manifest.SortedKeys(candidates) {
+		if ok, _ := path.Match(ref.Path, p); !ok {
+			continue
+		}
+		name := assetName(assetType, path.Base(p))
+		if !manifest.ValidName(name) {
+			return nil, fmt.Errorf("%s: cannot derive a valid entry name from %q", p, path.Base(p))
+		}
+		if other, dup := names[name]; dup {
+			return nil, fmt.Errorf("%s and %s would both be named %q", other, p, name)
+		}
+		names[name] = p
+		matched := strings.Join(strings.Split(p, "/")[first:], "/")
+		matches = append(matches, globMatch{
+			name:   name,
+			rawRef: strings.Replace(rawPattern, wildcards, matched, 1),
+		})
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no %s match %s", assetType, rawPattern)
+	}
+	return matches, nil
+}
+
+// assetName derives an entry name from a file or directory name by
+// removing the type's extension ("review.instructions.md" → "review") or,
+// failing that, the last extension ("review.md" → "review").
+func assetName(assetType config.AssetType, base string) string {
+	if assetType.IsDirectory() {
+		return base
+	}
+	if ext := assetType.FileExtension(); strings.HasSuffix(base, ext) && base != ext {
+		return strings.TrimSuffix(base, ext)
+	}
+	if ext := path.Ext(base); ext != base {
+		return strings.TrimSuffix(base, ext)
+	}
+	return base
+}
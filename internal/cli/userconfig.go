@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/auth"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+// userConfig is the subset of the user configuration file
+// (auth.UserConfigPath) read by the commands; auth reads the credentials
+// and network settings. Everything it sets ranks below the project's
+// copilot.toml, the environment and the command line.
+type userConfig struct {
+	// CacheDir replaces the cops folder of the OS user cache directory,
+	// holding the content store and the HTTP cache. A leading "~/" is
+	// expanded to the home directory.
+	CacheDir string `toml:"cache_dir"`
+
+	// Flags maps a command, such as "sync" or "lock rebuild", to the
+	// flags it runs with unless given on the command line, written
+	// "--name" or "--name=value".
+	Flags map[string][]string `toml:"flags"`
+
+	// CIFlags is Flags for CI runs (see inCI), applied over Flags.
+	CIFlags map[string][]string `toml:"ci_flags"`
+
+	// Sources holds personal source aliases, known to every manifest
+	// beneath its own [sources].
+	Sources map[string]string `toml:"sources"`
+}
+
+// userCfg is the user configuration, read by applyUserConfig.
+var userCfg userConfig
+
+// loadUserConfig reads the user configuration file. A missing file, or a
+// missing configuration directory, yields an empty configuration.
+func loadUserConfig() (userConfig, error) {
+	var cfg userConfig
+	path, err := auth.UserConfigPath()
+	if err != nil {
+		return cfg, nil
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if cfg.CacheDir, err = auth.ExpandHome(cfg.CacheDir); err != nil {
+		return cfg, fmt.Errorf("cache_dir: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyUserConfig reads the user configuration and applies it to cmd,
+// about to run: its flags defaults and source aliases.
+func applyUserConfig(cmd *cobra.Command) error {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+	userCfg = cfg
+	if err := manifest.SetUserSources(cfg.Sources); err != nil {
+		return fmt.Errorf("user configuration: sources: %w", err)
+	}
+	flags := []map[string][]string{cfg.Flags}
+	if inCI() {
+		flags = append(flags, cfg.CIFlags)
+	}
+	return applyDefaultFlags(cmd, flags...)
+}
+
+// inCI reports whether cops runs in CI, as the CI variable most CI
+// services set says.
+func inCI() bool {
+	return envEnabled("CI")
+}
+
+// applyDefaultFlags sets the flags each of defaults lists for cmd, in
+// order, so later tables win. Flags given on the command line are kept.
+// Commands that do not exist, and flags cmd does not have, are errors,
+// so a typo is not silently ignored.
+func applyDefaultFlags(cmd *cobra.Command, defaults ...map[string][]string) error {
+	root := cmd.Root()
+	path := strings.TrimPrefix(cmd.CommandPath(), root.Name()+" ")
+	set := make(map[string]bool) // by the defaults, not the command line
+
+	for _, table := range defaults {
+		for _, name := range manifest.SortedKeys(table) {
+			if found, _, err := root.Find(strings.Fields(name)); err != nil || found == root || strings.TrimPrefix(found.CommandPath(), root.Name()+" ") != name {
+				return fmt.Errorf("user configuration: flags: unknown command %q", name)
+			}
+		}
+		for _, arg := range table[path] {
+			flagName, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			flag := cmd.Flags().Lookup(flagName)
+			switch {
+			case !strings.HasPrefix(arg, "--") || flag == nil:
+				return fmt.Errorf("user configuration: flags: %q is not a flag of '%s'", arg, cmd.CommandPath())
+			case !hasValue && flag.NoOptDefVal == "":
+				return fmt.Errorf("user configuration: flags: %q needs a value, e.g. --%s=<value>", arg, flagName)
+			case !hasValue:
+				value = flag.NoOptDefVal
+			}
+			if cmd.Flags().Changed(flagName) && !set[flagName] {
+				continue
+			}
+			if err := cmd.Flags().Set(flagName, value); err != nil {
+				return fmt.Errorf("user configuration: flags: %s: %w", arg, err)
+			}
+			set[flagName] = true
+		}
+	}
+	return nil
+}
+
+// cacheDir returns the folder replacing the cops folder of the OS user
+// cache directory: $COPS_CACHE_DIR if set, the cache_dir of the user
+// configuration otherwise. Empty keeps the OS default.
+func cacheDir() string {
+	if dir := os.Getenv(cacheDirEnvVar); dir != "" {
+		return dir
+	}
+	return userCfg.CacheDir
+}
+
+// contentStore returns the user-level content store: $COPS_STORE if set,
+// the cache directory (see cacheDir), then the OS default.
+func contentStore() (store.Store, error) {
+	if dir := cacheDir(); os.Getenv(store.EnvVar) == "" && dir != "" {
+		return store.Store{Dir: dir}, nil
+	}
+	return store.Default()
+}
+
+// httpCacheDir returns where HTTP responses are cached: $COPS_HTTP_CACHE
+// if set, the http folder of the cache directory (see cacheDir), then the
+// OS default.
+func httpCacheDir() string {
+	if dir := cacheDir(); os.Getenv(resolver.HTTPCacheEnvVar) == "" && dir != "" {
+		return filepath.Join(dir, "http")
+	}
+	return resolver.DefaultHTTPCacheDir()
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// newValidateCmd creates the `validate` command.
+// Usage: cops validate [manifest]
+func newValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [manifest]",
+		Short: "Check the manifest for mistakes without contacting GitHub",
+		Long: `Checks copilot.toml (or the given manifest) beyond its syntax: unknown
+sections and options, empty or invalid entry names, malformed refs and
+options, unknown source aliases, and entries written to the same target.
+
+Every problem is reported with its line and column. No network call is made,
+so validate is a cheap first step in CI and pre-commit hooks.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := manifestFile()
+			if len(args) == 1 {
+				path = userPath(args[0])
+			}
+			return runValidateWith(path)
+		},
+	}
+}
+
+// runValidateWith is the testable core of the validate command.
+func runValidateWith(manifestPath string) error {
+	problems, err := manifest.Validate(manifestPath)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		printf("✅ %s is valid.\n", manifestPath)
+		return nil
+	}
+
+	for _, p := range problems {
+		printf("  ❌ %s\n", p)
+	}
+	fmt.Println()
+	return fmt.Errorf("%d problem(s) found in %s", len(problems), manifestPath)
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/ui"
+)
+
+// newVerifyCmd creates the `verify` command.
+// Usage: cops verify
+func newVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the lock file and the assets it records",
+		Long: `Checks that .cops.lock is intact, then that every asset it records is
+still on disk with the content, size and permissions it was synced with.
+Unlike check, verify does not read copilot.toml.
+
+A lock file that was edited by hand or only partly written is reported as
+corrupted, separately from assets that drifted: recreate it with
+'cops lock rebuild', and restore drifted assets with 'cops sync'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerifyWith(lockFile(), ".")
+		},
+	}
+}
+
+// runVerifyWith is the testable core of the verify command.
+func runVerifyWith(lockPath, rootDir string) error {
+	lock, err := manifest.LoadLock(lockPath)
+	if errors.Is(err, manifest.ErrCorruptLock) {
+		printf("🔒 %s: %v\n", lockPath, err)
+		printf("   Run 'cops lock rebuild' to recreate it from copilot.toml and the files on disk.\n")
+		return fmt.Errorf("corrupted lock file %s", lockPath)
+	}
+	if err != nil {
+		return fmt.Errorf("loading lock file: %w", err)
+	}
+	if len(lock.Entries) == 0 {
+		printf("📋 No entries in %s — nothing to verify.\n", lockPath)
+		return nil
+	}
+
+	printf("🔍 Verifying %d asset(s) against %s...\n\n", len(lock.Entries), lockPath)
+
+	var drifted int
+	for _, key := range manifest.SortedKeys(lock.Entries) {
+		e := lock.Entries[key]
+		target := filepath.Join(rootDir, e.TargetPath)
+		if _, err := os.Stat(target); err != nil {
+			printf("  ❌ %s — missing (was synced at %s)\n", key, e.SyncedAt)
+			drifted++
+			continue
+		}
+		isDir := config.AssetType(e.Type).IsDirectory()
+		problem := verifyContent(e, target, isDir)
+		if problem == "" {
+			problem = verifyOutputs(e, rootDir, isDir)
+		}
+		if problem != "" {
+			printf("  ❌ %s — %s\n", key, ui.Text(problem))
+			drifted++
+			continue
+		}
+		printf("  ✅ %s — ok\n", key)
+	}
+
+	fmt.Println()
+	if drifted > 0 {
+		return fmt.Errorf("%d asset(s) drifted from the lock file. Run 'cops sync' to restore them", drifted)
+	}
+	printf("✅ Lock file intact and all assets match it.\n")
+	return nil
+}
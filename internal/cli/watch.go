@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
+	"github.com/cbout22/copilot-sync/internal/ui"
+)
+
+// watchOptions holds the flags accepted by the watch command.
+type watchOptions struct {
+	// Sync holds the options of every sync; only changed entries are
+	// synced.
+	Sync syncOptions
+
+	// Interval is how often copilot.toml is looked at for changes.
+	Interval time.Duration
+
+	// Remote is how often the floating refs of copilot.toml are resolved
+	// to catch new upstream commits. Zero disables it.
+	Remote time.Duration
+
+	// Live rewrites the status line in place, as on a terminal.
+	Live bool
+}
+
+// newWatchCmd creates the `watch` command.
+// Usage: cops watch [--interval <duration>] [--remote <duration>] [--group <name>]... [--env <env>] [--no-global] [--force]
+func newWatchCmd() *cobra.Command {
+	var opts watchOptions
+	var noGlobal bool
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Re-sync assets whenever copilot.toml changes",
+		Long: `Syncs the assets of copilot.toml, then keeps watching it: every time it
+is saved (or its copilot.<env>.toml overlay), the entries that changed are
+synced again. A status line shows what is watched and when the last sync
+ran. Stop with Ctrl-C.
+
+With --remote, the branches the manifest tracks are also resolved at that
+interval, and entries whose branch got new commits upstream are synced:
+handy while iterating on a shared prompt repository.
+
+A failed sync, such as one of a half-edited manifest, is reported and the
+watch goes on. Files edited by hand are skipped unless --force is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			if opts.Remote < 0 {
+				return fmt.Errorf("--remote must not be negative")
+			}
+			opts.Sync.GlobalManifest = globalManifest(noGlobal)
+			opts.Sync.Env = manifestEnv(opts.Sync.Env)
+			opts.Sync.Context = cmd.Context()
+			st, err := contentStore()
+			opts.Sync.Store, opts.Sync.Cache = st, err == nil && !store.CacheDisabled()
+			if stat, err := os.Stdout.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				opts.Live, opts.Sync.Live = true, true
+			}
+			// A fresh resolver per sync, so refs are resolved again
+			// rather than answered from the previous sync.
+			newRes := func() (resolver.ResolverAPI, error) { return newResolver(cmd.Context()) }
+			return runWatchWith(opts, manifestFile(), lockFile(), newRes, ".")
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.Interval, "interval", time.Second, "How often copilot.toml is checked for changes")
+	cmd.Flags().DurationVar(&opts.Remote, "remote", 0, "Also look for new commits on tracked branches this often, e.g. 5m (default off)")
+	cmd.Flags().StringSliceVar(&opts.Sync.Groups, "group", nil, "Only sync entries in this group (repeatable)")
+	cmd.Flags().StringVar(&opts.Sync.Env, "env", "", "Apply the copilot.<env>.toml overlay (default $COPS_ENV)")
+	cmd.Flags().BoolVar(&noGlobal, "no-global", false, "Ignore the user-level manifest")
+	cmd.Flags().BoolVar(&opts.Sync.Force, "force", false, "Overwrite files edited since the last sync")
+
+	return cmd
+}
+
+// runWatchWith is the testable core of the watch command. It returns once
+// the context of opts.Sync is done; newRes creates the resolver of each
+// sync.
+func runWatchWith(opts watchOptions, manifestPath, lockPath string, newRes func() (resolver.ResolverAPI, error), rootDir string) error {
+	ctx := opts.Sync.context()
+	watched := []string{manifestPath}
+	if opts.Sync.Env != "" {
+		watched = append(watched, manifest.OverlayPath(manifestPath, opts.Sync.Env))
+	}
+	names := make([]string, len(watched))
+	for i, path := range watched {
+		names[i] = filepath.Base(path)
+	}
+	what := strings.Join(names, " and ")
+	if opts.Remote > 0 {
+		what += fmt.Sprintf(" and tracked branches (every %s)", opts.Remote)
+	}
+
+	status := &statusLine{live: opts.Live}
+	var lastSync string
+	sync := func(reason string, entries []string) {
+		status.clear()
+		printf("%s %s\n\n", time.Now().Format(time.TimeOnly), ui.Text(reason))
+		o := opts.Sync
+		o.Changed, o.Entries = entries == nil, entries
+		res, err := newRes()
+		if err == nil {
+			// The lock is held for each sync only, so other commands can
+			// run in between.
+			err = withProjectLock(lockPath, func() error {
+				return runSyncWith(o, manifestPath, lockPath, res, rootDir)
+			})
+		}
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			printf("❌ %v\n\n", err)
+			lastSync = time.Now().Format(time.TimeOnly) + " ❌"
+		default:
+			fmt.Println()
+			lastSync = time.Now().Format(time.TimeOnly) + " ✅"
+		}
+	}
+
+	sync("🔄 Syncing changed assets...", nil)
+	seen := fingerprint(watched)
+
+	files := time.NewTicker(opts.Interval)
+	defer files.Stop()
+	var remote <-chan time.Time
+	if opts.Remote > 0 {
+		t := time.NewTicker(opts.Remote)
+		defer t.Stop()
+		remote = t.C
+	}
+
+	for {
+		status.show(fmt.Sprintf("👀 Watching %s — last sync %s (Ctrl-C to stop)", what, lastSync))
+		select {
+		case <-ctx.Done():
+			status.clear()
+			printf("👋 Stopped watching.\n")
+			return nil
+		case <-files.C:
+			if fp := fingerprint(watched); fp != seen {
+				sync("📝 "+what+" changed", nil)
+				seen = fingerprint(watched)
+			}
+		case <-remote:
+			if moved := movedEntries(opts, manifestPath, lockPath, newRes); len(moved) > 0 {
+				sync("⬆️  New upstream commits for "+strings.Join(moved, ", "), moved)
+				seen = fingerprint(watched)
+			}
+		}
+	}
+}
+
+// movedEntries returns the entries tracking a branch whose branch now
+// resolves to another commit than the locked one, in byte-wise order.
+func movedEntries(opts watchOptions, manifestPath, lockPath string, newRes func() (resolver.ResolverAPI, error)) []string {
+	res, err := newRes()
+	if err != nil {
+		return nil
+	}
+	m, err := manifest.LoadWith(manifestPath, manifest.LoadOptions{
+		Env:        opts.Sync.Env,
+		GlobalPath: opts.Sync.GlobalManifest,
+		Fetch:      fetchTemplate(res),
+	})
+	if err != nil {
+		return nil
+	}
+	entries, err := m.EntriesInGroups(opts.Sync.Groups)
+	if err != nil {
+		return nil
+	}
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		return nil
+	}
+	var moved []string
+	for _, h := range startUpdatePrefetch(res, entries, lock).collect(opts.Remote) {
+		moved = append(moved, h.Type+"/"+h.Name)
+	}
+	return moved
+}
+
+// fingerprint identifies the contents of paths; missing files count as
+// empty.
+func fingerprint(paths []string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, path := range paths {
+		data, _ := os.ReadFile(path)
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(data))
+		h.Write(data)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// statusLine prints a status that is rewritten in place when live, and
+// printed again only when it changes otherwise.
+type statusLine struct {
+	live  bool
+	shown string
+}
+
+// show displays text as the status.
+func (s *statusLine) show(text string) {
+	if text == s.shown {
+		return
+	}
+	if s.live {
+		fmt.Print("\r\033[K" + ui.Text(text))
+	} else {
+		fmt.Println(ui.Text(text))
+	}
+	s.shown = text
+}
+
+// clear removes the status, so other output can follow.
+func (s *statusLine) clear() {
+	if s.live && s.shown != "" {
+		fmt.Print("\r\033[K")
+	}
+	s.shown = ""
+}
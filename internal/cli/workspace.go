@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// runWorkspaceWith runs fn for every member of the workspace whose
+// cops-workspace.toml sits in rootDir, passing the member directory, and
+// reports failures once all members have run.
+func runWorkspaceWith(rootDir string, fn func(memberDir string) error) error {
+	ws, err := manifest.LoadWorkspace(filepath.Join(rootDir, manifest.DefaultWorkspaceFile))
+	if err != nil {
+		return err
+	}
+	dirs, err := ws.MemberDirs(rootDir)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, dir := range dirs {
+		name := filepath.ToSlash(dir)
+		printf("📂 %s\n", name)
+		if err := fn(filepath.Join(rootDir, dir)); err != nil {
+			printf("❌ %s: %s\n", name, err)
+			failed = append(failed, name)
+		}
+		fmt.Println()
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d workspace member(s) failed: %s", len(failed), len(dirs), strings.Join(failed, ", "))
+	}
+	printf("✅ All %d workspace member(s) succeeded.\n", len(dirs))
+	return nil
+}
+
+// memberPaths returns the manifest and lock file paths of a workspace member.
+func memberPaths(dir string) (manifestPath, lockPath string) {
+	return manifest.Find(dir), filepath.Join(dir, manifest.DefaultLockFile)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TypeDef describes an asset type declared in a manifest's [types] table,
+// for files Copilot or another tool reads that cops has no built-in type
+// for, such as .github/copilot/rules/<name>.rule.md.
+type TypeDef struct {
+	// Dir is where assets of the type are written, relative to the output
+	// root. Default: the name of the type.
+	Dir string `toml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Extension is appended to the entry name to form the file name, e.g.
+	// ".rule.md". Directory types have none.
+	Extension string `toml:"extension,omitempty" json:"extension,omitempty"`
+
+	// Directory makes each entry a whole directory, like skills.
+	Directory bool `toml:"directory,omitempty" json:"directory,omitempty"`
+}
+
+// typeNamePattern matches the names custom asset types may take, which are
+// also manifest sections and command names.
+var typeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// reservedTypeNames are the top-level manifest keys that are not sections.
+var reservedTypeNames = []string{"extends", "include", "output_root", "readonly", "sources", "default_ref", "template", "targets", "limits", "hooks", "settings", "types", "collections", "dirs"}
+
+var (
+	customMu    sync.RWMutex
+	customTypes = make(map[AssetType]TypeDef)
+)
+
+// CheckType validates the declaration of the custom asset type name. Errors
+// do not repeat the name.
+func CheckType(name string, def TypeDef) error {
+	switch {
+	case !typeNamePattern.MatchString(name):
+		return fmt.Errorf("invalid type name: use lowercase letters, digits and dashes")
+	case isBuiltin(AssetType(name)):
+		return fmt.Errorf("%s is a built-in type", name)
+	case slices.Contains(reservedTypeNames, name):
+		return fmt.Errorf("%s is a reserved manifest key", name)
+	case def.Dir != "" && !projectDir(def.Dir):
+		return fmt.Errorf("dir %q must be a relative path inside the project, out of .git", def.Dir)
+	case def.Directory && def.Extension != "":
+		return fmt.Errorf("directory types have no extension")
+	case strings.ContainsAny(def.Extension, `/\`):
+		return fmt.Errorf("invalid extension %q", def.Extension)
+	}
+	return nil
+}
+
+// projectDir reports whether the slash-separated path dir stays inside the
+// project and out of its .git directory, where written files could be run
+// as git hooks.
+func projectDir(dir string) bool {
+	dir = filepath.FromSlash(dir)
+	if !filepath.IsLocal(dir) {
+		return false
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(dir)), "/")
+	return !strings.EqualFold(first, ".git")
+}
+
+// RegisterType makes name a valid asset type, defined by def. Registering
+// a type again replaces its definition.
+func RegisterType(name string, def TypeDef) error {
+	if err := CheckType(name, def); err != nil {
+		return err
+	}
+	customMu.Lock()
+	defer customMu.Unlock()
+	customTypes[AssetType(name)] = def
+	return nil
+}
+
+// CustomTypes returns the registered custom asset types, by name.
+func CustomTypes() []AssetType {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	types := make([]AssetType, 0, len(customTypes))
+	for t := range customTypes {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// custom returns the definition of t if it is a registered custom type.
+func (t AssetType) custom() (TypeDef, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	def, ok := customTypes[t]
+	return def, ok
+}
+
+// IsCustom reports whether t is a registered custom type.
+func (t AssetType) IsCustom() bool {
+	_, ok := t.custom()
+	return ok
+}
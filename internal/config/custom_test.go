@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRegisterType(t *testing.T) {
+	t.Parallel()
+	if err := RegisterType("test-rules", TypeDef{Dir: "copilot/rules", Extension: ".rule.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterType("test-packs", TypeDef{Directory: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, packs := AssetType("test-rules"), AssetType("test-packs")
+	if !rules.IsValid() || !rules.IsCustom() || rules.IsDirectory() {
+		t.Errorf("test-rules: IsValid %v, IsCustom %v, IsDirectory %v", rules.IsValid(), rules.IsCustom(), rules.IsDirectory())
+	}
+	if got, want := rules.TargetPath("team"), filepath.Join(".github", "copilot", "rules", "team.rule.md"); got != want {
+		t.Errorf("TargetPath() = %q, want %q", got, want)
+	}
+	if !packs.IsDirectory() || packs.FileExtension() != "" {
+		t.Errorf("test-packs: IsDirectory %v, FileExtension %q", packs.IsDirectory(), packs.FileExtension())
+	}
+	if got, want := packs.TargetPathIn("docs/ai", "p1"), filepath.Join("docs", "ai", "test-packs", "p1"); got != want {
+		t.Errorf("TargetPathIn() = %q, want %q", got, want)
+	}
+	if types := ValidAssetTypes(); !slices.Contains(types, rules) || types[0] != Instructions {
+		t.Errorf("ValidAssetTypes() = %v", types)
+	}
+	if Instructions.IsCustom() {
+		t.Error("instructions: IsCustom() = true")
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		def  TypeDef
+	}{
+		{"Rules", TypeDef{}},
+		{"1rules", TypeDef{}},
+		{"skills", TypeDef{}},
+		{"settings", TypeDef{}},
+		{"rules", TypeDef{Dir: "../rules"}},
+		{"rules", TypeDef{Dir: "/etc"}},
+		{"rules", TypeDef{Dir: ".git/hooks"}},
+		{"rules", TypeDef{Dir: ".GIT"}},
+		{"rules", TypeDef{Directory: true, Extension: ".md"}},
+		{"rules", TypeDef{Extension: "x/y.md"}},
+	}
+	for _, tc := range cases {
+		if err := CheckType(tc.name, tc.def); err == nil {
+			t.Errorf("CheckType(%q, %+v): expected an error", tc.name, tc.def)
+		}
+	}
+	if err := CheckType("rules", TypeDef{Dir: "copilot/rules", Extension: ".rule.md"}); err != nil {
+		t.Errorf("CheckType: %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -13,23 +16,62 @@ const (
 	Instructions AssetType = "instructions"
 	Agents       AssetType = "agents"
 	Prompts      AssetType = "prompts"
+	Chatmodes    AssetType = "chatmodes"
 	Skills       AssetType = "skills"
+
+	// RootInstructions is the repository-wide copilot-instructions.md,
+	// which Copilot applies to every request.
+	RootInstructions AssetType = "root-instructions"
+
+	// AgentsMD is the AGENTS.md at the root of the project, which many
+	// coding agents read.
+	AgentsMD AssetType = "agents-md"
+
+	// SetupSteps is the workflow preparing the environment of the Copilot
+	// coding agent, .github/workflows/copilot-setup-steps.yml.
+	SetupSteps AssetType = "setup-steps"
 )
 
-// ValidAssetTypes returns all supported asset types.
+// singletonFiles maps the singleton types to the name of the file they are
+// written to, in their TargetDirIn.
+var singletonFiles = map[AssetType]string{
+	RootInstructions: "copilot-instructions.md",
+	AgentsMD:         "AGENTS.md",
+	SetupSteps:       "copilot-setup-steps.yml",
+}
+
+// SingletonFile returns the name of the file a singleton type is written
+// to, or "" for other types.
+func (t AssetType) SingletonFile() string {
+	return singletonFiles[t]
+}
+
+// ValidAssetTypes returns all supported asset types: the built-in ones,
+// then the registered custom types (see RegisterType).
 func ValidAssetTypes() []AssetType {
-	return []AssetType{Instructions, Agents, Prompts, Skills}
+	return append([]AssetType{Instructions, Agents, Prompts, Chatmodes, Skills, RootInstructions, AgentsMD, SetupSteps}, CustomTypes()...)
 }
 
 // IsValid checks whether the asset type is one of the known types.
 func (t AssetType) IsValid() bool {
+	return isBuiltin(t) || t.IsCustom()
+}
+
+// isBuiltin reports whether t is one of the types cops knows natively.
+func isBuiltin(t AssetType) bool {
 	switch t {
-	case Instructions, Agents, Prompts, Skills:
+	case Instructions, Agents, Prompts, Chatmodes, Skills, RootInstructions, AgentsMD, SetupSteps:
 		return true
 	}
 	return false
 }
 
+// IsSingleton reports whether the type holds a single entry, written to a
+// fixed file whatever its name: RootInstructions, AgentsMD and SetupSteps.
+func (t AssetType) IsSingleton() bool {
+	return t.SingletonFile() != ""
+}
+
 // FileExtension returns the file suffix used for this type.
 // Skills are directories, so they return an empty string.
 func (t AssetType) FileExtension() string {
@@ -40,42 +82,198 @@ func (t AssetType) FileExtension() string {
 		return ".agent.md"
 	case Prompts:
 		return ".prompt.md"
+	case Chatmodes:
+		return ".chatmode.md"
 	case Skills:
 		return "" // skills are directories
+	case RootInstructions, AgentsMD:
+		return ".md"
+	case SetupSteps:
+		return ".yml"
 	}
-	return ""
+	def, _ := t.custom()
+	return def.Extension
 }
 
+// DefaultOutputRoot is the directory assets are written under, relative to
+// the project root, unless the manifest sets output_root.
+const DefaultOutputRoot = ".github"
+
 // TargetDir returns the .github subdirectory where assets of this type live.
 func (t AssetType) TargetDir() string {
-	return filepath.Join(".github", string(t))
+	return t.TargetDirIn(DefaultOutputRoot)
+}
+
+// TargetDirIn returns the subdirectory of outputRoot, a slash-separated path
+// relative to the project root, where assets of this type live. An empty
+// outputRoot means DefaultOutputRoot. Singletons live in outputRoot itself,
+// except those read from a fixed place: AgentsMD at the root of the
+// project, and SetupSteps with the GitHub workflows.
+func (t AssetType) TargetDirIn(outputRoot string) string {
+	switch t {
+	case AgentsMD:
+		return ""
+	case SetupSteps:
+		return filepath.Join(DefaultOutputRoot, "workflows")
+	}
+	if outputRoot == "" {
+		outputRoot = DefaultOutputRoot
+	}
+	if t.IsSingleton() {
+		return filepath.FromSlash(outputRoot)
+	}
+	if def, ok := t.custom(); ok && def.Dir != "" {
+		return filepath.Join(filepath.FromSlash(outputRoot), filepath.FromSlash(def.Dir))
+	}
+	return filepath.Join(filepath.FromSlash(outputRoot), string(t))
 }
 
 // TargetPath returns the full relative path for a named asset.
-// For skills this returns a directory path; for others a file path.
+// For skills this returns a directory path; for others a file path. The
+// path of a singleton does not depend on name.
 func (t AssetType) TargetPath(name string) string {
-	if t == Skills {
-		return filepath.Join(t.TargetDir(), name)
+	return t.TargetPathIn(DefaultOutputRoot, name)
+}
+
+// TargetPathIn is TargetPath for assets written under outputRoot (see
+// TargetDirIn).
+func (t AssetType) TargetPathIn(outputRoot, name string) string {
+	return t.PathIn(t.TargetDirIn(outputRoot), name)
+}
+
+// PathIn returns where the asset name of this type is written when dir is
+// the directory of the type: a file with the type's extension, or a folder
+// for directory types.
+func (t AssetType) PathIn(dir, name string) string {
+	if t.IsSingleton() {
+		return filepath.Join(dir, t.SingletonFile())
+	}
+	if t.IsDirectory() {
+		return filepath.Join(dir, name)
 	}
-	return filepath.Join(t.TargetDir(), name+t.FileExtension())
+	return filepath.Join(dir, name+t.FileExtension())
 }
 
-// IsDirectory returns true if this asset type maps to a folder (skills).
+// IsDirectory returns true if this asset type maps to a folder (skills,
+// and custom types declared as directories).
 func (t AssetType) IsDirectory() bool {
-	return t == Skills
+	if t == Skills {
+		return true
+	}
+	def, _ := t.custom()
+	return def.Directory
 }
 
-// AssetRef represents a parsed reference like "org/repo/path/to/file@v1.2".
+// AssetRef represents a parsed reference like "org/repo/path/to/file@v1.2",
+// a direct "https://host/path/file.md" URL, an OCI artifact such as
+// "oci://ghcr.io/org/bundle:v1//path/in/bundle", a GitHub release asset
+// such as "org/repo!release:v1.2.0/assets.tar.gz//path/in/archive", an
+// object-store key such as "s3://bucket/prefix/review.md@<version-id>", a
+// registry package such as "registry:awesome/code-review@1.2.0", or a path
+// in the project such as "local:.github/prompts/review.prompt.md".
+//
+// For OCI refs, Repo holds the repository path inside the registry, Ref the
+// tag or "sha256:..." digest, and Path the optional location inside the
+// bundle. For release refs, Ref holds the release tag and Path the optional
+// location inside the archive. For bucket refs, Path holds the object key
+// (or key prefix, for directories) and Ref the optional object version: an
+// S3 version ID or a GCS generation number. For registry refs, Package holds
+// the package name, Ref the package version (or "latest") and Path the
+// optional location inside the package. For local refs, Path holds the
+// slash-separated path relative to the project root.
 type AssetRef struct {
 	Org  string // GitHub organisation or user
 	Repo string // Repository name
 	Path string // Path inside the repository
 	Ref  string // Git ref: tag, branch, or commit SHA
+
+	URL      string // Direct HTTPS URL (set only for URL sources)
+	Checksum string // Optional pinned SHA-256 hex digest for URL sources
+
+	Registry string // OCI registry host (set only for OCI sources)
+
+	ReleaseAsset string // GitHub release asset name (set only for release sources)
+
+	Store  string // Object store scheme, "s3" or "gs" (set only for bucket sources)
+	Bucket string // Object store bucket name (set only for bucket sources)
+
+	Package string // Registry package name (set only for registry sources)
+
+	Local bool // Path is a path in the project itself (set only for local sources)
+
+	Plugin string // Name of the source plugin serving Path (set only for plugin sources)
 }
 
+// checksumFragmentPrefix introduces a pinned checksum in a URL reference,
+// e.g. "https://example.com/file.md#sha256=<hex>".
+const checksumFragmentPrefix = "sha256="
+
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var (
+	commitSHAPattern  = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	versionTagPattern = regexp.MustCompile(`^v?\d+(\.\d+)*([-+][0-9A-Za-z.-]+)?$`)
+)
+
+// ociScheme prefixes OCI artifact references.
+const ociScheme = "oci://"
+
+// bucketSchemes lists the object stores supported by bucket references.
+var bucketSchemes = []string{"s3", "gs"}
+
+// registryScheme prefixes registry package references.
+const registryScheme = "registry:"
+
+// localScheme prefixes references to assets authored in the project.
+const localScheme = "local:"
+
+// pluginScheme prefixes references served by a source plugin.
+const pluginScheme = "plugin:"
+
+// pluginNamePattern matches valid source plugin names.
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidPluginName reports whether name can name a source plugin.
+func ValidPluginName(name string) bool {
+	return pluginNamePattern.MatchString(name)
+}
+
+// releaseMarker separates the repository from the release tag in release
+// asset references.
+const releaseMarker = "!release:"
+
 // ParseRef parses a raw reference string into an AssetRef.
-// Expected format: "org/repo/path/to/file@ref"
+// Expected format: "org/repo/path/to/file@ref",
+// "https://host/path/to/file[#sha256=<hex>]" or
+// "oci://registry/repository(:tag|@sha256:digest)[//path]" or
+// "org/repo!release:tag/asset[//path]" or
+// "(s3|gs)://bucket/key[@version]" or "registry:package@version[//path]"
+// or "local:path/in/project" or "plugin:name/path[@ref]".
 func ParseRef(raw string) (AssetRef, error) {
+	if strings.HasPrefix(raw, localScheme) {
+		return parseLocalRef(raw)
+	}
+	if strings.HasPrefix(raw, pluginScheme) {
+		return parsePluginRef(raw)
+	}
+	if strings.HasPrefix(raw, ociScheme) {
+		return parseOCIRef(raw)
+	}
+	if strings.HasPrefix(raw, registryScheme) {
+		return parseRegistryRef(raw)
+	}
+	for _, scheme := range bucketSchemes {
+		if strings.HasPrefix(raw, scheme+"://") {
+			return parseBucketRef(scheme, raw)
+		}
+	}
+	if strings.Contains(raw, releaseMarker) {
+		return parseReleaseRef(raw)
+	}
+	if strings.Contains(raw, "://") {
+		return parseURLRef(raw)
+	}
+
 	// Split on @ to separate the ref
 	parts := strings.SplitN(raw, "@", 2)
 	if len(parts) != 2 || parts[1] == "" {
@@ -99,12 +297,375 @@ func ParseRef(raw string) (AssetRef, error) {
 	}, nil
 }
 
+// parseURLRef parses a direct HTTPS reference with an optional
+// "#sha256=<hex>" fragment pinning the expected content checksum.
+func parseURLRef(raw string) (AssetRef, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return AssetRef{}, fmt.Errorf("invalid URL reference %q: %w", raw, err)
+	}
+	if u.Scheme != "https" {
+		return AssetRef{}, fmt.Errorf("invalid URL reference %q: only https:// URLs are supported", raw)
+	}
+	if u.Host == "" || u.Path == "" || u.Path == "/" {
+		return AssetRef{}, fmt.Errorf("invalid URL reference %q: must be https://host/path/to/file", raw)
+	}
+
+	var checksum string
+	if u.Fragment != "" {
+		if !strings.HasPrefix(u.Fragment, checksumFragmentPrefix) {
+			return AssetRef{}, fmt.Errorf("invalid URL reference %q: fragment must be #sha256=<hex>", raw)
+		}
+		checksum = strings.ToLower(strings.TrimPrefix(u.Fragment, checksumFragmentPrefix))
+		if !sha256HexPattern.MatchString(checksum) {
+			return AssetRef{}, fmt.Errorf("invalid URL reference %q: sha256 checksum must be 64 hex characters", raw)
+		}
+		u.Fragment = ""
+	}
+
+	return AssetRef{
+		Path:     strings.TrimPrefix(u.Path, "/"),
+		URL:      u.String(),
+		Checksum: checksum,
+	}, nil
+}
+
+// parseOCIRef parses an OCI artifact reference. A "//" separates the image
+// reference from an optional path inside the bundle.
+func parseOCIRef(raw string) (AssetRef, error) {
+	image, path, _ := strings.Cut(strings.TrimPrefix(raw, ociScheme), "//")
+
+	registry, repo, ok := strings.Cut(image, "/")
+	if !ok || registry == "" || repo == "" {
+		return AssetRef{}, fmt.Errorf("invalid OCI reference %q: must be oci://registry/repository:tag", raw)
+	}
+
+	var ref string
+	if i := strings.Index(repo, "@"); i != -1 {
+		repo, ref = repo[:i], repo[i+1:]
+		if !strings.HasPrefix(ref, "sha256:") || !sha256HexPattern.MatchString(strings.TrimPrefix(ref, "sha256:")) {
+			return AssetRef{}, fmt.Errorf("invalid OCI reference %q: digest must be sha256:<64 hex characters>", raw)
+		}
+	} else if i := strings.LastIndex(repo, ":"); i != -1 && !strings.Contains(repo[i:], "/") {
+		repo, ref = repo[:i], repo[i+1:]
+	}
+	if ref == "" || repo == "" {
+		return AssetRef{}, fmt.Errorf("invalid OCI reference %q: must include a :tag or @sha256: digest", raw)
+	}
+
+	return AssetRef{
+		Repo:     repo,
+		Path:     strings.Trim(path, "/"),
+		Ref:      ref,
+		Registry: registry,
+	}, nil
+}
+
+// parseReleaseRef parses a GitHub release asset reference. A "//" separates
+// the asset name from an optional path inside the archive.
+func parseReleaseRef(raw string) (AssetRef, error) {
+	repoPart, rest, _ := strings.Cut(raw, releaseMarker)
+	org, repo, ok := strings.Cut(repoPart, "/")
+	if !ok || org == "" || repo == "" || strings.Contains(repo, "/") {
+		return AssetRef{}, fmt.Errorf("invalid release reference %q: must be org/repo!release:tag/asset", raw)
+	}
+
+	rest, path, _ := strings.Cut(rest, "//")
+	tag, asset, ok := strings.Cut(rest, "/")
+	if !ok || tag == "" || asset == "" || strings.Contains(asset, "/") {
+		return AssetRef{}, fmt.Errorf("invalid release reference %q: must be org/repo!release:tag/asset", raw)
+	}
+
+	return AssetRef{
+		Org:          org,
+		Repo:         repo,
+		Path:         strings.Trim(path, "/"),
+		Ref:          tag,
+		ReleaseAsset: asset,
+	}, nil
+}
+
+// parseBucketRef parses an object-store reference. A trailing "@version"
+// pins a specific object version.
+func parseBucketRef(scheme, raw string) (AssetRef, error) {
+	rest := strings.TrimPrefix(raw, scheme+"://")
+	var version string
+	if i := strings.LastIndex(rest, "@"); i != -1 {
+		rest, version = rest[:i], rest[i+1:]
+		if version == "" {
+			return AssetRef{}, fmt.Errorf("invalid %s reference %q: empty version after @", scheme, raw)
+		}
+	}
+
+	bucket, key, _ := strings.Cut(rest, "/")
+	key = strings.Trim(key, "/")
+	if bucket == "" || key == "" {
+		return AssetRef{}, fmt.Errorf("invalid %s reference %q: must be %s://bucket/path/to/object", scheme, raw, scheme)
+	}
+
+	return AssetRef{
+		Path:   key,
+		Ref:    version,
+		Store:  scheme,
+		Bucket: bucket,
+	}, nil
+}
+
+// parseRegistryRef parses a registry package reference. A "//" separates
+// the version from an optional path inside the package.
+func parseRegistryRef(raw string) (AssetRef, error) {
+	name, rest, ok := strings.Cut(strings.TrimPrefix(raw, registryScheme), "@")
+	version, path, _ := strings.Cut(rest, "//")
+	if !ok || name == "" || version == "" {
+		return AssetRef{}, fmt.Errorf("invalid registry reference %q: must be registry:<package>@<version> (e.g. registry:awesome/code-review@1.2.0)", raw)
+	}
+	return AssetRef{Package: name, Ref: version, Path: strings.Trim(path, "/")}, nil
+}
+
+// parseLocalRef parses a reference to a file or directory of the project,
+// as a slash-separated path relative to its root.
+func parseLocalRef(raw string) (AssetRef, error) {
+	p := strings.TrimPrefix(raw, localScheme)
+	if p == "" || path.IsAbs(p) || !filepath.IsLocal(filepath.FromSlash(p)) {
+		return AssetRef{}, fmt.Errorf("invalid local reference %q: must be local:path/in/project", raw)
+	}
+	return AssetRef{Path: path.Clean(p), Local: true}, nil
+}
+
+// parsePluginRef parses a reference served by a source plugin: the plugin
+// name, then the path and ref it is asked for, both opaque to cops.
+func parsePluginRef(raw string) (AssetRef, error) {
+	rest, ref, _ := strings.Cut(strings.TrimPrefix(raw, pluginScheme), "@")
+	name, p, _ := strings.Cut(rest, "/")
+	p = strings.Trim(p, "/")
+	if !ValidPluginName(name) || p == "" {
+		return AssetRef{}, fmt.Errorf("invalid plugin reference %q: must be plugin:<name>/<path>[@<ref>] (e.g. plugin:artifactory/prompts/review.md@v3)", raw)
+	}
+	return AssetRef{Plugin: name, Path: p, Ref: ref}, nil
+}
+
+// IsPlugin reports whether the ref is served by a source plugin.
+func (r AssetRef) IsPlugin() bool {
+	return r.Plugin != ""
+}
+
+// IsRegistry reports whether the ref names a package in the registry index.
+func (r AssetRef) IsRegistry() bool {
+	return r.Package != ""
+}
+
+// IsBucket reports whether the ref points at an S3 or GCS object.
+func (r AssetRef) IsBucket() bool {
+	return r.Bucket != ""
+}
+
+// IsRelease reports whether the ref points at a GitHub release asset.
+func (r AssetRef) IsRelease() bool {
+	return r.ReleaseAsset != ""
+}
+
+// IsOCI reports whether the ref points at an OCI registry artifact.
+func (r AssetRef) IsOCI() bool {
+	return r.Registry != ""
+}
+
+// IsGitHub reports whether the ref points at a path in a GitHub repository.
+func (r AssetRef) IsGitHub() bool {
+	return !r.IsURL() && !r.IsOCI() && !r.IsRelease() && !r.IsBucket() && !r.IsRegistry() && !r.IsLocal() && !r.IsPlugin()
+}
+
+// IsLocal reports whether the ref points at a file or directory of the
+// project itself.
+func (r AssetRef) IsLocal() bool {
+	return r.Local
+}
+
+// IsURL reports whether the ref points at a direct HTTPS URL rather than
+// a GitHub repository.
+func (r AssetRef) IsURL() bool {
+	return r.URL != ""
+}
+
+// IsPinned reports whether the ref identifies immutable content: a commit
+// SHA, a version tag, a URL with a pinned checksum, a bucket object with
+// a pinned version, or a local path, versioned with the project. Anything
+// else (for example "latest" or a branch name)
+// may change between syncs.
+func (r AssetRef) IsPinned() bool {
+	if r.IsLocal() {
+		return true
+	}
+	if r.IsURL() {
+		return r.Checksum != ""
+	}
+	if r.IsBucket() {
+		return r.Ref != ""
+	}
+	if r.IsRegistry() {
+		return versionTagPattern.MatchString(r.Ref)
+	}
+	if r.IsOCI() {
+		return strings.HasPrefix(r.Ref, "sha256:") || versionTagPattern.MatchString(r.Ref)
+	}
+	if r.IsRelease() {
+		return versionTagPattern.MatchString(r.Ref)
+	}
+	if r.IsPlugin() && r.Ref == "" {
+		return false
+	}
+	return commitSHAPattern.MatchString(r.Ref) || versionTagPattern.MatchString(r.Ref)
+}
+
+// IsVersionTag reports whether the ref of a GitHub reference is a version
+// tag, such as "v1.2.0", rather than a branch or a commit SHA.
+func (r AssetRef) IsVersionTag() bool {
+	return r.IsGitHub() && versionTagPattern.MatchString(r.Ref) && !commitSHAPattern.MatchString(r.Ref)
+}
+
 // Raw returns the canonical string representation of the ref.
 func (r AssetRef) Raw() string {
+	if r.IsLocal() {
+		return localScheme + r.Path
+	}
+	if r.IsPlugin() {
+		raw := pluginScheme + r.Plugin + "/" + r.Path
+		if r.Ref != "" {
+			raw += "@" + r.Ref
+		}
+		return raw
+	}
+	if r.IsURL() {
+		if r.Checksum != "" {
+			return r.URL + "#" + checksumFragmentPrefix + r.Checksum
+		}
+		return r.URL
+	}
+	if r.IsOCI() {
+		sep := ":"
+		if strings.HasPrefix(r.Ref, "sha256:") {
+			sep = "@"
+		}
+		raw := ociScheme + r.Registry + "/" + r.Repo + sep + r.Ref
+		if r.Path != "" {
+			raw += "//" + r.Path
+		}
+		return raw
+	}
+	if r.IsRegistry() {
+		raw := registryScheme + r.Package + "@" + r.Ref
+		if r.Path != "" {
+			raw += "//" + r.Path
+		}
+		return raw
+	}
+	if r.IsBucket() {
+		raw := r.Store + "://" + r.Bucket + "/" + r.Path
+		if r.Ref != "" {
+			raw += "@" + r.Ref
+		}
+		return raw
+	}
+	if r.IsRelease() {
+		raw := r.Org + "/" + r.Repo + releaseMarker + r.Ref + "/" + r.ReleaseAsset
+		if r.Path != "" {
+			raw += "//" + r.Path
+		}
+		return raw
+	}
 	return fmt.Sprintf("%s/%s/%s@%s", r.Org, r.Repo, r.Path, r.Ref)
 }
 
-// RepoFullName returns "org/repo".
+// Source kinds recorded in the lock file, one per kind of reference.
+const (
+	SourceGitHub   = "github"
+	SourceRelease  = "github-release"
+	SourceHTTP     = "http"
+	SourceOCI      = "oci"
+	SourceS3       = "s3"
+	SourceGCS      = "gs"
+	SourceRegistry = "registry"
+	SourceLocal    = "local"
+	SourcePlugin   = "plugin"
+)
+
+// githubAPIURL is the API all GitHub and release refs are resolved through.
+const githubAPIURL = "https://api.github.com"
+
+// SourceKind returns the kind of source the ref is fetched from, one of
+// the Source constants.
+func (r AssetRef) SourceKind() string {
+	switch {
+	case r.IsLocal():
+		return SourceLocal
+	case r.IsPlugin():
+		return SourcePlugin
+	case r.IsURL():
+		return SourceHTTP
+	case r.IsOCI():
+		return SourceOCI
+	case r.IsRelease():
+		return SourceRelease
+	case r.IsRegistry():
+		return SourceRegistry
+	case r.IsBucket() && r.Store == "gs":
+		return SourceGCS
+	case r.IsBucket():
+		return SourceS3
+	default:
+		return SourceGitHub
+	}
+}
+
+// Host returns the host the ref is fetched from, or "" for local and
+// plugin refs and for bucket and registry refs, whose endpoints are
+// configured at run time.
+func (r AssetRef) Host() string {
+	switch {
+	case r.IsURL():
+		if u, err := url.Parse(r.URL); err == nil {
+			return u.Host
+		}
+		return ""
+	case r.IsOCI():
+		return r.Registry
+	case r.IsGitHub(), r.IsRelease():
+		return "github.com"
+	default:
+		return ""
+	}
+}
+
+// APIURL returns the base URL of the API the ref is resolved through, or
+// "" for sources fetched directly (URLs) or configured at run time.
+func (r AssetRef) APIURL() string {
+	switch {
+	case r.IsOCI():
+		return "https://" + r.Registry + "/v2"
+	case r.IsGitHub(), r.IsRelease():
+		return githubAPIURL
+	default:
+		return ""
+	}
+}
+
+// RepoFullName returns "org/repo", "registry/repository" for OCI refs, or
+// "scheme://bucket" for bucket refs, "registry:package" for registry refs,
+// or "local" for local refs, or "plugin:name" for plugin refs.
 func (r AssetRef) RepoFullName() string {
+	if r.IsLocal() {
+		return SourceLocal
+	}
+	if r.IsPlugin() {
+		return pluginScheme + r.Plugin
+	}
+	if r.IsRegistry() {
+		return registryScheme + r.Package
+	}
+	if r.IsBucket() {
+		return r.Store + "://" + r.Bucket
+	}
+	if r.IsOCI() {
+		return r.Registry + "/" + r.Repo
+	}
 	return fmt.Sprintf("%s/%s", r.Org, r.Repo)
 }
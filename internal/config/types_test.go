@@ -14,6 +14,10 @@ func TestAssetTypeIsValid(t *testing.T) {
 		{Instructions, true},
 		{Agents, true},
 		{Prompts, true},
+		{Chatmodes, true},
+		{RootInstructions, true},
+		{AgentsMD, true},
+		{SetupSteps, true},
 		{Skills, true},
 		{"unknown", false},
 		{"", false},
@@ -35,6 +39,7 @@ func TestAssetTypeFileExtension(t *testing.T) {
 		{Instructions, ".instructions.md"},
 		{Agents, ".agent.md"},
 		{Prompts, ".prompt.md"},
+		{Chatmodes, ".chatmode.md"},
 		{Skills, ""},
 	}
 	for _, tc := range cases {
@@ -53,6 +58,7 @@ func TestAssetTypeTargetDir(t *testing.T) {
 		{Instructions, filepath.Join(".github", "instructions")},
 		{Agents, filepath.Join(".github", "agents")},
 		{Prompts, filepath.Join(".github", "prompts")},
+		{Chatmodes, filepath.Join(".github", "chatmodes")},
 		{Skills, filepath.Join(".github", "skills")},
 	}
 	for _, tc := range cases {
@@ -62,6 +68,29 @@ func TestAssetTypeTargetDir(t *testing.T) {
 	}
 }
 
+func TestAssetTypeTargetPathIn(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		root string
+		t    AssetType
+		want string
+	}{
+		{"", Instructions, filepath.Join(".github", "instructions", "go.instructions.md")},
+		{"docs/ai", Agents, filepath.Join("docs", "ai", "agents", "go.agent.md")},
+		{".", Skills, filepath.Join("skills", "go")},
+		{"", RootInstructions, filepath.Join(".github", "copilot-instructions.md")},
+		{"docs/ai", RootInstructions, filepath.Join("docs", "ai", "copilot-instructions.md")},
+		{"", AgentsMD, "AGENTS.md"},
+		{"docs/ai", AgentsMD, "AGENTS.md"},
+		{"docs/ai", SetupSteps, filepath.Join(".github", "workflows", "copilot-setup-steps.yml")},
+	}
+	for _, tc := range cases {
+		if got := tc.t.TargetPathIn(tc.root, "go"); got != tc.want {
+			t.Errorf("AssetType(%q).TargetPathIn(%q, go) = %q, want %q", tc.t, tc.root, got, tc.want)
+		}
+	}
+}
+
 func TestAssetTypeTargetPath_FileTypes(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
@@ -72,6 +101,7 @@ func TestAssetTypeTargetPath_FileTypes(t *testing.T) {
 		{Instructions, "my-review", filepath.Join(".github", "instructions", "my-review.instructions.md")},
 		{Agents, "helper", filepath.Join(".github", "agents", "helper.agent.md")},
 		{Prompts, "deploy", filepath.Join(".github", "prompts", "deploy.prompt.md")},
+		{Chatmodes, "planner", filepath.Join(".github", "chatmodes", "planner.chatmode.md")},
 	}
 	for _, tc := range cases {
 		if got := tc.assetType.TargetPath(tc.name); got != tc.want {
@@ -94,7 +124,7 @@ func TestAssetTypeIsDirectory(t *testing.T) {
 	if !Skills.IsDirectory() {
 		t.Error("Skills.IsDirectory() = false, want true")
 	}
-	for _, at := range []AssetType{Instructions, Agents, Prompts} {
+	for _, at := range []AssetType{Instructions, Agents, Prompts, Chatmodes, RootInstructions, AgentsMD, SetupSteps} {
 		if at.IsDirectory() {
 			t.Errorf("%s.IsDirectory() = true, want false", at)
 		}
@@ -182,3 +212,365 @@ func TestAssetRefRepoFullName(t *testing.T) {
 		t.Errorf("RepoFullName() = %q, want %q", got, want)
 	}
 }
+
+func TestParseRef_URL(t *testing.T) {
+	t.Parallel()
+	ref, err := ParseRef("https://artifacts.example.com/copilot/review.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ref.IsURL() {
+		t.Fatal("IsURL() = false, want true")
+	}
+	if ref.URL != "https://artifacts.example.com/copilot/review.md" {
+		t.Errorf("URL = %q", ref.URL)
+	}
+	if ref.Path != "copilot/review.md" {
+		t.Errorf("Path = %q, want %q", ref.Path, "copilot/review.md")
+	}
+	if ref.Checksum != "" {
+		t.Errorf("Checksum = %q, want empty", ref.Checksum)
+	}
+}
+
+func TestParseRef_URLWithChecksum(t *testing.T) {
+	t.Parallel()
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	raw := "https://artifacts.example.com/review.md#sha256=" + sum
+	ref, err := ParseRef(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Checksum != sum {
+		t.Errorf("Checksum = %q, want %q", ref.Checksum, sum)
+	}
+	if ref.URL != "https://artifacts.example.com/review.md" {
+		t.Errorf("URL should not keep the fragment, got %q", ref.URL)
+	}
+	if got := ref.Raw(); got != raw {
+		t.Errorf("Raw() roundtrip failed: got %q, want %q", got, raw)
+	}
+}
+
+func TestParseRef_URLErrorCases(t *testing.T) {
+	t.Parallel()
+	cases := []string{
+		"http://example.com/file.md",
+		"ftp://example.com/file.md",
+		"https:///file.md",
+		"https://example.com/",
+		"https://example.com/file.md#md5=abc",
+		"https://example.com/file.md#sha256=tooshort",
+	}
+	for _, raw := range cases {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestAssetRefIsPinned(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"o/r/p@v1.2.3", true},
+		{"o/r/p@1.0", true},
+		{"o/r/p@v2.0.0-rc.1", true},
+		{"o/r/p@a1b2c3d", true},
+		{"o/r/p@a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2", true},
+		{"o/r/p@latest", false},
+		{"o/r/p@main", false},
+		{"o/r/p@release-1.2", false},
+		{"https://example.com/f.md", false},
+		{"https://example.com/f.md#sha256=9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", true},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): %v", tc.raw, err)
+		}
+		if got := ref.IsPinned(); got != tc.want {
+			t.Errorf("ParseRef(%q).IsPinned() = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestAssetRefIsVersionTag(t *testing.T) {
+	t.Parallel()
+	cases := map[string]bool{
+		"o/r/p@v1.2.3":                     true,
+		"o/r/p@1.0":                        true,
+		"o/r/p@v2.0.0-rc.1":                true,
+		"o/r/p@a1b2c3d":                    false,
+		"o/r/p@1234567":                    false,
+		"o/r/p@main":                       false,
+		"o/r!release:v1.2.0/bundle.tar.gz": false,
+	}
+	for raw, want := range cases {
+		ref, err := ParseRef(raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): %v", raw, err)
+		}
+		if got := ref.IsVersionTag(); got != want {
+			t.Errorf("ParseRef(%q).IsVersionTag() = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestParseRef_OCI(t *testing.T) {
+	t.Parallel()
+	digest := "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	cases := []struct {
+		raw                       string
+		registry, repo, ref, path string
+	}{
+		{"oci://ghcr.io/org/copilot-assets:v1", "ghcr.io", "org/copilot-assets", "v1", ""},
+		{"oci://ghcr.io/org/copilot-assets:v1//skills/k8s", "ghcr.io", "org/copilot-assets", "v1", "skills/k8s"},
+		{"oci://localhost:5000/assets@" + digest, "localhost:5000", "assets", digest, ""},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): unexpected error: %v", tc.raw, err)
+		}
+		if !ref.IsOCI() || ref.IsGitHub() || ref.IsURL() {
+			t.Errorf("ParseRef(%q): wrong source kind: %+v", tc.raw, ref)
+		}
+		if ref.Registry != tc.registry || ref.Repo != tc.repo || ref.Ref != tc.ref || ref.Path != tc.path {
+			t.Errorf("ParseRef(%q) = %+v", tc.raw, ref)
+		}
+		if got := ref.Raw(); got != tc.raw {
+			t.Errorf("Raw() roundtrip failed: got %q, want %q", got, tc.raw)
+		}
+	}
+}
+
+func TestParseRef_OCIErrorCases(t *testing.T) {
+	t.Parallel()
+	cases := []string{
+		"oci://ghcr.io",
+		"oci://ghcr.io/org/assets",
+		"oci://ghcr.io/org/assets@sha256:abc",
+		"oci:///org/assets:v1",
+	}
+	for _, raw := range cases {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestParseRef_Release(t *testing.T) {
+	t.Parallel()
+	raw := "org/repo!release:v1.2.0/assets.tar.gz//prompts/review.md"
+	ref, err := ParseRef(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ref.IsRelease() || ref.IsGitHub() {
+		t.Errorf("wrong source kind: %+v", ref)
+	}
+	if ref.Org != "org" || ref.Repo != "repo" || ref.Ref != "v1.2.0" || ref.ReleaseAsset != "assets.tar.gz" || ref.Path != "prompts/review.md" {
+		t.Errorf("ParseRef(%q) = %+v", raw, ref)
+	}
+	if got := ref.Raw(); got != raw {
+		t.Errorf("Raw() roundtrip failed: got %q, want %q", got, raw)
+	}
+	if !ref.IsPinned() {
+		t.Error("release ref on a version tag should be pinned")
+	}
+}
+
+func TestParseRef_ReleaseErrorCases(t *testing.T) {
+	t.Parallel()
+	cases := []string{
+		"org!release:v1/assets.zip",
+		"org/repo!release:v1",
+		"org/repo!release:/assets.zip",
+		"org/repo/sub!release:v1/assets.zip",
+	}
+	for _, raw := range cases {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestParseRef_Bucket(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw                      string
+		store, bucket, path, ref string
+		pinned                   bool
+	}{
+		{"s3://mirror/copilot/review.md", "s3", "mirror", "copilot/review.md", "", false},
+		{"s3://mirror/copilot/review.md@3HL4kqtJlcpXroDTDmJ.rmSpXd3dIbrHY", "s3", "mirror", "copilot/review.md", "3HL4kqtJlcpXroDTDmJ.rmSpXd3dIbrHY", true},
+		{"gs://mirror/skills/k8s@1700000000000000", "gs", "mirror", "skills/k8s", "1700000000000000", true},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): unexpected error: %v", tc.raw, err)
+		}
+		if !ref.IsBucket() || ref.IsGitHub() || ref.IsURL() {
+			t.Errorf("ParseRef(%q): wrong source kind: %+v", tc.raw, ref)
+		}
+		if ref.Store != tc.store || ref.Bucket != tc.bucket || ref.Path != tc.path || ref.Ref != tc.ref {
+			t.Errorf("ParseRef(%q) = %+v", tc.raw, ref)
+		}
+		if got := ref.IsPinned(); got != tc.pinned {
+			t.Errorf("ParseRef(%q).IsPinned() = %v, want %v", tc.raw, got, tc.pinned)
+		}
+		if got := ref.Raw(); got != tc.raw {
+			t.Errorf("Raw() roundtrip failed: got %q, want %q", got, tc.raw)
+		}
+	}
+}
+
+func TestParseRef_BucketErrorCases(t *testing.T) {
+	t.Parallel()
+	cases := []string{
+		"s3://",
+		"s3://mirror",
+		"s3://mirror/",
+		"gs:///review.md",
+		"s3://mirror/review.md@",
+	}
+	for _, raw := range cases {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestParseRef_Registry(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw, pkg, version, path string
+		pinned                  bool
+	}{
+		{"registry:awesome/code-review@1.2.0", "awesome/code-review", "1.2.0", "", true},
+		{"registry:k8s@latest", "k8s", "latest", "", false},
+		{"registry:awesome/skills@2.0.0//k8s", "awesome/skills", "2.0.0", "k8s", true},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): unexpected error: %v", tc.raw, err)
+		}
+		if !ref.IsRegistry() || ref.IsGitHub() {
+			t.Errorf("ParseRef(%q): wrong source kind: %+v", tc.raw, ref)
+		}
+		if ref.Package != tc.pkg || ref.Ref != tc.version || ref.Path != tc.path {
+			t.Errorf("ParseRef(%q) = %+v", tc.raw, ref)
+		}
+		if got := ref.IsPinned(); got != tc.pinned {
+			t.Errorf("ParseRef(%q).IsPinned() = %v, want %v", tc.raw, got, tc.pinned)
+		}
+		if got := ref.Raw(); got != tc.raw {
+			t.Errorf("Raw() roundtrip failed: got %q, want %q", got, tc.raw)
+		}
+	}
+
+	for _, raw := range []string{"registry:", "registry:awesome/code-review", "registry:@1.0.0", "registry:pkg@"} {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestParseRef_Local(t *testing.T) {
+	t.Parallel()
+	ref, err := ParseRef("local:.github/prompts/./review.prompt.md")
+	if err != nil {
+		t.Fatalf("ParseRef: unexpected error: %v", err)
+	}
+	if !ref.IsLocal() || ref.IsGitHub() || !ref.IsPinned() {
+		t.Errorf("ParseRef: wrong source kind: %+v", ref)
+	}
+	if ref.Path != ".github/prompts/review.prompt.md" {
+		t.Errorf("Path = %q, want the cleaned path", ref.Path)
+	}
+	if got := ref.Raw(); got != "local:.github/prompts/review.prompt.md" {
+		t.Errorf("Raw() = %q", got)
+	}
+
+	for _, raw := range []string{"local:", "local:/etc/passwd", "local:../other/review.md", "local:a/../../b"} {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestParseRef_Plugin(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw, plugin, path, ref string
+		pinned                 bool
+	}{
+		{"plugin:artifactory/prompts/review.md@v3", "artifactory", "prompts/review.md", "v3", true},
+		{"plugin:vault/skills/k8s@main", "vault", "skills/k8s", "main", false},
+		{"plugin:corp-docs/guide.md", "corp-docs", "guide.md", "", false},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): unexpected error: %v", tc.raw, err)
+		}
+		if !ref.IsPlugin() || ref.IsGitHub() {
+			t.Errorf("ParseRef(%q): wrong source kind: %+v", tc.raw, ref)
+		}
+		if ref.Plugin != tc.plugin || ref.Path != tc.path || ref.Ref != tc.ref {
+			t.Errorf("ParseRef(%q) = %+v", tc.raw, ref)
+		}
+		if got := ref.IsPinned(); got != tc.pinned {
+			t.Errorf("ParseRef(%q).IsPinned() = %v, want %v", tc.raw, got, tc.pinned)
+		}
+		if got := ref.Raw(); got != tc.raw {
+			t.Errorf("Raw() roundtrip failed: got %q, want %q", got, tc.raw)
+		}
+		if got := ref.RepoFullName(); got != "plugin:"+tc.plugin {
+			t.Errorf("RepoFullName() = %q", got)
+		}
+	}
+
+	for _, raw := range []string{"plugin:", "plugin:artifactory", "plugin:artifactory/", "plugin:Bad_Name/a.md", "plugin:/a.md@v1"} {
+		if _, err := ParseRef(raw); err == nil {
+			t.Errorf("ParseRef(%q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestAssetRef_SourceMetadata(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw, kind, host, api string
+	}{
+		{"org/repo/review.md@v1", SourceGitHub, "github.com", "https://api.github.com"},
+		{"org/repo!release:v1/assets.tgz", SourceRelease, "github.com", "https://api.github.com"},
+		{"https://artifacts.example.com/review.md", SourceHTTP, "artifacts.example.com", ""},
+		{"oci://ghcr.io/org/bundle:v1", SourceOCI, "ghcr.io", "https://ghcr.io/v2"},
+		{"s3://mirror/review.md", SourceS3, "", ""},
+		{"gs://mirror/review.md", SourceGCS, "", ""},
+		{"registry:awesome/review@1.0.0", SourceRegistry, "", ""},
+		{"local:.github/prompts/review.prompt.md", SourceLocal, "", ""},
+		{"plugin:artifactory/prompts/review.md@v3", SourcePlugin, "", ""},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseRef(%q): %v", tc.raw, err)
+		}
+		if got := ref.SourceKind(); got != tc.kind {
+			t.Errorf("%s: SourceKind() = %q, want %q", tc.raw, got, tc.kind)
+		}
+		if got := ref.Host(); got != tc.host {
+			t.Errorf("%s: Host() = %q, want %q", tc.raw, got, tc.host)
+		}
+		if got := ref.APIURL(); got != tc.api {
+			t.Errorf("%s: APIURL() = %q, want %q", tc.raw, got, tc.api)
+		}
+	}
+}
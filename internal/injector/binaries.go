@@ -0,0 +1,59 @@
+package injector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// QuarantineFolder receives, relative to the project root, the binary
+// files left out of skills with [limits] binaries = "quarantine", under
+// <type>/<name>/ so they can be reviewed.
+const QuarantineFolder = ".cops-quarantine"
+
+// binarySniffLen is how much of a file isBinary looks at, as git does.
+const binarySniffLen = 8000
+
+// isBinary reports whether content looks like a binary file rather than
+// text: like git, whether its first 8000 bytes hold a NUL byte.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0
+}
+
+// checkBinary reports whether the file at rel of a directory asset is a
+// binary that o.Binaries sets aside, or an error if it refuses the asset.
+func (o Options) checkBinary(rel string, content []byte) (bool, error) {
+	if o.Binaries == "" || o.Binaries == manifest.BinariesAllow || !isBinary(content) {
+		return false, nil
+	}
+	if o.Binaries == manifest.BinariesFail {
+		return false, fmt.Errorf("%s is a binary file — refusing to install ([limits] binaries = %q)", rel, manifest.BinariesFail)
+	}
+	return true, nil
+}
+
+// setAsideBinaries writes the binaries checkBinary set aside from the
+// directory entry assetType/name (a skill) to the quarantine folder, if
+// o.Binaries says so, and describes what happened to each.
+func (inj *Injector) setAsideBinaries(assetType, name string, binaries map[string][]byte, o Options) ([]string, error) {
+	var warnings []string
+	for _, rel := range manifest.SortedKeys(binaries) {
+		if o.Binaries != manifest.BinariesQuarantine {
+			warnings = append(warnings, fmt.Sprintf("%s is a binary file, skipped", rel))
+			continue
+		}
+		dest := filepath.Join(QuarantineFolder, assetType, name, filepath.FromSlash(rel))
+		abs := filepath.Join(inj.rootDir, dest)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return nil, fmt.Errorf("creating quarantine directory: %w", err)
+		}
+		if err := os.WriteFile(abs, binaries[rel], 0644); err != nil {
+			return nil, fmt.Errorf("quarantining %s: %w", rel, err)
+		}
+		warnings = append(warnings, fmt.Sprintf("%s is a binary file, quarantined to %s for review", rel, filepath.ToSlash(dest)))
+	}
+	return warnings, nil
+}
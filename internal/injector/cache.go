@@ -0,0 +1,89 @@
+package injector
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+// fullCommitSHA matches a ref naming a commit by its full SHA, the only
+// GitHub ref whose content can never change.
+var fullCommitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// UseCache makes the injector keep what it downloads in st, shared by
+// every project of the user, and look content up there before any network
+// call when the lock file already pins it: assets written from a lock file
+// and GitHub refs pinned to the commit they were locked at.
+func (inj *Injector) UseCache(st store.Store) {
+	inj.cache = &st
+}
+
+// cacheFile adds content to the download cache. A cache that cannot be
+// written only costs a download next time.
+func (inj *Injector) cacheFile(content []byte, executable bool) {
+	if inj.cache != nil {
+		_, _ = inj.cache.Put(content, executable)
+	}
+}
+
+// cacheDirectory adds the files of a directory asset to the download
+// cache.
+func (inj *Injector) cacheDirectory(contents map[string][]byte, executable map[string]bool) {
+	for rel, content := range contents {
+		inj.cacheFile(content, executable[rel])
+	}
+}
+
+// cachedFile returns the single file locked by locked from the download
+// cache.
+func (inj *Injector) cachedFile(locked manifest.LockEntry) ([]byte, bool) {
+	if inj.cache == nil || locked.Checksum == "" {
+		return nil, false
+	}
+	return inj.cache.Get(locked.Checksum)
+}
+
+// cachedDirectory returns the files of the directory asset locked by
+// locked from the download cache, with those locked executable. It fails
+// unless the lock records every file and all of them are cached.
+func (inj *Injector) cachedDirectory(locked manifest.LockEntry) (map[string][]byte, map[string]bool, bool) {
+	if inj.cache == nil || len(locked.Files) == 0 {
+		return nil, nil, false
+	}
+	contents := make(map[string][]byte, len(locked.Files))
+	executable := make(map[string]bool)
+	for rel, digest := range locked.Files {
+		content, ok := inj.cache.Get(digest.SHA256)
+		if !ok {
+			return nil, nil, false
+		}
+		contents[rel] = content
+		if mode, err := strconv.ParseUint(digest.Mode, 8, 32); err == nil && mode&0111 != 0 {
+			executable[rel] = true
+		}
+	}
+	if manifest.Checksum(computeDirectoryChecksum(contents)) != locked.Checksum {
+		return nil, nil, false
+	}
+	return contents, executable, true
+}
+
+// pinnedEntry returns the lock entry of an asset whose content cannot have
+// changed since it was locked at targetPath: a GitHub ref pinned to the
+// full commit SHA it resolved to, with no option changing its content.
+func (inj *Injector) pinnedEntry(ref config.AssetRef, assetType config.AssetType, name, rawRef, targetPath string, opts Options) (manifest.LockEntry, bool) {
+	if inj.cache == nil || !ref.IsGitHub() || !fullCommitSHA.MatchString(ref.Ref) {
+		return manifest.LockEntry{}, false
+	}
+	if opts.transforms() || (assetType.IsDirectory() && opts.Files != nil) {
+		return manifest.LockEntry{}, false
+	}
+	locked, ok := inj.lock.Get(string(assetType), name)
+	if !ok || locked.Ref != rawRef || locked.ResolvedSHA != ref.Ref || locked.TargetPath != targetPath {
+		return manifest.LockEntry{}, false
+	}
+	return locked, true
+}
@@ -0,0 +1,42 @@
+package injector
+
+import (
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/store"
+)
+
+func TestPinnedEntry(t *testing.T) {
+	t.Parallel()
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	lock := manifest.NewLockFile()
+	lock.Set("instructions", "go", "myorg/myrepo/go.md@"+commit, commit, ".github/instructions/go.instructions.md", []byte("Use gofmt."))
+	lock.Set("instructions", "branch", "myorg/myrepo/go.md@main", commit, ".github/instructions/branch.instructions.md", []byte("Use gofmt."))
+	inj := New(nil, lock, t.TempDir())
+	inj.UseCache(store.Store{Dir: t.TempDir()})
+
+	tests := []struct {
+		name, entry, rawRef, target string
+		opts                        Options
+		want                        bool
+	}{
+		{"full commit", "go", "myorg/myrepo/go.md@" + commit, ".github/instructions/go.instructions.md", Options{}, true},
+		{"branch", "branch", "myorg/myrepo/go.md@main", ".github/instructions/branch.instructions.md", Options{}, false},
+		{"moved", "go", "myorg/myrepo/go.md@" + commit, "docs/go.md", Options{}, false},
+		{"transformed", "go", "myorg/myrepo/go.md@" + commit, ".github/instructions/go.instructions.md", Options{Vars: map[string]string{"team": "web"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ref, err := config.ParseRef(tt.rawRef)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, got := inj.pinnedEntry(ref, config.Instructions, tt.entry, tt.rawRef, tt.target, tt.opts); got != tt.want {
+				t.Errorf("pinnedEntry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
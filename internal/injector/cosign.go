@@ -0,0 +1,44 @@
+package injector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// verifySignature checks that the asset at ref carries the cosign
+// signature c requires, and returns who signed it. A relative key path is
+// resolved from the project root.
+func (inj *Injector) verifySignature(ref config.AssetRef, c *manifest.CosignOptions) (string, error) {
+	v, ok := inj.resolver.(resolver.SignatureVerifier)
+	if !ok {
+		return "", fmt.Errorf("cosign signatures cannot be verified for %s", ref.Raw())
+	}
+	policy := resolver.CosignPolicy{Identity: c.Identity, Issuer: c.Issuer}
+	var err error
+	if c.Key != "" {
+		path := filepath.FromSlash(c.Key)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(inj.rootDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading cosign key: %w", err)
+		}
+		if policy.Key, err = resolver.ParseCosignKey(data); err != nil {
+			return "", fmt.Errorf("cosign key %s: %w", c.Key, err)
+		}
+	} else {
+		if policy.Roots, err = resolver.FulcioRootsFromEnv(); err != nil {
+			return "", err
+		}
+		if policy.RekorKeys, err = resolver.RekorKeysFromEnv(); err != nil {
+			return "", err
+		}
+	}
+	return v.VerifySignature(ref, policy)
+}
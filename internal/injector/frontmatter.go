@@ -0,0 +1,133 @@
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// mergeFrontmatter sets fields in the YAML frontmatter of a Markdown
+// document. Top-level keys already present are replaced, together with
+// their nested lines; the others are appended in byte-wise order. A
+// document without frontmatter gets one. Everything else is kept byte for
+// byte, so merging the same fields twice changes nothing.
+func mergeFrontmatter(content []byte, fields map[string]any) ([]byte, error) {
+	if len(fields) == 0 {
+		return content, nil
+	}
+
+	text := string(content)
+	nl := "\n"
+	if strings.HasPrefix(text, "---\r\n") {
+		nl = "\r\n"
+	}
+	header, body, ok := splitFrontmatter(text)
+	if !ok {
+		body = text
+	}
+
+	var out []string
+	done := make(map[string]bool, len(fields))
+	for i := 0; i < len(header); i++ {
+		key, isKey := frontmatterKey(header[i])
+		value, override := fields[key]
+		if !isKey || !override {
+			out = append(out, header[i])
+			continue
+		}
+		line, err := frontmatterLine(key, value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, line)
+		done[key] = true
+		// Drop the nested lines of the replaced value.
+		for i+1 < len(header) && isContinuation(header[i+1]) {
+			i++
+		}
+	}
+	for _, key := range manifest.SortedKeys(fields) {
+		if done[key] {
+			continue
+		}
+		line, err := frontmatterLine(key, fields[key])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, line)
+	}
+
+	var b strings.Builder
+	b.WriteString("---" + nl)
+	for _, line := range out {
+		b.WriteString(line + nl)
+	}
+	b.WriteString("---" + nl)
+	b.WriteString(body)
+	return []byte(b.String()), nil
+}
+
+// splitFrontmatter splits text into the lines of its frontmatter, without
+// line endings, and the body after the closing "---". ok is false if text
+// does not start with a complete frontmatter block.
+func splitFrontmatter(text string) (header []string, body string, ok bool) {
+	first, rest, found := strings.Cut(text, "\n")
+	if !found || strings.TrimSuffix(first, "\r") != "---" {
+		return nil, "", false
+	}
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if line == "---" || line == "..." {
+			return header, next, true
+		}
+		header = append(header, line)
+		rest = next
+	}
+	return nil, "", false
+}
+
+// frontmatterKey returns the key of a top-level "key: value" line.
+func frontmatterKey(line string) (string, bool) {
+	if line == "" || isContinuation(line) || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	key, _, found := strings.Cut(line, ":")
+	if !found {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(key), `"'`), true
+}
+
+// isContinuation reports whether line belongs to the value of the key
+// above it: it is indented or a block sequence item.
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "- ") || line == "-"
+}
+
+// frontmatterLine formats a "key: value" line. Strings are written as
+// double-quoted scalars and lists as flow sequences, in their JSON form,
+// which YAML reads as-is.
+func frontmatterLine(key string, value any) (string, error) {
+	switch v := value.(type) {
+	case string, []string:
+		data, _ := json.Marshal(v)
+		return key + ": " + string(data), nil
+	case bool:
+		return key + ": " + strconv.FormatBool(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("frontmatter %s: list items must be strings", key)
+			}
+			items = append(items, s)
+		}
+		return frontmatterLine(key, items)
+	}
+	return "", fmt.Errorf("frontmatter %s: must be a string, a boolean or a list of strings", key)
+}
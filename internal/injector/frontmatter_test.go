@@ -0,0 +1,66 @@
+package injector
+
+import "testing"
+
+func TestMergeFrontmatter(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		content string
+		fields  map[string]any
+		want    string
+	}{
+		{
+			name:    "replaces and appends keys",
+			content: "---\ndescription: upstream\napplyTo: '**'\n---\n# Body\n",
+			fields:  map[string]any{"applyTo": "src/**/*.ts", "infer": false},
+			want:    "---\ndescription: upstream\napplyTo: \"src/**/*.ts\"\ninfer: false\n---\n# Body\n",
+		},
+		{
+			name:    "replaces a block value",
+			content: "---\ntools:\n  - search\n  - edit\nmodel: gpt\n---\nBody\n",
+			fields:  map[string]any{"tools": []any{"search"}},
+			want:    "---\ntools: [\"search\"]\nmodel: gpt\n---\nBody\n",
+		},
+		{
+			name:    "adds frontmatter",
+			content: "# Body\n",
+			fields:  map[string]any{"description": "Local"},
+			want:    "---\ndescription: \"Local\"\n---\n# Body\n",
+		},
+		{
+			name:    "keeps CRLF line endings",
+			content: "---\r\napplyTo: '**'\r\n---\r\nBody\r\n",
+			fields:  map[string]any{"applyTo": "docs/**"},
+			want:    "---\r\napplyTo: \"docs/**\"\r\n---\r\nBody\r\n",
+		},
+		{
+			name:    "no fields",
+			content: "---\na: b\n---\n",
+			want:    "---\na: b\n---\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := mergeFrontmatter([]byte(tc.content), tc.fields)
+			if err != nil {
+				t.Fatalf("mergeFrontmatter: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("mergeFrontmatter:\ngot  %q\nwant %q", got, tc.want)
+			}
+			again, err := mergeFrontmatter(got, tc.fields)
+			if err != nil || string(again) != string(got) {
+				t.Errorf("merging twice changed the result: %q, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestMergeFrontmatter_InvalidValue(t *testing.T) {
+	t.Parallel()
+	if _, err := mergeFrontmatter([]byte("# Body\n"), map[string]any{"n": 3.0}); err == nil {
+		t.Error("mergeFrontmatter(number) succeeded, want an error")
+	}
+}
@@ -1,23 +1,66 @@
 package injector
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
+	"sync"
 
 	"github.com/cbout22/copilot-sync/internal/config"
 	"github.com/cbout22/copilot-sync/internal/manifest"
 	"github.com/cbout22/copilot-sync/internal/resolver"
+	"github.com/cbout22/copilot-sync/internal/store"
 )
 
+// UnknownSHA is recorded in the lock file when the commit an asset came from
+// cannot be determined.
+const UnknownSHA = "unknown"
+
 // Injector downloads assets from GitHub and writes them to the correct
 // .github/<type>/ directory.
 type Injector struct {
 	resolver resolver.ResolverAPI
 	lock     *manifest.LockFile
 	rootDir  string // project root directory
+
+	// store and linkMode, when set by UseStore, make files links into a
+	// shared content store instead of copies.
+	store    *store.Store
+	linkMode string
+
+	// cache, when set by UseCache, keeps downloads to be reused by later
+	// syncs of any project.
+	cache *store.Store
+
+	// readOnly, set by SetReadOnly, writes files without write permission.
+	readOnly bool
+
+	// parallelism, set by SetParallelism, bounds the files of a directory
+	// asset downloaded at once.
+	parallelism int
+
+	// ctx, set by SetContext, cancels downloads and transform commands.
+	// Once the files of an asset are downloaded they are all written, so
+	// cancellation never leaves an asset half-written.
+	ctx context.Context
+
+	// progress, set by SetProgress, is told about every file of a
+	// directory downloaded.
+	progress func(Progress)
+
+	// downloaded counts the bytes downloaded for the asset being injected.
+	downloaded int64
+}
+
+// Progress reports how far the download of a directory asset went: Done
+// of its Total files, making Bytes, were downloaded.
+type Progress struct {
+	Done, Total int
+	Bytes       int64
 }
 
 // New creates an Injector.
@@ -26,9 +69,41 @@ func New(res resolver.ResolverAPI, lock *manifest.LockFile, rootDir string) *Inj
 		resolver: res,
 		lock:     lock,
 		rootDir:  rootDir,
+		ctx:      context.Background(),
 	}
 }
 
+// SetContext makes the injector stop, before writing anything more, once
+// ctx is done.
+func (inj *Injector) SetContext(ctx context.Context) {
+	inj.ctx = ctx
+}
+
+// SetProgress makes the injector call progress after every file of a
+// directory asset it downloads. Nil reports nothing.
+func (inj *Injector) SetProgress(progress func(Progress)) {
+	inj.progress = progress
+}
+
+// UseStore makes the injector write each file as a link, of the given
+// store.Symlink or store.Hardlink mode, to its copy in st.
+func (inj *Injector) UseStore(st store.Store, mode string) {
+	inj.store, inj.linkMode = &st, mode
+}
+
+// SetReadOnly makes the injector write files without write permission,
+// or with it again when readOnly is false. Links into a store are left as
+// they are: stored files are always read-only.
+func (inj *Injector) SetReadOnly(readOnly bool) {
+	inj.readOnly = readOnly
+}
+
+// SetParallelism makes the injector download up to n files of a
+// directory asset at once. Below 2, files are downloaded one at a time.
+func (inj *Injector) SetParallelism(n int) {
+	inj.parallelism = n
+}
+
 // InjectResult holds the outcome of injecting a single asset.
 type InjectResult struct {
 	Type       string
@@ -37,15 +112,159 @@ type InjectResult struct {
 	TargetPath string
 	SHA        string
 	Err        error
+
+	// Bytes is how much was downloaded: nothing for content taken from
+	// the download cache or found unchanged upstream.
+	Bytes int64
+
+	// Warnings are problems that did not stop the asset from being
+	// written, e.g. possible secrets in an entry with secrets = "warn".
+	Warnings []string
+}
+
+// Options adjusts how a single entry is written. The zero value writes the
+// asset as downloaded.
+type Options struct {
+	// Frontmatter holds YAML frontmatter fields merged into single-file
+	// assets, overriding the upstream values.
+	Frontmatter map[string]any
+
+	// Files selects the files of directory assets to download, by their
+	// slash-separated path inside the directory. Nil selects every file.
+	Files func(rel string) bool
+
+	// Vars holds the values substituted for {{name}} placeholders in every
+	// file written.
+	Vars map[string]string
+
+	// Copies and Sections are the extra outputs the asset is written to,
+	// relative to the project root (see manifest.Manifest.Outputs): paths
+	// receiving a copy, and shared files receiving single-file assets as a
+	// managed section.
+	Copies   []string
+	Sections []string
+
+	// MaxFileSize and MaxDirSize cap the size in bytes of every downloaded
+	// file and of the files of a directory asset together. Zero is no
+	// limit.
+	MaxFileSize int64
+	MaxDirSize  int64
+
+	// Transform is a shell command every downloaded file is piped through,
+	// after the other changes, from the project root (see runTransform).
+	Transform string
+
+	// Integrity is the "sha256-<base64>" integrity value the downloaded
+	// content must have before any other option changes it: the file, or
+	// the selected files of a directory together. Empty accepts any.
+	Integrity string
+
+	// Cosign requires the asset to carry a cosign signature, checked
+	// before anything is written. Nil requires none.
+	Cosign *manifest.CosignOptions
+
+	// Secrets is what to do when the files about to be written look like
+	// they hold credentials: manifest.SecretsBlock (the default, when
+	// empty), SecretsWarn or SecretsAllow.
+	Secrets string
+
+	// Binaries is what to do with binary files in directory assets:
+	// manifest.BinariesAllow (the default, when empty), BinariesSkip,
+	// BinariesQuarantine or BinariesFail.
+	Binaries string
+
+	// License refuses the license of the repository the asset comes from,
+	// an SPDX identifier, by returning an error. Nil accepts any license,
+	// and lets assets whose license cannot be looked up through.
+	License func(license string) error
+
+	// EOL converts the line endings of text files to manifest.EOLLF or
+	// manifest.EOLCRLF. Empty keeps them as downloaded.
+	EOL string
+
+	// Banner is written as an HTML comment at the top of Markdown files,
+	// after their frontmatter. Empty writes none.
+	Banner string
 }
 
-// Inject downloads and writes a single asset.
+// checkIntegrity reports an error unless content, as downloaded, matches
+// o.Integrity.
+func (o Options) checkIntegrity(content []byte) error {
+	if o.Integrity == "" {
+		return nil
+	}
+	if got := manifest.Integrity(content); got != o.Integrity {
+		return fmt.Errorf("integrity mismatch: downloaded content is %s, copilot.toml pins %s — refusing to install", got, o.Integrity)
+	}
+	return nil
+}
+
+// checkSize reports an error if a downloaded file of size bytes, at path,
+// is over o.MaxFileSize.
+func (o Options) checkSize(path string, size int) error {
+	if o.MaxFileSize > 0 && int64(size) > o.MaxFileSize {
+		return fmt.Errorf("%s is %s, over the %s limit per file", path, manifest.FormatSize(int64(size)), manifest.FormatSize(o.MaxFileSize))
+	}
+	return nil
+}
+
+// limitDownloads has the resolver, if it can, enforce the size limits of
+// opts while it downloads, so an oversized file fails before it is all in
+// memory. Binaries opts sets aside count for neither limit but are only
+// told apart once downloaded, so then MaxArchiveSize bounds them instead.
+func (inj *Injector) limitDownloads(opts Options) {
+	l, ok := inj.resolver.(resolver.Limiter)
+	if !ok {
+		return
+	}
+	limits := resolver.Limits{File: opts.MaxFileSize, Dir: opts.MaxDirSize}
+	if opts.Binaries == manifest.BinariesSkip || opts.Binaries == manifest.BinariesQuarantine {
+		limits = resolver.Limits{File: resolver.MaxArchiveSize, Dir: resolver.MaxArchiveSize}
+	}
+	l.SetLimits(limits)
+}
+
+// selects reports whether o keeps the file at rel of a directory asset.
+func (o Options) selects(rel string) bool {
+	return o.Files == nil || o.Files(rel)
+}
+
+// transforms reports whether o changes the content of single-file assets.
+func (o Options) transforms() bool {
+	return len(o.Frontmatter) > 0 || len(o.Vars) > 0 || o.Transform != "" || o.EOL != "" || o.Banner != ""
+}
+
+// transform applies opts to the downloaded content of a single-file asset
+// at path upstream: frontmatter is merged first, so its values may use
+// placeholders too, and the transform command sees the result, which is
+// then given its banner and line endings. The lock file checksum is
+// computed from what comes out, which is what is written to disk.
+func (inj *Injector) transform(opts Options, path string, content []byte) ([]byte, error) {
+	content, err := mergeFrontmatter(content, opts.Frontmatter)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = inj.runTransform(opts, path, substituteVars(content, opts.Vars)); err != nil {
+		return nil, err
+	}
+	return normalize(opts, path, content), nil
+}
+
+// Inject downloads and writes a single asset to its type's default location.
 func (inj *Injector) Inject(assetType config.AssetType, name, rawRef string) InjectResult {
+	return inj.InjectTo(assetType, name, rawRef, assetType.TargetPath(name), Options{})
+}
+
+// InjectTo downloads and writes a single asset to targetPath, relative to
+// the project root, adjusted by opts.
+func (inj *Injector) InjectTo(assetType config.AssetType, name, rawRef, targetPath string, opts Options) InjectResult {
 	result := InjectResult{
 		Type: string(assetType),
 		Name: name,
 		Ref:  rawRef,
 	}
+	inj.downloaded = 0
+	inj.limitDownloads(opts)
 
 	// Parse the reference
 	ref, err := config.ParseRef(rawRef)
@@ -54,135 +273,772 @@ func (inj *Injector) Inject(assetType config.AssetType, name, rawRef string) Inj
 		return result
 	}
 
-	targetPath := assetType.TargetPath(name)
 	result.TargetPath = targetPath
 	absTarget := filepath.Join(inj.rootDir, targetPath)
 
+	var signer string
+	if opts.Cosign != nil {
+		if signer, err = inj.verifySignature(ref, opts.Cosign); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	license, err := inj.license(ref, string(assetType), name, rawRef, opts)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
 	if assetType.IsDirectory() {
-		err = inj.injectDirectory(ref, absTarget)
+		result.Warnings, err = inj.injectDirectory(ref, absTarget, assetType, name, targetPath, opts)
 	} else {
-		err = inj.injectFile(ref, absTarget, assetType, name, rawRef)
+		result.Warnings, err = inj.injectFile(ref, absTarget, assetType, name, rawRef, targetPath, opts)
+	}
+	if err == nil && signer != "" {
+		inj.lock.RecordSignature(string(assetType), name, signer)
+	}
+	if err == nil && license != "" {
+		inj.lock.RecordLicense(string(assetType), name, license)
 	}
 
-	result.Err = err
+	result.Err, result.Bytes = err, inj.downloaded
 	return result
 }
 
 // injectFile downloads a single file asset and writes it to disk.
-func (inj *Injector) injectFile(ref config.AssetRef, absTarget string, assetType config.AssetType, name, rawRef string) error {
+func (inj *Injector) injectFile(ref config.AssetRef, absTarget string, assetType config.AssetType, name, rawRef, targetPath string, opts Options) ([]string, error) {
 	// Ensure target directory exists
 	if err := os.MkdirAll(filepath.Dir(absTarget), 0755); err != nil {
-		return fmt.Errorf("creating directory: %w", err)
+		return nil, fmt.Errorf("creating directory: %w", err)
 	}
 
-	// Resolve commit SHA for the lock file
-	sha, err := inj.resolver.ResolveSHA(ref)
-	if err != nil {
-		return fmt.Errorf("resolving commit SHA: %w", err)
+	// A file pinned to the commit it was locked at is taken from the
+	// download cache when it is there.
+	var sha string
+	var content []byte
+	var validators resolver.Validators
+	if locked, ok := inj.pinnedEntry(ref, assetType, name, rawRef, targetPath, opts); ok {
+		if cached, ok := inj.cachedFile(locked); ok {
+			sha, content = locked.ResolvedSHA, cached
+			validators = resolver.Validators{ETag: locked.ETag, LastModified: locked.LastModified}
+		}
+	}
+	if content != nil {
+		if err := opts.checkIntegrity(content); err != nil {
+			return nil, err
+		}
 	}
 
-	// Download the file
-	content, err := inj.resolver.DownloadFile(ref)
+	if content == nil {
+		// Resolve commit SHA for the lock file
+		var err error
+		sha, err = inj.resolver.ResolveSHA(ref)
+		if err != nil {
+			return nil, fmt.Errorf("resolving commit SHA: %w", err)
+		}
+
+		// Download the file, unless it has not changed since the last sync.
+		// The local copy of a transformed file cannot stand in for the
+		// upstream one, so those are always downloaded.
+		if opts.transforms() {
+			content, err = inj.resolver.DownloadFile(ref)
+			inj.downloaded += int64(len(content))
+			if err == nil {
+				err = opts.checkIntegrity(content)
+			}
+			if err == nil {
+				content, err = inj.transform(opts, ref.Path, content)
+			}
+		} else {
+			content, validators, err = inj.downloadIfChanged(ref, assetType, name, rawRef, absTarget)
+			if err == nil {
+				err = opts.checkIntegrity(content)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := opts.checkSize(ref.Path, len(content)); err != nil {
+			return nil, err
+		}
+		inj.cacheFile(content, false)
+	}
+
+	if err := checkContent(assetType, ref.Path, content); err != nil {
+		return nil, err
+	}
+	warnings, err := opts.checkSecrets(map[string][]byte{ref.Path: content})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := inj.ctx.Err(); err != nil {
+		return nil, err
 	}
+	if err := inj.writeFile(absTarget, content, false); err != nil {
+		return nil, err
+	}
+	if err := inj.updateOutputs(string(assetType), name, opts, func() error {
+		return inj.writeFileOutputs(string(assetType)+"/"+name, content, opts)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Update the lock file
+	inj.lock.Set(string(assetType), name, rawRef, sha, targetPath, content)
+	inj.lock.RecordValidators(string(assetType), name, validators.ETag, validators.LastModified)
+	inj.lock.RecordOutputs(string(assetType), name, opts.Copies, opts.Sections)
+
+	return warnings, inj.lock.RecordModes(string(assetType), name, absTarget)
+}
+
+// downloadIfChanged downloads the file at ref. When the resolver supports
+// conditional requests and the lock file holds validators for the same ref,
+// the download is made conditional on them; if the file has not changed,
+// the local copy at absTarget is returned instead. Validators are only sent
+// while the local copy still matches the lock file.
+func (inj *Injector) downloadIfChanged(ref config.AssetRef, assetType config.AssetType, name, rawRef, absTarget string) ([]byte, resolver.Validators, error) {
+	cd, ok := inj.resolver.(resolver.ConditionalDownloader)
+	if !ok {
+		content, err := inj.resolver.DownloadFile(ref)
+		inj.downloaded += int64(len(content))
+		return content, resolver.Validators{}, err
+	}
+
+	var prev resolver.Validators
+	var local []byte
+	if locked, ok := inj.lock.Get(string(assetType), name); ok && locked.Ref == rawRef {
+		if data, err := os.ReadFile(absTarget); err == nil && manifest.Checksum(data) == locked.Checksum {
+			prev = resolver.Validators{ETag: locked.ETag, LastModified: locked.LastModified}
+			local = data
+		}
+	}
+
+	content, validators, err := cd.DownloadFileIfChanged(ref, prev)
+	if errors.Is(err, resolver.ErrNotModified) {
+		return local, prev, nil
+	}
+	inj.downloaded += int64(len(content))
+	return content, validators, err
+}
 
-	// Remove existing file if it exists to avoid stale content
-	if _, err := os.Stat(absTarget); err == nil {
+// writeFile replaces the file at absTarget with content, or with a link to
+// it in the store set by UseStore. Executable files get the execute bits.
+func (inj *Injector) writeFile(absTarget string, content []byte, executable bool) error {
+	if inj.store != nil {
+		return inj.store.Link(inj.linkMode, absTarget, content, executable)
+	}
+
+	// Remove existing file if it exists to avoid stale content. A link
+	// into the store must not be written through. A read-only file gets
+	// its write permission back first, which some platforms require.
+	if info, err := os.Lstat(absTarget); err == nil {
+		if info.Mode().IsRegular() && info.Mode().Perm()&0200 == 0 {
+			_ = os.Chmod(absTarget, info.Mode().Perm()|0200)
+		}
 		if err := os.Remove(absTarget); err != nil {
 			return fmt.Errorf("removing existing file: %w", err)
 		}
 	}
 
 	// Write to disk
-	if err := os.WriteFile(absTarget, content, 0644); err != nil {
+	perm := fs.FileMode(0644)
+	if executable {
+		perm = 0755
+	}
+	if inj.readOnly {
+		perm &^= 0222
+	}
+	if err := os.WriteFile(absTarget, content, perm); err != nil {
 		return fmt.Errorf("writing file %s: %w", absTarget, err)
 	}
+	return nil
+}
 
-	// Update the lock file
-	inj.lock.Set(string(assetType), name, rawRef, sha, assetType.TargetPath(name), content)
+// writeDirectory writes contents, keyed by relative path, under absTargetDir.
+// The paths set in executable are written executable. A path leading out of
+// absTargetDir fails before anything is written, whatever the source. If a
+// write fails, what was already written is put back as it was, so a failed
+// sync leaves no half-written skill.
+func (inj *Injector) writeDirectory(absTargetDir string, contents map[string][]byte, executable map[string]bool) (err error) {
+	for relPath := range contents {
+		if !filepath.IsLocal(filepath.FromSlash(relPath)) {
+			return fmt.Errorf("invalid file path %q: outside of the directory", relPath)
+		}
+	}
+
+	var snap snapshot
+	defer func() {
+		if err != nil {
+			snap.restore()
+		}
+	}()
+
+	// Ensure base target directory exists
+	if err := snap.mkdirAll(absTargetDir); err != nil {
+		return fmt.Errorf("creating skill directory: %w", err)
+	}
+
+	for _, relPath := range manifest.SortedKeys(contents) {
+		targetFile := filepath.Join(absTargetDir, filepath.FromSlash(relPath))
+
+		// Ensure subdirectories exist
+		if err := snap.mkdirAll(filepath.Dir(targetFile)); err != nil {
+			return fmt.Errorf("creating directory for %s: %w", relPath, err)
+		}
 
+		if err := snap.save(targetFile); err != nil {
+			return err
+		}
+		if err := inj.writeFile(targetFile, contents[relPath], executable[relPath]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot records the files and directories writeDirectory is about to
+// change, so restore can put them back.
+type snapshot struct {
+	files []savedFile
+	dirs  []string // created, parents first
+}
+
+// savedFile is a file as it was before being written.
+type savedFile struct {
+	path    string
+	existed bool
+	mode    fs.FileMode
+	content []byte
+	link    string // target, for symbolic links
+}
+
+// mkdirAll is os.MkdirAll, recording the directories it creates.
+func (s *snapshot) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append(missing, d)
+	}
+	err := os.MkdirAll(dir, 0755)
+	for i := len(missing) - 1; i >= 0; i-- {
+		if info, statErr := os.Lstat(missing[i]); statErr == nil && info.IsDir() {
+			s.dirs = append(s.dirs, missing[i])
+		}
+	}
+	return err
+}
+
+// save records the file at path as it is now.
+func (s *snapshot) save(path string) error {
+	saved := savedFile{path: path}
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case info.Mode()&fs.ModeSymlink != 0:
+		saved.existed, saved.mode = true, info.Mode()
+		if saved.link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	default:
+		saved.existed, saved.mode = true, info.Mode()
+		if saved.content, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("reading existing file: %w", err)
+		}
+	}
+	s.files = append(s.files, saved)
 	return nil
 }
 
+// restore puts back the files saved, last first, then removes the
+// directories created. It is best effort: it runs after a failure, which
+// is what gets reported.
+func (s *snapshot) restore() {
+	for i := len(s.files) - 1; i >= 0; i-- {
+		f := s.files[i]
+		if info, err := os.Lstat(f.path); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0200 == 0 {
+			_ = os.Chmod(f.path, info.Mode().Perm()|0200)
+		}
+		_ = os.Remove(f.path)
+		switch {
+		case !f.existed:
+		case f.mode&fs.ModeSymlink != 0:
+			_ = os.Symlink(f.link, f.path)
+		default:
+			if os.WriteFile(f.path, f.content, 0600) == nil {
+				_ = os.Chmod(f.path, f.mode.Perm())
+			}
+		}
+	}
+	for i := len(s.dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(s.dirs[i])
+	}
+}
+
 // computeDirectoryChecksum creates a combined checksum for all files in a directory.
 func computeDirectoryChecksum(contents map[string][]byte) []byte {
-	// Sort keys so the checksum is deterministic regardless of map iteration order.
-	keys := make([]string, 0, len(contents))
-	for k := range contents {
-		keys = append(keys, k)
+	// Keys are sorted byte-wise so the checksum is deterministic regardless
+	// of map iteration order or locale.
+	return manifest.DirectoryContent(contents)
+}
+
+// injectDirectory downloads the files of a directory (for skills) selected
+// by opts and writes them. Files written by the previous sync that are no
+// longer selected, or no longer exist upstream, are removed unless they were
+// edited since.
+func (inj *Injector) injectDirectory(ref config.AssetRef, absTargetDir string, assetType config.AssetType, name, targetPath string, opts Options) ([]string, error) {
+	// A directory pinned to the commit it was locked at is taken from the
+	// download cache when all its files are there.
+	sha := ""
+	var allContents map[string][]byte
+	var executable map[string]bool
+	var setAside []string // warnings about the binaries left out
+	if locked, ok := inj.pinnedEntry(ref, assetType, name, ref.Raw(), targetPath, opts); ok {
+		if contents, exec, ok := inj.cachedDirectory(locked); ok {
+			if err := opts.checkIntegrity(computeDirectoryChecksum(contents)); err != nil {
+				return nil, err
+			}
+			sha, allContents, executable = locked.ResolvedSHA, contents, exec
+		}
+	}
+	if allContents == nil {
+		var err error
+		var binaries map[string][]byte
+		if allContents, executable, binaries, err = inj.fetchDirectory(ref, opts); err != nil {
+			return nil, err
+		}
+		inj.cacheDirectory(allContents, executable)
+		if setAside, err = inj.setAsideBinaries(string(assetType), name, binaries, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	warnings, err := opts.checkSecrets(directoryFiles(ref, allContents))
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(setAside, warnings...)
+	if err := inj.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := inj.writeDirectory(absTargetDir, allContents, executable); err != nil {
+		return nil, err
+	}
+	prev, _ := inj.lock.Get(string(assetType), name)
+	if prev.TargetPath == targetPath {
+		if err := removeStale(absTargetDir, prev.Files, allContents); err != nil {
+			return nil, err
+		}
+	}
+	if err := inj.updateOutputs(string(assetType), name, opts, func() error {
+		return inj.writeDirectoryOutputs(allContents, executable, prev.Files, opts)
+	}); err != nil {
+		return nil, err
 	}
-	sort.Strings(keys)
 
-	var combined []byte
-	for _, k := range keys {
-		combined = append(combined, contents[k]...)
+	// Resolve commit SHA for the lock file
+	if sha == "" {
+		var err error
+		if sha, err = inj.resolver.ResolveSHA(ref); err != nil {
+			// Non-fatal: we still wrote the files, just can't lock the SHA
+			sha = UnknownSHA
+		}
 	}
-	return combined
+
+	// Update the lock file with the combined and per-file checksums
+	inj.lock.SetDirectory(string(assetType), name, ref.Raw(), sha, targetPath, allContents)
+	inj.lock.RecordOutputs(string(assetType), name, opts.Copies, opts.Sections)
+
+	return warnings, inj.lock.RecordModes(string(assetType), name, absTargetDir)
 }
 
-// injectDirectory downloads all files in a directory (for skills) and writes them.
-func (inj *Injector) injectDirectory(ref config.AssetRef, absTargetDir string) error {
-	// List all files in the remote directory
-	entries, err := inj.resolver.ListDirectory(ref)
-	if err != nil {
+// updateOutputs writes the extra outputs of an entry with write, then
+// removes those its lock entry records that opts no longer lists.
+func (inj *Injector) updateOutputs(assetType, name string, opts Options, write func() error) error {
+	if err := write(); err != nil {
 		return err
 	}
+	if prev, ok := inj.lock.Get(assetType, name); ok {
+		return inj.removeUnusedOutputs(prev, opts)
+	}
+	return nil
+}
 
-	// Ensure base target directory exists
-	if err := os.MkdirAll(absTargetDir, 0755); err != nil {
-		return fmt.Errorf("creating skill directory: %w", err)
+// removeStale removes the files recorded in prev that are not in contents
+// and still match their recorded digest, then the directories they leave
+// empty under absTargetDir.
+func removeStale(absTargetDir string, prev map[string]manifest.FileDigest, contents map[string][]byte) error {
+	for _, rel := range manifest.SortedKeys(prev) {
+		if _, ok := contents[rel]; ok || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			continue
+		}
+		file := filepath.Join(absTargetDir, filepath.FromSlash(rel))
+		data, err := os.ReadFile(file)
+		if err != nil || manifest.Checksum(data) != prev[rel].SHA256 {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("removing %s: %w", file, err)
+		}
+		// Remove fails on directories that still hold files.
+		for dir := filepath.Dir(file); dir != absTargetDir; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
 	}
+	return nil
+}
 
-	// Track all downloaded contents for checksum
-	allContents := make(map[string][]byte)
+// fetchDirectory downloads the files under a remote directory that opts
+// selects, with placeholders substituted, keyed by their path relative to
+// that directory, and the set of those paths that are executable upstream.
+// Binary files opts.Binaries skips or quarantines are returned apart, as
+// downloaded; they count for neither the size limit nor opts.Integrity.
+func (inj *Injector) fetchDirectory(ref config.AssetRef, opts Options) (map[string][]byte, map[string]bool, map[string][]byte, error) {
+	// List all files in the remote directory
+	entries, err := inj.resolver.ListDirectory(ref)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
+	// Compute relative paths within the skill directory
+	relPaths := make(map[string]string, len(entries))
+	var selected []resolver.GitHubTreeEntry
 	for _, entry := range entries {
-		// Compute relative path within the skill directory
-		relPath := strings.TrimPrefix(entry.Path, ref.Path+"/")
-		if relPath == entry.Path {
-			// It's the directory entry itself, use the filename
-			relPath = filepath.Base(entry.Path)
+		relPath := entry.Path
+		if ref.Path != "" {
+			relPath = strings.TrimPrefix(entry.Path, ref.Path+"/")
+			if relPath == entry.Path {
+				// It's the directory entry itself, use the filename
+				relPath = filepath.Base(entry.Path)
+			}
 		}
+		if opts.selects(relPath) {
+			relPaths[entry.Path] = relPath
+			selected = append(selected, entry)
+		}
+	}
 
-		targetFile := filepath.Join(absTargetDir, relPath)
+	files, err := inj.downloadFiles(ref, selected)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-		// Ensure subdirectories exist
-		if err := os.MkdirAll(filepath.Dir(targetFile), 0755); err != nil {
-			return fmt.Errorf("creating directory for %s: %w", relPath, err)
+	contents := make(map[string][]byte)
+	executable := make(map[string]bool)
+	downloaded := make(map[string][]byte) // as downloaded, for opts.Integrity
+	binaries := make(map[string][]byte)   // set aside by opts.Binaries
+	var total int64
+	for i, entry := range selected {
+		relPath, content := relPaths[entry.Path], files[i]
+		if setAside, err := opts.checkBinary(relPath, content); err != nil {
+			return nil, nil, nil, err
+		} else if setAside {
+			binaries[relPath] = content
+			continue
+		}
+		if err := opts.checkSize(relPath, len(content)); err != nil {
+			return nil, nil, nil, err
+		}
+		total += int64(len(content))
+		if opts.MaxDirSize > 0 && total > opts.MaxDirSize {
+			return nil, nil, nil, fmt.Errorf("files are over the %s limit per directory", manifest.FormatSize(opts.MaxDirSize))
+		}
+		if opts.Integrity != "" {
+			downloaded[relPath] = content
 		}
+		if content, err = inj.runTransform(opts, entry.Path, substituteVars(content, opts.Vars)); err != nil {
+			return nil, nil, nil, err
+		}
+		contents[relPath] = normalize(opts, relPath, content)
+		if entry.Executable() {
+			executable[relPath] = true
+		}
+	}
+	if err := opts.checkIntegrity(computeDirectoryChecksum(downloaded)); err != nil {
+		return nil, nil, nil, err
+	}
+	return contents, executable, binaries, nil
+}
 
-		// Download each file using raw URL
-		fileRef := config.AssetRef{
-			Org:  ref.Org,
-			Repo: ref.Repo,
-			Path: entry.Path,
-			Ref:  ref.Ref,
+// downloadFiles downloads the files of the directory at ref listed by
+// entries, up to inj.parallelism at once, and returns their contents in
+// the same order. No download starts once one has failed or the context
+// is done.
+func (inj *Injector) downloadFiles(ref config.AssetRef, entries []resolver.GitHubTreeEntry) ([][]byte, error) {
+	files := make([][]byte, len(entries))
+	errs := make([]error, len(entries))
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		done   int
+		failed bool
+	)
+	sem := make(chan struct{}, max(inj.parallelism, 1))
+	for i, entry := range entries {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if err := inj.ctx.Err(); err != nil || stop {
+			errs[i] = err
+			<-sem
+			break
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Download each file from the same source, pointing at the entry path
+			fileRef := ref
+			fileRef.Path = entry.Path
+			content, err := inj.resolver.DownloadFile(fileRef)
 
-		content, err := inj.resolver.DownloadFile(fileRef)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[i], failed = fmt.Errorf("downloading %s: %w", entry.Path, err), true
+				return
+			}
+			files[i] = content
+			done++
+			inj.downloaded += int64(len(content))
+			if inj.progress != nil {
+				inj.progress(Progress{Done: done, Total: len(entries), Bytes: inj.downloaded})
+			}
+		}()
+	}
+	wg.Wait()
+	// The first error in order is the one a sequential download would hit.
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("downloading %s: %w", entry.Path, err)
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// InjectLocked writes the asset recorded by locked to targetPath, relative
+// to the project root, without re-resolving rawRef: GitHub refs are fetched
+// at the locked commit and OCI refs at the locked digest. Content whose
+// checksum differs from the lock file is rejected before anything is
+// written, so every machine gets the same bytes. The lock file is left
+// untouched.
+func (inj *Injector) InjectLocked(assetType config.AssetType, name, rawRef, targetPath string, locked manifest.LockEntry, opts Options) InjectResult {
+	result := InjectResult{
+		Type:       string(assetType),
+		Name:       name,
+		Ref:        rawRef,
+		TargetPath: targetPath,
+		SHA:        locked.ResolvedSHA,
+	}
+	inj.downloaded = 0
+	inj.limitDownloads(opts)
+
+	ref, err := config.ParseRef(rawRef)
+	if err == nil {
+		ref, err = pinRef(ref, locked.ResolvedSHA)
+	}
+	if err == nil && opts.Cosign != nil {
+		_, err = inj.verifySignature(ref, opts.Cosign)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	absTarget := filepath.Join(inj.rootDir, targetPath)
+	if assetType.IsDirectory() {
+		result.Warnings, result.Err = inj.writeLockedDirectory(ref, absTarget, locked, opts)
+	} else {
+		result.Warnings, result.Err = inj.writeLockedFile(ref, absTarget, locked, opts)
+	}
+	result.Bytes = inj.downloaded
+	return result
+}
+
+// writeLockedFile downloads the file at ref, adjusted by opts, unless the
+// download cache holds it, and writes it to absTarget if it matches locked.
+func (inj *Injector) writeLockedFile(ref config.AssetRef, absTarget string, locked manifest.LockEntry, opts Options) ([]string, error) {
+	content, cached := inj.cachedFile(locked)
+	if cached && !opts.transforms() {
+		// The cached content is the file as downloaded.
+		if err := opts.checkIntegrity(content); err != nil {
+			return nil, err
+		}
+	}
+	if !cached {
+		var err error
+		if content, err = inj.resolver.DownloadFile(ref); err != nil {
+			return nil, err
+		}
+		inj.downloaded += int64(len(content))
+		if err := opts.checkIntegrity(content); err != nil {
+			return nil, err
 		}
+		if err := opts.checkSize(ref.Path, len(content)); err != nil {
+			return nil, err
+		}
+		if content, err = inj.transform(opts, ref.Path, content); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkLocked(locked, content); err != nil {
+		return nil, err
+	}
+	if !cached {
+		inj.cacheFile(content, false)
+	}
+	warnings, err := opts.checkSecrets(map[string][]byte{ref.Path: content})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(absTarget), 0755); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+	if err := inj.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := inj.writeFile(absTarget, content, false); err != nil {
+		return nil, err
+	}
+	return warnings, inj.writeFileOutputs(locked.Type+"/"+locked.Name, content, opts)
+}
 
-		if err := os.WriteFile(targetFile, content, 0644); err != nil {
-			return fmt.Errorf("writing %s: %w", targetFile, err)
+// writeLockedDirectory downloads the files of the directory at ref selected
+// by opts, unless the download cache holds them, and writes them to
+// absTargetDir if they match locked.
+func (inj *Injector) writeLockedDirectory(ref config.AssetRef, absTargetDir string, locked manifest.LockEntry, opts Options) ([]string, error) {
+	contents, executable, cached := inj.cachedDirectory(locked)
+	if cached && !opts.transforms() {
+		if err := opts.checkIntegrity(computeDirectoryChecksum(contents)); err != nil {
+			return nil, err
+		}
+	}
+	var setAside []string // warnings about the binaries left out
+	if !cached {
+		var binaries map[string][]byte
+		var err error
+		if contents, executable, binaries, err = inj.fetchDirectory(ref, opts); err != nil {
+			return nil, err
+		}
+		if setAside, err = inj.setAsideBinaries(locked.Type, locked.Name, binaries, opts); err != nil {
+			return nil, err
 		}
+	}
+	if err := checkLocked(locked, computeDirectoryChecksum(contents)); err != nil {
+		return nil, err
+	}
+	if !cached {
+		inj.cacheDirectory(contents, executable)
+	}
+	warnings, err := opts.checkSecrets(directoryFiles(ref, contents))
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(setAside, warnings...)
+	if err := inj.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := inj.writeDirectory(absTargetDir, contents, executable); err != nil {
+		return nil, err
+	}
+	return warnings, inj.writeDirectoryOutputs(contents, executable, nil, opts)
+}
 
-		allContents[relPath] = content
+// pinRef points ref at the commit or digest sha recorded in the lock file,
+// for the sources that can address content by it. Other sources keep their
+// ref; their content is still checked against the locked checksum.
+func pinRef(ref config.AssetRef, sha string) (config.AssetRef, error) {
+	switch {
+	case ref.IsGitHub():
+		if sha == "" || sha == UnknownSHA {
+			return ref, fmt.Errorf("locked to an unverified commit; run 'cops sync' to lock it")
+		}
+		ref.Ref = sha
+	case ref.IsOCI() && strings.HasPrefix(sha, "sha256:"):
+		ref.Ref = sha
 	}
+	return ref, nil
+}
 
-	// Resolve commit SHA for the lock file
+// checkLocked reports whether content, as the lock file checksum is computed
+// from it, matches locked.
+func checkLocked(locked manifest.LockEntry, content []byte) error {
+	if got := manifest.Checksum(content); got != locked.Checksum {
+		return fmt.Errorf("downloaded content does not match the lock file (checksum %s, locked %s)", got, locked.Checksum)
+	}
+	return nil
+}
+
+// FetchDirectory downloads a directory asset without writing it to disk
+// and returns the files opts selects, keyed by their slash-separated path
+// inside the directory, as they would be written, together with the
+// resolved commit SHA.
+func (inj *Injector) FetchDirectory(rawRef string, opts Options) (map[string][]byte, string, error) {
+	inj.limitDownloads(opts)
+	ref, err := config.ParseRef(rawRef)
+	if err != nil {
+		return nil, "", err
+	}
 	sha, err := inj.resolver.ResolveSHA(ref)
 	if err != nil {
-		// Non-fatal: we still wrote the files, just can't lock the SHA
-		sha = "unknown"
+		return nil, "", fmt.Errorf("resolving commit SHA: %w", err)
 	}
+	if opts.Cosign != nil {
+		if _, err := inj.verifySignature(ref, opts.Cosign); err != nil {
+			return nil, "", err
+		}
+	}
+	contents, _, _, err := inj.fetchDirectory(ref, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, sha, nil
+}
 
-	// Update the lock file with combined checksum
-	combinedContent := computeDirectoryChecksum(allContents)
-	targetPath := strings.TrimPrefix(absTargetDir, inj.rootDir+"/")
-	inj.lock.Set("skills", filepath.Base(absTargetDir), ref.Raw(), sha, targetPath, combinedContent)
+// Fetch downloads an asset without writing it to disk and returns the
+// content the lock file checksum is computed from (the concatenated files
+// selected by opts for directories, the content adjusted by opts for files)
+// together with the resolved commit SHA.
+func (inj *Injector) Fetch(assetType config.AssetType, rawRef string, opts Options) ([]byte, string, error) {
+	if assetType.IsDirectory() {
+		contents, sha, err := inj.FetchDirectory(rawRef, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return computeDirectoryChecksum(contents), sha, nil
+	}
+	inj.limitDownloads(opts)
 
-	return nil
+	ref, err := config.ParseRef(rawRef)
+	if err != nil {
+		return nil, "", err
+	}
+	sha, err := inj.resolver.ResolveSHA(ref)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving commit SHA: %w", err)
+	}
+	if opts.Cosign != nil {
+		if _, err := inj.verifySignature(ref, opts.Cosign); err != nil {
+			return nil, "", err
+		}
+	}
+
+	content, err := inj.resolver.DownloadFile(ref)
+	if err == nil {
+		err = opts.checkIntegrity(content)
+	}
+	if err == nil {
+		content, err = inj.transform(opts, ref.Path, content)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return content, sha, nil
 }
@@ -2,7 +2,12 @@ package injector
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
 )
 
 func TestComputeDirectoryChecksum_Deterministic(t *testing.T) {
@@ -74,3 +79,104 @@ func TestComputeDirectoryChecksum_StableAcrossMultipleCalls(t *testing.T) {
 		t.Errorf("expected ABCDE, got %q", first)
 	}
 }
+
+func TestWriteDirectory_RejectsEscapingPaths(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	inj := New(nil, manifest.NewLockFile(), root)
+	skill := filepath.Join(root, ".github", "skills", "k8s")
+
+	err := inj.writeDirectory(skill, map[string][]byte{
+		"SKILL.md":                       []byte("# Kubernetes\n"),
+		"../../../.git/hooks/pre-commit": []byte("#!/bin/sh\n"),
+	}, nil)
+	if err == nil {
+		t.Fatal("writeDirectory with a .. path: expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git", "hooks", "pre-commit")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(skill, "SKILL.md")); !os.IsNotExist(err) {
+		t.Errorf("SKILL.md written despite the error: %v", err)
+	}
+}
+
+func TestWriteDirectory_RestoresOnFailure(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	inj := New(nil, manifest.NewLockFile(), root)
+	skill := filepath.Join(root, ".github", "skills", "k8s")
+	if err := os.MkdirAll(skill, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := map[string]string{"SKILL.md": "# Old\n", "ref": "not a directory\n"}
+	for name, content := range old {
+		if err := os.WriteFile(filepath.Join(skill, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files are written in path order: SKILL.md and new.md are written
+	// before ref/notes.md fails, as ref is a file.
+	err := inj.writeDirectory(skill, map[string][]byte{
+		"SKILL.md":     []byte("# New\n"),
+		"new.md":       []byte("new\n"),
+		"ref/notes.md": []byte("notes\n"),
+	}, nil)
+	if err == nil {
+		t.Fatal("writeDirectory over a file in the way: expected an error")
+	}
+	for name, content := range old {
+		if got, err := os.ReadFile(filepath.Join(skill, name)); err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(skill, "new.md")); !os.IsNotExist(err) {
+		t.Errorf("new.md left behind: %v", err)
+	}
+}
+
+func TestWriteDirectory_RemovesCreatedDirectories(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	inj := New(nil, manifest.NewLockFile(), root)
+	skill := filepath.Join(root, ".github", "skills", "k8s")
+
+	err := inj.writeDirectory(skill, map[string][]byte{
+		"a/SKILL.md":   []byte("# Kubernetes\n"),
+		"a/SKILL.md/x": []byte("x\n"),
+	}, nil)
+	if err == nil {
+		t.Fatal("writeDirectory with a file in the way: expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(root, ".github")); !os.IsNotExist(err) {
+		t.Errorf(".github left behind: %v", err)
+	}
+}
+
+// limitedResolver records the limits it is given.
+type limitedResolver struct {
+	resolver.ResolverAPI
+	limits resolver.Limits
+}
+
+func (r *limitedResolver) SetLimits(l resolver.Limits) { r.limits = l }
+
+func TestLimitDownloads(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		binaries string
+		want     resolver.Limits
+	}{
+		{"", resolver.Limits{File: 10, Dir: 100}},
+		{manifest.BinariesFail, resolver.Limits{File: 10, Dir: 100}},
+		{manifest.BinariesSkip, resolver.Limits{File: resolver.MaxArchiveSize, Dir: resolver.MaxArchiveSize}},
+	}
+	for _, tc := range cases {
+		res := &limitedResolver{}
+		New(res, manifest.NewLockFile(), t.TempDir()).limitDownloads(Options{MaxFileSize: 10, MaxDirSize: 100, Binaries: tc.binaries})
+		if res.limits != tc.want {
+			t.Errorf("binaries %q: limits = %+v, want %+v", tc.binaries, res.limits, tc.want)
+		}
+	}
+}
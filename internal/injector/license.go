@@ -0,0 +1,33 @@
+package injector
+
+import (
+	"fmt"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// license returns the license of the repository ref points into and checks
+// it against opts.License. The license recorded for the entry is reused
+// while its ref is unchanged, so a sync does not look it up again. It
+// returns "" when the source cannot tell.
+func (inj *Injector) license(ref config.AssetRef, assetType, name, rawRef string, opts Options) (string, error) {
+	var license string
+	if locked, ok := inj.lock.Get(assetType, name); ok && locked.Ref == rawRef && locked.License != "" {
+		license = locked.License
+	} else if d, ok := inj.resolver.(resolver.LicenseDetector); ok {
+		var err error
+		if license, err = d.License(ref); err != nil {
+			if opts.License == nil {
+				return "", nil
+			}
+			return "", fmt.Errorf("checking the license: %w", err)
+		}
+	}
+	if license != "" && opts.License != nil {
+		if err := opts.License(license); err != nil {
+			return "", err
+		}
+	}
+	return license, nil
+}
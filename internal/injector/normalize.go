@@ -0,0 +1,56 @@
+package injector
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// normalize gives content, a text file at path, the banner and line
+// endings opts asks for. Binary files are returned as they are.
+func normalize(opts Options, path string, content []byte) []byte {
+	if isBinary(content) {
+		return content
+	}
+	if opts.Banner != "" && isMarkdown(path) {
+		content = addBanner(content, opts.Banner)
+	}
+	switch opts.EOL {
+	case manifest.EOLLF:
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	case manifest.EOLCRLF:
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+	}
+	return content
+}
+
+// isMarkdown reports whether the file at p is a Markdown file.
+func isMarkdown(p string) bool {
+	return strings.EqualFold(path.Ext(p), ".md")
+}
+
+// addBanner inserts banner as an HTML comment at the top of content, a
+// Markdown file, after its frontmatter: Copilot reads the frontmatter only
+// at the very start of the file. Content already starting with the banner
+// is returned as it is.
+func addBanner(content []byte, banner string) []byte {
+	text := string(content)
+	nl := "\n"
+	if strings.Contains(text, "\r\n") {
+		nl = "\r\n"
+	}
+	comment := "<!-- " + banner + " -->"
+
+	var header string
+	body := text
+	if _, rest, ok := splitFrontmatter(text); ok {
+		header, body = text[:len(text)-len(rest)], rest
+	}
+	if strings.HasPrefix(body, comment) {
+		return content
+	}
+	return []byte(header + comment + nl + body)
+}
@@ -0,0 +1,63 @@
+package injector
+
+import (
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		opts    Options
+		path    string
+		content string
+		want    string
+	}{
+		"banner at the top": {
+			Options{Banner: "managed"}, "go.instructions.md",
+			"# Go\n", "<!-- managed -->\n# Go\n",
+		},
+		"banner after the frontmatter": {
+			Options{Banner: "managed"}, "SKILL.md",
+			"---\nname: k8s\n---\n# K8s\n", "---\nname: k8s\n---\n<!-- managed -->\n# K8s\n",
+		},
+		"banner with crlf": {
+			Options{Banner: "managed"}, "a.md",
+			"---\r\na: 1\r\n---\r\nbody\r\n", "---\r\na: 1\r\n---\r\n<!-- managed -->\r\nbody\r\n",
+		},
+		"banner only once": {
+			Options{Banner: "managed"}, "a.md",
+			"<!-- managed -->\n# A\n", "<!-- managed -->\n# A\n",
+		},
+		"no banner outside markdown": {
+			Options{Banner: "managed"}, "scripts/run.sh",
+			"echo hi\n", "echo hi\n",
+		},
+		"lf": {
+			Options{EOL: manifest.EOLLF}, "a.md",
+			"a\r\nb\nc\r\n", "a\nb\nc\n",
+		},
+		"crlf": {
+			Options{EOL: manifest.EOLCRLF}, "a.md",
+			"a\r\nb\nc", "a\r\nb\r\nc",
+		},
+		"banner and crlf": {
+			Options{Banner: "managed", EOL: manifest.EOLCRLF}, "a.md",
+			"# A\n", "<!-- managed -->\r\n# A\r\n",
+		},
+		"binary untouched": {
+			Options{Banner: "managed", EOL: manifest.EOLCRLF}, "logo.md",
+			"\x00\n\x01\n", "\x00\n\x01\n",
+		},
+		"nothing to do": {
+			Options{}, "a.md",
+			"a\r\nb\n", "a\r\nb\n",
+		},
+	}
+	for name, tc := range cases {
+		if got := normalize(tc.opts, tc.path, []byte(tc.content)); string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", name, got, tc.want)
+		}
+	}
+}
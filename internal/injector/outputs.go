@@ -0,0 +1,192 @@
+package injector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// sectionMarkers returns the comments delimiting the managed section of the
+// entry id ("<type>/<name>") in a shared file.
+func sectionMarkers(id string) (begin, end string) {
+	return "<!-- cops:begin " + id + " -->\n", "<!-- cops:end " + id + " -->\n"
+}
+
+// SectionBody returns what the managed section of a single-file asset
+// holds: its content without frontmatter, ending with a newline.
+func SectionBody(content []byte) []byte {
+	if _, body, ok := splitFrontmatter(string(content)); ok {
+		content = []byte(body)
+	}
+	content = bytes.TrimLeft(content, "\r\n")
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(slices.Clip(content), '\n')
+	}
+	return content
+}
+
+// FindSection returns the body of the managed section id in doc.
+func FindSection(doc []byte, id string) ([]byte, bool) {
+	start, end, bodyStart, bodyEnd := sectionBounds(doc, id)
+	if start < 0 || end < 0 {
+		return nil, false
+	}
+	return doc[bodyStart:bodyEnd], true
+}
+
+// sectionBounds returns the byte range of the section id in doc, markers
+// included, and the range of its body. start is -1 if there is none.
+func sectionBounds(doc []byte, id string) (start, end, bodyStart, bodyEnd int) {
+	begin, stop := sectionMarkers(id)
+	start = bytes.Index(doc, []byte(begin))
+	if start < 0 {
+		return -1, -1, 0, 0
+	}
+	bodyStart = start + len(begin)
+	i := bytes.Index(doc[bodyStart:], []byte(stop))
+	if i < 0 {
+		return -1, -1, 0, 0
+	}
+	bodyEnd = bodyStart + i
+	return start, bodyEnd + len(stop), bodyStart, bodyEnd
+}
+
+// upsertSection replaces the section id of doc with body, or appends it
+// after a blank line.
+func upsertSection(doc []byte, id string, body []byte) []byte {
+	begin, stop := sectionMarkers(id)
+	block := slices.Concat([]byte(begin), body, []byte(stop))
+	if start, end, _, _ := sectionBounds(doc, id); start >= 0 {
+		return slices.Concat(doc[:start], block, doc[end:])
+	}
+	switch {
+	case len(doc) == 0:
+	case bytes.HasSuffix(doc, []byte("\n\n")):
+	case bytes.HasSuffix(doc, []byte("\n")):
+		doc = append(slices.Clip(doc), '\n')
+	default:
+		doc = append(slices.Clip(doc), '\n', '\n')
+	}
+	return slices.Concat(doc, block)
+}
+
+// removeSection removes the section id from doc, together with the blank
+// line upsertSection put before it.
+func removeSection(doc []byte, id string) []byte {
+	start, end, _, _ := sectionBounds(doc, id)
+	if start < 0 {
+		return doc
+	}
+	if bytes.HasSuffix(doc[:start], []byte("\n\n")) {
+		start--
+	}
+	return slices.Concat(doc[:start], doc[end:])
+}
+
+// writeSection writes body as the section id of the shared file at path,
+// relative to rootDir, creating the file if needed.
+func writeSection(rootDir, path, id string, body []byte) error {
+	abs := filepath.Join(rootDir, path)
+	doc, err := os.ReadFile(abs)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(abs, upsertSection(doc, id, body), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// dropSection removes the section id from the shared file at path,
+// relative to rootDir, and the file itself once nothing else is left.
+func dropSection(rootDir, path, id string) error {
+	abs := filepath.Join(rootDir, path)
+	doc, err := os.ReadFile(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	doc = removeSection(doc, id)
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return os.Remove(abs)
+	}
+	return os.WriteFile(abs, doc, 0644)
+}
+
+// writeFileOutputs writes content, as written to the target of the
+// single-file asset id, to the copies and sections of opts.
+func (inj *Injector) writeFileOutputs(id string, content []byte, opts Options) error {
+	for _, path := range opts.Copies {
+		abs := filepath.Join(inj.rootDir, path)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		if err := inj.writeFile(abs, content, false); err != nil {
+			return err
+		}
+	}
+	for _, path := range opts.Sections {
+		if err := writeSection(inj.rootDir, path, id, SectionBody(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDirectoryOutputs writes contents, as written to the target of a
+// directory asset, to the copies of opts. Files of prev no longer in
+// contents are removed as from the target.
+func (inj *Injector) writeDirectoryOutputs(contents map[string][]byte, executable map[string]bool, prev map[string]manifest.FileDigest, opts Options) error {
+	for _, path := range opts.Copies {
+		abs := filepath.Join(inj.rootDir, path)
+		if err := inj.writeDirectory(abs, contents, executable); err != nil {
+			return err
+		}
+		if err := removeStale(abs, prev, contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUnusedOutputs removes the outputs recorded in prev that opts no
+// longer writes to.
+func (inj *Injector) removeUnusedOutputs(prev manifest.LockEntry, opts Options) error {
+	prev.Copies = slices.DeleteFunc(slices.Clone(prev.Copies), func(p string) bool { return slices.Contains(opts.Copies, p) })
+	prev.Sections = slices.DeleteFunc(slices.Clone(prev.Sections), func(p string) bool { return slices.Contains(opts.Sections, p) })
+	return RemoveOutputs(inj.rootDir, prev)
+}
+
+// RemoveOutputs deletes the copies of the asset recorded by locked and its
+// sections in shared files, under rootDir.
+func RemoveOutputs(rootDir string, locked manifest.LockEntry) error {
+	id := locked.Type + "/" + locked.Name
+	for _, path := range locked.Copies {
+		if !filepath.IsLocal(path) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(rootDir, path)); err != nil {
+			return fmt.Errorf("deleting %s: %w", path, err)
+		}
+	}
+	for _, path := range locked.Sections {
+		if !filepath.IsLocal(path) {
+			continue
+		}
+		if err := dropSection(rootDir, path, id); err != nil {
+			return fmt.Errorf("updating %s: %w", path, err)
+		}
+	}
+	return nil
+}
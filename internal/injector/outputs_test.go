@@ -0,0 +1,45 @@
+package injector
+
+import "testing"
+
+func TestSections(t *testing.T) {
+	t.Parallel()
+	doc := []byte("# Project notes\nKeep this.")
+
+	doc = upsertSection(doc, "instructions/go", []byte("Use gofmt.\n"))
+	doc = upsertSection(doc, "instructions/sql", []byte("Use sqlc.\n"))
+	want := "# Project notes\nKeep this.\n\n" +
+		"<!-- cops:begin instructions/go -->\nUse gofmt.\n<!-- cops:end instructions/go -->\n\n" +
+		"<!-- cops:begin instructions/sql -->\nUse sqlc.\n<!-- cops:end instructions/sql -->\n"
+	if string(doc) != want {
+		t.Fatalf("after upsert:\ngot  %q\nwant %q", doc, want)
+	}
+
+	doc = upsertSection(doc, "instructions/go", []byte("Use gofumpt.\n"))
+	if body, ok := FindSection(doc, "instructions/go"); !ok || string(body) != "Use gofumpt.\n" {
+		t.Errorf("FindSection after replace = %q, %v", body, ok)
+	}
+	if _, ok := FindSection(doc, "instructions/rust"); ok {
+		t.Error("FindSection found a section never written")
+	}
+
+	doc = removeSection(doc, "instructions/go")
+	doc = removeSection(doc, "instructions/sql")
+	if string(doc) != "# Project notes\nKeep this.\n" {
+		t.Errorf("after remove: %q", doc)
+	}
+}
+
+func TestSectionBody(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"---\napplyTo: '**'\n---\n\n# Go\nUse gofmt.": "# Go\nUse gofmt.\n",
+		"# Plain\n": "# Plain\n",
+		"":          "",
+	}
+	for content, want := range cases {
+		if got := SectionBody([]byte(content)); string(got) != want {
+			t.Errorf("SectionBody(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
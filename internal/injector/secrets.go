@@ -0,0 +1,41 @@
+package injector
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/cbout22/copilot-sync/internal/audit"
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// checkSecrets scans files, keyed by their upstream path, for credentials
+// before they are written into the project. A likely credential refuses
+// the asset unless o.Secrets only warns; the findings that do not are
+// returned as warnings.
+func (o Options) checkSecrets(files map[string][]byte) ([]string, error) {
+	if o.Secrets == manifest.SecretsAllow {
+		return nil, nil
+	}
+	var warnings []string
+	for _, p := range manifest.SortedKeys(files) {
+		for _, f := range audit.Secrets(files[p]) {
+			msg := fmt.Sprintf("%s:%d: %s %s", p, f.Line, f.Message, f.Match)
+			if f.Severity == audit.High && o.Secrets != manifest.SecretsWarn {
+				return nil, fmt.Errorf("%s — refusing to install; set secrets = %q on the entry if it is a false positive", msg, manifest.SecretsWarn)
+			}
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings, nil
+}
+
+// directoryFiles keys the files of the directory asset at ref by their
+// upstream path.
+func directoryFiles(ref config.AssetRef, contents map[string][]byte) map[string][]byte {
+	files := make(map[string][]byte, len(contents))
+	for rel, content := range contents {
+		files[path.Join(ref.Path, rel)] = content
+	}
+	return files
+}
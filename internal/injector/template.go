@@ -0,0 +1,35 @@
+package injector
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// placeholderPattern matches a {{name}} placeholder, optionally padded with
+// spaces inside the braces.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// substituteVars replaces the {{name}} placeholders of content whose name
+// is in vars. Other placeholders are kept, and so are GitHub Actions
+// expressions (${{ ... }}), so upstream content using the same braces for
+// something else is left untouched.
+func substituteVars(content []byte, vars map[string]string) []byte {
+	if len(vars) == 0 || !bytes.Contains(content, []byte("{{")) {
+		return content
+	}
+	var out []byte
+	last := 0
+	for _, m := range placeholderPattern.FindAllSubmatchIndex(content, -1) {
+		value, ok := vars[string(content[m[2]:m[3]])]
+		if !ok || (m[0] > 0 && content[m[0]-1] == '$') {
+			continue
+		}
+		out = append(out, content[last:m[0]]...)
+		out = append(out, value...)
+		last = m[1]
+	}
+	if out == nil {
+		return content
+	}
+	return append(out, content[last:]...)
+}
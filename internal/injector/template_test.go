@@ -0,0 +1,26 @@
+package injector
+
+import "testing"
+
+func TestSubstituteVars(t *testing.T) {
+	t.Parallel()
+	vars := map[string]string{"project": "billing", "team": "@org/payments"}
+	cases := map[string]struct {
+		content string
+		want    string
+	}{
+		"replaces placeholders":  {"# {{project}} by {{ team }}\n", "# billing by @org/payments\n"},
+		"keeps unknown names":    {"{{project}} {{language}}", "billing {{language}}"},
+		"keeps actions":          {"run: echo ${{ project }} {{project}}", "run: echo ${{ project }} billing"},
+		"keeps other braces":     {"{{ .Values.name }} {{}}", "{{ .Values.name }} {{}}"},
+		"no placeholders at all": {"plain text", "plain text"},
+	}
+	for name, tc := range cases {
+		if got := substituteVars([]byte(tc.content), vars); string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", name, got, tc.want)
+		}
+	}
+	if got := substituteVars([]byte("{{project}}"), nil); string(got) != "{{project}}" {
+		t.Errorf("no vars: got %q", got)
+	}
+}
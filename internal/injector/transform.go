@@ -0,0 +1,52 @@
+package injector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// transformTimeout bounds each run of a transform command.
+const transformTimeout = time.Minute
+
+// transformFileEnvVar passes the upstream path of the piped content to
+// transform commands.
+const transformFileEnvVar = "COPS_FILE"
+
+// runTransform pipes content through the opts.Transform shell command, run
+// from the project root, and returns what it prints. path, the upstream
+// path of the content, is passed as COPS_FILE. Content is returned as-is
+// when no command is set.
+func (inj *Injector) runTransform(opts Options, path string, content []byte) ([]byte, error) {
+	if opts.Transform == "" {
+		return content, nil
+	}
+	ctx, cancel := context.WithTimeout(inj.ctx, transformTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", opts.Transform)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", opts.Transform)
+	}
+	cmd.Dir = inj.rootDir
+	cmd.Env = append(os.Environ(), transformFileEnvVar+"="+path)
+	cmd.Stdin = bytes.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("transform %q on %s: timed out after %s", opts.Transform, path, transformTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transform %q on %s failed: %w — %s", opts.Transform, path, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
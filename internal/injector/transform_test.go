@@ -0,0 +1,32 @@
+package injector
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunTransform(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands run through sh")
+	}
+	inj := New(nil, nil, t.TempDir())
+
+	out, err := inj.runTransform(Options{Transform: `tr a-z A-Z; printf '%s' "$COPS_FILE"`}, "docs/go.md", []byte("use gofmt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "USE GOFMT\ndocs/go.md"; string(out) != want {
+		t.Errorf("runTransform() = %q, want %q", out, want)
+	}
+
+	if out, err := inj.runTransform(Options{}, "docs/go.md", []byte("as is")); err != nil || string(out) != "as is" {
+		t.Errorf("runTransform(no command) = %q, %v", out, err)
+	}
+
+	_, err = inj.runTransform(Options{Transform: "echo broken >&2; exit 3"}, "docs/go.md", nil)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("runTransform(failing) error = %v, want the command's stderr", err)
+	}
+}
@@ -0,0 +1,26 @@
+package injector
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// workflowJobs and setupStepsJob match the jobs of a workflow and the
+// declaration of the job GitHub runs before the Copilot coding agent
+// starts, which it only finds under this name.
+var (
+	workflowJobs  = regexp.MustCompile(`(?m)^jobs:[ \t]*(#.*)?\r?$`)
+	setupStepsJob = regexp.MustCompile(`(?m)^[ \t]+copilot-setup-steps:[ \t]*(#.*)?\r?$`)
+)
+
+// checkContent refuses content, downloaded for an asset of assetType, that
+// the tool reading it would reject: a setup steps workflow that defines no
+// copilot-setup-steps job leaves the coding agent without its environment.
+func checkContent(assetType config.AssetType, path string, content []byte) error {
+	if assetType == config.SetupSteps && !(workflowJobs.Match(content) && setupStepsJob.Match(content)) {
+		return fmt.Errorf("%s: not a Copilot setup steps workflow: it must define a job named copilot-setup-steps", path)
+	}
+	return nil
+}
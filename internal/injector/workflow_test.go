@@ -0,0 +1,36 @@
+package injector
+
+import (
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestCheckContent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		assetType config.AssetType
+		content   string
+		wantErr   bool
+	}{
+		{
+			"setup steps",
+			config.SetupSteps,
+			"on: workflow_dispatch\njobs:\n  copilot-setup-steps:  # required name\n    runs-on: ubuntu-latest\n",
+			false,
+		},
+		{"other job", config.SetupSteps, "jobs:\n  build:\n    runs-on: ubuntu-latest\n", true},
+		{"not a workflow", config.SetupSteps, "# copilot-setup-steps:\n", true},
+		{"other type", config.Prompts, "# Review\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkContent(tt.assetType, "copilot-setup-steps.yml", []byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
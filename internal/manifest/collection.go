@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// CollectionsSection is the manifest section listing collections: upstream
+// collection manifests that expand into the assets they list.
+const CollectionsSection = "collections"
+
+// Collection is a collection manifest, in the YAML format of the
+// github/awesome-copilot collections: assets that belong together, listed
+// by their path in the repository holding the collection.
+//
+//	id: azure-cloud-development
+//	name: Azure & Cloud Development
+//	items:
+//	  - path: instructions/bicep.instructions.md
+//	    kind: instruction
+//	  - path: chatmodes/azure-architect.chatmode.md
+//	    kind: chat-mode
+type Collection struct {
+	ID          string
+	Name        string
+	Description string
+	Items       []CollectionItem
+}
+
+// CollectionItem is one asset of a collection.
+type CollectionItem struct {
+	Path string // slash-separated, from the root of the repository
+	Kind string // instruction, prompt, agent, chat-mode or skill
+}
+
+// collectionKinds maps the kinds of collection items to asset types.
+var collectionKinds = map[string]config.AssetType{
+	"instruction": config.Instructions,
+	"prompt":      config.Prompts,
+	"agent":       config.Agents,
+	"chat-mode":   config.Chatmodes,
+	"chatmode":    config.Chatmodes,
+	"skill":       config.Skills,
+}
+
+// ParseCollection parses a collection manifest.
+func ParseCollection(data []byte) (*Collection, error) {
+	doc, _, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collection{}
+	c.ID, _ = doc["id"].(string)
+	c.Name, _ = doc["name"].(string)
+	c.Description, _ = doc["description"].(string)
+	items, ok := doc["items"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("items must be a list")
+	}
+	for i, v := range items {
+		item, _ := v.(map[string]any)
+		p, _ := item["path"].(string)
+		kind, _ := item["kind"].(string)
+		if p == "" || kind == "" {
+			return nil, fmt.Errorf("items[%d]: expected a path and a kind", i)
+		}
+		c.Items = append(c.Items, CollectionItem{Path: p, Kind: kind})
+	}
+	return c, nil
+}
+
+// Entries returns the entries the collection expands to when it is the
+// collection name of the manifest, fetched from ref: one per item, from the
+// same repository and ref, named after the item's file (its directory for
+// skills) and written under outputRoot.
+func (c *Collection) Entries(name string, ref config.AssetRef, m *Manifest) ([]Entry, error) {
+	if !ref.IsGitHub() && !ref.IsLocal() && !ref.IsPlugin() {
+		return nil, fmt.Errorf("%s: a collection must be a file in a repository", ref.Raw())
+	}
+	var entries []Entry
+	for _, item := range c.Items {
+		t, ok := collectionKinds[item.Kind]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown kind %q", item.Path, item.Kind)
+		}
+		p := path.Clean(strings.TrimPrefix(item.Path, "/"))
+		if !filepath.IsLocal(filepath.FromSlash(p)) {
+			return nil, fmt.Errorf("%s: path must stay inside the repository", item.Path)
+		}
+		if t.IsDirectory() {
+			p = strings.TrimSuffix(p, "/SKILL.md")
+		}
+		itemRef := ref
+		itemRef.Path = p
+		entries = append(entries, Entry{
+			Type:       string(t),
+			Name:       itemName(t, p),
+			Ref:        itemRef.Raw(),
+			OutputRoot: m.OutputRoot,
+			Dir:        m.typeDir(string(t)),
+			Collection: name,
+		})
+	}
+	return entries, nil
+}
+
+// itemName returns the entry name of the collection item of type t at p:
+// its file name without the type's extension, or without .md.
+func itemName(t config.AssetType, p string) string {
+	base := path.Base(p)
+	if ext := t.FileExtension(); ext != "" && strings.HasSuffix(base, ext) {
+		return strings.TrimSuffix(base, ext)
+	}
+	return strings.TrimSuffix(base, ".md")
+}
+
+// LockedMembers returns the entries the collections of m expanded to at
+// the last sync, as the lock file records them.
+func (m *Manifest) LockedMembers(lock *LockFile) []Entry {
+	var members []Entry
+	for _, key := range SortedKeys(lock.Entries) {
+		e := lock.Entries[key]
+		if _, ok := m.Collections[e.Collection]; !ok || e.Collection == "" {
+			continue
+		}
+		members = append(members, Entry{Type: e.Type, Name: e.Name, Ref: e.Ref, OutputRoot: m.OutputRoot, Dir: m.typeDir(e.Type), Collection: e.Collection})
+	}
+	return members
+}
+
+// MergeMembers adds to entries the collection members no entry has the
+// type and name of: an entry of the manifest wins over a collection's, and
+// the first collection over the next. It describes each member left out.
+func MergeMembers(entries, members []Entry) ([]Entry, []string) {
+	seen := make(map[string]string, len(entries))
+	for _, e := range entries {
+		seen[entryKey(e.Type, e.Name)] = ""
+	}
+	var skipped []string
+	for _, e := range members {
+		key := entryKey(e.Type, e.Name)
+		if by, ok := seen[key]; ok {
+			if by == "" {
+				skipped = append(skipped, fmt.Sprintf("%s/%s: %s is in copilot.toml, which wins", CollectionsSection, e.Collection, key))
+			} else {
+				skipped = append(skipped, fmt.Sprintf("%s/%s: %s also comes from %s/%s, which wins", CollectionsSection, e.Collection, key, CollectionsSection, by))
+			}
+			continue
+		}
+		seen[key] = e.Collection
+		entries = append(entries, e)
+	}
+	return entries, skipped
+}
@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestParseCollection(t *testing.T) {
+	t.Parallel()
+	c, err := ParseCollection([]byte(`id: azure
+name: Azure & Cloud
+description: Build on Azure
+items:
+  - path: instructions/bicep.instructions.md
+    kind: instruction
+  - path: skills/deploy/SKILL.md
+    kind: skill
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Collection{
+		ID:          "azure",
+		Name:        "Azure & Cloud",
+		Description: "Build on Azure",
+		Items: []CollectionItem{
+			{Path: "instructions/bicep.instructions.md", Kind: "instruction"},
+			{Path: "skills/deploy/SKILL.md", Kind: "skill"},
+		},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("ParseCollection() = %+v, want %+v", c, want)
+	}
+}
+
+func TestParseCollection_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"no items", "id: azure\n"},
+		{"item without kind", "items:\n  - path: a.md\n"},
+		{"not yaml", "items: [\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseCollection([]byte(tt.in)); err == nil {
+				t.Error("ParseCollection() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCollection_Entries(t *testing.T) {
+	t.Parallel()
+	ref, err := config.ParseRef("myorg/assets/collections/azure.collection.yml@v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Collection{Items: []CollectionItem{
+		{Path: "instructions/bicep.instructions.md", Kind: "instruction"},
+		{Path: "chatmodes/architect.chatmode.md", Kind: "chat-mode"},
+		{Path: "skills/deploy/SKILL.md", Kind: "skill"},
+		{Path: "prompts/review.md", Kind: "prompt"},
+	}}
+	got, err := c.Entries("azure", ref, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Type: "instructions", Name: "bicep", Ref: "myorg/assets/instructions/bicep.instructions.md@v1", Collection: "azure"},
+		{Type: "chatmodes", Name: "architect", Ref: "myorg/assets/chatmodes/architect.chatmode.md@v1", Collection: "azure"},
+		{Type: "skills", Name: "deploy", Ref: "myorg/assets/skills/deploy@v1", Collection: "azure"},
+		{Type: "prompts", Name: "review", Ref: "myorg/assets/prompts/review.md@v1", Collection: "azure"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %+v, want %+v", got, want)
+	}
+
+	for _, item := range []CollectionItem{
+		{Path: "a.md", Kind: "toolset"},
+		{Path: "../escape.md", Kind: "prompt"},
+	} {
+		c := &Collection{Items: []CollectionItem{item}}
+		if _, err := c.Entries("azure", ref, New()); err == nil {
+			t.Errorf("Entries() with %+v succeeded, want an error", item)
+		}
+	}
+}
+
+func TestMergeMembers(t *testing.T) {
+	t.Parallel()
+	entries := []Entry{{Type: "prompts", Name: "review", Ref: "org/repo/review.md@v1"}}
+	members := []Entry{
+		{Type: "prompts", Name: "review", Ref: "org/a/review.md@v1", Collection: "a"},
+		{Type: "agents", Name: "planner", Ref: "org/a/planner.md@v1", Collection: "a"},
+		{Type: "agents", Name: "planner", Ref: "org/b/planner.md@v1", Collection: "b"},
+	}
+	got, skipped := MergeMembers(entries, members)
+	want := []Entry{entries[0], members[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeMembers() = %+v, want %+v", got, want)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("MergeMembers() skipped %q, want 2 members", skipped)
+	}
+}
+
+func TestLockedMembers(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.Collections["azure"] = "myorg/assets/collections/azure.collection.yml@v1"
+	lock := NewLockFile()
+	lock.Set("prompts", "review", "org/repo/review.md@v1", "abc", ".github/prompts/review.prompt.md", nil)
+	lock.Set("agents", "planner", "myorg/assets/agents/planner.agent.md@v1", "abc", ".github/agents/planner.agent.md", nil)
+	lock.RecordCollection("agents", "planner", "azure")
+	lock.Set("agents", "old", "myorg/old/agents/old.agent.md@v1", "abc", ".github/agents/old.agent.md", nil)
+	lock.RecordCollection("agents", "old", "removed")
+
+	want := []Entry{{Type: "agents", Name: "planner", Ref: "myorg/assets/agents/planner.agent.md@v1", Collection: "azure"}}
+	if got := m.LockedMembers(lock); !reflect.DeepEqual(got, want) {
+		t.Errorf("LockedMembers() = %+v, want %+v", got, want)
+	}
+}
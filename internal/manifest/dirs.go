@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// typeDir returns the directory [dirs] writes the entries of assetType to,
+// relative to the project root, or "" if it leaves them under the output
+// root.
+func (m *Manifest) typeDir(assetType string) string {
+	if dirs := m.Dirs[assetType]; len(dirs) > 0 {
+		return dirs[0]
+	}
+	return ""
+}
+
+// mirrors returns where the entry assetType/name is copied to by the
+// directories [dirs] lists after the first, relative to the project root.
+func (m *Manifest) mirrors(assetType, name string) []string {
+	dirs := m.Dirs[assetType]
+	if len(dirs) < 2 {
+		return nil
+	}
+	var paths []string
+	for _, dir := range dirs[1:] {
+		paths = append(paths, config.AssetType(assetType).PathIn(filepath.FromSlash(dir), name))
+	}
+	return paths
+}
+
+// checkDirs validates the [dirs] table.
+func checkDirs(dirs map[string][]string) error {
+	for _, assetType := range SortedKeys(dirs) {
+		if err := checkDir(assetType, dirs[assetType]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDir validates the directories [dirs] lists for assetType.
+func checkDir(assetType string, dirs []string) error {
+	t := config.AssetType(assetType)
+	if !t.IsValid() {
+		return fmt.Errorf("dirs: unknown asset type %q", assetType)
+	}
+	if t.IsSingleton() {
+		return fmt.Errorf("dirs.%s: %s is a single file, use [targets] to write it elsewhere", assetType, assetType)
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("dirs.%s: list at least one directory", assetType)
+	}
+	for i, dir := range dirs {
+		if !projectPath(dir) {
+			return fmt.Errorf("dirs.%s: %q must be a relative path inside the project, out of .git", assetType, dir)
+		}
+		if slices.Contains(dirs[:i], dir) {
+			return fmt.Errorf("dirs.%s: %q is listed twice", assetType, dir)
+		}
+	}
+	return nil
+}
+
+// overlayDirs sets the directories of o in m, replacing those of the same
+// type.
+func (m *Manifest) overlayDirs(o *Manifest) {
+	if len(o.Dirs) == 0 {
+		return
+	}
+	if m.Dirs == nil {
+		m.Dirs = make(map[string][]string, len(o.Dirs))
+	}
+	maps.Copy(m.Dirs, o.Dirs)
+}
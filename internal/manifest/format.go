@@ -0,0 +1,296 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// manifestFiles lists the manifest file names Find looks for, in order of
+// preference.
+var manifestFiles = []string{DefaultManifestFile, "copilot.yaml", "copilot.yml", "copilot.json"}
+
+// format is a manifest file format.
+type format int
+
+const (
+	formatTOML format = iota
+	formatYAML
+	formatJSON
+)
+
+// formatOf returns the format of the manifest at path, chosen by its
+// extension. Anything that is not YAML or JSON is read as TOML.
+func formatOf(path string) format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	default:
+		return formatTOML
+	}
+}
+
+// Find returns the path of the manifest in dir: the first of copilot.toml,
+// copilot.yaml, copilot.yml and copilot.json that exists, or copilot.toml if
+// none does so that new manifests are written as TOML.
+func Find(dir string) string {
+	for _, name := range manifestFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, DefaultManifestFile)
+}
+
+// FindRoot returns the root of the project dir is in: the closest of dir
+// and its parents that holds a manifest or a workspace file, as an
+// absolute path, so commands work from any subdirectory like git does. The
+// search stops at the root of the git repository dir belongs to, so a
+// manifest above it is never picked up. It returns dir if none is found.
+func FindRoot(dir string) string {
+	if root, ok := findUp(dir, append(slices.Clone(manifestFiles), DefaultWorkspaceFile)); ok {
+		return root
+	}
+	return dir
+}
+
+// FindWorkspaceRoot returns the closest of dir and its parents that holds
+// a workspace file, within the same bounds as FindRoot, or dir if none
+// does.
+func FindWorkspaceRoot(dir string) string {
+	if root, ok := findUp(dir, []string{DefaultWorkspaceFile}); ok {
+		return root
+	}
+	return dir
+}
+
+// findUp walks from dir up to the root of its git repository, or of the
+// file system, and returns the first directory holding one of names.
+func findUp(dir string, names []string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, true
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// decode fills m from a manifest document in the given format.
+func (m *Manifest) decode(f format, data []byte) error {
+	switch f {
+	case formatTOML:
+		return m.decodeTOML(data)
+	case formatJSON:
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		return jsonPosition(data, m.decodeJSON(data))
+	}
+	doc, _, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	yamlScalars(doc)
+	// YAML values are strings, lists and mappings, so the document has an
+	// exact JSON form that encoding/json can decode with the struct tags.
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return m.decodeJSON(data)
+}
+
+// decodeJSON fills m from the JSON form of a manifest document.
+func (m *Manifest) decodeJSON(data []byte) error {
+	var raw struct {
+		fileHeader
+		Instructions     map[string]json.RawMessage `json:"instructions"`
+		Agents           map[string]json.RawMessage `json:"agents"`
+		Prompts          map[string]json.RawMessage `json:"prompts"`
+		Chatmodes        map[string]json.RawMessage `json:"chatmodes"`
+		Skills           map[string]json.RawMessage `json:"skills"`
+		RootInstructions map[string]json.RawMessage `json:"root-instructions"`
+		AgentsMD         map[string]json.RawMessage `json:"agents-md"`
+		SetupSteps       map[string]json.RawMessage `json:"setup-steps"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.setHeader(raw.fileHeader)
+	if err := m.registerTypes(raw.Types); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		assetType string
+		raw       map[string]json.RawMessage
+	}{
+		{"instructions", raw.Instructions},
+		{"agents", raw.Agents},
+		{"prompts", raw.Prompts},
+		{"chatmodes", raw.Chatmodes},
+		{"skills", raw.Skills},
+		{"root-instructions", raw.RootInstructions},
+		{"agents-md", raw.AgentsMD},
+		{"setup-steps", raw.SetupSteps},
+	}
+	if custom := config.CustomTypes(); len(custom) > 0 {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		for _, t := range custom {
+			if _, ok := doc[string(t)]; !ok {
+				continue
+			}
+			var section map[string]json.RawMessage
+			if err := json.Unmarshal(doc[string(t)], &section); err != nil {
+				return fmt.Errorf("%s: %w", t, err)
+			}
+			sections = append(sections, struct {
+				assetType string
+				raw       map[string]json.RawMessage
+			}{string(t), section})
+		}
+	}
+	for _, s := range sections {
+		for _, name := range SortedKeys(s.raw) {
+			value := s.raw[name]
+			if bytes.Equal(value, []byte("null")) {
+				return fmt.Errorf("%s/%s: missing ref", s.assetType, name)
+			}
+			decode := func(v any) error { return json.Unmarshal(value, v) }
+			if err := m.decodeEntry(s.assetType, name, decode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPosition adds the line and column of a document-level JSON syntax or
+// type error to err, so hand-edited and generated files are easy to fix.
+// Entry errors are already wrapped with the entry name and kept as is.
+func jsonPosition(data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset - 1 // Offset counts the offending byte
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	pos := offsetPosition(data, int(offset))
+	return fmt.Errorf("line %d, column %d: %w", pos.line, pos.col, err)
+}
+
+// encode writes out to w in the given format, followed by the sections of
+// custom asset types, keyed by type. A YAML manifest keeps the layout and
+// comments of prev, the file it replaces, if any (see mergeYAML).
+func encode(w io.Writer, f format, out manifestFile, custom map[string]map[string]any, prev []byte) error {
+	switch f {
+	case formatTOML:
+		if err := toml.NewEncoder(w).Encode(out); err != nil {
+			return err
+		}
+		if len(custom) == 0 {
+			return nil
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+		return toml.NewEncoder(w).Encode(custom)
+	case formatJSON:
+		data, err := marshalJSON(out, "")
+		if err != nil {
+			return err
+		}
+		if len(custom) > 0 {
+			// Custom sections follow the others, as in the TOML form.
+			var b bytes.Buffer
+			b.Write(bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}"))))
+			sep := ",\n"
+			if bytes.Equal(bytes.TrimSpace(data), []byte("{}")) {
+				sep = "\n"
+			}
+			for _, t := range SortedKeys(custom) {
+				section, err := marshalJSON(custom[t], "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(&b, "%s  %q: %s", sep, t, bytes.TrimSpace(section))
+				sep = ",\n"
+			}
+			b.WriteString("\n}\n")
+			data = b.Bytes()
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	keys := []string{"extends", "include", "output_root", "readonly", "sources", "default_ref", "template", "targets", "dirs", "limits", "hooks", "settings", "types", "collections", "instructions", "agents", "prompts", "chatmodes", "skills", "root-instructions", "agents-md", "setup-steps"}
+	for _, t := range SortedKeys(custom) {
+		data, err := json.Marshal(custom[t])
+		if err != nil {
+			return err
+		}
+		var section map[string]any
+		if err := json.Unmarshal(data, &section); err != nil {
+			return err
+		}
+		doc[t] = section
+		keys = append(keys, t)
+	}
+	if prev != nil {
+		_, err = w.Write(mergeYAML(prev, doc, keys))
+		return err
+	}
+	_, err = w.Write(encodeYAML(doc, keys))
+	return err
+}
+
+// marshalJSON returns the indented JSON form of v, each line after the
+// first starting with prefix, with HTML characters left as they are.
+func marshalJSON(v any, prefix string) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(prefix, "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,310 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"none", nil, "copilot.toml"},
+		{"yaml", []string{"copilot.yaml"}, "copilot.yaml"},
+		{"yml", []string{"copilot.yml"}, "copilot.yml"},
+		{"json", []string{"copilot.json"}, "copilot.json"},
+		{"toml preferred", []string{"copilot.yaml", "copilot.toml"}, "copilot.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := Find(dir); got != filepath.Join(dir, tt.want) {
+				t.Errorf("Find() = %q, want %q", got, filepath.Join(dir, tt.want))
+			}
+		})
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	t.Parallel()
+
+	// repo/.git
+	// repo/copilot.toml
+	// repo/docs/guide/
+	// repo/services/cops-workspace.toml
+	// repo/services/api/copilot.yaml
+	// repo/services/api/src/
+	// repo/services/web/
+	// outside/copilot.toml
+	// outside/repo/.git
+	// outside/repo/src/
+	base := t.TempDir()
+	for _, dir := range []string{"repo/.git", "repo/docs/guide", "repo/services/api/src", "repo/services/web", "outside/repo/.git", "outside/repo/src"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"repo/copilot.toml", "repo/services/cops-workspace.toml", "repo/services/api/copilot.yaml", "outside/copilot.toml"} {
+		if err := os.WriteFile(filepath.Join(base, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir, root, workspace string
+	}{
+		{"repo", "repo", "repo"},
+		{"repo/docs/guide", "repo", "repo/docs/guide"},
+		{"repo/services/api/src", "repo/services/api", "repo/services"},
+		{"repo/services/web", "repo/services", "repo/services"},
+		// The search ends at the repository root: outside/copilot.toml
+		// belongs to another project.
+		{"outside/repo/src", "outside/repo/src", "outside/repo/src"},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(base, tt.dir)
+		if got, want := FindRoot(dir), filepath.Join(base, tt.root); got != want {
+			t.Errorf("FindRoot(%s) = %q, want %q", tt.dir, got, want)
+		}
+		if got, want := FindWorkspaceRoot(dir), filepath.Join(base, tt.workspace); got != want {
+			t.Errorf("FindWorkspaceRoot(%s) = %q, want %q", tt.dir, got, want)
+		}
+	}
+}
+
+func TestLoadSave_YAML(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"copilot.yaml": `# Team assets
+sources:
+  awesome: github/awesome-copilot@v2
+
+agents:
+  planner: awesome:agents/planner.md
+  reviewer:
+    ref: org/repo/reviewer.md@main
+    allow_branch_until: "2099-12-31"
+    groups: [ci]
+
+skills:
+  testing: org/repo/skills/testing@v1
+`,
+	})
+	path := filepath.Join(dir, "copilot.yaml")
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Agents["planner"] != "awesome:agents/planner.md" || m.Skills["testing"] != "org/repo/skills/testing@v1" {
+		t.Errorf("entries = %v / %v", m.Agents, m.Skills)
+	}
+	if opts := m.Options("agents", "reviewer"); opts.AllowBranchUntil != "2099-12-31" || !opts.InGroup("ci") {
+		t.Errorf("reviewer options = %+v", opts)
+	}
+
+	// Save keeps the YAML format, and the result loads back identically.
+	if err := m.Set("prompts", "review", "org/repo/review.md@v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data := string(readBytes(t, path))
+	if !strings.Contains(data, "prompts:\n  review: org/repo/review.md@v1\n") || strings.Contains(data, "[prompts]") {
+		t.Errorf("saved manifest is not YAML:\n%s", data)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reloading saved manifest: %v\n%s", err, data)
+	}
+	if got, want := reloaded.AllEntries(), m.AllEntries(); len(got) != len(want) {
+		t.Fatalf("reloaded %d entries, want %d", len(got), len(want))
+	}
+	for i, e := range reloaded.AllEntries() {
+		if want := m.AllEntries()[i]; e.Ref != want.Ref || e.Options.AllowBranchUntil != want.Options.AllowBranchUntil {
+			t.Errorf("entry %d = %+v, want %+v", i, e, want)
+		}
+	}
+}
+
+func TestSave_YAMLKeepsLayout(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"copilot.yaml": `# Team assets, reviewed by @platform.
+
+# Shared sources
+sources:
+  awesome: github/awesome-copilot@v2
+
+agents:
+  # Plans the work first.
+  reviewer: org/repo/reviewer.md@main
+  # Retired next quarter.
+  legacy: org/repo/legacy.md@v1
+  planner: awesome:agents/planner.md # pinned by the source
+
+skills:
+  testing: org/repo/skills/testing@v1
+`,
+	})
+	path := filepath.Join(dir, "copilot.yaml")
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Remove("agents", "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	for _, set := range [][3]string{
+		{"agents", "reviewer", "org/repo/reviewer.md@v2"},
+		{"agents", "coder", "org/repo/coder.md@v1"},
+		{"prompts", "review", "org/repo/review.md@v1"},
+	} {
+		if err := m.Set(set[0], set[1], set[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Team assets, reviewed by @platform.
+
+# Shared sources
+sources:
+  awesome: github/awesome-copilot@v2
+
+agents:
+  # Plans the work first.
+  reviewer: org/repo/reviewer.md@v2
+  planner: awesome:agents/planner.md # pinned by the source
+  coder: org/repo/coder.md@v1
+
+skills:
+  testing: org/repo/skills/testing@v1
+
+prompts:
+  review: org/repo/review.md@v1
+`
+	if data := string(readBytes(t, path)); data != want {
+		t.Errorf("saved manifest:\n%s\nwant:\n%s", data, want)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reloaded.AllEntries()); got != 5 {
+		t.Errorf("reloaded %d entries, want 5", got)
+	}
+}
+
+func TestLoad_YAMLErrors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"syntax":      "agents:\n  planner: [unterminated\n",
+		"missing ref": "agents:\n  planner:\n",
+		"bad type":    "sources: [a, b]\n",
+		"bad option":  "agents:\n  planner:\n    ref: org/repo/p.md@v1\n    target: ../outside.md\n",
+	}
+	for name, content := range cases {
+		dir := writeTree(t, map[string]string{"copilot.yml": content})
+		if _, err := Load(filepath.Join(dir, "copilot.yml")); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadSave_JSON(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"copilot.json": `{
+  "default_ref": {"org/repo": "v2"},
+  "agents": {
+    "planner": "org/repo/planner.md",
+    "reviewer": {"ref": "org/repo/reviewer.md@main", "groups": ["ci"], "target": "docs/reviewer.md"}
+  }
+}`,
+	})
+	path := filepath.Join(dir, "copilot.json")
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := m.AllEntries()
+	if len(entries) != 2 || entries[0].Ref != "org/repo/planner.md@v2" {
+		t.Fatalf("AllEntries() = %+v", entries)
+	}
+	if opts := m.Options("agents", "reviewer"); opts.Target != "docs/reviewer.md" || !opts.InGroup("ci") {
+		t.Errorf("reviewer options = %+v", opts)
+	}
+
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "default_ref": {
+    "org/repo": "v2"
+  },
+  "agents": {
+    "planner": "org/repo/planner.md",
+    "reviewer": {
+      "ref": "org/repo/reviewer.md@main",
+      "target": "docs/reviewer.md",
+      "groups": [
+        "ci"
+      ]
+    }
+  }
+}
+`
+	if got := string(readBytes(t, path)); got != want {
+		t.Errorf("saved copilot.json =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLoad_JSONErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax", "{\n  \"agents\": {,}\n}", "line 2, column 14"},
+		{"type", "{\n  \"include\": \"base.json\"\n}", "line 2, column 25"},
+		{"entry", `{"agents": {"planner": 42}}`, "agents/planner"},
+		{"null entry", `{"agents": {"planner": null}}`, "agents/planner: missing ref"},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"copilot.json": tt.content})
+		_, err := Load(filepath.Join(dir, "copilot.json"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoad_EmptyJSON(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{"copilot.json": "\n"})
+	m, err := Load(filepath.Join(dir, "copilot.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.AllEntries()); n != 0 {
+		t.Errorf("empty copilot.json has %d entries", n)
+	}
+}
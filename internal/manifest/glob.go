@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether name, a slash-separated relative path, matches
+// pattern. A pattern without a slash matches the base name of files at any
+// depth ("*.md"). Otherwise it matches the whole path, segment by segment
+// with path.Match, where a "**" segment matches any number of directories
+// ("templates/**", "docs/**/*.md").
+func MatchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validateGlob checks that pattern is a well-formed relative glob.
+func validateGlob(pattern string) error {
+	if pattern == "" || strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("invalid pattern %q: must be a non-empty relative path", pattern)
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
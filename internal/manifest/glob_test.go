@@ -0,0 +1,29 @@
+package manifest
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.md", "SKILL.md", true},
+		{"*.md", "docs/deep/guide.md", true},
+		{"*.md", "run.sh", false},
+		{"templates/**", "templates/a.yaml", true},
+		{"templates/**", "templates/x/y/z.txt", true},
+		{"templates/**", "other/templates/a.yaml", false},
+		{"docs/**/*.md", "docs/a.md", true},
+		{"docs/**/*.md", "docs/x/y/a.md", true},
+		{"docs/**/*.md", "docs/x/a.txt", false},
+		{"**/test_*.py", "test_a.py", true},
+		{"scripts/*.sh", "scripts/run.sh", true},
+		{"scripts/*.sh", "scripts/sub/run.sh", false},
+	}
+	for _, tc := range cases {
+		if got := MatchGlob(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// GlobalManifestEnvVar names the environment variable overriding the path
+// of the user-level manifest.
+const GlobalManifestEnvVar = "COPS_GLOBAL_MANIFEST"
+
+// GlobalManifestPath returns the user-level manifest whose entries are
+// synced into every project, e.g. ~/.config/cops/copilot.toml (or
+// copilot.yaml) on Linux. It returns "" if no user configuration directory
+// is available.
+func GlobalManifestPath() string {
+	if path := os.Getenv(GlobalManifestEnvVar); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return Find(filepath.Join(dir, "cops"))
+}
+
+// Underlay merges the entries and template variables of base beneath m:
+// those m (or a manifest it includes) already defines are kept, the others
+// are added. Like included entries, they are never written by Save.
+func (m *Manifest) Underlay(base *Manifest) {
+	defined := make(map[string]bool)
+	for _, e := range m.AllEntries() {
+		defined[entryKey(e.Type, e.Name)] = true
+	}
+	if m.inherited == nil {
+		m.inherited = New()
+	}
+	for _, e := range base.AllEntries() {
+		if defined[entryKey(e.Type, e.Name)] {
+			continue
+		}
+		_ = m.inherited.Set(e.Type, e.Name, e.Ref)
+		m.inherited.SetOptions(e.Type, e.Name, e.Options)
+	}
+	m.underlayVars(base)
+}
+
+// LoadOptions selects what LoadWith merges into a project manifest.
+type LoadOptions struct {
+	// Env selects the copilot.<env>.toml overlay; empty applies none.
+	Env string
+
+	// GlobalPath is the user-level manifest merged beneath the project's.
+	// Empty, or a path that does not exist, adds nothing.
+	GlobalPath string
+
+	// Fetch downloads the template named by an extends directive. It may be
+	// nil if the manifest extends nothing.
+	Fetch Fetcher
+}
+
+// LoadWith loads the project manifest at path with its env overlay (see
+// LoadEnv), then merges beneath it the template it extends and the
+// user-level manifest, in that order of precedence.
+func LoadWith(path string, opts LoadOptions) (*Manifest, error) {
+	m, err := LoadEnv(path, opts.Env)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ApplyExtends(opts.Fetch); err != nil {
+		return nil, err
+	}
+	if opts.GlobalPath == "" {
+		return m, nil
+	}
+	if _, err := os.Stat(opts.GlobalPath); errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	global, err := Load(opts.GlobalPath)
+	if err != nil {
+		return nil, fmt.Errorf("global manifest %s: %w", opts.GlobalPath, err)
+	}
+	m.Underlay(global)
+	return m, nil
+}
@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadWith_Global(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"home/copilot.toml": `[agents]
+planner = "me/dotfiles/planner.md@v1"
+
+[prompts]
+scratch = { ref = "me/dotfiles/scratch.md@v1", groups = ["personal"] }
+`,
+		"proj/copilot.toml": `[agents]
+planner = "org/team/planner.md@v2"
+`,
+	})
+	path := filepath.Join(dir, "proj", "copilot.toml")
+	globalPath := filepath.Join(dir, "home", "copilot.toml")
+
+	m, err := LoadWith(path, LoadOptions{GlobalPath: globalPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, e := range m.AllEntries() {
+		got[e.Type+"/"+e.Name] = e.Ref
+	}
+	want := map[string]string{
+		"agents/planner":  "org/team/planner.md@v2", // project wins
+		"prompts/scratch": "me/dotfiles/scratch.md@v1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("AllEntries() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if !m.Options("prompts", "scratch").InGroup("personal") {
+		t.Errorf("global options = %+v", m.Options("prompts", "scratch"))
+	}
+
+	// Global entries are never written to the project manifest.
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if data := string(readBytes(t, path)); strings.Contains(data, "scratch") {
+		t.Errorf("saved manifest contains global entries:\n%s", data)
+	}
+}
+
+func TestLoadWith_GlobalMissing(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"copilot.toml": "[agents]\nplanner = \"org/team/planner.md@v2\"\n",
+		"broken.toml":  "[agents\n",
+	})
+	path := filepath.Join(dir, "copilot.toml")
+
+	for _, globalPath := range []string{"", filepath.Join(dir, "nope.toml")} {
+		m, err := LoadWith(path, LoadOptions{GlobalPath: globalPath})
+		if err != nil {
+			t.Fatalf("LoadWith(%q): %v", globalPath, err)
+		}
+		if n := len(m.AllEntries()); n != 1 {
+			t.Errorf("LoadWith(%q): %d entries, want 1", globalPath, n)
+		}
+	}
+	if _, err := LoadWith(path, LoadOptions{GlobalPath: filepath.Join(dir, "broken.toml")}); err == nil {
+		t.Error("expected error for a malformed global manifest")
+	}
+}
@@ -0,0 +1,45 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hooks holds the [hooks] section: shell commands run from the project
+// root around a sync.
+type Hooks struct {
+	// PostSync runs, in order, after a sync that succeeded, e.g. to format
+	// the written files or regenerate an index of them.
+	PostSync []string `toml:"post_sync,omitempty" json:"post_sync,omitempty"`
+}
+
+// IsZero reports whether no hook is set.
+func (h Hooks) IsZero() bool {
+	return len(h.PostSync) == 0
+}
+
+// checkHooks validates the [hooks] section.
+func checkHooks(h Hooks) error {
+	for _, command := range h.PostSync {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("hooks: post_sync commands must not be empty")
+		}
+	}
+	return nil
+}
+
+// hooksSection returns the on-disk [hooks] section, or nil if no hook is
+// set.
+func (m *Manifest) hooksSection() *Hooks {
+	if m.Hooks.IsZero() {
+		return nil
+	}
+	return &m.Hooks
+}
+
+// overlayHooks replaces the hooks of m with those o sets.
+func (m *Manifest) overlayHooks(o *Manifest) {
+	if len(o.Hooks.PostSync) > 0 {
+		m.Hooks.PostSync = o.Hooks.PostSync
+	}
+}
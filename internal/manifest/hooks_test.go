@@ -0,0 +1,35 @@
+package manifest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `[hooks]
+post_sync = ["npx prettier --write .github", "make index"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"npx prettier --write .github", "make index"}; !slices.Equal(m.Hooks.PostSync, want) {
+		t.Errorf("PostSync = %q, want %q", m.Hooks.PostSync, want)
+	}
+
+	path := tempPath(t, "copilot.yaml")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m2.Hooks.PostSync, m.Hooks.PostSync) {
+		t.Errorf("hooks after roundtrip = %q", m2.Hooks.PostSync)
+	}
+
+	if _, err := Load(writeTempFile(t, "copilot.toml", "[hooks]\npost_sync = [\" \"]\n")); err == nil {
+		t.Error("empty command: expected error, got nil")
+	}
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+)
+
+// loadIncludes loads the manifests included by the manifest at path and
+// merges them in the order listed. Nested includes are resolved relative to
+// the manifest that names them. Two included manifests defining the same
+// entry differently is a conflict; the including manifest itself may
+// override any included entry.
+func loadIncludes(path string, includes []string, stack []string) (*Manifest, error) {
+	self, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", path, err)
+	}
+	stack = append(stack, self)
+
+	merged := New()
+	origin := make(map[string]string) // entry key → manifest that defined it
+	for _, inc := range includes {
+		incPath := inc
+		if !filepath.IsAbs(incPath) {
+			incPath = filepath.Join(filepath.Dir(path), inc)
+		}
+		abs, err := filepath.Abs(incPath)
+		if err != nil {
+			return nil, fmt.Errorf("resolving include %q: %w", inc, err)
+		}
+		if slices.Contains(stack, abs) {
+			return nil, fmt.Errorf("include cycle: %s includes %s", path, inc)
+		}
+		if _, err := os.Stat(incPath); err != nil {
+			return nil, fmt.Errorf("%s: include %q: %w", path, inc, err)
+		}
+
+		child, err := load(incPath, stack)
+		if err == nil {
+			err = child.checkSources()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %q: %w", path, inc, err)
+		}
+		for _, e := range child.AllEntries() {
+			key := entryKey(e.Type, e.Name)
+			if other := merged.singletonEntry(e.Type); other != "" && other != e.Name {
+				return nil, fmt.Errorf("%s: %s is defined differently in %s and %s", path, e.Type, origin[entryKey(e.Type, other)], incPath)
+			}
+			if prev, ok := origin[key]; ok {
+				existing, _ := merged.Section(e.Type)
+				if existing[e.Name] != e.Ref || !reflect.DeepEqual(merged.Options(e.Type, e.Name), e.Options) {
+					return nil, fmt.Errorf("%s: %s is defined differently in %s and %s", path, key, prev, incPath)
+				}
+				continue
+			}
+			origin[key] = incPath
+			_ = merged.Set(e.Type, e.Name, e.Ref)
+			merged.SetOptions(e.Type, e.Name, e.Options)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,135 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files (slash-separated paths) under a new temp dir and
+// returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad_Include(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"shared/copilot.toml": `include = ["nested.toml"]
+
+[agents]
+reviewer = { ref = "org/base/reviewer.md@v1", groups = ["ci"] }
+`,
+		"shared/nested.toml": `[prompts]
+tests = "org/base/tests.md@v1"
+`,
+		"proj/team-base.toml": `[agents]
+reviewer = { ref = "org/base/reviewer.md@v1", groups = ["ci"] }
+planner  = "org/base/planner.md@v1"
+`,
+		"proj/copilot.toml": `include = ["team-base.toml", "../shared/copilot.toml"]
+
+[agents]
+planner = "org/local/planner.md@v2"
+`,
+	})
+	path := filepath.Join(dir, "proj", "copilot.toml")
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, e := range m.AllEntries() {
+		got[e.Type+"/"+e.Name] = e.Ref
+	}
+	want := map[string]string{
+		"agents/planner":  "org/local/planner.md@v2", // local override wins
+		"agents/reviewer": "org/base/reviewer.md@v1", // identical in both includes
+		"prompts/tests":   "org/base/tests.md@v1",    // nested include
+	}
+	if len(got) != len(want) {
+		t.Errorf("AllEntries() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if g := m.Options("agents", "reviewer").Groups; len(g) != 1 || g[0] != "ci" {
+		t.Errorf("inherited options = %+v", m.Options("agents", "reviewer"))
+	}
+
+	// Saving keeps the include directive and only the local entries.
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data := string(readBytes(t, path))
+	if !strings.HasPrefix(data, "include = ") || strings.Contains(data, "reviewer") {
+		t.Errorf("saved manifest inlined included entries:\n%s", data)
+	}
+}
+
+func TestLoad_IncludeSingleton(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"base.toml": "[root-instructions]\nbase = \"org/base/copilot-instructions.md@v1\"\n",
+		"copilot.toml": `include = ["base.toml"]
+
+[root-instructions]
+team = "org/team/copilot-instructions.md@v2"
+`,
+	})
+	m, err := Load(filepath.Join(dir, "copilot.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range m.AllEntries() {
+		got = append(got, e.Type+"/"+e.Name)
+	}
+	if len(got) != 1 || got[0] != "root-instructions/team" {
+		t.Errorf("AllEntries() = %v, want the local root-instructions/team only", got)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]map[string]string{
+		"singleton conflict": {
+			"a.toml":       "[root-instructions]\nx = \"org/a/x.md@v1\"\n",
+			"b.toml":       "[root-instructions]\ny = \"org/b/y.md@v1\"\n",
+			"copilot.toml": "include = [\"a.toml\", \"b.toml\"]\n",
+		},
+		"conflict": {
+			"a.toml":       "[agents]\nx = \"org/a/x.md@v1\"\n",
+			"b.toml":       "[agents]\nx = \"org/b/x.md@v1\"\n",
+			"copilot.toml": "include = [\"a.toml\", \"b.toml\"]\n",
+		},
+		"cycle": {
+			"a.toml":       "include = [\"copilot.toml\"]\n",
+			"copilot.toml": "include = [\"a.toml\"]\n",
+		},
+		"missing": {
+			"copilot.toml": "include = [\"nope.toml\"]\n",
+		},
+	}
+	for name, files := range cases {
+		dir := writeTree(t, files)
+		if _, err := Load(filepath.Join(dir, "copilot.toml")); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
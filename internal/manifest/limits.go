@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default download size limits, used when [limits] does not set them.
+const (
+	DefaultMaxFileSize  int64 = 5 << 20
+	DefaultMaxSkillSize int64 = 20 << 20
+)
+
+// Values of [limits] binaries: what happens to binary files found in a
+// skill download.
+const (
+	BinariesAllow      = "allow"      // write them like any file (the default)
+	BinariesSkip       = "skip"       // leave them out of the skill
+	BinariesQuarantine = "quarantine" // write them to a review folder instead
+	BinariesFail       = "fail"       // refuse to install the skill
+)
+
+// Limits holds the [limits] section: sizes written as a number of bytes
+// or with a KB, MB or GB suffix (powers of 1024), e.g. "5MB".
+type Limits struct {
+	// MaxFileSize caps every downloaded file.
+	MaxFileSize string `toml:"max_file_size,omitempty" json:"max_file_size,omitempty"`
+
+	// MaxSkillSize caps the files of a skill directory together.
+	MaxSkillSize string `toml:"max_skill_size,omitempty" json:"max_skill_size,omitempty"`
+
+	// Binaries says what to do with binary files found in skills: one of
+	// BinariesAllow, BinariesSkip, BinariesQuarantine or BinariesFail.
+	// Empty allows them.
+	Binaries string `toml:"binaries,omitempty" json:"binaries,omitempty"`
+}
+
+// sizeUnits lists the accepted size suffixes, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "5MB", "512 KB" or "1048576".
+func ParseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text, multiplier = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid size %q: use a positive number of bytes, KB, MB or GB", s)
+	}
+	return n * multiplier, nil
+}
+
+// FormatSize formats n bytes for messages, e.g. "5 MB" or "1.5 KB".
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits[:3] {
+		if n >= u.bytes {
+			value := strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64)
+			return strings.TrimSuffix(value, ".0") + " " + u.suffix
+		}
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// checkLimits validates the [limits] section.
+func checkLimits(l Limits) error {
+	for _, s := range []string{l.MaxFileSize, l.MaxSkillSize} {
+		if s == "" {
+			continue
+		}
+		if _, err := ParseSize(s); err != nil {
+			return fmt.Errorf("limits: %w", err)
+		}
+	}
+	switch l.Binaries {
+	case "", BinariesAllow, BinariesSkip, BinariesQuarantine, BinariesFail:
+		return nil
+	default:
+		return fmt.Errorf("limits: invalid binaries %q: must be %q, %q, %q or %q", l.Binaries, BinariesAllow, BinariesSkip, BinariesQuarantine, BinariesFail)
+	}
+}
+
+// limitsSection returns the on-disk [limits] section, or nil if no limit
+// is set.
+func (m *Manifest) limitsSection() *Limits {
+	if m.Limits == (Limits{}) {
+		return nil
+	}
+	return &m.Limits
+}
+
+// SizeLimits returns the maximum size of a downloaded file and of a
+// skill directory, in bytes: those of [limits], or the defaults.
+func (m *Manifest) SizeLimits() (file, skill int64) {
+	return sizeOr(m.Limits.MaxFileSize, DefaultMaxFileSize), sizeOr(m.Limits.MaxSkillSize, DefaultMaxSkillSize)
+}
+
+// sizeOr parses s, which Load has validated, or returns def if it is empty.
+func sizeOr(s string, def int64) int64 {
+	if n, err := ParseSize(s); err == nil {
+		return n
+	}
+	return def
+}
+
+// overlayLimits sets the limits o defines in m, keeping the others.
+func (m *Manifest) overlayLimits(o *Manifest) {
+	if o.Limits.MaxFileSize != "" {
+		m.Limits.MaxFileSize = o.Limits.MaxFileSize
+	}
+	if o.Limits.MaxSkillSize != "" {
+		m.Limits.MaxSkillSize = o.Limits.MaxSkillSize
+	}
+	if o.Limits.Binaries != "" {
+		m.Limits.Binaries = o.Limits.Binaries
+	}
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	cases := map[string]int64{
+		"1048576": 1 << 20,
+		"512KB":   512 << 10,
+		"5 MB":    5 << 20,
+		"1gb":     1 << 30,
+		"100B":    100,
+	}
+	for in, want := range cases {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1MB", "0", "5TB", "1.5MB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q): expected error, got nil", in)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+	cases := map[int64]string{
+		5 << 20:   "5 MB",
+		1536:      "1.5 KB",
+		300:       "300 bytes",
+		500 << 20: "500 MB",
+	}
+	for in, want := range cases {
+		if got := FormatSize(in); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", "[limits]\nmax_file_size = \"1MB\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file, skill := m.SizeLimits(); file != 1<<20 || skill != DefaultMaxSkillSize {
+		t.Errorf("SizeLimits() = %d, %d", file, skill)
+	}
+
+	path := tempPath(t, "copilot.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2.Limits != m.Limits {
+		t.Errorf("limits after roundtrip = %+v", m2.Limits)
+	}
+
+	if _, err := Load(writeTempFile(t, "copilot.toml", "[limits]\nmax_skill_size = \"lots\"\n")); err == nil {
+		t.Error("invalid size: expected error, got nil")
+	}
+}
+
+func TestLimits_Binaries(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", "[limits]\nbinaries = \"quarantine\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Limits.Binaries != BinariesQuarantine {
+		t.Errorf("Limits.Binaries = %q, want %q", m.Limits.Binaries, BinariesQuarantine)
+	}
+	if _, err := Load(writeTempFile(t, "copilot.toml", "[limits]\nbinaries = \"delete\"\n")); err == nil {
+		t.Error("invalid binaries: expected error, got nil")
+	}
+}
@@ -2,20 +2,41 @@ package manifest
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
 )
 
 const DefaultLockFile = ".cops.lock"
 
+// LockVersion is the lock file format this version of cops writes. Version
+// 2 adds per-file digests for directory assets, the size and mode of what
+// was written, and where each entry comes from. Version 1 files are read
+// as-is: the source metadata is derived from their refs, the entries are
+// checked without the rest, and they are written back as version 2.
+const LockVersion = 2
+
 // LockFile is the shadow manifest that tracks which files cops "owns".
 // It stores the resolved state of each asset so that `cops sync` and
 // `cops check` can detect drift.
 type LockFile struct {
 	// Version of the lock file format.
 	Version int `json:"version"`
+	// Integrity is a "sha256:<hex>" hash of the entries, set by Save, that
+	// reveals hand edits and partial writes. Files without one (version 1)
+	// are trusted.
+	Integrity string `json:"integrity,omitempty"`
 	// Entries keyed by "<type>/<name>".
 	Entries map[string]LockEntry `json:"entries"`
 }
@@ -29,12 +50,78 @@ type LockEntry struct {
 	TargetPath  string `json:"target_path"`  // local file/dir path relative to project root
 	Checksum    string `json:"checksum"`     // SHA-256 of the downloaded content
 	SyncedAt    string `json:"synced_at"`    // RFC 3339 timestamp of last sync
+
+	// Size is the number of bytes written: the file's size, or the total
+	// of a directory's files. Zero if unknown (version 1 entries).
+	Size int64 `json:"size,omitempty"`
+
+	// Mode is the permission bits of a single file as written, in octal
+	// (e.g. "0644"). Directory assets record a mode per file instead.
+	Mode string `json:"mode,omitempty"`
+
+	// Source is the kind of source Ref is fetched from (see
+	// config.AssetRef.SourceKind), with the Host and API base URL it was
+	// resolved through when they are fixed by the ref.
+	Source string `json:"source,omitempty"`
+	Host   string `json:"host,omitempty"`
+	APIURL string `json:"api_url,omitempty"`
+
+	// ETag and LastModified are the HTTP validators a single file was
+	// served with, sent back on the next sync so an unchanged file is not
+	// downloaded again. Empty when the source returned none.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// Signature says who signed the asset, as cosign verification accepted
+	// it (e.g. "cosign key sha256:<fingerprint>"). Empty when the entry
+	// requires no signature.
+	Signature string `json:"signature,omitempty"`
+
+	// License is the SPDX identifier of the license of the repository the
+	// asset comes from (e.g. "MIT"), "NONE" if it has none, or
+	// "NOASSERTION" if it was not recognised. Empty when the source cannot
+	// tell.
+	License string `json:"license,omitempty"`
+
+	// Files holds the digest of every file of a directory asset, keyed by
+	// its slash-separated path inside the directory, so drift can be traced
+	// to a single file. Empty for single files and version 1 entries.
+	Files map[string]FileDigest `json:"files,omitempty"`
+
+	// Copies and Sections are the extra outputs the asset was written to
+	// (see Manifest.Outputs), relative to the project root: files or
+	// directories holding a copy, and shared files holding it as a managed
+	// section. They are removed with the asset.
+	Copies   []string `json:"copies,omitempty"`
+	Sections []string `json:"sections,omitempty"`
+
+	// Collection is the [collections] entry the asset was synced as a
+	// member of. Empty for the entries of the manifest's own sections.
+	Collection string `json:"collection,omitempty"`
+}
+
+// FileDigest identifies the content of one file of a directory asset.
+type FileDigest struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode,omitempty"` // permission bits in octal, e.g. "0755"
+}
+
+// FileChange describes how a file of a directory asset differs from the
+// lock file.
+type FileChange struct {
+	Path   string // slash-separated path inside the directory
+	Change string // "modified", "added", "removed" or "mode 0644 → 0755"
 }
 
+// ErrCorruptLock is returned, wrapped, by LoadLock for lock files that
+// cannot be parsed or whose entries do not match their integrity hash.
+var ErrCorruptLock = errors.New("lock file is corrupted")
+
 // NewLockFile returns an initialised empty lock file.
 func NewLockFile() *LockFile {
 	return &LockFile{
-		Version: 1,
+		Version: LockVersion,
 		Entries: make(map[string]LockEntry),
 	}
 }
@@ -53,7 +140,21 @@ func LoadLock(path string) (*LockFile, error) {
 	}
 
 	if err := json.Unmarshal(data, lf); err != nil {
-		return nil, fmt.Errorf("parsing lock file: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrCorruptLock, err)
+	}
+	if lf.Integrity != "" {
+		if got, err := lf.integrity(); err != nil || got != lf.Integrity {
+			return nil, fmt.Errorf("%w: entries do not match the integrity hash", ErrCorruptLock)
+		}
+	}
+	if lf.Version > LockVersion {
+		return nil, fmt.Errorf("lock file version %d is newer than this cops supports (%d); upgrade cops", lf.Version, LockVersion)
+	}
+	lf.Version = LockVersion
+	for key, e := range lf.Entries {
+		if e.Source == "" {
+			lf.Entries[key] = e.withSource()
+		}
 	}
 
 	if lf.Entries == nil {
@@ -63,8 +164,14 @@ func LoadLock(path string) (*LockFile, error) {
 	return lf, nil
 }
 
-// Save writes the lock file to the given path.
+// Save writes the lock file to the given path, with a fresh integrity
+// hash.
 func (lf *LockFile) Save(path string) error {
+	integrity, err := lf.integrity()
+	if err != nil {
+		return fmt.Errorf("encoding lock file: %w", err)
+	}
+	lf.Integrity = integrity
 	data, err := json.MarshalIndent(lf, "", "  ")
 	if err != nil {
 		return fmt.Errorf("encoding lock file: %w", err)
@@ -77,6 +184,16 @@ func (lf *LockFile) Save(path string) error {
 	return nil
 }
 
+// integrity hashes the compact JSON form of the entries, whose map keys
+// encoding/json sorts, so the hash does not depend on formatting.
+func (lf *LockFile) integrity() (string, error) {
+	data, err := json.Marshal(lf.Entries)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + Checksum(data), nil
+}
+
 // entryKey builds the map key for a lock entry.
 func entryKey(assetType, name string) string {
 	return assetType + "/" + name
@@ -93,7 +210,155 @@ func (lf *LockFile) Set(assetType, name, ref, resolvedSHA, targetPath string, co
 		TargetPath:  targetPath,
 		Checksum:    Checksum(content),
 		SyncedAt:    time.Now().UTC().Format(time.RFC3339),
+		Size:        int64(len(content)),
+	}.withSource()
+}
+
+// withSource returns e with its source metadata derived from its ref. Refs
+// that do not parse are left without.
+func (e LockEntry) withSource() LockEntry {
+	ref, err := config.ParseRef(e.Ref)
+	if err != nil {
+		return e
 	}
+	e.Source, e.Host, e.APIURL = ref.SourceKind(), ref.Host(), ref.APIURL()
+	return e
+}
+
+// RecordModes stats the asset written at absTarget and records the
+// permission bits of the file, or of every file a directory entry lists.
+func (lf *LockFile) RecordModes(assetType, name, absTarget string) error {
+	key := entryKey(assetType, name)
+	e, ok := lf.Entries[key]
+	if !ok {
+		return fmt.Errorf("%s: not in lock file", key)
+	}
+	if len(e.Files) == 0 {
+		info, err := os.Stat(absTarget)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			e.Mode = FormatMode(info.Mode())
+		}
+	}
+	for rel, digest := range e.Files {
+		info, err := os.Stat(filepath.Join(absTarget, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		digest.Mode = FormatMode(info.Mode())
+		e.Files[rel] = digest
+	}
+	lf.Entries[key] = e
+	return nil
+}
+
+// RecordValidators records the HTTP validators a single-file entry was
+// served with.
+func (lf *LockFile) RecordValidators(assetType, name, etag, lastModified string) {
+	key := entryKey(assetType, name)
+	if e, ok := lf.Entries[key]; ok {
+		e.ETag, e.LastModified = etag, lastModified
+		lf.Entries[key] = e
+	}
+}
+
+// RecordSignature records the verified signer of an entry.
+func (lf *LockFile) RecordSignature(assetType, name, signer string) {
+	key := entryKey(assetType, name)
+	if e, ok := lf.Entries[key]; ok {
+		e.Signature = signer
+		lf.Entries[key] = e
+	}
+}
+
+// RecordLicense records the license of the repository an entry comes
+// from.
+func (lf *LockFile) RecordLicense(assetType, name, license string) {
+	key := entryKey(assetType, name)
+	if e, ok := lf.Entries[key]; ok {
+		e.License = license
+		lf.Entries[key] = e
+	}
+}
+
+// RecordCollection records the collection an entry was synced as a member
+// of, or that it is no member if collection is empty.
+func (lf *LockFile) RecordCollection(assetType, name, collection string) {
+	key := entryKey(assetType, name)
+	if e, ok := lf.Entries[key]; ok {
+		e.Collection = collection
+		lf.Entries[key] = e
+	}
+}
+
+// RecordOutputs records the extra outputs an entry was written to.
+func (lf *LockFile) RecordOutputs(assetType, name string, copies, sections []string) {
+	key := entryKey(assetType, name)
+	if e, ok := lf.Entries[key]; ok {
+		e.Copies, e.Sections = copies, sections
+		lf.Entries[key] = e
+	}
+}
+
+// FormatMode formats the permission bits of mode as recorded in the lock
+// file, e.g. "0644".
+func FormatMode(mode fs.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// SetDirectory records or updates the lock entry of a directory asset
+// after a successful sync. files holds the content of each file keyed by its
+// slash-separated path inside the directory.
+func (lf *LockFile) SetDirectory(assetType, name, ref, resolvedSHA, targetPath string, files map[string][]byte) {
+	lf.Set(assetType, name, ref, resolvedSHA, targetPath, DirectoryContent(files))
+	e := lf.Entries[entryKey(assetType, name)]
+	e.Files = make(map[string]FileDigest, len(files))
+	for rel, data := range files {
+		e.Files[rel] = FileDigest{SHA256: Checksum(data), Size: int64(len(data))}
+	}
+	lf.Entries[entryKey(assetType, name)] = e
+}
+
+// DiffFiles compares the files of a directory asset on disk with the
+// digests recorded in e.Files and returns the differences in byte-wise path
+// order. modes holds the permission bits of the files on disk; it may be
+// nil, and files without a recorded mode are not compared, to skip mode
+// checks. It returns nil if e has no per-file digests.
+func (e LockEntry) DiffFiles(files map[string][]byte, modes map[string]fs.FileMode) []FileChange {
+	if len(e.Files) == 0 {
+		return nil
+	}
+	var changes []FileChange
+	for _, rel := range SortedKeys(e.Files) {
+		data, ok := files[rel]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{rel, "removed"})
+		case Checksum(data) != e.Files[rel].SHA256:
+			changes = append(changes, FileChange{rel, "modified"})
+		case modes != nil && e.Files[rel].Mode != "" && FormatMode(modes[rel]) != e.Files[rel].Mode:
+			changes = append(changes, FileChange{rel, fmt.Sprintf("mode %s → %s", e.Files[rel].Mode, FormatMode(modes[rel]))})
+		}
+	}
+	for _, rel := range SortedKeys(files) {
+		if _, ok := e.Files[rel]; !ok {
+			changes = append(changes, FileChange{rel, "added"})
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b FileChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes
+}
+
+// DirectoryContent returns the bytes the checksum of a directory asset is
+// computed from: its files concatenated in byte-wise path order.
+func DirectoryContent(files map[string][]byte) []byte {
+	var combined []byte
+	for _, rel := range SortedKeys(files) {
+		combined = append(combined, files[rel]...)
+	}
+	return combined
 }
 
 // Get retrieves a lock entry, if it exists.
@@ -109,8 +374,86 @@ func (lf *LockFile) Remove(assetType, name string) {
 	delete(lf.Entries, key)
 }
 
+// MergeLocks performs a three-way merge of two lock files that diverged from
+// base, such as the two sides of a git merge conflict. Each entry is taken
+// from the side that changed it; entries added on either side are kept, and
+// an entry changed on one side and removed on the other is kept. When both
+// sides changed an entry differently, the one synced last wins, ties going
+// to theirs, so the result does not depend on who merges. Entries that
+// differ only by their sync time count as equal. The returned notes describe,
+// in byte-wise key order, every entry that was changed on both sides or
+// kept despite a removal.
+func MergeLocks(base, ours, theirs *LockFile) (*LockFile, []string) {
+	merged := NewLockFile()
+	keys := make(map[string]bool)
+	for _, lf := range []*LockFile{base, ours, theirs} {
+		for key := range lf.Entries {
+			keys[key] = true
+		}
+	}
+
+	var notes []string
+	for _, key := range SortedKeys(keys) {
+		b, inBase := base.Entries[key]
+		o, inOurs := ours.Entries[key]
+		t, inTheirs := theirs.Entries[key]
+		switch {
+		case inOurs && inTheirs && sameEntry(o, t):
+			merged.Entries[key] = laterSync(o, t)
+		case inOurs && inTheirs && inBase && sameEntry(o, b):
+			merged.Entries[key] = t
+		case inOurs && inTheirs && inBase && sameEntry(t, b):
+			merged.Entries[key] = o
+		case inOurs && inTheirs:
+			kept := laterSync(o, t)
+			merged.Entries[key] = kept
+			notes = append(notes, fmt.Sprintf("%s: changed on both sides; kept %s (synced %s)", key, kept.Ref, kept.SyncedAt))
+		case !inBase && inOurs:
+			merged.Entries[key] = o
+		case !inBase && inTheirs:
+			merged.Entries[key] = t
+		case inOurs && !sameEntry(o, b):
+			merged.Entries[key] = o
+			notes = append(notes, fmt.Sprintf("%s: removed on their side but changed on ours; kept", key))
+		case inTheirs && !sameEntry(t, b):
+			merged.Entries[key] = t
+			notes = append(notes, fmt.Sprintf("%s: removed on our side but changed on theirs; kept", key))
+		}
+		// Otherwise the entry was removed on one side and left as-is on
+		// the other, or removed on both: it stays out.
+	}
+	return merged, notes
+}
+
+// sameEntry reports whether a and b are equal apart from their sync time.
+func sameEntry(a, b LockEntry) bool {
+	a.SyncedAt, b.SyncedAt = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// laterSync returns whichever of ours and theirs was synced last, or
+// theirs if they were synced at the same time. RFC 3339 UTC timestamps
+// compare correctly as strings.
+func laterSync(ours, theirs LockEntry) LockEntry {
+	if ours.SyncedAt > theirs.SyncedAt {
+		return ours
+	}
+	return theirs
+}
+
 // Checksum returns the hex-encoded SHA-256 of the given data.
 func Checksum(data []byte) string {
 	h := sha256.Sum256(data)
 	return fmt.Sprintf("%x", h)
 }
+
+// integrityPattern matches the integrity values entries may pin, in the
+// Subresource Integrity format.
+var integrityPattern = regexp.MustCompile(`^sha256-[A-Za-z0-9+/]{43}=$`)
+
+// Integrity returns the "sha256-<base64>" integrity value of data, as the
+// integrity option of an entry pins it.
+func Integrity(data []byte) string {
+	h := sha256.Sum256(data)
+	return "sha256-" + base64.StdEncoding.EncodeToString(h[:])
+}
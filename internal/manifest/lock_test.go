@@ -2,8 +2,11 @@ package manifest
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -14,8 +17,8 @@ import (
 func TestNewLockFile(t *testing.T) {
 	t.Parallel()
 	lf := NewLockFile()
-	if lf.Version != 1 {
-		t.Errorf("Version = %d, want 1", lf.Version)
+	if lf.Version != LockVersion {
+		t.Errorf("Version = %d, want %d", lf.Version, LockVersion)
 	}
 	if lf.Entries == nil {
 		t.Error("Entries is nil")
@@ -163,8 +166,8 @@ func TestLoadLock_Missing_ReturnsEmpty(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if lf.Version != 1 {
-		t.Errorf("Version = %d, want 1", lf.Version)
+	if lf.Version != LockVersion {
+		t.Errorf("Version = %d, want %d", lf.Version, LockVersion)
 	}
 	if len(lf.Entries) != 0 {
 		t.Error("expected empty entries")
@@ -196,8 +199,8 @@ func TestLoadLock_ValidJSON(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if lf.Version != 1 {
-		t.Errorf("Version = %d", lf.Version)
+	if lf.Version != LockVersion { // version 1 is upgraded on read
+		t.Errorf("Version = %d, want %d", lf.Version, LockVersion)
 	}
 	entry, ok := lf.Get("instructions", "reviews")
 	if !ok {
@@ -250,7 +253,7 @@ func TestLockFile_Save_Roundtrip(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if lf2.Version != 1 {
+	if lf2.Version != LockVersion {
 		t.Errorf("Version = %d after roundtrip", lf2.Version)
 	}
 	entry, ok := lf2.Get("agents", "helper")
@@ -354,7 +357,8 @@ func TestLockFile_Save_GoldenJSON(t *testing.T) {
 	got := string(readBytesLock(t, path))
 
 	want := `{
-  "version": 1,
+  "version": 2,
+  "integrity": "sha256:9468edbe5e312c351a04a0e1b7a56882a855c07cc3041526873bc127d77f0e9e",
   "entries": {
     "instructions/reviews": {
       "type": "instructions",
@@ -414,3 +418,213 @@ func readBytesLock(t *testing.T, path string) []byte {
 	}
 	return data
 }
+
+// --- Version 2 ---
+
+func TestLoadLock_NewerVersion(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".cops.lock")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "entries": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLock(path); err == nil || !strings.Contains(err.Error(), "upgrade cops") {
+		t.Errorf("LoadLock(v99) = %v, want an upgrade error", err)
+	}
+}
+
+func TestLockFile_SetDirectory_DiffFiles(t *testing.T) {
+	t.Parallel()
+	lf := NewLockFile()
+	lf.SetDirectory("skills", "k8s", "org/repo/skills/k8s@v1", "sha", ".github/skills/k8s", map[string][]byte{
+		"SKILL.md":          []byte("skill"),
+		"scripts/deploy.sh": []byte("deploy"),
+		"z.md":              []byte("z"),
+	})
+	e, _ := lf.Get("skills", "k8s")
+	if e.Checksum != Checksum([]byte("skilldeployz")) {
+		t.Errorf("Checksum = %s, want the concatenated content's", e.Checksum)
+	}
+	if got := e.Files["scripts/deploy.sh"]; got.SHA256 != Checksum([]byte("deploy")) || got.Size != 6 {
+		t.Errorf("Files[scripts/deploy.sh] = %+v", got)
+	}
+
+	if changes := e.DiffFiles(map[string][]byte{
+		"SKILL.md":          []byte("skill"),
+		"scripts/deploy.sh": []byte("deploy"),
+		"z.md":              []byte("z"),
+	}, nil); len(changes) != 0 {
+		t.Errorf("DiffFiles(same) = %v, want none", changes)
+	}
+	got := e.DiffFiles(map[string][]byte{
+		"SKILL.md": []byte("edited"),
+		"extra.md": []byte("new"),
+		"z.md":     []byte("z"),
+	}, nil)
+	want := []FileChange{{"SKILL.md", "modified"}, {"extra.md", "added"}, {"scripts/deploy.sh", "removed"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFiles = %v, want %v", got, want)
+	}
+
+	// Version 1 entries have no per-file digests.
+	if changes := (LockEntry{Checksum: "x"}).DiffFiles(map[string][]byte{"a": nil}, nil); changes != nil {
+		t.Errorf("DiffFiles(v1) = %v, want nil", changes)
+	}
+}
+
+func TestLockFile_RecordModes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string][]byte{"SKILL.md": []byte("skill"), "scripts/run.sh": []byte("run")}
+	for rel, data := range files {
+		p := filepath.Join(dir, "k8s", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "k8s", "scripts", "run.sh")
+	if err := os.Chmod(script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	single := filepath.Join(dir, "k8s", "SKILL.md")
+	if err := os.Chmod(single, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lf := NewLockFile()
+	lf.Set("agents", "a", "ref", "sha", "path", []byte("skill"))
+	lf.SetDirectory("skills", "k8s", "ref", "sha", "path", files)
+	if err := lf.RecordModes("agents", "a", single); err != nil {
+		t.Fatal(err)
+	}
+	if err := lf.RecordModes("skills", "k8s", filepath.Join(dir, "k8s")); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := lf.Get("agents", "a"); e.Mode != "0600" || e.Size != 5 {
+		t.Errorf("file entry mode/size = %q/%d, want 0600/5", e.Mode, e.Size)
+	}
+	e, _ := lf.Get("skills", "k8s")
+	if e.Mode != "" || e.Size != 8 || e.Files["scripts/run.sh"].Mode != "0755" {
+		t.Errorf("directory entry = %+v", e)
+	}
+
+	modes := map[string]fs.FileMode{"SKILL.md": 0600, "scripts/run.sh": 0644}
+	got := e.DiffFiles(files, modes)
+	want := []FileChange{{"scripts/run.sh", "mode 0755 → 0644"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFiles(chmod) = %v, want %v", got, want)
+	}
+	if err := lf.RecordModes("agents", "missing", single); err == nil {
+		t.Error("RecordModes(unknown entry): expected error")
+	}
+}
+
+func TestLockFile_SourceMetadata(t *testing.T) {
+	t.Parallel()
+	lf := NewLockFile()
+	lf.Set("agents", "a", "oci://ghcr.io/org/bundle:v1//a.md", "sha", "path", nil)
+	if e, _ := lf.Get("agents", "a"); e.Source != "oci" || e.Host != "ghcr.io" || e.APIURL != "https://ghcr.io/v2" {
+		t.Errorf("Set: source metadata = %q/%q/%q", e.Source, e.Host, e.APIURL)
+	}
+
+	// Version 1 entries get theirs on read.
+	path := filepath.Join(t.TempDir(), ".cops.lock")
+	v1 := `{"version": 1, "entries": {"prompts/p": {"type": "prompts", "name": "p", "ref": "https://example.com/p.md"}}}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lf, err := LoadLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := lf.Get("prompts", "p"); e.Source != "http" || e.Host != "example.com" || e.APIURL != "" {
+		t.Errorf("LoadLock(v1): source metadata = %q/%q/%q", e.Source, e.Host, e.APIURL)
+	}
+}
+
+func TestLoadLock_Integrity(t *testing.T) {
+	t.Parallel()
+	lf := NewLockFile()
+	lf.Set("agents", "a", "org/repo/a.md@v1", "sha", ".github/agents/a.agent.md", []byte("a"))
+	path := filepath.Join(t.TempDir(), ".cops.lock")
+	if err := lf.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(lf.Integrity, "sha256:") {
+		t.Fatalf("Integrity = %q after Save", lf.Integrity)
+	}
+	if _, err := LoadLock(path); err != nil {
+		t.Fatalf("LoadLock(intact): %v", err)
+	}
+
+	data := readBytesLock(t, path)
+	for name, corrupt := range map[string][]byte{
+		"edited":    bytes.Replace(data, []byte(`"sha"`), []byte(`"evil"`), 1),
+		"truncated": data[:len(data)/2],
+	} {
+		p := filepath.Join(t.TempDir(), ".cops.lock")
+		if err := os.WriteFile(p, corrupt, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLock(p); !errors.Is(err, ErrCorruptLock) {
+			t.Errorf("LoadLock(%s) = %v, want ErrCorruptLock", name, err)
+		}
+	}
+}
+
+func TestMergeLocks(t *testing.T) {
+	t.Parallel()
+	entry := func(ref, sha, syncedAt string) LockEntry {
+		return LockEntry{Type: "agents", Name: "a", Ref: ref, ResolvedSHA: sha, SyncedAt: syncedAt}
+	}
+	v1 := entry("org/repo/a.md@v1", "s1", "2026-01-01T00:00:00Z")
+	v1Later := entry("org/repo/a.md@v1", "s1", "2026-01-03T00:00:00Z")
+	v2 := entry("org/repo/a.md@v2", "s2", "2026-01-02T00:00:00Z")
+	v3 := entry("org/repo/a.md@v3", "s3", "2026-01-02T00:00:00Z")
+	v3Later := entry("org/repo/a.md@v3", "s3", "2026-01-03T00:00:00Z")
+	lock := func(e *LockEntry) *LockFile {
+		lf := NewLockFile()
+		if e != nil {
+			lf.Entries["agents/a"] = *e
+		}
+		return lf
+	}
+
+	tests := []struct {
+		name               string
+		base, ours, theirs *LockEntry
+		want               *LockEntry
+		wantNote           bool
+	}{
+		{"unchanged", &v1, &v1, &v1, &v1, false},
+		{"changed on ours", &v1, &v2, &v1, &v2, false},
+		{"changed on theirs", &v1, &v1, &v2, &v2, false},
+		{"same change on both sides", &v1, &v1Later, &v1, &v1Later, false},
+		{"added on ours", nil, &v2, nil, &v2, false},
+		{"added on theirs", nil, nil, &v2, &v2, false},
+		{"removed on ours", &v1, nil, &v1, nil, false},
+		{"removed on both sides", &v1, nil, nil, nil, false},
+		{"removed on theirs, changed on ours", &v1, &v2, nil, &v2, true},
+		{"changed differently, theirs later", &v1, &v2, &v3Later, &v3Later, true},
+		{"changed differently, ours later", &v1, &v3Later, &v2, &v3Later, true},
+		{"changed differently, same time", &v1, &v2, &v3, &v3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			merged, notes := MergeLocks(lock(tt.base), lock(tt.ours), lock(tt.theirs))
+			got, ok := merged.Entries["agents/a"]
+			switch {
+			case tt.want == nil && ok:
+				t.Errorf("merged entry = %+v, want none", got)
+			case tt.want != nil && (!ok || !reflect.DeepEqual(got, *tt.want)):
+				t.Errorf("merged entry = %+v, want %+v", got, *tt.want)
+			}
+			if (len(notes) > 0) != tt.wantNote {
+				t.Errorf("notes = %q, want notes: %v", notes, tt.wantNote)
+			}
+		})
+	}
+}
@@ -2,35 +2,194 @@ package manifest
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/cbout22/copilot-sync/internal/config"
 )
 
 const DefaultManifestFile = "copilot.toml"
 
 // Manifest represents the full copilot.toml file.
-// Each section maps asset names to their remote references.
+// Each section maps asset names to their remote references. Entries that
+// set per-entry options are written as sub-tables instead of plain strings:
+//
+//	[instructions]
+//	  plain = "org/repo/plain.md@v1"
+//
+//	  [instructions.security]
+//	    ref = "org/repo/security.md@main"
+//	    allow_branch_until = "2025-12-31"
+//	    target = "docs/ai/security.md"
+//
+// The sections hold only the manifest's own entries; entries pulled in
+// through Include are kept apart so that Save never inlines them.
 type Manifest struct {
-	Instructions map[string]string `toml:"instructions,omitempty"`
-	Agents       map[string]string `toml:"agents,omitempty"`
-	Prompts      map[string]string `toml:"prompts,omitempty"`
-	Skills       map[string]string `toml:"skills,omitempty"`
+	// Extends names a remote template manifest whose entries are merged
+	// beneath this one's (see ApplyExtends).
+	Extends string
+
+	// Include lists other manifests whose entries this one extends, as
+	// paths relative to this manifest.
+	Include []string
+
+	// OutputRoot replaces .github as the directory entries are written
+	// under, as a slash-separated path relative to the project root. Empty
+	// means config.DefaultOutputRoot.
+	OutputRoot string
+
+	// ReadOnly makes sync write managed files without write permission,
+	// to discourage local edits the next sync would overwrite.
+	ReadOnly bool
+
+	// Sources maps aliases to "org/repo[/path][@ref]", so entries can be
+	// written as "alias:path[@ref]". Entries keep their alias form on disk
+	// and are expanded by AllEntries.
+	Sources map[string]string
+
+	// DefaultRefs maps "org/repo" to the ref its entries use when they omit
+	// "@ref", so a version bump is a one-line change.
+	DefaultRefs map[string]string
+
+	// Vars holds the [template.vars] values that replace {{name}}
+	// placeholders in downloaded assets, e.g. the project name or team.
+	Vars map[string]string
+
+	// Targets maps the name of another tool's layout (e.g. "cursor") to the
+	// path pattern each asset type is also written to there, keyed by type
+	// (see Outputs).
+	Targets map[string]map[string]string
+
+	// Dirs maps an asset type to the directories its entries are written
+	// to, relative to the project root: the first replaces the type's
+	// directory under the output root, the others receive copies (see
+	// Outputs).
+	Dirs map[string][]string
+
+	// Limits caps the size of downloads (see SizeLimits).
+	Limits Limits
+
+	// Hooks holds the commands run around a sync.
+	Hooks Hooks
+
+	// Settings holds the project-wide defaults of [settings].
+	Settings Settings
+
+	// Types declares custom asset types, keyed by name, whose entries are
+	// listed in a section of that name (see config.RegisterType).
+	Types map[string]config.TypeDef
+
+	// Collections maps names to the refs of collection manifests, which
+	// expand into the assets they list when synced (see Collection).
+	Collections map[string]string
+
+	Instructions map[string]string
+	Agents       map[string]string
+	Prompts      map[string]string
+	Chatmodes    map[string]string
+	Skills       map[string]string
+
+	// RootInstructions holds at most one entry: the repository-wide
+	// copilot-instructions.md.
+	RootInstructions map[string]string
+
+	// AgentsMD holds at most one entry: the AGENTS.md at the root of the
+	// project.
+	AgentsMD map[string]string
+
+	// SetupSteps holds at most one entry: the workflow setting up the
+	// environment of the Copilot coding agent.
+	SetupSteps map[string]string
+
+	// custom holds the sections of custom asset types, keyed by type.
+	custom map[string]map[string]string
+
+	// options holds per-entry settings keyed by "<type>/<name>".
+	options map[string]EntryOptions
+
+	// inherited holds the merged entries of the included manifests.
+	inherited *Manifest
+
+	// template is set on remote templates being decoded, which may not
+	// declare types (see applyExtends).
+	template bool
+}
+
+// manifestFile is the on-disk shape of copilot.toml and copilot.json (and
+// of copilot.yaml, through its JSON form). Section values are either a ref
+// string or an entryTable.
+type manifestFile struct {
+	Extends          string                       `toml:"extends,omitempty" json:"extends,omitempty"`
+	Include          []string                     `toml:"include,omitempty" json:"include,omitempty"`
+	OutputRoot       string                       `toml:"output_root,omitempty" json:"output_root,omitempty"`
+	ReadOnly         bool                         `toml:"readonly,omitempty" json:"readonly,omitempty"`
+	Sources          map[string]string            `toml:"sources,omitempty" json:"sources,omitempty"`
+	DefaultRefs      map[string]string            `toml:"default_ref,omitempty" json:"default_ref,omitempty"`
+	Template         *templateFile                `toml:"template,omitempty" json:"template,omitempty"`
+	Targets          map[string]map[string]string `toml:"targets,omitempty" json:"targets,omitempty"`
+	Dirs             map[string][]string          `toml:"dirs,omitempty" json:"dirs,omitempty"`
+	Limits           *Limits                      `toml:"limits,omitempty" json:"limits,omitempty"`
+	Hooks            *Hooks                       `toml:"hooks,omitempty" json:"hooks,omitempty"`
+	Settings         *Settings                    `toml:"settings,omitempty" json:"settings,omitempty"`
+	Types            map[string]config.TypeDef    `toml:"types,omitempty" json:"types,omitempty"`
+	Collections      map[string]string            `toml:"collections,omitempty" json:"collections,omitempty"`
+	Instructions     map[string]any               `toml:"instructions,omitempty" json:"instructions,omitempty"`
+	Agents           map[string]any               `toml:"agents,omitempty" json:"agents,omitempty"`
+	Prompts          map[string]any               `toml:"prompts,omitempty" json:"prompts,omitempty"`
+	Chatmodes        map[string]any               `toml:"chatmodes,omitempty" json:"chatmodes,omitempty"`
+	Skills           map[string]any               `toml:"skills,omitempty" json:"skills,omitempty"`
+	RootInstructions map[string]any               `toml:"root-instructions,omitempty" json:"root-instructions,omitempty"`
+	AgentsMD         map[string]any               `toml:"agents-md,omitempty" json:"agents-md,omitempty"`
+	SetupSteps       map[string]any               `toml:"setup-steps,omitempty" json:"setup-steps,omitempty"`
+}
+
+// entryTable is the table form of a manifest entry.
+type entryTable struct {
+	Ref string `toml:"ref" json:"ref"`
+	EntryOptions
 }
 
 // New returns an empty Manifest with initialised maps.
 func New() *Manifest {
 	return &Manifest{
-		Instructions: make(map[string]string),
-		Agents:       make(map[string]string),
-		Prompts:      make(map[string]string),
-		Skills:       make(map[string]string),
+		Sources:          make(map[string]string),
+		DefaultRefs:      make(map[string]string),
+		Collections:      make(map[string]string),
+		Instructions:     make(map[string]string),
+		Agents:           make(map[string]string),
+		Prompts:          make(map[string]string),
+		Chatmodes:        make(map[string]string),
+		Skills:           make(map[string]string),
+		RootInstructions: make(map[string]string),
+		AgentsMD:         make(map[string]string),
+		SetupSteps:       make(map[string]string),
+		options:          make(map[string]EntryOptions),
 	}
 }
 
-// Load reads and parses a copilot.toml file from the given path.
-// If the file does not exist it returns an empty manifest (no error).
+// Load reads and parses a manifest from the given path, along with the
+// manifests it includes. The format follows the file extension (see
+// Find). If the file does not exist it returns an empty manifest (no
+// error).
 func Load(path string) (*Manifest, error) {
+	m, err := load(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkSources(); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	return m, nil
+}
+
+// load reads the manifest at path without validating source aliases, which
+// an overlay may borrow from its base manifest. stack holds the absolute
+// paths of the manifests currently being loaded, to detect include cycles.
+func load(path string, stack []string) (*Manifest, error) {
 	m := New()
 
 	data, err := os.ReadFile(path)
@@ -41,36 +200,218 @@ func Load(path string) (*Manifest, error) {
 		return nil, fmt.Errorf("reading manifest: %w", err)
 	}
 
-	if err := toml.Unmarshal(data, m); err != nil {
+	if err := m.decode(formatOf(path), data); err != nil {
 		return nil, fmt.Errorf("parsing manifest: %w", err)
 	}
-
-	// Ensure nil maps are initialised
-	if m.Instructions == nil {
-		m.Instructions = make(map[string]string)
+	if err := checkVars(m.Vars); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
 	}
-	if m.Agents == nil {
-		m.Agents = make(map[string]string)
+	if err := checkTargets(m.Targets); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
 	}
-	if m.Prompts == nil {
-		m.Prompts = make(map[string]string)
+	if err := checkDirs(m.Dirs); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
 	}
-	if m.Skills == nil {
-		m.Skills = make(map[string]string)
+	if err := checkOutputRoot(m.OutputRoot); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if err := checkLimits(m.Limits); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if err := checkHooks(m.Hooks); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if err := checkSettings(m.Settings); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+
+	if len(m.Include) > 0 {
+		if m.inherited, err = loadIncludes(path, m.Include, stack); err != nil {
+			return nil, err
+		}
 	}
 
 	return m, nil
 }
 
-// Save writes the manifest back to the given path.
+// decodeTOML fills m from a copilot.toml document.
+func (m *Manifest) decodeTOML(data []byte) error {
+	var raw struct {
+		fileHeader
+		Instructions     map[string]toml.Primitive `toml:"instructions"`
+		Agents           map[string]toml.Primitive `toml:"agents"`
+		Prompts          map[string]toml.Primitive `toml:"prompts"`
+		Chatmodes        map[string]toml.Primitive `toml:"chatmodes"`
+		Skills           map[string]toml.Primitive `toml:"skills"`
+		RootInstructions map[string]toml.Primitive `toml:"root-instructions"`
+		AgentsMD         map[string]toml.Primitive `toml:"agents-md"`
+		SetupSteps       map[string]toml.Primitive `toml:"setup-steps"`
+	}
+	md, err := toml.Decode(string(data), &raw)
+	if err != nil {
+		return err
+	}
+	m.setHeader(raw.fileHeader)
+	if err := m.registerTypes(raw.Types); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		assetType string
+		raw       map[string]toml.Primitive
+	}{
+		{"instructions", raw.Instructions},
+		{"agents", raw.Agents},
+		{"prompts", raw.Prompts},
+		{"chatmodes", raw.Chatmodes},
+		{"skills", raw.Skills},
+		{"root-instructions", raw.RootInstructions},
+		{"agents-md", raw.AgentsMD},
+		{"setup-steps", raw.SetupSteps},
+	}
+	if custom := config.CustomTypes(); len(custom) > 0 {
+		var doc map[string]toml.Primitive
+		if md, err = toml.Decode(string(data), &doc); err != nil {
+			return err
+		}
+		for _, t := range custom {
+			if _, ok := doc[string(t)]; !ok {
+				continue
+			}
+			var section map[string]toml.Primitive
+			if err := md.PrimitiveDecode(doc[string(t)], &section); err != nil {
+				return fmt.Errorf("%s: %w", t, err)
+			}
+			sections = append(sections, struct {
+				assetType string
+				raw       map[string]toml.Primitive
+			}{string(t), section})
+		}
+	}
+	for _, s := range sections {
+		for _, name := range SortedKeys(s.raw) {
+			decode := func(v any) error { return md.PrimitiveDecode(s.raw[name], v) }
+			if err := m.decodeEntry(s.assetType, name, decode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileHeader holds the non-entry settings of a manifest file, as decoded.
+type fileHeader struct {
+	Extends     string                       `toml:"extends" json:"extends"`
+	Include     []string                     `toml:"include" json:"include"`
+	OutputRoot  string                       `toml:"output_root" json:"output_root"`
+	ReadOnly    bool                         `toml:"readonly" json:"readonly"`
+	Sources     map[string]string            `toml:"sources" json:"sources"`
+	DefaultRefs map[string]string            `toml:"default_ref" json:"default_ref"`
+	Template    templateFile                 `toml:"template" json:"template"`
+	Targets     map[string]map[string]string `toml:"targets" json:"targets"`
+	Dirs        map[string][]string          `toml:"dirs" json:"dirs"`
+	Limits      Limits                       `toml:"limits" json:"limits"`
+	Hooks       Hooks                        `toml:"hooks" json:"hooks"`
+	Settings    Settings                     `toml:"settings" json:"settings"`
+	Types       map[string]config.TypeDef    `toml:"types" json:"types"`
+	Collections map[string]string            `toml:"collections" json:"collections"`
+}
+
+// setHeader records the non-entry settings of a decoded manifest file.
+func (m *Manifest) setHeader(h fileHeader) {
+	m.Extends = h.Extends
+	m.Include = h.Include
+	m.OutputRoot = h.OutputRoot
+	m.ReadOnly = h.ReadOnly
+	m.Vars = h.Template.Vars
+	m.Targets = h.Targets
+	m.Dirs = h.Dirs
+	m.Limits = h.Limits
+	m.Hooks = h.Hooks
+	m.Settings = h.Settings
+	m.Types = h.Types
+	if h.Sources != nil {
+		m.Sources = h.Sources
+	}
+	if h.DefaultRefs != nil {
+		m.DefaultRefs = h.DefaultRefs
+	}
+	if h.Collections != nil {
+		m.Collections = h.Collections
+	}
+}
+
+// decodeEntry decodes a single section value, which is either a plain ref
+// string or an entryTable. decode unmarshals the value into its argument.
+func (m *Manifest) decodeEntry(assetType, name string, decode func(v any) error) error {
+	if other := m.singletonEntry(assetType); other != "" && other != name {
+		return fmt.Errorf("%s/%s: %s holds a single entry, already %s", assetType, name, assetType, other)
+	}
+	var ref string
+	if err := decode(&ref); err == nil {
+		return m.Set(assetType, name, ref)
+	}
+
+	var table entryTable
+	if err := decode(&table); err != nil {
+		return fmt.Errorf("%s/%s: %w", assetType, name, err)
+	}
+	if table.Ref == "" {
+		return fmt.Errorf("%s/%s: missing ref", assetType, name)
+	}
+	if err := table.EntryOptions.validateFor(assetType); err != nil {
+		return fmt.Errorf("%s/%s: %w", assetType, name, err)
+	}
+	if err := m.Set(assetType, name, table.Ref); err != nil {
+		return err
+	}
+	m.SetOptions(assetType, name, table.EntryOptions)
+	return nil
+}
+
+// Save writes the manifest back to the given path, in the format its
+// extension selects. Rewriting a YAML manifest keeps its comments and the
+// order of its sections and entries.
 func (m *Manifest) Save(path string) error {
+	// The file being replaced, whose layout a YAML manifest keeps.
+	prev, _ := os.ReadFile(path)
 	f, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("creating manifest file: %w", err)
 	}
 
-	encoder := toml.NewEncoder(f)
-	if err := encoder.Encode(m); err != nil {
+	out := manifestFile{
+		Extends:          m.Extends,
+		Include:          m.Include,
+		OutputRoot:       m.OutputRoot,
+		ReadOnly:         m.ReadOnly,
+		Sources:          m.Sources,
+		DefaultRefs:      m.DefaultRefs,
+		Template:         m.templateSection(),
+		Targets:          m.Targets,
+		Dirs:             m.Dirs,
+		Limits:           m.limitsSection(),
+		Hooks:            m.hooksSection(),
+		Settings:         m.settingsSection(),
+		Types:            m.Types,
+		Collections:      m.Collections,
+		Instructions:     m.fileSection("instructions", m.Instructions),
+		Agents:           m.fileSection("agents", m.Agents),
+		Prompts:          m.fileSection("prompts", m.Prompts),
+		Chatmodes:        m.fileSection("chatmodes", m.Chatmodes),
+		Skills:           m.fileSection("skills", m.Skills),
+		RootInstructions: m.fileSection("root-instructions", m.RootInstructions),
+		AgentsMD:         m.fileSection("agents-md", m.AgentsMD),
+		SetupSteps:       m.fileSection("setup-steps", m.SetupSteps),
+	}
+
+	custom := make(map[string]map[string]any)
+	for t, section := range m.custom {
+		if len(section) > 0 {
+			custom[t] = m.fileSection(t, section)
+		}
+	}
+	if err := encode(f, formatOf(path), out, custom, prev); err != nil {
 		_ = f.Close()
 		return fmt.Errorf("encoding manifest: %w", err)
 	}
@@ -82,6 +423,21 @@ func (m *Manifest) Save(path string) error {
 	return nil
 }
 
+// fileSection converts a section to its on-disk form: plain strings for
+// entries without options, tables otherwise.
+func (m *Manifest) fileSection(assetType string, section map[string]string) map[string]any {
+	out := make(map[string]any, len(section))
+	for name, ref := range section {
+		opts := m.Options(assetType, name)
+		if opts.IsZero() {
+			out[name] = ref
+		} else {
+			out[name] = entryTable{Ref: ref, EntryOptions: opts}
+		}
+	}
+	return out
+}
+
 // Section returns the map for the given asset type name.
 func (m *Manifest) Section(assetType string) (map[string]string, error) {
 	switch assetType {
@@ -91,23 +447,59 @@ func (m *Manifest) Section(assetType string) (map[string]string, error) {
 		return m.Agents, nil
 	case "prompts":
 		return m.Prompts, nil
+	case "chatmodes":
+		return m.Chatmodes, nil
 	case "skills":
 		return m.Skills, nil
-	default:
+	case "root-instructions":
+		return m.RootInstructions, nil
+	case "agents-md":
+		return m.AgentsMD, nil
+	case "setup-steps":
+		return m.SetupSteps, nil
+	}
+	if !config.AssetType(assetType).IsCustom() {
 		return nil, fmt.Errorf("unknown asset type: %s", assetType)
 	}
+	section, ok := m.custom[assetType]
+	if !ok {
+		section = make(map[string]string)
+		if m.custom == nil {
+			m.custom = make(map[string]map[string]string)
+		}
+		m.custom[assetType] = section
+	}
+	return section, nil
 }
 
-// Set adds or updates an entry in the given asset type section.
+// Set adds or updates an entry in the given asset type section. The entry
+// of a singleton type replaces the one it holds, if named otherwise.
 func (m *Manifest) Set(assetType, name, ref string) error {
 	section, err := m.Section(assetType)
 	if err != nil {
 		return err
 	}
+	if other := m.singletonEntry(assetType); other != "" && other != name {
+		delete(section, other)
+		delete(m.options, entryKey(assetType, other))
+	}
 	section[name] = ref
 	return nil
 }
 
+// singletonEntry returns the name of the entry of assetType, a singleton
+// type, or "" if it holds none or the type is not a singleton.
+func (m *Manifest) singletonEntry(assetType string) string {
+	if !config.AssetType(assetType).IsSingleton() {
+		return ""
+	}
+	section, _ := m.Section(assetType)
+	for name := range section {
+		return name
+	}
+	return ""
+}
+
 // Remove deletes an entry from the given asset type section.
 // Returns true if the entry existed, false otherwise.
 func (m *Manifest) Remove(assetType, name string) (bool, error) {
@@ -119,30 +511,181 @@ func (m *Manifest) Remove(assetType, name string) (bool, error) {
 		return false, nil
 	}
 	delete(section, name)
+	delete(m.options, entryKey(assetType, name))
 	return true, nil
 }
 
-// AllEntries returns every (type, name, ref) triple in the manifest.
+// Options returns the per-entry options for the given entry, or the zero
+// value if none are set. Entries not defined locally take the options of
+// the included manifest that defines them.
+func (m *Manifest) Options(assetType, name string) EntryOptions {
+	if section, err := m.Section(assetType); err == nil && m.inherited != nil {
+		if _, local := section[name]; !local {
+			return m.inherited.Options(assetType, name)
+		}
+	}
+	return m.options[entryKey(assetType, name)]
+}
+
+// SetOptions records per-entry options. Setting the zero value clears them.
+func (m *Manifest) SetOptions(assetType, name string, opts EntryOptions) {
+	key := entryKey(assetType, name)
+	if opts.IsZero() {
+		delete(m.options, key)
+		return
+	}
+	if m.options == nil {
+		m.options = make(map[string]EntryOptions)
+	}
+	m.options[key] = opts
+}
+
+// TargetPath returns where the given entry is written, relative to the
+// project root: its target option if set, the type's default otherwise.
+func (m *Manifest) TargetPath(assetType, name string) string {
+	return m.Entry(assetType, name).TargetPath()
+}
+
+// Entry returns the entry assetType/name as m places it, with its options,
+// output root and [dirs] directory, whether or not m holds it. Its Ref is
+// left empty.
+func (m *Manifest) Entry(assetType, name string) Entry {
+	return Entry{Type: assetType, Name: name, Options: m.Options(assetType, name), OutputRoot: m.OutputRoot, Dir: m.typeDir(assetType)}
+}
+
+// manifestSection pairs an asset type with its section map.
+type manifestSection struct {
+	assetType string
+	section   map[string]string
+}
+
+// sections returns the manifest's own sections in declaration order, the
+// custom types last by name.
+func (m *Manifest) sections() []manifestSection {
+	sections := []manifestSection{
+		{"instructions", m.Instructions},
+		{"agents", m.Agents},
+		{"prompts", m.Prompts},
+		{"chatmodes", m.Chatmodes},
+		{"skills", m.Skills},
+		{"root-instructions", m.RootInstructions},
+		{"agents-md", m.AgentsMD},
+		{"setup-steps", m.SetupSteps},
+	}
+	for _, t := range config.CustomTypes() {
+		section, _ := m.Section(string(t))
+		sections = append(sections, manifestSection{string(t), section})
+	}
+	return sections
+}
+
+// AllEntries returns every (type, name, ref) triple in the manifest,
+// including entries from included manifests that are not overridden
+// locally. Refs using a [sources] alias are expanded to their full form.
+// Entries are grouped by asset type in declaration order, then ordered by
+// name using byte-wise comparison so output never depends on map iteration
+// order or the user's locale.
 func (m *Manifest) AllEntries() []Entry {
 	var entries []Entry
-	for name, ref := range m.Instructions {
-		entries = append(entries, Entry{Type: "instructions", Name: name, Ref: ref})
+	for _, s := range m.sections() {
+		merged := s.section
+		// A singleton of the manifest replaces an inherited one, whatever
+		// their names.
+		if m.inherited != nil && (len(s.section) == 0 || !config.AssetType(s.assetType).IsSingleton()) {
+			inherited, _ := m.inherited.Section(s.assetType)
+			merged = maps.Clone(inherited)
+			maps.Copy(merged, s.section)
+		}
+		for _, name := range SortedKeys(merged) {
+			ref, err := m.ExpandRef(merged[name])
+			if err != nil {
+				// Load validates aliases; keep the raw ref if one was added since.
+				ref = merged[name]
+			}
+			entries = append(entries, Entry{
+				Type:       s.assetType,
+				Name:       name,
+				Ref:        ref,
+				Options:    m.Options(s.assetType, name),
+				OutputRoot: m.OutputRoot,
+				Dir:        m.typeDir(s.assetType),
+			})
+		}
+	}
+	return entries
+}
+
+// Groups returns the names of all groups entries are tagged with, in
+// byte-wise order.
+func (m *Manifest) Groups() []string {
+	set := make(map[string]bool)
+	for _, e := range m.AllEntries() {
+		for _, g := range e.Options.Groups {
+			set[g] = true
+		}
 	}
-	for name, ref := range m.Agents {
-		entries = append(entries, Entry{Type: "agents", Name: name, Ref: ref})
+	return SortedKeys(set)
+}
+
+// EntriesInGroups returns the entries of AllEntries tagged with any of
+// groups. No groups selects every entry. Naming a group no entry belongs to
+// is an error, so typos do not silently select nothing.
+func (m *Manifest) EntriesInGroups(groups []string) ([]Entry, error) {
+	entries := m.AllEntries()
+	if len(groups) == 0 {
+		return entries, nil
 	}
-	for name, ref := range m.Prompts {
-		entries = append(entries, Entry{Type: "prompts", Name: name, Ref: ref})
+	known := m.Groups()
+	for _, g := range groups {
+		if !slices.Contains(known, g) {
+			return nil, fmt.Errorf("unknown group %q", g)
+		}
 	}
-	for name, ref := range m.Skills {
-		entries = append(entries, Entry{Type: "skills", Name: name, Ref: ref})
+	var selected []Entry
+	for _, e := range entries {
+		if e.Options.InGroup(groups...) {
+			selected = append(selected, e)
+		}
 	}
-	return entries
+	return selected, nil
+}
+
+// SortedKeys returns the keys of m in byte-wise order. This is the single
+// ordering cops uses for everything it prints or writes, so generated
+// artifacts are identical regardless of locale.
+func SortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
 }
 
 // Entry is a flattened manifest row.
 type Entry struct {
-	Type string
-	Name string
-	Ref  string
+	Type    string
+	Name    string
+	Ref     string
+	Options EntryOptions
+
+	// OutputRoot is the output_root of the manifest the entry was read
+	// from; empty means config.DefaultOutputRoot.
+	OutputRoot string
+
+	// Dir is the directory [dirs] writes the entry's type to, relative to
+	// the project root; empty means the type's directory under OutputRoot.
+	Dir string
+
+	// Collection is the [collections] entry the entry was expanded from,
+	// if any.
+	Collection string
+}
+
+// TargetPath returns where the entry is written, relative to the project
+// root: its target option if set, its [dirs] directory or the type's
+// default location under the output root otherwise.
+func (e Entry) TargetPath() string {
+	if e.Options.Target != "" {
+		return filepath.Clean(filepath.FromSlash(e.Options.Target))
+	}
+	if e.Dir != "" {
+		return config.AssetType(e.Type).PathIn(filepath.FromSlash(e.Dir), e.Name)
+	}
+	return config.AssetType(e.Type).TargetPathIn(e.OutputRoot, e.Name)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"testing"
 )
@@ -47,9 +48,15 @@ func TestNew_EmptyInitializedMaps(t *testing.T) {
 	if m.Prompts == nil {
 		t.Error("Prompts is nil")
 	}
+	if m.Chatmodes == nil {
+		t.Error("Chatmodes is nil")
+	}
 	if m.Skills == nil {
 		t.Error("Skills is nil")
 	}
+	if m.RootInstructions == nil {
+		t.Error("RootInstructions is nil")
+	}
 	if len(m.Instructions) != 0 || len(m.Agents) != 0 || len(m.Prompts) != 0 || len(m.Skills) != 0 {
 		t.Error("maps should be empty")
 	}
@@ -88,6 +95,22 @@ func TestManifest_Set_UnknownType(t *testing.T) {
 	}
 }
 
+func TestManifest_Set_Singleton(t *testing.T) {
+	t.Parallel()
+	m := New()
+	_ = m.Set("root-instructions", "team", "org/repo/team.md@v1")
+	m.SetOptions("root-instructions", "team", EntryOptions{Groups: []string{"ci"}})
+	if err := m.Set("root-instructions", "main", "org/repo/main.md@v1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.RootInstructions) != 1 || m.RootInstructions["main"] != "org/repo/main.md@v1" {
+		t.Errorf("RootInstructions = %v, want only main", m.RootInstructions)
+	}
+	if opts := m.Options("root-instructions", "team"); len(opts.Groups) != 0 {
+		t.Errorf("options of the replaced entry kept: %+v", opts)
+	}
+}
+
 // --- Remove ---
 
 func TestManifest_Remove_Existing(t *testing.T) {
@@ -153,6 +176,43 @@ func TestManifest_AllEntries_Coverage(t *testing.T) {
 	}
 }
 
+func TestManifest_AllEntries_ByteWiseOrder(t *testing.T) {
+	t.Parallel()
+	m := New()
+	// Mixed case, accents and punctuation: locale collation would interleave
+	// these differently, byte-wise order must not.
+	for _, name := range []string{"zeta", "Beta", "éclair", "alpha", "_private", "a-b", "a_b"} {
+		_ = m.Set("instructions", name, "ref")
+	}
+	_ = m.Set("skills", "aaa", "ref")
+	_ = m.Set("agents", "zzz", "ref")
+
+	want := []string{
+		"instructions/Beta", "instructions/_private", "instructions/a-b", "instructions/a_b",
+		"instructions/alpha", "instructions/zeta", "instructions/éclair",
+		"agents/zzz",
+		"skills/aaa",
+	}
+	for i := 0; i < 20; i++ {
+		var got []string
+		for _, e := range m.AllEntries() {
+			got = append(got, e.Type+"/"+e.Name)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("AllEntries() order:\ngot  %v\nwant %v", got, want)
+		}
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	t.Parallel()
+	got := SortedKeys(map[string]int{"b": 1, "B": 2, "a": 3, "ä": 4})
+	want := []string{"B", "a", "b", "ä"}
+	if !slices.Equal(got, want) {
+		t.Errorf("SortedKeys() = %v, want %v", got, want)
+	}
+}
+
 // --- Load ---
 
 func TestLoad_MissingFile_ReturnsEmptyManifest(t *testing.T) {
@@ -188,6 +248,17 @@ func TestLoad_ValidTOML(t *testing.T) {
 	}
 }
 
+func TestLoad_SingletonHoldsOneEntry(t *testing.T) {
+	t.Parallel()
+	path := writeTempFile(t, "copilot.toml", `[root-instructions]
+main = "org/repo/main.md@v1"
+team = "org/repo/team.md@v1"
+`)
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for two root-instructions entries")
+	}
+}
+
 func TestLoad_InvalidTOML(t *testing.T) {
 	t.Parallel()
 	path := writeTempFile(t, "bad.toml", "[[[[invalid")
@@ -306,6 +377,7 @@ func TestSave_TOML_SectionsFollowStructOrder(t *testing.T) {
 	m := New()
 	// Add entries in reverse struct-field order
 	_ = m.Set("skills", "s", "org/repo/s@v1")
+	_ = m.Set("chatmodes", "c", "org/repo/c@v1")
 	_ = m.Set("prompts", "p", "org/repo/p@v1")
 	_ = m.Set("agents", "a", "org/repo/a@v1")
 	_ = m.Set("instructions", "i", "org/repo/i@v1")
@@ -316,8 +388,8 @@ func TestSave_TOML_SectionsFollowStructOrder(t *testing.T) {
 	}
 	got := string(readBytes(t, path))
 
-	// Struct field declaration order: Instructions, Agents, Prompts, Skills
-	sections := []string{"[instructions]", "[agents]", "[prompts]", "[skills]"}
+	// Struct field declaration order: Instructions, Agents, Prompts, Chatmodes, Skills
+	sections := []string{"[instructions]", "[agents]", "[prompts]", "[chatmodes]", "[skills]"}
 	positions := make([]int, len(sections))
 	for i, sec := range sections {
 		pos := bytes.Index([]byte(got), []byte(sec))
@@ -349,3 +421,21 @@ func TestSave_TOML_EmptySectionsOmitted(t *testing.T) {
 		}
 	}
 }
+
+func TestLoad_ReadOnly(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", "readonly = true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.ReadOnly {
+		t.Fatal("ReadOnly = false, want true")
+	}
+	path := tempPath(t, "copilot.yaml")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if m2, err := Load(path); err != nil || !m2.ReadOnly {
+		t.Errorf("ReadOnly after roundtrip = %v, %v", m2 != nil && m2.ReadOnly, err)
+	}
+}
@@ -0,0 +1,260 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// ExceptionWarnWindow is how long before expiry an allow_branch_until
+// exception starts producing warnings.
+const ExceptionWarnWindow = 14 * 24 * time.Hour
+
+// exceptionDateLayout is the format of allow_branch_until values.
+const exceptionDateLayout = "2006-01-02"
+
+// Values of the secrets option: what happens when a downloaded file looks
+// like it holds a credential.
+const (
+	SecretsBlock = "block" // refuse to install the entry (the default)
+	SecretsWarn  = "warn"  // install it and report the findings
+	SecretsAllow = "allow" // do not scan it
+)
+
+// EntryOptions holds optional per-entry settings. They are only written to
+// copilot.toml when at least one is set.
+type EntryOptions struct {
+	// AllowBranchUntil is a YYYY-MM-DD date until which the entry may track a
+	// branch instead of a pinned tag or commit. The exception is valid through
+	// the end of that day (UTC).
+	AllowBranchUntil string `toml:"allow_branch_until,omitempty" json:"allow_branch_until,omitempty"`
+
+	// Target overrides where the entry is written, as a slash-separated path
+	// relative to the project root (e.g. "docs/ai/setup.md"). Empty means
+	// the type's default <output_root>/<type>/<name> location.
+	Target string `toml:"target,omitempty" json:"target,omitempty"`
+
+	// Groups tags the entry into named groups (e.g. "backend", "ci-only")
+	// that `cops sync --group` and `cops check --group` select from.
+	Groups []string `toml:"groups,omitempty" json:"groups,omitempty"`
+
+	// Description says what the entry is for. Like Owner and Tags, it is
+	// only shown by `cops list` and `cops info` and never affects syncing.
+	Description string `toml:"description,omitempty" json:"description,omitempty"`
+
+	// Owner is who to ask about the entry, e.g. "@my-org/platform".
+	Owner string `toml:"owner,omitempty" json:"owner,omitempty"`
+
+	// Tags are free-form labels that `cops list --tag` filters on.
+	Tags []string `toml:"tags,omitempty" json:"tags,omitempty"`
+
+	// Frontmatter holds YAML frontmatter fields (e.g. applyTo or
+	// description) merged into the downloaded file, overriding upstream
+	// values. Values are strings, booleans or lists of strings. Skills do
+	// not support it.
+	Frontmatter map[string]any `toml:"frontmatter,omitempty" json:"frontmatter,omitempty"`
+
+	// Include and Exclude select the files of a skill that are downloaded,
+	// as glob patterns over paths relative to the skill directory (e.g.
+	// "*.md" or "templates/**"). A file is kept if it matches an Include
+	// pattern, or Include is empty, and matches no Exclude pattern. Only
+	// skills support them.
+	Include []string `toml:"include,omitempty" json:"include,omitempty"`
+	Exclude []string `toml:"exclude,omitempty" json:"exclude,omitempty"`
+
+	// Transform is a shell command, run from the project root, that every
+	// downloaded file of the entry is piped through before it is written
+	// (e.g. "scripts/localize.sh"). Templates may not set it.
+	Transform string `toml:"transform,omitempty" json:"transform,omitempty"`
+
+	// Integrity pins the content the entry must download, as
+	// "sha256-<base64>" of the file, or of the selected files of a skill
+	// together (see DirectoryContent), before any other option changes it.
+	// Content that does not match is never installed, even from a branch.
+	Integrity string `toml:"integrity,omitempty" json:"integrity,omitempty"`
+
+	// Cosign requires the entry, an OCI artifact or a release asset, to be
+	// signed with cosign as it describes before it is installed.
+	Cosign *CosignOptions `toml:"cosign,omitempty" json:"cosign,omitempty"`
+
+	// Secrets says what to do when the entry's files look like they hold
+	// credentials: SecretsBlock, SecretsWarn or SecretsAllow. Empty blocks.
+	Secrets string `toml:"secrets,omitempty" json:"secrets,omitempty"`
+}
+
+// CosignOptions describes the cosign signature an entry must carry: one
+// made with the public key at Key, or a keyless one issued to Identity by
+// the OIDC provider Issuer.
+type CosignOptions struct {
+	// Key is the path of a PEM public key, relative to the project root.
+	Key string `toml:"key,omitempty" json:"key,omitempty"`
+
+	// Identity is the subject of the signing certificate: an email, or a
+	// URI such as the GitHub Actions workflow that signed.
+	Identity string `toml:"identity,omitempty" json:"identity,omitempty"`
+
+	// Issuer is the OIDC issuer of the signing certificate, e.g.
+	// "https://token.actions.githubusercontent.com".
+	Issuer string `toml:"issuer,omitempty" json:"issuer,omitempty"`
+}
+
+// IsZero reports whether no option is set.
+func (o EntryOptions) IsZero() bool {
+	return o.AllowBranchUntil == "" && o.Target == "" && len(o.Groups) == 0 &&
+		o.Description == "" && o.Owner == "" && len(o.Tags) == 0 && len(o.Frontmatter) == 0 &&
+		len(o.Include) == 0 && len(o.Exclude) == 0 && o.Transform == "" && o.Integrity == "" && o.Cosign == nil &&
+		o.Secrets == ""
+}
+
+// Filtered reports whether Include or Exclude narrow the files of a skill.
+func (o EntryOptions) Filtered() bool {
+	return len(o.Include) > 0 || len(o.Exclude) > 0
+}
+
+// SelectsFile reports whether the file at rel, a slash-separated path
+// relative to the skill directory, passes the Include and Exclude patterns.
+func (o EntryOptions) SelectsFile(rel string) bool {
+	if len(o.Include) > 0 && !slices.ContainsFunc(o.Include, func(p string) bool { return MatchGlob(p, rel) }) {
+		return false
+	}
+	return !slices.ContainsFunc(o.Exclude, func(p string) bool { return MatchGlob(p, rel) })
+}
+
+// InGroup reports whether the entry is tagged with any of groups.
+func (o EntryOptions) InGroup(groups ...string) bool {
+	for _, g := range groups {
+		if slices.Contains(o.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectPath reports whether the slash-separated path p stays inside the
+// project and out of its .git directory, where a written file could be
+// run as a git hook.
+func projectPath(p string) bool {
+	p = filepath.FromSlash(p)
+	if !filepath.IsLocal(p) {
+		return false
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(p)), "/")
+	return !strings.EqualFold(first, ".git")
+}
+
+func (o EntryOptions) validate() error {
+	if o.AllowBranchUntil != "" {
+		if _, err := time.Parse(exceptionDateLayout, o.AllowBranchUntil); err != nil {
+			return fmt.Errorf("invalid allow_branch_until %q: must be YYYY-MM-DD", o.AllowBranchUntil)
+		}
+	}
+	if o.Target != "" && !projectPath(o.Target) {
+		return fmt.Errorf("invalid target %q: must be a relative path inside the project, out of .git", o.Target)
+	}
+	for _, g := range o.Groups {
+		if g == "" || strings.ContainsAny(g, " \t,") {
+			return fmt.Errorf("invalid group %q: must be a non-empty name without spaces or commas", g)
+		}
+	}
+	for _, tag := range o.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t,") {
+			return fmt.Errorf("invalid tag %q: must be a non-empty name without spaces or commas", tag)
+		}
+	}
+	for _, key := range SortedKeys(o.Frontmatter) {
+		if !frontmatterKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid frontmatter key %q", key)
+		}
+		switch v := o.Frontmatter[key].(type) {
+		case string, bool, []string:
+			continue
+		case []any:
+			if _, ok := stringList(v); ok {
+				continue
+			}
+		}
+		return fmt.Errorf("invalid frontmatter %s: must be a string, a boolean or a list of strings", key)
+	}
+	for _, p := range slices.Concat(o.Include, o.Exclude) {
+		if err := validateGlob(p); err != nil {
+			return err
+		}
+	}
+	if o.Transform != "" && strings.TrimSpace(o.Transform) == "" {
+		return fmt.Errorf("invalid transform: must be a command")
+	}
+	if o.Integrity != "" && !integrityPattern.MatchString(o.Integrity) {
+		return fmt.Errorf("invalid integrity %q: must be \"sha256-\" followed by the base64-encoded digest", o.Integrity)
+	}
+	switch o.Secrets {
+	case "", SecretsBlock, SecretsWarn, SecretsAllow:
+	default:
+		return fmt.Errorf("invalid secrets %q: must be %q, %q or %q", o.Secrets, SecretsBlock, SecretsWarn, SecretsAllow)
+	}
+	if c := o.Cosign; c != nil {
+		keyless := c.Identity != "" || c.Issuer != ""
+		switch {
+		case c.Key != "" && keyless:
+			return fmt.Errorf("invalid cosign: set either key, or identity and issuer")
+		case c.Key == "" && (c.Identity == "" || c.Issuer == ""):
+			return fmt.Errorf("invalid cosign: needs a key, or both identity and issuer")
+		}
+	}
+	return nil
+}
+
+// validateFor validates the options of an entry of the given asset type.
+func (o EntryOptions) validateFor(assetType string) error {
+	if len(o.Frontmatter) > 0 && config.AssetType(assetType).IsDirectory() {
+		return fmt.Errorf("frontmatter is not supported for %s", assetType)
+	}
+	if o.Filtered() && !config.AssetType(assetType).IsDirectory() {
+		return fmt.Errorf("include and exclude are only supported for skills, not %s", assetType)
+	}
+	return o.validate()
+}
+
+// frontmatterKeyPattern matches the frontmatter keys an entry may set.
+var frontmatterKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ExceptionStatus describes where an allow_branch_until exception stands.
+type ExceptionStatus int
+
+const (
+	// ExceptionNone means the entry has no branch exception.
+	ExceptionNone ExceptionStatus = iota
+	// ExceptionActive means the exception is valid and not close to expiry.
+	ExceptionActive
+	// ExceptionExpiring means the exception expires within ExceptionWarnWindow.
+	ExceptionExpiring
+	// ExceptionExpired means the exception date has passed.
+	ExceptionExpired
+)
+
+// BranchException evaluates the entry's allow_branch_until exception at the
+// given time and returns its status along with the moment it expires.
+func (o EntryOptions) BranchException(now time.Time) (ExceptionStatus, time.Time) {
+	if o.AllowBranchUntil == "" {
+		return ExceptionNone, time.Time{}
+	}
+	day, err := time.Parse(exceptionDateLayout, o.AllowBranchUntil)
+	if err != nil {
+		// Load rejects malformed dates; treat anything else as already expired.
+		return ExceptionExpired, time.Time{}
+	}
+	expiry := day.AddDate(0, 0, 1)
+
+	switch {
+	case !now.Before(expiry):
+		return ExceptionExpired, expiry
+	case expiry.Sub(now) <= ExceptionWarnWindow:
+		return ExceptionExpiring, expiry
+	default:
+		return ExceptionActive, expiry
+	}
+}
@@ -0,0 +1,296 @@
+package manifest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBranchException(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		until string
+		want  ExceptionStatus
+	}{
+		{"", ExceptionNone},
+		{"2026-06-30", ExceptionActive},
+		{"2025-12-14", ExceptionExpiring},
+		{"2025-12-01", ExceptionExpiring}, // valid through the end of the day
+		{"2025-11-30", ExceptionExpired},
+		{"not-a-date", ExceptionExpired},
+	}
+	for _, tc := range cases {
+		got, _ := EntryOptions{AllowBranchUntil: tc.until}.BranchException(now)
+		if got != tc.want {
+			t.Errorf("BranchException(%q) = %v, want %v", tc.until, got, tc.want)
+		}
+	}
+}
+
+func TestBranchException_Expiry(t *testing.T) {
+	t.Parallel()
+	_, expiry := EntryOptions{AllowBranchUntil: "2025-12-31"}.BranchException(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !expiry.Equal(want) {
+		t.Errorf("expiry = %s, want %s", expiry, want)
+	}
+}
+
+func TestLoad_EntryTable(t *testing.T) {
+	t.Parallel()
+	content := `[instructions]
+  plain = "org/repo/plain.md@v1"
+
+  [instructions.security]
+    ref = "org/repo/security.md@main"
+    allow_branch_until = "2025-12-31"
+`
+	m, err := Load(writeTempFile(t, "copilot.toml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Instructions["security"] != "org/repo/security.md@main" {
+		t.Errorf("Instructions[security] = %q", m.Instructions["security"])
+	}
+	if got := m.Options("instructions", "security").AllowBranchUntil; got != "2025-12-31" {
+		t.Errorf("AllowBranchUntil = %q, want %q", got, "2025-12-31")
+	}
+	if !m.Options("instructions", "plain").IsZero() {
+		t.Error("plain entry should have no options")
+	}
+}
+
+func TestLoad_EntryTable_Errors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"missing ref": `[agents.a]
+allow_branch_until = "2025-12-31"
+`,
+		"bad date": `[agents.a]
+ref = "org/repo/a.md@main"
+allow_branch_until = "31/12/2025"
+`,
+		"target outside project": `[agents]
+a = { ref = "org/repo/a.md@v1", target = "../elsewhere/a.md" }
+`,
+		"absolute target": `[agents]
+a = { ref = "org/repo/a.md@v1", target = "/etc/a.md" }
+`,
+		"target in .git": `[agents]
+a = { ref = "org/repo/a.md@v1", target = "./.git/hooks/pre-commit" }
+`,
+		"blank group": `[agents]
+a = { ref = "org/repo/a.md@v1", groups = ["backend", ""] }
+`,
+		"tag with space": `[agents]
+a = { ref = "org/repo/a.md@v1", tags = ["code review"] }
+`,
+		"frontmatter table value": `[agents]
+a = { ref = "org/repo/a.md@v1", frontmatter = { model = { name = "x" } } }
+`,
+		"frontmatter on a skill": `[skills]
+k8s = { ref = "org/repo/skills/k8s@v1", frontmatter = { description = "x" } }
+`,
+		"include on an agent": `[agents]
+a = { ref = "org/repo/a.md@v1", include = ["*.md"] }
+`,
+		"malformed exclude": `[skills]
+k8s = { ref = "org/repo/skills/k8s@v1", exclude = ["scripts/[a-"] }
+`,
+		"absolute include": `[skills]
+k8s = { ref = "org/repo/skills/k8s@v1", include = ["/SKILL.md"] }
+`,
+		"hex integrity": `[agents]
+a = { ref = "org/repo/a.md@main", integrity = "sha256-4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393" }
+`,
+		"unsupported integrity algorithm": `[agents]
+a = { ref = "org/repo/a.md@main", integrity = "sha512-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" }
+`,
+		"unknown secrets mode": `[agents]
+a = { ref = "org/repo/a.md@main", secrets = "ignore" }
+`,
+		"cosign without key or identity": `[agents]
+a = { ref = "oci://ghcr.io/org/assets:v1//a.md", cosign = { issuer = "https://token.actions.githubusercontent.com" } }
+`,
+		"cosign with key and identity": `[agents]
+a = { ref = "oci://ghcr.io/org/assets:v1//a.md", cosign = { key = "cosign.pub", identity = "me@example.com", issuer = "https://accounts.google.com" } }
+`,
+	}
+	for name, content := range cases {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestSave_EntryTable_Roundtrip(t *testing.T) {
+	t.Parallel()
+	m1 := New()
+	_ = m1.Set("agents", "plain", "org/repo/plain.md@v1")
+	_ = m1.Set("agents", "tracked", "org/repo/tracked.md@main")
+	m1.SetOptions("agents", "tracked", EntryOptions{
+		AllowBranchUntil: "2025-12-31",
+		Groups:           []string{"backend", "ci-only"},
+		Description:      "Reviews backend changes",
+		Owner:            "@org/platform",
+		Tags:             []string{"review"},
+		Frontmatter:      map[string]any{"applyTo": "src/**/*.go", "infer": true},
+	})
+
+	path := tempPath(t, "copilot.toml")
+	if err := m1.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2.Agents["plain"] != "org/repo/plain.md@v1" || m2.Agents["tracked"] != "org/repo/tracked.md@main" {
+		t.Errorf("refs after roundtrip: %v", m2.Agents)
+	}
+	if got := m2.Options("agents", "tracked"); !reflect.DeepEqual(got, m1.Options("agents", "tracked")) {
+		t.Errorf("options after roundtrip = %+v", got)
+	}
+}
+
+func TestSelectsFile(t *testing.T) {
+	t.Parallel()
+	o := EntryOptions{
+		Include: []string{"*.md", "templates/**"},
+		Exclude: []string{"tests/**", "templates/*.tmp"},
+	}
+	cases := map[string]bool{
+		"SKILL.md":             true,
+		"docs/guide.md":        true,
+		"templates/a.yaml":     true,
+		"templates/deep/b.txt": true,
+		"templates/x.tmp":      false,
+		"scripts/run.sh":       false,
+		"tests/SKILL.md":       false,
+	}
+	for rel, want := range cases {
+		if got := o.SelectsFile(rel); got != want {
+			t.Errorf("SelectsFile(%q) = %v, want %v", rel, got, want)
+		}
+	}
+	if !(EntryOptions{}).SelectsFile("scripts/run.sh") {
+		t.Error("no patterns should select every file")
+	}
+}
+
+func TestRemove_ClearsOptions(t *testing.T) {
+	t.Parallel()
+	m := New()
+	_ = m.Set("agents", "a", "org/repo/a.md@main")
+	m.SetOptions("agents", "a", EntryOptions{AllowBranchUntil: "2025-12-31"})
+	if _, err := m.Remove("agents", "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Set("agents", "a", "org/repo/a.md@main")
+	if !m.Options("agents", "a").IsZero() {
+		t.Error("options should be cleared by Remove")
+	}
+}
+
+func TestEntry_TargetPath(t *testing.T) {
+	t.Parallel()
+	content := `[instructions]
+plain = "org/repo/plain.md@v1"
+setup = { ref = "org/repo/setup.md@v1", target = "docs/ai/setup.md" }
+`
+	m, err := Load(writeTempFile(t, "copilot.toml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"plain": filepath.Join(".github", "instructions", "plain.instructions.md"),
+		"setup": filepath.Join("docs", "ai", "setup.md"),
+	}
+	for name, want := range cases {
+		if got := m.TargetPath("instructions", name); got != want {
+			t.Errorf("TargetPath(%s) = %q, want %q", name, got, want)
+		}
+	}
+	for _, e := range m.AllEntries() {
+		if got := e.TargetPath(); got != cases[e.Name] {
+			t.Errorf("Entry(%s).TargetPath() = %q, want %q", e.Name, got, cases[e.Name])
+		}
+	}
+}
+
+func TestEntry_OutputRoot(t *testing.T) {
+	t.Parallel()
+	content := `output_root = "docs/ai"
+
+[instructions]
+plain = "org/repo/plain.md@v1"
+setup = { ref = "org/repo/setup.md@v1", target = "setup.md" }
+`
+	m, err := Load(writeTempFile(t, "copilot.toml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.TargetPath("instructions", "plain"), filepath.Join("docs", "ai", "instructions", "plain.instructions.md"); got != want {
+		t.Errorf("TargetPath(plain) = %q, want %q", got, want)
+	}
+	if got := m.TargetPath("instructions", "setup"); got != "setup.md" {
+		t.Errorf("TargetPath(setup) = %q, want the target option", got)
+	}
+
+	path := tempPath(t, "copilot.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if m2, err := Load(path); err != nil || m2.OutputRoot != "docs/ai" {
+		t.Errorf("output_root after roundtrip = %v, %v", m2, err)
+	}
+
+	if _, err := Load(writeTempFile(t, "copilot.toml", "output_root = \"../shared\"\n")); err == nil {
+		t.Error("output_root outside the project: expected error, got nil")
+	}
+}
+
+func TestEntriesInGroups(t *testing.T) {
+	t.Parallel()
+	content := `[agents]
+api  = { ref = "org/repo/api.md@v1", groups = ["backend"] }
+ci   = { ref = "org/repo/ci.md@v1", groups = ["ci-only", "backend"] }
+ui   = { ref = "org/repo/ui.md@v1", groups = ["frontend"] }
+base = "org/repo/base.md@v1"
+`
+	m, err := Load(writeTempFile(t, "copilot.toml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Groups(); !reflect.DeepEqual(got, []string{"backend", "ci-only", "frontend"}) {
+		t.Errorf("Groups() = %v", got)
+	}
+
+	cases := []struct {
+		groups []string
+		want   []string
+	}{
+		{nil, []string{"api", "base", "ci", "ui"}},
+		{[]string{"backend"}, []string{"api", "ci"}},
+		{[]string{"ci-only", "frontend"}, []string{"ci", "ui"}},
+	}
+	for _, tc := range cases {
+		entries, err := m.EntriesInGroups(tc.groups)
+		if err != nil {
+			t.Fatalf("EntriesInGroups(%v): %v", tc.groups, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, tc.want) {
+			t.Errorf("EntriesInGroups(%v) = %v, want %v", tc.groups, names, tc.want)
+		}
+	}
+
+	if _, err := m.EntriesInGroups([]string{"backnd"}); err == nil {
+		t.Error("EntriesInGroups(unknown): expected error")
+	}
+}
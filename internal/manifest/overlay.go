@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvVar names the environment variable selecting a manifest overlay when
+// no --env flag is given.
+const EnvVar = "COPS_ENV"
+
+// OverlayPath returns the overlay manifest for env that sits next to the
+// manifest at path, in the same format: "copilot.toml" and "ci" give
+// "copilot.ci.toml", "copilot.yaml" gives "copilot.ci.yaml".
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// LoadEnv loads the manifest at path and, if env is not empty, applies the
+// copilot.<env>.toml overlay on top of it. Unlike the base manifest, the
+// overlay must exist, so a mistyped environment is reported.
+//
+// The result merges two files and must not be saved back over either.
+func LoadEnv(path, env string) (*Manifest, error) {
+	m, err := Load(path)
+	if err != nil || env == "" {
+		return m, err
+	}
+	if strings.ContainsAny(env, `/\`) || strings.HasPrefix(env, ".") {
+		return nil, fmt.Errorf("invalid environment %q", env)
+	}
+
+	overlayPath := OverlayPath(path, env)
+	if _, err := os.Stat(overlayPath); err != nil {
+		return nil, fmt.Errorf("environment %q: overlay %s not found", env, overlayPath)
+	}
+	overlay, err := load(overlayPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q: %w", env, err)
+	}
+	// The overlay may use the base manifest's source aliases and default refs.
+	for alias, value := range m.Sources {
+		if _, ok := overlay.Sources[alias]; !ok {
+			overlay.Sources[alias] = value
+		}
+	}
+	for repo, ref := range m.DefaultRefs {
+		if _, ok := overlay.DefaultRefs[repo]; !ok {
+			overlay.DefaultRefs[repo] = ref
+		}
+	}
+	if err := overlay.checkSources(); err != nil {
+		return nil, fmt.Errorf("environment %q: %w", env, err)
+	}
+	m.Overlay(overlay)
+	return m, nil
+}
+
+// Overlay merges o into m. Entries in o are added to m or replace the
+// entry of the same type and name, options included, and so do its
+// template variables, targets, directories, output root, limits, hooks, settings and
+// collections. An overlay can make files read-only but not writable again.
+func (m *Manifest) Overlay(o *Manifest) {
+	m.ReadOnly = m.ReadOnly || o.ReadOnly
+	m.overlayVars(o)
+	m.overlayTargets(o)
+	m.overlayDirs(o)
+	m.overlayLimits(o)
+	m.overlayHooks(o)
+	m.overlaySettings(o)
+	if o.OutputRoot != "" {
+		m.OutputRoot = o.OutputRoot
+	}
+	maps.Copy(m.Collections, o.Collections)
+	for _, e := range o.AllEntries() {
+		// AllEntries only yields known types, so Set cannot fail.
+		_ = m.Set(e.Type, e.Name, e.Ref)
+		m.SetOptions(e.Type, e.Name, e.Options)
+	}
+}
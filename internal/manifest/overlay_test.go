@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverlayPath(t *testing.T) {
+	t.Parallel()
+	if got := OverlayPath(filepath.Join("proj", "copilot.toml"), "dev"); got != filepath.Join("proj", "copilot.dev.toml") {
+		t.Errorf("OverlayPath() = %q", got)
+	}
+	if got := OverlayPath("copilot.yaml", "ci"); got != "copilot.ci.yaml" {
+		t.Errorf("OverlayPath(yaml) = %q", got)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "copilot.toml")
+	files := map[string]string{
+		"copilot.toml": `[prompts]
+review = { ref = "org/repo/review.md@v1.0", groups = ["ci"] }
+tests  = "org/repo/tests.md@v1.0"
+`,
+		"copilot.dev.toml": `[prompts]
+review = "org/repo/review.md@experimental"
+
+[agents]
+scratch = "org/repo/scratch.md@main"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := LoadEnv(base, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Prompts["review"] != "org/repo/review.md@v1.0" || len(m.Agents) != 0 {
+		t.Errorf("no env: got %v / %v", m.Prompts, m.Agents)
+	}
+
+	m, err = LoadEnv(base, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Prompts["review"] != "org/repo/review.md@experimental" {
+		t.Errorf("overridden entry = %q", m.Prompts["review"])
+	}
+	if !m.Options("prompts", "review").IsZero() {
+		t.Errorf("override should replace options, got %+v", m.Options("prompts", "review"))
+	}
+	if m.Prompts["tests"] != "org/repo/tests.md@v1.0" || m.Agents["scratch"] != "org/repo/scratch.md@main" {
+		t.Errorf("merged manifest = %v / %v", m.Prompts, m.Agents)
+	}
+
+	for _, env := range []string{"staging", "../dev", ".hidden"} {
+		if _, err := LoadEnv(base, env); err == nil {
+			t.Errorf("LoadEnv(%q): expected error", env)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// DefaultPolicyFile is the source policy of a project, next to its
+// manifest.
+const DefaultPolicyFile = ".cops-policy.toml"
+
+// PolicyEnvVar names the environment variable overriding the path of the
+// organisation-wide source policy.
+const PolicyEnvVar = "COPS_POLICY"
+
+// Policy restricts where the assets of a project may come from:
+//
+//	[allow]
+//	orgs  = ["my-org"]
+//	repos = ["github/awesome-copilot"]
+//	hosts = ["ghcr.io"]
+//
+//	[deny]
+//	repos = ["my-org/experiments"]
+//
+//	[licenses]
+//	allow = ["MIT", "Apache-2.0"]
+//
+// A reference is refused if it matches a deny rule or, when any allow rule
+// is set, none of them. An asset is refused if its repository's license
+// is not one of the allowed licenses, when some are listed.
+type Policy struct {
+	Allow    PolicyRules  `toml:"allow"`
+	Deny     PolicyRules  `toml:"deny"`
+	Licenses LicenseRules `toml:"licenses"`
+
+	path string // file the policy was read from
+}
+
+// PolicyRules match references by where they come from. Names are
+// compared case-insensitively.
+type PolicyRules struct {
+	// Orgs are GitHub organisations or users, matching the GitHub and
+	// release references of their repositories.
+	Orgs []string `toml:"orgs"`
+
+	// Repos are repositories as config.AssetRef.RepoFullName spells them:
+	// "org/repo", "registry/repository" for OCI artifacts, "s3://bucket"
+	// or "registry:package".
+	Repos []string `toml:"repos"`
+
+	// Hosts are the hosts references are fetched from, e.g. "github.com"
+	// or "ghcr.io".
+	Hosts []string `toml:"hosts"`
+}
+
+// LicenseRules restrict the licenses of the repositories assets come
+// from.
+type LicenseRules struct {
+	// Allow lists the SPDX identifiers accepted, e.g. "MIT", compared
+	// case-insensitively. "NONE" accepts repositories without a license and
+	// "NOASSERTION" those whose license GitHub does not recognise.
+	Allow []string `toml:"allow"`
+}
+
+// empty reports whether r has no rule.
+func (r PolicyRules) empty() bool {
+	return len(r.Orgs) == 0 && len(r.Repos) == 0 && len(r.Hosts) == 0
+}
+
+// match returns the rule of r that ref matches, or "".
+func (r PolicyRules) match(ref config.AssetRef) string {
+	fold := func(name string) func(string) bool {
+		return func(rule string) bool { return name != "" && strings.EqualFold(rule, name) }
+	}
+	if ref.IsGitHub() || ref.IsRelease() {
+		if slices.ContainsFunc(r.Orgs, fold(ref.Org)) {
+			return "org " + ref.Org
+		}
+	}
+	if slices.ContainsFunc(r.Repos, fold(ref.RepoFullName())) {
+		return "repo " + ref.RepoFullName()
+	}
+	if slices.ContainsFunc(r.Hosts, fold(ref.Host())) {
+		return "host " + ref.Host()
+	}
+	return ""
+}
+
+// OrgPolicyPath returns the organisation-wide source policy applied to
+// every project, e.g. ~/.config/cops/policy.toml on Linux, or $COPS_POLICY.
+// It returns "" if no user configuration directory is available.
+func OrgPolicyPath() string {
+	if path := os.Getenv(PolicyEnvVar); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cops", "policy.toml")
+}
+
+// LoadPolicy reads a policy file. A missing file yields a nil policy,
+// which allows everything. Unknown keys are rejected, so a misspelt rule
+// is not silently ignored.
+func LoadPolicy(path string) (*Policy, error) {
+	p := &Policy{path: path}
+	md, err := toml.DecodeFile(path, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading policy %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("reading policy %s: unknown key %q", path, undecoded[0].String())
+	}
+	return p, nil
+}
+
+// Policies are the source policies a project is subject to; a reference
+// must pass all of them.
+type Policies []*Policy
+
+// LoadPolicies reads the policy of the project at rootDir and the
+// organisation-wide one at orgPath (see OrgPolicyPath), skipping those
+// that do not exist.
+func LoadPolicies(rootDir, orgPath string) (Policies, error) {
+	var ps Policies
+	for _, path := range []string{filepath.Join(rootDir, DefaultPolicyFile), orgPath} {
+		if path == "" {
+			continue
+		}
+		p, err := LoadPolicy(path)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			ps = append(ps, p)
+		}
+	}
+	return ps, nil
+}
+
+// Check reports an error if a policy refuses the reference raw.
+func (ps Policies) Check(raw string) error {
+	if len(ps) == 0 {
+		return nil
+	}
+	ref, err := config.ParseRef(raw)
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		if rule := p.Deny.match(ref); rule != "" {
+			return fmt.Errorf("%s is refused by %s: %s is denied", raw, p.path, rule)
+		}
+		if !p.Allow.empty() && p.Allow.match(ref) == "" {
+			return fmt.Errorf("%s is refused by %s: its source is not allowed", raw, p.path)
+		}
+	}
+	return nil
+}
+
+// Guard returns fetch, refusing the references ps refuses, so templates
+// pulled in with extends come from allowed sources too.
+func (ps Policies) Guard(fetch Fetcher) Fetcher {
+	if len(ps) == 0 || fetch == nil {
+		return fetch
+	}
+	return func(ref string) ([]byte, error) {
+		if err := ps.Check(ref); err != nil {
+			return nil, err
+		}
+		return fetch(ref)
+	}
+}
+
+// RestrictsLicenses reports whether a policy only allows some licenses.
+func (ps Policies) RestrictsLicenses() bool {
+	return slices.ContainsFunc(ps, func(p *Policy) bool { return len(p.Licenses.Allow) > 0 })
+}
+
+// CheckLicense reports an error if a policy refuses assets from
+// repositories under license, an SPDX identifier.
+func (ps Policies) CheckLicense(license string) error {
+	for _, p := range ps {
+		if len(p.Licenses.Allow) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(p.Licenses.Allow, func(allowed string) bool { return strings.EqualFold(allowed, license) }) {
+			return fmt.Errorf("license %s is refused by %s: allowed licenses are %s", license, p.path, strings.Join(p.Licenses.Allow, ", "))
+		}
+	}
+	return nil
+}
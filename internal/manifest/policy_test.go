@@ -0,0 +1,106 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	p, err := LoadPolicy(filepath.Join(dir, "missing.toml"))
+	if err != nil || p != nil {
+		t.Errorf("LoadPolicy(missing) = %v, %v; want nil, nil", p, err)
+	}
+
+	path := filepath.Join(dir, "policy.toml")
+	if err := os.WriteFile(path, []byte("[allow]\nrepo = [\"org/repo\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(path); err == nil || !strings.Contains(err.Error(), "allow.repo") {
+		t.Errorf("LoadPolicy(misspelt key) error = %v, want the unknown key", err)
+	}
+}
+
+func TestPolicies_Check(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		DefaultPolicyFile: `[allow]
+orgs  = ["My-Org"]
+repos = ["github/awesome-copilot"]
+hosts = ["ghcr.io"]
+
+[deny]
+repos = ["my-org/experiments"]
+`,
+		"org.toml": `[deny]
+repos = ["my-org/legacy"]
+`,
+	})
+	ps, err := LoadPolicies(dir, filepath.Join(dir, "org.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("LoadPolicies() = %d policies, want 2", len(ps))
+	}
+
+	cases := []struct {
+		ref  string
+		want string // substring of the error, "" if allowed
+	}{
+		{"my-org/skills/review.md@v1", ""},
+		{"my-org/skills!release:v1/review.md", ""},
+		{"github/awesome-copilot/prompts/a.md@main", ""},
+		{"oci://ghcr.io/team/assets:v1//review.md", ""},
+		{"my-org/experiments/a.md@main", "repo my-org/experiments is denied"},
+		{"evil/repo/a.md@main", "not allowed"},
+		{"my-org/legacy/a.md@main", "org.toml: repo my-org/legacy is denied"},
+		{"not-a-ref", "invalid"},
+	}
+	for _, tc := range cases {
+		err := ps.Check(tc.ref)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("Check(%q) = %v, want allowed", tc.ref, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("Check(%q) = %v, want %q", tc.ref, err, tc.want)
+		}
+	}
+
+	if err := Policies(nil).Check("evil/repo/a.md@main"); err != nil {
+		t.Errorf("Check() without policies = %v", err)
+	}
+}
+
+func TestPolicies_CheckLicense(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		DefaultPolicyFile: "[licenses]\nallow = [\"MIT\", \"Apache-2.0\", \"NONE\"]\n",
+		"org.toml":        "[deny]\nrepos = [\"my-org/legacy\"]\n",
+	})
+	ps, err := LoadPolicies(dir, filepath.Join(dir, "org.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ps.RestrictsLicenses() {
+		t.Error("RestrictsLicenses() = false, want true")
+	}
+	for _, license := range []string{"MIT", "apache-2.0", "NONE"} {
+		if err := ps.CheckLicense(license); err != nil {
+			t.Errorf("CheckLicense(%q) = %v, want allowed", license, err)
+		}
+	}
+	for _, license := range []string{"GPL-3.0", "NOASSERTION"} {
+		if err := ps.CheckLicense(license); err == nil || !strings.Contains(err.Error(), "MIT, Apache-2.0, NONE") {
+			t.Errorf("CheckLicense(%q) = %v, want the allowed licenses", license, err)
+		}
+	}
+
+	if ps[1:].RestrictsLicenses() || ps[1:].CheckLicense("GPL-3.0") != nil {
+		t.Error("a policy without [licenses] restricts licenses")
+	}
+}
@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// position is a 1-based line and column in a manifest file.
+type position struct {
+	line, col int
+}
+
+// positions records where each key of a manifest document is written,
+// keyed by its path from the document root (e.g. "agents", "reviewer",
+// "ref").
+type positions map[string]position
+
+func positionKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// add records the position of path unless an earlier one was recorded.
+func (p positions) add(path []string, line, col int) {
+	key := positionKey(path)
+	if _, ok := p[key]; !ok {
+		p[key] = position{line, col}
+	}
+}
+
+// at returns the position of path, or of its closest recorded ancestor.
+// The zero position means the location is unknown.
+func (p positions) at(path ...string) position {
+	for n := len(path); n > 0; n-- {
+		if pos, ok := p[positionKey(path[:n])]; ok {
+			return pos
+		}
+	}
+	return position{}
+}
+
+// offsetPosition converts a byte offset in data to a position.
+func offsetPosition(data []byte, offset int) position {
+	before := data[:min(max(offset, 0), len(data))]
+	return position{
+		line: bytes.Count(before, []byte("\n")) + 1,
+		col:  len(before) - bytes.LastIndexByte(before, '\n'),
+	}
+}
+
+var (
+	// tomlKeyLine matches the key of a "key = value" line; keys may be
+	// dotted and quoted.
+	tomlKeyLine = regexp.MustCompile(`^((?:"[^"]*"|'[^']*'|[A-Za-z0-9_.\- \t])+)=`)
+	// tomlInlineKey matches a key inside an inline table.
+	tomlInlineKey = regexp.MustCompile(`[{,]\s*("[^"]*"|'[^']*'|[A-Za-z0-9_-]+)\s*=`)
+)
+
+// tomlKeyPositions locates the keys of a TOML document that toml.Decode
+// accepted. The TOML decoder does not expose key positions, so table
+// headers, "key = value" lines and single-line inline tables are scanned
+// directly.
+func tomlKeyPositions(src string) positions {
+	pos := make(positions)
+	var table []string
+	for i, line := range strings.Split(src, "\n") {
+		text := strings.TrimRight(line, "\r")
+		trimmed := strings.TrimLeft(text, " \t")
+		col := len(text) - len(trimmed) + 1
+
+		if strings.HasPrefix(trimmed, "[") {
+			header := strings.TrimPrefix(trimmed[1:], "[")
+			if end := strings.IndexByte(header, ']'); end >= 0 {
+				table = splitTOMLKey(header[:end])
+				pos.add(table, i+1, col+len(trimmed)-len(header))
+			}
+			continue
+		}
+		m := tomlKeyLine.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		key := append(slices.Clone(table), splitTOMLKey(m[1])...)
+		pos.add(key, i+1, col)
+
+		value := trimmed[len(m[0]):]
+		if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+			continue
+		}
+		valueCol := col + len(m[0])
+		for _, idx := range tomlInlineKey.FindAllStringSubmatchIndex(value, -1) {
+			inner := append(slices.Clone(key), splitTOMLKey(value[idx[2]:idx[3]])...)
+			pos.add(inner, i+1, valueCol+idx[2])
+		}
+	}
+	return pos
+}
+
+// splitTOMLKey splits a dotted TOML key into its unquoted parts.
+func splitTOMLKey(s string) []string {
+	var parts []string
+	var cur strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(parts, strings.TrimSpace(cur.String()))
+}
+
+// jsonKeyPositions locates the object keys of a valid JSON document.
+func jsonKeyPositions(data []byte) positions {
+	pos := make(positions)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(path []string) error
+	walk = func(path []string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := tok.(string)
+				// The offset is just past the key's closing quote.
+				quoted, _ := json.Marshal(key)
+				p := offsetPosition(data, int(dec.InputOffset())-len(quoted))
+				child := append(slices.Clone(path), key)
+				pos.add(child, p.line, p.col)
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		case json.Delim('['):
+			for dec.More() {
+				if err := walk(path); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+			return err
+		}
+		return nil
+	}
+	_ = walk(nil)
+	return pos
+}
@@ -0,0 +1,64 @@
+package manifest
+
+import "fmt"
+
+// MaxParallelism caps [settings] parallelism, to stay well within API
+// rate limits.
+const MaxParallelism = 16
+
+// Values of [settings] eol: the line endings of the text files written.
+const (
+	EOLLF   = "lf"   // "\n"
+	EOLCRLF = "crlf" // "\r\n"
+)
+
+// Settings holds the [settings] section: project-wide defaults for how
+// assets are downloaded and written, so they need not be repeated on every
+// run.
+type Settings struct {
+	// Parallelism is how many files of a skill are downloaded at once.
+	// Zero downloads them one at a time.
+	Parallelism int `toml:"parallelism,omitempty" json:"parallelism,omitempty"`
+
+	// EOL converts the line endings of the text files written to EOLLF or
+	// EOLCRLF. Empty keeps them as downloaded.
+	EOL string `toml:"eol,omitempty" json:"eol,omitempty"`
+
+	// Banner starts every Markdown file written with a comment saying
+	// where it comes from and that local edits are overwritten.
+	Banner bool `toml:"banner,omitempty" json:"banner,omitempty"`
+}
+
+// checkSettings validates the [settings] section.
+func checkSettings(s Settings) error {
+	if s.Parallelism < 0 || s.Parallelism > MaxParallelism {
+		return fmt.Errorf("settings: invalid parallelism %d: must be between 1 and %d", s.Parallelism, MaxParallelism)
+	}
+	switch s.EOL {
+	case "", EOLLF, EOLCRLF:
+		return nil
+	default:
+		return fmt.Errorf("settings: invalid eol %q: must be %q or %q", s.EOL, EOLLF, EOLCRLF)
+	}
+}
+
+// settingsSection returns the on-disk [settings] section, or nil if
+// nothing is set.
+func (m *Manifest) settingsSection() *Settings {
+	if m.Settings == (Settings{}) {
+		return nil
+	}
+	return &m.Settings
+}
+
+// overlaySettings sets the settings o defines in m, keeping the others.
+// Like readonly, a banner can be turned on by an overlay but not off.
+func (m *Manifest) overlaySettings(o *Manifest) {
+	if o.Settings.Parallelism != 0 {
+		m.Settings.Parallelism = o.Settings.Parallelism
+	}
+	if o.Settings.EOL != "" {
+		m.Settings.EOL = o.Settings.EOL
+	}
+	m.Settings.Banner = m.Settings.Banner || o.Settings.Banner
+}
@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `[settings]
+parallelism = 8
+eol         = "lf"
+banner      = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{Parallelism: 8, EOL: EOLLF, Banner: true}
+	if m.Settings != want {
+		t.Errorf("Settings = %+v, want %+v", m.Settings, want)
+	}
+
+	for _, name := range []string{"copilot.yaml", "copilot.json", "copilot.toml"} {
+		path := tempPath(t, name)
+		if err := m.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if m2.Settings != want {
+			t.Errorf("%s: settings after roundtrip = %+v, want %+v", name, m2.Settings, want)
+		}
+	}
+
+	for _, content := range []string{
+		"[settings]\nparallelism = -1\n",
+		"[settings]\nparallelism = 17\n",
+		"[settings]\neol = \"cr\"\n",
+	} {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("Load(%q): expected error, got nil", content)
+		}
+	}
+}
+
+func TestSettings_Overlay(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"copilot.toml":     "[settings]\nparallelism = 4\nbanner = true\n",
+		"copilot.ci.toml":  "[settings]\neol = \"crlf\"\n",
+		"copilot.dev.toml": "[settings]\nparallelism = 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for env, want := range map[string]Settings{
+		"ci":  {Parallelism: 4, EOL: EOLCRLF, Banner: true},
+		"dev": {Parallelism: 1, Banner: true},
+	} {
+		m, err := LoadEnv(filepath.Join(dir, "copilot.toml"), env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Settings != want {
+			t.Errorf("%s: Settings = %+v, want %+v", env, m.Settings, want)
+		}
+	}
+}
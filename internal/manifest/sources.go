@@ -0,0 +1,189 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// aliasPattern matches valid [sources] alias names.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedAliases cannot be used as aliases because they prefix other
+// reference forms.
+var reservedAliases = []string{"registry", "local", "plugin", "oci", "s3", "gs", "http", "https"}
+
+// source is a parsed [sources] value: "org/repo[/path][@ref]".
+type source struct {
+	repo string // "org/repo", plus an optional path prefix
+	ref  string // default ref, may be empty
+}
+
+func parseSource(alias, value string) (source, error) {
+	if !aliasPattern.MatchString(alias) || slices.Contains(reservedAliases, alias) {
+		return source{}, fmt.Errorf("invalid source alias %q", alias)
+	}
+	repo, ref, _ := strings.Cut(value, "@")
+	repo = strings.Trim(repo, "/")
+	if parts := strings.SplitN(repo, "/", 3); len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return source{}, fmt.Errorf("source %q: %q must be org/repo[/path][@ref]", alias, value)
+	}
+	return source{repo: repo, ref: ref}, nil
+}
+
+// userSources holds the aliases of the user configuration, set by
+// SetUserSources.
+var userSources map[string]string
+
+// SetUserSources makes aliases, the [sources] table of the user
+// configuration, known to every manifest. A manifest's own [sources] wins
+// over an alias of the same name, and Save never writes them.
+func SetUserSources(aliases map[string]string) error {
+	for _, alias := range SortedKeys(aliases) {
+		if _, err := parseSource(alias, aliases[alias]); err != nil {
+			return err
+		}
+	}
+	userSources = aliases
+	return nil
+}
+
+// splitAlias splits "alias:path[@ref]" references. It reports false for
+// every other reference form, including "registry:" and URL schemes.
+func splitAlias(raw string) (alias, rest string, ok bool) {
+	alias, rest, ok = strings.Cut(raw, ":")
+	if !ok || !aliasPattern.MatchString(alias) || slices.Contains(reservedAliases, alias) || strings.HasPrefix(rest, "//") {
+		return "", "", false
+	}
+	return alias, rest, true
+}
+
+// ExpandRef resolves a manifest reference before it is parsed:
+//
+//   - "alias:path[@ref]" is rewritten with the [sources] table, or the
+//     user aliases (see SetUserSources), into "org/repo/path@ref"; the
+//     entry's ref, if any, overrides the source's.
+//   - a GitHub reference without "@ref" takes the default_ref of its
+//     "org/repo", if one is set.
+//
+// Other references are returned unchanged.
+func (m *Manifest) ExpandRef(raw string) (string, error) {
+	expanded := raw
+	aliased := false
+	if alias, rest, ok := splitAlias(raw); ok {
+		value, known := m.Sources[alias]
+		if !known {
+			value, known = userSources[alias]
+		}
+		if !known {
+			return "", fmt.Errorf("reference %q: unknown source alias %q (declare it under [sources])", raw, alias)
+		}
+		src, err := parseSource(alias, value)
+		if err != nil {
+			return "", err
+		}
+		path, ref, _ := strings.Cut(rest, "@")
+		path = strings.Trim(path, "/")
+		if path == "" {
+			return "", fmt.Errorf("reference %q: missing path after %q", raw, alias+":")
+		}
+		if ref == "" {
+			ref = src.ref
+		}
+		expanded = src.repo + "/" + path
+		if ref != "" {
+			expanded += "@" + ref
+		}
+		aliased = true
+	}
+
+	// Every non-GitHub reference form contains a colon.
+	if strings.Contains(expanded, "@") || strings.Contains(expanded, ":") {
+		return expanded, nil
+	}
+	if parts := strings.SplitN(expanded, "/", 3); len(parts) == 3 {
+		if ref, ok := m.DefaultRefs[parts[0]+"/"+parts[1]]; ok {
+			return expanded + "@" + ref, nil
+		}
+	}
+	if aliased {
+		return "", fmt.Errorf("reference %q: no ref given, and neither the source nor default_ref provides one", raw)
+	}
+	return expanded, nil
+}
+
+// checkSources validates the [sources] and default_ref tables and that
+// every local entry using an alias expands.
+func (m *Manifest) checkSources() error {
+	for _, repo := range SortedKeys(m.DefaultRefs) {
+		if err := checkDefaultRef(repo, m.DefaultRefs[repo]); err != nil {
+			return err
+		}
+	}
+	for _, alias := range SortedKeys(m.Sources) {
+		if _, err := parseSource(alias, m.Sources[alias]); err != nil {
+			return err
+		}
+	}
+	for _, s := range m.sections() {
+		for _, name := range SortedKeys(s.section) {
+			if _, err := m.ExpandRef(s.section[name]); err != nil {
+				return fmt.Errorf("%s/%s: %w", s.assetType, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDefaultRef validates a default_ref entry.
+func checkDefaultRef(repo, ref string) error {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || ref == "" {
+		return fmt.Errorf("invalid default_ref.%q = %q: keys must be \"org/repo\" and values a ref", repo, ref)
+	}
+	return nil
+}
+
+// RenameRepo rewrites every reference to the GitHub repository from
+// ("org/repo", compared case-insensitively) to point at to instead: the
+// entries of the manifest's own sections, the [sources] aliases and the
+// default_ref keys. It returns the number of values rewritten.
+func (m *Manifest) RenameRepo(from, to string) int {
+	n := 0
+	for _, s := range m.sections() {
+		for name, ref := range s.section {
+			if renamed, ok := renameRepoPrefix(ref, from, to); ok {
+				s.section[name] = renamed
+				n++
+			}
+		}
+	}
+	for alias, value := range m.Sources {
+		if renamed, ok := renameRepoPrefix(value, from, to); ok {
+			m.Sources[alias] = renamed
+			n++
+		}
+	}
+	for repo, ref := range m.DefaultRefs {
+		if strings.EqualFold(repo, from) {
+			delete(m.DefaultRefs, repo)
+			m.DefaultRefs[to] = ref
+			n++
+		}
+	}
+	return n
+}
+
+// renameRepoPrefix replaces the repository from at the start of a GitHub,
+// release or [sources] reference with to.
+func renameRepoPrefix(raw, from, to string) (string, bool) {
+	if len(raw) < len(from) || !strings.EqualFold(raw[:len(from)], from) {
+		return "", false
+	}
+	rest := raw[len(from):]
+	if rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "@") && !strings.HasPrefix(rest, "!release:") {
+		return "", false
+	}
+	return to + rest, true
+}
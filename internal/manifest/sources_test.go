@@ -0,0 +1,197 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandRef(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.Sources["awesome"] = "github/awesome-copilot@v2"
+	m.Sources["std"] = "my-org/standards/copilot"
+
+	cases := []struct {
+		raw, want string
+		wantErr   bool
+	}{
+		{"awesome:instructions/code-review.md", "github/awesome-copilot/instructions/code-review.md@v2", false},
+		{"awesome:instructions/code-review.md@v3", "github/awesome-copilot/instructions/code-review.md@v3", false},
+		{"std:security.md@main", "my-org/standards/copilot/security.md@main", false},
+		{"org/repo/path.md@v1", "org/repo/path.md@v1", false},
+		{"registry:awesome/review@1.0.0", "registry:awesome/review@1.0.0", false},
+		{"s3://bucket/key.md", "s3://bucket/key.md", false},
+		{"https://example.com/a.md", "https://example.com/a.md", false},
+		{"org/repo!release:v1/asset.md", "org/repo!release:v1/asset.md", false},
+		{"std:security.md", "", true}, // no ref anywhere
+		{"nope:path.md@v1", "", true},
+		{"awesome:@v1", "", true},
+	}
+	for _, tc := range cases {
+		got, err := m.ExpandRef(tc.raw)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ExpandRef(%q) = %q, %v; want %q, err=%v", tc.raw, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestLoad_Sources(t *testing.T) {
+	t.Parallel()
+	content := `[sources]
+awesome = "github/awesome-copilot@v2"
+
+[instructions]
+review = "awesome:instructions/code-review.md"
+`
+	path := writeTempFile(t, "copilot.toml", content)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := m.AllEntries()
+	if len(entries) != 1 || entries[0].Ref != "github/awesome-copilot/instructions/code-review.md@v2" {
+		t.Errorf("AllEntries() = %+v, want expanded ref", entries)
+	}
+
+	// Saving keeps the alias form.
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if data := string(readBytes(t, path)); !strings.Contains(data, `"awesome:instructions/code-review.md"`) || !strings.Contains(data, "[sources]") {
+		t.Errorf("saved manifest lost the alias:\n%s", data)
+	}
+}
+
+func TestLoad_SourcesErrors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"unknown alias":  "[agents]\na = \"nope:a.md@v1\"\n",
+		"reserved alias": "[sources]\nregistry = \"org/repo@v1\"\n",
+		"bad source":     "[sources]\nx = \"just-a-name\"\n",
+	}
+	for name, content := range cases {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestSetUserSources is not parallel: the user aliases are global.
+func TestSetUserSources(t *testing.T) {
+	t.Cleanup(func() { userSources = nil })
+	if err := SetUserSources(map[string]string{"registry": "org/repo@v1"}); err == nil {
+		t.Error("SetUserSources(): expected error for a reserved alias")
+	}
+	if err := SetUserSources(map[string]string{"mine": "me/prompts@main", "awesome": "someone/else@v1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeTempFile(t, "copilot.toml", `[sources]
+awesome = "github/awesome-copilot@v2"
+
+[prompts]
+review = "mine:review.md"
+audit  = "awesome:audit.md"
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := make(map[string]string)
+	for _, e := range m.AllEntries() {
+		refs[e.Type+"/"+e.Name] = e.Ref
+	}
+	if refs["prompts/review"] != "me/prompts/review.md@main" || refs["prompts/audit"] != "github/awesome-copilot/audit.md@v2" {
+		t.Errorf("Refs() = %v; want the user alias, and the manifest's own over the user's", refs)
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if data := string(readBytes(t, path)); strings.Contains(data, "me/prompts") {
+		t.Errorf("saved manifest holds a user alias:\n%s", data)
+	}
+}
+
+func TestExpandRef_DefaultRef(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.Sources["std"] = "my-org/standards/copilot"
+	m.DefaultRefs["my-org/standards"] = "v3.1.0"
+
+	cases := []struct {
+		raw, want string
+		wantErr   bool
+	}{
+		{"my-org/standards/security.md", "my-org/standards/security.md@v3.1.0", false},
+		{"my-org/standards/security.md@main", "my-org/standards/security.md@main", false},
+		{"std:security.md", "my-org/standards/copilot/security.md@v3.1.0", false},
+		{"other/repo/a.md", "other/repo/a.md", false}, // left for ParseRef to reject
+	}
+	for _, tc := range cases {
+		got, err := m.ExpandRef(tc.raw)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ExpandRef(%q) = %q, %v; want %q", tc.raw, got, err, tc.want)
+		}
+	}
+}
+
+func TestLoad_DefaultRef(t *testing.T) {
+	t.Parallel()
+	content := `default_ref."my-org/standards" = "v3.1.0"
+
+[instructions]
+security = "my-org/standards/security.md"
+`
+	path := writeTempFile(t, "copilot.toml", content)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := m.AllEntries(); len(e) != 1 || e[0].Ref != "my-org/standards/security.md@v3.1.0" {
+		t.Errorf("AllEntries() = %+v", e)
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.DefaultRefs["my-org/standards"] != "v3.1.0" || reloaded.Instructions["security"] != "my-org/standards/security.md" {
+		t.Errorf("after roundtrip: %v / %v", reloaded.DefaultRefs, reloaded.Instructions)
+	}
+
+	if _, err := Load(writeTempFile(t, "copilot.toml", `default_ref."just-org" = "v1"`)); err == nil {
+		t.Error("Load: expected error for a default_ref key that is not org/repo")
+	}
+}
+
+func TestRenameRepo(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.Instructions["review"] = "Old-Org/Tools/review.md@v1"
+	m.Instructions["other"] = "old-org/tools-extra/review.md@v1"
+	m.Agents["release"] = "old-org/tools!release:v2/agent.md"
+	m.Prompts["url"] = "https://example.com/old-org/tools/p.md"
+	m.Sources["tools"] = "old-org/tools@v1"
+	m.DefaultRefs["old-org/tools"] = "main"
+
+	if n := m.RenameRepo("old-org/tools", "new-org/kit"); n != 4 {
+		t.Errorf("RenameRepo() = %d, want 4", n)
+	}
+	cases := []struct{ got, want string }{
+		{m.Instructions["review"], "new-org/kit/review.md@v1"},
+		{m.Instructions["other"], "old-org/tools-extra/review.md@v1"},
+		{m.Agents["release"], "new-org/kit!release:v2/agent.md"},
+		{m.Prompts["url"], "https://example.com/old-org/tools/p.md"},
+		{m.Sources["tools"], "new-org/kit@v1"},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+	if _, ok := m.DefaultRefs["old-org/tools"]; ok || m.DefaultRefs["new-org/kit"] != "main" {
+		t.Errorf("DefaultRefs = %v, want the key renamed", m.DefaultRefs)
+	}
+}
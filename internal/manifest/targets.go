@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// namePlaceholder in a [targets] path stands for the entry name.
+const namePlaceholder = "{name}"
+
+// Outputs returns the extra locations the entry is written to, besides its
+// target, as paths relative to the project root: first the mirrors [dirs]
+// lists, then the [targets] in byte-wise order of their name. A target
+// path containing {name} receives a copy of the asset; one without is a
+// file shared by every entry of the type, which gets the asset as a
+// managed section.
+func (m *Manifest) Outputs(assetType, name string) (copies, sections []string) {
+	copies = m.mirrors(assetType, name)
+	for _, target := range SortedKeys(m.Targets) {
+		pattern, ok := m.Targets[target][assetType]
+		if !ok {
+			continue
+		}
+		path := filepath.FromSlash(strings.ReplaceAll(pattern, namePlaceholder, name))
+		if strings.Contains(pattern, namePlaceholder) {
+			copies = append(copies, path)
+		} else {
+			sections = append(sections, path)
+		}
+	}
+	return copies, sections
+}
+
+// checkTargets validates the [targets] tables.
+func checkTargets(targets map[string]map[string]string) error {
+	for _, target := range SortedKeys(targets) {
+		for _, assetType := range SortedKeys(targets[target]) {
+			if err := checkTarget(target, assetType, targets[target][assetType]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTarget validates the path pattern of assetType in the target named
+// target.
+func checkTarget(target, assetType, pattern string) error {
+	if !namePattern.MatchString(target) {
+		return fmt.Errorf("invalid target name %q", target)
+	}
+	t := config.AssetType(assetType)
+	if !t.IsValid() {
+		return fmt.Errorf("targets.%s: unknown asset type %q", target, assetType)
+	}
+	path := strings.ReplaceAll(pattern, namePlaceholder, "x")
+	if !projectPath(path) {
+		return fmt.Errorf("targets.%s.%s: %q must be a relative path inside the project, out of .git", target, assetType, pattern)
+	}
+	if t.IsDirectory() && !strings.Contains(pattern, namePlaceholder) {
+		return fmt.Errorf("targets.%s.%s: %q must contain %s", target, assetType, pattern, namePlaceholder)
+	}
+	return nil
+}
+
+// checkOutputRoot validates the output_root setting.
+func checkOutputRoot(root string) error {
+	if root != "" && !projectPath(root) {
+		return fmt.Errorf("invalid output_root %q: must be a relative path inside the project, out of .git", root)
+	}
+	return nil
+}
+
+// overlayTargets sets the targets of o in m, replacing those of the same
+// name.
+func (m *Manifest) overlayTargets(o *Manifest) {
+	if len(o.Targets) == 0 {
+		return
+	}
+	if m.Targets == nil {
+		m.Targets = make(map[string]map[string]string, len(o.Targets))
+	}
+	maps.Copy(m.Targets, o.Targets)
+}
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `[targets.cursor]
+instructions = ".cursor/rules/{name}.mdc"
+skills       = ".cursor/skills/{name}"
+
+[targets.claude]
+instructions = "CLAUDE.md"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copies, sections := m.Outputs("instructions", "go")
+	if !slices.Equal(copies, []string{filepath.Join(".cursor", "rules", "go.mdc")}) || !slices.Equal(sections, []string{"CLAUDE.md"}) {
+		t.Errorf("Outputs(instructions, go) = %v, %v", copies, sections)
+	}
+	if copies, sections := m.Outputs("prompts", "review"); copies != nil || sections != nil {
+		t.Errorf("Outputs(prompts, review) = %v, %v, want none", copies, sections)
+	}
+
+	path := tempPath(t, "copilot.yaml")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m2.Targets, m.Targets) {
+		t.Errorf("targets after roundtrip = %v", m2.Targets)
+	}
+}
+
+func TestLoad_TargetErrors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"unknown type":       "[targets.cursor]\nrules = \".cursor/rules/{name}.mdc\"\n",
+		"outside project":    "[targets.cursor]\ninstructions = \"../rules/{name}.md\"\n",
+		"skill without name": "[targets.claude]\nskills = \".claude/skills\"\n",
+		"invalid name":       "[targets.\"my tool\"]\nagents = \"x/{name}.md\"\n",
+	}
+	for name, content := range cases {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
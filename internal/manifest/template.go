@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+)
+
+// templateFile is the on-disk shape of the [template] section.
+type templateFile struct {
+	// Vars holds the values substituted for {{name}} placeholders.
+	Vars map[string]string `toml:"vars,omitempty" json:"vars,omitempty"`
+}
+
+// varNamePattern matches the names of template variables.
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkVar validates a [template.vars] name.
+func checkVar(name string) error {
+	if !varNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid template variable %q: use letters, digits and '_'", name)
+	}
+	return nil
+}
+
+// checkVars validates the [template.vars] table.
+func checkVars(vars map[string]string) error {
+	for _, name := range SortedKeys(vars) {
+		if err := checkVar(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateSection returns the on-disk [template] section, or nil if no
+// variable is set.
+func (m *Manifest) templateSection() *templateFile {
+	if len(m.Vars) == 0 {
+		return nil
+	}
+	return &templateFile{Vars: m.Vars}
+}
+
+// TemplateVars returns the template variables substituted in downloaded
+// assets: those of the manifest and its overlay, over those of the
+// template it extends and the user-level manifest (see Underlay).
+func (m *Manifest) TemplateVars() map[string]string {
+	var vars map[string]string
+	if m.inherited != nil {
+		vars = maps.Clone(m.inherited.Vars)
+	}
+	if vars == nil {
+		vars = make(map[string]string, len(m.Vars))
+	}
+	maps.Copy(vars, m.Vars)
+	return vars
+}
+
+// underlayVars adds the variables of base that m does not define yet to
+// the inherited ones, which Save never writes.
+func (m *Manifest) underlayVars(base *Manifest) {
+	defined := m.TemplateVars()
+	for name, value := range base.TemplateVars() {
+		if _, ok := defined[name]; ok {
+			continue
+		}
+		if m.inherited.Vars == nil {
+			m.inherited.Vars = make(map[string]string)
+		}
+		m.inherited.Vars[name] = value
+	}
+}
+
+// overlayVars sets the variables of o in m, keeping the others.
+func (m *Manifest) overlayVars(o *Manifest) {
+	if len(o.Vars) == 0 {
+		return
+	}
+	if m.Vars == nil {
+		m.Vars = make(map[string]string, len(o.Vars))
+	}
+	maps.Copy(m.Vars, o.Vars)
+}
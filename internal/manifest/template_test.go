@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_TemplateVars(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `[template.vars]
+project  = "billing"
+language = "Go"
+
+[instructions]
+style = "org/repo/style.md@v1"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"project": "billing", "language": "Go"}
+	if !maps.Equal(m.TemplateVars(), want) {
+		t.Errorf("TemplateVars() = %v, want %v", m.TemplateVars(), want)
+	}
+
+	// Every format keeps the section.
+	for _, name := range []string{"copilot.toml", "copilot.yaml", "copilot.json"} {
+		path := tempPath(t, name)
+		if err := m.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !maps.Equal(m2.TemplateVars(), want) {
+			t.Errorf("%s: TemplateVars() after roundtrip = %v", name, m2.TemplateVars())
+		}
+	}
+}
+
+func TestLoad_TemplateVars_InvalidName(t *testing.T) {
+	t.Parallel()
+	_, err := Load(writeTempFile(t, "copilot.toml", "[template.vars]\n\"team name\" = \"x\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid template variable") {
+		t.Errorf("Load() error = %v, want invalid template variable", err)
+	}
+}
+
+func TestLoadWith_TemplateVars(t *testing.T) {
+	t.Parallel()
+	dir := writeTree(t, map[string]string{
+		"home/copilot.toml":    "[template.vars]\nteam = \"me\"\nlanguage = \"Rust\"\n",
+		"proj/copilot.toml":    "[template.vars]\nproject = \"billing\"\nlanguage = \"Go\"\n",
+		"proj/copilot.ci.toml": "[template.vars]\nproject = \"billing-ci\"\n",
+	})
+	path := filepath.Join(dir, "proj", "copilot.toml")
+	m, err := LoadWith(path, LoadOptions{Env: "ci", GlobalPath: filepath.Join(dir, "home", "copilot.toml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"project": "billing-ci", "language": "Go", "team": "me"}
+	if !maps.Equal(m.TemplateVars(), want) {
+		t.Errorf("TemplateVars() = %v, want %v", m.TemplateVars(), want)
+	}
+}
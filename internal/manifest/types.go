@@ -0,0 +1,24 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// registerTypes registers the custom asset types a manifest declares under
+// [types], so that their sections are read like the built-in ones, in this
+// manifest and in those loaded with it. Templates declare none: a type
+// chooses where its files are written, and registering it affects every
+// manifest loaded afterwards.
+func (m *Manifest) registerTypes(types map[string]config.TypeDef) error {
+	if m.template && len(types) > 0 {
+		return fmt.Errorf("[types] is not allowed in templates")
+	}
+	for _, name := range SortedKeys(types) {
+		if err := config.RegisterType(name, types[name]); err != nil {
+			return fmt.Errorf("types.%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestCustomTypes(t *testing.T) {
+	t.Parallel()
+	m, err := Load(writeTempFile(t, "copilot.toml", `[types.mrules]
+dir = "copilot/rules"
+extension = ".rule.md"
+
+[types.mpacks]
+directory = true
+
+[mrules]
+team = "myorg/rules/team.md@v1"
+
+[mpacks.k8s]
+ref = "myorg/packs/k8s@v1"
+include = ["*.md"]
+
+[instructions]
+go = "myorg/std/go.md@v1"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (config.TypeDef{Dir: "copilot/rules", Extension: ".rule.md"}); m.Types["mrules"] != want {
+		t.Errorf("Types[mrules] = %+v, want %+v", m.Types["mrules"], want)
+	}
+	if got, want := m.TargetPath("mrules", "team"), filepath.Join(".github", "copilot", "rules", "team.rule.md"); got != want {
+		t.Errorf("TargetPath(mrules/team) = %q, want %q", got, want)
+	}
+	if got := m.Options("mpacks", "k8s").Include; len(got) != 1 {
+		t.Errorf("Options(mpacks/k8s).Include = %v", got)
+	}
+
+	for _, name := range []string{"copilot.yaml", "copilot.json", "copilot.toml"} {
+		path := tempPath(t, name)
+		if err := m.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var ids []string
+		for _, e := range m2.AllEntries() {
+			ids = append(ids, e.Type+"/"+e.Name+"="+e.Ref)
+		}
+		want := "instructions/go=myorg/std/go.md@v1,mpacks/k8s=myorg/packs/k8s@v1,mrules/team=myorg/rules/team.md@v1"
+		if got := strings.Join(ids, ","); got != want {
+			t.Errorf("%s: entries after roundtrip = %s, want %s", name, got, want)
+		}
+		if problems, err := Validate(path); err != nil || len(problems) > 0 {
+			t.Errorf("%s: Validate() = %v, %v", name, problems, err)
+		}
+	}
+
+	for _, content := range []string{
+		"[types.skills]\ndirectory = true\n",
+		"[types.mbad]\ndir = \"../outside\"\n",
+		"[types.mbad]\ndirectory = true\nextension = \".md\"\n",
+	} {
+		if _, err := Load(writeTempFile(t, "copilot.toml", content)); err == nil {
+			t.Errorf("Load(%q): expected an error", content)
+		}
+	}
+}
@@ -0,0 +1,528 @@
+package manifest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// namePattern matches valid entry names. Names become file and directory
+// names, so they are restricted to a portable character set.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidName reports whether name is a valid entry name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Problem is an issue found by Validate.
+type Problem struct {
+	File    string
+	Line    int // 1-based; 0 if the problem is not tied to a line
+	Column  int // 1-based; 0 if unknown
+	Message string
+}
+
+// String formats the problem like a compiler diagnostic:
+// "copilot.toml:3:1: message".
+func (p Problem) String() string {
+	switch {
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+	}
+}
+
+// Validate checks the manifest at path without any network access and
+// returns every problem found, ordered by position: syntax errors, unknown
+// sections and options, empty or invalid entry names, malformed refs and
+// options, and entries written to the same target. The returned error is
+// only set if the file cannot be read.
+func Validate(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	v := &validator{file: path}
+	doc, err := v.parse(formatOf(path), data)
+	if err != nil {
+		return v.problems, nil
+	}
+	v.document(doc)
+
+	// Includes span other files; check them once this file is sound.
+	if len(v.problems) == 0 {
+		if _, err := Load(path); err != nil {
+			v.problems = append(v.problems, Problem{File: path, Message: err.Error()})
+		}
+	}
+
+	slices.SortStableFunc(v.problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return v.problems, nil
+}
+
+type validator struct {
+	file     string
+	keys     positions
+	problems []Problem
+}
+
+func (v *validator) report(pos position, format string, args ...any) {
+	v.problems = append(v.problems, Problem{
+		File:    v.file,
+		Line:    pos.line,
+		Column:  pos.col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// reportAt reports a problem at the key path.
+func (v *validator) reportAt(path []string, format string, args ...any) {
+	v.report(v.keys.at(path...), format, args...)
+}
+
+// parse decodes data into a generic document and records its key
+// positions. A syntax error is reported and returned.
+func (v *validator) parse(f format, data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	var err error
+	switch f {
+	case formatTOML:
+		if _, err = toml.Decode(string(data), &doc); err != nil {
+			var pe toml.ParseError
+			if errors.As(err, &pe) {
+				v.report(position{pe.Position.Line, pe.Position.Col}, "%s", pe.Message)
+				return nil, err
+			}
+		}
+		v.keys = tomlKeyPositions(string(data))
+	case formatYAML:
+		if doc, v.keys, err = parseYAML(data); err != nil {
+			var ye *yamlError
+			if errors.As(err, &ye) {
+				v.report(position{ye.line, ye.col}, "%s", ye.msg)
+				return nil, err
+			}
+		}
+		yamlScalars(doc)
+	case formatJSON:
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err = json.Unmarshal(data, &doc); err != nil {
+				var se *json.SyntaxError
+				if errors.As(err, &se) {
+					v.report(offsetPosition(data, int(se.Offset)-1), "%s", se)
+					return nil, err
+				}
+			}
+		}
+		v.keys = jsonKeyPositions(data)
+	}
+	if err != nil {
+		v.report(position{}, "%s", err)
+	}
+	return doc, err
+}
+
+// document checks the top-level keys and every entry.
+func (v *validator) document(doc map[string]any) {
+	// Declared types are registered first: their sections are known.
+	for _, name := range v.table(doc, "types") {
+		def, ok := typeDef(doc["types"].(map[string]any)[name])
+		if !ok {
+			v.reportAt([]string{"types", name}, "types.%s must be a table of dir, extension and directory", name)
+		} else if err := config.RegisterType(name, def); err != nil {
+			v.reportAt([]string{"types", name}, "types.%s: %s", name, err)
+		}
+	}
+
+	known := tagNames(reflect.TypeFor[manifestFile]())
+	for _, key := range SortedKeys(doc) {
+		if !slices.Contains(known, key) && !config.AssetType(key).IsCustom() {
+			v.reportAt([]string{key}, "unknown section %q", key)
+		}
+	}
+
+	if include, ok := doc["include"]; ok {
+		if _, ok := stringList(include); !ok {
+			v.reportAt([]string{"include"}, "include must be a list of manifest paths")
+		}
+	}
+
+	// Valid sources and default refs are kept to expand entry refs.
+	m := New()
+	for _, alias := range v.table(doc, "sources") {
+		value, ok := doc["sources"].(map[string]any)[alias].(string)
+		if !ok {
+			v.reportAt([]string{"sources", alias}, "source %q must be a string", alias)
+			continue
+		}
+		if _, err := parseSource(alias, value); err != nil {
+			v.reportAt([]string{"sources", alias}, "%s", err)
+			continue
+		}
+		m.Sources[alias] = value
+	}
+	for _, repo := range v.table(doc, "default_ref") {
+		ref, _ := doc["default_ref"].(map[string]any)[repo].(string)
+		if err := checkDefaultRef(repo, ref); err != nil {
+			v.reportAt([]string{"default_ref", repo}, "%s", err)
+			continue
+		}
+		m.DefaultRefs[repo] = ref
+	}
+	for _, name := range v.table(doc, CollectionsSection) {
+		raw, ok := doc[CollectionsSection].(map[string]any)[name].(string)
+		if !ok {
+			v.reportAt([]string{CollectionsSection, name}, "collection %q must be a reference", name)
+		} else if expanded, err := m.ExpandRef(raw); err != nil {
+			v.reportAt([]string{CollectionsSection, name}, "collections/%s: %s", name, err)
+		} else if _, err := config.ParseRef(expanded); err != nil {
+			v.reportAt([]string{CollectionsSection, name}, "collections/%s: %s", name, err)
+		}
+	}
+
+	for _, key := range v.table(doc, "template") {
+		if key != "vars" {
+			v.reportAt([]string{"template", key}, "unknown template option %q", key)
+			continue
+		}
+		vars, ok := doc["template"].(map[string]any)["vars"].(map[string]any)
+		if !ok {
+			v.reportAt([]string{"template", "vars"}, "template.vars must be a table")
+			continue
+		}
+		for _, name := range SortedKeys(vars) {
+			if err := checkVar(name); err != nil {
+				v.reportAt([]string{"template", "vars", name}, "%s", err)
+			} else if _, ok := vars[name].(string); !ok {
+				v.reportAt([]string{"template", "vars", name}, "template variable %q must be a string", name)
+			}
+		}
+	}
+
+	if root, ok := doc["output_root"]; ok {
+		s, ok := root.(string)
+		if !ok {
+			v.reportAt([]string{"output_root"}, "output_root must be a path")
+		} else if err := checkOutputRoot(s); err != nil {
+			v.reportAt([]string{"output_root"}, "%s", err)
+		} else {
+			m.OutputRoot = s
+		}
+	}
+
+	if readOnly, ok := doc["readonly"]; ok {
+		if _, ok := readOnly.(bool); !ok {
+			v.reportAt([]string{"readonly"}, "readonly must be true or false")
+		}
+	}
+
+	for _, target := range v.table(doc, "targets") {
+		paths, ok := doc["targets"].(map[string]any)[target].(map[string]any)
+		if !ok {
+			v.reportAt([]string{"targets", target}, "targets.%s must be a table", target)
+			continue
+		}
+		for _, assetType := range SortedKeys(paths) {
+			pattern, ok := paths[assetType].(string)
+			if !ok {
+				v.reportAt([]string{"targets", target, assetType}, "targets.%s.%s must be a path", target, assetType)
+			} else if err := checkTarget(target, assetType, pattern); err != nil {
+				v.reportAt([]string{"targets", target, assetType}, "%s", err)
+			}
+		}
+	}
+
+	for _, assetType := range v.table(doc, "dirs") {
+		dirs, ok := stringList(doc["dirs"].(map[string]any)[assetType])
+		if !ok {
+			v.reportAt([]string{"dirs", assetType}, "dirs.%s must be a list of directories", assetType)
+		} else if err := checkDir(assetType, dirs); err != nil {
+			v.reportAt([]string{"dirs", assetType}, "%s", err)
+		} else {
+			if m.Dirs == nil {
+				m.Dirs = make(map[string][]string)
+			}
+			m.Dirs[assetType] = dirs
+		}
+	}
+
+	for _, key := range v.table(doc, "limits") {
+		if key == "binaries" {
+			if s, ok := doc["limits"].(map[string]any)[key].(string); !ok {
+				v.reportAt([]string{"limits", key}, "limits.binaries must be a string")
+			} else if err := checkLimits(Limits{Binaries: s}); err != nil {
+				v.reportAt([]string{"limits", key}, "%s", err)
+			}
+			continue
+		}
+		if key != "max_file_size" && key != "max_skill_size" {
+			v.reportAt([]string{"limits", key}, "unknown limit %q", key)
+			continue
+		}
+		if s, ok := doc["limits"].(map[string]any)[key].(string); !ok {
+			v.reportAt([]string{"limits", key}, "limits.%s must be a size such as \"5MB\"", key)
+		} else if _, err := ParseSize(s); err != nil {
+			v.reportAt([]string{"limits", key}, "limits.%s: %s", key, err)
+		}
+	}
+
+	for _, key := range v.table(doc, "hooks") {
+		if key != "post_sync" {
+			v.reportAt([]string{"hooks", key}, "unknown hook %q", key)
+			continue
+		}
+		commands, ok := stringList(doc["hooks"].(map[string]any)[key])
+		if !ok {
+			v.reportAt([]string{"hooks", key}, "hooks.%s must be a list of commands", key)
+		} else if err := checkHooks(Hooks{PostSync: commands}); err != nil {
+			v.reportAt([]string{"hooks", key}, "%s", err)
+		}
+	}
+
+	for _, key := range v.table(doc, "settings") {
+		value := doc["settings"].(map[string]any)[key]
+		switch key {
+		case "parallelism":
+			n, ok := integer(value)
+			if !ok {
+				v.reportAt([]string{"settings", key}, "settings.parallelism must be a number")
+			} else if err := checkSettings(Settings{Parallelism: n}); err != nil {
+				v.reportAt([]string{"settings", key}, "%s", err)
+			}
+		case "eol":
+			if s, ok := value.(string); !ok {
+				v.reportAt([]string{"settings", key}, "settings.eol must be a string")
+			} else if err := checkSettings(Settings{EOL: s}); err != nil {
+				v.reportAt([]string{"settings", key}, "%s", err)
+			}
+		case "banner":
+			if _, ok := value.(bool); !ok {
+				v.reportAt([]string{"settings", key}, "settings.banner must be true or false")
+			}
+		default:
+			v.reportAt([]string{"settings", key}, "unknown setting %q", key)
+		}
+	}
+
+	// The template itself is not fetched; only its reference is checked.
+	if extends, ok := doc["extends"]; ok {
+		raw, ok := extends.(string)
+		if !ok {
+			v.reportAt([]string{"extends"}, "extends must be a template reference")
+		} else if expanded, err := m.ExpandRef(raw); err != nil {
+			v.reportAt([]string{"extends"}, "extends: %s", err)
+		} else if _, err := config.ParseRef(expanded); err != nil {
+			v.reportAt([]string{"extends"}, "extends: %s", err)
+		}
+	}
+
+	targets := make(map[string]string)
+	for _, t := range config.ValidAssetTypes() {
+		section := string(t)
+		for _, name := range v.table(doc, section) {
+			v.entry(m, t, name, doc[section].(map[string]any)[name], targets)
+		}
+	}
+}
+
+// typeDef returns value, a [types] declaration as decoded, as a TypeDef.
+func typeDef(value any) (config.TypeDef, bool) {
+	var def config.TypeDef
+	table, ok := value.(map[string]any)
+	if !ok {
+		return def, false
+	}
+	for key, v := range table {
+		switch key {
+		case "dir":
+			def.Dir, ok = v.(string)
+		case "extension":
+			def.Extension, ok = v.(string)
+		case "directory":
+			def.Directory, ok = v.(bool)
+		default:
+			ok = false
+		}
+		if !ok {
+			return def, false
+		}
+	}
+	return def, true
+}
+
+// integer returns value as an int if it is a whole number, as TOML, JSON
+// and YAML documents decode them.
+func integer(value any) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), n == int64(int(n))
+	case float64:
+		return int(n), n == float64(int(n))
+	}
+	return 0, false
+}
+
+// table returns the sorted keys of the top-level table key, reporting it if
+// it is not a table.
+func (v *validator) table(doc map[string]any, key string) []string {
+	value, ok := doc[key]
+	if !ok || value == nil {
+		return nil
+	}
+	table, ok := value.(map[string]any)
+	if !ok {
+		v.reportAt([]string{key}, "%s must be a table, got %s", key, kindOf(value))
+		return nil
+	}
+	return SortedKeys(table)
+}
+
+// entry checks a single entry. targets maps each target path seen so far
+// to the entry writing it.
+func (v *validator) entry(m *Manifest, t config.AssetType, name string, value any, targets map[string]string) {
+	path := []string{string(t), name}
+	id := string(t) + "/" + name
+	if strings.TrimSpace(name) == "" {
+		v.reportAt(path, "%s: empty entry name", string(t))
+		return
+	}
+	validName := namePattern.MatchString(name)
+	if !validName {
+		v.reportAt(path, "%s: invalid characters in name (use letters, digits, '.', '_' and '-')", id)
+	}
+
+	var table entryTable
+	refPath := path
+	switch value := value.(type) {
+	case string:
+		table.Ref = value
+	case map[string]any:
+		refPath = append(slices.Clone(path), "ref")
+		known := tagNames(reflect.TypeFor[entryTable]())
+		for _, key := range SortedKeys(value) {
+			if !slices.Contains(known, key) {
+				v.reportAt(append(slices.Clone(path), key), "%s: unknown option %q", id, key)
+			}
+		}
+		if _, ok := value["ref"]; !ok {
+			v.reportAt(path, "%s: missing ref", id)
+			return
+		}
+		data, _ := json.Marshal(value)
+		if err := json.Unmarshal(data, &table); err != nil {
+			var te *json.UnmarshalTypeError
+			if errors.As(err, &te) {
+				v.reportAt(append(slices.Clone(path), te.Field), "%s: %s must be %s, not %s", id, te.Field, kindOf(reflect.Zero(te.Type).Interface()), te.Value)
+			} else {
+				v.reportAt(path, "%s: %s", id, err)
+			}
+			return
+		}
+		if err := table.EntryOptions.validateFor(string(t)); err != nil {
+			v.reportAt(path, "%s: %s", id, err)
+		}
+	default:
+		v.reportAt(path, "%s: must be a ref string or a table, got %s", id, kindOf(value))
+		return
+	}
+
+	if table.Ref == "" {
+		v.reportAt(refPath, "%s: empty ref", id)
+		return
+	}
+	expanded, err := m.ExpandRef(table.Ref)
+	if err == nil {
+		var ref config.AssetRef
+		if ref, err = config.ParseRef(expanded); err == nil && ref.IsURL() && t.IsDirectory() {
+			err = fmt.Errorf("%s cannot be sourced from a URL", t)
+		}
+	}
+	if err != nil {
+		v.reportAt(refPath, "%s: %s", id, err)
+	}
+
+	if !validName || (table.Target != "" && !projectPath(table.Target)) {
+		return
+	}
+	target := filepath.ToSlash(Entry{Type: string(t), Name: name, Options: table.EntryOptions, OutputRoot: m.OutputRoot, Dir: m.typeDir(string(t))}.TargetPath())
+	if other, dup := targets[target]; dup {
+		v.reportAt(path, "%s: target %s is also written by %s", id, target, other)
+		return
+	}
+	targets[target] = id
+}
+
+// tagNames returns the TOML key names of a struct's fields, including those
+// of embedded structs.
+func tagNames(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			names = append(names, tagNames(f.Type)...)
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get("toml"), ","); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// stringList reports whether v is a list of strings.
+func stringList(v any) ([]string, bool) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// kindOf describes the type of a decoded value for error messages.
+func kindOf(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int64, float64, json.Number:
+		return "a number"
+	case []any, []string:
+		return "a list"
+	case map[string]any:
+		return "a table"
+	case nil:
+		return "empty"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
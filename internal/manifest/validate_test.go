@@ -0,0 +1,267 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string // "line:col: message" prefixes, in order
+	}{
+		{
+			name: "valid toml",
+			file: "copilot.toml",
+			content: `[sources]
+awesome = "github/awesome-copilot@v2"
+
+[agents]
+planner  = "awesome:agents/planner.md"
+reviewer = { ref = "org/repo/reviewer.md@v1", groups = ["ci"] }
+`,
+		},
+		{
+			name: "toml problems",
+			file: "copilot.toml",
+			content: `[agnts]
+x = "org/repo/x.md@v1"
+
+[agents]
+"bad name" = "org/repo/x.md@v1"
+planner    = "org/repo/planner.md@v1"
+reviewer   = { ref = "org/repo/reviewer.md@v1", colour = "red" }
+writer     = { ref = "nope:writer.md@v1", target = ".github/agents/planner.agent.md" }
+
+[prompts.review]
+ref                = "not-a-ref"
+allow_branch_until = "tomorrow"
+`,
+			want: []string{
+				`1:2: unknown section "agnts"`,
+				`5:1: agents/bad name: invalid characters in name`,
+				`7:49: agents/reviewer: unknown option "colour"`,
+				`8:1: agents/writer: target .github/agents/planner.agent.md is also written by agents/planner`,
+				`8:16: agents/writer: reference "nope:writer.md@v1": unknown source alias "nope"`,
+				`10:2: prompts/review: invalid allow_branch_until "tomorrow"`,
+				`11:1: prompts/review: invalid reference "not-a-ref"`,
+			},
+		},
+		{
+			name: "dirs",
+			file: "copilot.toml",
+			content: `[dirs]
+prompts   = [".github/prompts", ".vscode/prompts"]
+agents-md = ["docs"]
+skills    = "tools/skills"
+
+[prompts]
+review = "org/repo/review.prompt.md@v1"
+
+[prompts.copy]
+ref    = "org/repo/copy.prompt.md@v1"
+target = ".github/prompts/review.prompt.md"
+`,
+			want: []string{
+				`3:1: dirs.agents-md: agents-md is a single file, use [targets] to write it elsewhere`,
+				`4:1: dirs.skills must be a list of directories`,
+				`7:1: prompts/review: target .github/prompts/review.prompt.md is also written by prompts/copy`,
+			},
+		},
+		{
+			name: "collections",
+			file: "copilot.toml",
+			content: `[collections]
+azure = "github/awesome-copilot/collections/azure.collection.yml@main"
+bad   = "nope:x.yml"
+`,
+			want: []string{
+				`3:1: collections/bad: reference "nope:x.yml": unknown source alias "nope"`,
+			},
+		},
+		{
+			name: "extends",
+			file: "copilot.json",
+			content: `{
+  "extends": "tpl:base.toml@v5",
+  "agents": {"planner": "org/repo/planner.md@v1"}
+}
+`,
+			want: []string{
+				`2:3: extends: reference "tpl:base.toml@v5": unknown source alias "tpl"`,
+			},
+		},
+		{
+			name:    "toml syntax",
+			file:    "copilot.toml",
+			content: "[agents]\nplanner = \n",
+			want:    []string{"2:"},
+		},
+		{
+			name: "yaml problems",
+			file: "copilot.yaml",
+			content: `agents:
+  planner: org/repo/planner.md@v1
+  reviewer: { ref: org/repo/reviewer.md@v1, groupz: [ci] }
+  "": org/repo/empty.md@v1
+skills:
+  web: https://example.com/skill.md
+prompts: [a]
+`,
+			want: []string{
+				`3:45: agents/reviewer: unknown option "groupz"`,
+				`4:3: agents: empty entry name`,
+				`6:3: skills/web: skills cannot be sourced from a URL`,
+				`7:1: prompts must be a table, got a list`,
+			},
+		},
+		{
+			name:    "yaml syntax",
+			file:    "copilot.yml",
+			content: "agents:\n  planner: [a\n",
+			want:    []string{"2:3: unterminated flow sequence"},
+		},
+		{
+			name: "json problems",
+			file: "copilot.json",
+			content: `{
+  "agents": {
+    "planner": {"ref": "org/repo/planner.md@v1", "groups": "ci"},
+    "writer": 5
+  },
+  "extra": true
+}`,
+			want: []string{
+				`3:50: agents/planner: groups must be a list, not string`,
+				`4:5: agents/writer: must be a ref string or a table, got a number`,
+				`6:3: unknown section "extra"`,
+			},
+		},
+		{
+			name:    "json syntax",
+			file:    "copilot.json",
+			content: "{\n  \"agents\": {,}\n}",
+			want:    []string{"2:14: invalid character ','"},
+		},
+		{
+			name: "settings problems",
+			file: "copilot.toml",
+			content: `[settings]
+parallelism = 64
+eol         = "cr"
+banner      = "yes"
+colour      = "red"
+`,
+			want: []string{
+				`2:1: settings: invalid parallelism 64: must be between 1 and 16`,
+				`3:1: settings: invalid eol "cr": must be "lf" or "crlf"`,
+				`4:1: settings.banner must be true or false`,
+				`5:1: unknown setting "colour"`,
+			},
+		},
+		{
+			name: "template problems",
+			file: "copilot.toml",
+			content: `[template]
+colour = "red"
+
+[template.vars]
+project     = "billing"
+"team name" = "x"
+size        = 3
+`,
+			want: []string{
+				`2:1: unknown template option "colour"`,
+				`6:1: invalid template variable "team name"`,
+				`7:1: template variable "size" must be a string`,
+			},
+		},
+		{
+			name: "target problems",
+			file: "copilot.toml",
+			content: `[targets.cursor]
+instructions = ".cursor/rules/{name}.mdc"
+rules        = ".cursor/rules/{name}.mdc"
+skills       = ".cursor/skills"
+`,
+			want: []string{
+				`3:1: targets.cursor: unknown asset type "rules"`,
+				`4:1: targets.cursor.skills: ".cursor/skills" must contain {name}`,
+			},
+		},
+		{
+			name: "limit problems",
+			file: "copilot.toml",
+			content: `[limits]
+max_file_size  = "5 TB"
+max_skill_size = 20
+max_files      = "10"
+binaries       = "delete"
+`,
+			want: []string{
+				`2:1: limits.max_file_size: invalid size "5 TB": use a positive number of bytes, KB, MB or GB`,
+				`3:1: limits.max_skill_size must be a size such as "5MB"`,
+				`4:1: unknown limit "max_files"`,
+				`5:1: limits: invalid binaries "delete": must be "allow", "skip", "quarantine" or "fail"`,
+			},
+		},
+		{
+			name:    "readonly not a boolean",
+			file:    "copilot.toml",
+			content: "readonly = \"yes\"\n",
+			want:    []string{`1:1: readonly must be true or false`},
+		},
+		{
+			name: "hook problems",
+			file: "copilot.toml",
+			content: `[hooks]
+post_sync = "make index"
+pre_sync  = ["make"]
+`,
+			want: []string{
+				`2:1: hooks.post_sync must be a list of commands`,
+				`3:1: unknown hook "pre_sync"`,
+			},
+		},
+		{
+			name:    "missing include",
+			file:    "copilot.toml",
+			content: "include = [\"nope.toml\"]\n",
+			want:    []string{`include "nope.toml"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := writeTree(t, map[string]string{tt.file: tt.content})
+			path := filepath.Join(dir, tt.file)
+			problems, err := Validate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, p := range problems {
+				got := strings.TrimPrefix(p.String(), path+":")
+				if p.Line == 0 {
+					got = strings.TrimPrefix(p.String(), path+": ")
+				}
+				if !strings.Contains(got, tt.want[i]) {
+					t.Errorf("problem %d = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_Unreadable(t *testing.T) {
+	t.Parallel()
+	if _, err := Validate(filepath.Join(t.TempDir(), "copilot.toml")); err == nil {
+		t.Error("expected error for a missing manifest")
+	}
+}
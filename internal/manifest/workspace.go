@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultWorkspaceFile is the workspace file at the root of a monorepo.
+const DefaultWorkspaceFile = "cops-workspace.toml"
+
+// Workspace lists the subprojects of a monorepo, each with its own
+// copilot.toml and .cops.lock:
+//
+//	members = ["services/api", "services/web", "libs/*"]
+type Workspace struct {
+	// Members are directories relative to the workspace file. Glob patterns
+	// select every matching directory that contains a manifest (see Find).
+	Members []string `toml:"members"`
+}
+
+// LoadWorkspace reads a workspace file.
+func LoadWorkspace(path string) (*Workspace, error) {
+	var w Workspace
+	if _, err := toml.DecodeFile(path, &w); err != nil {
+		return nil, fmt.Errorf("reading workspace: %w", err)
+	}
+	if len(w.Members) == 0 {
+		return nil, fmt.Errorf("reading workspace: %s lists no members", path)
+	}
+	return &w, nil
+}
+
+// MemberDirs returns the member directories relative to root, expanded and
+// in byte-wise order. Explicit members must contain a manifest.
+func (w *Workspace) MemberDirs(root string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, member := range w.Members {
+		pattern := filepath.FromSlash(member)
+		if !filepath.IsLocal(pattern) {
+			return nil, fmt.Errorf("workspace member %q: must be a relative path inside the workspace", member)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			// A literal member must exist and hold a manifest.
+			if _, err := os.Stat(Find(filepath.Join(root, pattern))); err != nil {
+				return nil, fmt.Errorf("workspace member %q: %w", member, err)
+			}
+			seen[filepath.Clean(pattern)] = true
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("workspace member %q: %w", member, err)
+		}
+		for _, match := range matches {
+			if _, err := os.Stat(Find(match)); err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			seen[rel] = true
+		}
+	}
+	dirs := SortedKeys(seen)
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("workspace members match no directory with a manifest")
+	}
+	return dirs, nil
+}
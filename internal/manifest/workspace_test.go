@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspace_MemberDirs(t *testing.T) {
+	t.Parallel()
+	root := writeTree(t, map[string]string{
+		DefaultWorkspaceFile:        "members = [\"services/*\", \"tools\", \"services/api\"]\n",
+		"services/api/copilot.toml": "",
+		"services/web/copilot.toml": "",
+		"services/docs/README.md":   "not a member",
+		"tools/copilot.toml":        "",
+	})
+	ws, err := LoadWorkspace(filepath.Join(root, DefaultWorkspaceFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := ws.MemberDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("services", "api"), filepath.Join("services", "web"), "tools"}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("MemberDirs() = %v, want %v", dirs, want)
+	}
+}
+
+func TestWorkspace_MemberDirsErrors(t *testing.T) {
+	t.Parallel()
+	root := writeTree(t, map[string]string{"api/copilot.toml": ""})
+	cases := map[string][]string{
+		"missing literal":          {"api", "web"},
+		"outside root":             {"../elsewhere"},
+		"glob matches no manifest": {"docs/*"},
+	}
+	for name, members := range cases {
+		if _, err := (&Workspace{Members: members}).MemberDirs(root); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := LoadWorkspace(writeTempFile(t, DefaultWorkspaceFile, "members = []\n")); err == nil {
+		t.Error("LoadWorkspace: expected error for an empty member list")
+	}
+}
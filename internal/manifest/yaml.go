@@ -0,0 +1,815 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of YAML that copilot.yaml needs: block
+// mappings and sequences (including "- key: value" items), single-line flow
+// sequences and mappings, plain and quoted scalars, and comments. Every scalar is a string. Anchors, tags,
+// multi-line scalars and multiple documents are rejected rather than
+// misread.
+
+// yamlLine is a non-empty source line with its comment removed.
+type yamlLine struct {
+	num    int // 1-based
+	indent int
+	text   string
+}
+
+func (l yamlLine) errorf(format string, args ...any) error {
+	return &yamlError{line: l.num, col: l.indent + 1, msg: fmt.Sprintf(format, args...)}
+}
+
+// yamlError is a YAML syntax error.
+type yamlError struct {
+	line, col int
+	msg       string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// parseYAML parses a YAML document whose top level is a mapping. Mappings
+// decode to map[string]any, sequences to []any, scalars to string and
+// empty values to nil. It also returns where each mapping key is written.
+func parseYAML(data []byte) (map[string]any, positions, error) {
+	lines, err := splitYAMLLines(string(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &yamlParser{lines: lines, keys: make(positions)}
+	if len(lines) == 0 {
+		return map[string]any{}, p.keys, nil
+	}
+	if lines[0].indent != 0 {
+		return nil, nil, lines[0].errorf("unexpected indentation")
+	}
+	if isYAMLSeqItem(lines[0].text) {
+		return nil, nil, lines[0].errorf("top level must be a mapping")
+	}
+	doc, err := p.parseMapping(0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(lines) {
+		return nil, nil, lines[p.pos].errorf("unexpected indentation")
+	}
+	return doc, p.keys, nil
+}
+
+// splitYAMLLines drops blank lines, comments and the leading document
+// marker.
+func splitYAMLLines(src string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimSuffix(raw, "\r")
+		content := strings.TrimLeft(raw, " ")
+		l := yamlLine{num: i + 1, indent: len(raw) - len(content)}
+		if strings.HasPrefix(content, "\t") {
+			return nil, l.errorf("tabs are not allowed in indentation")
+		}
+		l.text = strings.TrimRight(stripYAMLComment(content), " \t")
+		switch {
+		case l.text == "":
+			continue
+		case l.indent == 0 && (l.text == "---" || l.text == "..."):
+			if len(lines) > 0 {
+				return nil, l.errorf("multiple documents are not supported")
+			}
+			continue
+		case l.indent == 0 && strings.HasPrefix(l.text, "%"):
+			return nil, l.errorf("directives are not supported")
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// stripYAMLComment removes a trailing "# comment" that is not inside a
+// quoted scalar.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[{,:", s[i-1]) >= 0):
+			quote = c
+		}
+	}
+	return s
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+	keys  positions
+}
+
+// parseBlock parses the mapping or sequence at path starting at the
+// current line.
+func (p *yamlParser) parseBlock(indent int, path []string) (any, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent, path)
+	}
+	return p.parseMapping(indent, path)
+}
+
+// nested parses the block value of a key or sequence item that has no
+// inline value. A mapping value may be a sequence at the key's own
+// indentation. Without a block the value is empty (nil).
+func (p *yamlParser) nested(indent int, path []string, allowSameIndentSeq bool) (any, error) {
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.indent > indent || (allowSameIndentSeq && next.indent == indent && isYAMLSeqItem(next.text)) {
+			return p.parseBlock(next.indent, path)
+		}
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) parseMapping(indent int, path []string) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, l.errorf("unexpected indentation")
+		}
+		if isYAMLSeqItem(l.text) {
+			return nil, l.errorf("unexpected sequence item in a mapping")
+		}
+		key, rest, err := splitYAMLKey(l)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			return nil, l.errorf("duplicate key %q", key)
+		}
+		p.pos++
+		child := append(slices.Clone(path), key)
+		p.keys.add(child, l.num, l.indent+1)
+
+		var v any
+		if rest != "" {
+			v, err = p.parseInline(l, rest, child)
+		} else {
+			v, err = p.nested(indent, child, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int, path []string) ([]any, error) {
+	seq := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isYAMLSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, l.errorf("unexpected indentation")
+		}
+		rest := strings.TrimSpace(l.text[1:])
+		if isYAMLCompactMapping(rest) {
+			// "- key: value" starts a mapping indented like its first key:
+			// the item is parsed as if the key were on its own line.
+			p.lines[p.pos] = yamlLine{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, err := p.parseMapping(p.lines[p.pos].indent, path)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		p.pos++
+
+		var v any
+		var err error
+		if rest != "" {
+			v, err = p.parseInline(l, rest, path)
+		} else {
+			v, err = p.nested(indent, path, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+	return seq, nil
+}
+
+// isYAMLCompactMapping reports whether text, following a sequence dash,
+// starts a mapping: a plain key followed by ':'.
+func isYAMLCompactMapping(text string) bool {
+	return text != "" && strings.IndexByte("[{\"'", text[0]) < 0 && yamlKeyColon(text) >= 0
+}
+
+// splitYAMLKey splits a "key: value" line into its key and the (possibly
+// empty) inline value.
+func splitYAMLKey(l yamlLine) (key, rest string, err error) {
+	text := l.text
+	if text[0] == '"' || text[0] == '\'' {
+		s := &yamlScanner{line: l, s: text, keys: positions{}}
+		if key, err = s.quoted(); err != nil {
+			return "", "", err
+		}
+		rest = strings.TrimLeft(text[s.i:], " ")
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", l.errorf("expected ':' after key %q", key)
+		}
+		return key, strings.TrimSpace(rest[1:]), nil
+	}
+
+	i := yamlKeyColon(text)
+	if i < 0 {
+		return "", "", l.errorf("expected \"key: value\", got %q", text)
+	}
+	key = strings.TrimSpace(text[:i])
+	if key == "" || strings.IndexByte("[]{},&*!|>%@`", key[0]) >= 0 {
+		return "", "", l.errorf("unsupported key %q", key)
+	}
+	return key, strings.TrimSpace(text[i+1:]), nil
+}
+
+// yamlKeyColon returns the index of the first ':' that ends a plain key,
+// i.e. one followed by a space or the end of the text, or -1.
+func yamlKeyColon(text string) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseInline parses the value at path written on the same line as its key
+// or sequence dash. text is a suffix of the line's text.
+func (p *yamlParser) parseInline(l yamlLine, text string, path []string) (any, error) {
+	col := l.indent + 1 + len(l.text) - len(text)
+	s := &yamlScanner{line: l, s: text, col: col, keys: p.keys}
+	v, err := s.value(false, path)
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpaces()
+	if !s.eof() {
+		return nil, l.errorf("unexpected %q after value", s.s[s.i:])
+	}
+	return v, nil
+}
+
+// yamlScanner reads inline values from a single line.
+type yamlScanner struct {
+	line yamlLine
+	s    string
+	i    int
+	col  int // column of s[0] in the line
+	keys positions
+}
+
+func (s *yamlScanner) eof() bool { return s.i >= len(s.s) }
+
+func (s *yamlScanner) skipSpaces() {
+	for !s.eof() && s.s[s.i] == ' ' {
+		s.i++
+	}
+}
+
+// value reads the scalar or flow collection at path. Inside a flow
+// collection plain scalars end at ',', ']' or '}'.
+func (s *yamlScanner) value(flow bool, path []string) (any, error) {
+	s.skipSpaces()
+	if s.eof() {
+		return "", nil
+	}
+	switch c := s.s[s.i]; c {
+	case '[':
+		return s.flowSequence(path)
+	case '{':
+		return s.flowMapping(path)
+	case '"', '\'':
+		return s.quoted()
+	case '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, s.line.errorf("unsupported YAML syntax %q", c)
+	}
+	return s.plain(flow)
+}
+
+func (s *yamlScanner) plain(flow bool) (string, error) {
+	start := s.i
+	if !flow {
+		s.i = len(s.s)
+		v := strings.TrimSpace(s.s[start:])
+		if yamlKeyColon(v) >= 0 {
+			return "", s.line.errorf("nested mapping %q must be on its own lines", v)
+		}
+		return v, nil
+	}
+	for !s.eof() && strings.IndexByte(",]}", s.s[s.i]) < 0 {
+		if s.s[s.i] == ':' && (s.i+1 == len(s.s) || strings.IndexByte(" ,]}", s.s[s.i+1]) >= 0) {
+			break
+		}
+		s.i++
+	}
+	return strings.TrimSpace(s.s[start:s.i]), nil
+}
+
+func (s *yamlScanner) flowSequence(path []string) ([]any, error) {
+	s.i++ // '['
+	seq := []any{}
+	for {
+		s.skipSpaces()
+		if s.eof() {
+			return nil, s.line.errorf("unterminated flow sequence")
+		}
+		if s.s[s.i] == ']' {
+			s.i++
+			return seq, nil
+		}
+		v, err := s.value(true, path)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		if err := s.flowSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *yamlScanner) flowMapping(path []string) (map[string]any, error) {
+	s.i++ // '{'
+	m := make(map[string]any)
+	for {
+		s.skipSpaces()
+		if s.eof() {
+			return nil, s.line.errorf("unterminated flow mapping")
+		}
+		if s.s[s.i] == '}' {
+			s.i++
+			return m, nil
+		}
+		var key string
+		var err error
+		start := s.i
+		if c := s.s[s.i]; c == '"' || c == '\'' {
+			key, err = s.quoted()
+		} else {
+			key, err = s.plain(true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpaces()
+		if s.eof() || s.s[s.i] != ':' {
+			return nil, s.line.errorf("expected ':' after key %q", key)
+		}
+		s.i++
+		if _, dup := m[key]; dup {
+			return nil, s.line.errorf("duplicate key %q", key)
+		}
+		child := append(slices.Clone(path), key)
+		s.keys.add(child, s.line.num, s.col+start)
+		if m[key], err = s.value(true, child); err != nil {
+			return nil, err
+		}
+		if err := s.flowSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// flowSeparator consumes the ',' between flow items, leaving a closing
+// bracket for the caller.
+func (s *yamlScanner) flowSeparator(closing byte) error {
+	s.skipSpaces()
+	switch {
+	case s.eof():
+		return nil // reported as unterminated by the caller
+	case s.s[s.i] == ',':
+		s.i++
+		return nil
+	case s.s[s.i] == closing:
+		return nil
+	default:
+		return s.line.errorf("expected ',' or %q, got %q", closing, s.s[s.i:])
+	}
+}
+
+// quoted reads a single- or double-quoted scalar.
+func (s *yamlScanner) quoted() (string, error) {
+	q := s.s[s.i]
+	s.i++
+	var b strings.Builder
+	for !s.eof() {
+		c := s.s[s.i]
+		switch {
+		case c == q && q == '\'' && s.i+1 < len(s.s) && s.s[s.i+1] == '\'':
+			b.WriteByte('\'')
+			s.i += 2
+		case c == q:
+			s.i++
+			return b.String(), nil
+		case c == '\\' && q == '"':
+			r, n, err := yamlEscape(s.s[s.i:])
+			if err != nil {
+				return "", s.line.errorf("%v", err)
+			}
+			b.WriteRune(r)
+			s.i += n
+		default:
+			b.WriteByte(c)
+			s.i++
+		}
+	}
+	return "", s.line.errorf("unterminated quoted string")
+}
+
+// yamlEscape decodes the escape sequence at the start of s, returning the
+// rune and the number of bytes consumed.
+func yamlEscape(s string) (rune, int, error) {
+	if len(s) < 2 {
+		return 0, 0, fmt.Errorf("incomplete escape sequence")
+	}
+	simple := map[byte]rune{
+		'0': 0, 'a': '\a', 'b': '\b', 't': '\t', 'n': '\n', 'v': '\v', 'f': '\f',
+		'r': '\r', 'e': 0x1b, ' ': ' ', '"': '"', '/': '/', '\\': '\\',
+	}
+	if r, ok := simple[s[1]]; ok {
+		return r, 2, nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[1]]
+	if width == 0 || len(s) < 2+width {
+		return 0, 0, fmt.Errorf("invalid escape sequence %q", s[:min(len(s), 2+width)])
+	}
+	n, err := strconv.ParseUint(s[2:2+width], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return 0, 0, fmt.Errorf("invalid escape sequence %q", s[:2+width])
+	}
+	return rune(n), 2 + width, nil
+}
+
+// yamlBoolKeys lists the top-level settings that are booleans. Scalars
+// are parsed as strings, so these are converted by yamlScalars, as are the
+// booleans and integers of [settings].
+var yamlBoolKeys = []string{"readonly"}
+
+// yamlScalars converts the "true" and "false" values of the yamlBoolKeys
+// of doc, of settings.banner and of types.<name>.directory to booleans,
+// and settings.parallelism to an integer. Other values are left for the
+// caller to reject.
+func yamlScalars(doc map[string]any) {
+	yamlBools(doc, yamlBoolKeys)
+	if types, ok := doc["types"].(map[string]any); ok {
+		for _, def := range types {
+			if def, ok := def.(map[string]any); ok {
+				yamlBools(def, []string{"directory"})
+			}
+		}
+	}
+	settings, ok := doc["settings"].(map[string]any)
+	if !ok {
+		return
+	}
+	yamlBools(settings, []string{"banner"})
+	if s, ok := settings["parallelism"].(string); ok {
+		if n, err := strconv.Atoi(s); err == nil {
+			settings["parallelism"] = n
+		}
+	}
+}
+
+// yamlBools converts the "true" and "false" values of keys in doc to
+// booleans.
+func yamlBools(doc map[string]any, keys []string) {
+	for _, key := range keys {
+		switch doc[key] {
+		case "true":
+			doc[key] = true
+		case "false":
+			doc[key] = false
+		}
+	}
+}
+
+// encodeYAML writes doc, a JSON-shaped value tree, as block YAML. Top-level
+// keys come in the order given by keys; within each mapping "ref" comes
+// first and the remaining keys follow in byte-wise order.
+func encodeYAML(doc map[string]any, keys []string) []byte {
+	var b bytes.Buffer
+	for _, k := range keys {
+		if v, ok := doc[k]; ok {
+			writeYAML(&b, k, v, 0)
+		}
+	}
+	return b.Bytes()
+}
+
+func writeYAML(b *bytes.Buffer, key string, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case map[string]any:
+		fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(key))
+		keys := SortedKeys(v)
+		if _, ok := v["ref"]; ok {
+			keys = append([]string{"ref"}, deleteString(keys, "ref")...)
+		}
+		for _, k := range keys {
+			writeYAML(b, k, v[k], indent+1)
+		}
+	case []any:
+		fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(key))
+		for _, item := range v {
+			fmt.Fprintf(b, "%s  - %s\n", pad, yamlScalar(fmt.Sprint(item)))
+		}
+	case bool:
+		fmt.Fprintf(b, "%s%s: %t\n", pad, yamlScalar(key), v)
+	default:
+		fmt.Fprintf(b, "%s%s: %s\n", pad, yamlScalar(key), yamlScalar(fmt.Sprint(v)))
+	}
+}
+
+// mergeYAML writes doc like encodeYAML, keeping the layout and comments of
+// prev, the YAML document it replaces: top-level keys, and the keys of
+// the mappings under them, whose value did not change keep their lines as
+// they were; changed ones are rewritten in place and removed ones dropped
+// with the comments above them. New keys follow the others. If prev
+// cannot be parsed, doc is written from scratch.
+func mergeYAML(prev []byte, doc map[string]any, keys []string) []byte {
+	old, _, err := parseYAML(prev)
+	if err != nil {
+		return encodeYAML(doc, keys)
+	}
+	src := strings.TrimSuffix(strings.TrimPrefix(string(prev), "\ufeff"), "\n")
+	if src == "" {
+		return encodeYAML(doc, keys)
+	}
+	lines := strings.Split(src, "\n")
+	blocks := yamlBlocks(lines, 0, len(lines), 0)
+	if len(blocks) == 0 {
+		return encodeYAML(doc, keys)
+	}
+	// Comments above the first key describe the file, not the key.
+	blocks[0].lead = blocks[0].key
+
+	out := slices.Clone(lines[:blocks[0].lead])
+	seen := make(map[string]bool)
+	for _, blk := range blocks {
+		seen[blk.name] = true
+		v, ok := doc[blk.name]
+		switch {
+		case !ok:
+			continue
+		case yamlEqual(old[blk.name], v):
+			out = append(out, lines[blk.lead:blk.end]...)
+		default:
+			out = append(out, mergeYAMLSection(lines, blk, old[blk.name], v)...)
+		}
+	}
+	// New sections are set apart like the others, if they are.
+	spaced := slices.ContainsFunc(lines, func(l string) bool { return strings.TrimSpace(l) == "" })
+	var added []string
+	for _, k := range keys {
+		if v, ok := doc[k]; ok && !seen[k] {
+			if spaced {
+				added = append(added, "")
+			}
+			added = append(added, yamlLines(k, v, 0)...)
+		}
+	}
+	out = insertBeforeBlank(out, added)
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// mergeYAMLSection returns the lines of the top-level key of blk, whose
+// value changed from old to v, keeping those of its unchanged keys.
+func mergeYAMLSection(lines []string, blk yamlBlock, old, v any) []string {
+	oldMap, ok1 := old.(map[string]any)
+	newMap, ok2 := v.(map[string]any)
+	indent := -1
+	for i := blk.key + 1; i < blk.end; i++ {
+		if text, n, ok := yamlContent(lines[i]); ok {
+			if !isYAMLSeqItem(text) {
+				indent = n
+			}
+			break
+		}
+	}
+	if !ok1 || !ok2 || indent <= 0 {
+		out := slices.Concat(lines[blk.lead:blk.key], yamlLines(blk.name, v, 0))
+		return append(out, yamlTrailingBlank(lines, blk)...)
+	}
+
+	out := slices.Clone(lines[blk.lead : blk.key+1])
+	seen := make(map[string]bool)
+	for _, child := range yamlBlocks(lines, blk.key+1, blk.end, indent) {
+		seen[child.name] = true
+		cv, ok := newMap[child.name]
+		switch {
+		case !ok:
+			continue
+		case yamlEqual(oldMap[child.name], cv):
+			out = append(out, lines[child.lead:child.end]...)
+		default:
+			out = append(out, lines[child.lead:child.key]...)
+			out = append(out, yamlLines(child.name, cv, indent)...)
+			out = append(out, yamlTrailingBlank(lines, child)...)
+		}
+	}
+	var added []string
+	for _, k := range SortedKeys(newMap) {
+		if !seen[k] {
+			added = append(added, yamlLines(k, newMap[k], indent)...)
+		}
+	}
+	return insertBeforeBlank(out, added)
+}
+
+// yamlBlock is a mapping key in the lines of a YAML document: the comments
+// above it from lead, the key itself at key, and its value up to end.
+type yamlBlock struct {
+	name           string
+	lead, key, end int
+}
+
+// yamlBlocks returns the keys written at indent in lines[from:to].
+func yamlBlocks(lines []string, from, to, indent int) []yamlBlock {
+	var blocks []yamlBlock
+	for i := from; i < to; i++ {
+		text, n, ok := yamlContent(lines[i])
+		if !ok || n != indent || isYAMLSeqItem(text) || text == "---" || text == "..." {
+			continue
+		}
+		name, _, err := splitYAMLKey(yamlLine{num: i + 1, indent: n, text: text})
+		if err != nil {
+			continue
+		}
+		lead := i
+		floor := from
+		if len(blocks) > 0 {
+			floor = blocks[len(blocks)-1].key + 1
+		}
+		for lead > floor {
+			prev := lines[lead-1]
+			if _, _, ok := yamlContent(prev); ok || len(prev)-len(strings.TrimLeft(prev, " ")) > indent && strings.TrimSpace(prev) != "" {
+				break
+			}
+			lead--
+		}
+		if len(blocks) > 0 {
+			blocks[len(blocks)-1].end = lead
+		}
+		blocks = append(blocks, yamlBlock{name: name, lead: lead, key: i, end: to})
+	}
+	return blocks
+}
+
+// yamlContent returns the text of line without its comment, and its
+// indentation, or false if it holds nothing else.
+func yamlContent(line string) (string, int, bool) {
+	content := strings.TrimLeft(line, " ")
+	text := strings.TrimRight(stripYAMLComment(content), " \t\r")
+	return text, len(line) - len(content), text != ""
+}
+
+// yamlTrailingBlank returns the blank lines ending the value of blk.
+func yamlTrailingBlank(lines []string, blk yamlBlock) []string {
+	i := blk.end
+	for i > blk.key+1 && strings.TrimSpace(lines[i-1]) == "" {
+		i--
+	}
+	return lines[i:blk.end]
+}
+
+// insertBeforeBlank inserts added before the blank lines ending lines.
+func insertBeforeBlank(lines, added []string) []string {
+	if len(added) == 0 {
+		return lines
+	}
+	i := len(lines)
+	for i > 0 && strings.TrimSpace(lines[i-1]) == "" {
+		i--
+	}
+	return slices.Concat(lines[:i], added, lines[i:])
+}
+
+// yamlLines returns the lines writeYAML writes for key, indented by
+// indent spaces.
+func yamlLines(key string, v any, indent int) []string {
+	var b bytes.Buffer
+	writeYAML(&b, key, v, 0)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	pad := strings.Repeat(" ", indent)
+	for i := range lines {
+		lines[i] = pad + lines[i]
+	}
+	return lines
+}
+
+// yamlEqual reports whether a, parsed from YAML, and b, a JSON-shaped
+// value, hold the same data, every scalar being compared as a string.
+func yamlEqual(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !yamlEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !yamlEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	}
+	switch b.(type) {
+	case map[string]any, []any, nil:
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func deleteString(s []string, v string) []string {
+	out := s[:0]
+	for _, x := range s {
+		if x != v {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// yamlScalar returns s as a plain scalar when that reads back as the same
+// string in any YAML parser, and double-quoted otherwise.
+func yamlScalar(s string) string {
+	if s == "" || !yamlPlainSafe(s) {
+		b, _ := json.Marshal(s) // JSON strings are valid double-quoted YAML
+		return string(b)
+	}
+	return s
+}
+
+func yamlPlainSafe(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return false
+	}
+	c := s[0]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '.' || c == '/') {
+		return false // digits could read as numbers or dates
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("_./@+-~", c) >= 0:
+		case c == ':' && i+1 < len(s) && s[i+1] != ' ':
+		default:
+			return false
+		}
+	}
+	return true
+}
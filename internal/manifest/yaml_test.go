@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   string
+		want map[string]any
+	}{
+		{"empty", "# nothing\n", map[string]any{}},
+		{
+			"block mapping",
+			"---\nagents:\n  planner: org/repo/planner.md@v1  # pinned\n  'quoted key': \"a # b\"\n",
+			map[string]any{"agents": map[string]any{"planner": "org/repo/planner.md@v1", "quoted key": "a # b"}},
+		},
+		{
+			"sequences",
+			"include:\n  - a.yaml\n  - 'b.yaml'\nmore:\n- c\nflow: [x, \"y\", 'it''s']\n",
+			map[string]any{
+				"include": []any{"a.yaml", "b.yaml"},
+				"more":    []any{"c"},
+				"flow":    []any{"x", "y", "it's"},
+			},
+		},
+		{
+			"flow mapping",
+			"review: { ref: awesome:review.md@v2, groups: [ci, backend] }\n",
+			map[string]any{"review": map[string]any{"ref": "awesome:review.md@v2", "groups": []any{"ci", "backend"}}},
+		},
+		{
+			"sequence of mappings",
+			"items:\n  - path: a.md\n    kind: prompt\n  - path: b.md\n    kind: agent\n",
+			map[string]any{"items": []any{
+				map[string]any{"path": "a.md", "kind": "prompt"},
+				map[string]any{"path": "b.md", "kind": "agent"},
+			}},
+		},
+		{
+			"scalars stay strings",
+			"a: 2025-12-31\nb: true\nc: \"tab\\there \\u00e9\"\nd:\n",
+			map[string]any{"a": "2025-12-31", "b": "true", "c": "tab\there é", "d": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, _, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("parseYAML() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"top-level sequence", "- a\n", "line 1: top level must be a mapping"},
+		{"bad indentation", "a:\n  b: c\n    d: e\n", "line 3: unexpected indentation"},
+		{"duplicate key", "a: x\na: y\n", `line 2: duplicate key "a"`},
+		{"tab indentation", "a:\n\tb: c\n", "line 2: tabs"},
+		{"anchor", "a: &x b\n", "line 1: unsupported YAML syntax"},
+		{"block scalar", "a: |\n  text\n", "line 1: unsupported YAML syntax"},
+		{"unterminated quote", "a: \"b\n", "line 1: unterminated quoted string"},
+		{"unterminated flow", "a: [b, c\n", "line 1: unterminated flow sequence"},
+		{"not a key", "just text\n", "line 1: expected"},
+		{"two documents", "a: b\n---\nc: d\n", "line 2: multiple documents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := parseYAML([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeYAML_RoundTrip(t *testing.T) {
+	t.Parallel()
+	doc := map[string]any{
+		"include": []any{"base.yaml"},
+		"agents": map[string]any{
+			"planner": "org/repo/planner.md@v1",
+			"tricky":  map[string]any{"target": "docs/a b.md", "ref": "alias:x.md", "allow_branch_until": "2025-12-31"},
+			"yes":     "true",
+		},
+	}
+	out := encodeYAML(doc, []string{"include", "agents"})
+	if !strings.Contains(string(out), "  tricky:\n    ref: alias:x.md\n") {
+		t.Errorf("ref is not written first:\n%s", out)
+	}
+	got, _, err := parseYAML(out)
+	if err != nil {
+		t.Fatalf("parseYAML(encodeYAML()) error: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(got, doc) {
+		t.Errorf("round trip = %#v, want %#v", got, doc)
+	}
+}
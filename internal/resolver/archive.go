@@ -0,0 +1,206 @@
+package resolver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// bundle is a set of files extracted from an archive or artifact, keyed by
+// slash-separated path relative to the archive root.
+type bundle map[string][]byte
+
+// isArchive reports whether extractArchive unpacks the file called name.
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractArchive unpacks data according to the archive name's extension,
+// up to MaxArchiveSize bytes of files. Anything that is not a recognised
+// archive becomes a single-file bundle.
+func extractArchive(name string, data []byte) (bundle, error) {
+	switch {
+	case !isArchive(name):
+		return bundle{name: data}, nil
+	case strings.HasSuffix(strings.ToLower(name), ".zip"):
+		return extractZip(data, MaxArchiveSize)
+	}
+	return extractTarball(data)
+}
+
+// extractZip unpacks a zip archive, failing with ErrTooLarge once its
+// files are over limit bytes. Directories are skipped.
+func extractZip(data []byte, limit int64) (bundle, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("opening zip archive: %w", err)
+	}
+
+	files := make(bundle)
+	left := limit
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, err := cleanArchivePath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if left == 0 {
+			return nil, fmt.Errorf("zip archive files: %w", tooLarge(limit))
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening %s in zip archive: %w", f.Name, err)
+		}
+		// The announced size may lie: the read stops at what is left.
+		content, err := readLimited(rc, int64(min(f.UncompressedSize64, 1<<62)), left)
+		_ = rc.Close()
+		if errors.Is(err, ErrTooLarge) {
+			return nil, fmt.Errorf("zip archive files: %w", tooLarge(limit))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from zip archive: %w", f.Name, err)
+		}
+		files[name] = content
+		left -= int64(len(content))
+	}
+	return files, nil
+}
+
+// extractTarball unpacks a tar archive, transparently handling gzip
+// compression, up to MaxArchiveSize bytes of files. Only regular files are
+// kept.
+func extractTarball(data []byte) (bundle, error) {
+	files, _, err := extractTarballFiltered(data, 0, nil, Limits{Dir: MaxArchiveSize})
+	return files, err
+}
+
+// extractTarballFiltered unpacks a tar archive, dropping the first strip
+// path components of every member and keeping only members for which keep
+// (if non-nil) returns true. It also returns the set of kept members that
+// have an execute bit set. Kept members fail with ErrTooLarge once one is
+// over limits.File or all are over limits.Dir.
+func extractTarballFiltered(data []byte, strip int, keep func(name string) bool, limits Limits) (bundle, map[string]bool, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening gzip stream: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	files := make(bundle)
+	executable := make(map[string]bool)
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := cleanArchivePath(hdr.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strip > 0 {
+			parts := strings.SplitN(name, "/", strip+1)
+			if len(parts) <= strip {
+				continue
+			}
+			name = parts[strip]
+		}
+		if keep != nil && !keep(name) {
+			continue
+		}
+		// Members announce their exact size, so the limits are checked
+		// before reading.
+		switch {
+		case limits.File > 0 && hdr.Size > limits.File:
+			return nil, nil, fmt.Errorf("%s in tar archive: %w", hdr.Name, tooLarge(limits.File))
+		case limits.Dir > 0 && total+hdr.Size > limits.Dir:
+			return nil, nil, fmt.Errorf("tar archive files: %w", tooLarge(limits.Dir))
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s from tar archive: %w", hdr.Name, err)
+		}
+		total += int64(len(content))
+		files[name] = content
+		if hdr.Mode&0111 != 0 {
+			executable[name] = true
+		}
+	}
+	return files, executable, nil
+}
+
+// cleanArchivePath normalises an archive member name and rejects entries
+// that would escape the extraction root.
+func cleanArchivePath(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the archive root", name)
+	}
+	return cleaned, nil
+}
+
+// file returns the content at p. If p is empty and the bundle holds a
+// single file, that file is returned.
+func (b bundle) file(p string) ([]byte, error) {
+	if p == "" && len(b) == 1 {
+		for _, content := range b {
+			return content, nil
+		}
+	}
+	if content, ok := b[p]; ok {
+		return content, nil
+	}
+	if content, ok := b[p+".md"]; ok {
+		return content, nil
+	}
+	return nil, fmt.Errorf("%s not found in bundle", p)
+}
+
+// entries lists the files at or under dir (the whole bundle when dir is
+// empty) in byte-wise path order.
+func (b bundle) entries(dir string) []GitHubTreeEntry {
+	var keys []string
+	for p := range b {
+		if dir == "" || p == dir || strings.HasPrefix(p, dir+"/") {
+			keys = append(keys, p)
+		}
+	}
+	slices.Sort(keys)
+
+	entries := make([]GitHubTreeEntry, 0, len(keys))
+	for _, p := range keys {
+		entries = append(entries, GitHubTreeEntry{
+			Path: p,
+			Type: "blob",
+			SHA:  fmt.Sprintf("%x", sha256.Sum256(b[p])),
+		})
+	}
+	return entries
+}
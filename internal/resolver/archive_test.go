@@ -0,0 +1,193 @@
+package resolver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+// buildTar creates a tar archive from path → content, gzipped if requested.
+func buildTar(t *testing.T, files map[string]string, gz bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gzw *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if gz {
+		gzw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gzw)
+	}
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTarball(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{
+		"./skills/k8s/SKILL.md": "skill",
+		"prompts/review.md":     "prompt",
+	}
+	for _, gz := range []bool{false, true} {
+		b, err := extractTarball(buildTar(t, files, gz))
+		if err != nil {
+			t.Fatalf("extractTarball(gz=%v): %v", gz, err)
+		}
+		if string(b["skills/k8s/SKILL.md"]) != "skill" || string(b["prompts/review.md"]) != "prompt" {
+			t.Errorf("extractTarball(gz=%v) = %v", gz, b)
+		}
+	}
+}
+
+func TestExtractTarballFiltered_Executable(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, mode := range map[string]int64{"repo/SKILL.md": 0644, "repo/run.sh": 0755} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, executable, err := extractTarballFiltered(buf.Bytes(), 1, nil, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !executable["run.sh"] || executable["SKILL.md"] {
+		t.Errorf("executable = %v, want only run.sh", executable)
+	}
+}
+
+func TestExtractTarballFiltered_Limits(t *testing.T) {
+	t.Parallel()
+	data := buildTar(t, map[string]string{"repo/a.md": "0123456789", "repo/b.md": "0123456789"}, true)
+	cases := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"within", Limits{File: 10, Dir: 20}, false},
+		{"file over", Limits{File: 9}, true},
+		{"files over together", Limits{Dir: 15}, true},
+	}
+	for _, tc := range cases {
+		_, _, err := extractTarballFiltered(data, 1, nil, tc.limits)
+		if tc.wantErr != errors.Is(err, ErrTooLarge) || (!tc.wantErr && err != nil) {
+			t.Errorf("%s: extractTarballFiltered: got %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestExtractZip_Limit(t *testing.T) {
+	t.Parallel()
+	// A megabyte of zeros compresses to a few hundred bytes.
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	w, _ := zw.Create("bomb.md")
+	_, _ = w.Write(make([]byte, 1<<20))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := extractZip(zbuf.Bytes(), 1<<10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("extractZip: got %v, want ErrTooLarge", err)
+	}
+	if _, err := extractZip(zbuf.Bytes(), 1<<20); err != nil {
+		t.Errorf("extractZip within the limit: %v", err)
+	}
+}
+
+func TestExtractTarball_RejectsEscapingPaths(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"../evil.md", "/etc/passwd", "a/../../evil.md"} {
+		if _, err := extractTarball(buildTar(t, map[string]string{name: "x"}, false)); err == nil {
+			t.Errorf("extractTarball(%q): expected error, got nil", name)
+		}
+	}
+}
+
+func TestBundle_FileAndEntries(t *testing.T) {
+	t.Parallel()
+	b := bundle{
+		"skills/k8s/SKILL.md":     []byte("skill"),
+		"skills/k8s/ref/notes.md": []byte("notes"),
+		"skills/k8s-extra/x.md":   []byte("x"),
+		"prompts/review.md":       []byte("prompt"),
+	}
+
+	if got, err := b.file("prompts/review"); err != nil || string(got) != "prompt" {
+		t.Errorf("file(prompts/review) = %q, %v", got, err)
+	}
+	if _, err := b.file("missing.md"); err == nil {
+		t.Error("file(missing.md): expected error")
+	}
+
+	entries := b.entries("skills/k8s")
+	if len(entries) != 2 || entries[0].Path != "skills/k8s/SKILL.md" || entries[1].Path != "skills/k8s/ref/notes.md" {
+		t.Errorf("entries(skills/k8s) = %+v", entries)
+	}
+	if got := len(b.entries("")); got != 4 {
+		t.Errorf("entries(\"\") returned %d entries, want 4", got)
+	}
+}
+
+func TestBundle_FileSingleFileDefault(t *testing.T) {
+	t.Parallel()
+	b := bundle{"only.md": []byte("only")}
+	if got, err := b.file(""); err != nil || string(got) != "only" {
+		t.Errorf("file(\"\") = %q, %v", got, err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	t.Parallel()
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	w, _ := zw.Create("prompts/review.md")
+	_, _ = w.Write([]byte("zipped"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+		path string
+		want string
+	}{
+		{"assets.zip", zbuf.Bytes(), "prompts/review.md", "zipped"},
+		{"assets.tgz", buildTar(t, map[string]string{"a.md": "tarred"}, true), "a.md", "tarred"},
+		{"assets.tar", buildTar(t, map[string]string{"a.md": "plain"}, false), "a.md", "plain"},
+		{"single.md", []byte("single"), "single.md", "single"},
+	}
+	for _, tc := range cases {
+		b, err := extractArchive(tc.name, tc.data)
+		if err != nil {
+			t.Fatalf("extractArchive(%s): %v", tc.name, err)
+		}
+		if got, err := b.file(tc.path); err != nil || string(got) != tc.want {
+			t.Errorf("extractArchive(%s).file(%s) = %q, %v", tc.name, tc.path, got, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// errObjectNotFound is returned by object stores when a key does not exist.
+var errObjectNotFound = errors.New("object not found")
+
+// objectInfo describes a single object in a bucket.
+type objectInfo struct {
+	Key  string
+	ETag string // unquoted
+}
+
+// objectStore is the backend-specific half of BucketSource.
+type objectStore interface {
+	// get downloads the object at ref.Path, at version ref.Ref if set,
+	// failing with ErrTooLarge past limit bytes (see readLimited).
+	get(ref config.AssetRef, limit int64) ([]byte, error)
+	// stat returns the metadata of the object at ref.Path, at version
+	// ref.Ref if set, or errObjectNotFound.
+	stat(ref config.AssetRef) (objectInfo, error)
+	// list returns the current objects whose key starts with prefix.
+	list(bucket, prefix string) ([]objectInfo, error)
+}
+
+// BucketOptions configures how BucketSource reaches object stores. The zero
+// value targets the public AWS and Google Cloud endpoints with the standard
+// credential chains.
+type BucketOptions struct {
+	S3Endpoint  string // custom S3-compatible endpoint (path-style addressing), e.g. MinIO
+	S3Region    string // AWS region; defaults to us-east-1
+	GCSEndpoint string // custom GCS endpoint, e.g. a storage emulator
+	Anonymous   bool   // skip credential lookup and send unsigned requests
+}
+
+// BucketOptionsFromEnv reads endpoint and region overrides from the
+// environment variables the AWS and Google Cloud tooling already use.
+func BucketOptionsFromEnv() BucketOptions {
+	opts := BucketOptions{
+		S3Endpoint: firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		S3Region:   firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+	}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		opts.GCSEndpoint = host
+	}
+	return opts
+}
+
+// firstEnv returns the value of the first non-empty environment variable.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// BucketSource serves assets stored in S3 ("s3://") or Google Cloud
+// Storage ("gs://") buckets, such as an enterprise's internal mirror.
+// Credentials come from each cloud's standard chain (environment, shared
+// config files, then instance metadata); with none available, requests are
+// sent anonymously so public buckets still work.
+//
+// Objects have no commit history, so ResolveSHA returns the object's ETag,
+// which the lock file records in place of a commit SHA.
+type BucketSource struct {
+	stores map[string]objectStore
+
+	readLimits
+}
+
+// NewBucketSource creates a BucketSource using the given HTTP client. The
+// client must not carry GitHub credentials.
+func NewBucketSource(client *http.Client, opts BucketOptions) *BucketSource {
+	return &BucketSource{stores: map[string]objectStore{
+		"s3": newS3Store(client, opts),
+		"gs": newGCSStore(client, opts),
+	}}
+}
+
+// Supports reports whether ref is an S3 or GCS object reference.
+func (s *BucketSource) Supports(ref config.AssetRef) bool {
+	return ref.IsBucket()
+}
+
+// ResolveRef returns the ref unchanged: object versions have no aliases.
+func (s *BucketSource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	return ref, nil
+}
+
+func (s *BucketSource) store(ref config.AssetRef) (objectStore, error) {
+	st, ok := s.stores[ref.Store]
+	if !ok {
+		return nil, fmt.Errorf("unsupported object store %q", ref.Store)
+	}
+	return st, nil
+}
+
+// DownloadFile fetches a single object. If the exact key does not exist, it
+// retries with a .md extension, like the GitHub source.
+func (s *BucketSource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	st, err := s.store(ref)
+	if err != nil {
+		return nil, err
+	}
+	limit := s.limits().File
+	data, err := st.get(ref, limit)
+	if errors.Is(err, errObjectNotFound) && !strings.HasSuffix(ref.Path, ".md") {
+		mdRef := ref
+		mdRef.Path += ".md"
+		if mdData, mdErr := st.get(mdRef, limit); mdErr == nil {
+			return mdData, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", ref.Raw(), err)
+	}
+	return data, nil
+}
+
+// ListDirectory lists the objects under the ref's key prefix. Versions only
+// identify single objects, so directory refs cannot pin one. Keys that
+// would lead out of the directory, such as "skills/k8s/../../x", fail the
+// listing.
+func (s *BucketSource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	if ref.Ref != "" {
+		return nil, fmt.Errorf("%s: object versions can only pin single files, not directories", ref.Raw())
+	}
+	objects, err := s.listObjects(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]GitHubTreeEntry, 0, len(objects))
+	for _, obj := range objects {
+		rel := path.Clean(strings.TrimPrefix(obj.Key, ref.Path+"/"))
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("listing %s: object %q is outside the directory", ref.Raw(), obj.Key)
+		}
+		entries = append(entries, GitHubTreeEntry{Path: obj.Key, Type: "blob", SHA: obj.ETag})
+	}
+	return entries, nil
+}
+
+// listObjects returns the objects under the ref's key prefix in byte-wise
+// key order, skipping "directory marker" keys, or an error if there are none.
+func (s *BucketSource) listObjects(ref config.AssetRef) ([]objectInfo, error) {
+	st, err := s.store(ref)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := st.list(ref.Bucket, ref.Path+"/")
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", ref.Raw(), err)
+	}
+
+	files := objects[:0]
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Key, "/") {
+			files = append(files, obj)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no objects found under %s", ref.Raw())
+	}
+	slices.SortFunc(files, func(a, b objectInfo) int { return strings.Compare(a.Key, b.Key) })
+	return files, nil
+}
+
+// ResolveSHA returns the ETag of the object at the ref. For a key prefix
+// (a directory asset) it returns a SHA-256 over the member keys and ETags,
+// so any change to the directory changes the recorded value.
+func (s *BucketSource) ResolveSHA(ref config.AssetRef) (string, error) {
+	st, err := s.store(ref)
+	if err != nil {
+		return "", err
+	}
+	info, err := st.stat(ref)
+	if err == nil {
+		return info.ETag, nil
+	}
+	if !errors.Is(err, errObjectNotFound) || ref.Ref != "" {
+		return "", fmt.Errorf("resolving %s: %w", ref.Raw(), err)
+	}
+
+	objects, err := s.listObjects(ref)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, obj := range objects {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\n", obj.Key, obj.ETag)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// objectStoreError converts a non-2xx response into an error.
+func objectStoreError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errObjectNotFound
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("HTTP %d — %s", resp.StatusCode, string(body))
+}
+
+// unquoteETag strips the quotes S3 wraps around ETag values.
+func unquoteETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
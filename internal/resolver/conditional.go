@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// ErrNotModified is returned by DownloadFileIfChanged when the file has not
+// changed since the download its validators came from.
+var ErrNotModified = errors.New("not modified")
+
+// Validators are the HTTP cache validators returned with a downloaded file.
+// Sending them back makes the next download conditional, so an unchanged
+// file costs a 304 response without a body.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// setHeaders makes req conditional on v.
+func (v Validators) setHeaders(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// validatorsOf returns the validators of resp.
+func validatorsOf(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// ConditionalDownloader is implemented by sources that can skip downloading
+// a file that has not changed since a previous download.
+type ConditionalDownloader interface {
+	// DownloadFileIfChanged is DownloadFile sent with the validators of a
+	// previous download. It returns ErrNotModified if the file has not
+	// changed, and otherwise its content with its new validators.
+	DownloadFileIfChanged(ref config.AssetRef, prev Validators) ([]byte, Validators, error)
+}
+
+// DownloadFileIfChanged delegates to the source that supports ref. Sources
+// without conditional requests download the file unconditionally.
+func (rt *Router) DownloadFileIfChanged(ref config.AssetRef, prev Validators) ([]byte, Validators, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	if cd, ok := s.(ConditionalDownloader); ok {
+		return cd.DownloadFileIfChanged(ref, prev)
+	}
+	data, err := s.DownloadFile(ref)
+	return data, Validators{}, err
+}
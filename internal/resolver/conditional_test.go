@@ -0,0 +1,74 @@
+package resolver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// conditionalHandler serves body with an ETag and Last-Modified, answering
+// 304 to requests that send them back.
+func conditionalHandler(body string) func(w http.ResponseWriter, r *http.Request) {
+	const etag, lastModified = `"v1"`, "Mon, 02 Feb 2026 10:00:00 GMT"
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestDownloadFileIfChanged(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/myorg/myrepo/main/prompts/review.md": conditionalHandler("# Review\n"),
+	})
+	t.Cleanup(ts.Close)
+	urlServer := httptest.NewTLSServer(http.HandlerFunc(conditionalHandler("# Review\n")))
+	t.Cleanup(urlServer.Close)
+
+	github := New(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}})
+	urlRef, err := config.ParseRef(urlServer.URL + "/prompts/review.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  ConditionalDownloader
+		ref  config.AssetRef
+	}{
+		{"github", github, config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "prompts/review.md", Ref: "main"}},
+		{"url", NewURLSource(urlServer.Client()), urlRef},
+		{"router", NewRouter(github), config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "prompts/review.md", Ref: "main"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data, v, err := tt.src.DownloadFileIfChanged(tt.ref, Validators{})
+			if err != nil || string(data) != "# Review\n" {
+				t.Fatalf("DownloadFileIfChanged(no validators) = %q, %v", data, err)
+			}
+			if v.ETag != `"v1"` || v.LastModified == "" {
+				t.Errorf("validators = %+v", v)
+			}
+			data, _, err = tt.src.DownloadFileIfChanged(tt.ref, v)
+			if !errors.Is(err, ErrNotModified) || data != nil {
+				t.Errorf("DownloadFileIfChanged(unchanged) = %q, %v; want ErrNotModified", data, err)
+			}
+		})
+	}
+}
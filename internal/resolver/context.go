@@ -0,0 +1,56 @@
+package resolver
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// WithContext returns a copy of client whose requests are cancelled, body
+// included, when ctx is done, so an interrupted command stops its
+// downloads in flight.
+func WithContext(client *http.Client, ctx context.Context) *http.Client {
+	bound := *client
+	bound.Transport = &contextTransport{base: client.Transport, ctx: ctx}
+	return &bound
+}
+
+// contextTransport is an http.RoundTripper cancelling every request when
+// ctx is done.
+type contextTransport struct {
+	base http.RoundTripper // nil means http.DefaultTransport
+	ctx  context.Context
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(t.ctx, cancel)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		stop()
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, release: func() { stop(); cancel() }}
+	return resp, nil
+}
+
+// cancelBody releases the cancellation of its request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
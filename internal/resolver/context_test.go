@@ -0,0 +1,40 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithContext(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := WithContext(ts.Client(), ctx)
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// A request in flight is cancelled with ctx.
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := client.Get(ts.URL + "/slow"); !errors.Is(err, context.Canceled) {
+		t.Errorf("in-flight request error = %v, want context.Canceled", err)
+	}
+	// So is any later request.
+	if _, err := client.Get(ts.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("later request error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,445 @@
+package resolver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// FulcioRootsEnvVar names the environment variable listing the PEM files
+// of the Fulcio root certificates keyless signatures must chain to,
+// separated like PATH entries.
+const FulcioRootsEnvVar = "COPS_FULCIO_ROOTS"
+
+// RekorKeysEnvVar names the environment variable listing the PEM files of
+// the public keys of the Rekor transparency logs keyless signatures must
+// be recorded in, separated like PATH entries.
+const RekorKeysEnvVar = "COPS_REKOR_PUBLIC_KEY"
+
+// Annotations cosign sets on the layers of a signature manifest.
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// Extensions Fulcio records the OIDC issuer of a certificate in: the
+// deprecated raw string, and its DER-encoded replacement.
+var (
+	fulcioIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	fulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// CosignPolicy says whose cosign signature an asset must carry: one made
+// with Key or, keyless, one recorded in a Rekor log signed by one of
+// RekorKeys, whose Fulcio certificate chains to Roots, was valid when the
+// signature was logged, and names Identity and Issuer.
+type CosignPolicy struct {
+	Key crypto.PublicKey // nil for keyless signatures
+
+	Identity  string             // certificate subject: a URI (e.g. a workflow) or an email
+	Issuer    string             // OIDC issuer, e.g. "https://token.actions.githubusercontent.com"
+	Roots     *x509.CertPool     // Fulcio root certificates
+	RekorKeys []crypto.PublicKey // public keys of the Rekor logs
+}
+
+// SignatureVerifier is implemented by sources whose assets can be signed
+// with cosign.
+type SignatureVerifier interface {
+	// VerifySignature checks that the asset ref points at is signed as
+	// policy requires and describes the signature that was accepted.
+	VerifySignature(ref config.AssetRef, policy CosignPolicy) (string, error)
+}
+
+// VerifySignature delegates to the source serving ref.
+func (rt *Router) VerifySignature(ref config.AssetRef, policy CosignPolicy) (string, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return "", err
+	}
+	v, ok := s.(SignatureVerifier)
+	if !ok {
+		return "", fmt.Errorf("cosign signatures can only be verified for OCI and release refs, not %s", ref.Raw())
+	}
+	return v.VerifySignature(ref, policy)
+}
+
+// ParseCosignKey parses a PEM-encoded public key, as written by
+// "cosign generate-key-pair".
+func ParseCosignKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM public key found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// FulcioRootsFromEnv returns the certificates of the files listed in
+// $COPS_FULCIO_ROOTS, or nil if it is unset.
+func FulcioRootsFromEnv() (*x509.CertPool, error) {
+	env := os.Getenv(FulcioRootsEnvVar)
+	if env == "" {
+		return nil, nil
+	}
+	roots := x509.NewCertPool()
+	for _, path := range filepath.SplitList(env) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", FulcioRootsEnvVar, err)
+		}
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no PEM certificate found in %s", FulcioRootsEnvVar, path)
+		}
+	}
+	return roots, nil
+}
+
+// RekorKeysFromEnv returns the public keys of the files listed in
+// $COPS_REKOR_PUBLIC_KEY, or nil if it is unset.
+func RekorKeysFromEnv() ([]crypto.PublicKey, error) {
+	env := os.Getenv(RekorKeysEnvVar)
+	if env == "" {
+		return nil, nil
+	}
+	var keys []crypto.PublicKey
+	for _, path := range filepath.SplitList(env) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", RekorKeysEnvVar, err)
+		}
+		key, err := ParseCosignKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", RekorKeysEnvVar, path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// verify checks sig, base64-encoded, over payload against the policy. For
+// keyless signatures, certPEM is the signing certificate, chainPEM the
+// intermediates, if any, and bundle the Rekor bundle recording the
+// signature. It returns who signed.
+func (p CosignPolicy) verify(payload []byte, sig string, certPEM, chainPEM, bundle []byte) (string, error) {
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil {
+		return "", fmt.Errorf("decoding signature: %w", err)
+	}
+	if p.Key != nil {
+		if err := verifySignature(p.Key, payload, rawSig); err != nil {
+			return "", err
+		}
+		return "cosign key " + keyFingerprint(p.Key), nil
+	}
+
+	if p.Roots == nil {
+		return "", fmt.Errorf("keyless verification needs the Fulcio root certificates: set %s", FulcioRootsEnvVar)
+	}
+	if len(p.RekorKeys) == 0 {
+		return "", fmt.Errorf("keyless verification needs the Rekor public key: set %s", RekorKeysEnvVar)
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return "", fmt.Errorf("signature certificate: %w", err)
+	}
+	logged, err := p.verifyBundle(bundle, payload, rawSig, cert)
+	if err != nil {
+		return "", fmt.Errorf("transparency log: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	if len(chainPEM) > 0 {
+		intermediates.AppendCertsFromPEM(chainPEM)
+	}
+	// Fulcio certificates are valid for minutes: the signature must have
+	// been logged within that window, which is checked rather than the
+	// current time.
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         p.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   logged,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return "", fmt.Errorf("signature certificate: %w", err)
+	}
+	if !slices.Contains(certIdentities(cert), p.Identity) {
+		return "", fmt.Errorf("signed by %s, not %s", strings.Join(certIdentities(cert), ", "), p.Identity)
+	}
+	if issuer := certIssuer(cert); issuer != p.Issuer {
+		return "", fmt.Errorf("signature issued by %q, not %q", issuer, p.Issuer)
+	}
+	if err := verifySignature(cert.PublicKey, payload, rawSig); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("cosign keyless %s (%s)", p.Identity, p.Issuer), nil
+}
+
+// rekorBundle is the proof cosign attaches to a signature that Rekor
+// recorded it: the log entry and the log's signature over it.
+type rekorBundle struct {
+	SignedEntryTimestamp []byte     `json:"SignedEntryTimestamp"`
+	Payload              rekorEntry `json:"Payload"`
+}
+
+// rekorEntry is a Rekor log entry. Its fields are in the order of its
+// canonical JSON form, which the signed entry timestamp signs.
+type rekorEntry struct {
+	Body           string `json:"body"` // base64 of the entry, see hashedRekord
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the Rekor entry of a signature.
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"` // PEM certificate
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle checks that bundle, a Rekor bundle or the bundle
+// "cosign sign-blob --bundle" writes, proves that a log of p.RekorKeys
+// recorded sig over payload with cert, and returns when it did.
+func (p CosignPolicy) verifyBundle(bundle, payload, sig []byte, cert *x509.Certificate) (time.Time, error) {
+	if len(bundle) == 0 {
+		return time.Time{}, errors.New("missing: keyless signatures must come with a Rekor bundle")
+	}
+	var b rekorBundle
+	var blob struct {
+		RekorBundle *rekorBundle `json:"rekorBundle"`
+	}
+	if err := json.Unmarshal(bundle, &blob); err == nil && blob.RekorBundle != nil {
+		b = *blob.RekorBundle
+	} else if err := json.Unmarshal(bundle, &b); err != nil {
+		return time.Time{}, fmt.Errorf("decoding bundle: %w", err)
+	}
+
+	signed, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !slices.ContainsFunc(p.RekorKeys, func(key crypto.PublicKey) bool {
+		return verifySignature(key, signed, b.SignedEntryTimestamp) == nil
+	}) {
+		return time.Time{}, errors.New("signed entry timestamp does not match the Rekor public key")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decoding entry: %w", err)
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("decoding entry: %w", err)
+	}
+	if entry.Kind != "hashedrekord" {
+		return time.Time{}, fmt.Errorf("unsupported entry kind %q", entry.Kind)
+	}
+	digest := sha256.Sum256(payload)
+	hash := entry.Spec.Data.Hash
+	if hash.Algorithm != "sha256" || !strings.EqualFold(hash.Value, hex.EncodeToString(digest[:])) {
+		return time.Time{}, errors.New("entry is for other content")
+	}
+	if string(entry.Spec.Signature.Content) != string(sig) {
+		return time.Time{}, errors.New("entry is for another signature")
+	}
+	logged, err := parseCertificate(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || !logged.Equal(cert) {
+		return time.Time{}, errors.New("entry is for another certificate")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// verifySignature checks sig over payload with key, hashing with SHA-256
+// as cosign does.
+func verifySignature(key crypto.PublicKey, payload, sig []byte) error {
+	digest := sha256.Sum256(payload)
+	ok := false
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, payload, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !ok {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// keyFingerprint identifies key by the SHA-256 of its encoding.
+func keyFingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "(unknown)"
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(der))
+}
+
+// parseCertificate parses a PEM certificate, or one base64-encoded as
+// "cosign sign-blob --output-certificate" writes it.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	if len(data) == 0 {
+		return nil, errors.New("missing: keyless signatures come with a certificate")
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, errors.New("not a PEM certificate")
+		}
+		data = decoded
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("not a PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// certIdentities returns the subjects a Fulcio certificate was issued to.
+func certIdentities(cert *x509.Certificate) []string {
+	ids := slices.Clone(cert.EmailAddresses)
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	return ids
+}
+
+// certIssuer returns the OIDC issuer a Fulcio certificate records.
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(fulcioIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(fulcioIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// cosignPayload is the part of the simple signing payload cosign signs for
+// a container image that ties the signature to it.
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// VerifySignature checks the cosign signatures stored next to the
+// artifact, under the "sha256-<digest>.sig" tag of its repository. One
+// signature matching the policy is enough.
+func (s *OCISource) VerifySignature(ref config.AssetRef, policy CosignPolicy) (string, error) {
+	_, digest, err := s.loadBundle(ref)
+	if err != nil {
+		return "", err
+	}
+	sigRef := ref
+	sigRef.Ref = strings.Replace(digest, ":", "-", 1) + ".sig"
+	m, _, err := s.fetchManifest(sigRef)
+	if err != nil {
+		return "", fmt.Errorf("no cosign signature found for %s: %w", ref.Raw(), err)
+	}
+
+	var errs []string
+	for _, layer := range m.Layers {
+		sig, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := s.fetchBlob(sigRef, layer, ociMaxManifestSize)
+		if err != nil {
+			return "", err
+		}
+		var p cosignPayload
+		if err := json.Unmarshal(payload, &p); err != nil || p.Critical.Image.DockerManifestDigest != digest {
+			errs = append(errs, "signature is for another artifact")
+			continue
+		}
+		signer, err := policy.verify(payload, sig,
+			[]byte(layer.Annotations[cosignCertificateAnnotation]), []byte(layer.Annotations[cosignChainAnnotation]),
+			[]byte(layer.Annotations[cosignBundleAnnotation]))
+		if err == nil {
+			return signer, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("no cosign signature found for %s", ref.Raw())
+	}
+	return "", fmt.Errorf("cosign verification failed for %s: %s", ref.Raw(), strings.Join(errs, "; "))
+}
+
+// VerifySignature checks the signature "cosign sign-blob" made of the
+// release asset, published as the "<asset>.sig" asset of the same release
+// with, for keyless signatures, its certificate as "<asset>.pem" and its
+// Rekor bundle as "<asset>.bundle".
+func (s *ReleaseSource) VerifySignature(ref config.AssetRef, policy CosignPolicy) (string, error) {
+	if _, err := s.loadBundle(ref); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	data := s.assets[releaseKey(ref)]
+	s.mu.Unlock()
+
+	release, err := s.release(ref)
+	if err != nil {
+		return "", err
+	}
+	sig, err := s.downloadNamedAsset(ref, release, ref.ReleaseAsset+".sig")
+	if err != nil {
+		return "", fmt.Errorf("no cosign signature found for %s: %w", ref.Raw(), err)
+	}
+	var cert, bundle []byte
+	if policy.Key == nil {
+		if cert, err = s.downloadNamedAsset(ref, release, ref.ReleaseAsset+".pem"); err != nil {
+			return "", fmt.Errorf("no cosign certificate found for %s: %w", ref.Raw(), err)
+		}
+		if bundle, err = s.downloadNamedAsset(ref, release, ref.ReleaseAsset+".bundle"); err != nil {
+			return "", fmt.Errorf("no Rekor bundle found for %s: %w", ref.Raw(), err)
+		}
+	}
+	signer, err := policy.verify(data, string(sig), cert, nil, bundle)
+	if err != nil {
+		return "", fmt.Errorf("cosign verification failed for %s: %w", ref.Raw(), err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,276 @@
+package resolver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// cosignSign signs payload with key as cosign does, base64-encoded.
+func cosignSign(t *testing.T, key *ecdsa.PrivateKey, payload []byte) string {
+	t.Helper()
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// rekorTestBundle returns the Rekor bundle of an entry recording sig over
+// payload with certPEM at logged, signed with rekorKey.
+func rekorTestBundle(t *testing.T, rekorKey *ecdsa.PrivateKey, payload []byte, sig string, certPEM []byte, logged time.Time) []byte {
+	t.Helper()
+	digest := sha256.Sum256(payload)
+	rawSig, _ := base64.StdEncoding.DecodeString(sig)
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data":      map[string]any{"hash": map[string]string{"algorithm": "sha256", "value": fmt.Sprintf("%x", digest)}},
+			"signature": map[string]any{"content": rawSig, "publicKey": map[string]any{"content": certPEM}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := rekorEntry{Body: base64.StdEncoding.EncodeToString(body), IntegratedTime: logged.Unix(), LogID: "c0d23d6a", LogIndex: 42}
+	signed, _ := json.Marshal(entry)
+	set, _ := base64.StdEncoding.DecodeString(cosignSign(t, rekorKey, signed))
+	bundle, err := json.Marshal(rekorBundle{SignedEntryTimestamp: set, Payload: entry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+func newCosignKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseCosignKey(t *testing.T) {
+	t.Parallel()
+	key := newCosignKey(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParseCosignKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || !key.PublicKey.Equal(pub) {
+		t.Errorf("ParseCosignKey() = %v, %v; want the public key", pub, err)
+	}
+	if _, err := ParseCosignKey([]byte("not a key")); err == nil {
+		t.Error("ParseCosignKey() accepted a non-PEM input")
+	}
+}
+
+// newSignedRegistryServer serves the artifact "team/assets:v1", holding
+// review.md, with a cosign signature made by sign unless sign is nil.
+func newSignedRegistryServer(t *testing.T, sign func(payload []byte) string) *httptest.Server {
+	t.Helper()
+	content := []byte("# Review\n")
+	layer := ociDescriptor{MediaType: "text/markdown", Digest: sha256Digest(content), Annotations: map[string]string{ociTitleAnnotation: "review.md"}}
+	manifest, _ := json.Marshal(ociManifest{MediaType: ociManifestMediaType, Layers: []ociDescriptor{layer}})
+	digest := sha256Digest(manifest)
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"team/assets"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+	blobs := map[string][]byte{layer.Digest: content, sha256Digest(payload): payload}
+	var sigManifest []byte
+	if sign != nil {
+		sigLayer := ociDescriptor{
+			MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+			Digest:      sha256Digest(payload),
+			Annotations: map[string]string{cosignSignatureAnnotation: sign(payload)},
+		}
+		sigManifest, _ = json.Marshal(ociManifest{MediaType: ociManifestMediaType, Layers: []ociDescriptor{sigLayer}})
+	}
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/team/assets/manifests/v1":
+			_, _ = w.Write(manifest)
+		case r.URL.Path == "/v2/team/assets/manifests/"+strings.Replace(digest, ":", "-", 1)+".sig" && sigManifest != nil:
+			_, _ = w.Write(sigManifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/team/assets/blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/team/assets/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOCISource_VerifySignature(t *testing.T) {
+	t.Parallel()
+	key, other := newCosignKey(t), newCosignKey(t)
+
+	ts := newSignedRegistryServer(t, func(payload []byte) string { return cosignSign(t, key, payload) })
+	defer ts.Close()
+	src := NewOCISource(ts.Client(), "")
+	ref := ociTestRef(t, ts, "review.md")
+
+	signer, err := src.VerifySignature(ref, CosignPolicy{Key: &key.PublicKey})
+	if err != nil || signer != "cosign key "+keyFingerprint(&key.PublicKey) {
+		t.Errorf("VerifySignature() = %q, %v; want the signing key", signer, err)
+	}
+	if _, err := src.VerifySignature(ref, CosignPolicy{Key: &other.PublicKey}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("VerifySignature() with another key: error = %v, want a mismatch", err)
+	}
+
+	unsigned := newSignedRegistryServer(t, nil)
+	defer unsigned.Close()
+	_, err = NewOCISource(unsigned.Client(), "").VerifySignature(ociTestRef(t, unsigned, "review.md"), CosignPolicy{Key: &key.PublicKey})
+	if err == nil || !strings.Contains(err.Error(), "no cosign signature") {
+		t.Errorf("VerifySignature() of an unsigned artifact: error = %v", err)
+	}
+}
+
+func TestReleaseSource_VerifySignature(t *testing.T) {
+	t.Parallel()
+	key := newCosignKey(t)
+	data := []byte("# Review")
+	sig := cosignSign(t, key, data)
+
+	var ts *httptest.Server
+	ts = newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/myrepo/releases/tags/v1.2.0": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"assets": []map[string]string{
+				{"name": "review.prompt.md", "url": ts.URL + "/assets/1"},
+				{"name": "review.prompt.md.sig", "url": ts.URL + "/assets/2"},
+			}})
+		},
+		"/assets/1": func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(data) },
+		"/assets/2": func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(sig)) },
+	})
+	defer ts.Close()
+	src := NewReleaseSource(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}})
+
+	ref, _ := config.ParseRef("myorg/myrepo!release:v1.2.0/review.prompt.md")
+	if _, err := src.VerifySignature(ref, CosignPolicy{Key: &key.PublicKey}); err != nil {
+		t.Errorf("VerifySignature() = %v", err)
+	}
+	if _, err := src.VerifySignature(ref, CosignPolicy{Key: &newCosignKey(t).PublicKey}); err == nil {
+		t.Error("VerifySignature() accepted a signature made with another key")
+	}
+}
+
+func TestCosignPolicy_Keyless(t *testing.T) {
+	t.Parallel()
+
+	// A root CA standing in for Fulcio, and a leaf certificate issued to
+	// a GitHub Actions workflow.
+	rootKey := newCosignKey(t)
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+
+	const identity = "https://github.com/my-org/skills/.github/workflows/release.yml@refs/tags/v1"
+	const issuer = "https://token.actions.githubusercontent.com"
+	issuerExt, _ := asn1.Marshal(issuer)
+	workflow, _ := url.Parse(identity)
+	leafKey := newCosignKey(t)
+	leafTmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(-time.Second), // expired since, like any Fulcio certificate
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{workflow},
+		ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerV2, Value: issuerExt}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, root, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	payload := []byte("bundle")
+	sig := cosignSign(t, leafKey, payload)
+	rekorKey := newCosignKey(t)
+	rekor := []crypto.PublicKey{&rekorKey.PublicKey}
+	bundle := rekorTestBundle(t, rekorKey, payload, sig, certPEM, time.Now().Add(-30*time.Second))
+	policy := CosignPolicy{Identity: identity, Issuer: issuer, Roots: roots, RekorKeys: rekor}
+
+	signer, err := policy.verify(payload, sig, certPEM, nil, bundle)
+	if err != nil || !strings.Contains(signer, identity) {
+		t.Errorf("verify() = %q, %v; want the workflow identity", signer, err)
+	}
+	// cosign sign-blob writes the certificate base64-encoded, and the Rekor
+	// bundle inside its own.
+	encoded := []byte(base64.StdEncoding.EncodeToString(certPEM))
+	blob := []byte(fmt.Sprintf(`{"base64Signature": %q, "rekorBundle": %s}`, sig, bundle))
+	if _, err := policy.verify(payload, sig, encoded, nil, blob); err != nil {
+		t.Errorf("verify() with a sign-blob bundle = %v", err)
+	}
+
+	otherSig := cosignSign(t, leafKey, []byte("other"))
+	cases := map[string]struct {
+		policy CosignPolicy
+		bundle []byte
+		want   string
+	}{
+		"other identity": {CosignPolicy{Identity: "https://github.com/evil/repo", Issuer: issuer, Roots: roots, RekorKeys: rekor}, bundle, "signed by"},
+		"other issuer":   {CosignPolicy{Identity: identity, Issuer: "https://accounts.google.com", Roots: roots, RekorKeys: rekor}, bundle, "issued by"},
+		"untrusted root": {CosignPolicy{Identity: identity, Issuer: issuer, Roots: x509.NewCertPool(), RekorKeys: rekor}, bundle, "certificate"},
+		"no roots":       {CosignPolicy{Identity: identity, Issuer: issuer, RekorKeys: rekor}, bundle, FulcioRootsEnvVar},
+		"no rekor key":   {CosignPolicy{Identity: identity, Issuer: issuer, Roots: roots}, bundle, RekorKeysEnvVar},
+		"no bundle":      {policy, nil, "must come with a Rekor bundle"},
+		"other log":      {CosignPolicy{Identity: identity, Issuer: issuer, Roots: roots, RekorKeys: []crypto.PublicKey{&newCosignKey(t).PublicKey}}, bundle, "does not match the Rekor public key"},
+		// A signature logged after the certificate expired, as one made
+		// with a leaked key would be.
+		"logged after expiry":        {policy, rekorTestBundle(t, rekorKey, payload, sig, certPEM, time.Now()), "expired"},
+		"entry of another signature": {policy, rekorTestBundle(t, rekorKey, payload, otherSig, certPEM, time.Now().Add(-30*time.Second)), "another signature"},
+		"entry of other content":     {policy, rekorTestBundle(t, rekorKey, []byte("other"), sig, certPEM, time.Now().Add(-30*time.Second)), "other content"},
+	}
+	for name, tc := range cases {
+		if _, err := tc.policy.verify(payload, sig, certPEM, nil, tc.bundle); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: verify() error = %v, want %q", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package resolver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+const (
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+	defaultGoogleToken = "https://oauth2.googleapis.com/token"
+	gcsReadOnlyScope   = "https://www.googleapis.com/auth/devstorage.read_only"
+)
+
+// gcsStore talks to the Google Cloud Storage JSON API.
+type gcsStore struct {
+	client    *http.Client
+	endpoint  string
+	anonymous bool
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // zero for tokens with unknown lifetime
+	loaded  bool
+}
+
+func newGCSStore(client *http.Client, opts BucketOptions) *gcsStore {
+	endpoint := opts.GCSEndpoint
+	if endpoint == "" {
+		endpoint = defaultGCSEndpoint
+	}
+	return &gcsStore{
+		client:    client,
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		anonymous: opts.Anonymous,
+	}
+}
+
+// objectURL returns the JSON API URL of the object. Object names are
+// escaped as a single path segment, slashes included.
+func (s *gcsStore) objectURL(ref config.AssetRef, media bool) string {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", s.endpoint, url.PathEscape(ref.Bucket),
+		strings.ReplaceAll(url.PathEscape(ref.Path), "/", "%2F"))
+	q := url.Values{}
+	if media {
+		q.Set("alt", "media")
+	}
+	if ref.Ref != "" {
+		q.Set("generation", ref.Ref)
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// do sends an authorized GET request; the caller closes the response.
+func (s *gcsStore) do(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.client.Do(req)
+}
+
+func (s *gcsStore) get(ref config.AssetRef, limit int64) ([]byte, error) {
+	resp, err := s.do(s.objectURL(ref, true))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := objectStoreError(resp); err != nil {
+		return nil, err
+	}
+	return readBody(resp, limit)
+}
+
+// gcsObject is the subset of the GCS object resource cops needs.
+type gcsObject struct {
+	Name string `json:"name"`
+	ETag string `json:"etag"`
+}
+
+func (s *gcsStore) stat(ref config.AssetRef) (objectInfo, error) {
+	resp, err := s.do(s.objectURL(ref, false))
+	if err != nil {
+		return objectInfo{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := objectStoreError(resp); err != nil {
+		return objectInfo{}, err
+	}
+	var obj gcsObject
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return objectInfo{}, fmt.Errorf("decoding object metadata: %w", err)
+	}
+	return objectInfo{Key: obj.Name, ETag: obj.ETag}, nil
+}
+
+func (s *gcsStore) list(bucket, prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	pageToken := ""
+	for {
+		q := url.Values{"prefix": {prefix}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		resp, err := s.do(fmt.Sprintf("%s/storage/v1/b/%s/o?%s", s.endpoint, url.PathEscape(bucket), q.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items         []gcsObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		err = objectStoreError(resp)
+		if err == nil {
+			err = json.NewDecoder(resp.Body).Decode(&page)
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			objects = append(objects, objectInfo{Key: item.Name, ETag: item.ETag})
+		}
+		if page.NextPageToken == "" {
+			return objects, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// accessToken returns a cached OAuth2 access token, resolving one through
+// the credential chain on first use or after expiry. It returns "" for
+// anonymous access.
+func (s *gcsStore) accessToken() (string, error) {
+	if s.anonymous {
+		return "", nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded && (s.expires.IsZero() || time.Now().Before(s.expires.Add(-time.Minute))) {
+		return s.token, nil
+	}
+	token, expires, err := googleCredentialChain(s.client)
+	if err != nil {
+		return "", fmt.Errorf("loading Google Cloud credentials: %w", err)
+	}
+	s.token, s.expires, s.loaded = token, expires, true
+	return token, nil
+}
+
+// googleToken is an OAuth2 token endpoint response.
+type googleToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (t googleToken) expiry() time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// googleCredentialChain resolves an access token the way Google's
+// Application Default Credentials do: GOOGLE_OAUTH_ACCESS_TOKEN, the
+// GOOGLE_APPLICATION_CREDENTIALS key file, gcloud's application default
+// credentials, then the GCE metadata server. It returns "" when no
+// credentials are configured.
+func googleCredentialChain(client *http.Client) (string, time.Time, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, time.Time{}, nil
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudADCPath()
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			tok, err := exchangeGoogleCredentials(client, data)
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("%s: %w", path, err)
+			}
+			return tok.AccessToken, tok.expiry(), nil
+		}
+		if !os.IsNotExist(err) || os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+			return "", time.Time{}, err
+		}
+	}
+
+	tok, ok := googleMetadataToken(client)
+	if !ok {
+		return "", time.Time{}, nil
+	}
+	return tok.AccessToken, tok.expiry(), nil
+}
+
+// gcloudADCPath returns where `gcloud auth application-default login`
+// stores credentials.
+func gcloudADCPath() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "gcloud", "application_default_credentials.json")
+		}
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// googleCredentialsFile is the union of the credential file formats cops
+// understands.
+type googleCredentialsFile struct {
+	Type string `json:"type"`
+
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// exchangeGoogleCredentials trades a credentials file for an access token.
+func exchangeGoogleCredentials(client *http.Client, data []byte) (googleToken, error) {
+	var cf googleCredentialsFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return googleToken{}, fmt.Errorf("parsing credentials: %w", err)
+	}
+	tokenURI := cf.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultGoogleToken
+	}
+
+	var form url.Values
+	switch cf.Type {
+	case "service_account":
+		assertion, err := serviceAccountJWT(cf, tokenURI, time.Now())
+		if err != nil {
+			return googleToken{}, err
+		}
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {cf.ClientID},
+			"client_secret": {cf.ClientSecret},
+			"refresh_token": {cf.RefreshToken},
+		}
+	default:
+		return googleToken{}, fmt.Errorf("unsupported credentials type %q", cf.Type)
+	}
+
+	resp, err := client.PostForm(tokenURI, form)
+	if err != nil {
+		return googleToken{}, fmt.Errorf("requesting access token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return googleToken{}, fmt.Errorf("requesting access token: HTTP %d — %s", resp.StatusCode, string(body))
+	}
+	var tok googleToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return googleToken{}, fmt.Errorf("decoding access token: %w", err)
+	}
+	return tok, nil
+}
+
+// serviceAccountJWT builds the signed RS256 assertion a service account
+// exchanges for an access token.
+func serviceAccountJWT(cf googleCredentialsFile, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(cf.PrivateKey))
+	if block == nil {
+		return "", errors.New("service account private_key is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", errors.New("service account private_key is not an RSA key")
+		}
+		key = rsaKey
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("parsing service account private_key: %w", err)
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if cf.PrivateKeyID != "" {
+		header["kid"] = cf.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   cf.ClientEmail,
+		"scope": gcsReadOnlyScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing service account assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// googleMetadataToken asks the GCE metadata server for the default service
+// account's token. It reports false when not running on Google Cloud.
+func googleMetadataToken(client *http.Client) (googleToken, bool) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequest(http.MethodGet,
+		"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return googleToken{}, false
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var tok googleToken
+	if err := getJSON(metadataClient(client), req, &tok); err != nil || tok.AccessToken == "" {
+		return googleToken{}, false
+	}
+	return tok, true
+}
@@ -0,0 +1,201 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// fullCommitSHA matches a ref naming a commit by its full SHA.
+var fullCommitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// graphQLBatchSize caps the (repository, ref) pairs resolved by a single
+// GraphQL query, well within GitHub's query complexity limits.
+const graphQLBatchSize = 100
+
+// SHAPrefetcher is implemented by sources that can resolve the commit SHAs
+// of many refs at once. ResolveSHA then answers the prefetched refs without
+// a request of its own.
+type SHAPrefetcher interface {
+	// PrefetchSHAs resolves the commit SHAs of refs. Refs it cannot
+	// resolve are left to ResolveSHA, so an error only means the batch
+	// saved no requests.
+	PrefetchSHAs(refs []config.AssetRef) error
+}
+
+// PrefetchSHAs hands each source that is an SHAPrefetcher the refs it
+// serves.
+func (rt *Router) PrefetchSHAs(refs []config.AssetRef) error {
+	bySource := make(map[SHAPrefetcher][]config.AssetRef)
+	var order []SHAPrefetcher
+	for _, ref := range refs {
+		s, err := rt.sourceFor(ref)
+		if err != nil {
+			continue
+		}
+		p, ok := s.(SHAPrefetcher)
+		if !ok {
+			continue
+		}
+		if _, seen := bySource[p]; !seen {
+			order = append(order, p)
+		}
+		bySource[p] = append(bySource[p], ref)
+	}
+	var errs []error
+	for _, p := range order {
+		if err := p.PrefetchSHAs(bySource[p]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// shaKey identifies a (repository, ref) pair in the prefetched SHAs.
+func shaKey(ref config.AssetRef) string {
+	return ref.RepoFullName() + "@" + ref.Ref
+}
+
+// prefetchedSHA returns the commit SHA PrefetchSHAs resolved for ref.
+func (r *Resolver) prefetchedSHA(ref config.AssetRef) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sha, ok := r.shas[shaKey(ref)]
+	return sha, ok
+}
+
+// PrefetchSHAs resolves the commit SHAs of every distinct (repository,
+// ref) pair of refs through the GitHub GraphQL API, in one query per
+// graphQLBatchSize pairs instead of one REST call each. "latest" resolves
+// to the head of the default branch, and a full commit SHA to itself.
+// GraphQL needs a token: without one, ResolveSHA falls back to the REST
+// API.
+func (r *Resolver) PrefetchSHAs(refs []config.AssetRef) error {
+	seen := make(map[string]bool)
+	var pending []config.AssetRef
+	for _, ref := range refs {
+		key := shaKey(ref)
+		if _, done := r.prefetchedSHA(ref); done || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if fullCommitSHA.MatchString(ref.Ref) {
+			r.mu.Lock()
+			r.shas[key] = ref.Ref
+			r.mu.Unlock()
+			continue
+		}
+		pending = append(pending, ref)
+	}
+	for len(pending) > 0 {
+		batch := pending[:min(len(pending), graphQLBatchSize)]
+		pending = pending[len(batch):]
+		if err := r.prefetchBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// graphQLObject is the part of a resolved ref the query asks for: the
+// commit itself, or the commit an annotated tag points at.
+type graphQLObject struct {
+	Typename string `json:"__typename"`
+	OID      string `json:"oid"`
+	Target   *struct {
+		Typename string `json:"__typename"`
+		OID      string `json:"oid"`
+	} `json:"target"`
+}
+
+// commitSHA returns the SHA of the commit o is or points at.
+func (o *graphQLObject) commitSHA() string {
+	switch {
+	case o == nil:
+		return ""
+	case o.Typename == "Commit":
+		return o.OID
+	case o.Target != nil && o.Target.Typename == "Commit":
+		return o.Target.OID
+	}
+	return ""
+}
+
+// prefetchBatch resolves refs with a single GraphQL query, one aliased
+// repository field per ref, and records the SHAs found.
+func (r *Resolver) prefetchBatch(refs []config.AssetRef) error {
+	const object = `{ __typename oid ... on Tag { target { __typename oid } } }`
+	var params, fields []string
+	vars := make(map[string]string)
+	for i, ref := range refs {
+		params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+		vars[fmt.Sprintf("o%d", i)], vars[fmt.Sprintf("n%d", i)] = ref.Org, ref.Repo
+		if ref.Ref == "latest" {
+			fields = append(fields, fmt.Sprintf("r%d: repository(owner: $o%d, name: $n%d) { defaultBranchRef { target %s } }", i, i, i, object))
+			continue
+		}
+		params = append(params, fmt.Sprintf("$e%d: String!", i))
+		vars[fmt.Sprintf("e%d", i)] = ref.Ref
+		fields = append(fields, fmt.Sprintf("r%d: repository(owner: $o%d, name: $n%d) { object(expression: $e%d) %s }", i, i, i, i, object))
+	}
+	query := fmt.Sprintf("query(%s) {\n%s\n}", strings.Join(params, ", "), strings.Join(fields, "\n"))
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, githubAPIBase+"/graphql", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("resolving commit SHAs: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("resolving commit SHAs: HTTP %d — %s", resp.StatusCode, string(msg))
+	}
+
+	// Refs that do not resolve come back null, with an entry in "errors";
+	// ResolveSHA reports them on its own.
+	var result struct {
+		Data map[string]*struct {
+			Object           *graphQLObject `json:"object"`
+			DefaultBranchRef *struct {
+				Target *graphQLObject `json:"target"`
+			} `json:"defaultBranchRef"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding commit SHAs: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, ref := range refs {
+		repo := result.Data[fmt.Sprintf("r%d", i)]
+		if repo == nil {
+			continue
+		}
+		obj := repo.Object
+		if repo.DefaultBranchRef != nil {
+			obj = repo.DefaultBranchRef.Target
+		}
+		if sha := obj.commitSHA(); sha != "" {
+			r.shas[shaKey(ref)] = sha
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestPrefetchSHAs(t *testing.T) {
+	t.Parallel()
+
+	const pinned = "0123456789abcdef0123456789abcdef01234567"
+	var queries int32
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/graphql": func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&queries, 1)
+			var req struct {
+				Query     string            `json:"query"`
+				Variables map[string]string `json:"variables"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decoding query: %v", err)
+			}
+			if strings.Contains(req.Query, pinned) || len(req.Variables) != 11 {
+				t.Errorf("variables = %v, want 4 refs and no pinned commit", req.Variables)
+			}
+			_, _ = w.Write([]byte(`{"data": {
+				"r0": {"object": {"__typename": "Commit", "oid": "aaa"}},
+				"r1": {"object": {"__typename": "Tag", "oid": "tag", "target": {"__typename": "Commit", "oid": "bbb"}}},
+				"r2": {"defaultBranchRef": {"target": {"__typename": "Commit", "oid": "ccc"}}},
+				"r3": {"object": null}
+			}, "errors": [{"message": "Could not resolve to a Revision"}]}`))
+		},
+	})
+	defer ts.Close()
+
+	res := New(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}})
+	ref := func(repo, at string) config.AssetRef {
+		return config.AssetRef{Org: "myorg", Repo: repo, Path: "go.md", Ref: at}
+	}
+	refs := []config.AssetRef{
+		ref("a", "main"), ref("b", "v1.0"), ref("c", "latest"), ref("d", "gone"),
+		ref("a", "main"), ref("e", pinned),
+	}
+	if err := res.PrefetchSHAs(refs); err != nil {
+		t.Fatal(err)
+	}
+	if queries != 1 {
+		t.Errorf("sent %d queries, want 1", queries)
+	}
+
+	for _, tt := range []struct {
+		ref  config.AssetRef
+		want string
+	}{
+		{ref("a", "main"), "aaa"},
+		{ref("b", "v1.0"), "bbb"},
+		{ref("c", "latest"), "ccc"},
+		{ref("e", pinned), pinned},
+	} {
+		if got, err := res.ResolveSHA(tt.ref); err != nil || got != tt.want {
+			t.Errorf("ResolveSHA(%s) = %q, %v, want %q", shaKey(tt.ref), got, err, tt.want)
+		}
+	}
+	// An unresolved ref is left to the REST API, which reports the error.
+	if _, err := res.ResolveSHA(ref("d", "gone")); err == nil {
+		t.Error("ResolveSHA(gone): expected error, got nil")
+	}
+}
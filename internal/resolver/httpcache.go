@@ -0,0 +1,230 @@
+package resolver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// HTTPCacheEnvVar overrides the directory HTTP responses are cached in;
+// "off" disables the cache.
+const HTTPCacheEnvVar = "COPS_HTTP_CACHE"
+
+// DefaultHTTPCacheDir returns where HTTP responses are cached:
+// $COPS_HTTP_CACHE if set, the cops folder of the OS user cache directory
+// otherwise (e.g. ~/.cache/cops/http on Linux). It returns "" if the cache
+// is disabled or no cache directory is known.
+func DefaultHTTPCacheDir() string {
+	switch dir := os.Getenv(HTTPCacheEnvVar); dir {
+	case "off":
+		return ""
+	case "":
+	default:
+		return dir
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "cops", "http")
+}
+
+// WithHTTPCache returns a copy of client whose GET responses are cached in
+// dir (see CachingTransport). An empty dir returns client unchanged.
+func WithHTTPCache(client *http.Client, dir string) *http.Client {
+	return WithHTTPCacheMaxAge(client, dir, 0)
+}
+
+// WithHTTPCacheMaxAge is like WithHTTPCache, but responses cached less
+// than maxAge ago are served without asking the server at all.
+func WithHTTPCacheMaxAge(client *http.Client, dir string, maxAge time.Duration) *http.Client {
+	if dir == "" {
+		return client
+	}
+	cached := *client
+	cached.Transport = &CachingTransport{Base: client.Transport, Dir: dir, MaxAge: maxAge}
+	return &cached
+}
+
+// CachingTransport is an http.RoundTripper that keeps successful GET
+// responses carrying an ETag on disk, keyed by URL and Accept header, and
+// revalidates them with If-None-Match. A response that has not changed
+// then costs a 304 without a body, which GitHub does not count against the
+// rate limit, and is served from the cache. Requests that are already
+// conditional pass through untouched.
+//
+// With a MaxAge, responses cached or revalidated less than MaxAge ago are
+// served without a request, for callers such as shell completion that
+// prefer a slightly stale answer to a round trip.
+type CachingTransport struct {
+	Base   http.RoundTripper // nil means http.DefaultTransport
+	Dir    string
+	MaxAge time.Duration // 0 revalidates every response
+}
+
+// cachedResponse is the metadata stored next to a cached body.
+type cachedResponse struct {
+	URL         string    `json:"url"`
+	ETag        string    `json:"etag"`
+	ContentType string    `json:"content_type,omitempty"`
+	CheckedAt   time.Time `json:"checked_at,omitzero"` // when the server last confirmed it
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return base.RoundTrip(req)
+	}
+
+	key := t.key(req)
+	meta, body, cached := t.load(key)
+	if cached && t.MaxAge > 0 && time.Since(meta.CheckedAt) < t.MaxAge {
+		return meta.response(req, body), nil
+	}
+	if cached {
+		r := req.Clone(req.Context())
+		r.Header.Set("If-None-Match", meta.ETag)
+		req = r
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cached && resp.StatusCode == http.StatusNotModified:
+		_ = resp.Body.Close()
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		resp.Header.Set("ETag", meta.ETag)
+		if meta.ContentType != "" {
+			resp.Header.Set("Content-Type", meta.ContentType)
+		}
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		resp.ContentLength = int64(len(body))
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if t.MaxAge > 0 {
+			meta.CheckedAt = time.Now()
+			_ = t.save(key, meta, nil)
+		}
+		return resp, nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" && resp.ContentLength <= MaxArchiveSize:
+		// Nothing reads more than MaxArchiveSize: a larger body is passed
+		// on uncached, for the caller to refuse.
+		data, err := io.ReadAll(io.LimitReader(resp.Body, MaxArchiveSize+1))
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+		if int64(len(data)) > MaxArchiveSize {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		// A cache that cannot be written only costs the next request.
+		_ = t.save(key, cachedResponse{
+			URL:         req.URL.String(),
+			ETag:        resp.Header.Get("ETag"),
+			ContentType: resp.Header.Get("Content-Type"),
+			CheckedAt:   time.Now(),
+		}, data)
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return resp, nil
+	}
+	return resp, nil
+}
+
+// response returns the cached response to req, with the given body.
+func (meta cachedResponse) response(req *http.Request, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("ETag", meta.ETag)
+	if meta.ContentType != "" {
+		header.Set("Content-Type", meta.ContentType)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// key identifies the response to req in the cache.
+func (t *CachingTransport) key(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(sum[:])
+}
+
+// load returns the cached response stored under key.
+func (t *CachingTransport) load(key string) (cachedResponse, []byte, bool) {
+	var meta cachedResponse
+	data, err := os.ReadFile(filepath.Join(t.Dir, key+".json"))
+	if err != nil || json.Unmarshal(data, &meta) != nil || meta.ETag == "" {
+		return meta, nil, false
+	}
+	body, err := os.ReadFile(filepath.Join(t.Dir, key+".body"))
+	if err != nil {
+		return meta, nil, false
+	}
+	return meta, body, true
+}
+
+// save stores a response under key. The body is written first, so the
+// metadata never points at a missing or partial body; a nil body only
+// updates the metadata.
+func (t *CachingTransport) save(key string, meta cachedResponse, body []byte) error {
+	if err := os.MkdirAll(t.Dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		if err := writeFileAtomic(filepath.Join(t.Dir, key+".body"), body); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filepath.Join(t.Dir, key+".json"), data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so concurrent runs never read a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("caching %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package resolver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachingTransport(t *testing.T) {
+	t.Parallel()
+
+	var body atomic.Value
+	body.Store("v1")
+	var full, revalidated int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + body.Load().(string) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", etag)
+		_, _ = io.WriteString(w, body.Load().(string))
+	}))
+	defer ts.Close()
+
+	client := WithHTTPCache(ts.Client(), t.TempDir())
+	get := func(header ...string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/repos/org/repo", nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	for range 2 {
+		if status, got := get(); status != http.StatusOK || got != "v1" {
+			t.Fatalf("GET = %d %q, want 200 v1", status, got)
+		}
+	}
+	if f, r := atomic.LoadInt32(&full), atomic.LoadInt32(&revalidated); f != 1 || r != 1 {
+		t.Errorf("server sent %d full responses and %d 304s, want 1 and 1", f, r)
+	}
+
+	// Requests that are already conditional get the server's answer.
+	if status, _ := get("If-None-Match", `"v1"`); status != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", status)
+	}
+
+	// A changed response replaces the cached one.
+	body.Store("v2")
+	if _, got := get(); got != "v2" {
+		t.Errorf("GET after change = %q, want v2", got)
+	}
+	if _, got := get(); got != "v2" {
+		t.Errorf("cached GET after change = %q, want v2", got)
+	}
+}
+
+func TestWithHTTPCache_Disabled(t *testing.T) {
+	t.Parallel()
+	client := &http.Client{}
+	if got := WithHTTPCache(client, ""); got != client {
+		t.Error("WithHTTPCache(\"\") returned a different client")
+	}
+}
+
+func TestCachingTransport_MaxAge(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, "v1")
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	get := func(client *http.Client) string {
+		t.Helper()
+		resp, err := client.Get(ts.URL + "/repos/org/repo/git/refs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("GET = %d (%s), want 200 with the cached content type", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return string(data)
+	}
+
+	fresh := WithHTTPCacheMaxAge(ts.Client(), dir, time.Minute)
+	for range 3 {
+		if got := get(fresh); got != "v1" {
+			t.Fatalf("GET = %q, want v1", got)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("server got %d requests, want 1: fresh responses are served from the cache", n)
+	}
+
+	// Without a max age, the same cache revalidates.
+	if got := get(WithHTTPCache(ts.Client(), dir)); got != "v1" {
+		t.Fatalf("GET = %q, want v1", got)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("server got %d requests, want 2", n)
+	}
+
+	// A stale response is revalidated, and fresh again afterwards.
+	stale := WithHTTPCacheMaxAge(ts.Client(), dir, time.Nanosecond)
+	if got := get(stale); got != "v1" {
+		t.Fatalf("GET = %q, want v1", got)
+	}
+	if got := get(fresh); got != "v1" {
+		t.Fatalf("GET = %q, want v1", got)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+}
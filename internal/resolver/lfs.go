@@ -0,0 +1,168 @@
+package resolver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// githubBase serves the Git LFS API of GitHub repositories.
+const githubBase = "https://github.com"
+
+// lfsPointerPrefix starts every Git LFS pointer file.
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1\n"
+
+// lfsPointerMaxSize bounds the size of a pointer file, as git-lfs does.
+const lfsPointerMaxSize = 1024
+
+var lfsOIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// lfsPointer is the object a Git LFS pointer file stands for.
+type lfsPointer struct {
+	OID  string `json:"oid"` // SHA-256 of the content, hex-encoded
+	Size int64  `json:"size"`
+}
+
+// parseLFSPointer reports whether data is a Git LFS pointer file rather
+// than content, and which object it points at.
+func parseLFSPointer(data []byte) (lfsPointer, bool) {
+	if len(data) > lfsPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerPrefix)) {
+		return lfsPointer{}, false
+	}
+	var p lfsPointer
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "oid":
+			p.OID, _ = strings.CutPrefix(value, "sha256:")
+		case "size":
+			p.Size, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	if !lfsOIDPattern.MatchString(p.OID) || p.Size < 0 {
+		return lfsPointer{}, false
+	}
+	return p, true
+}
+
+// resolveLFS returns data unchanged, unless it is a Git LFS pointer: the
+// object it points at is then downloaded through the repository's LFS
+// batch API, so the pointer text is never written in place of the file.
+func (r *Resolver) resolveLFS(ref config.AssetRef, data []byte) ([]byte, error) {
+	p, ok := parseLFSPointer(data)
+	if !ok {
+		return data, nil
+	}
+	if limit := r.limits().File; limit > 0 && p.Size > limit {
+		return nil, fmt.Errorf("%s is stored with Git LFS: object of %s is %w", ref.Path, manifest.FormatSize(p.Size), tooLarge(limit))
+	}
+	content, err := r.fetchLFSObject(ref, p)
+	if err != nil {
+		return nil, fmt.Errorf("%s is stored with Git LFS and its content could not be fetched: %w", ref.Path, err)
+	}
+	return content, nil
+}
+
+// fetchLFSObject asks the LFS batch API of ref's repository where to
+// download p, then downloads and verifies it.
+func (r *Resolver) fetchLFSObject(ref config.AssetRef, p lfsPointer) ([]byte, error) {
+	body, err := json.Marshal(map[string]any{
+		"operation": "download",
+		"transfers": []string{"basic"},
+		"ref":       map[string]string{"name": ref.Ref},
+		"objects":   []lfsPointer{p},
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/%s/%s.git/info/lfs/objects/batch", githubBase, ref.Org, ref.Repo)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("LFS batch API: HTTP %d — %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var batch struct {
+		Objects []struct {
+			OID     string `json:"oid"`
+			Actions struct {
+				Download *struct {
+					Href   string            `json:"href"`
+					Header map[string]string `json:"header"`
+				} `json:"download"`
+			} `json:"actions"`
+			Error *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("decoding LFS batch response: %w", err)
+	}
+	if len(batch.Objects) != 1 || batch.Objects[0].OID != p.OID {
+		return nil, fmt.Errorf("LFS batch API did not return object %s", p.OID)
+	}
+	obj := batch.Objects[0]
+	switch {
+	case obj.Error != nil:
+		return nil, fmt.Errorf("LFS object %s: %s (%d)", p.OID, obj.Error.Message, obj.Error.Code)
+	case obj.Actions.Download == nil:
+		return nil, fmt.Errorf("LFS batch API offers no download for object %s", p.OID)
+	}
+	return r.downloadLFSObject(obj.Actions.Download.Href, obj.Actions.Download.Header, p)
+}
+
+// downloadLFSObject downloads p from href, sending the headers the batch
+// API returned instead of the GitHub token: the object usually lives on
+// another host, which the headers authorize.
+func (r *Resolver) downloadLFSObject(href string, header map[string]string, p lfsPointer) ([]byte, error) {
+	client := r.mirrorClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, href, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading LFS object %s: %w", p.OID, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading LFS object %s: HTTP %d", p.OID, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.Size+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading LFS object %s: %w", p.OID, err)
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != p.Size || hex.EncodeToString(sum[:]) != p.OID {
+		return nil, fmt.Errorf("LFS object %s: downloaded content does not match its pointer", p.OID)
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestParseLFSPointer(t *testing.T) {
+	t.Parallel()
+	const oid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	cases := []struct {
+		data string
+		want lfsPointer
+		ok   bool
+	}{
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345\n", lfsPointer{oid, 12345}, true},
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:nothex\nsize 1\n", lfsPointer{}, false},
+		{"# Go\n\nversion https://git-lfs.github.com/spec/v1\n", lfsPointer{}, false},
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\n" + strings.Repeat("x", 1024), lfsPointer{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseLFSPointer([]byte(tc.data))
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("parseLFSPointer(%q) = %+v, %v; want %+v, %v", tc.data, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestDownloadFile_LFS(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("# Large instructions\n")
+	sum := sha256.Sum256(content)
+	oid := hex.EncodeToString(sum[:])
+	pointer := fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, len(content))
+	badPointer := fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", strings.Repeat("0", 64), len(content))
+
+	var objectAuth string
+	var serverURL string
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/myorg/myrepo/main/big.md": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(pointer))
+		},
+		"/myorg/myrepo/main/corrupt.md": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(badPointer))
+		},
+		"/myorg/myrepo.git/info/lfs/objects/batch": func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Operation string       `json:"operation"`
+				Objects   []lfsPointer `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Operation != "download" || len(req.Objects) != 1 {
+				t.Errorf("batch request = %+v, %v", req, err)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"objects": []any{map[string]any{
+				"oid":  req.Objects[0].OID,
+				"size": req.Objects[0].Size,
+				"actions": map[string]any{"download": map[string]any{
+					"href":   serverURL + "/objects/" + req.Objects[0].OID,
+					"header": map[string]string{"Authorization": "RemoteAuth secret"},
+				}},
+			}}})
+		},
+		"/objects/" + oid: func(w http.ResponseWriter, r *http.Request) {
+			objectAuth = r.Header.Get("Authorization")
+			_, _ = w.Write(content)
+		},
+		"/objects/" + strings.Repeat("0", 64): func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(content)
+		},
+	})
+	defer ts.Close()
+	serverURL = ts.URL
+
+	// Every GitHub host is served by the test server, with a token.
+	target, _ := url.Parse(ts.URL)
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		req.Header.Set("Authorization", "Bearer token")
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	res := New(client).WithMirrors(ts.Client())
+
+	data, err := res.DownloadFile(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "big.md", Ref: "main"})
+	if err != nil || string(data) != string(content) {
+		t.Fatalf("DownloadFile() = %q, %v; want the LFS object", data, err)
+	}
+	if objectAuth != "RemoteAuth secret" {
+		t.Errorf("object download sent Authorization %q, want the batch API's header", objectAuth)
+	}
+
+	_, err = res.DownloadFile(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "corrupt.md", Ref: "main"})
+	if err == nil || !strings.Contains(err.Error(), "Git LFS") || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("DownloadFile() error = %v, want a mismatch with the pointer", err)
+	}
+}
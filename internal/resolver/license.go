@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+const (
+	// NoLicense is the license reported for repositories without one.
+	NoLicense = "NONE"
+	// UnknownLicense is the license reported for repositories whose
+	// license GitHub does not recognise.
+	UnknownLicense = "NOASSERTION"
+)
+
+// LicenseDetector is implemented by sources that can tell the license of
+// the repository an asset comes from.
+type LicenseDetector interface {
+	// License returns the SPDX identifier of the license of the
+	// repository ref points into (e.g. "MIT"), NoLicense or
+	// UnknownLicense.
+	License(ref config.AssetRef) (string, error)
+}
+
+// License delegates to the source serving ref. It returns "" for sources
+// that cannot tell the license of what they serve.
+func (rt *Router) License(ref config.AssetRef) (string, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return "", err
+	}
+	d, ok := s.(LicenseDetector)
+	if !ok {
+		return "", nil
+	}
+	return d.License(ref)
+}
+
+// License returns the license of the repository ref points into.
+func (r *Resolver) License(ref config.AssetRef) (string, error) {
+	return repoLicense(r.client, ref)
+}
+
+// License returns the license of the repository the release belongs to.
+func (s *ReleaseSource) License(ref config.AssetRef) (string, error) {
+	return repoLicense(s.client, ref)
+}
+
+// repoLicense asks the GitHub API for the license of the repository ref
+// points into, as detected at its default branch.
+func repoLicense(client *http.Client, ref config.AssetRef) (string, error) {
+	resp, err := client.Get(fmt.Sprintf("%s/repos/%s/%s/license", githubAPIBase, ref.Org, ref.Repo))
+	if err != nil {
+		return "", fmt.Errorf("fetching license of %s: %w", ref.RepoFullName(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return NoLicense, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("fetching license of %s: HTTP %d — %s", ref.RepoFullName(), resp.StatusCode, string(body))
+	}
+
+	var info struct {
+		License struct {
+			SPDXID string `json:"spdx_id"`
+		} `json:"license"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("decoding license of %s: %w", ref.RepoFullName(), err)
+	}
+	if info.License.SPDXID == "" {
+		return UnknownLicense, nil
+	}
+	return info.License.SPDXID, nil
+}
@@ -0,0 +1,43 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestResolver_License(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/mit/license": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"license": map[string]string{"key": "mit", "spdx_id": "MIT"}})
+		},
+		"/repos/myorg/custom/license": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"license": map[string]string{"key": "other", "spdx_id": "NOASSERTION"}})
+		},
+		"/repos/myorg/broken/license": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusForbidden)
+		},
+	})
+	defer ts.Close()
+	res := New(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}})
+
+	for repo, want := range map[string]string{"mit": "MIT", "custom": UnknownLicense, "unlicensed": NoLicense} {
+		got, err := res.License(config.AssetRef{Org: "myorg", Repo: repo, Path: "a.md", Ref: "v1"})
+		if err != nil || got != want {
+			t.Errorf("License(%s) = %q, %v; want %q", repo, got, err, want)
+		}
+	}
+	if _, err := res.License(config.AssetRef{Org: "myorg", Repo: "broken", Path: "a.md", Ref: "v1"}); err == nil {
+		t.Error("License() of a failing request: expected an error")
+	}
+}
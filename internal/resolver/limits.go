@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+// MaxArchiveSize bounds an archive downloaded to be unpacked (a repository
+// tarball, a release asset, an OCI layer) and the files unpacked from it.
+// Archives hold more than the asset they serve, so the Limits of the
+// manifest do not apply to them as a whole.
+const MaxArchiveSize int64 = 512 << 20
+
+// ErrTooLarge is returned by sources that stop reading a download once it
+// is over their Limits.
+var ErrTooLarge = errors.New("over the size limit")
+
+// Limits bound what sources read into memory: the size of a file, and of
+// the files of a directory asset together. Zero means no limit.
+type Limits struct {
+	File int64
+	Dir  int64
+}
+
+// Limiter is implemented by sources that enforce Limits while they read,
+// so an oversized download fails before it is all in memory.
+type Limiter interface {
+	SetLimits(Limits)
+}
+
+// SetLimits sets the limits of every source that enforces them.
+func (rt *Router) SetLimits(l Limits) {
+	for _, s := range rt.sources {
+		if lim, ok := s.(Limiter); ok {
+			lim.SetLimits(l)
+		}
+	}
+}
+
+// readLimits holds the Limits of a source. Sources embed it to implement
+// Limiter; it is safe for concurrent use.
+type readLimits struct {
+	file, dir atomic.Int64
+}
+
+// SetLimits replaces the limits.
+func (l *readLimits) SetLimits(lim Limits) {
+	l.file.Store(lim.File)
+	l.dir.Store(lim.Dir)
+}
+
+// limits returns the current limits.
+func (l *readLimits) limits() Limits {
+	return Limits{File: l.file.Load(), Dir: l.dir.Load()}
+}
+
+// readLimited reads r, failing with ErrTooLarge as soon as it holds more
+// than limit bytes, or before reading if its announced size, -1 if
+// unknown, already is. A limit of zero reads everything.
+func readLimited(r io.Reader, size, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	if size > limit {
+		return nil, tooLarge(limit)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, tooLarge(limit)
+	}
+	return data, nil
+}
+
+// readBody reads the body of resp, up to limit bytes (see readLimited).
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	return readLimited(resp.Body, resp.ContentLength, limit)
+}
+
+func tooLarge(limit int64) error {
+	return fmt.Errorf("%w of %s", ErrTooLarge, manifest.FormatSize(limit))
+}
@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// unreadable fails the test if it is read.
+type unreadable struct{ t *testing.T }
+
+func (r unreadable) Read([]byte) (int, error) {
+	r.t.Error("body was read despite its announced size")
+	return 0, errors.New("unreadable")
+}
+
+func TestReadLimited(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		body    string
+		size    int64
+		limit   int64
+		wantErr bool
+	}{
+		{"under the limit", "12345", 5, 5, false},
+		{"no limit", "1234567890", -1, 0, false},
+		{"unknown size under the limit", "12345", -1, 5, false},
+		{"body over the limit", "123456", -1, 5, true},
+		{"body over its announced size", "123456", 3, 5, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := readLimited(strings.NewReader(tc.body), tc.size, tc.limit)
+			if tc.wantErr {
+				if !errors.Is(err, ErrTooLarge) {
+					t.Errorf("readLimited = %q, %v, want ErrTooLarge", got, err)
+				}
+				return
+			}
+			if err != nil || string(got) != tc.body {
+				t.Errorf("readLimited = %q, %v, want %q", got, err, tc.body)
+			}
+		})
+	}
+}
+
+func TestReadLimited_AnnouncedSize(t *testing.T) {
+	t.Parallel()
+	_, err := readLimited(unreadable{t}, 6, 5)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("readLimited: got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestRouter_SetLimits(t *testing.T) {
+	t.Parallel()
+	url := NewURLSource(nil)
+	NewRouter(NewLocalSource("."), url).SetLimits(Limits{File: 1, Dir: 2})
+	if got := url.limits(); got != (Limits{File: 1, Dir: 2}) {
+		t.Errorf("limits = %+v, want {File:1 Dir:2}", got)
+	}
+}
@@ -0,0 +1,80 @@
+package resolver
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// LocalSource reads assets authored in the project itself, such as those
+// created by `cops new --register`, from disk.
+type LocalSource struct {
+	root string
+}
+
+// NewLocalSource creates a LocalSource reading paths relative to the
+// project root.
+func NewLocalSource(root string) *LocalSource {
+	return &LocalSource{root: root}
+}
+
+// Supports reports whether ref is a local reference.
+func (s *LocalSource) Supports(ref config.AssetRef) bool {
+	return ref.IsLocal()
+}
+
+// ResolveRef returns the ref unchanged: local paths have no aliases.
+func (s *LocalSource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	return ref, nil
+}
+
+// DownloadFile reads the file at the ref's path.
+func (s *LocalSource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	data, err := os.ReadFile(s.path(ref.Path))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ref.Raw(), err)
+	}
+	return data, nil
+}
+
+// ListDirectory lists the files under the ref's path, in lexical order,
+// with their paths relative to the project root.
+func (s *LocalSource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	var entries []GitHubTreeEntry
+	err := filepath.WalkDir(s.path(ref.Path), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		entry := GitHubTreeEntry{Path: filepath.ToSlash(rel), Type: "blob"}
+		if info, err := d.Info(); err == nil && info.Mode().Perm()&0o111 != 0 {
+			entry.Mode = ModeExecutable
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", ref.Raw(), err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("listing %s: no files", ref.Raw())
+	}
+	return entries, nil
+}
+
+// ResolveSHA returns an empty SHA: local assets are versioned with the
+// project, not on their own.
+func (s *LocalSource) ResolveSHA(ref config.AssetRef) (string, error) {
+	return "", nil
+}
+
+// path returns the location on disk of the slash-separated project path p.
+func (s *LocalSource) path(p string) string {
+	return filepath.Join(s.root, filepath.FromSlash(p))
+}
@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestLocalSource(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	skill := filepath.Join(root, "assets", "skills", "k8s")
+	if err := os.MkdirAll(filepath.Join(skill, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skill, "SKILL.md"), []byte("# K8s"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skill, "bin", "deploy.sh"), []byte("#!/bin/sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := NewLocalSource(root)
+
+	ref, err := config.ParseRef("local:assets/skills/k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Supports(ref) {
+		t.Fatalf("Supports(%s) = false", ref.Raw())
+	}
+	entries, err := s.ListDirectory(ref)
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "assets/skills/k8s/SKILL.md" || entries[0].Executable() ||
+		entries[1].Path != "assets/skills/k8s/bin/deploy.sh" || !entries[1].Executable() {
+		t.Errorf("ListDirectory() = %+v", entries)
+	}
+
+	ref.Path = entries[0].Path
+	if got, err := s.DownloadFile(ref); err != nil || string(got) != "# K8s" {
+		t.Errorf("DownloadFile() = %q, %v", got, err)
+	}
+	if sha, err := s.ResolveSHA(ref); err != nil || sha != "" {
+		t.Errorf("ResolveSHA() = %q, %v; want no SHA", sha, err)
+	}
+
+	missing, _ := config.ParseRef("local:assets/missing.md")
+	if _, err := s.DownloadFile(missing); err == nil {
+		t.Error("DownloadFile of a missing file: expected an error")
+	}
+	if _, err := s.ListDirectory(missing); err == nil {
+		t.Error("ListDirectory of a missing directory: expected an error")
+	}
+}
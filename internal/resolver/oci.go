@@ -0,0 +1,358 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// ociTitleAnnotation names the file a layer holds (ORAS convention).
+	ociTitleAnnotation = "org.opencontainers.image.title"
+
+	ghcrRegistry = "ghcr.io"
+
+	// ociMaxManifestSize bounds a downloaded image manifest.
+	ociMaxManifestSize = 4 << 20
+)
+
+// ociDescriptor references a blob in an OCI registry.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is the subset of an OCI image manifest cops needs.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// OCISource pulls asset bundles published as OCI artifacts.
+//
+// Bundle layout: every layer is either a tar archive (optionally gzipped),
+// whose files are unpacked at their archive paths, or a single file named
+// by its "org.opencontainers.image.title" annotation. The union of all
+// layers forms the bundle; the ref's path selects a file or directory in it.
+type OCISource struct {
+	client    *http.Client
+	ghcrToken string // GitHub token, only ever sent to ghcr.io
+
+	mu      sync.Mutex
+	tokens  map[string]string // "<registry>/<repo>" → bearer token
+	bundles map[string]bundle // manifest digest → files
+	digests map[string]string // image reference → manifest digest
+
+	readLimits
+}
+
+// NewOCISource creates an OCISource. ghcrToken may be empty; when set it is
+// used to authenticate against ghcr.io only.
+func NewOCISource(client *http.Client, ghcrToken string) *OCISource {
+	return &OCISource{
+		client:    client,
+		ghcrToken: ghcrToken,
+		tokens:    make(map[string]string),
+		bundles:   make(map[string]bundle),
+		digests:   make(map[string]string),
+	}
+}
+
+// Supports reports whether ref is an OCI artifact reference.
+func (s *OCISource) Supports(ref config.AssetRef) bool {
+	return ref.IsOCI()
+}
+
+// ResolveRef returns the ref unchanged: tags are resolved by ResolveSHA.
+func (s *OCISource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	return ref, nil
+}
+
+// DownloadFile returns a single file from the bundle.
+func (s *OCISource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	b, _, err := s.loadBundle(ref)
+	if err != nil {
+		return nil, err
+	}
+	return b.file(ref.Path)
+}
+
+// ListDirectory lists the bundle files under the ref's path.
+func (s *OCISource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	b, _, err := s.loadBundle(ref)
+	if err != nil {
+		return nil, err
+	}
+	entries := b.entries(ref.Path)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no files found under %q in %s", ref.Path, ref.RepoFullName())
+	}
+	return entries, nil
+}
+
+// ResolveSHA returns the manifest digest the ref points at.
+func (s *OCISource) ResolveSHA(ref config.AssetRef) (string, error) {
+	_, digest, err := s.loadBundle(ref)
+	return digest, err
+}
+
+// imageKey identifies the image independent of the in-bundle path.
+func imageKey(ref config.AssetRef) string {
+	return ref.Registry + "/" + ref.Repo + "@" + ref.Ref
+}
+
+// loadBundle fetches and unpacks the artifact, caching it for the lifetime
+// of the source so skills don't re-download the bundle per file.
+func (s *OCISource) loadBundle(ref config.AssetRef) (bundle, string, error) {
+	s.mu.Lock()
+	if digest, ok := s.digests[imageKey(ref)]; ok {
+		b := s.bundles[digest]
+		s.mu.Unlock()
+		return b, digest, nil
+	}
+	s.mu.Unlock()
+
+	manifest, digest, err := s.fetchManifest(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Tar layers are bounded by MaxArchiveSize, other layers by the file
+	// limit, and the files of all layers together by MaxArchiveSize.
+	files := make(bundle)
+	var total int64
+	for _, layer := range manifest.Layers {
+		title := layer.Annotations[ociTitleAnnotation]
+		tarLayer := isTarLayer(layer.MediaType, title)
+		limit := s.limits().File
+		if tarLayer {
+			limit = MaxArchiveSize
+		}
+		if total >= MaxArchiveSize {
+			return nil, "", fmt.Errorf("layers of %s: %w", ref.Raw(), tooLarge(MaxArchiveSize))
+		}
+		data, err := s.fetchBlob(ref, layer, limit)
+		if err != nil {
+			return nil, "", err
+		}
+		if tarLayer {
+			extracted, _, err := extractTarballFiltered(data, 0, nil, Limits{Dir: MaxArchiveSize - total})
+			if err != nil {
+				return nil, "", fmt.Errorf("unpacking layer %s: %w", layer.Digest, err)
+			}
+			for p, content := range extracted {
+				files[p] = content
+				total += int64(len(content))
+			}
+			continue
+		}
+		if title == "" {
+			return nil, "", fmt.Errorf("layer %s in %s has no %s annotation", layer.Digest, ref.RepoFullName(), ociTitleAnnotation)
+		}
+		name, err := cleanArchivePath(title)
+		if err != nil {
+			return nil, "", err
+		}
+		files[name] = data
+		total += int64(len(data))
+	}
+
+	s.mu.Lock()
+	s.bundles[digest] = files
+	s.digests[imageKey(ref)] = digest
+	s.mu.Unlock()
+
+	return files, digest, nil
+}
+
+// isTarLayer reports whether a layer holds a tar archive to unpack.
+func isTarLayer(mediaType, title string) bool {
+	if strings.Contains(mediaType, ".tar") {
+		return true
+	}
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(title, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *OCISource) fetchManifest(ref config.AssetRef) (ociManifest, string, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repo, ref.Ref)
+	body, header, err := s.get(ref, u, ociManifestMediaType+", "+dockerManifestMediaType, ociMaxManifestSize)
+	if err != nil {
+		return ociManifest{}, "", fmt.Errorf("fetching manifest for %s: %w", ref.Raw(), err)
+	}
+
+	digest := "sha256:" + fmt.Sprintf("%x", sha256.Sum256(body))
+	if h := header.Get("Docker-Content-Digest"); h != "" && h != digest {
+		return ociManifest{}, "", fmt.Errorf("manifest digest mismatch for %s: registry says %s, content is %s", ref.Raw(), h, digest)
+	}
+	if strings.HasPrefix(ref.Ref, "sha256:") && ref.Ref != digest {
+		return ociManifest{}, "", fmt.Errorf("manifest digest mismatch for %s: got %s", ref.Raw(), digest)
+	}
+
+	var m ociManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return ociManifest{}, "", fmt.Errorf("decoding manifest for %s: %w", ref.Raw(), err)
+	}
+	if len(m.Layers) == 0 {
+		return ociManifest{}, "", fmt.Errorf("manifest for %s has no layers", ref.Raw())
+	}
+	return m, digest, nil
+}
+
+// fetchBlob downloads the blob desc points at, up to limit bytes (see
+// readLimited).
+func (s *OCISource) fetchBlob(ref config.AssetRef, desc ociDescriptor, limit int64) ([]byte, error) {
+	if limit > 0 && desc.Size > limit {
+		return nil, fmt.Errorf("fetching blob %s: %w", desc.Digest, tooLarge(limit))
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.Registry, ref.Repo, desc.Digest)
+	data, _, err := s.get(ref, u, "", limit)
+	if err != nil {
+		return nil, fmt.Errorf("fetching blob %s: %w", desc.Digest, err)
+	}
+	if got := "sha256:" + fmt.Sprintf("%x", sha256.Sum256(data)); got != desc.Digest {
+		return nil, fmt.Errorf("blob digest mismatch: got %s, want %s", got, desc.Digest)
+	}
+	return data, nil
+}
+
+// get performs a registry GET, reading up to limit bytes of the response
+// (see readLimited), and answering a bearer-token challenge once if the
+// registry requires it (anonymous pulls on ghcr.io still need a token).
+func (s *OCISource) get(ref config.AssetRef, u, accept string, limit int64) ([]byte, http.Header, error) {
+	key := ref.RepoFullName()
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		s.mu.Lock()
+		token := s.tokens[key]
+		s.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := readBody(resp, limit)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			token, err := s.fetchToken(ref, resp.Header.Get("WWW-Authenticate"))
+			if err != nil {
+				return nil, nil, err
+			}
+			s.mu.Lock()
+			s.tokens[key] = token
+			s.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil, fmt.Errorf("HTTP %d — %s", resp.StatusCode, string(body))
+		}
+		return body, resp.Header, nil
+	}
+	return nil, nil, fmt.Errorf("registry %s rejected credentials", ref.Registry)
+}
+
+// fetchToken answers a "Bearer realm=...,service=...,scope=..." challenge.
+func (s *OCISource) fetchToken(ref config.AssetRef, challenge string) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s requires unsupported authentication %q", ref.Registry, challenge)
+	}
+
+	q := url.Values{}
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repo + ":pull"
+	}
+	q.Set("scope", scope)
+
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if ref.Registry == ghcrRegistry && s.ghcrToken != "" {
+		req.SetBasicAuth("cops", s.ghcrToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching registry token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("fetching registry token: HTTP %d — %s", resp.StatusCode, string(body))
+	}
+
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("decoding registry token: %w", err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry %s returned an empty token", ref.Registry)
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header into its scheme and
+// quoted key/value parameters.
+func parseAuthChallenge(h string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var kv string
+		rest = strings.TrimLeft(rest, " ,")
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end == -1 {
+				break
+			}
+			kv, rest = after[1:end+1], after[end+2:]
+		} else {
+			kv, rest, _ = strings.Cut(after, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = kv
+	}
+	return scheme, params
+}
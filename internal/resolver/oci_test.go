@@ -0,0 +1,165 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func sha256Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// newRegistryServer serves a single OCI artifact "team/assets:v1" built from
+// the given layers, requiring a bearer token like ghcr.io does.
+func newRegistryServer(t *testing.T, layers []ociDescriptor, blobs map[string][]byte) (*httptest.Server, *int32) {
+	t.Helper()
+	manifest, _ := json.Marshal(ociManifest{MediaType: ociManifestMediaType, Layers: layers})
+	var manifestFetches int32
+
+	var ts *httptest.Server
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:team/assets:pull" {
+				t.Errorf("unexpected token scope %q", r.URL.Query().Get("scope"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "registry-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:team/assets:pull"`, ts.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/team/assets/manifests/v1":
+			atomic.AddInt32(&manifestFetches, 1)
+			w.Header().Set("Docker-Content-Digest", sha256Digest(manifest))
+			_, _ = w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/team/assets/blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/team/assets/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return ts, &manifestFetches
+}
+
+func ociTestRef(t *testing.T, ts *httptest.Server, path string) config.AssetRef {
+	t.Helper()
+	raw := "oci://" + strings.TrimPrefix(ts.URL, "https://") + "/team/assets:v1"
+	if path != "" {
+		raw += "//" + path
+	}
+	ref, err := config.ParseRef(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestOCISource_TarLayer(t *testing.T) {
+	t.Parallel()
+	layer := buildTar(t, map[string]string{
+		"skills/k8s/SKILL.md":      "skill",
+		"skills/k8s/scripts/a.sh":  "echo",
+		"instructions/security.md": "security",
+	}, true)
+	desc := ociDescriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: sha256Digest(layer), Size: int64(len(layer))}
+	ts, fetches := newRegistryServer(t, []ociDescriptor{desc}, map[string][]byte{desc.Digest: layer})
+	defer ts.Close()
+
+	src := NewOCISource(ts.Client(), "")
+
+	got, err := src.DownloadFile(ociTestRef(t, ts, "instructions/security.md"))
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if string(got) != "security" {
+		t.Errorf("DownloadFile = %q", got)
+	}
+
+	entries, err := src.ListDirectory(ociTestRef(t, ts, "skills/k8s"))
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("ListDirectory returned %d entries, want 2: %+v", len(entries), entries)
+	}
+
+	sha, err := src.ResolveSHA(ociTestRef(t, ts, ""))
+	if err != nil || !strings.HasPrefix(sha, "sha256:") {
+		t.Errorf("ResolveSHA = %q, %v", sha, err)
+	}
+
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("manifest fetched %d times, want 1 (bundle should be cached)", n)
+	}
+}
+
+func TestOCISource_TitledFileLayers(t *testing.T) {
+	t.Parallel()
+	content := []byte("# Review\n")
+	desc := ociDescriptor{
+		MediaType:   "text/markdown",
+		Digest:      sha256Digest(content),
+		Annotations: map[string]string{ociTitleAnnotation: "review.md"},
+	}
+	ts, _ := newRegistryServer(t, []ociDescriptor{desc}, map[string][]byte{desc.Digest: content})
+	defer ts.Close()
+
+	got, err := NewOCISource(ts.Client(), "").DownloadFile(ociTestRef(t, ts, ""))
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("DownloadFile = %q", got)
+	}
+}
+
+func TestOCISource_BlobDigestMismatch(t *testing.T) {
+	t.Parallel()
+	desc := ociDescriptor{
+		MediaType:   "text/markdown",
+		Digest:      sha256Digest([]byte("expected")),
+		Annotations: map[string]string{ociTitleAnnotation: "review.md"},
+	}
+	ts, _ := newRegistryServer(t, []ociDescriptor{desc}, map[string][]byte{desc.Digest: []byte("tampered")})
+	defer ts.Close()
+
+	if _, err := NewOCISource(ts.Client(), "").DownloadFile(ociTestRef(t, ts, "")); err == nil {
+		t.Fatal("DownloadFile: expected digest mismatch error, got nil")
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	t.Parallel()
+	scheme, params := parseAuthChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/a,b:pull"`)
+	if scheme != "Bearer" {
+		t.Errorf("scheme = %q", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:org/a,b:pull",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+		}
+	}
+}
+
+var _ SourceRepository = (*OCISource)(nil)
@@ -0,0 +1,206 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// PluginDirEnvVar overrides the directory source plugins are discovered in.
+const PluginDirEnvVar = "COPS_PLUGIN_DIR"
+
+// pluginPrefix starts the file name of every source plugin: the plugin
+// "artifactory" is the executable cops-source-artifactory.
+const pluginPrefix = "cops-source-"
+
+// PluginProtocolVersion is sent with every plugin request, so plugins can
+// refuse requests they do not understand.
+const PluginProtocolVersion = 1
+
+// pluginTimeout bounds a single plugin call.
+const pluginTimeout = 5 * time.Minute
+
+// DefaultPluginDir returns where source plugins are discovered:
+// $COPS_PLUGIN_DIR if set, the plugins folder of the cops user
+// configuration directory otherwise (e.g. ~/.config/cops/plugins on Linux).
+// It returns "" if no configuration directory is known.
+func DefaultPluginDir() string {
+	if dir := os.Getenv(PluginDirEnvVar); dir != "" {
+		return dir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cops", "plugins")
+}
+
+// DiscoverPlugins returns the source plugins found in dir, executables
+// named cops-source-<name>, in lexical order. A missing dir has none.
+func DiscoverPlugins(ctx context.Context, dir string) ([]*PluginSource, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading plugins: %w", err)
+	}
+	var plugins []*PluginSource
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+		if runtime.GOOS == "windows" {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		info, err := e.Info()
+		if !ok || err != nil || !info.Mode().IsRegular() || !config.ValidPluginName(name) {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		plugins = append(plugins, NewPluginSource(ctx, name, filepath.Join(dir, e.Name())))
+	}
+	return plugins, nil
+}
+
+// PluginSource serves "plugin:<name>/<path>[@<ref>]" references by running
+// the executable of the plugin, so organisations can fetch assets from
+// systems cops does not know. Each call runs
+//
+//	<executable> <operation>
+//
+// with a JSON request on standard input,
+//
+//	{"version": 1, "path": "prompts/review.md", "ref": "v3"}
+//
+// and reads a JSON response on standard output:
+//
+//	resolve   {"sha": "<immutable version the ref points at, or empty>"}
+//	download  {"content": "<base64 of the file>"}
+//	list      {"files": [{"path": "<relative to the path>", "executable": false}]}
+//
+// A non-zero exit status fails the call, with standard error as the reason.
+type PluginSource struct {
+	name string
+	path string
+	ctx  context.Context
+}
+
+// NewPluginSource creates the source of the plugin name, whose executable
+// is at path. Calls are cancelled when ctx is done.
+func NewPluginSource(ctx context.Context, name, path string) *PluginSource {
+	return &PluginSource{name: name, path: path, ctx: ctx}
+}
+
+// Name returns the name of the plugin.
+func (s *PluginSource) Name() string {
+	return s.name
+}
+
+// Path returns the executable of the plugin.
+func (s *PluginSource) Path() string {
+	return s.path
+}
+
+// Supports reports whether ref names this plugin.
+func (s *PluginSource) Supports(ref config.AssetRef) bool {
+	return ref.Plugin == s.name
+}
+
+// ResolveRef returns the ref unchanged: refs mean what the plugin says.
+func (s *PluginSource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	return ref, nil
+}
+
+// DownloadFile asks the plugin for the content of the file at ref.
+func (s *PluginSource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	var resp struct {
+		Content []byte `json:"content"`
+	}
+	if err := s.call("download", ref, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Content, nil
+}
+
+// ListDirectory asks the plugin for the files under the directory at ref.
+func (s *PluginSource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	var resp struct {
+		Files []struct {
+			Path       string `json:"path"`
+			Executable bool   `json:"executable"`
+		} `json:"files"`
+	}
+	if err := s.call("list", ref, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Files) == 0 {
+		return nil, fmt.Errorf("listing %s: no files", ref.Raw())
+	}
+	entries := make([]GitHubTreeEntry, 0, len(resp.Files))
+	for _, f := range resp.Files {
+		rel := path.Clean(strings.TrimPrefix(f.Path, "/"))
+		if rel == "." || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("listing %s: plugin %s returned an invalid path %q", ref.Raw(), s.name, f.Path)
+		}
+		entry := GitHubTreeEntry{Path: ref.Path + "/" + rel, Type: "blob"}
+		if f.Executable {
+			entry.Mode = ModeExecutable
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ResolveSHA asks the plugin for the immutable version ref points at. An
+// empty answer records the asset as unverifiable, like a local one.
+func (s *PluginSource) ResolveSHA(ref config.AssetRef) (string, error) {
+	var resp struct {
+		SHA string `json:"sha"`
+	}
+	if err := s.call("resolve", ref, &resp); err != nil {
+		return "", err
+	}
+	return resp.SHA, nil
+}
+
+// call runs operation on the plugin for ref and decodes its response into
+// resp.
+func (s *PluginSource) call(operation string, ref config.AssetRef, resp any) error {
+	req, err := json.Marshal(map[string]any{"version": PluginProtocolVersion, "path": ref.Path, "ref": ref.Ref})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, pluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.path, operation)
+	cmd.Stdin = bytes.NewReader(req)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return fmt.Errorf("plugin %s %s %s: %s", s.name, operation, ref.Raw(), reason)
+	}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return fmt.Errorf("plugin %s %s %s: invalid response: %w", s.name, operation, ref.Raw(), err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// testPlugin answers every operation with a fixed response, and fails on
+// the "missing" path.
+const testPlugin = `#!/bin/sh
+input=$(cat)
+case "$input" in *missing*) echo "no such asset" >&2; exit 3;; esac
+case "$1" in
+resolve) echo '{"sha": "v3-build-42"}';;
+download) echo '{"content": "IyBSZXZpZXc="}';;
+list) echo '{"files": [{"path": "SKILL.md"}, {"path": "bin/run.sh", "executable": true}]}';;
+esac
+`
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "cops-source-vault", testPlugin, 0o755)
+	writePlugin(t, dir, "cops-source-artifactory", testPlugin, 0o755)
+	writePlugin(t, dir, "cops-source-notexec", testPlugin, 0o644)
+	writePlugin(t, dir, "cops-source-Bad_Name", testPlugin, 0o755)
+	writePlugin(t, dir, "other-tool", testPlugin, 0o755)
+
+	plugins, err := DiscoverPlugins(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range plugins {
+		names = append(names, p.Name())
+	}
+	if got := strings.Join(names, ","); got != "artifactory,vault" {
+		t.Errorf("DiscoverPlugins() = %s, want artifactory,vault", got)
+	}
+
+	if plugins, err := DiscoverPlugins(context.Background(), filepath.Join(dir, "missing")); err != nil || plugins != nil {
+		t.Errorf("DiscoverPlugins(missing dir) = %v, %v; want none", plugins, err)
+	}
+}
+
+func TestPluginSource(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	s := NewPluginSource(context.Background(), "vault", writePlugin(t, t.TempDir(), "cops-source-vault", testPlugin, 0o755))
+
+	ref, err := config.ParseRef("plugin:vault/skills/review@v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Supports(ref) {
+		t.Fatalf("Supports(%s) = false", ref.Raw())
+	}
+	if other, _ := config.ParseRef("plugin:other/skills/review"); s.Supports(other) {
+		t.Errorf("Supports(%s) = true", other.Raw())
+	}
+
+	if sha, err := s.ResolveSHA(ref); err != nil || sha != "v3-build-42" {
+		t.Errorf("ResolveSHA() = %q, %v", sha, err)
+	}
+	if got, err := s.DownloadFile(ref); err != nil || string(got) != "# Review" {
+		t.Errorf("DownloadFile() = %q, %v", got, err)
+	}
+	entries, err := s.ListDirectory(ref)
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "skills/review/SKILL.md" || entries[0].Executable() ||
+		entries[1].Path != "skills/review/bin/run.sh" || !entries[1].Executable() {
+		t.Errorf("ListDirectory() = %+v", entries)
+	}
+
+	missing, _ := config.ParseRef("plugin:vault/missing.md")
+	if _, err := s.DownloadFile(missing); err == nil || !strings.Contains(err.Error(), "no such asset") {
+		t.Errorf("DownloadFile of a missing file = %v, want the plugin's error", err)
+	}
+}
+
+func TestPluginSource_InvalidPath(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	script := "#!/bin/sh\ncat >/dev/null\necho '{\"files\": [{\"path\": \"../escape.md\"}]}'\n"
+	s := NewPluginSource(context.Background(), "evil", writePlugin(t, t.TempDir(), "cops-source-evil", script, 0o755))
+	ref, _ := config.ParseRef("plugin:evil/skills/x")
+	if _, err := s.ListDirectory(ref); err == nil {
+		t.Error("ListDirectory with a path outside the directory: expected an error")
+	}
+}
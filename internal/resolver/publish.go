@@ -0,0 +1,169 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// PublishedFile is the new content of a file to publish.
+type PublishedFile struct {
+	Content    []byte
+	Executable bool
+}
+
+// Publication is a change to commit to a branch of a GitHub repository.
+type Publication struct {
+	Repo    string // "org/repo"
+	Base    string // branch the change is based on
+	Branch  string // branch committed to: created from Base, or reset to it
+	Message string // commit message
+
+	// Files maps slash-separated repository paths to their new content,
+	// and Delete lists the paths to remove.
+	Files  map[string]PublishedFile
+	Delete []string
+}
+
+// Publisher commits files to GitHub repositories and proposes them as
+// pull requests, through the GitHub API.
+type Publisher struct {
+	client *http.Client
+}
+
+// NewPublisher returns a Publisher sending requests with client.
+func NewPublisher(client *http.Client) *Publisher {
+	return &Publisher{client: client}
+}
+
+// DefaultBranch returns the default branch of repo ("org/repo").
+func (p *Publisher) DefaultBranch(repo string) (string, error) {
+	return DefaultBranch(p.client, repo)
+}
+
+// OpenPullRequest opens pr on repo; see OpenPullRequest.
+func (p *Publisher) OpenPullRequest(repo string, pr PullRequest) (string, error) {
+	return OpenPullRequest(p.client, repo, pr)
+}
+
+// Publish commits pub on top of its base branch, as a single commit, and
+// points pub.Branch at it. It returns the commit SHA, or "" without
+// touching any branch if the base already has the content.
+func (p *Publisher) Publish(pub Publication) (string, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s", githubAPIBase, pub.Repo)
+
+	var head struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := p.send(http.MethodGet, repoURL+"/git/ref/heads/"+pub.Base, nil, &head); err != nil {
+		return "", fmt.Errorf("looking up branch %s of %s: %w", pub.Base, pub.Repo, err)
+	}
+	var base struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := p.send(http.MethodGet, repoURL+"/git/commits/"+head.Object.SHA, nil, &base); err != nil {
+		return "", fmt.Errorf("reading commit %s of %s: %w", head.Object.SHA, pub.Repo, err)
+	}
+
+	// A nil SHA deletes the path from the base tree.
+	type treeEntry struct {
+		Path string  `json:"path"`
+		Mode string  `json:"mode"`
+		Type string  `json:"type"`
+		SHA  *string `json:"sha"`
+	}
+	var entries []treeEntry
+	paths := make([]string, 0, len(pub.Files))
+	for path := range pub.Files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		file := pub.Files[path]
+		var blob struct {
+			SHA string `json:"sha"`
+		}
+		content := map[string]string{"content": base64.StdEncoding.EncodeToString(file.Content), "encoding": "base64"}
+		if err := p.send(http.MethodPost, repoURL+"/git/blobs", content, &blob); err != nil {
+			return "", fmt.Errorf("uploading %s to %s: %w", path, pub.Repo, err)
+		}
+		mode := "100644"
+		if file.Executable {
+			mode = "100755"
+		}
+		entries = append(entries, treeEntry{Path: path, Mode: mode, Type: "blob", SHA: &blob.SHA})
+	}
+	for _, path := range pub.Delete {
+		entries = append(entries, treeEntry{Path: path, Mode: "100644", Type: "blob"})
+	}
+
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := p.send(http.MethodPost, repoURL+"/git/trees", map[string]any{"base_tree": base.Tree.SHA, "tree": entries}, &tree); err != nil {
+		return "", fmt.Errorf("creating tree on %s: %w", pub.Repo, err)
+	}
+	if tree.SHA == base.Tree.SHA {
+		return "", nil
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	body := map[string]any{"message": pub.Message, "tree": tree.SHA, "parents": []string{head.Object.SHA}}
+	if err := p.send(http.MethodPost, repoURL+"/git/commits", body, &commit); err != nil {
+		return "", fmt.Errorf("creating commit on %s: %w", pub.Repo, err)
+	}
+
+	ref := map[string]any{"ref": "refs/heads/" + pub.Branch, "sha": commit.SHA}
+	if err := p.send(http.MethodPost, repoURL+"/git/refs", ref, nil); err != nil {
+		// The branch exists, e.g. from an earlier publication: it is
+		// moved to the new commit.
+		update := map[string]any{"sha": commit.SHA, "force": true}
+		if err := p.send(http.MethodPatch, repoURL+"/git/refs/heads/"+pub.Branch, update, nil); err != nil {
+			return "", fmt.Errorf("updating branch %s of %s: %w", pub.Branch, pub.Repo, err)
+		}
+	}
+	return commit.SHA, nil
+}
+
+// send sends a request with body, if not nil, encoded as JSON, and decodes
+// the JSON response into v, if not nil.
+func (p *Publisher) send(method, url string, body, v any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d — %s", resp.StatusCode, string(data))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
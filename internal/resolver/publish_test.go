@@ -0,0 +1,154 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// publishServer fakes the Git data API of myorg/assets, whose main branch
+// points at commit c0 with tree t0.
+type publishServer struct {
+	mu      sync.Mutex
+	blobs   []string       // decoded contents uploaded
+	tree    map[string]any // last tree request
+	commit  map[string]any // last commit request
+	patched map[string]any // last branch update
+	exists  bool           // whether the branch already exists
+	treeSHA string         // SHA the tree request answers with
+}
+
+func (s *publishServer) routes() map[string]func(w http.ResponseWriter, r *http.Request) {
+	reply := func(w http.ResponseWriter, status int, v any) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	decode := func(r *http.Request) map[string]any {
+		var v map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&v)
+		return v
+	}
+	return map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/assets/git/ref/heads/main": func(w http.ResponseWriter, r *http.Request) {
+			reply(w, http.StatusOK, map[string]any{"object": map[string]string{"sha": "c0"}})
+		},
+		"/repos/myorg/assets/git/commits/c0": func(w http.ResponseWriter, r *http.Request) {
+			reply(w, http.StatusOK, map[string]any{"tree": map[string]string{"sha": "t0"}})
+		},
+		"/repos/myorg/assets/git/blobs": func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			content, _ := base64.StdEncoding.DecodeString(decode(r)["content"].(string))
+			s.blobs = append(s.blobs, string(content))
+			reply(w, http.StatusCreated, map[string]string{"sha": "b" + string(rune('0'+len(s.blobs)))})
+		},
+		"/repos/myorg/assets/git/trees": func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.tree = decode(r)
+			reply(w, http.StatusCreated, map[string]string{"sha": s.treeSHA})
+		},
+		"/repos/myorg/assets/git/commits": func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.commit = decode(r)
+			reply(w, http.StatusCreated, map[string]string{"sha": "c1"})
+		},
+		"/repos/myorg/assets/git/refs": func(w http.ResponseWriter, r *http.Request) {
+			if s.exists {
+				reply(w, http.StatusUnprocessableEntity, map[string]string{"message": "Reference already exists"})
+				return
+			}
+			reply(w, http.StatusCreated, map[string]string{"ref": "refs/heads/cops/publish/skills/k8s"})
+		},
+		"/repos/myorg/assets/git/refs/heads/cops/publish/skills/k8s": func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.patched = decode(r)
+			reply(w, http.StatusOK, map[string]string{"ref": "refs/heads/cops/publish/skills/k8s"})
+		},
+	}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	t.Parallel()
+
+	pub := Publication{
+		Repo:    "myorg/assets",
+		Base:    "main",
+		Branch:  "cops/publish/skills/k8s",
+		Message: "Update skills/k8s",
+		Files: map[string]PublishedFile{
+			"skills/k8s/SKILL.md":      {Content: []byte("# K8s v2")},
+			"skills/k8s/bin/deploy.sh": {Content: []byte("#!/bin/sh"), Executable: true},
+		},
+		Delete: []string{"skills/k8s/old.md"},
+	}
+
+	t.Run("new branch", func(t *testing.T) {
+		t.Parallel()
+		s := &publishServer{treeSHA: "t1"}
+		p := NewPublisher(newUpdatesTestClient(t, s.routes()))
+		sha, err := p.Publish(pub)
+		if err != nil || sha != "c1" {
+			t.Fatalf("Publish() = %q, %v; want c1", sha, err)
+		}
+		if len(s.blobs) != 2 || s.blobs[0] != "# K8s v2" || s.blobs[1] != "#!/bin/sh" {
+			t.Errorf("uploaded blobs = %q", s.blobs)
+		}
+		entries, _ := s.tree["tree"].([]any)
+		if s.tree["base_tree"] != "t0" || len(entries) != 3 {
+			t.Fatalf("tree request = %v", s.tree)
+		}
+		want := []map[string]any{
+			{"path": "skills/k8s/SKILL.md", "mode": "100644", "type": "blob", "sha": "b1"},
+			{"path": "skills/k8s/bin/deploy.sh", "mode": "100755", "type": "blob", "sha": "b2"},
+			{"path": "skills/k8s/old.md", "mode": "100644", "type": "blob", "sha": nil},
+		}
+		for _, w := range want {
+			found := false
+			for _, e := range entries {
+				e := e.(map[string]any)
+				if e["path"] == w["path"] && e["mode"] == w["mode"] && e["sha"] == w["sha"] {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("tree entries %v lack %v", entries, w)
+			}
+		}
+		if parents, _ := s.commit["parents"].([]any); s.commit["tree"] != "t1" || len(parents) != 1 || parents[0] != "c0" {
+			t.Errorf("commit request = %v", s.commit)
+		}
+		if s.patched != nil {
+			t.Errorf("new branch updated instead of created: %v", s.patched)
+		}
+	})
+
+	t.Run("existing branch", func(t *testing.T) {
+		t.Parallel()
+		s := &publishServer{treeSHA: "t1", exists: true}
+		p := NewPublisher(newUpdatesTestClient(t, s.routes()))
+		if _, err := p.Publish(pub); err != nil {
+			t.Fatalf("Publish(): %v", err)
+		}
+		if s.patched["sha"] != "c1" || s.patched["force"] != true {
+			t.Errorf("branch update = %v", s.patched)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		t.Parallel()
+		s := &publishServer{treeSHA: "t0"}
+		p := NewPublisher(newUpdatesTestClient(t, s.routes()))
+		sha, err := p.Publish(pub)
+		if err != nil || sha != "" {
+			t.Errorf("Publish() of unchanged content = %q, %v; want no commit", sha, err)
+		}
+		if s.commit != nil {
+			t.Errorf("commit created: %v", s.commit)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/ui"
+)
+
+// RateLimitEnvVar names the environment variable choosing what happens when
+// the GitHub API rate limit is exhausted: "fail" (the default) or "wait".
+const RateLimitEnvVar = "COPS_RATE_LIMIT"
+
+// Rate limit modes.
+const (
+	RateLimitFail = "fail"
+	RateLimitWait = "wait"
+)
+
+// RateLimitModeFromEnv returns the rate limit mode set by $COPS_RATE_LIMIT.
+func RateLimitModeFromEnv() (string, error) {
+	switch mode := os.Getenv(RateLimitEnvVar); mode {
+	case "", RateLimitFail:
+		return RateLimitFail, nil
+	case RateLimitWait:
+		return RateLimitWait, nil
+	default:
+		return "", fmt.Errorf("%s: invalid mode %q: use %q or %q", RateLimitEnvVar, mode, RateLimitFail, RateLimitWait)
+	}
+}
+
+// RateLimitError reports a request refused because the GitHub API rate
+// limit is exhausted until Reset.
+type RateLimitError struct {
+	Limit string // requests allowed per window, as GitHub reports it
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "GitHub API rate limit exceeded"
+	if e.Limit != "" {
+		msg += " (" + e.Limit + " requests per hour)"
+	}
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf("; it resets at %s (in %s)", e.Reset.Local().Format("15:04:05"), max(time.Until(e.Reset), 0).Round(time.Second))
+	}
+	return msg + ". Set GITHUB_TOKEN (or run `cops login`) for a higher limit, or " + RateLimitEnvVar + "=" + RateLimitWait + " to wait for the reset"
+}
+
+// RateLimitStatus is the state of the GitHub API rate limit of a client.
+type RateLimitStatus struct {
+	Limit     int // requests allowed per hour
+	Remaining int // requests left until Reset
+	Reset     time.Time
+}
+
+// CurrentRateLimit returns the core GitHub API rate limit of the
+// credentials client sends. The lookup does not count against it.
+func CurrentRateLimit(client *http.Client) (RateLimitStatus, error) {
+	resp, err := client.Get(githubAPIBase + "/rate_limit")
+	if err != nil {
+		return RateLimitStatus{}, fmt.Errorf("fetching rate limit: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return RateLimitStatus{}, fmt.Errorf("fetching rate limit: HTTP %d — %s", resp.StatusCode, string(body))
+	}
+	var payload struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return RateLimitStatus{}, fmt.Errorf("decoding rate limit: %w", err)
+	}
+	core := payload.Resources.Core
+	return RateLimitStatus{Limit: core.Limit, Remaining: core.Remaining, Reset: time.Unix(core.Reset, 0)}, nil
+}
+
+// WithRateLimit returns a copy of client that handles an exhausted GitHub
+// rate limit as mode says (see RateLimitTransport).
+func WithRateLimit(client *http.Client, mode string) *http.Client {
+	limited := *client
+	limited.Transport = &RateLimitTransport{Base: client.Transport, Wait: mode == RateLimitWait, Out: os.Stderr}
+	return &limited
+}
+
+// RateLimitTransport is an http.RoundTripper that recognises responses
+// refused because the GitHub rate limit is exhausted (a 403 or 429 with
+// X-RateLimit-Remaining: 0). With Wait it counts down to the time in
+// X-RateLimit-Reset on Out and sends the request again; otherwise it
+// returns a *RateLimitError naming the reset time.
+type RateLimitTransport struct {
+	Base http.RoundTripper // nil means http.DefaultTransport
+	Wait bool
+	Out  io.Writer // nil discards the countdown
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || !rateLimited(resp) {
+		return resp, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+
+	limitErr := &RateLimitError{Limit: resp.Header.Get("X-RateLimit-Limit")}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		limitErr.Reset = time.Unix(reset, 0)
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !t.Wait || limitErr.Reset.IsZero() || !replayable {
+		return nil, limitErr
+	}
+
+	if err := t.countdown(req, limitErr.Reset); err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return base.RoundTrip(req)
+}
+
+// countdown waits until a second past reset, printing the time left every
+// second, or until the request is cancelled.
+func (t *RateLimitTransport) countdown(req *http.Request, reset time.Time) error {
+	out := t.Out
+	if out == nil {
+		out = io.Discard
+	}
+	until := reset.Add(time.Second)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for left := time.Until(until); left > 0; left = time.Until(until) {
+		fmt.Fprintf(out, ui.Text("\r⏳ GitHub API rate limit exceeded; waiting %s for it to reset..."), left.Round(time.Second))
+		select {
+		case <-req.Context().Done():
+			fmt.Fprintln(out)
+			return req.Context().Err()
+		case <-ticker.C:
+		}
+	}
+	fmt.Fprintf(out, "\r%-70s\n", ui.Text("⏳ GitHub API rate limit reset; resuming."))
+	return nil
+}
+
+// rateLimited reports whether resp was refused because the rate limit is
+// exhausted.
+func rateLimited(resp *http.Response) bool {
+	return (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
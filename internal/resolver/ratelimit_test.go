@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	t.Parallel()
+
+	// The first request exhausts a limit that has already reset.
+	newServer := func(calls *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(calls, 1) == 1 {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-2*time.Second).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		ts := newServer(&calls)
+		defer ts.Close()
+
+		client := &http.Client{Transport: &RateLimitTransport{Base: ts.Client().Transport}}
+		_, err := client.Get(ts.URL)
+		var limitErr *RateLimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("error = %v, want a *RateLimitError", err)
+		}
+		if limitErr.Reset.IsZero() || !strings.Contains(err.Error(), "60 requests per hour") {
+			t.Errorf("error = %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		ts := newServer(&calls)
+		defer ts.Close()
+
+		var out bytes.Buffer
+		client := &http.Client{Transport: &RateLimitTransport{Base: ts.Client().Transport, Wait: true, Out: &out}}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls != 2 {
+			t.Errorf("status = %d after %d calls, want 200 after 2", resp.StatusCode, calls)
+		}
+		if !strings.Contains(out.String(), "rate limit") {
+			t.Errorf("output = %q, want a countdown", out.String())
+		}
+	})
+}
+
+func TestCurrentRateLimit(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/rate_limit": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"resources":{"core":{"limit":5000,"remaining":4321,"reset":1767225600}}}`))
+		},
+	})
+	defer ts.Close()
+
+	client := &http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}}
+	got, err := CurrentRateLimit(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RateLimitStatus{Limit: 5000, Remaining: 4321, Reset: time.Unix(1767225600, 0)}
+	if got != want {
+		t.Errorf("CurrentRateLimit() = %+v, want %+v", got, want)
+	}
+
+	if _, err := CurrentRateLimit(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL + "/missing",
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}}); err == nil {
+		t.Error("CurrentRateLimit() succeeded on HTTP 404")
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status    int
+		remaining string
+		want      bool
+	}{
+		{http.StatusForbidden, "0", true},
+		{http.StatusTooManyRequests, "0", true},
+		{http.StatusForbidden, "", false}, // a permission error
+		{http.StatusOK, "0", false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.remaining != "" {
+			resp.Header.Set("X-RateLimit-Remaining", tt.remaining)
+		}
+		if got := rateLimited(resp); got != tt.want {
+			t.Errorf("rateLimited(%d, %q) = %v, want %v", tt.status, tt.remaining, got, tt.want)
+		}
+	}
+}
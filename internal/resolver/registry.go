@@ -0,0 +1,260 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// RegistryIndexEnvVar names the environment variable holding the registry
+// index URL.
+const RegistryIndexEnvVar = "COPS_REGISTRY_URL"
+
+// registryIndex is the published JSON index mapping package names to the
+// concrete sources that serve them:
+//
+//	{
+//	  "packages": {
+//	    "awesome/code-review": {
+//	      "repo": "github/awesome-copilot",
+//	      "path": "instructions/code-review.instructions.md",
+//	      "versions": {"1.2.0": "v1.2.0", "1.3.0": "4f2c1e9"}
+//	    }
+//	  }
+//	}
+//
+// Each version maps to a Git ref (tag, branch or commit SHA) in the repo.
+type registryIndex struct {
+	Packages map[string]registryPackage `json:"packages"`
+}
+
+// Redirector is implemented by sources serving refs from another source,
+// so that what a ref really fetches can be checked against a policy.
+type Redirector interface {
+	// Target returns the ref the content of ref is fetched from.
+	Target(ref config.AssetRef) (config.AssetRef, error)
+}
+
+// Target delegates to the source serving ref. Refs of sources that fetch
+// what they name are their own target.
+func (rt *Router) Target(ref config.AssetRef) (config.AssetRef, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return ref, err
+	}
+	r, ok := s.(Redirector)
+	if !ok {
+		return ref, nil
+	}
+	return r.Target(ref)
+}
+
+// registryPackage is a single package in the registry index.
+type registryPackage struct {
+	Repo     string            `json:"repo"` // "org/repo"
+	Path     string            `json:"path"` // file or directory inside the repo; may be empty for the repo root
+	Versions map[string]string `json:"versions"`
+}
+
+// RegistrySource serves "registry:<package>@<version>" references by
+// looking the package up in a JSON index and delegating to the source
+// that serves the concrete "org/repo/path@ref" it maps to. The index is
+// downloaded once per source.
+type RegistrySource struct {
+	client   *http.Client
+	indexURL string
+	upstream ResolverAPI
+
+	mu    sync.Mutex
+	index *registryIndex
+}
+
+// NewRegistrySource creates a RegistrySource reading the index at indexURL
+// with client, and fetching package content through upstream. An empty
+// indexURL makes every registry ref fail with a configuration hint.
+func NewRegistrySource(client *http.Client, indexURL string, upstream ResolverAPI) *RegistrySource {
+	return &RegistrySource{client: client, indexURL: indexURL, upstream: upstream}
+}
+
+// Supports reports whether ref is a registry package reference.
+func (s *RegistrySource) Supports(ref config.AssetRef) bool {
+	return ref.IsRegistry()
+}
+
+// ResolveRef resolves "latest" to the package's highest published version.
+func (s *RegistrySource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	if ref.Ref != "latest" {
+		return ref, nil
+	}
+	pkg, err := s.lookupPackage(ref)
+	if err != nil {
+		return ref, err
+	}
+	latest := ""
+	for v := range pkg.Versions {
+		if latest == "" || compareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return ref, fmt.Errorf("registry package %q has no published versions", ref.Package)
+	}
+	ref.Ref = latest
+	return ref, nil
+}
+
+// DownloadFile fetches the file the package version maps to.
+func (s *RegistrySource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	target, err := s.Target(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.upstream.DownloadFile(target)
+}
+
+// ListDirectory lists the directory the package version maps to. Entry
+// paths are relative to the package root, like those of bundle sources.
+func (s *RegistrySource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	target, err := s.Target(ref)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.upstream.ListDirectory(target)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := s.lookupPackage(ref)
+	if err != nil {
+		return nil, err
+	}
+	if root := strings.Trim(pkg.Path, "/"); root != "" {
+		for i := range entries {
+			entries[i].Path = strings.TrimPrefix(entries[i].Path, root+"/")
+		}
+	}
+	return entries, nil
+}
+
+// ResolveSHA resolves the commit SHA the package version maps to.
+func (s *RegistrySource) ResolveSHA(ref config.AssetRef) (string, error) {
+	target, err := s.Target(ref)
+	if err != nil {
+		return "", err
+	}
+	return s.upstream.ResolveSHA(target)
+}
+
+// Target translates a registry ref into the concrete ref it maps to. A path
+// in the registry ref selects a location inside the package.
+func (s *RegistrySource) Target(ref config.AssetRef) (config.AssetRef, error) {
+	ref, err := s.ResolveRef(ref)
+	if err != nil {
+		return ref, err
+	}
+	pkg, err := s.lookupPackage(ref)
+	if err != nil {
+		return ref, err
+	}
+	gitRef, ok := pkg.Versions[ref.Ref]
+	if !ok {
+		return ref, fmt.Errorf("registry package %q has no version %q", ref.Package, ref.Ref)
+	}
+	target, err := config.ParseRef(pkg.Repo + "/" + path.Join(strings.Trim(pkg.Path, "/"), ref.Path) + "@" + gitRef)
+	if err != nil {
+		return ref, fmt.Errorf("registry package %q: %w", ref.Package, err)
+	}
+	if !target.IsGitHub() {
+		return ref, fmt.Errorf("registry package %q: must map to a GitHub repository path", ref.Package)
+	}
+	return target, nil
+}
+
+func (s *RegistrySource) lookupPackage(ref config.AssetRef) (registryPackage, error) {
+	index, err := s.loadIndex()
+	if err != nil {
+		return registryPackage{}, err
+	}
+	pkg, ok := index.Packages[ref.Package]
+	if !ok {
+		return registryPackage{}, fmt.Errorf("package %q not found in registry index %s", ref.Package, s.indexURL)
+	}
+	return pkg, nil
+}
+
+// loadIndex downloads and caches the registry index.
+func (s *RegistrySource) loadIndex() (*registryIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		return s.index, nil
+	}
+	if s.indexURL == "" {
+		return nil, fmt.Errorf("no registry index configured: set %s to the URL of the index JSON", RegistryIndexEnvVar)
+	}
+
+	resp, err := s.client.Get(s.indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching registry index: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("fetching registry index: HTTP %d — %s", resp.StatusCode, string(body))
+	}
+
+	var index registryIndex
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("decoding registry index: %w", err)
+	}
+	s.index = &index
+	return s.index, nil
+}
+
+// compareVersions orders dotted version strings numerically ("1.10.0" >
+// "1.9.2"), ignoring a leading "v"; missing components count as zero. A
+// version with a pre-release suffix sorts before the same version without
+// one. Non-numeric components are compared byte-wise.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		ap, bp := "0", "0"
+		if i < len(aParts) {
+			ap = aParts[i]
+		}
+		if i < len(bParts) {
+			bp = bParts[i]
+		}
+		an, aErr := strconv.Atoi(ap)
+		bn, bErr := strconv.Atoi(bp)
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && ap != bp:
+			return strings.Compare(ap, bp)
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
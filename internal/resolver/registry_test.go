@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// recordingResolver returns the raw ref it was asked for as content.
+type recordingResolver struct{}
+
+func (recordingResolver) ResolveRef(ref config.AssetRef) (config.AssetRef, error) { return ref, nil }
+func (recordingResolver) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	return []byte(ref.Raw()), nil
+}
+func (recordingResolver) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	return []GitHubTreeEntry{{Path: ref.Path + "/SKILL.md", Type: "blob"}}, nil
+}
+func (recordingResolver) ResolveSHA(ref config.AssetRef) (string, error) {
+	return "sha-" + ref.Ref, nil
+}
+
+func newRegistryTestSource(t *testing.T) (*RegistrySource, *int32) {
+	t.Helper()
+	var fetches int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"packages": map[string]any{
+				"awesome/code-review": map[string]any{
+					"repo":     "github/awesome-copilot",
+					"path":     "instructions/code-review.instructions.md",
+					"versions": map[string]string{"1.2.0": "v1.2.0", "1.10.0": "abc1234", "1.9.0": "v1.9.0"},
+				},
+				"awesome/k8s": map[string]any{
+					"repo":     "github/awesome-copilot",
+					"path":     "skills/k8s/",
+					"versions": map[string]string{"2.0.0": "v2"},
+				},
+			},
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return NewRegistrySource(ts.Client(), ts.URL+"/index.json", recordingResolver{}), &fetches
+}
+
+func TestRegistrySource(t *testing.T) {
+	t.Parallel()
+	src, fetches := newRegistryTestSource(t)
+
+	cases := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"registry:awesome/code-review@1.2.0", "github/awesome-copilot/instructions/code-review.instructions.md@v1.2.0", false},
+		{"registry:awesome/code-review@latest", "github/awesome-copilot/instructions/code-review.instructions.md@abc1234", false},
+		{"registry:awesome/code-review@3.0.0", "", true},
+		{"registry:awesome/unknown@1.0.0", "", true},
+	}
+	for _, tc := range cases {
+		got, err := src.DownloadFile(mustParseRef(t, tc.raw))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("DownloadFile(%s): err = %v, wantErr %v", tc.raw, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && string(got) != tc.want {
+			t.Errorf("DownloadFile(%s) fetched %s, want %s", tc.raw, got, tc.want)
+		}
+	}
+
+	entries, err := src.ListDirectory(mustParseRef(t, "registry:awesome/k8s@2.0.0"))
+	if err != nil || len(entries) != 1 || entries[0].Path != "SKILL.md" {
+		t.Errorf("ListDirectory = %+v, %v", entries, err)
+	}
+	if sha, err := src.ResolveSHA(mustParseRef(t, "registry:awesome/k8s@2.0.0")); err != nil || sha != "sha-v2" {
+		t.Errorf("ResolveSHA = %q, %v", sha, err)
+	}
+
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("index fetched %d times, want 1", n)
+	}
+}
+
+func TestRouter_Target(t *testing.T) {
+	t.Parallel()
+	src, _ := newRegistryTestSource(t)
+	rt := NewRouter(src, NewLocalSource(t.TempDir()))
+
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"registry:awesome/code-review@1.2.0", "github/awesome-copilot/instructions/code-review.instructions.md@v1.2.0"},
+		{"registry:awesome/k8s@latest", "github/awesome-copilot/skills/k8s@v2"},
+		{"local:docs/a.md", "local:docs/a.md"},
+	}
+	for _, tc := range cases {
+		got, err := rt.Target(mustParseRef(t, tc.raw))
+		if err != nil || got.Raw() != tc.want {
+			t.Errorf("Target(%s) = %s, %v; want %s", tc.raw, got.Raw(), err, tc.want)
+		}
+	}
+	if _, err := rt.Target(mustParseRef(t, "registry:awesome/unknown@1.0.0")); err == nil {
+		t.Error("Target() of an unknown package: expected an error")
+	}
+}
+
+func TestRegistrySource_NoIndexConfigured(t *testing.T) {
+	t.Parallel()
+	src := NewRegistrySource(http.DefaultClient, "", recordingResolver{})
+	_, err := src.DownloadFile(mustParseRef(t, "registry:awesome/code-review@1.2.0"))
+	if err == nil {
+		t.Fatal("expected error without an index URL")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"v1.2.0", "1.2.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.0-rc.1", "1.2.0", -1},
+		{"2.0.0-beta", "2.0.0-alpha", 1},
+		{"1.0.0", "2.0.0", -1},
+	}
+	for _, tc := range cases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
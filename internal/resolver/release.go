@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// ReleaseSource fetches assets attached to GitHub releases. Archives
+// (.zip, .tar, .tar.gz, .tgz) are unpacked and the ref's path selects a file
+// or directory inside; any other asset is served as a single file.
+type ReleaseSource struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	bundles map[string]bundle // "<org>/<repo>@<tag>/<asset>" → files
+	assets  map[string][]byte // "<org>/<repo>@<tag>/<asset>" → asset as downloaded
+
+	readLimits
+}
+
+// NewReleaseSource creates a ReleaseSource with the given (authenticated)
+// HTTP client.
+func NewReleaseSource(client *http.Client) *ReleaseSource {
+	return &ReleaseSource{
+		client:  client,
+		bundles: make(map[string]bundle),
+		assets:  make(map[string][]byte),
+	}
+}
+
+// Supports reports whether ref is a release asset reference.
+func (s *ReleaseSource) Supports(ref config.AssetRef) bool {
+	return ref.IsRelease()
+}
+
+// ResolveRef returns the ref unchanged: release tags have no aliases.
+func (s *ReleaseSource) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	return ref, nil
+}
+
+// DownloadFile returns a single file from the release asset.
+func (s *ReleaseSource) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	b, err := s.loadBundle(ref)
+	if err != nil {
+		return nil, err
+	}
+	return b.file(ref.Path)
+}
+
+// ListDirectory lists the archive files under the ref's path.
+func (s *ReleaseSource) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	b, err := s.loadBundle(ref)
+	if err != nil {
+		return nil, err
+	}
+	entries := b.entries(ref.Path)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no files found under %q in %s", ref.Path, ref.Raw())
+	}
+	return entries, nil
+}
+
+// ResolveSHA returns the commit SHA the release tag points at.
+func (s *ReleaseSource) ResolveSHA(ref config.AssetRef) (string, error) {
+	return New(s.client).ResolveSHA(config.AssetRef{Org: ref.Org, Repo: ref.Repo, Ref: ref.Ref})
+}
+
+// releaseInfo is the subset of the GitHub Releases API response cops needs.
+type releaseInfo struct {
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"` // API URL; serves the binary with Accept: application/octet-stream
+	} `json:"assets"`
+}
+
+// releaseKey identifies the release asset ref points at.
+func releaseKey(ref config.AssetRef) string {
+	return fmt.Sprintf("%s@%s/%s", ref.RepoFullName(), ref.Ref, ref.ReleaseAsset)
+}
+
+// loadBundle downloads and unpacks the release asset, caching it so
+// directory assets don't re-download the archive per file.
+func (s *ReleaseSource) loadBundle(ref config.AssetRef) (bundle, error) {
+	key := releaseKey(ref)
+	s.mu.Lock()
+	b, ok := s.bundles[key]
+	s.mu.Unlock()
+	if ok {
+		return b, nil
+	}
+
+	release, err := s.release(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.downloadNamedAsset(ref, release, ref.ReleaseAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = extractArchive(ref.ReleaseAsset, data)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking %s: %w", ref.ReleaseAsset, err)
+	}
+
+	s.mu.Lock()
+	s.bundles[key] = b
+	s.assets[key] = data
+	s.mu.Unlock()
+
+	return b, nil
+}
+
+// release fetches the release tagged ref.Ref, with its assets.
+func (s *ReleaseSource) release(ref config.AssetRef) (releaseInfo, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", githubAPIBase, ref.Org, ref.Repo, ref.Ref)
+	resp, err := s.client.Get(url)
+	if err != nil {
+		return releaseInfo{}, fmt.Errorf("fetching release %s for %s: %w", ref.Ref, ref.RepoFullName(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return releaseInfo{}, fmt.Errorf("fetching release %s for %s: HTTP %d — %s", ref.Ref, ref.RepoFullName(), resp.StatusCode, string(body))
+	}
+
+	var release releaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return releaseInfo{}, fmt.Errorf("decoding release response: %w", err)
+	}
+	return release, nil
+}
+
+// downloadNamedAsset downloads the asset called name of release. Archives
+// are bounded by MaxArchiveSize, other assets by the file limit.
+func (s *ReleaseSource) downloadNamedAsset(ref config.AssetRef, release releaseInfo, name string) ([]byte, error) {
+	limit := s.limits().File
+	if isArchive(name) {
+		limit = MaxArchiveSize
+	}
+	for _, a := range release.Assets {
+		if a.Name == name {
+			return s.downloadAsset(a.URL, limit)
+		}
+	}
+	return nil, fmt.Errorf("release %s of %s has no asset named %q", ref.Ref, ref.RepoFullName(), name)
+}
+
+func (s *ReleaseSource) downloadAsset(url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading release asset: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("downloading release asset: HTTP %d — %s", resp.StatusCode, string(body))
+	}
+
+	data, err := readBody(resp, limit)
+	if err != nil {
+		return nil, fmt.Errorf("reading release asset: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// newReleaseTestResolver serves release v1.2.0 of myorg/myrepo with a
+// single asset whose content is data.
+func newReleaseTestResolver(t *testing.T, assetName string, data []byte) (*ReleaseSource, *httptest.Server) {
+	t.Helper()
+	var ts *httptest.Server
+	ts = newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/myrepo/releases/tags/v1.2.0": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"assets": []map[string]string{
+					{"name": assetName, "url": ts.URL + "/assets/42"},
+				},
+			})
+		},
+		"/assets/42": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				t.Errorf("asset download Accept = %q", r.Header.Get("Accept"))
+			}
+			_, _ = w.Write(data)
+		},
+		"/repos/myorg/myrepo/commits/v1.2.0": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]string{"sha": "release-sha"})
+		},
+	})
+	client := &http.Client{
+		Transport: &rewriteTransport{
+			base:    ts.Client().Transport,
+			apiBase: ts.URL,
+			rawBase: ts.URL,
+			origAPI: githubAPIBase,
+			origRaw: githubRawBase,
+		},
+	}
+	return NewReleaseSource(client), ts
+}
+
+func TestReleaseSource_TarballAsset(t *testing.T) {
+	t.Parallel()
+	archive := buildTar(t, map[string]string{
+		"prompts/review.md":     "review",
+		"skills/k8s/SKILL.md":   "skill",
+		"skills/k8s/scripts.sh": "echo",
+	}, true)
+	src, ts := newReleaseTestResolver(t, "assets.tar.gz", archive)
+	defer ts.Close()
+
+	ref, err := config.ParseRef("myorg/myrepo!release:v1.2.0/assets.tar.gz//prompts/review.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := src.DownloadFile(ref)
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if string(got) != "review" {
+		t.Errorf("DownloadFile = %q", got)
+	}
+
+	ref.Path = "skills/k8s"
+	entries, err := src.ListDirectory(ref)
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("ListDirectory returned %d entries, want 2", len(entries))
+	}
+
+	sha, err := src.ResolveSHA(ref)
+	if err != nil || sha != "release-sha" {
+		t.Errorf("ResolveSHA = %q, %v", sha, err)
+	}
+}
+
+func TestReleaseSource_PlainAsset(t *testing.T) {
+	t.Parallel()
+	src, ts := newReleaseTestResolver(t, "review.prompt.md", []byte("# Review"))
+	defer ts.Close()
+
+	ref, _ := config.ParseRef("myorg/myrepo!release:v1.2.0/review.prompt.md")
+	got, err := src.DownloadFile(ref)
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if string(got) != "# Review" {
+		t.Errorf("DownloadFile = %q", got)
+	}
+}
+
+func TestReleaseSource_MissingAsset(t *testing.T) {
+	t.Parallel()
+	src, ts := newReleaseTestResolver(t, "other.zip", nil)
+	defer ts.Close()
+
+	ref, _ := config.ParseRef("myorg/myrepo!release:v1.2.0/assets.tar.gz")
+	if _, err := src.DownloadFile(ref); err == nil {
+		t.Fatal("DownloadFile: expected error for missing asset, got nil")
+	}
+}
+
+var _ SourceRepository = (*ReleaseSource)(nil)
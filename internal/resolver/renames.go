@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RenameReporter is implemented by sources that notice when a repository
+// they fetched from has been renamed or transferred.
+type RenameReporter interface {
+	// Renames maps the "<org>/<repo>" names requested since the source was
+	// created that GitHub redirected elsewhere to their current names.
+	Renames() map[string]string
+}
+
+// Renames merges the renames reported by every source.
+func (rt *Router) Renames() map[string]string {
+	renames := make(map[string]string)
+	for _, s := range rt.sources {
+		if rr, ok := s.(RenameReporter); ok {
+			for from, to := range rr.Renames() {
+				renames[from] = to
+			}
+		}
+	}
+	return renames
+}
+
+// checkRedirect follows redirects as the default policy does, noting
+// those that move a request from one repository to another. Renamed
+// repositories redirect API calls to /repositories/<id>, which does not
+// name the new location: Renames looks it up.
+func (r *Resolver) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	from := repoOfURL(via[0].URL)
+	if from == "" {
+		return nil
+	}
+	to := repoOfURL(req.URL)
+	moved := to != "" && !strings.EqualFold(from, to)
+	if moved || strings.HasPrefix(req.URL.Path, "/repositories/") {
+		r.mu.Lock()
+		if r.moved[from] == "" {
+			r.moved[from] = to
+		}
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// repoOfURL returns the "<org>/<repo>" a GitHub API, raw content or
+// archive URL points at, or "" for other URLs.
+func repoOfURL(u *url.URL) string {
+	var parts []string
+	switch u.Host {
+	case "api.github.com":
+		rest, ok := strings.CutPrefix(u.Path, "/repos/")
+		if !ok {
+			return ""
+		}
+		parts = strings.SplitN(rest, "/", 3)
+	case "raw.githubusercontent.com", "codeload.github.com":
+		parts = strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 3)
+	default:
+		return ""
+	}
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// Renames returns the repositories GitHub redirected since r was created,
+// mapped to their current "<org>/<repo>" names.
+func (r *Resolver) Renames() map[string]string {
+	r.mu.Lock()
+	var unknown []string
+	renames := make(map[string]string, len(r.moved))
+	for from, to := range r.moved {
+		if to == "" {
+			unknown = append(unknown, from)
+		} else {
+			renames[from] = to
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Strings(unknown)
+	for _, from := range unknown {
+		// A name that cannot be looked up is left out: the redirect
+		// still served the request.
+		if to, err := r.currentName(from); err == nil && !strings.EqualFold(from, to) {
+			renames[from] = to
+		}
+	}
+	return renames
+}
+
+// currentName asks GitHub for the current full name of a repository,
+// following the redirect of a renamed one.
+func (r *Resolver) currentName(fullName string) (string, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/repos/%s", githubAPIBase, fullName))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching repo info for %s: HTTP %d", fullName, resp.StatusCode)
+	}
+	var info struct {
+		FullName string `json:"full_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil || info.FullName == "" {
+		return "", fmt.Errorf("decoding repo info for %s: %v", fullName, err)
+	}
+	return info.FullName, nil
+}
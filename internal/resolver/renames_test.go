@@ -0,0 +1,88 @@
+package resolver
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func TestRenames(t *testing.T) {
+	t.Parallel()
+
+	redirect := func(to string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, http.StatusMovedPermanently)
+		}
+	}
+	ts := newTestServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		// Raw content redirects to the new name.
+		"/old-org/tools/main/go.md": redirect("/new-org/kit/main/go.md"),
+		"/new-org/kit/main/go.md": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("# Go"))
+		},
+		// The API redirects by repository ID.
+		"/repos/old-org/other/commits/main": redirect("/repositories/42/commits/main"),
+		"/repositories/42/commits/main": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"sha": "abc123"}`))
+		},
+		"/repos/old-org/other": redirect("/repositories/42"),
+		"/repositories/42": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"full_name": "new-org/other"}`))
+		},
+		"/repos/myorg/stays/commits/main": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"sha": "def456"}`))
+		},
+	})
+	defer ts.Close()
+
+	res := New(&http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}})
+	if got := res.Renames(); len(got) != 0 {
+		t.Errorf("Renames() before any request = %v, want none", got)
+	}
+
+	data, err := res.DownloadFile(config.AssetRef{Org: "old-org", Repo: "tools", Path: "go.md", Ref: "main"})
+	if err != nil || string(data) != "# Go" {
+		t.Fatalf("DownloadFile() = %q, %v; want the redirected content", data, err)
+	}
+	if sha, err := res.ResolveSHA(config.AssetRef{Org: "old-org", Repo: "other", Path: "go.md", Ref: "main"}); err != nil || sha != "abc123" {
+		t.Fatalf("ResolveSHA() = %q, %v; want the redirected SHA", sha, err)
+	}
+	if _, err := res.ResolveSHA(config.AssetRef{Org: "myorg", Repo: "stays", Path: "go.md", Ref: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"old-org/tools": "new-org/kit", "old-org/other": "new-org/other"}
+	if got := res.Renames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Renames() = %v, want %v", got, want)
+	}
+}
+
+func TestRepoOfURL(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"https://api.github.com/repos/org/repo/commits/main": "org/repo",
+		"https://api.github.com/repos/org/repo":              "org/repo",
+		"https://api.github.com/repositories/42":             "",
+		"https://raw.githubusercontent.com/org/repo/v1/a.md": "org/repo",
+		"https://codeload.github.com/org/repo/tar.gz/main":   "org/repo",
+		"https://example.com/org/repo/a.md":                  "",
+	}
+	for raw, want := range cases {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := repoOfURL(u); got != want {
+			t.Errorf("repoOfURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
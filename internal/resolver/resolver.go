@@ -2,10 +2,12 @@ package resolver
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/cbout22/copilot-sync/internal/config"
 )
@@ -25,24 +27,57 @@ type ResolverAPI interface {
 // Resolver turns asset references into downloadable URLs and fetches content.
 type Resolver struct {
 	client *http.Client
+
+	mirrors      []Mirror     // raw-content fallbacks, tried in order
+	mirrorClient *http.Client // client for mirrors and LFS storage; never carries the GitHub token
+
+	mu       sync.Mutex
+	archives map[string][]byte // "<org>/<repo>@<ref>" → repository tarball
+	files    map[string]bundle // "<org>/<repo>@<ref>" → files extracted so far
+	shas     map[string]string // "<org>/<repo>@<ref>" → commit SHA, from PrefetchSHAs
+	moved    map[string]string // "<org>/<repo>" → new name, "" until looked up
+
+	readLimits
 }
 
 // New creates a Resolver with the given (authenticated) HTTP client.
+// Redirects are followed, and those left by renamed repositories are
+// reported by Renames.
 func New(client *http.Client) *Resolver {
-	return &Resolver{client: client}
+	r := &Resolver{
+		archives: make(map[string][]byte),
+		files:    make(map[string]bundle),
+		shas:     make(map[string]string),
+		moved:    make(map[string]string),
+	}
+	c := *client
+	c.CheckRedirect = r.checkRedirect
+	r.client = &c
+	return r
+}
+
+// Supports reports whether ref points at a GitHub repository.
+func (r *Resolver) Supports(ref config.AssetRef) bool {
+	return ref.IsGitHub()
 }
 
 func (r *Resolver) ResolveDefaultBranchName(ref config.AssetRef) (string, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s", githubAPIBase, ref.Org, ref.Repo)
-	resp, err := r.client.Get(url)
+	return DefaultBranch(r.client, ref.RepoFullName())
+}
+
+// DefaultBranch returns the default branch of the GitHub repository repo
+// ("org/repo").
+func DefaultBranch(client *http.Client, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s", githubAPIBase, repo)
+	resp, err := client.Get(url)
 	if err != nil {
-		return "", fmt.Errorf("fetching repo info for %s: %w", ref.RepoFullName(), err)
+		return "", fmt.Errorf("fetching repo info for %s: %w", repo, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("fetching repo info for %s: HTTP %d — %s", ref.RepoFullName(), resp.StatusCode, string(body))
+		return "", fmt.Errorf("fetching repo info for %s: HTTP %d — %s", repo, resp.StatusCode, string(body))
 	}
 
 	var repoInfo struct {
@@ -53,7 +88,7 @@ func (r *Resolver) ResolveDefaultBranchName(ref config.AssetRef) (string, error)
 	}
 
 	if repoInfo.DefaultBranch == "" {
-		return "", fmt.Errorf("could not determine default branch for %s", ref.RepoFullName())
+		return "", fmt.Errorf("could not determine default branch for %s", repo)
 	}
 
 	return repoInfo.DefaultBranch, nil
@@ -80,12 +115,27 @@ func RawFileURL(ref config.AssetRef) string {
 }
 
 // DownloadFile fetches a single file from GitHub using the raw content URL.
+// Files stored with Git LFS are fetched through the LFS batch API.
 // If the exact path returns a 404, it retries with common extensions (.md).
 func (r *Resolver) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	data, _, err := r.DownloadFileIfChanged(ref, Validators{})
+	return data, err
+}
+
+// DownloadFileIfChanged is DownloadFile sent as a conditional request with
+// the validators of a previous download. Files served from a cached
+// tarball come without validators.
+func (r *Resolver) DownloadFileIfChanged(ref config.AssetRef, prev Validators) ([]byte, Validators, error) {
 	// Resolve @latest to the default branch
 	ref, err := r.ResolveRef(ref)
 	if err != nil {
-		return nil, err
+		return nil, Validators{}, err
+	}
+
+	// Serve files of directories already fetched through the tarball API
+	if content, ok := r.cachedFile(ref); ok {
+		content, err := r.resolveLFS(ref, content)
+		return content, Validators{}, err
 	}
 
 	// Try the exact path first, then fall back to common extensions
@@ -98,45 +148,101 @@ func (r *Resolver) DownloadFile(ref config.AssetRef) ([]byte, error) {
 	for _, path := range pathsToTry {
 		candidate := ref
 		candidate.Path = path
-		url := RawFileURL(candidate)
 
-		resp, err := r.client.Get(url)
-		if err != nil {
-			lastErr = fmt.Errorf("fetching %s: %w", url, err)
-			continue
+		data, validators, status, err := r.fetchRaw(candidate, prev)
+		if err == nil {
+			data, err = r.resolveLFS(candidate, data)
+			return data, validators, err
 		}
-
-		if resp.StatusCode == http.StatusNotFound {
-			_ = resp.Body.Close()
-			lastErr = fmt.Errorf("fetching %s: HTTP 404", url)
+		if status == http.StatusNotModified {
+			return data, validators, err
+		}
+		if status == http.StatusNotFound || status == 0 {
+			lastErr = err
 			continue
 		}
+		return nil, Validators{}, err
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-			return nil, fmt.Errorf("fetching %s: HTTP %d — %s", url, resp.StatusCode, string(body))
-		}
+	return nil, Validators{}, lastErr
+}
 
-		data, err := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("reading response from %s: %w", url, err)
+// fetchRaw downloads a single raw file. When raw.githubusercontent.com is
+// unreachable or refuses the request (anything but success, 304 or 404), the
+// configured mirrors are tried in order. It returns the HTTP status of the
+// deciding attempt, or 0 if the host could not be reached.
+func (r *Resolver) fetchRaw(ref config.AssetRef, prev Validators) ([]byte, Validators, int, error) {
+	limit := r.limits().File
+	data, validators, status, err := getRaw(r.client, RawFileURL(ref), nil, prev, limit)
+	if err == nil || status == http.StatusNotFound || status == http.StatusNotModified || errors.Is(err, ErrTooLarge) || len(r.mirrors) == 0 {
+		return data, validators, status, err
+	}
+
+	errs := []string{err.Error()}
+	for _, m := range r.mirrors {
+		data, validators, mirrorStatus, mirrorErr := getRaw(r.mirrorClient, m.fileURL(ref), m.authorize, prev, limit)
+		if mirrorErr == nil || mirrorStatus == http.StatusNotFound || mirrorStatus == http.StatusNotModified {
+			return data, validators, mirrorStatus, mirrorErr
 		}
+		errs = append(errs, mirrorErr.Error())
+	}
+	return nil, Validators{}, status, fmt.Errorf("all hosts failed: %s", strings.Join(errs, "; "))
+}
 
-		return data, nil
+// getRaw GETs url, letting authorize add credentials and sending prev's
+// validators, and reads up to limit bytes of it. It returns the response's
+// validators and HTTP status, or 0 if the request could not be sent; a 304
+// response yields ErrNotModified.
+func getRaw(client *http.Client, url string, authorize func(*http.Request), prev Validators, limit int64) ([]byte, Validators, int, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, Validators{}, 0, err
 	}
+	if authorize != nil {
+		authorize(req)
+	}
+	prev.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, Validators{}, 0, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
 
-	return nil, lastErr
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, prev, resp.StatusCode, ErrNotModified
+	case http.StatusNotFound:
+		return nil, Validators{}, resp.StatusCode, fmt.Errorf("fetching %s: HTTP 404", url)
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, Validators{}, resp.StatusCode, fmt.Errorf("fetching %s: HTTP %d — %s", url, resp.StatusCode, string(body))
+	}
+
+	data, err := readBody(resp, limit)
+	if err != nil {
+		return nil, Validators{}, resp.StatusCode, fmt.Errorf("reading response from %s: %w", url, err)
+	}
+	return data, validatorsOf(resp), resp.StatusCode, nil
 }
 
 // GitHubTreeEntry represents one item in the GitHub Trees API response.
 type GitHubTreeEntry struct {
 	Path string `json:"path"`
-	Type string `json:"type"` // "blob" or "tree"
+	Mode string `json:"mode,omitempty"` // git file mode, e.g. "100644"
+	Type string `json:"type"`           // "blob" or "tree"
 	SHA  string `json:"sha"`
 }
 
+// ModeExecutable is the git file mode of an executable blob.
+const ModeExecutable = "100755"
+
+// Executable reports whether the entry is a blob with the execute bit set.
+func (e GitHubTreeEntry) Executable() bool {
+	return e.Mode == ModeExecutable
+}
+
 // GitHubTreeResponse is the response from the GitHub Trees API.
 type GitHubTreeResponse struct {
 	SHA  string            `json:"sha"`
@@ -145,6 +251,11 @@ type GitHubTreeResponse struct {
 
 // ListDirectory fetches the recursive file listing of a directory in the repo.
 // This is used for skills which are downloaded as entire folders.
+//
+// The repository tarball is downloaded once per repo/ref and the requested
+// directory extracted from it, so DownloadFile can serve every file without
+// one HTTP request per blob. If the tarball is unavailable, it falls back to
+// the Trees API.
 func (r *Resolver) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
 	// Resolve @latest to the default branch
 	ref, err := r.ResolveRef(ref)
@@ -152,7 +263,102 @@ func (r *Resolver) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error)
 		return nil, err
 	}
 
-	// First, get the tree SHA for the ref
+	if entries, err := r.listFromArchive(ref); err == nil {
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("no files found under %s in %s@%s", ref.Path, ref.RepoFullName(), ref.Ref)
+		}
+		return entries, nil
+	}
+
+	return r.listFromTree(ref)
+}
+
+// archiveKey identifies a repository snapshot.
+func archiveKey(ref config.AssetRef) string {
+	return ref.RepoFullName() + "@" + ref.Ref
+}
+
+// listFromArchive extracts the ref's directory from the cached repository
+// tarball (downloading it on first use) and lists its files.
+func (r *Resolver) listFromArchive(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	data, err := r.tarball(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub tarballs wrap everything in a single "<org>-<repo>-<sha>/" directory.
+	prefix := ref.Path + "/"
+	extracted, executable, err := extractTarballFiltered(data, 1, func(name string) bool {
+		return name == ref.Path || strings.HasPrefix(name, prefix)
+	}, r.limits())
+	if err != nil {
+		return nil, fmt.Errorf("unpacking tarball for %s: %w", archiveKey(ref), err)
+	}
+
+	r.mu.Lock()
+	files := r.files[archiveKey(ref)]
+	if files == nil {
+		files = make(bundle)
+		r.files[archiveKey(ref)] = files
+	}
+	for p, content := range extracted {
+		files[p] = content
+	}
+	r.mu.Unlock()
+
+	entries := extracted.entries(ref.Path)
+	for i := range entries {
+		if executable[entries[i].Path] {
+			entries[i].Mode = ModeExecutable
+		}
+	}
+	return entries, nil
+}
+
+// tarball returns the repository tarball for the ref, downloading it once.
+func (r *Resolver) tarball(ref config.AssetRef) ([]byte, error) {
+	key := archiveKey(ref)
+	r.mu.Lock()
+	data, ok := r.archives[key]
+	r.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/tarball/%s", githubAPIBase, ref.Org, ref.Repo, ref.Ref)
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tarball for %s: %w", key, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching tarball for %s: HTTP %d", key, resp.StatusCode)
+	}
+
+	data, err = readBody(resp, MaxArchiveSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading tarball for %s: %w", key, err)
+	}
+
+	r.mu.Lock()
+	r.archives[key] = data
+	r.mu.Unlock()
+
+	return data, nil
+}
+
+// cachedFile returns a file previously extracted from a tarball, if any.
+func (r *Resolver) cachedFile(ref config.AssetRef) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	content, ok := r.files[archiveKey(ref)][ref.Path]
+	return content, ok
+}
+
+// listFromTree lists the directory through the Trees API. Files are then
+// downloaded one by one from raw.githubusercontent.com.
+func (r *Resolver) listFromTree(ref config.AssetRef) ([]GitHubTreeEntry, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1",
 		githubAPIBase, ref.Org, ref.Repo, ref.Ref)
 
@@ -188,8 +394,13 @@ func (r *Resolver) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error)
 	return entries, nil
 }
 
-// ResolveSHA resolves the given ref (branch, tag, or SHA) to a commit SHA.
+// ResolveSHA resolves the given ref (branch, tag, or SHA) to a commit SHA,
+// answering from the SHAs fetched by PrefetchSHAs when it can.
 func (r *Resolver) ResolveSHA(ref config.AssetRef) (string, error) {
+	if sha, ok := r.prefetchedSHA(ref); ok {
+		return sha, nil
+	}
+
 	// Resolve @latest to the default branch
 	ref, err := r.ResolveRef(ref)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/cbout22/copilot-sync/internal/config"
@@ -265,6 +266,69 @@ func TestListDirectory_NoFiles(t *testing.T) {
 	}
 }
 
+func TestListDirectory_Tarball(t *testing.T) {
+	t.Parallel()
+
+	tarball := buildTar(t, map[string]string{
+		"myorg-myrepo-abc123/skills/my-skill/SKILL.md":    "skill",
+		"myorg-myrepo-abc123/skills/my-skill/lib/util.go": "util",
+		"myorg-myrepo-abc123/skills/my-skill-other/x.md":  "other",
+		"myorg-myrepo-abc123/README.md":                   "readme",
+	}, true)
+
+	var tarballRequests, otherRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/myorg/myrepo/tarball/v1.0" {
+			atomic.AddInt32(&tarballRequests, 1)
+			_, _ = w.Write(tarball)
+			return
+		}
+		atomic.AddInt32(&otherRequests, 1)
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: &rewriteTransport{
+			base:    ts.Client().Transport,
+			apiBase: ts.URL,
+			rawBase: ts.URL,
+			origAPI: githubAPIBase,
+			origRaw: githubRawBase,
+		},
+	}
+	res := New(client)
+
+	ref := config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "skills/my-skill", Ref: "v1.0"}
+	entries, err := res.ListDirectory(ref)
+	if err != nil {
+		t.Fatalf("ListDirectory: unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "skills/my-skill/SKILL.md" || entries[1].Path != "skills/my-skill/lib/util.go" {
+		t.Fatalf("ListDirectory: got %+v", entries)
+	}
+
+	for _, e := range entries {
+		fileRef := ref
+		fileRef.Path = e.Path
+		if _, err := res.DownloadFile(fileRef); err != nil {
+			t.Fatalf("DownloadFile(%s): %v", e.Path, err)
+		}
+	}
+
+	// A second skill from the same repo/ref reuses the tarball.
+	if _, err := res.ListDirectory(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "skills/my-skill-other", Ref: "v1.0"}); err != nil {
+		t.Fatalf("ListDirectory(other): %v", err)
+	}
+
+	if n := atomic.LoadInt32(&tarballRequests); n != 1 {
+		t.Errorf("tarball fetched %d times, want 1", n)
+	}
+	if n := atomic.LoadInt32(&otherRequests); n != 0 {
+		t.Errorf("made %d per-file requests, want 0", n)
+	}
+}
+
 func TestResolveSHA_Success(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,159 @@
+package resolvertest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// ConformanceFiles returns the snapshot every conformance target must serve.
+// "skills/k8s-extra" shares a prefix with "skills/k8s" to catch sloppy
+// directory matching.
+func ConformanceFiles() map[string][]byte {
+	return map[string][]byte{
+		"instructions/review.md":       []byte("# Review\n"),
+		"skills/k8s/SKILL.md":          []byte("# Kubernetes\n"),
+		"skills/k8s/scripts/deploy.sh": []byte("#!/bin/sh\necho deploy\n"),
+		"skills/k8s-extra/SKILL.md":    []byte("# Extra\n"),
+	}
+}
+
+// Target is a source under test, already serving ConformanceFiles.
+type Target struct {
+	// Source is the implementation under test.
+	Source resolver.SourceRepository
+	// Ref builds a reference to path inside the served snapshot.
+	Ref func(path string) config.AssetRef
+	// Directories reports whether the source supports ListDirectory.
+	// Single-file sources must return an error from it instead.
+	Directories bool
+}
+
+// RunConformance checks the behavioral contract every SourceRepository must
+// honour. newTarget is called once per subtest with ConformanceFiles and must
+// return a fresh Target serving them.
+func RunConformance(t *testing.T, newTarget func(t *testing.T, files map[string][]byte) Target) {
+	t.Helper()
+
+	t.Run("Supports", func(t *testing.T) {
+		tg := newTarget(t, ConformanceFiles())
+		if ref := tg.Ref("instructions/review.md"); !tg.Source.Supports(ref) {
+			t.Errorf("Supports(%s) = false, want true", ref.Raw())
+		}
+	})
+
+	t.Run("ResolveRefStable", func(t *testing.T) {
+		tg := newTarget(t, ConformanceFiles())
+		first, err := tg.Source.ResolveRef(tg.Ref("instructions/review.md"))
+		if err != nil {
+			t.Fatalf("ResolveRef: %v", err)
+		}
+		if !tg.Source.Supports(first) {
+			t.Errorf("ResolveRef returned %s, which the source does not support", first.Raw())
+		}
+		second, err := tg.Source.ResolveRef(first)
+		if err != nil {
+			t.Fatalf("ResolveRef(resolved): %v", err)
+		}
+		if second != first {
+			t.Errorf("ResolveRef is not idempotent: %s then %s", first.Raw(), second.Raw())
+		}
+	})
+
+	t.Run("DownloadFile", func(t *testing.T) {
+		files := ConformanceFiles()
+		tg := newTarget(t, files)
+		for path, want := range files {
+			got, err := tg.Source.DownloadFile(tg.Ref(path))
+			if err != nil {
+				t.Errorf("DownloadFile(%s): %v", path, err)
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("DownloadFile(%s) = %q, want %q", path, got, want)
+			}
+		}
+	})
+
+	t.Run("DownloadFileMissing", func(t *testing.T) {
+		tg := newTarget(t, ConformanceFiles())
+		if _, err := tg.Source.DownloadFile(tg.Ref("does/not/exist.md")); err == nil {
+			t.Error("DownloadFile(missing): expected error, got nil")
+		}
+	})
+
+	t.Run("ResolveSHAStable", func(t *testing.T) {
+		tg := newTarget(t, ConformanceFiles())
+		ref := tg.Ref("instructions/review.md")
+		first, err := tg.Source.ResolveSHA(ref)
+		if err != nil {
+			t.Fatalf("ResolveSHA: %v", err)
+		}
+		second, err := tg.Source.ResolveSHA(ref)
+		if err != nil {
+			t.Fatalf("ResolveSHA (second call): %v", err)
+		}
+		if first != second {
+			t.Errorf("ResolveSHA is not stable: %q then %q", first, second)
+		}
+	})
+
+	if !newTarget(t, ConformanceFiles()).Directories {
+		t.Run("ListDirectoryUnsupported", func(t *testing.T) {
+			tg := newTarget(t, ConformanceFiles())
+			if _, err := tg.Source.ListDirectory(tg.Ref("skills/k8s")); err == nil {
+				t.Error("ListDirectory: single-file source must return an error")
+			}
+		})
+		return
+	}
+
+	t.Run("ListDirectory", func(t *testing.T) {
+		files := ConformanceFiles()
+		tg := newTarget(t, files)
+		dir := tg.Ref("skills/k8s")
+		entries, err := tg.Source.ListDirectory(dir)
+		if err != nil {
+			t.Fatalf("ListDirectory: %v", err)
+		}
+
+		got := make(map[string]bool)
+		for _, e := range entries {
+			if e.Type != "blob" {
+				t.Errorf("entry %s has type %q, want blob", e.Path, e.Type)
+			}
+			if !strings.HasPrefix(e.Path, "skills/k8s/") {
+				t.Errorf("entry %s is outside skills/k8s/", e.Path)
+			}
+			got[e.Path] = true
+
+			// Every listed entry must be downloadable from the same source.
+			fileRef := dir
+			fileRef.Path = e.Path
+			content, err := tg.Source.DownloadFile(fileRef)
+			if err != nil {
+				t.Errorf("DownloadFile(%s) after ListDirectory: %v", e.Path, err)
+			} else if !bytes.Equal(content, files[e.Path]) {
+				t.Errorf("DownloadFile(%s) = %q, want %q", e.Path, content, files[e.Path])
+			}
+		}
+		for _, want := range []string{"skills/k8s/SKILL.md", "skills/k8s/scripts/deploy.sh"} {
+			if !got[want] {
+				t.Errorf("ListDirectory missing %s", want)
+			}
+		}
+		if len(got) != 2 {
+			t.Errorf("ListDirectory returned %d entries, want 2", len(got))
+		}
+	})
+
+	t.Run("ListDirectoryMissing", func(t *testing.T) {
+		tg := newTarget(t, ConformanceFiles())
+		if _, err := tg.Source.ListDirectory(tg.Ref("skills/none")); err == nil {
+			t.Error("ListDirectory(missing): expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package resolvertest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/manifest"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+// FixedSyncedAt is the timestamp LockBuilder stamps on every entry so lock
+// files are byte-identical across runs.
+const FixedSyncedAt = "2025-01-01T00:00:00Z"
+
+// ManifestBuilder assembles a manifest for golden tests.
+type ManifestBuilder struct {
+	m *manifest.Manifest
+}
+
+// NewManifest starts an empty manifest.
+func NewManifest() *ManifestBuilder {
+	return &ManifestBuilder{m: manifest.New()}
+}
+
+// Entry adds an entry; it panics on an unknown asset type.
+func (b *ManifestBuilder) Entry(assetType, name, ref string) *ManifestBuilder {
+	if err := b.m.Set(assetType, name, ref); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Options attaches per-entry options to an existing entry.
+func (b *ManifestBuilder) Options(assetType, name string, opts manifest.EntryOptions) *ManifestBuilder {
+	b.m.SetOptions(assetType, name, opts)
+	return b
+}
+
+// Build returns the manifest.
+func (b *ManifestBuilder) Build() *manifest.Manifest {
+	return b.m
+}
+
+// Bytes renders the manifest as it would be saved to copilot.toml.
+func (b *ManifestBuilder) Bytes(t *testing.T) []byte {
+	t.Helper()
+	return render(t, b.m.Save)
+}
+
+// LockBuilder assembles a lock file with deterministic timestamps.
+type LockBuilder struct {
+	lf *manifest.LockFile
+}
+
+// NewLock starts an empty lock file.
+func NewLock() *LockBuilder {
+	return &LockBuilder{lf: manifest.NewLockFile()}
+}
+
+// Entry records an asset as synced at FixedSyncedAt.
+func (b *LockBuilder) Entry(assetType, name, ref, sha, targetPath string, content []byte) *LockBuilder {
+	b.lf.Set(assetType, name, ref, sha, targetPath, content)
+	e, _ := b.lf.Get(assetType, name)
+	e.SyncedAt = FixedSyncedAt
+	b.lf.Entries[assetType+"/"+name] = e
+	return b
+}
+
+// Directory records a directory asset, with per-file digests, as synced at
+// FixedSyncedAt.
+func (b *LockBuilder) Directory(assetType, name, ref, sha, targetPath string, files map[string][]byte) *LockBuilder {
+	b.lf.SetDirectory(assetType, name, ref, sha, targetPath, files)
+	e, _ := b.lf.Get(assetType, name)
+	e.SyncedAt = FixedSyncedAt
+	b.lf.Entries[assetType+"/"+name] = e
+	return b
+}
+
+// Build returns the lock file.
+func (b *LockBuilder) Build() *manifest.LockFile {
+	return b.lf
+}
+
+// Bytes renders the lock file as it would be saved to .cops.lock.
+func (b *LockBuilder) Bytes(t *testing.T) []byte {
+	t.Helper()
+	return render(t, b.lf.Save)
+}
+
+// render saves through save into a temp file and returns its content.
+func render(t *testing.T, save func(path string) error) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out")
+	if err := save(path); err != nil {
+		t.Fatalf("saving: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// AssertGolden compares got with testdata/<name>.golden, relative to the
+// calling test's package. Run the tests with -update to rewrite the file.
+func AssertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match golden file:\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,446 @@
+package resolvertest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/manifest"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// tarGz packs files into a gzipped tarball, each path prefixed by prefix.
+func tarGz(t *testing.T, prefix string, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, p := range manifest.SortedKeys(files) {
+		hdr := &tar.Header{Name: prefix + p, Mode: 0644, Size: int64(len(files[p])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(files[p]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestMain turns the test binary into a fixture source plugin when it runs
+// as one, named cops-source-<name>: it then serves the files of the "files"
+// directory next to it (see TestConformance_Plugin).
+func TestMain(m *testing.M) {
+	if strings.HasPrefix(filepath.Base(os.Args[0]), "cops-source-") {
+		os.Exit(servePlugin(filepath.Join(filepath.Dir(os.Args[0]), "files"), os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// servePlugin answers one plugin request for the files under root, in the
+// protocol of resolver.PluginSource, and returns the exit status.
+func servePlugin(root string, args []string) int {
+	var req struct {
+		Version int    `json:"version"`
+		Path    string `json:"path"`
+		Ref     string `json:"ref"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil || len(args) != 1 || req.Version != resolver.PluginProtocolVersion {
+		fmt.Fprintln(os.Stderr, "invalid request")
+		return 2
+	}
+	dir := filepath.Join(root, filepath.FromSlash(req.Path))
+
+	var resp any
+	switch args[0] {
+	case "resolve":
+		resp = map[string]string{"sha": "fixture-" + req.Ref}
+	case "download":
+		content, err := os.ReadFile(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		resp = map[string][]byte{"content": content}
+	case "list":
+		type file struct {
+			Path       string `json:"path"`
+			Executable bool   `json:"executable"`
+		}
+		var files []file
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			files = append(files, file{Path: filepath.ToSlash(rel), Executable: strings.HasSuffix(p, ".sh")})
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		resp = map[string][]file{"files": files}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown operation %q\n", args[0])
+		return 2
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// writeFiles writes files under root.
+func writeFiles(t *testing.T, root string, files map[string][]byte) {
+	t.Helper()
+	for p, content := range files {
+		dest := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dest, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func mustParse(t *testing.T, raw string) config.AssetRef {
+	t.Helper()
+	ref, err := config.ParseRef(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestConformance_Source(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		return Target{
+			Source:      NewSource("abc123", files),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "myorg/myrepo/"+p+"@v1") },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_GitHub(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		archive := tarGz(t, "myorg-myrepo-abc123/", files)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/repos/myorg/myrepo/tarball/v1":
+				_, _ = w.Write(archive)
+			case r.URL.Path == "/repos/myorg/myrepo/commits/v1":
+				_ = json.NewEncoder(w).Encode(map[string]string{"sha": "abc123"})
+			case strings.HasPrefix(r.URL.Path, "/myorg/myrepo/v1/"):
+				content, ok := files[strings.TrimPrefix(r.URL.Path, "/myorg/myrepo/v1/")]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(content)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(ts.Close)
+		return Target{
+			Source:      resolver.New(RoutingClient(ts)),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "myorg/myrepo/"+p+"@v1") },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_Release(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		archive := tarGz(t, "", files)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/myorg/myrepo/releases/tags/v1.0.0":
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"assets": []map[string]string{{"name": "assets.tar.gz", "url": "https://api.github.com/assets/1"}},
+				})
+			case "/assets/1":
+				_, _ = w.Write(archive)
+			case "/repos/myorg/myrepo/commits/v1.0.0":
+				_ = json.NewEncoder(w).Encode(map[string]string{"sha": "abc123"})
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(ts.Close)
+		return Target{
+			Source: resolver.NewReleaseSource(RoutingClient(ts)),
+			Ref: func(p string) config.AssetRef {
+				return mustParse(t, "myorg/myrepo!release:v1.0.0/assets.tar.gz//"+p)
+			},
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_OCI(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		layer := tarGz(t, "", files)
+		layerDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(layer))
+		manifestJSON, _ := json.Marshal(map[string]any{
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"layers": []map[string]any{{
+				"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+				"digest":    layerDigest,
+				"size":      len(layer),
+			}},
+		})
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/team/assets/manifests/v1":
+				w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256(manifestJSON)))
+				_, _ = w.Write(manifestJSON)
+			case "/v2/team/assets/blobs/" + layerDigest:
+				_, _ = w.Write(layer)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(ts.Close)
+		return Target{
+			Source: resolver.NewOCISource(RoutingClient(ts), ""),
+			Ref: func(p string) config.AssetRef {
+				return mustParse(t, "oci://registry.example.com/team/assets:v1//"+p)
+			},
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_Local(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		root := t.TempDir()
+		writeFiles(t, root, files)
+		return Target{
+			Source:      resolver.NewLocalSource(root),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "local:"+p) },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_Plugin(t *testing.T) {
+	t.Parallel()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		// The plugin is this test binary under the name of a plugin, with
+		// the files it serves next to it.
+		dir := t.TempDir()
+		plugin := filepath.Join(dir, "cops-source-fixture"+filepath.Ext(exe))
+		if err := os.Link(exe, plugin); err != nil {
+			data, err := os.ReadFile(exe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(plugin, data, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeFiles(t, filepath.Join(dir, "files"), files)
+		return Target{
+			Source:      resolver.NewPluginSource(context.Background(), "fixture", plugin),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "plugin:fixture/"+p+"@v1") },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_URL(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			content, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(content)
+		}))
+		t.Cleanup(ts.Close)
+		return Target{
+			Source: resolver.NewURLSource(ts.Client()),
+			Ref:    func(p string) config.AssetRef { return mustParse(t, ts.URL+"/"+p) },
+		}
+	})
+}
+
+func TestSource_Calls(t *testing.T) {
+	t.Parallel()
+	src := NewSource("abc123", ConformanceFiles())
+	ref := mustParse(t, "myorg/myrepo/skills/k8s@v1")
+	for range 2 {
+		if _, err := src.ListDirectory(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := src.Calls("ListDirectory"); got != 2 {
+		t.Errorf("Calls(ListDirectory) = %d, want 2", got)
+	}
+	if got := src.Calls("DownloadFile"); got != 0 {
+		t.Errorf("Calls(DownloadFile) = %d, want 0", got)
+	}
+}
+
+func TestGolden_Manifest(t *testing.T) {
+	t.Parallel()
+	got := NewManifest().
+		Entry("instructions", "review", "myorg/myrepo/instructions/review.md@v1").
+		Entry("skills", "k8s", "myorg/myrepo/skills/k8s@main").
+		Options("skills", "k8s", manifest.EntryOptions{AllowBranchUntil: "2025-06-30"}).
+		Bytes(t)
+	AssertGolden(t, "manifest", got)
+}
+
+func TestGolden_Lock(t *testing.T) {
+	t.Parallel()
+	got := NewLock().
+		Directory("skills", "k8s", "myorg/myrepo/skills/k8s@v1", "abc123", ".github/skills/k8s", map[string][]byte{
+			"SKILL.md":          []byte("skill"),
+			"scripts/deploy.sh": []byte("#!/bin/sh\n"),
+		}).
+		Entry("instructions", "review", "myorg/myrepo/instructions/review.md@v1", "abc123", ".github/instructions/review.instructions.md", []byte("# Review\n")).
+		Bytes(t)
+	AssertGolden(t, "lock", got)
+}
+
+// newBucketServer serves files as bucket "mirror" through either the S3
+// (path-style) or the GCS JSON API, with one-object pages to exercise
+// pagination.
+func newBucketServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	etag := func(p string) string { return fmt.Sprintf("%x", sha256.Sum256(files[p]))[:16] }
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, q := r.URL.EscapedPath(), r.URL.Query()
+		after := q.Get("continuation-token") + q.Get("pageToken")
+		var next []string
+		for _, p := range manifest.SortedKeys(files) {
+			if strings.HasPrefix(p, q.Get("prefix")) && p > after {
+				next = append(next, p)
+			}
+		}
+
+		switch {
+		case path == "/mirror/" && q.Get("list-type") == "2":
+			_, _ = fmt.Fprint(w, "<ListBucketResult>")
+			if len(next) > 0 {
+				_, _ = fmt.Fprintf(w, `<Contents><Key>%s</Key><ETag>"%s"</ETag></Contents>`, next[0], etag(next[0]))
+			}
+			if len(next) > 1 {
+				_, _ = fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", next[0])
+			}
+			_, _ = fmt.Fprint(w, "</ListBucketResult>")
+		case strings.HasPrefix(r.URL.Path, "/mirror/"):
+			p := strings.TrimPrefix(r.URL.Path, "/mirror/")
+			if _, ok := files[p]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("ETag", `"`+etag(p)+`"`)
+			_, _ = w.Write(files[p])
+		case path == "/storage/v1/b/mirror/o":
+			page := map[string]any{"items": []map[string]string{}}
+			if len(next) > 0 {
+				page["items"] = []map[string]string{{"name": next[0], "etag": etag(next[0])}}
+			}
+			if len(next) > 1 {
+				page["nextPageToken"] = next[0]
+			}
+			_ = json.NewEncoder(w).Encode(page)
+		case strings.HasPrefix(path, "/storage/v1/b/mirror/o/"):
+			p := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/mirror/o/")
+			if _, ok := files[p]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if q.Get("alt") == "media" {
+				_, _ = w.Write(files[p])
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"name": p, "etag": etag(p)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestConformance_S3(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		ts := newBucketServer(t, files)
+		return Target{
+			Source:      resolver.NewBucketSource(ts.Client(), resolver.BucketOptions{S3Endpoint: ts.URL, Anonymous: true}),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "s3://mirror/"+p) },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_GCS(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		ts := newBucketServer(t, files)
+		return Target{
+			Source:      resolver.NewBucketSource(ts.Client(), resolver.BucketOptions{GCSEndpoint: ts.URL, Anonymous: true}),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "gs://mirror/"+p) },
+			Directories: true,
+		}
+	})
+}
+
+func TestConformance_Registry(t *testing.T) {
+	t.Parallel()
+	RunConformance(t, func(t *testing.T, files map[string][]byte) Target {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"packages": map[string]any{
+					"conformance": map[string]any{
+						"repo":     "myorg/myrepo",
+						"versions": map[string]string{"1.0.0": "v1"},
+					},
+				},
+			})
+		}))
+		t.Cleanup(ts.Close)
+		return Target{
+			Source:      resolver.NewRegistrySource(ts.Client(), ts.URL, NewSource("abc123", files)),
+			Ref:         func(p string) config.AssetRef { return mustParse(t, "registry:conformance@1.0.0//"+p) },
+			Directories: true,
+		}
+	})
+}
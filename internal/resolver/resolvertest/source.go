@@ -0,0 +1,122 @@
+// Package resolvertest provides fixtures for testing code that depends on
+// resolver.SourceRepository: an in-memory source, an HTTP client that routes
+// every request to a test server, golden manifest/lock builders, and a
+// conformance suite new source implementations can run against.
+package resolvertest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+	"github.com/cbout22/copilot-sync/internal/resolver"
+)
+
+// Source is an in-memory resolver.SourceRepository. It serves Files for any
+// GitHub-style ref, regardless of org, repo or ref, and counts calls so tests
+// can assert on network usage.
+type Source struct {
+	SHA   string            // returned by ResolveSHA
+	Files map[string][]byte // path → content
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+var _ resolver.SourceRepository = (*Source)(nil)
+
+// NewSource returns a Source serving files at the given commit SHA.
+func NewSource(sha string, files map[string][]byte) *Source {
+	return &Source{SHA: sha, Files: files}
+}
+
+func (s *Source) record(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls == nil {
+		s.calls = make(map[string]int)
+	}
+	s.calls[method]++
+}
+
+// Calls returns how many times the named method has been called.
+func (s *Source) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Supports reports whether ref is a GitHub-style reference.
+func (s *Source) Supports(ref config.AssetRef) bool {
+	return ref.IsGitHub()
+}
+
+// ResolveRef returns the ref unchanged.
+func (s *Source) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	s.record("ResolveRef")
+	return ref, nil
+}
+
+// DownloadFile returns the content at ref.Path.
+func (s *Source) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	s.record("DownloadFile")
+	if content, ok := s.Files[ref.Path]; ok {
+		return content, nil
+	}
+	return nil, fmt.Errorf("%s: not found", ref.Raw())
+}
+
+// ListDirectory lists the files at or under ref.Path in byte-wise order.
+func (s *Source) ListDirectory(ref config.AssetRef) ([]resolver.GitHubTreeEntry, error) {
+	s.record("ListDirectory")
+	var entries []resolver.GitHubTreeEntry
+	for p, content := range s.Files {
+		if p == ref.Path || strings.HasPrefix(p, ref.Path+"/") {
+			entries = append(entries, resolver.GitHubTreeEntry{
+				Path: p,
+				Type: "blob",
+				SHA:  fmt.Sprintf("%x", sha256.Sum256(content)),
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no files found under %s", ref.Path)
+	}
+	slices.SortFunc(entries, func(a, b resolver.GitHubTreeEntry) int { return strings.Compare(a.Path, b.Path) })
+	return entries, nil
+}
+
+// ResolveSHA returns s.SHA.
+func (s *Source) ResolveSHA(ref config.AssetRef) (string, error) {
+	s.record("ResolveSHA")
+	return s.SHA, nil
+}
+
+// RoutingClient returns an HTTP client that sends every request to ts,
+// whatever host it was addressed to, keeping the path and query. It lets
+// sources with hard-coded hosts (api.github.com, ghcr.io, ...) be exercised
+// against a single test server.
+func RoutingClient(ts *httptest.Server) *http.Client {
+	return &http.Client{Transport: &routingTransport{server: ts}}
+}
+
+type routingTransport struct {
+	server *httptest.Server
+}
+
+func (rt *routingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	target := rt.server.URL + req.URL.RequestURI()
+	u, err := req.URL.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	r.URL = u
+	r.Host = u.Host
+	return rt.server.Client().Transport.RoundTrip(r)
+}
@@ -0,0 +1,164 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetriesEnvVar names the environment variable setting how many times a
+// request failing transiently is retried; 0 disables retries.
+const RetriesEnvVar = "COPS_RETRIES"
+
+// Retry defaults, used when RetryTransport leaves them unset.
+const (
+	DefaultRetries = 3
+	defaultMinWait = 500 * time.Millisecond
+	defaultMaxWait = 30 * time.Second
+)
+
+// RetriesFromEnv returns the number of retries set by $COPS_RETRIES, or
+// DefaultRetries.
+func RetriesFromEnv() (int, error) {
+	s := os.Getenv(RetriesEnvVar)
+	if s == "" {
+		return DefaultRetries, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: %q is not a number of retries", RetriesEnvVar, s)
+	}
+	return n, nil
+}
+
+// WithRetries returns a copy of client whose requests are retried up to
+// retries times (see RetryTransport). Zero retries returns client
+// unchanged.
+func WithRetries(client *http.Client, retries int) *http.Client {
+	if retries <= 0 {
+		return client
+	}
+	retrying := *client
+	retrying.Transport = &RetryTransport{Base: client.Transport, Retries: retries}
+	return &retrying
+}
+
+// RetryTransport is an http.RoundTripper that retries requests failing
+// transiently — a network error, a 5xx response or a 429 — with
+// exponential backoff and jitter, so a single flaky request does not fail
+// a whole sync. A Retry-After header, in seconds, sets the wait instead.
+// Requests whose body cannot be replayed are sent once.
+type RetryTransport struct {
+	Base    http.RoundTripper // nil means http.DefaultTransport
+	Retries int               // attempts after the first
+
+	// MinWait is the wait before the first retry, doubled for each next
+	// one up to MaxWait. Zero uses 500ms and 30s.
+	MinWait time.Duration
+	MaxWait time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := base.RoundTrip(r)
+		if attempt >= t.Retries || !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				wait = min(after, t.maxWait())
+			}
+			// Drain the body so the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a request that got resp or err may succeed
+// when sent again. An exhausted rate limit is left to RateLimitTransport:
+// retrying within seconds cannot help.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if rateLimited(resp) {
+		return false
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// backoff returns the wait before retry attempt+1: MinWait doubled attempt
+// times, capped at MaxWait, of which a random part up to half is dropped so
+// parallel clients do not retry in step.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	wait := t.MinWait
+	if wait <= 0 {
+		wait = defaultMinWait
+	}
+	for i := 0; i < attempt && wait < t.maxWait(); i++ {
+		wait *= 2
+	}
+	wait = min(wait, t.maxWait())
+	return wait - rand.N(wait/2+1)
+}
+
+// maxWait returns the longest wait between two attempts.
+func (t *RetryTransport) maxWait() time.Duration {
+	if t.MaxWait <= 0 {
+		return defaultMaxWait
+	}
+	return t.MaxWait
+}
+
+// retryAfter returns the wait a Retry-After header in seconds asks for.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// withoutRetries returns t without the RetryTransport it may wrap.
+func withoutRetries(t http.RoundTripper) http.RoundTripper {
+	switch t := t.(type) {
+	case *RetryTransport:
+		return t.Base
+	case *contextTransport:
+		return &contextTransport{base: withoutRetries(t.base), ctx: t.ctx}
+	}
+	return t
+}
@@ -0,0 +1,100 @@
+package resolver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		failures  int32 // requests answered with status before a 200
+		status    int
+		retries   int
+		wantCode  int
+		wantCalls int32
+	}{
+		{"server error then success", 2, http.StatusBadGateway, 3, http.StatusOK, 3},
+		{"rate limited then success", 1, http.StatusTooManyRequests, 3, http.StatusOK, 2},
+		{"gives up", 5, http.StatusServiceUnavailable, 2, http.StatusServiceUnavailable, 3},
+		{"client error is final", 1, http.StatusNotFound, 3, http.StatusNotFound, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("attempt %d body = %q", atomic.LoadInt32(&calls)+1, body)
+				}
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = io.WriteString(w, "ok")
+			}))
+			defer ts.Close()
+
+			client := &http.Client{Transport: &RetryTransport{Base: ts.Client().Transport, Retries: tt.retries, MinWait: time.Millisecond}}
+			resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_NetworkError(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	var calls int32
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	client := &http.Client{Transport: &RetryTransport{Base: base, Retries: 2, MinWait: time.Millisecond}}
+	if _, err := client.Get(url); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetriesFromEnv(t *testing.T) {
+	t.Setenv(RetriesEnvVar, "")
+	if n, err := RetriesFromEnv(); err != nil || n != DefaultRetries {
+		t.Errorf("RetriesFromEnv() = %d, %v, want %d", n, err, DefaultRetries)
+	}
+	t.Setenv(RetriesEnvVar, "0")
+	if n, err := RetriesFromEnv(); err != nil || n != 0 {
+		t.Errorf("RetriesFromEnv(0) = %d, %v", n, err)
+	}
+	t.Setenv(RetriesEnvVar, "many")
+	if _, err := RetriesFromEnv(); err == nil {
+		t.Error("RetriesFromEnv(many): expected error, got nil")
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
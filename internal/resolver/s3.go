@@ -0,0 +1,493 @@
+package resolver
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+
+	// emptyPayloadSHA256 is the hex SHA-256 of an empty request body.
+	emptyPayloadSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// metadataTimeout bounds calls to instance metadata services, which are
+	// unreachable (and may hang) outside the cloud.
+	metadataTimeout = time.Second
+)
+
+// awsCredentials is a resolved AWS access key.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // zero for long-lived keys
+}
+
+// expired reports whether the credentials need refreshing.
+func (c *awsCredentials) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && now.After(c.Expires.Add(-time.Minute))
+}
+
+// s3Store talks to Amazon S3 or an S3-compatible endpoint, signing requests
+// with AWS Signature Version 4.
+type s3Store struct {
+	client    *http.Client
+	endpoint  string // "" for AWS virtual-hosted addressing
+	region    string
+	anonymous bool
+
+	mu    sync.Mutex
+	creds *awsCredentials
+}
+
+func newS3Store(client *http.Client, opts BucketOptions) *s3Store {
+	region := opts.S3Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	return &s3Store{
+		client:    client,
+		endpoint:  strings.TrimSuffix(opts.S3Endpoint, "/"),
+		region:    region,
+		anonymous: opts.Anonymous,
+	}
+}
+
+// bucketURL returns the base URL of the bucket, ending in "/".
+func (s *s3Store) bucketURL(bucket string) string {
+	if s.endpoint != "" {
+		return s.endpoint + "/" + bucket + "/"
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucket, s.region)
+}
+
+func (s *s3Store) objectURL(ref config.AssetRef) string {
+	u := s.bucketURL(ref.Bucket) + awsURIEncode(ref.Path, false)
+	if ref.Ref != "" {
+		u += "?versionId=" + awsURIEncode(ref.Ref, true)
+	}
+	return u
+}
+
+// do sends a signed request and returns the response; the caller closes it.
+func (s *s3Store) do(method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.credentials()
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		signV4(req, creds, s.region, "s3", time.Now())
+	}
+	return s.client.Do(req)
+}
+
+func (s *s3Store) get(ref config.AssetRef, limit int64) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, s.objectURL(ref))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := objectStoreError(resp); err != nil {
+		return nil, err
+	}
+	return readBody(resp, limit)
+}
+
+func (s *s3Store) stat(ref config.AssetRef) (objectInfo, error) {
+	resp, err := s.do(http.MethodHead, s.objectURL(ref))
+	if err != nil {
+		return objectInfo{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := objectStoreError(resp); err != nil {
+		return objectInfo{}, err
+	}
+	return objectInfo{Key: ref.Path, ETag: unquoteETag(resp.Header.Get("ETag"))}, nil
+}
+
+// s3ListResult is the subset of a ListObjectsV2 response cops needs.
+type s3ListResult struct {
+	Contents []struct {
+		Key  string
+		ETag string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *s3Store) list(bucket, prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	token := ""
+	for {
+		u := s.bucketURL(bucket) + "?list-type=2&prefix=" + awsURIEncode(prefix, true)
+		if token != "" {
+			u += "&continuation-token=" + awsURIEncode(token, true)
+		}
+		resp, err := s.do(http.MethodGet, u)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = objectStoreError(resp)
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&result)
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, objectInfo{Key: c.Key, ETag: unquoteETag(c.ETag)})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// credentials returns cached credentials, resolving them through the chain
+// on first use or after expiry. It returns nil for anonymous access.
+func (s *s3Store) credentials() (*awsCredentials, error) {
+	if s.anonymous {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creds != nil && !s.creds.expired(time.Now()) {
+		return s.creds, nil
+	}
+	creds, err := awsCredentialChain(s.client)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS credentials: %w", err)
+	}
+	s.creds = creds
+	return creds, nil
+}
+
+// awsCredentialChain resolves credentials the way the AWS CLI does:
+// environment variables, the shared credentials file, the ECS container
+// endpoint, then the EC2 instance metadata service. It returns nil, nil
+// when no credentials are configured.
+func awsCredentialChain(client *http.Client) (*awsCredentials, error) {
+	providers := []func() (*awsCredentials, error){
+		awsEnvCredentials,
+		awsSharedCredentials,
+		func() (*awsCredentials, error) { return awsContainerCredentials(client) },
+		func() (*awsCredentials, error) { return awsInstanceCredentials(client) },
+	}
+	for _, provider := range providers {
+		creds, err := provider()
+		if err != nil || creds != nil {
+			return creds, err
+		}
+	}
+	return nil, nil
+}
+
+func awsEnvCredentials() (*awsCredentials, error) {
+	id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return nil, nil
+	}
+	return &awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+}
+
+// awsSharedCredentials reads the AWS_PROFILE (or "default") section of the
+// shared credentials file.
+func awsSharedCredentials() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	if values["aws_access_key_id"] == "" || values["aws_secret_access_key"] == "" {
+		return nil, nil
+	}
+	return &awsCredentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}, nil
+}
+
+// awsMetadataCredentials is the credential document served by the ECS
+// container endpoint and the EC2 instance metadata service.
+type awsMetadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (m awsMetadataCredentials) credentials() *awsCredentials {
+	return &awsCredentials{
+		AccessKeyID:     m.AccessKeyID,
+		SecretAccessKey: m.SecretAccessKey,
+		SessionToken:    m.Token,
+		Expires:         m.Expiration,
+	}
+}
+
+// awsContainerCredentials queries the ECS/EKS container credential
+// endpoint, which is only configured through environment variables.
+func awsContainerCredentials(client *http.Client) (*awsCredentials, error) {
+	u := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		u = "http://169.254.170.2" + rel
+	}
+	if u == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	var doc awsMetadataCredentials
+	if err := getJSON(metadataClient(client), req, &doc); err != nil {
+		return nil, fmt.Errorf("container credentials: %w", err)
+	}
+	return doc.credentials(), nil
+}
+
+// awsInstanceCredentials queries the EC2 instance metadata service (IMDSv2)
+// for the instance role's credentials. An unreachable service means "not on
+// EC2" and is not an error.
+func awsInstanceCredentials(client *http.Client) (*awsCredentials, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, nil
+	}
+	base := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if base == "" {
+		base = "http://169.254.169.254"
+	}
+	base = strings.TrimSuffix(base, "/")
+	mc := metadataClient(client)
+
+	req, err := http.NewRequest(http.MethodPut, base+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	resp, err := mc.Do(req)
+	if err != nil {
+		return nil, nil
+	}
+	token, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+
+	credsURL := base + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequest(http.MethodGet, credsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	resp, err = mc.Do(req)
+	if err != nil {
+		return nil, nil
+	}
+	role, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(role) == 0 {
+		// No instance role attached.
+		return nil, nil
+	}
+
+	req, err = http.NewRequest(http.MethodGet, credsURL+strings.TrimSpace(string(role)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	var doc awsMetadataCredentials
+	if err := getJSON(mc, req, &doc); err != nil {
+		return nil, fmt.Errorf("instance credentials: %w", err)
+	}
+	return doc.credentials(), nil
+}
+
+// metadataClient returns a copy of client with a short timeout and no
+// retries, for probing instance metadata services that are usually absent.
+func metadataClient(client *http.Client) *http.Client {
+	return &http.Client{Transport: withoutRetries(client.Transport), Timeout: metadataTimeout}
+}
+
+// getJSON sends req and decodes a 200 JSON response into v.
+func getJSON(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d — %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// signV4 signs req in place with AWS Signature Version 4. Every header
+// already set on the request is signed, along with Host.
+func signV4(req *http.Request, creds *awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadSHA256)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadSHA256,
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires.
+func canonicalQuery(q url.Values) string {
+	pairs := make([]string, 0, len(q))
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes everything except RFC 3986 unreserved
+// characters, and "/" unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
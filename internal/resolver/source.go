@@ -0,0 +1,72 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// SourceRepository is a backend that serves assets for a particular kind of
+// reference (GitHub repositories, direct HTTPS URLs, ...).
+type SourceRepository interface {
+	ResolverAPI
+	// Supports reports whether this source can serve the given reference.
+	Supports(ref config.AssetRef) bool
+}
+
+// Router dispatches each call to the first SourceRepository that supports
+// the reference. It implements ResolverAPI so it can be handed to the
+// injector and CLI commands in place of a single source.
+type Router struct {
+	sources []SourceRepository
+}
+
+// NewRouter creates a Router over the given sources, tried in order.
+func NewRouter(sources ...SourceRepository) *Router {
+	return &Router{sources: sources}
+}
+
+func (rt *Router) sourceFor(ref config.AssetRef) (SourceRepository, error) {
+	for _, s := range rt.sources {
+		if s.Supports(ref) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no source available for %s", ref.Raw())
+}
+
+// ResolveRef delegates to the source that supports ref.
+func (rt *Router) ResolveRef(ref config.AssetRef) (config.AssetRef, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return ref, err
+	}
+	return s.ResolveRef(ref)
+}
+
+// DownloadFile delegates to the source that supports ref.
+func (rt *Router) DownloadFile(ref config.AssetRef) ([]byte, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.DownloadFile(ref)
+}
+
+// ListDirectory delegates to the source that supports ref.
+func (rt *Router) ListDirectory(ref config.AssetRef) ([]GitHubTreeEntry, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.ListDirectory(ref)
+}
+
+// ResolveSHA delegates to the source that supports ref.
+func (rt *Router) ResolveSHA(ref config.AssetRef) (string, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return "", err
+	}
+	return s.ResolveSHA(ref)
+}
@@ -0,0 +1,236 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// Commit is an upstream commit, as listed in an update's changelog.
+type Commit struct {
+	SHA     string
+	Message string // first line of the commit message
+	URL     string // web page of the commit
+}
+
+// UpdateFinder is implemented by sources that can look up newer versions
+// of a reference and what changed since.
+type UpdateFinder interface {
+	// NewerTag returns the highest version tag of the repository ref
+	// points into, pre-releases excluded, if it is above ref.Ref, and ""
+	// otherwise.
+	NewerTag(ref config.AssetRef) (string, error)
+
+	// Compare lists the commits of the repository ref points into that
+	// are reachable from head but not from base and change ref.Path (any
+	// path if it is empty), oldest first.
+	Compare(ref config.AssetRef, base, head string) ([]Commit, error)
+}
+
+// NewerTag delegates to the source serving ref.
+func (rt *Router) NewerTag(ref config.AssetRef) (string, error) {
+	f, err := rt.updateFinder(ref)
+	if err != nil {
+		return "", err
+	}
+	return f.NewerTag(ref)
+}
+
+// Compare delegates to the source serving ref.
+func (rt *Router) Compare(ref config.AssetRef, base, head string) ([]Commit, error) {
+	f, err := rt.updateFinder(ref)
+	if err != nil {
+		return nil, err
+	}
+	return f.Compare(ref, base, head)
+}
+
+// updateFinder returns the source serving ref, if it can look up updates.
+func (rt *Router) updateFinder(ref config.AssetRef) (UpdateFinder, error) {
+	s, err := rt.sourceFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := s.(UpdateFinder)
+	if !ok {
+		return nil, fmt.Errorf("updates can only be looked up for GitHub refs, not %s", ref.Raw())
+	}
+	return f, nil
+}
+
+// NewerTag returns the highest version tag of the repository, among its
+// 100 most recent tags, if it is above ref.Ref.
+func (r *Resolver) NewerTag(ref config.AssetRef) (string, error) {
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=100", githubAPIBase, ref.Org, ref.Repo)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := getJSON(r.client, req, &tags); err != nil {
+		return "", fmt.Errorf("listing tags of %s: %w", ref.RepoFullName(), err)
+	}
+	latest := ref.Ref
+	for _, tag := range tags {
+		candidate := ref
+		candidate.Ref = tag.Name
+		if !candidate.IsVersionTag() || strings.Contains(tag.Name, "-") {
+			continue
+		}
+		if compareVersions(tag.Name, latest) > 0 {
+			latest = tag.Name
+		}
+	}
+	if latest == ref.Ref {
+		return "", nil
+	}
+	return latest, nil
+}
+
+// Compare lists the commits between base and head, as GitHub's compare
+// API returns them (at most 250), keeping those among the 100 latest
+// commits of head that change ref.Path.
+func (r *Resolver) Compare(ref config.AssetRef, base, head string) ([]Commit, error) {
+	var comparison struct {
+		Commits []struct {
+			SHA     string `json:"sha"`
+			HTMLURL string `json:"html_url"`
+			Commit  struct {
+				Message string `json:"message"`
+			} `json:"commit"`
+		} `json:"commits"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s", githubAPIBase, ref.Org, ref.Repo, base, head)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := getJSON(r.client, req, &comparison); err != nil {
+		return nil, fmt.Errorf("comparing %s@%s with %s: %w", ref.RepoFullName(), base, head, err)
+	}
+	var touching map[string]bool
+	if ref.Path != "" {
+		if touching, err = r.pathCommits(ref, head); err != nil {
+			return nil, err
+		}
+	}
+	commits := make([]Commit, 0, len(comparison.Commits))
+	for _, c := range comparison.Commits {
+		if touching != nil && !touching[c.SHA] {
+			continue
+		}
+		subject, _, _ := strings.Cut(c.Commit.Message, "\n")
+		commits = append(commits, Commit{SHA: c.SHA, Message: subject, URL: c.HTMLURL})
+	}
+	return commits, nil
+}
+
+// pathCommits returns the SHAs of the 100 latest commits of head that
+// change ref.Path.
+func (r *Resolver) pathCommits(ref config.AssetRef, head string) (map[string]bool, error) {
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	query := url.Values{"sha": {head}, "path": {ref.Path}, "per_page": {"100"}}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/%s/commits?%s", githubAPIBase, ref.Org, ref.Repo, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := getJSON(r.client, req, &commits); err != nil {
+		return nil, fmt.Errorf("listing commits of %s changing %s: %w", ref.RepoFullName(), ref.Path, err)
+	}
+	shas := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		shas[c.SHA] = true
+	}
+	return shas, nil
+}
+
+// PullRequest is a pull request to open on GitHub.
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"` // branch holding the changes
+	Base  string `json:"base"` // branch to merge them into
+}
+
+// OpenPullRequest opens pr on the GitHub repository repo ("org/repo")
+// and returns its web URL. If a pull request is already open for the
+// head branch, its title and body are updated instead.
+func OpenPullRequest(client *http.Client, repo string, pr PullRequest) (string, error) {
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Post(fmt.Sprintf("%s/repos/%s/pulls", githubAPIBase, repo), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("opening pull request on %s: %w", repo, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+			return "", fmt.Errorf("decoding pull request: %w", err)
+		}
+		return created.HTMLURL, nil
+	case http.StatusUnprocessableEntity:
+		// Most likely one is already open for the branch.
+		if url, err := updatePullRequest(client, repo, pr); err == nil {
+			return url, nil
+		}
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return "", fmt.Errorf("opening pull request on %s: HTTP %d — %s", repo, resp.StatusCode, string(data))
+}
+
+// updatePullRequest updates the title and body of the open pull request
+// of pr.Head, returning its web URL.
+func updatePullRequest(client *http.Client, repo string, pr PullRequest) (string, error) {
+	owner, _, _ := strings.Cut(repo, "/")
+	var open []struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/pulls?state=open&head=%s:%s", githubAPIBase, repo, owner, pr.Head)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := getJSON(client, req, &open); err != nil {
+		return "", err
+	}
+	if len(open) == 0 {
+		return "", fmt.Errorf("no open pull request for %s", pr.Head)
+	}
+
+	body, err := json.Marshal(map[string]string{"title": pr.Title, "body": pr.Body})
+	if err != nil {
+		return "", err
+	}
+	req, err = http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/repos/%s/pulls/%d", githubAPIBase, repo, open[0].Number), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("updating pull request #%d: HTTP %d", open[0].Number, resp.StatusCode)
+	}
+	return open[0].HTMLURL, nil
+}
@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+// newUpdatesTestClient returns a client sending GitHub API requests to a
+// server with routes.
+func newUpdatesTestClient(t *testing.T, routes map[string]func(w http.ResponseWriter, r *http.Request)) *http.Client {
+	t.Helper()
+	ts := newTestServer(t, routes)
+	t.Cleanup(ts.Close)
+	return &http.Client{Transport: &rewriteTransport{
+		base:    ts.Client().Transport,
+		apiBase: ts.URL,
+		rawBase: ts.URL,
+		origAPI: githubAPIBase,
+		origRaw: githubRawBase,
+	}}
+}
+
+func TestResolver_NewerTag(t *testing.T) {
+	t.Parallel()
+	client := newUpdatesTestClient(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/myrepo/tags": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]string{
+				{"name": "v1.9.0"}, {"name": "v1.10.0"}, {"name": "v2.0.0-rc.1"}, {"name": "nightly"}, {"name": "v1.2.0"},
+			})
+		},
+	})
+
+	res := New(client)
+	got, err := res.NewerTag(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "a.md", Ref: "v1.2.0"})
+	if err != nil || got != "v1.10.0" {
+		t.Errorf("NewerTag(v1.2.0) = %q, %v; want v1.10.0", got, err)
+	}
+	if got, err := res.NewerTag(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "a.md", Ref: "v1.10.0"}); err != nil || got != "" {
+		t.Errorf("NewerTag(v1.10.0) = %q, %v; want none", got, err)
+	}
+}
+
+func TestResolver_Compare(t *testing.T) {
+	t.Parallel()
+	client := newUpdatesTestClient(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/myrepo/compare/v1.0.0...v1.1.0": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"commits": []map[string]any{
+				{"sha": "aaa", "html_url": "https://github.com/myorg/myrepo/commit/aaa", "commit": map[string]string{"message": "Tighten the review checklist\n\nDetails."}},
+				{"sha": "bbb", "html_url": "https://github.com/myorg/myrepo/commit/bbb", "commit": map[string]string{"message": "Release v1.1.0"}},
+			}})
+		},
+		"/repos/myorg/myrepo/commits": func(w http.ResponseWriter, r *http.Request) {
+			if q := r.URL.Query(); q.Get("sha") != "v1.1.0" || q.Get("path") != "prompts/a.md" {
+				t.Errorf("commits listed with %s, want sha=v1.1.0 and path=prompts/a.md", r.URL.RawQuery)
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"sha": "aaa"}, {"sha": "older"}})
+		},
+	})
+	res := New(client)
+
+	commits, err := res.Compare(config.AssetRef{Org: "myorg", Repo: "myrepo", Ref: "v1.0.0"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Compare() = %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "Tighten the review checklist" || commits[1].SHA != "bbb" {
+		t.Errorf("Compare() = %+v", commits)
+	}
+
+	// Only the commits changing the asset are kept.
+	commits, err = res.Compare(config.AssetRef{Org: "myorg", Repo: "myrepo", Path: "prompts/a.md", Ref: "v1.0.0"}, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Compare(path) = %v", err)
+	}
+	if len(commits) != 1 || commits[0].SHA != "aaa" {
+		t.Errorf("Compare(path) = %+v, want only aaa", commits)
+	}
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	t.Parallel()
+	var got PullRequest
+	client := newUpdatesTestClient(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/project/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&got)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"number": 7, "html_url": "https://github.com/myorg/project/pull/7"})
+		},
+	})
+
+	pr := PullRequest{Title: "Update Copilot assets", Body: "body", Head: "cops/update-assets", Base: "main"}
+	url, err := OpenPullRequest(client, "myorg/project", pr)
+	if err != nil || url != "https://github.com/myorg/project/pull/7" {
+		t.Errorf("OpenPullRequest() = %q, %v", url, err)
+	}
+	if got != pr {
+		t.Errorf("request = %+v, want %+v", got, pr)
+	}
+}
+
+func TestOpenPullRequest_UpdatesOpenOne(t *testing.T) {
+	t.Parallel()
+	var patched map[string]string
+	client := newUpdatesTestClient(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/repos/myorg/project/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				if r.URL.Query().Get("head") != "myorg:cops/update-assets" {
+					t.Errorf("head = %q", r.URL.Query().Get("head"))
+				}
+				_ = json.NewEncoder(w).Encode([]map[string]any{{"number": 3, "html_url": "https://github.com/myorg/project/pull/3"}})
+				return
+			}
+			http.Error(w, `{"message":"A pull request already exists"}`, http.StatusUnprocessableEntity)
+		},
+		"/repos/myorg/project/pulls/3": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&patched)
+			_ = json.NewEncoder(w).Encode(map[string]any{"number": 3})
+		},
+	})
+
+	url, err := OpenPullRequest(client, "myorg/project", PullRequest{Title: "Update", Body: "new body", Head: "cops/update-assets", Base: "main"})
+	if err != nil || url != "https://github.com/myorg/project/pull/3" {
+		t.Errorf("OpenPullRequest() = %q, %v; want the open pull request", url, err)
+	}
+	if patched["body"] != "new body" {
+		t.Errorf("patched = %v, want the new body", patched)
+	}
+}
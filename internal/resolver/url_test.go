@@ -0,0 +1,162 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cbout22/copilot-sync/internal/config"
+)
+
+func newURLTestServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("URL source must not send credentials, got Authorization=%q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/assets/review.md" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestURLSource_DownloadFile(t *testing.T) {
+	t.Parallel()
+	ts := newURLTestServer(t, "# Review\n")
+	defer ts.Close()
+
+	ref, err := config.ParseRef(ts.URL + "/assets/review.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewURLSource(ts.Client()).DownloadFile(ref)
+	if err != nil {
+		t.Fatalf("DownloadFile: unexpected error: %v", err)
+	}
+	if string(got) != "# Review\n" {
+		t.Errorf("DownloadFile: got %q", got)
+	}
+}
+
+func TestURLSource_DownloadFile_Checksum(t *testing.T) {
+	t.Parallel()
+	body := "# Review\n"
+	ts := newURLTestServer(t, body)
+	defer ts.Close()
+
+	good := fmt.Sprintf("%x", sha256.Sum256([]byte(body)))
+	bad := strings.Repeat("0", 64)
+
+	cases := []struct {
+		name    string
+		sum     string
+		wantErr bool
+	}{
+		{"match", good, false},
+		{"mismatch", bad, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := config.ParseRef(ts.URL + "/assets/review.md#sha256=" + tc.sum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewURLSource(ts.Client()).DownloadFile(ref)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("DownloadFile: err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestURLSource_DownloadFile_NotFound(t *testing.T) {
+	t.Parallel()
+	ts := newURLTestServer(t, "")
+	defer ts.Close()
+
+	ref, err := config.ParseRef(ts.URL + "/missing.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewURLSource(ts.Client()).DownloadFile(ref); err == nil {
+		t.Fatal("DownloadFile(missing): expected error, got nil")
+	}
+}
+
+func TestURLSource_ListDirectory_Unsupported(t *testing.T) {
+	t.Parallel()
+	ref, _ := config.ParseRef("https://example.com/skills/k8s")
+	if _, err := NewURLSource(&http.Client{}).ListDirectory(ref); err == nil {
+		t.Fatal("ListDirectory: expected error for URL source, got nil")
+	}
+}
+
+func TestRouter_DispatchesBySource(t *testing.T) {
+	t.Parallel()
+	ts := newURLTestServer(t, "from url")
+	defer ts.Close()
+
+	rt := NewRouter(NewURLSource(ts.Client()), New(&http.Client{}))
+
+	urlRef, _ := config.ParseRef(ts.URL + "/assets/review.md")
+	got, err := rt.DownloadFile(urlRef)
+	if err != nil {
+		t.Fatalf("DownloadFile(url): unexpected error: %v", err)
+	}
+	if string(got) != "from url" {
+		t.Errorf("DownloadFile(url): got %q", got)
+	}
+
+	ghRef := config.AssetRef{Org: "o", Repo: "r", Path: "p", Ref: "v1"}
+	resolved, err := rt.ResolveRef(ghRef)
+	if err != nil || resolved != ghRef {
+		t.Errorf("ResolveRef(github): got %+v, %v", resolved, err)
+	}
+}
+
+func TestRouter_NoSource(t *testing.T) {
+	t.Parallel()
+	rt := NewRouter(New(&http.Client{}))
+	ref, _ := config.ParseRef("https://example.com/file.md")
+	if _, err := rt.DownloadFile(ref); err == nil {
+		t.Fatal("DownloadFile: expected error when no source supports the ref")
+	}
+}
+
+// Verify sources implement SourceRepository at compile time.
+var (
+	_ SourceRepository = (*Resolver)(nil)
+	_ SourceRepository = (*URLSource)(nil)
+	_ ResolverAPI      = (*Router)(nil)
+)
+
+func TestURLSource_DownloadFile_Limit(t *testing.T) {
+	t.Parallel()
+	body := strings.Repeat("x", 2<<10)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked.md" {
+			// Flushing first leaves the length unannounced.
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	src := NewURLSource(ts.Client())
+	src.SetLimits(Limits{File: 1 << 10})
+	for _, p := range []string{"/sized.md", "/chunked.md"} {
+		ref, err := config.ParseRef(ts.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := src.DownloadFile(ref); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("DownloadFile(%s): got %v, want ErrTooLarge", p, err)
+		}
+	}
+}
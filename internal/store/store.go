@@ -0,0 +1,188 @@
+// Package store keeps asset content in a user-level, content-addressed
+// directory, so projects can link to a single shared copy instead of each
+// holding their own, and content already downloaded once is not downloaded
+// again.
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvVar names the environment variable overriding the store directory.
+const EnvVar = "COPS_STORE"
+
+// CacheEnvVar names the environment variable that disables looking up
+// downloads in the store when set to "off".
+const CacheEnvVar = "COPS_CACHE"
+
+// Link modes: how project files point at their stored copy.
+const (
+	Symlink  = "symlink"
+	Hardlink = "hardlink"
+)
+
+// Store is a content-addressed directory: each content lives, read-only,
+// at sha256/<hex digest> under Dir, and at sha256/<hex digest>.x when it
+// is stored executable.
+type Store struct {
+	Dir string
+}
+
+// Default returns the user-level store: $COPS_STORE if set, the cops
+// folder of the OS user cache directory otherwise (e.g.
+// ~/.cache/cops on Linux, which keeps content in ~/.cache/cops/sha256).
+func Default() (Store, error) {
+	if dir := os.Getenv(EnvVar); dir != "" {
+		return Store{Dir: dir}, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return Store{}, fmt.Errorf("locating the content store: %w (set %s)", err, EnvVar)
+	}
+	return Store{Dir: filepath.Join(cache, "cops")}, nil
+}
+
+// CacheDisabled reports whether $COPS_CACHE turns the download cache off.
+func CacheDisabled() bool {
+	return os.Getenv(CacheEnvVar) == "off"
+}
+
+// CheckMode validates a link mode.
+func CheckMode(mode string) error {
+	switch mode {
+	case Symlink, Hardlink:
+		return nil
+	}
+	return fmt.Errorf("invalid link mode %q: use %q or %q", mode, Symlink, Hardlink)
+}
+
+// Path returns where content is kept in the store.
+func (s Store) Path(content []byte, executable bool) string {
+	sum := sha256.Sum256(content)
+	name := hex.EncodeToString(sum[:])
+	if executable {
+		name += ".x"
+	}
+	return filepath.Join(s.Dir, "sha256", name)
+}
+
+// Get returns the stored content whose hex SHA-256 digest is digest,
+// executable or not. A copy that no longer matches its digest is ignored.
+func (s Store) Get(digest string) ([]byte, bool) {
+	for _, name := range []string{digest, digest + ".x"} {
+		content, err := os.ReadFile(filepath.Join(s.Dir, "sha256", name))
+		if err != nil {
+			continue
+		}
+		if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) == digest {
+			return content, true
+		}
+	}
+	return nil, false
+}
+
+// Put adds content to the store, unless an intact copy is already there,
+// and returns its path. Stored files are read-only, so an edit through a
+// link cannot change what other projects see.
+func (s Store) Put(content []byte, executable bool) (string, error) {
+	path := s.Path(content, executable)
+	perm := fs.FileMode(0444)
+	if executable {
+		perm = 0555
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return path, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating store directory: %w", err)
+	}
+
+	// Write a temporary file next to the final one and rename it into
+	// place, so concurrent syncs never see a partial copy.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return "", fmt.Errorf("writing to store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("writing to store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("writing to store: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return "", fmt.Errorf("writing to store: %w", err)
+	}
+	// A damaged read-only copy must be removed before it can be replaced
+	// on every platform.
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("replacing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("writing to store: %w", err)
+	}
+	return path, nil
+}
+
+// Usage returns how many contents the store holds and their total size in
+// bytes. A store not created yet is empty.
+func (s Store) Usage() (files int, size int64, err error) {
+	entries, err := os.ReadDir(filepath.Join(s.Dir, "sha256"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading store: %w", err)
+	}
+	for _, e := range entries {
+		// Temporary files of a Put in progress are not contents yet.
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files++
+		size += info.Size()
+	}
+	return files, size, nil
+}
+
+// Link replaces the file at target with a link, of the given mode, to the
+// stored copy of content.
+func (s Store) Link(mode, target string, content []byte, executable bool) error {
+	if err := CheckMode(mode); err != nil {
+		return err
+	}
+	stored, err := s.Put(content, executable)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing existing file: %w", err)
+	}
+	if mode == Hardlink {
+		if err := os.Link(stored, target); err != nil {
+			return fmt.Errorf("linking %s: %w (hard links need the store on the same file system; try %s)", target, err, Symlink)
+		}
+		return nil
+	}
+	abs, err := filepath.Abs(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(abs, target); err != nil {
+		return fmt.Errorf("linking %s: %w", target, err)
+	}
+	return nil
+}
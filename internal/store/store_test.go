@@ -0,0 +1,153 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPut(t *testing.T) {
+	t.Parallel()
+	s := Store{Dir: t.TempDir()}
+	content := []byte("Use gofmt.")
+
+	path, err := s.Put(content, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != s.Path(content, false) {
+		t.Errorf("Put() = %q, want %q", path, s.Path(content, false))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("stored file is writable: %v", info.Mode())
+	}
+
+	// A damaged copy is replaced.
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(content, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(content) {
+		t.Errorf("stored content = %q after Put, want %q", got, content)
+	}
+}
+
+func TestPut_Executable(t *testing.T) {
+	t.Parallel()
+	s := Store{Dir: t.TempDir()}
+	content := []byte("#!/bin/sh\n")
+
+	path, err := s.Put(content, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == s.Path(content, false) {
+		t.Error("executable copy shares the path of the plain one")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0555 {
+		t.Errorf("mode = %v, want 0555", got)
+	}
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	s := Store{Dir: t.TempDir()}
+	plain, script := []byte("Use gofmt."), []byte("#!/bin/sh\n")
+	for _, c := range []struct {
+		content    []byte
+		executable bool
+	}{{plain, false}, {script, true}} {
+		if _, err := s.Put(c.content, c.executable); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(c.content)
+		got, ok := s.Get(hex.EncodeToString(sum[:]))
+		if !ok || string(got) != string(c.content) {
+			t.Errorf("Get() = %q, %v, want %q, true", got, ok, c.content)
+		}
+	}
+
+	// A damaged copy is a miss.
+	path := s.Path(plain, false)
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(plain)
+	if _, ok := s.Get(hex.EncodeToString(sum[:])); ok {
+		t.Error("Get() returned a damaged copy")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+	s := Store{Dir: t.TempDir()}
+	if files, size, err := s.Usage(); err != nil || files != 0 || size != 0 {
+		t.Errorf("Usage() of a new store = %d, %d, %v, want 0, 0, nil", files, size, err)
+	}
+	for _, content := range []string{"Use gofmt.", "Review the diff.", "Use gofmt."} {
+		if _, err := s.Put([]byte(content), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, size, err := s.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len("Use gofmt.") + len("Review the diff.")); files != 2 || size != want {
+		t.Errorf("Usage() = %d, %d, want 2, %d", files, size, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	t.Parallel()
+	for _, mode := range []string{Symlink, Hardlink} {
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+			s := Store{Dir: t.TempDir()}
+			target := filepath.Join(t.TempDir(), "go.instructions.md")
+			if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Link(mode, target, []byte("new"), false); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(target); string(got) != "new" {
+				t.Errorf("target content = %q, want %q", got, "new")
+			}
+			linked, _ := os.Stat(target)
+			stored, _ := os.Stat(s.Path([]byte("new"), false))
+			if !os.SameFile(linked, stored) {
+				t.Error("target does not point at the stored copy")
+			}
+			info, _ := os.Lstat(target)
+			if isSymlink := info.Mode()&os.ModeSymlink != 0; isSymlink != (mode == Symlink) {
+				t.Errorf("target mode = %v", info.Mode())
+			}
+		})
+	}
+}
+
+func TestCheckMode(t *testing.T) {
+	t.Parallel()
+	if err := CheckMode("copy"); err == nil {
+		t.Error("CheckMode(copy): expected error, got nil")
+	}
+}
@@ -0,0 +1,100 @@
+// Package ui adapts the messages cops prints to where they are shown: the
+// emoji and symbols they are decorated with are spelled in ASCII for CI
+// logs and terminals that render them badly.
+package ui
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// plain is set by SetPlain.
+var plain atomic.Bool
+
+// SetPlain makes Text spell symbols in ASCII, or leave them again when
+// enabled is false.
+func SetPlain(enabled bool) {
+	plain.Store(enabled)
+}
+
+// Plain reports whether messages are printed in plain ASCII.
+func Plain() bool {
+	return plain.Load()
+}
+
+// PlainByDefault reports whether output to f should be plain unless asked
+// otherwise: when NO_COLOR is set (https://no-color.org) or f is not a
+// terminal.
+func PlainByDefault(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	stat, err := f.Stat()
+	return err != nil || stat.Mode()&os.ModeCharDevice == 0
+}
+
+// symbols maps the symbols of messages to their ASCII spelling.
+var symbols = [][2]string{
+	{"✅", "[ok]"},
+	{"❌", "[error]"},
+	{"⚠", "[warn]"},
+	{"❓", "[?]"},
+	{"📋", "[info]"},
+	{"📦", "[asset]"},
+	{"🗑", "[deleted]"},
+	{"⬆", "[update]"},
+	{"🔍", "[check]"},
+	{"🔧", "[fix]"},
+	{"⏳", "[wait]"},
+	{"✏", "[edit]"},
+	{"🔀", "[pr]"},
+	{"🔄", "[sync]"},
+	{"📊", "[summary]"},
+	{"🔑", "[auth]"},
+	{"🔁", "[switch]"},
+	{"📄", "[file]"},
+	{"📂", "[dir]"},
+	{"🧹", "[clean]"},
+	{"📤", "[publish]"},
+	{"✨", "[new]"},
+	{"👀", "[watch]"},
+	{"👋", "[bye]"},
+	{"📝", "[changed]"},
+	{"⏹", "[stopped]"},
+	{"⏬", "[download]"},
+	{"💾", "[backup]"},
+	{"🔒", "[locked]"},
+	{"📸", "[snapshot]"},
+	{"█", "#"},
+	{"░", "."},
+	{"→", "->"},
+	{"←", "<-"},
+	{"—", "-"},
+}
+
+// variationSelector asks for the emoji presentation of the symbol before
+// it. Messages follow such symbols with two spaces, as terminals often
+// draw them one cell wide; the ASCII spelling needs one.
+const variationSelector = "\uFE0F"
+
+var replacer = func() *strings.Replacer {
+	var oldnew []string
+	for _, s := range symbols {
+		// Longer forms first: the replacer tries them in order.
+		oldnew = append(oldnew,
+			s[0]+variationSelector+"  ", s[1]+" ",
+			s[0]+variationSelector, s[1],
+			s[0], s[1])
+	}
+	return strings.NewReplacer(oldnew...)
+}()
+
+// Text returns s, a message, with its symbols spelled in ASCII when
+// messages are plain.
+func Text(s string) string {
+	if !Plain() {
+		return s
+	}
+	return replacer.Replace(s)
+}
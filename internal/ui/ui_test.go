@@ -0,0 +1,35 @@
+package ui
+
+import "testing"
+
+func TestText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"✅ All assets synced successfully.", "[ok] All assets synced successfully."},
+		{"  ⚠️  prompts/review — skipped", "  [warn] prompts/review - skipped"},
+		{"  📦 skills/k8s ← org/repo/k8s@v1", "  [asset] skills/k8s <- org/repo/k8s@v1"},
+		{"  ⏬ skills/k8s [██░░] 2/4 files", "  [download] skills/k8s [##..] 2/4 files"},
+		{"plain text, café", "plain text, café"},
+	}
+
+	SetPlain(false)
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.in {
+			t.Errorf("Text(%q) with symbols = %q, want it unchanged", tt.in, got)
+		}
+	}
+
+	SetPlain(true)
+	defer SetPlain(false)
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlainByDefault(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if !PlainByDefault(nil) {
+		t.Error("PlainByDefault() = false with NO_COLOR set")
+	}
+}